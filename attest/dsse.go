@@ -0,0 +1,110 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Envelope is a DSSE envelope as used by cosign attestations
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+type Signature struct {
+	KeyId string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// PAE returns the DSSE pre-authentication encoding of the payload that gets signed
+func PAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// Sign serializes the statement and signs it into a DSSE envelope
+func Sign(statement *Statement, signer Signer) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal statement")
+	}
+	sig, err := signer.Sign(PAE(InTotoPayloadType, payload))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign statement")
+	}
+	return &Envelope{
+		PayloadType: InTotoPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{{
+			Sig: base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, nil
+}
+
+// Verify checks the envelope is signed by key and returns the in-toto statement it carries
+func Verify(envelope []byte, key crypto.PublicKey) (*Statement, error) {
+	var env Envelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return nil, errors.Wrap(err, "failed to parse envelope")
+	}
+	if env.PayloadType != InTotoPayloadType {
+		return nil, errors.Errorf("unsupported payload type %s", env.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode payload")
+	}
+	pae := PAE(env.PayloadType, payload)
+	for _, s := range env.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		if verifySignature(key, pae, sig) {
+			var statement Statement
+			if err := json.Unmarshal(payload, &statement); err != nil {
+				return nil, errors.Wrap(err, "failed to parse statement")
+			}
+			return &statement, nil
+		}
+	}
+	return nil, errors.New("no valid signature found")
+}
+
+func verifySignature(key crypto.PublicKey, data []byte, sig []byte) bool {
+	digest := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, data, sig)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	statement, err := NewStatement("docker.io/library/alpine", "sha256:1234", PredicateSPDX, []byte(`{"spdxVersion": "SPDX-2.3"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := Sign(statement, NewSigner(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, _ := base64.StdEncoding.DecodeString(envelope.Payload)
+	sig, _ := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+	digest := sha256.Sum256(PAE(InTotoPayloadType, payload))
+	if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], sig) {
+		t.Error("signature does not verify")
+	}
+
+	var decoded Statement
+	_ = json.Unmarshal(payload, &decoded)
+	if decoded.Type != StatementType || decoded.Subject[0].Digest["sha256"] != "1234" {
+		t.Errorf("unexpected statement %s", string(payload))
+	}
+}
+
+func TestPredicateType(t *testing.T) {
+	if _, err := PredicateType("sarif"); err == nil {
+		t.Error("expected sarif to be rejected as predicate")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	statement, _ := NewStatement("docker.io/library/alpine", "sha256:1234", PredicateIndexSbom, []byte(`{}`))
+	envelope, _ := Sign(statement, NewSigner(key))
+	raw, _ := json.Marshal(envelope)
+
+	if s, err := Verify(raw, &key.PublicKey); err != nil || s.PredicateType != PredicateIndexSbom {
+		t.Errorf("expected envelope to verify: %v", err)
+	}
+	if _, err := Verify(raw, &other.PublicKey); err == nil {
+		t.Error("expected envelope signed by other key to be rejected")
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultFulcioUrl = "https://fulcio.sigstore.dev"
+	defaultRekorUrl  = "https://rekor.sigstore.dev"
+)
+
+// KeylessSigner signs with an ephemeral key certified by Fulcio for the OIDC identity
+type KeylessSigner struct {
+	Signer
+	// Certificate is the PEM encoded signing certificate issued by Fulcio
+	Certificate []byte
+	// Chain is the PEM encoded certificate chain of the signing certificate
+	Chain []byte
+}
+
+type fulcioRequest struct {
+	Credentials struct {
+		OidcIdentityToken string `json:"oidcIdentityToken"`
+	} `json:"credentials"`
+	PublicKeyRequest struct {
+		PublicKey struct {
+			Algorithm string `json:"algorithm"`
+			Content   string `json:"content"`
+		} `json:"publicKey"`
+		ProofOfPossession []byte `json:"proofOfPossession"`
+	} `json:"publicKeyRequest"`
+}
+
+type fulcioChain struct {
+	Chain struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chain"`
+}
+
+type fulcioResponse struct {
+	SignedCertificateEmbeddedSct *fulcioChain `json:"signedCertificateEmbeddedSct"`
+	SignedCertificateDetachedSct *fulcioChain `json:"signedCertificateDetachedSct"`
+}
+
+type rekorEntry struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+	} `json:"verification"`
+}
+
+// Bundle is the offline verifiable proof of the Rekor transparency log entry as cosign stores it
+type Bundle struct {
+	SignedEntryTimestamp string `json:"SignedEntryTimestamp"`
+	Payload              struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogIndex       int64  `json:"logIndex"`
+		LogID          string `json:"logID"`
+	} `json:"Payload"`
+}
+
+// NewKeylessSigner requests a Fulcio signing certificate for an ephemeral key and the identity of the
+// OIDC token. Without token the identity token is taken from SIGSTORE_ID_TOKEN or GitHub Actions.
+func NewKeylessSigner(token string) (*KeylessSigner, error) {
+	if token == "" {
+		var err error
+		if token, err = identityToken(); err != nil {
+			return nil, err
+		}
+	}
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate ephemeral key")
+	}
+	signer := NewSigner(key)
+	publicKey, err := signer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	proof, err := signer.Sign([]byte(subject))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create proof of possession")
+	}
+
+	var req fulcioRequest
+	req.Credentials.OidcIdentityToken = token
+	req.PublicKeyRequest.PublicKey.Algorithm = "ECDSA"
+	req.PublicKeyRequest.PublicKey.Content = string(publicKey)
+	req.PublicKeyRequest.ProofOfPossession = proof
+
+	var resp fulcioResponse
+	if err := postJson(sigstoreUrl("SIGSTORE_FULCIO_URL", defaultFulcioUrl)+"/api/v2/signingCert", req, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to request signing certificate from Fulcio")
+	}
+	chain := resp.SignedCertificateEmbeddedSct
+	if chain == nil {
+		chain = resp.SignedCertificateDetachedSct
+	}
+	if chain == nil || len(chain.Chain.Certificates) == 0 {
+		return nil, errors.New("Fulcio returned no signing certificate")
+	}
+	certs := chain.Chain.Certificates
+	return &KeylessSigner{
+		Signer:      signer,
+		Certificate: []byte(certs[0]),
+		Chain:       []byte(strings.Join(certs[1:], "")),
+	}, nil
+}
+
+// Upload records the signed envelope in the Rekor transparency log and returns the bundle
+func (s *KeylessSigner) Upload(envelope []byte) (*Bundle, error) {
+	entry := map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "intoto",
+		"spec": map[string]interface{}{
+			"content": map[string]interface{}{
+				"envelope": string(envelope),
+			},
+			"publicKey": base64.StdEncoding.EncodeToString(s.Certificate),
+		},
+	}
+	var resp map[string]rekorEntry
+	if err := postJson(sigstoreUrl("SIGSTORE_REKOR_URL", defaultRekorUrl)+"/api/v1/log/entries", entry, &resp); err != nil {
+		return nil, errors.Wrap(err, "failed to upload attestation to Rekor")
+	}
+	for _, e := range resp {
+		var bundle Bundle
+		bundle.SignedEntryTimestamp = e.Verification.SignedEntryTimestamp
+		bundle.Payload.Body = e.Body
+		bundle.Payload.IntegratedTime = e.IntegratedTime
+		bundle.Payload.LogIndex = e.LogIndex
+		bundle.Payload.LogID = e.LogID
+		return &bundle, nil
+	}
+	return nil, errors.New("Rekor returned no log entry")
+}
+
+// identityToken returns the OIDC token from the environment or requests one from GitHub Actions
+func identityToken() (string, error) {
+	if token, ok := os.LookupEnv("SIGSTORE_ID_TOKEN"); ok {
+		return token, nil
+	}
+	url, ok := os.LookupEnv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	if !ok {
+		return "", errors.New("no identity token available, provide one with --identity-token or SIGSTORE_ID_TOKEN")
+	}
+	req, err := http.NewRequest(http.MethodGet, url+"&audience=sigstore", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "bearer "+os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))
+	resp, err := internal.HttpClient().Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to request GitHub Actions identity token")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("failed to request GitHub Actions identity token: %s", resp.Status)
+	}
+	var token struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", errors.Wrap(err, "failed to parse GitHub Actions identity token")
+	}
+	return token.Value, nil
+}
+
+// tokenSubject returns the identity Fulcio expects the proof of possession to be signed for
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("identity token is not a JWT")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode identity token")
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return "", errors.Wrap(err, "failed to parse identity token")
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Subject == "" {
+		return "", errors.New("identity token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+func sigstoreUrl(env string, def string) string {
+	if url, ok := os.LookupEnv(env); ok {
+		return strings.TrimSuffix(url, "/")
+	}
+	return def
+}
+
+func postJson(url string, body interface{}, result interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := internal.HttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/internal"
+)
+
+// jwt returns an unsigned identity token with the given claims
+func jwt(claims string) string {
+	return "e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2ln"
+}
+
+// trustServer makes the TLS certificate of server trusted like --registry-ca does
+func trustServer(t *testing.T, server *httptest.Server) {
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := internal.SetTLSConfig(ca, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		internal.SetTLSConfig("", nil) //nolint:errcheck
+	})
+}
+
+func TestKeylessSigner(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca, caPem := certificate(t, caKey, nil, nil, "")
+	token := jwt(`{"sub": "1234", "email": "jane@example.com"}`)
+
+	var entry map[string]interface{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/signingCert":
+			var req fulcioRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Credentials.OidcIdentityToken != token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			block, _ := pem.Decode([]byte(req.PublicKeyRequest.PublicKey.Content))
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil || !verifySignature(key, []byte("jane@example.com"), req.PublicKeyRequest.ProofOfPossession) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			_, certPem := certificate(t, leafKey, ca, caKey, "jane@example.com")
+			var resp fulcioResponse
+			resp.SignedCertificateEmbeddedSct = &fulcioChain{}
+			resp.SignedCertificateEmbeddedSct.Chain.Certificates = []string{string(certPem), string(caPem)}
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(resp)
+		case "/api/v1/log/entries":
+			_ = json.NewDecoder(r.Body).Decode(&entry)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"24296fb2": {"body": "Ym9keQ==", "integratedTime": 1700000000, "logID": "c0d2", "logIndex": 42, "verification": {"signedEntryTimestamp": "c2V0"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("SIGSTORE_FULCIO_URL", server.URL)
+	t.Setenv("SIGSTORE_REKOR_URL", server.URL+"/")
+
+	if _, err := NewKeylessSigner(token); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected untrusted Fulcio to fail, got %v", err)
+	}
+	trustServer(t, server)
+
+	signer, err := NewKeylessSigner(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(signer.Certificate), "BEGIN CERTIFICATE") || string(signer.Chain) != string(caPem) {
+		t.Errorf("expected signing certificate and chain, got %s %s", signer.Certificate, signer.Chain)
+	}
+
+	bundle, err := signer.Upload([]byte(`{"payloadType": "application/vnd.in-toto+json"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.SignedEntryTimestamp != "c2V0" || bundle.Payload.LogIndex != 42 || bundle.Payload.IntegratedTime != 1700000000 || bundle.Payload.LogID != "c0d2" {
+		t.Errorf("unexpected bundle %+v", bundle)
+	}
+	spec, _ := entry["spec"].(map[string]interface{})
+	if entry["kind"] != "intoto" || spec["publicKey"] != base64.StdEncoding.EncodeToString(signer.Certificate) {
+		t.Errorf("unexpected Rekor entry %v", entry)
+	}
+}
+
+func TestIdentityToken(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer request-token" || r.URL.Query().Get("audience") != "sigstore" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value": "actions-token"}`))
+	}))
+	defer server.Close()
+	trustServer(t, server)
+
+	tests := []struct {
+		env   map[string]string
+		token string
+		err   bool
+	}{
+		{env: map[string]string{"SIGSTORE_ID_TOKEN": "env-token"}, token: "env-token"},
+		{env: map[string]string{"ACTIONS_ID_TOKEN_REQUEST_URL": server.URL + "/token?api-version=2.0", "ACTIONS_ID_TOKEN_REQUEST_TOKEN": "request-token"}, token: "actions-token"},
+		{env: map[string]string{"ACTIONS_ID_TOKEN_REQUEST_URL": server.URL + "/token?api-version=2.0", "ACTIONS_ID_TOKEN_REQUEST_TOKEN": "wrong"}, err: true},
+		{env: map[string]string{}, err: true},
+	}
+	for _, test := range tests {
+		for _, k := range []string{"SIGSTORE_ID_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_TOKEN"} {
+			t.Setenv(k, "")
+			os.Unsetenv(k)
+		}
+		for k, v := range test.env {
+			t.Setenv(k, v)
+		}
+		token, err := identityToken()
+		if test.err != (err != nil) || token != test.token {
+			t.Errorf("%v: expected token %q, got %q (%v)", test.env, test.token, token, err)
+		}
+	}
+}
+
+func TestTokenSubject(t *testing.T) {
+	tests := []struct {
+		token   string
+		subject string
+		err     bool
+	}{
+		{token: jwt(`{"sub": "1234", "email": "jane@example.com"}`), subject: "jane@example.com"},
+		{token: jwt(`{"sub": "repo:myorg/app:ref:refs/heads/main"}`), subject: "repo:myorg/app:ref:refs/heads/main"},
+		{token: jwt(`{}`), err: true},
+		{token: "not-a-jwt", err: true},
+	}
+	for _, test := range tests {
+		subject, err := tokenSubject(test.token)
+		if test.err != (err != nil) || subject != test.subject {
+			t.Errorf("%s: expected subject %q, got %q (%v)", test.token, test.subject, subject, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Signer signs DSSE payloads
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+	// PublicKey returns the PEM encoded public key to verify signatures with
+	PublicKey() ([]byte, error)
+}
+
+type keySigner struct {
+	key crypto.Signer
+}
+
+// encryptedKey is the scrypt and nacl/secretbox encrypted private key format of cosign
+type encryptedKey struct {
+	Kdf struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewSigner returns a signer for the given private key
+func NewSigner(key crypto.Signer) Signer {
+	return &keySigner{key: key}
+}
+
+// LoadKey reads a PEM encoded private key from path. Keys generated with cosign
+// generate-key-pair are decrypted with password.
+func LoadKey(path string, password []byte) (Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read key %s", path)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.Errorf("no PEM encoded key found in %s", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "ENCRYPTED COSIGN PRIVATE KEY", "ENCRYPTED SIGSTORE PRIVATE KEY":
+		der, err := decryptKey(block.Bytes, password)
+		if err != nil {
+			return nil, err
+		}
+		key, err = x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse decrypted key")
+		}
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported key type %s in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse key %s", path)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("unsupported key in %s", path)
+	}
+	return NewSigner(signer), nil
+}
+
+// LoadPublicKey reads a PEM encoded public key, like the cosign.pub of cosign generate-key-pair
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read key %s", path)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.Errorf("no PEM encoded public key found in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse key %s", path)
+	}
+	return key, nil
+}
+
+func decryptKey(b []byte, password []byte) ([]byte, error) {
+	var ek encryptedKey
+	if err := json.Unmarshal(b, &ek); err != nil {
+		return nil, errors.Wrap(err, "failed to parse encrypted key")
+	}
+	if ek.Kdf.Name != "scrypt" || ek.Cipher.Name != "nacl/secretbox" || len(ek.Cipher.Nonce) != 24 {
+		return nil, errors.Errorf("unsupported key encryption %s/%s", ek.Kdf.Name, ek.Cipher.Name)
+	}
+	secret, err := scrypt.Key(password, ek.Kdf.Salt, ek.Kdf.Params.N, ek.Kdf.Params.R, ek.Kdf.Params.P, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive key")
+	}
+	var nonce [24]byte
+	var k [32]byte
+	copy(nonce[:], ek.Cipher.Nonce)
+	copy(k[:], secret)
+	der, ok := secretbox.Open(nil, ek.Ciphertext, &nonce, &k)
+	if !ok {
+		return nil, errors.New("failed to decrypt key, invalid password")
+	}
+	return der, nil
+}
+
+func (s *keySigner) Sign(data []byte) ([]byte, error) {
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		return s.key.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(data)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (s *keySigner) PublicKey() ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
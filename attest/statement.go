@@ -0,0 +1,83 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StatementType      = "https://in-toto.io/Statement/v0.1"
+	InTotoPayloadType  = "application/vnd.in-toto+json"
+	PredicateSPDX      = "https://spdx.dev/Document"
+	PredicateCycloneDX = "https://cyclonedx.org/bom"
+	PredicateIndexSbom = "https://github.com/docker/index-cli-plugin/sbom/v1"
+)
+
+// predicateTypes maps the sbom output formats onto in-toto predicate types
+var predicateTypes = map[string]string{
+	"json":           PredicateIndexSbom,
+	"spdx-json":      PredicateSPDX,
+	"cyclonedx-json": PredicateCycloneDX,
+}
+
+type Statement struct {
+	Type          string          `json:"_type"`
+	PredicateType string          `json:"predicateType"`
+	Subject       []Subject       `json:"subject"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// PredicateType returns the in-toto predicate type of the sbom output format
+func PredicateType(format string) (string, error) {
+	if format == "" {
+		format = "json"
+	}
+	predicateType, ok := predicateTypes[format]
+	if !ok {
+		return "", errors.Errorf("format %s can't be used as attestation predicate", format)
+	}
+	return predicateType, nil
+}
+
+// NewStatement wraps the sbom document as predicate of an in-toto statement about the image
+func NewStatement(repository string, digest string, predicateType string, sbom []byte) (*Statement, error) {
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil, errors.Errorf("invalid image digest %s", digest)
+	}
+	if !json.Valid(sbom) {
+		return nil, errors.New("sbom is not a valid JSON document")
+	}
+	return &Statement{
+		Type:          StatementType,
+		PredicateType: predicateType,
+		Subject: []Subject{{
+			Name:   repository,
+			Digest: map[string]string{algorithm: hex},
+		}},
+		Predicate: sbom,
+	}, nil
+}
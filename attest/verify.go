@@ -0,0 +1,274 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/pkg/errors"
+)
+
+// Fulcio certificate extensions carrying the OIDC issuer of the token the certificate was
+// issued for, the deprecated one holds the raw issuer and the current one a DER encoded string
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Annotations cosign stores the keyless signing material of an attestation in
+const (
+	CertificateAnnotation = "dev.sigstore.cosign/certificate"
+	ChainAnnotation       = "dev.sigstore.cosign/chain"
+	BundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+// Verifier checks the signature of a DSSE envelope attached to an image with the given annotations
+// and returns the in-toto statement it carries
+type Verifier interface {
+	Verify(envelope []byte, annotations map[string]string) (*Statement, error)
+}
+
+type keyVerifier struct {
+	key crypto.PublicKey
+}
+
+// NewKeyVerifier returns a verifier accepting envelopes signed by key
+func NewKeyVerifier(key crypto.PublicKey) Verifier {
+	return keyVerifier{key: key}
+}
+
+func (v keyVerifier) Verify(envelope []byte, annotations map[string]string) (*Statement, error) {
+	return Verify(envelope, v.key)
+}
+
+// keylessVerifier accepts envelopes signed with a Fulcio certificate issued to identity by the
+// OIDC provider issuer and recorded in Rekor. The Fulcio roots and Rekor key are fetched once from the Sigstore
+// instance selected with SIGSTORE_FULCIO_URL and SIGSTORE_REKOR_URL.
+type keylessVerifier struct {
+	identity string
+	issuer   string
+
+	once          sync.Once
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	rekorKey      crypto.PublicKey
+	err           error
+}
+
+type fulcioTrustBundle struct {
+	Chains []struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chains"`
+}
+
+type rekorBody struct {
+	Spec struct {
+		Content struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"content"`
+		PublicKey string `json:"publicKey"`
+	} `json:"spec"`
+}
+
+// NewKeylessVerifier returns a verifier accepting envelopes signed keyless, see NewKeylessSigner,
+// by identity, the email address or URI of the OIDC token subject, with a token of issuer
+func NewKeylessVerifier(identity string, issuer string) Verifier {
+	return &keylessVerifier{identity: identity, issuer: issuer}
+}
+
+func (v *keylessVerifier) Verify(envelope []byte, annotations map[string]string) (*Statement, error) {
+	v.once.Do(v.loadTrustRoot)
+	if v.err != nil {
+		return nil, v.err
+	}
+	cert, err := parseCertificate([]byte(annotations[CertificateAnnotation]))
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	if err := json.Unmarshal([]byte(annotations[BundleAnnotation]), &bundle); err != nil {
+		return nil, errors.Wrap(err, "failed to parse Rekor bundle")
+	}
+	if err := v.verifyBundle(bundle, envelope, []byte(annotations[CertificateAnnotation])); err != nil {
+		return nil, err
+	}
+
+	intermediates := v.intermediates.Clone()
+	intermediates.AppendCertsFromPEM([]byte(annotations[ChainAnnotation]))
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Unix(bundle.Payload.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to verify signing certificate")
+	}
+	if !certificateIssuedTo(cert, v.identity) {
+		return nil, errors.Errorf("signing certificate isn't issued to %s", v.identity)
+	}
+	if issuer := certificateIssuer(cert); issuer != v.issuer {
+		return nil, errors.Errorf("signing certificate isn't issued by %s but %q", v.issuer, issuer)
+	}
+	return Verify(envelope, cert.PublicKey)
+}
+
+// verifyBundle checks the signed entry timestamp of the Rekor log entry and that the entry
+// records envelope signed with certificate
+func (v *keylessVerifier) verifyBundle(bundle Bundle, envelope []byte, certificate []byte) error {
+	set, err := base64.StdEncoding.DecodeString(bundle.SignedEntryTimestamp)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode signed entry timestamp")
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"body":           bundle.Payload.Body,
+		"integratedTime": bundle.Payload.IntegratedTime,
+		"logIndex":       bundle.Payload.LogIndex,
+		"logID":          bundle.Payload.LogID,
+	})
+	if err != nil {
+		return err
+	}
+	if !verifySignature(v.rekorKey, payload, set) {
+		return errors.New("invalid signed entry timestamp of Rekor bundle")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode Rekor entry")
+	}
+	var body rekorBody
+	if err := json.Unmarshal(b, &body); err != nil {
+		return errors.Wrap(err, "failed to parse Rekor entry")
+	}
+	digest := sha256.Sum256(envelope)
+	if body.Spec.PublicKey != base64.StdEncoding.EncodeToString(certificate) || body.Spec.Content.Hash.Value != hex.EncodeToString(digest[:]) {
+		return errors.New("Rekor entry doesn't match the attestation")
+	}
+	return nil
+}
+
+// loadTrustRoot fetches the Fulcio certificate chains and the Rekor public key
+func (v *keylessVerifier) loadTrustRoot() {
+	var trustBundle fulcioTrustBundle
+	if err := getJson(sigstoreUrl("SIGSTORE_FULCIO_URL", defaultFulcioUrl)+"/api/v2/trustBundle", &trustBundle); err != nil {
+		v.err = errors.Wrap(err, "failed to get Fulcio trust bundle")
+		return
+	}
+	v.roots, v.intermediates = x509.NewCertPool(), x509.NewCertPool()
+	for _, chain := range trustBundle.Chains {
+		for i, c := range chain.Certificates {
+			cert, err := parseCertificate([]byte(c))
+			if err != nil {
+				v.err = err
+				return
+			}
+			if i == len(chain.Certificates)-1 {
+				v.roots.AddCert(cert)
+			} else {
+				v.intermediates.AddCert(cert)
+			}
+		}
+	}
+
+	b, err := get(sigstoreUrl("SIGSTORE_REKOR_URL", defaultRekorUrl) + "/api/v1/log/publicKey")
+	if err != nil {
+		v.err = errors.Wrap(err, "failed to get Rekor public key")
+		return
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		v.err = errors.New("no PEM encoded Rekor public key found")
+		return
+	}
+	if v.rekorKey, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		v.err = errors.Wrap(err, "failed to parse Rekor public key")
+	}
+}
+
+func parseCertificate(b []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded signing certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	return cert, errors.Wrap(err, "failed to parse signing certificate")
+}
+
+func certificateIssuedTo(cert *x509.Certificate, identity string) bool {
+	for _, e := range cert.EmailAddresses {
+		if e == identity {
+			return true
+		}
+	}
+	for _, u := range cert.URIs {
+		if u.String() == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// certificateIssuer returns the OIDC issuer Fulcio recorded in cert
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, e := range cert.Extensions {
+		switch {
+		case e.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(e.Value, &issuer); err == nil {
+				return issuer
+			}
+		case e.Id.Equal(oidIssuerV1):
+			return string(e.Value)
+		}
+	}
+	return ""
+}
+
+func get(url string) ([]byte, error) {
+	resp, err := internal.HttpClient().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func getJson(url string, result interface{}) error {
+	b, err := get(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, result)
+}
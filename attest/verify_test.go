@@ -0,0 +1,167 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// certificate issues a certificate for key signed by parent, or self-signed without parent
+func certificate(t *testing.T, key *ecdsa.PrivateKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, email string, extensions ...pkix.Extension) (*x509.Certificate, []byte) {
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(time.Now().UnixNano()),
+		Subject:         pkix.Name{CommonName: "sigstore"},
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		ExtraExtensions: extensions,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	} else {
+		template.EmailAddresses = []string{email}
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// keylessAttestation signs statement with a certificate issued by ca for a token of issuer and
+// records it in a bundle signed by rekorKey
+func keylessAttestation(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, rekorKey *ecdsa.PrivateKey, email string, issuer string, digest string) ([]byte, map[string]string) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	value, _ := asn1.Marshal(issuer)
+	_, certPem := certificate(t, key, ca, caKey, email, pkix.Extension{Id: oidIssuerV2, Value: value})
+	statement, _ := NewStatement("docker.io/library/alpine", digest, PredicateIndexSbom, []byte(`{}`))
+	envelope, _ := Sign(statement, NewSigner(key))
+	raw, _ := json.Marshal(envelope)
+
+	var body rekorBody
+	hash := sha256.Sum256(raw)
+	body.Spec.Content.Hash.Algorithm = "sha256"
+	body.Spec.Content.Hash.Value = hex.EncodeToString(hash[:])
+	body.Spec.PublicKey = base64.StdEncoding.EncodeToString(certPem)
+	b, _ := json.Marshal(body)
+
+	var bundle Bundle
+	bundle.Payload.Body = base64.StdEncoding.EncodeToString(b)
+	bundle.Payload.IntegratedTime = time.Now().Unix()
+	bundle.Payload.LogIndex = 1
+	bundle.Payload.LogID = "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d"
+	payload, _ := json.Marshal(map[string]interface{}{
+		"body":           bundle.Payload.Body,
+		"integratedTime": bundle.Payload.IntegratedTime,
+		"logIndex":       bundle.Payload.LogIndex,
+		"logID":          bundle.Payload.LogID,
+	})
+	set, _ := NewSigner(rekorKey).Sign(payload)
+	bundle.SignedEntryTimestamp = base64.StdEncoding.EncodeToString(set)
+	js, _ := json.Marshal(bundle)
+	return raw, map[string]string{CertificateAnnotation: string(certPem), BundleAnnotation: string(js)}
+}
+
+func TestKeylessVerifier(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca, caPem := certificate(t, caKey, nil, nil, "")
+	otherCaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherCa, _ := certificate(t, otherCaKey, nil, nil, "")
+	rekorKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherRekorKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rekorPem, _ := NewSigner(rekorKey).PublicKey()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/trustBundle":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"chains": []map[string]interface{}{{"certificates": []string{string(caPem)}}},
+			})
+		case "/api/v1/log/publicKey":
+			_, _ = w.Write(rekorPem)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("SIGSTORE_FULCIO_URL", server.URL)
+	t.Setenv("SIGSTORE_REKOR_URL", server.URL)
+
+	envelope, annotations := keylessAttestation(t, ca, caKey, rekorKey, "dev@example.com", "https://accounts.example.com", "sha256:1234")
+	otherEnvelope, _ := keylessAttestation(t, ca, caKey, rekorKey, "dev@example.com", "https://accounts.example.com", "sha256:5678")
+	_, untrustedCa := keylessAttestation(t, otherCa, otherCaKey, rekorKey, "dev@example.com", "https://accounts.example.com", "sha256:1234")
+	_, untrustedRekor := keylessAttestation(t, ca, caKey, otherRekorKey, "dev@example.com", "https://accounts.example.com", "sha256:1234")
+	otherIssuerEnvelope, otherIssuer := keylessAttestation(t, ca, caKey, rekorKey, "dev@example.com", "https://token.example.com", "sha256:1234")
+
+	tests := []struct {
+		name        string
+		identity    string
+		issuer      string
+		envelope    []byte
+		annotations map[string]string
+		valid       bool
+	}{
+		{name: "valid", identity: "dev@example.com", issuer: "https://accounts.example.com", envelope: envelope, annotations: annotations, valid: true},
+		{name: "other identity", identity: "ops@example.com", issuer: "https://accounts.example.com", envelope: envelope, annotations: annotations},
+		{name: "other envelope", identity: "dev@example.com", issuer: "https://accounts.example.com", envelope: otherEnvelope, annotations: annotations},
+		{name: "untrusted certificate", identity: "dev@example.com", issuer: "https://accounts.example.com", envelope: envelope, annotations: untrustedCa},
+		{name: "untrusted bundle", identity: "dev@example.com", issuer: "https://accounts.example.com", envelope: envelope, annotations: untrustedRekor},
+		{name: "other issuer", identity: "dev@example.com", issuer: "https://accounts.example.com", envelope: otherIssuerEnvelope, annotations: otherIssuer},
+		{name: "unsigned", identity: "dev@example.com", issuer: "https://accounts.example.com", envelope: envelope, annotations: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement, err := NewKeylessVerifier(test.identity, test.issuer).Verify(test.envelope, test.annotations)
+			if test.valid && (err != nil || statement.Subject[0].Digest["sha256"] != "1234") {
+				t.Errorf("expected attestation to verify: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("expected attestation to be rejected")
+			}
+		})
+	}
+}
+
+func TestKeyVerifier(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	statement, _ := NewStatement("docker.io/library/alpine", "sha256:1234", PredicateIndexSbom, []byte(`{}`))
+	envelope, _ := Sign(statement, NewSigner(key))
+	raw, _ := json.Marshal(envelope)
+	if _, err := NewKeyVerifier(&key.PublicKey).Verify(raw, nil); err != nil {
+		t.Errorf("expected envelope to verify: %v", err)
+	}
+}
@@ -18,20 +18,40 @@ package commands
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/atomist-skills/go-skill"
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli-plugins/plugin"
 	"github.com/docker/cli/cli/command"
+	"github.com/docker/go-units"
+	"github.com/docker/index-cli-plugin/attest"
+	"github.com/docker/index-cli-plugin/compose"
+	"github.com/docker/index-cli-plugin/explore"
+	"github.com/docker/index-cli-plugin/history"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/k8s"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/metrics"
+	"github.com/docker/index-cli-plugin/progress"
 	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/registry"
 	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/server"
+	"github.com/docker/index-cli-plugin/storage"
+	"github.com/docker/index-cli-plugin/tracing"
 	"github.com/docker/index-cli-plugin/types"
+	"github.com/docker/index-cli-plugin/watch"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
 	"github.com/moby/term"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -43,11 +63,133 @@ func NewRootCmd(name string, isPlugin bool, dockerCli command.Cli) *cobra.Comman
 		Long:  `Index Docker images, create SBOMs and detect CVEs`,
 		Use:   name,
 	}
-	if isPlugin {
-		cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
-			return plugin.PersistentPreRunE(cmd, args)
+	var logFormat, cacheDir, pushMetrics string
+	var queryChunkSize int
+	var queryCacheTTL time.Duration
+	var noQueryCache bool
+	var rateLimitWait time.Duration
+	var registryCA string
+	var insecureRegistries []string
+	var daemonTimeout time.Duration
+	var keepImages bool
+	var purlQualifiers []string
+	var noCpes bool
+	var redactEnv bool
+	var enrich bool
+	var outputTemplate string
+	var csvColumns []string
+	var historyDb string
+	var noProgress bool
+	var configFile string
+	var commandTimeout, pullTimeout, queryTimeout time.Duration
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		defaults, err := readDefaults(configFile)
+		if err != nil {
+			return err
 		}
-	} else {
+		if err := applyDefaults(cmd.Flags(), defaults); err != nil {
+			return err
+		}
+		if err := log.SetFormat(logFormat); err != nil {
+			return err
+		}
+		if cacheDir != "" {
+			internal.SetCachePath(cacheDir)
+		}
+		querySettings = []query.Option{query.WithChunkSize(queryChunkSize), query.WithTimeout(queryTimeout)}
+		// the responses of the atomist backend, which has no data version, are only cached
+		// with an explicit TTL
+		if noQueryCache {
+			querySettings = append(querySettings, query.WithCacheTTL(0))
+		} else if cmd.Flags().Changed("query-cache-ttl") {
+			querySettings = append(querySettings, query.WithCacheTTL(queryCacheTTL))
+		}
+		if enrich {
+			querySettings = append(querySettings, query.WithEnrichment())
+		}
+		registry.SetRateLimitWait(rateLimitWait)
+		registry.SetDaemonTimeout(daemonTimeout)
+		registry.SetPullTimeout(pullTimeout)
+		qualifiers, err := types.ParsePurlQualifiers(purlQualifiers)
+		if err != nil {
+			return err
+		}
+		indexerSettings = []sbom.Option{sbom.WithCpes(!noCpes)}
+		if keepImages {
+			indexerSettings = append(indexerSettings, sbom.WithKeepImages())
+		}
+		if len(qualifiers) > 0 {
+			indexerSettings = append(indexerSettings, sbom.WithPurlQualifiers(qualifiers...))
+		}
+		if redactEnv {
+			indexerSettings = append(indexerSettings, sbom.WithRedactEnv())
+		}
+		tmpl, err := sbom.ParseTemplate(outputTemplate)
+		if err != nil {
+			return err
+		}
+		if err := sbom.ValidateCsvColumns(csvColumns); err != nil {
+			return err
+		}
+		writeSettings = []sbom.WriteOption{sbom.WithTemplate(tmpl), sbom.WithCsvColumns(csvColumns...)}
+		if err := internal.SetTLSConfig(registryCA, insecureRegistries); err != nil {
+			return err
+		}
+		if isPlugin {
+			if err := plugin.PersistentPreRunE(cmd, args); err != nil {
+				return err
+			}
+		}
+		// make registry pulls and pushes use the credentials of the Docker config the CLI
+		// was started with, e.g. docker --config
+		if f := dockerCli.ConfigFile(); f != nil && f.Filename != "" && os.Getenv("DOCKER_CONFIG") == "" {
+			_ = os.Setenv("DOCKER_CONFIG", filepath.Dir(f.Filename))
+		}
+		showProgress = !noProgress && logFormat == log.FormatText && term.IsTerminal(os.Stderr.Fd())
+		if commandTimeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), commandTimeout)
+			cmd.SetContext(ctx)
+			cancelTimeout = cancel
+		}
+		shutdown, err := tracing.Setup(cmd.Context())
+		if err != nil {
+			return err
+		}
+		ctx, span := tracing.Start(cmd.Context(), cmd.CommandPath())
+		cmd.SetContext(ctx)
+		stopTracing = func() {
+			span.End()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				log.Warnf("failed to export traces: %s", err)
+			}
+		}
+		return nil
+	}
+	cmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file with flag defaults (default ~/.docker/index/config.yaml)")
+	cmd.PersistentFlags().StringVar(&logFormat, "log-format", log.FormatText, "Log format (text or json)")
+	cmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Directory to cache images and SBOMs in")
+	cmd.PersistentFlags().BoolVar(&keepImages, "keep", false, "Keep the layers of indexed images in the cache instead of removing them")
+	cmd.PersistentFlags().StringVar(&pushMetrics, "push-metrics", "", "URL of Prometheus pushgateway to send scan metrics to")
+	cmd.PersistentFlags().IntVar(&queryChunkSize, "query-chunk-size", query.DefaultChunkSize, "Number of packages sent per vulnerability query")
+	cmd.PersistentFlags().DurationVar(&queryCacheTTL, "query-cache-ttl", query.DefaultCacheTTL, "How long to reuse cached vulnerability query responses; atomist responses are only cached if set")
+	cmd.PersistentFlags().BoolVar(&noQueryCache, "no-query-cache", false, "Always query vulnerabilities instead of using cached responses")
+	cmd.PersistentFlags().DurationVar(&rateLimitWait, "rate-limit-wait", 0, "How long to wait in total for registry rate limits, e.g. of Docker Hub, to reset before failing")
+	cmd.PersistentFlags().StringVar(&registryCA, "registry-ca", "", "PEM file of CA certificates to trust for registry and vulnerability query connections")
+	cmd.PersistentFlags().DurationVar(&daemonTimeout, "daemon-timeout", registry.DefaultDaemonTimeout, "How long an image export from the Docker daemon may stall before it is aborted, 0 to wait forever")
+	cmd.PersistentFlags().StringSliceVar(&insecureRegistries, "insecure-registry", nil, "Registry to connect to without verifying its certificate or over plain HTTP, e.g. registry.local:5000")
+	cmd.PersistentFlags().StringSliceVar(&purlQualifiers, "purl-qualifiers", nil, "Additional qualifiers to include in the purls of OS packages (arch, distro, epoch)")
+	cmd.PersistentFlags().BoolVar(&noCpes, "no-cpes", false, "Don't add guessed CPE names to packages")
+	cmd.PersistentFlags().BoolVar(&redactEnv, "redact-env", false, "Mask the values of environment variables and build args in the image config embedded in SBOMs")
+	cmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Go template to render SBOMs with in the template format, or @FILE to read it from")
+	cmd.PersistentFlags().StringSliceVar(&csvColumns, "csv-columns", nil, "Columns of the csv and cves-csv formats, e.g. name,version,licenses")
+	cmd.PersistentFlags().DurationVar(&pullTimeout, "pull-timeout", 0, "Fail if pulling an image takes longer, e.g. 5m (0 waits forever)")
+	cmd.PersistentFlags().DurationVar(&queryTimeout, "query-timeout", 0, "Fail if the vulnerability query of an image takes longer, e.g. 2m (0 waits forever)")
+	cmd.PersistentFlags().BoolVar(&enrich, "enrich", false, "Add EPSS scores and the CISA known exploited flag to detected CVEs")
+	cmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "Don't show a progress bar while indexing an image")
+	cmd.PersistentFlags().StringVar(&historyDb, "history-db", "", "SQLite file or postgres:// URL of the database to record scans in")
+	if !isPlugin {
 		cmd.SilenceUsage = true
 		cmd.SilenceErrors = true
 		cmd.TraverseChildren = true
@@ -58,8 +200,18 @@ func NewRootCmd(name string, isPlugin bool, dockerCli command.Cli) *cobra.Comman
 	config := dockerCli.ConfigFile()
 
 	var (
-		output, ociDir, image, workspace string
-		apiKeyStdin, includeCves         bool
+		output, workspace, format string
+		diffFormat                string
+		baseImages                []string
+		baseImageLabel            bool
+		failOn                    string
+		ecosystems                []string
+		apiKeyStdin, includeCves  bool
+		sbomFile                  string
+		imgOpts                   imageOptions
+		backendOpts               backendOptions
+		batchOpts                 batchOptions
+		sbomOpts                  sbomOptions
 	)
 
 	logoutCommand := &cobra.Command{
@@ -85,7 +237,7 @@ func NewRootCmd(name string, isPlugin bool, dockerCli command.Cli) *cobra.Comman
 				return err
 			}
 			if valid, err := query.CheckAuth(workspace, apiKey); err == nil && valid {
-				skill.Log.Info("Login successful")
+				log.Infof("Login successful")
 				config.SetPluginConfig("index", "workspace", workspace)
 				config.SetPluginConfig("index", "api-key", apiKey)
 				return config.Save()
@@ -98,48 +250,83 @@ func NewRootCmd(name string, isPlugin bool, dockerCli command.Cli) *cobra.Comman
 	loginCommandFlags.BoolVar(&apiKeyStdin, "api-key-stdin", false, "Atomist API key")
 
 	sbomCommand := &cobra.Command{
-		Use:   "sbom [OPTIONS]",
+		Use:   "sbom [OPTIONS] [IMAGE]",
 		Short: "Write SBOM file",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var err error
-			var sb *types.Sbom
-
-			if ociDir == "" {
-				sb, _, err = sbom.IndexImage(image, dockerCli.Client())
-			} else {
-				sb, _, err = sbom.IndexPath(ociDir, image)
-			}
-			if err != nil {
-				return err
-			}
-			if includeCves {
-				workspace, _ := config.PluginConfig("index", "workspace")
-				apiKey, _ := config.PluginConfig("index", "api-key")
-				cves, err := query.QueryCves(sb, "", workspace, apiKey)
-				if err != nil {
-					return err
-				}
-				sb.Vulnerabilities = *cves
-			}
-
-			js, err := json.MarshalIndent(sb, "", "  ")
-			if err != nil {
-				return err
-			}
-			if output != "" {
-				_ = os.WriteFile(output, js, 0644)
-				skill.Log.Infof("SBOM written to %s", output)
-			} else {
-				os.Stdout.WriteString(string(js) + "\n")
-			}
-			return nil
+			sbomOpts.historyDb = historyDb
+			return runSbom(cmd, args, &sbomOpts, dockerCli)
 		},
 	}
 	sbomCommandFlags := sbomCommand.Flags()
-	sbomCommandFlags.StringVarP(&output, "output", "o", "", "Location path to write SBOM to")
-	sbomCommandFlags.StringVarP(&image, "image", "i", "", "Image reference to index")
-	sbomCommandFlags.StringVarP(&ociDir, "oci-dir", "d", "", "Path to image in OCI format")
-	sbomCommandFlags.BoolVarP(&includeCves, "include-cves", "c", false, "Include package CVEs")
+	sbomCommandFlags.DurationVar(&commandTimeout, "timeout", 0, "Fail if the command takes longer, e.g. 15m (0 waits forever)")
+	sbomCommandFlags.StringVarP(&sbomOpts.output, "output", "o", "", "Location path to write SBOM to, or s3://, gs:// or az:// bucket to upload the SBOM and report to")
+	sbomCommandFlags.StringVar(&sbomOpts.storage.Encryption, "storage-encryption", "", "Server-side encryption of objects uploaded to S3 (AES256, aws:kms)")
+	sbomCommandFlags.StringVar(&sbomOpts.storage.KmsKey, "storage-kms-key", "", "KMS key of uploaded objects: the aws:kms key id, the Cloud KMS key name or the Azure encryption scope")
+	sbomCommandFlags.StringVarP(&sbomOpts.image.image, "image", "i", "", "Image reference to index")
+	sbomCommandFlags.StringVarP(&sbomOpts.image.ociDir, "oci-dir", "d", "", "Path to image in OCI format")
+	sbomCommandFlags.BoolVar(&sbomOpts.image.remote, "remote", false, "Pull image from registry without using the Docker daemon")
+	sbomCommandFlags.BoolVar(&sbomOpts.image.stream, "stream", false, "Analyze layers while pulling them from the registry instead of caching the image first")
+	sbomOpts.image.addCatalogerFlags(sbomCommandFlags)
+	sbomCommandFlags.BoolVar(&sbomOpts.image.requireAllCatalogers, "require-all-catalogers", false, "Fail if any cataloger fails instead of writing a partial SBOM")
+	sbomCommandFlags.BoolVar(&sbomOpts.image.includeRemoved, "include-removed", false, "List packages deleted or replaced by a later layer in the removed section of the SBOM")
+	sbomCommandFlags.BoolVar(&sbomOpts.image.files, "files", false, "Record every regular file of the image with its size, sha256 digest, owning package and layer")
+	sbomCommandFlags.BoolVar(&sbomOpts.image.packageFiles, "package-files", false, "List the files owned by apk, dpkg and rpm packages")
+	sbomCommandFlags.BoolVar(&sbomOpts.image.waste, "waste", false, "Report the space wasted by files overwritten or deleted by later layers, duplicate files and package manager caches")
+	sbomCommandFlags.BoolVar(&sbomOpts.image.leftovers, "leftovers", false, "Report compilers, download tools, package manager caches and pip wheels left in the final stage")
+	sbomCommandFlags.StringVar(&sbomOpts.failIfWasteAbove, "fail-if-waste-above", "", "Exit with status code 1 if the image wastes more space than the given size, e.g. 200MB (implies --waste)")
+	sbomCommandFlags.StringArrayVar(&sbomOpts.image.annotations, "annotation", nil, "Annotation to add to the SBOM as key=value, e.g. the git commit or build URL")
+	sbomCommandFlags.StringVar(&sbomOpts.image.platform, "platform", "", "Platform of image to index (e.g. linux/arm64)")
+	sbomCommandFlags.StringVar(&sbomOpts.image.ociLayout, "oci-layout", "", "Path to OCI image layout directory (select image with --image)")
+	sbomCommandFlags.StringVar(&sbomOpts.image.input, "input", "", "Path to image tarball written by docker save or in oci-archive format")
+	sbomCommandFlags.StringSliceVar(&sbomOpts.baseImages, "base-image", nil, "Candidate base image references to match against the image layers")
+	sbomCommandFlags.BoolVar(&sbomOpts.baseImageLabel, "base-image-label", false, "Match the base image recorded in the org.opencontainers.image.base.name label")
+	sbomCommandFlags.BoolVar(&sbomOpts.scanSecrets, "scan-secrets", true, "Scan layer contents for secrets like credentials and private keys")
+	sbomCommandFlags.StringVar(&sbomOpts.secretRules, "secret-rules", "", "YAML file with additional secret scanning rules")
+	sbomCommandFlags.BoolVarP(&sbomOpts.includeCves, "include-cves", "c", false, "Include package CVEs")
+	sbomCommandFlags.StringVar(&sbomOpts.previousSbom, "previous", "", "SBOM of an earlier scan to report new and resolved CVEs against, instead of the latest scan in --history-db")
+	sbomCommandFlags.BoolVar(&sbomOpts.onlyNew, "only-new", false, "Only include CVEs that are new since the previous scan")
+	sbomCommandFlags.StringSliceVar(&sbomOpts.severity, "severity", nil, "Only include CVEs of given severities (critical, high, medium, low, unspecified)")
+	sbomCommandFlags.BoolVar(&sbomOpts.onlyFixed, "only-fixed", false, "Only include CVEs with a known fixed version")
+	sbomOpts.backend.addFlags(sbomCommandFlags)
+	sbomCommandFlags.Float64Var(&sbomOpts.minEpss, "min-epss", 0, "Only include CVEs with an EPSS score of at least the given probability (0-1)")
+	sbomCommandFlags.BoolVar(&sbomOpts.onlyKev, "only-kev", false, "Only include CVEs listed in the CISA known exploited vulnerabilities catalog")
+	sbomCommandFlags.StringVar(&sbomOpts.sortBy, "sort-by", "severity", "Order of CVEs in output (severity, epss, kev)")
+	sbomCommandFlags.StringSliceVar(&sbomOpts.vexFiles, "vex", nil, "OpenVEX or CSAF VEX documents with statements to apply to detected CVEs")
+	sbomCommandFlags.StringVar(&sbomOpts.ignoreFile, "ignore-file", sbom.DefaultIgnoreFile, "YAML file of CVEs to suppress with justification and expiry date")
+	sbomCommandFlags.StringVar(&sbomOpts.licensePolicy, "license-policy", "", "YAML file of licenses to deny or flag; exits with status code 1 on denied licenses")
+	sbomCommandFlags.StringVar(&sbomOpts.packagePolicy, "package-policy", "", "YAML file of banned packages and version ranges; exits with status code 1 on denied packages")
+	sbomCommandFlags.StringSliceVar(&sbomOpts.policies, "policy", nil, "Rego policy files or directories whose deny rules are evaluated against the SBOM and CVEs; exits with status code 1 on violations")
+	sbomCommandFlags.StringVar(&sbomOpts.failOn, "fail-on", "", "Exit with status code 1 if CVEs of given severity or higher are detected")
+	sbomCommandFlags.BoolVar(&sbomOpts.failOnEol, "fail-on-eol", false, "Exit with status code 1 if the distro of the image reached its end of life")
+	sbomCommandFlags.StringVar(&sbomOpts.groupBy, "group-by", "", "Print packages grouped by introducing layer (layer)")
+	sbomCommandFlags.BoolVar(&sbomOpts.allPlatforms, "all-platforms", false, "Index all platform images of a multi-platform image from the registry")
+	sbomCommandFlags.StringVar(&sbomOpts.sbomFile, "sbom", "", "Path to SBOM to read instead of indexing the image (json, spdx-json, cyclonedx-json, syft-json)")
+	sbomCommandFlags.BoolVar(&sbomOpts.push, "push", false, "Attach the SBOM to the image in the registry as OCI referrer artifact")
+	sbomCommandFlags.StringSliceVar(&sbomOpts.uploadTargets, "upload", nil, "Upload the SBOM to the given services (dependency-track)")
+	sbomCommandFlags.StringVar(&sbomOpts.slackWebhook, "slack-webhook", "", "Slack incoming webhook URL to post a summary of the scan to (or set SLACK_WEBHOOK_URL)")
+	sbomCommandFlags.StringVar(&sbomOpts.teamsWebhook, "teams-webhook", "", "Microsoft Teams incoming webhook URL to post a summary of the scan to (or set TEAMS_WEBHOOK_URL)")
+	sbomCommandFlags.StringSliceVar(&sbomOpts.notifyThresholds, "notify-threshold", nil, "Number of CVEs per severity that trigger a notification, e.g. critical=1,high=10 (default critical=1)")
+	sbomCommandFlags.StringVar(&sbomOpts.reportUrl, "report-url", "", "URL of the full report to link from notifications")
+	sbomCommandFlags.StringVar(&sbomOpts.eventsUrl, "events", "", "Kafka (kafka://broker/topic) or NATS (nats://server/subject) destination to send scan completed events to")
+	sbomCommandFlags.BoolVar(&sbomOpts.eventFindings, "event-findings", false, "Also send an event per detected CVE to --events")
+	sbomCommandFlags.StringVar(&sbomOpts.jira.Url, "jira-url", "", "URL of the Jira server to open issues for critical and known exploited CVEs in")
+	sbomCommandFlags.StringVar(&sbomOpts.jira.User, "jira-user", "", "Jira Cloud account of the API token, omit to use a Jira Server personal access token")
+	sbomCommandFlags.StringVar(&sbomOpts.jira.Token, "jira-token", "", "Jira API token or personal access token (or set JIRA_API_TOKEN)")
+	sbomCommandFlags.StringVar(&sbomOpts.jira.Project, "jira-project", "", "Key of the Jira project to open issues in")
+	sbomCommandFlags.StringVar(&sbomOpts.jira.IssueType, "jira-issue-type", "Bug", "Type of the opened Jira issues")
+	sbomCommandFlags.StringVar(&sbomOpts.dependencyTrack.Url, "dt-url", "", "URL of the Dependency-Track server to upload to")
+	sbomCommandFlags.StringVar(&sbomOpts.dependencyTrack.ApiKey, "dt-api-key", "", "Dependency-Track API key with BOM_UPLOAD and PROJECT_CREATION_UPLOAD permissions (or set DT_API_KEY)")
+	sbomCommandFlags.StringVar(&sbomOpts.dependencyTrack.Project, "dt-project", "", "Dependency-Track project to upload to, the image name if not set")
+	sbomCommandFlags.BoolVar(&sbomOpts.githubSubmit, "github-submit", false, "Submit the packages to the dependency graph of GITHUB_REPOSITORY with the Dependency Submission API using GITHUB_TOKEN")
+	sbomCommandFlags.BoolVar(&sbomOpts.attest, "attest", false, "Sign the SBOM as in-toto attestation and attach it to the image in the registry")
+	sbomCommandFlags.StringVar(&sbomOpts.key, "key", "", "Private key to sign the attestation with (cosign keys are decrypted with COSIGN_PASSWORD)")
+	sbomCommandFlags.BoolVar(&sbomOpts.keyless, "keyless", false, "Sign the attestation with a Fulcio certificate and record it in Rekor")
+	sbomCommandFlags.StringVar(&sbomOpts.identityToken, "identity-token", "", "OIDC identity token for keyless signing")
+	sbomCommandFlags.BoolVar(&sbomOpts.reuseAttestation, "reuse-attestation", false, "Load the SBOM from an existing attestation of the image instead of indexing it")
+	sbomCommandFlags.StringVar(&sbomOpts.verifyKey, "verify-key", "", "Public key to verify cosign attestations with before reusing them")
+	sbomCommandFlags.StringVar(&sbomOpts.verifyIdentity, "verify-identity", "", "Email or URI of the identity keyless attestations must be signed by to be reused")
+	sbomCommandFlags.StringVar(&sbomOpts.verifyIssuer, "verify-issuer", "", "OIDC issuer of the identity keyless attestations must be signed by, e.g. https://token.actions.githubusercontent.com")
+	sbomCommandFlags.StringVar(&sbomOpts.format, "format", sbom.FormatJSON, formatUsage)
 
 	uploadCommand := &cobra.Command{
 		Use:   "upload [OPTIONS]",
@@ -167,11 +354,7 @@ func NewRootCmd(name string, isPlugin bool, dockerCli command.Cli) *cobra.Comman
 
 			var sb *types.Sbom
 			var img *v1.Image
-			if ociDir == "" {
-				sb, img, err = sbom.IndexImage(image, dockerCli.Client())
-			} else {
-				sb, img, err = sbom.IndexPath(ociDir, image)
-			}
+			sb, img, err = indexImage(cmd.Context(), imgOpts, nil, dockerCli)
 			if err != nil {
 				return err
 			}
@@ -181,8 +364,13 @@ func NewRootCmd(name string, isPlugin bool, dockerCli command.Cli) *cobra.Comman
 		},
 	}
 	uploadCommandFlags := uploadCommand.Flags()
-	uploadCommandFlags.StringVar(&image, "image", "", "Image reference to index")
-	uploadCommandFlags.StringVar(&ociDir, "oci-dir", "", "Path to image in OCI format")
+	uploadCommandFlags.DurationVar(&commandTimeout, "timeout", 0, "Fail if the command takes longer, e.g. 15m (0 waits forever)")
+	uploadCommandFlags.StringVar(&imgOpts.image, "image", "", "Image reference to index")
+	uploadCommandFlags.StringVar(&imgOpts.ociDir, "oci-dir", "", "Path to image in OCI format")
+	uploadCommandFlags.BoolVar(&imgOpts.remote, "remote", false, "Pull image from registry without using the Docker daemon")
+	uploadCommandFlags.StringVar(&imgOpts.platform, "platform", "", "Platform of image to index (e.g. linux/arm64)")
+	uploadCommandFlags.StringVar(&imgOpts.ociLayout, "oci-layout", "", "Path to OCI image layout directory (select image with --image)")
+	uploadCommandFlags.StringVar(&imgOpts.input, "input", "", "Path to image tarball written by docker save or in oci-archive format")
 	uploadCommandFlags.StringVar(&workspace, "workspace", "", "Atomist workspace")
 	uploadCommandFlags.BoolVar(&apiKeyStdin, "api-key-stdin", false, "Atomist API key")
 
@@ -197,65 +385,1265 @@ func NewRootCmd(name string, isPlugin bool, dockerCli command.Cli) *cobra.Comman
 			var err error
 			var sb *types.Sbom
 
-			if ociDir == "" {
-				sb, _, err = sbom.IndexImage(image, dockerCli.Client())
+			if sbomFile != "" {
+				sboms, err := readSboms(sbomFile, imgOpts.platform)
+				if err != nil {
+					return err
+				}
+				if len(sboms) > 1 {
+					return errors.Errorf("%s contains SBOMs of %d platforms, select one with --platform", sbomFile, len(sboms))
+				}
+				sb = sboms[0]
 			} else {
-				sb, _, err = sbom.IndexPath(ociDir, image)
+				sb, _, err = indexImage(cmd.Context(), imgOpts, nil, dockerCli)
+				if err != nil {
+					return err
+				}
 			}
+			base := sbom.DetectBaseImage(sb, baseImages, baseImageLabel)
+			sbom.AnnotateEol(sb, time.Now())
+			warnEol(sb)
+			b, err := backendOpts.newBackend(config)
 			if err != nil {
 				return err
 			}
-			workspace, _ := config.PluginConfig("index", "workspace")
-			apiKey, _ := config.PluginConfig("index", "api-key")
-			cves, err := query.QueryCves(sb, cve, workspace, apiKey)
+			cves, err := queryCves(cmd.Context(), sb, cve, b)
 			if err != nil {
 				return err
 			}
 
-			if len(*cves) > 0 {
+			if cves != nil && len(*cves) > 0 {
 				for _, c := range *cves {
-					skill.Log.Warnf("Detected %s at", cve)
-					skill.Log.Warnf("")
+					log.Warnf("Detected %s at", cve)
+					if c.KnownExploited {
+						log.Warnf("  Listed in CISA known exploited vulnerabilities catalog")
+					}
+					if c.Epss != nil {
+						log.Warnf("  EPSS %.2f%% (percentile %.2f)", c.Epss.Score*100, c.Epss.Percentile)
+					}
+					log.Warnf("")
 					purl := c.Purl
 					for _, p := range sb.Artifacts {
 						if p.Purl == purl {
-							skill.Log.Warnf("  %s", p.Purl)
+							log.Warnf("  %s", p.Purl)
+							// SBOMs read with --sbom may lack locations and the image config
+							if len(p.Locations) == 0 || sb.Source.Image.Config == nil {
+								continue
+							}
 							loc := p.Locations[0]
 							for i, l := range sb.Source.Image.Config.RootFS.DiffIDs {
 								if l.String() == loc.DiffId {
 									h := sb.Source.Image.Config.History[i]
-									skill.Log.Warnf("    ")
-									skill.Log.Warnf("    Instruction: %s", h.CreatedBy)
-									skill.Log.Warnf("    Layer %d: %s", i, loc.Digest)
+									log.Warnf("    ")
+									log.Warnf("    Instruction: %s", h.CreatedBy)
+									log.Warnf("    Layer %d: %s", i, loc.Digest)
+									if base != nil && p.Layer != nil && p.Layer.BaseImage {
+										log.Warnf("    Inherited from base image %s", base.Name)
+									} else if base != nil {
+										log.Warnf("    Introduced by build on top of base image %s", base.Name)
+									}
 								}
 							}
 						}
 					}
 				}
-				os.Exit(1)
+				exit(1)
 			} else {
-				skill.Log.Infof("%s not detected", cve)
-				os.Exit(0)
+				log.Infof("%s not detected", cve)
+				exit(0)
 			}
 			return nil
 		},
 	}
 	cveCommandFlags := cveCommand.Flags()
-	cveCommandFlags.StringVarP(&image, "image", "i", "", "Image reference to index")
-	cveCommandFlags.StringVarP(&ociDir, "oci-dir", "d", "", "Path to image in OCI format")
+	cveCommandFlags.DurationVar(&commandTimeout, "timeout", 0, "Fail if the command takes longer, e.g. 15m (0 waits forever)")
+	cveCommandFlags.StringVarP(&imgOpts.image, "image", "i", "", "Image reference to index")
+	cveCommandFlags.StringVarP(&imgOpts.ociDir, "oci-dir", "d", "", "Path to image in OCI format")
+	cveCommandFlags.BoolVar(&imgOpts.remote, "remote", false, "Pull image from registry without using the Docker daemon")
+	cveCommandFlags.BoolVar(&imgOpts.stream, "stream", false, "Analyze layers while pulling them from the registry instead of caching the image first")
+	imgOpts.addCatalogerFlags(cveCommandFlags)
+	cveCommandFlags.BoolVar(&imgOpts.requireAllCatalogers, "require-all-catalogers", false, "Fail if any cataloger fails instead of checking a partial SBOM")
+	cveCommandFlags.StringVar(&imgOpts.platform, "platform", "", "Platform of image to index (e.g. linux/arm64)")
+	cveCommandFlags.StringVar(&imgOpts.ociLayout, "oci-layout", "", "Path to OCI image layout directory (select image with --image)")
+	cveCommandFlags.StringVar(&imgOpts.input, "input", "", "Path to image tarball written by docker save or in oci-archive format")
+	cveCommandFlags.StringVar(&sbomFile, "sbom", "", "Path to SBOM to check instead of indexing the image (json, spdx-json, cyclonedx-json, syft-json)")
+	cveCommandFlags.StringSliceVar(&baseImages, "base-image", nil, "Candidate base image references to match against the image layers")
+	cveCommandFlags.BoolVar(&baseImageLabel, "base-image-label", false, "Match the base image recorded in the org.opencontainers.image.base.name label")
+	backendOpts.addFlags(cveCommandFlags)
 
 	diffCommand := &cobra.Command{
-		Use:   "diff [OPTIONS]",
-		Short: "Diff images",
+		Use:   "diff [OPTIONS] IMAGE1 IMAGE2",
+		Short: "Compare packages and CVEs of two images",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf(`"docker index diff" requires exactly 2 arguments`)
+			}
+			if failOn != "" {
+				if _, err := sbom.ParseSeverity(failOn); err != nil {
+					return err
+				}
+			}
+			b, err := backendOpts.newBackend(config)
+			if err != nil {
+				return err
+			}
+
+			sboms := make([]*types.Sbom, 0, 2)
+			for _, image := range args {
+				sb, _, err := indexImage(cmd.Context(), imageOptions{remote: imgOpts.remote, platform: imgOpts.platform}, []string{image}, dockerCli)
+				if err != nil {
+					return errors.Wrapf(err, "failed to index image %s", image)
+				}
+				cves, err := queryCves(cmd.Context(), sb, "", b)
+				if err != nil {
+					return err
+				}
+				sb.Vulnerabilities = *cves
+				sbom.SortSbom(sb)
+				sboms = append(sboms, sb)
+			}
+
+			var buf bytes.Buffer
+			if err := sbom.WriteDiff(sboms[0], sboms[1], diffFormat, &buf); err != nil {
+				return err
+			}
+			if output != "" {
+				if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+					return errors.Wrapf(err, "failed to write %s", output)
+				}
+				log.Infof("Diff written to %s", output)
+			} else {
+				os.Stdout.Write(buf.Bytes())
+			}
+
+			if failOn != "" {
+				introduced, _ := sbom.CvesAboveThreshold(sbom.Diff(sboms[0], sboms[1]).IntroducedCves, failOn)
+				if len(introduced) > 0 {
+					log.Warnf("%d vulnerabilities with severity %s or higher introduced", len(introduced), strings.ToLower(failOn))
+					exit(1)
+				}
+			}
+			return nil
+		},
+	}
+	diffCommandFlags := diffCommand.Flags()
+	diffCommandFlags.DurationVar(&commandTimeout, "timeout", 0, "Fail if the command takes longer, e.g. 15m (0 waits forever)")
+	diffCommandFlags.BoolVar(&imgOpts.remote, "remote", false, "Pull images from registry without using the Docker daemon")
+	diffCommandFlags.StringVar(&imgOpts.platform, "platform", "", "Platform of images to index (e.g. linux/arm64)")
+	backendOpts.addFlags(diffCommandFlags)
+	diffCommandFlags.StringVar(&diffFormat, "format", sbom.DiffFormatTable, "Output format (table, json, markdown)")
+	diffCommandFlags.StringVarP(&output, "output", "o", "", "Location path to write diff to")
+	diffCommandFlags.StringVar(&failOn, "fail-on", "", "Exit with status code 1 if CVEs of given severity or higher are introduced")
+
+	dbCommand := &cobra.Command{
+		Use:   "db",
+		Short: "Manage local vulnerability database for offline scans",
+	}
+	dbDownloadCommand := &cobra.Command{
+		Use:   "download [OPTIONS]",
+		Short: "Download vulnerability database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return query.DownloadDb(ecosystems)
+		},
+	}
+	dbDownloadCommand.Flags().StringSliceVar(&ecosystems, "ecosystem", query.DefaultEcosystems, "OSV ecosystems to download")
+	dbUpdateCommand := &cobra.Command{
+		Use:   "update",
+		Short: "Update previously downloaded vulnerability database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return query.UpdateDb()
+		},
+	}
+	dbCommand.AddCommand(dbDownloadCommand, dbUpdateCommand)
+
+	var (
+		maxAge  time.Duration
+		maxSize string
+	)
+	cacheCommand := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage cached images and SBOMs",
+	}
+	cacheLsCommand := &cobra.Command{
+		Use:   "ls",
+		Short: "List cached images",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := registry.DefaultCache().Entries()
+			if err != nil {
+				return err
+			}
+			return writeCacheEntries(entries, os.Stdout)
+		},
+	}
+	cachePruneCommand := &cobra.Command{
+		Use:   "prune [OPTIONS]",
+		Short: "Remove least recently used images from the cache",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var size int64
+			if maxSize != "" {
+				var err error
+				if size, err = units.FromHumanSize(maxSize); err != nil {
+					return errors.Wrapf(err, "invalid max size %s", maxSize)
+				}
+			}
+			removed, err := registry.DefaultCache().Prune(maxAge, size)
+			var freed int64
+			for _, e := range removed {
+				freed += e.Size
+			}
+			log.Infof("Removed %d cached images, freed %s", len(removed), units.HumanSize(float64(freed)))
+			return err
+		},
+	}
+	cachePruneCommand.Flags().DurationVar(&maxAge, "max-age", 30*24*time.Hour, "Remove images not used within this duration (0 to disable)")
+	cachePruneCommand.Flags().StringVar(&maxSize, "max-size", "", "Remove least recently used images until the cache is no larger than this size, e.g. 10GB")
+	cachePurgeCommand := &cobra.Command{
+		Use:   "purge",
+		Short: "Remove all cached images, SBOMs and the vulnerability database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache := registry.DefaultCache()
+			if err := cache.Purge(); err != nil {
+				return err
+			}
+			log.Infof("Removed cache %s", cache.Dir)
+			return nil
+		},
+	}
+	cacheCommand.AddCommand(cacheLsCommand, cachePruneCommand, cachePurgeCommand)
+
+	var (
+		addr      string
+		queueSize int
+		token     string
+	)
+	serveCommand := &cobra.Command{
+		Use:   "serve [OPTIONS]",
+		Short: "Run REST API to scan images in the background",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			indexerOpts, err := imgOpts.indexerOptions(dockerCli)
+			if err != nil {
+				return err
+			}
+			b, err := backendOpts.newBackend(config)
+			if err != nil {
+				return err
+			}
+			if token == "" {
+				token = os.Getenv("DOCKER_INDEX_SERVE_TOKEN")
+			}
+			s := server.New(cmd.Context(), sbom.NewIndexer(indexerOpts...), b, server.Options{
+				Parallelism: batchOpts.parallelism,
+				QueueSize:   queueSize,
+				Timeout:     batchOpts.timeout,
+				Token:       token,
+			})
+			return s.ListenAndServe(addr)
+		},
+	}
+	serveCommandFlags := serveCommand.Flags()
+	serveCommandFlags.StringVar(&addr, "addr", "127.0.0.1:8080", "Address to listen on, e.g. :8080 for all interfaces (requires --token)")
+	serveCommandFlags.StringVar(&token, "token", "", "Bearer token clients must send in the Authorization header (or set DOCKER_INDEX_SERVE_TOKEN)")
+	serveCommandFlags.IntVar(&batchOpts.parallelism, "parallelism", 1, "Number of images to scan at the same time")
+	serveCommandFlags.IntVar(&queueSize, "queue-size", server.DefaultQueueSize, "Number of scans waiting to be run before new ones are rejected")
+	serveCommandFlags.DurationVar(&batchOpts.timeout, "timeout", 0, "Time limit to scan a single image, no limit if 0")
+	imgOpts.addBatchFlags(serveCommandFlags, true)
+	serveCommandFlags.BoolVar(&imgOpts.requireAllCatalogers, "require-all-catalogers", false, "Fail scans if any cataloger fails instead of returning a partial SBOM")
+	backendOpts.addFlags(serveCommandFlags)
+
+	var (
+		interval  time.Duration
+		tags      []string
+		outputDir string
+		once      bool
+	)
+	watchCommand := &cobra.Command{
+		Use:   "watch [OPTIONS] REPOSITORY",
+		Short: "Index new tags and digests of a repository",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf(`"docker index watch" requires exactly 1 argument`)
+			}
+			imgOpts.remote = true
+			indexerOpts, err := imgOpts.indexerOptions(dockerCli)
+			if err != nil {
+				return err
+			}
+			opts := watch.Options{Interval: interval, Tags: tags}
+			if includeCves {
+				if opts.Backend, err = backendOpts.newBackend(config); err != nil {
+					return err
+				}
+			}
+			w, err := watch.New(args[0], sbom.NewIndexer(indexerOpts...), opts)
+			if err != nil {
+				return err
+			}
+			emit := func(r watch.Result) {
+				logWatchResult(r, format, outputDir)
+			}
+			if once {
+				return w.Poll(cmd.Context(), emit)
+			}
+			log.Infof("Watching %s every %s", args[0], interval)
+			return w.Run(cmd.Context(), emit)
+		},
+	}
+	watchCommandFlags := watchCommand.Flags()
+	watchCommandFlags.DurationVar(&interval, "interval", 5*time.Minute, "Time between two polls of the repository")
+	watchCommandFlags.StringSliceVar(&tags, "tag", nil, "Only index tags matching the given patterns, e.g. 1.*")
+	watchCommandFlags.BoolVar(&once, "once", false, "Poll the repository once and exit")
+	watchCommandFlags.StringVar(&outputDir, "output-dir", "", "Directory to write the SBOM of every indexed image to")
+	watchCommandFlags.StringVar(&format, "format", sbom.FormatJSON, formatUsage)
+	watchCommandFlags.BoolVarP(&includeCves, "include-cves", "c", false, "Include package CVEs")
+	imgOpts.addBatchFlags(watchCommandFlags, false)
+	backendOpts.addFlags(watchCommandFlags)
+
+	var (
+		kubeconfig, kubeContext string
+		namespace, reportFormat string
+	)
+	k8sCommand := &cobra.Command{
+		Use:   "k8s [OPTIONS]",
+		Short: "Report vulnerabilities of the images running in a Kubernetes cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kc, err := k8s.LoadConfig(kubeconfig, kubeContext)
+			if err != nil {
+				return err
+			}
+			images, err := k8s.NewClient(kc).ListImages(cmd.Context(), namespace)
+			if err != nil {
+				return err
+			}
+			imgOpts.remote = true
+			indexer, err := batchOpts.newIndexer(imgOpts, dockerCli)
+			if err != nil {
+				return err
+			}
+			b, err := backendOpts.newBackend(config)
+			if err != nil {
+				return err
+			}
+			report := k8s.Scan(cmd.Context(), images, indexer, b, sbom.BatchOptions{Timeout: batchOpts.timeout})
+			if err := cmd.Context().Err(); err != nil {
+				return err
+			}
+			return writeReport(report, reportFormat, output, func(w io.Writer) error {
+				return writeClusterReport(report, w)
+			})
+		},
+	}
+	k8sCommandFlags := k8sCommand.Flags()
+	k8sCommandFlags.StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig, defaults to KUBECONFIG or ~/.kube/config")
+	k8sCommandFlags.StringVar(&kubeContext, "context", "", "Kubeconfig context to use, defaults to the current context")
+	k8sCommandFlags.StringVarP(&namespace, "namespace", "n", "", "Only scan pods in the given namespace, all namespaces by default")
+	k8sCommandFlags.StringVarP(&output, "output", "o", "", "Write report to file")
+	k8sCommandFlags.StringVar(&reportFormat, "format", "table", "Output format (table, json)")
+	batchOpts.addFlags(k8sCommandFlags)
+	imgOpts.addBatchFlags(k8sCommandFlags, false)
+	backendOpts.addFlags(k8sCommandFlags)
+
+	var composeFiles, profiles []string
+	composeCommand := &cobra.Command{
+		Use:   "compose [OPTIONS]",
+		Short: "Report vulnerabilities of the images of a compose project per service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := composeFiles
+			if len(files) == 0 {
+				if env := os.Getenv("COMPOSE_FILE"); env != "" {
+					files = filepath.SplitList(env)
+				} else {
+					var err error
+					if files, err = compose.DefaultFiles("."); err != nil {
+						return err
+					}
+				}
+			}
+			services, err := compose.LoadServices(files, profiles)
+			if err != nil {
+				return err
+			}
+			indexer, err := batchOpts.newIndexer(imgOpts, dockerCli)
+			if err != nil {
+				return err
+			}
+			b, err := backendOpts.newBackend(config)
+			if err != nil {
+				return err
+			}
+			report := compose.Scan(cmd.Context(), services, indexer, b, sbom.BatchOptions{Timeout: batchOpts.timeout})
+			if err := cmd.Context().Err(); err != nil {
+				return err
+			}
+			return writeReport(report, reportFormat, output, func(w io.Writer) error {
+				return writeComposeReport(report, w)
+			})
+		},
+	}
+	composeCommandFlags := composeCommand.Flags()
+	composeCommandFlags.StringSliceVarP(&composeFiles, "file", "f", nil, "Compose files, defaults to COMPOSE_FILE or compose.yaml in the working directory")
+	composeCommandFlags.StringSliceVar(&profiles, "profile", nil, "Profiles to enable, defaults to COMPOSE_PROFILES")
+	composeCommandFlags.StringVarP(&output, "output", "o", "", "Write report to file")
+	composeCommandFlags.StringVar(&reportFormat, "format", "table", "Output format (table, json)")
+	batchOpts.addFlags(composeCommandFlags)
+	imgOpts.addBatchFlags(composeCommandFlags, true)
+	backendOpts.addFlags(composeCommandFlags)
+
+	var sweepTags, sweepRepositories []string
+	sweepCommand := &cobra.Command{
+		Use:   "sweep [OPTIONS] NAMESPACE",
+		Short: "Index the repositories of a registry or organization, e.g. ghcr.io/myorg",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf(`"docker index sweep" requires exactly 1 argument`)
+			}
+			imgOpts.remote = true
+			indexer, err := batchOpts.newIndexer(imgOpts, dockerCli)
+			if err != nil {
+				return err
+			}
+			opts := watch.Options{Tags: sweepTags, Timeout: batchOpts.timeout, Repositories: sweepRepositories}
+			if includeCves {
+				if opts.Backend, err = backendOpts.newBackend(config); err != nil {
+					return err
+				}
+			}
+			failed := 0
+			err = watch.Sweep(cmd.Context(), args[0], indexer, opts, func(r watch.Result) {
+				if !logWatchResult(r, format, outputDir) {
+					failed++
+				}
+			})
+			if err != nil {
+				return err
+			}
+			if failed > 0 {
+				return errors.Errorf("failed to index %d images", failed)
+			}
+			return nil
+		},
+	}
+	sweepCommandFlags := sweepCommand.Flags()
+	sweepCommandFlags.StringSliceVar(&sweepTags, "tag", []string{"latest"}, "Only index tags matching the given patterns, all tags if empty")
+	sweepCommandFlags.StringSliceVar(&sweepRepositories, "repository", nil, "Repositories below the namespace to index instead of listing them with the catalog API, e.g. web for ghcr.io/myorg/web")
+	sweepCommandFlags.StringVar(&outputDir, "output-dir", "", "Directory to write the SBOM of every indexed image to")
+	sweepCommandFlags.StringVar(&format, "format", sbom.FormatJSON, formatUsage)
+	sweepCommandFlags.BoolVarP(&includeCves, "include-cves", "c", false, "Include package CVEs")
+	batchOpts.addFlags(sweepCommandFlags)
+	imgOpts.addBatchFlags(sweepCommandFlags, false)
+	backendOpts.addFlags(sweepCommandFlags)
+
+	mergeCommand := &cobra.Command{
+		Use:   "merge [OPTIONS] SBOM...",
+		Short: "Merge SBOMs, e.g. of multiple platforms or of an app and its sidecar",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return fmt.Errorf(`"docker index merge" requires at least 2 arguments`)
+			}
+			sboms := make([]*types.Sbom, 0)
+			for _, path := range args {
+				sb, err := sbom.NewIndexer(indexerSettings...).ReadSboms(path)
+				if err != nil {
+					return err
+				}
+				sboms = append(sboms, sb...)
+			}
+			merged, err := sbom.Merge(sboms...)
+			if err != nil {
+				return err
+			}
+			log.Infof("Merged %d SBOMs into %d packages", len(sboms), len(merged.Artifacts))
+			var buf bytes.Buffer
+			if err := sbom.WriteFormat(merged, format, &buf, writeSettings...); err != nil {
+				return err
+			}
+			if output != "" {
+				if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+					return errors.Wrapf(err, "failed to write %s", output)
+				}
+				log.Infof("SBOM written to %s", output)
+			} else {
+				os.Stdout.Write(buf.Bytes())
+			}
+			return nil
+		},
+	}
+	mergeCommandFlags := mergeCommand.Flags()
+	mergeCommandFlags.StringVarP(&output, "output", "o", "", "Location path to write merged SBOM to")
+	mergeCommandFlags.StringVar(&format, "format", sbom.FormatJSON, formatUsage)
+
+	convertCommand := &cobra.Command{
+		Use:   "convert [OPTIONS] SBOM",
+		Short: "Convert a SBOM between the json, spdx-json, cyclonedx-json and syft-json formats",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf(`"docker index convert" requires exactly 1 argument`)
+			}
+			sboms, err := readSboms(args[0], imgOpts.platform)
+			if err != nil {
+				return err
+			}
+			if len(sboms) > 1 {
+				return writePlatformSboms(sboms, format, output)
+			}
+			var buf bytes.Buffer
+			if err := sbom.WriteFormat(sboms[0], format, &buf, writeSettings...); err != nil {
+				return err
+			}
+			if output != "" {
+				if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+					return errors.Wrapf(err, "failed to write %s", output)
+				}
+				log.Infof("SBOM written to %s", output)
+			} else {
+				os.Stdout.Write(buf.Bytes())
+			}
+			return nil
+		},
+	}
+	convertCommandFlags := convertCommand.Flags()
+	convertCommandFlags.StringVarP(&output, "output", "o", "", "Location path to write converted SBOM to")
+	convertCommandFlags.StringVar(&format, "format", sbom.FormatJSON, formatUsage)
+	convertCommandFlags.StringVar(&imgOpts.platform, "platform", "", "Platform of multi-platform SBOM to convert (e.g. linux/arm64)")
+
+	var printSchema bool
+	validateCommand := &cobra.Command{
+		Use:   "validate [OPTIONS] SBOM...",
+		Short: "Validate SBOMs in the native json format against the SBOM schema",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if printSchema {
+				schema, err := types.Schema()
+				if err != nil {
+					return err
+				}
+				os.Stdout.Write(append(schema, '\n'))
+				return nil
+			}
+			if len(args) == 0 {
+				return fmt.Errorf(`"docker index validate" requires at least 1 argument`)
+			}
+			invalid := 0
+			for _, path := range args {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return errors.Wrapf(err, "failed to read sbom %s", path)
+				}
+				problems, err := sbom.Validate(b)
+				if err != nil {
+					return errors.Wrapf(err, "failed to validate sbom %s", path)
+				}
+				if len(problems) == 0 {
+					log.Infof("%s is valid", path)
+					continue
+				}
+				invalid++
+				for _, p := range problems {
+					fmt.Printf("%s: %s\n", path, p)
+				}
+			}
+			if invalid > 0 {
+				return errors.Errorf("%d of %d SBOMs are invalid", invalid, len(args))
+			}
+			return nil
+		},
+	}
+	validateCommandFlags := validateCommand.Flags()
+	validateCommandFlags.BoolVar(&printSchema, "schema", false, "Print the JSON schema of the native SBOM format")
+
+	var historyLimit int
+	historyCommand := &cobra.Command{
+		Use:   "history [OPTIONS] IMAGE",
+		Short: "List prior scans of an image repository recorded with --history-db",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return sbom.DiffImages(args[0], args[1], dockerCli.Client(), "", "")
+			if historyDb == "" {
+				return errors.New("--history-db is required")
+			}
+			store, err := history.Open(historyDb)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+			scans, err := store.List(cmd.Context(), args[0], historyLimit)
+			if err != nil {
+				return err
+			}
+			switch reportFormat {
+			case "json":
+				js, err := json.MarshalIndent(scans, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(os.Stdout, string(js))
+				return err
+			case "table":
+				return writeHistory(scans, os.Stdout)
+			default:
+				return errors.Errorf("unsupported output format: %s", reportFormat)
+			}
 		},
 	}
+	historyCommandFlags := historyCommand.Flags()
+	historyCommandFlags.IntVar(&historyLimit, "limit", 20, "Number of most recent scans to list")
+	historyCommandFlags.StringVar(&reportFormat, "format", "table", "Output format (table, json)")
 
-	cmd.AddCommand(loginCommand, logoutCommand, sbomCommand, cveCommand, uploadCommand, diffCommand)
+	var noCves bool
+	exploreCommand := &cobra.Command{
+		Use:   "explore [OPTIONS] [IMAGE]",
+		Short: "Browse layers, packages, files and CVEs of an image in a terminal UI",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !term.IsTerminal(os.Stdout.Fd()) {
+				return errors.New(`"docker index explore" requires a terminal`)
+			}
+			var sb *types.Sbom
+			if sbomFile != "" {
+				sboms, err := readSboms(sbomFile, imgOpts.platform)
+				if err != nil {
+					return err
+				}
+				if len(sboms) > 1 {
+					return errors.Errorf("%s contains SBOMs of %d platforms, select one with --platform", sbomFile, len(sboms))
+				}
+				sb = sboms[0]
+			} else {
+				var err error
+				sb, _, err = indexImage(cmd.Context(), imgOpts, args, dockerCli)
+				if err != nil {
+					return err
+				}
+			}
+			if !noCves && len(sb.Vulnerabilities) == 0 {
+				b, err := backendOpts.newBackend(config)
+				if err != nil {
+					return err
+				}
+				cves, err := queryCves(cmd.Context(), sb, "", b)
+				if err != nil {
+					return err
+				}
+				if cves != nil {
+					sb.Vulnerabilities = *cves
+				}
+			}
+			return explore.Run(sb)
+		},
+	}
+	exploreCommandFlags := exploreCommand.Flags()
+	exploreCommandFlags.StringVarP(&imgOpts.image, "image", "i", "", "Image reference to index")
+	exploreCommandFlags.StringVarP(&imgOpts.ociDir, "oci-dir", "d", "", "Path to image in OCI format")
+	exploreCommandFlags.BoolVar(&imgOpts.remote, "remote", false, "Pull image from registry without using the Docker daemon")
+	exploreCommandFlags.StringVar(&imgOpts.platform, "platform", "", "Platform of image to index (e.g. linux/arm64)")
+	exploreCommandFlags.StringVar(&imgOpts.ociLayout, "oci-layout", "", "Path to OCI image layout directory (select image with --image)")
+	exploreCommandFlags.StringVar(&imgOpts.input, "input", "", "Path to image tarball written by docker save or in oci-archive format")
+	exploreCommandFlags.StringVar(&sbomFile, "sbom", "", "Path to SBOM to explore instead of indexing the image (json, spdx-json, cyclonedx-json, syft-json)")
+	exploreCommandFlags.BoolVar(&noCves, "no-cves", false, "Don't query vulnerabilities, e.g. to browse packages without network access")
+	backendOpts.addFlags(exploreCommandFlags)
+
+	explainCommand := &cobra.Command{
+		Use:   "explain [OPTIONS] [IMAGE] PACKAGE|CVE_ID",
+		Short: "Explain where a package of an image came from and why a CVE matched",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			subject := args[len(args)-1]
+			var sb *types.Sbom
+			if sbomFile != "" {
+				sboms, err := readSboms(sbomFile, imgOpts.platform)
+				if err != nil {
+					return err
+				}
+				if len(sboms) > 1 {
+					return errors.Errorf("%s contains SBOMs of %d platforms, select one with --platform", sbomFile, len(sboms))
+				}
+				sb = sboms[0]
+			} else {
+				var err error
+				sb, _, err = indexImage(cmd.Context(), imgOpts, args[:len(args)-1], dockerCli)
+				if err != nil {
+					return err
+				}
+			}
+			if !noCves && len(sb.Vulnerabilities) == 0 {
+				b, err := backendOpts.newBackend(config)
+				if err != nil {
+					return err
+				}
+				cves, err := queryCves(cmd.Context(), sb, "", b)
+				if err != nil {
+					return err
+				}
+				if cves != nil {
+					sb.Vulnerabilities = *cves
+				}
+			}
+			explanations, err := sbom.Explain(sb, subject)
+			if err != nil {
+				return err
+			}
+			switch reportFormat {
+			case "json":
+				js, err := json.MarshalIndent(explanations, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(os.Stdout, string(js))
+				return err
+			case "text":
+				return sbom.WriteExplanations(os.Stdout, explanations)
+			default:
+				return errors.Errorf("unsupported output format: %s", reportFormat)
+			}
+		},
+	}
+	explainCommandFlags := explainCommand.Flags()
+	explainCommandFlags.StringVarP(&imgOpts.image, "image", "i", "", "Image reference to index")
+	explainCommandFlags.StringVarP(&imgOpts.ociDir, "oci-dir", "d", "", "Path to image in OCI format")
+	explainCommandFlags.BoolVar(&imgOpts.remote, "remote", false, "Pull image from registry without using the Docker daemon")
+	explainCommandFlags.StringVar(&imgOpts.platform, "platform", "", "Platform of image to index (e.g. linux/arm64)")
+	explainCommandFlags.StringVar(&imgOpts.ociLayout, "oci-layout", "", "Path to OCI image layout directory (select image with --image)")
+	explainCommandFlags.StringVar(&imgOpts.input, "input", "", "Path to image tarball written by docker save or in oci-archive format")
+	explainCommandFlags.StringVar(&sbomFile, "sbom", "", "Path to SBOM to explain instead of indexing the image (json, spdx-json, cyclonedx-json, syft-json)")
+	explainCommandFlags.StringVar(&reportFormat, "format", "text", "Output format (text, json)")
+	explainCommandFlags.BoolVar(&noCves, "no-cves", false, "Don't query vulnerabilities, e.g. to explain packages without network access")
+	backendOpts.addFlags(explainCommandFlags)
+
+	dockerfileCommand := &cobra.Command{
+		Use:   "dockerfile [OPTIONS] [IMAGE]",
+		Short: "Reconstruct an approximate Dockerfile from the image history with the packages each instruction introduced",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var sb *types.Sbom
+			if sbomFile != "" {
+				sboms, err := readSboms(sbomFile, imgOpts.platform)
+				if err != nil {
+					return err
+				}
+				if len(sboms) > 1 {
+					return errors.Errorf("%s contains SBOMs of %d platforms, select one with --platform", sbomFile, len(sboms))
+				}
+				sb = sboms[0]
+			} else {
+				var err error
+				sb, _, err = indexImage(cmd.Context(), imgOpts, args, dockerCli)
+				if err != nil {
+					return err
+				}
+			}
+			sbom.DetectBaseImage(sb, baseImages, baseImageLabel)
+			return sbom.WriteDockerfile(sb, os.Stdout)
+		},
+	}
+	dockerfileCommandFlags := dockerfileCommand.Flags()
+	dockerfileCommandFlags.StringVarP(&imgOpts.image, "image", "i", "", "Image reference to index")
+	dockerfileCommandFlags.StringVarP(&imgOpts.ociDir, "oci-dir", "d", "", "Path to image in OCI format")
+	dockerfileCommandFlags.BoolVar(&imgOpts.remote, "remote", false, "Pull image from registry without using the Docker daemon")
+	dockerfileCommandFlags.StringVar(&imgOpts.platform, "platform", "", "Platform of image to index (e.g. linux/arm64)")
+	dockerfileCommandFlags.StringVar(&imgOpts.ociLayout, "oci-layout", "", "Path to OCI image layout directory (select image with --image)")
+	dockerfileCommandFlags.StringVar(&imgOpts.input, "input", "", "Path to image tarball written by docker save or in oci-archive format")
+	dockerfileCommandFlags.StringVar(&sbomFile, "sbom", "", "Path to SBOM to reconstruct the Dockerfile of instead of indexing the image (json)")
+	dockerfileCommandFlags.StringSliceVar(&baseImages, "base-image", nil, "Candidate base image references to match against the image layers")
+	dockerfileCommandFlags.BoolVar(&baseImageLabel, "base-image-label", false, "Match the base image recorded in the org.opencontainers.image.base.name label")
+
+	cmd.AddCommand(loginCommand, logoutCommand, sbomCommand, cveCommand, uploadCommand, diffCommand, dbCommand, cacheCommand, serveCommand, watchCommand, k8sCommand, composeCommand, sweepCommand, mergeCommand, convertCommand, validateCommand, historyCommand, exploreCommand, explainCommand, dockerfileCommand)
+	onExit = func() {
+		cancelTimeout()
+		stopTracing()
+		if pushMetrics == "" {
+			return
+		}
+		if err := metrics.Push(pushMetrics, "docker_index"); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+	withOnExit(cmd)
 	return cmd
 }
 
+// onExit runs once a command completed, also on failure or exit with a status code
+var onExit = func() {}
+
+// stopTracing ends the span of the command and exports the pending spans
+var stopTracing = func() {}
+
+// cancelTimeout releases the context of the command bounded by --timeout
+var cancelTimeout = func() {}
+
+// indexerSettings are the indexer options of the persistent flags, like --keep and --no-cpes
+var indexerSettings []sbom.Option
+
+// querySettings are the vulnerability backend options of the persistent flags, like --enrich
+var querySettings []query.Option
+
+// writeSettings are the output format options of --template and --csv-columns
+var writeSettings []sbom.WriteOption
+
+// showProgress renders the progress of indexing single images as a bar on stderr
+var showProgress bool
+
+// withOnExit calls onExit after the commands below cmd ran
+func withOnExit(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		withOnExit(c)
+	}
+	if cmd.RunE == nil {
+		return
+	}
+	runE := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		defer onExit()
+		err := runE(cmd, args)
+		if err != nil && cmd.Context().Err() == context.DeadlineExceeded {
+			return errors.Wrap(err, "command timed out, see --timeout")
+		}
+		return err
+	}
+}
+
+// exit runs onExit and terminates with the given status code
+func exit(code int) {
+	onExit()
+	os.Exit(code)
+}
+
+func indexImage(ctx context.Context, opts imageOptions, args []string, cli command.Cli) (sb *types.Sbom, img *v1.Image, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveScan(start, err)
+	}()
+	image := opts.imageRef(args)
+	indexerOpts, err := opts.indexerOptions(cli)
+	if err != nil {
+		return nil, nil, err
+	}
+	if showProgress {
+		bar := progress.NewBar(os.Stderr)
+		defer bar.Done()
+		indexerOpts = append(indexerOpts, sbom.WithProgress(bar), sbom.WithLogger(bar.Logger(log.Current())))
+	}
+	indexer := sbom.NewIndexer(indexerOpts...)
+	switch {
+	case opts.ociDir != "":
+		return indexer.IndexPath(ctx, opts.ociDir, image)
+	case opts.ociLayout != "":
+		return indexer.IndexOCILayout(ctx, opts.ociLayout, image)
+	case opts.input != "":
+		return indexer.IndexArchive(ctx, opts.input, image)
+	case image == "":
+		return nil, nil, errors.New("image reference required")
+	default:
+		return indexer.Index(ctx, image)
+	}
+}
+
+// uploadSboms uploads the native JSON sbom of every image and its report in format, if another
+// one, to the bucket at location below the image digest
+func uploadSboms(ctx context.Context, sboms []*types.Sbom, format string, location string, opts storage.Options) error {
+	sink, err := storage.Open(ctx, location, opts)
+	if err != nil {
+		return err
+	}
+	formats := []string{sbom.FormatJSON}
+	if format != "" && format != sbom.FormatJSON {
+		formats = append(formats, format)
+	}
+	for _, sb := range sboms {
+		for _, f := range formats {
+			key, err := storage.Key(sb, sbom.FileName(f))
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err := sbom.WriteFormat(sb, f, &buf, writeSettings...); err != nil {
+				return err
+			}
+			object, err := sink.Put(ctx, key, buf.Bytes(), sbom.MediaType(f))
+			if err != nil {
+				return err
+			}
+			log.Infof("%s of %s uploaded to %s", sbom.FileName(f), sb.Source.Image.Name, object)
+		}
+	}
+	return nil
+}
+
+// writePlatformSboms writes the SBOMs of a multi-platform image. The native JSON format
+// combines all SBOMs into one document keyed by platform; other formats are written
+// into one file per platform.
+func writePlatformSboms(sboms []*types.Sbom, format string, output string) error {
+	if format == "" || format == sbom.FormatJSON {
+		combined := make(map[string]*types.Sbom)
+		for _, sb := range sboms {
+			sbom.SortSbom(sb)
+			combined[sb.Source.Image.Platform.String()] = sb
+		}
+		js, err := json.MarshalIndent(combined, "", "  ")
+		if err != nil {
+			return err
+		}
+		if output != "" {
+			if err := os.WriteFile(output, js, 0644); err != nil {
+				return errors.Wrapf(err, "failed to write %s", output)
+			}
+			log.Infof("SBOMs written to %s", output)
+		} else {
+			os.Stdout.WriteString(string(js) + "\n")
+		}
+		return nil
+	}
+
+	if output == "" {
+		return errors.Errorf("--output is required to write %s SBOMs for multiple platforms", format)
+	}
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	for _, sb := range sboms {
+		var buf bytes.Buffer
+		if err := sbom.WriteFormat(sb, format, &buf, writeSettings...); err != nil {
+			return err
+		}
+		path := fmt.Sprintf("%s-%s%s", base, strings.ReplaceAll(sb.Source.Image.Platform.String(), "/", "-"), ext)
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", path)
+		}
+		log.Infof("SBOM written to %s", path)
+	}
+	return nil
+}
+
+// warnEol warns that no advisories are published for the distro of sb once it reached its end of life
+func warnEol(sb *types.Sbom) {
+	if d := sb.Source.Image.Distro; d.EndOfLife {
+		log.Warnf("Distro %s %s reached end of life on %s; missing vulnerabilities do not mean the image is safe", d.OsName, d.OsVersion, d.Eol)
+	}
+}
+
+// readSboms reads the SBOMs at path, selecting the one of platform from a multi-platform document
+func readSboms(path string, platform string) ([]*types.Sbom, error) {
+	sboms, err := sbom.NewIndexer(indexerSettings...).ReadSboms(path)
+	if err != nil || platform == "" || len(sboms) == 1 {
+		return sboms, err
+	}
+	selected := make([]*types.Sbom, 0)
+	for _, sb := range sboms {
+		if sb.Source.Image.Platform.String() == platform {
+			selected = append(selected, sb)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.Errorf("no SBOM for platform %s in %s", platform, path)
+	}
+	return selected, nil
+}
+
+// writeSboms writes sboms in format to output, a storage location, or stdout if output is empty
+func writeSboms(ctx context.Context, sboms []*types.Sbom, format string, output string, opts storage.Options) (err error) {
+	ctx, span := tracing.Start(ctx, "write output", tracing.Digest(sboms[0].Source.Image.Digest))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	if storage.IsLocation(output) {
+		return uploadSboms(ctx, sboms, format, output, opts)
+	}
+	if len(sboms) > 1 {
+		return writePlatformSboms(sboms, format, output)
+	}
+	var buf bytes.Buffer
+	if err := sbom.WriteFormat(sboms[0], format, &buf, writeSettings...); err != nil {
+		return err
+	}
+	if output != "" {
+		if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", output)
+		}
+		log.Infof("SBOM written to %s", output)
+	} else {
+		os.Stdout.Write(buf.Bytes())
+	}
+	return nil
+}
+
+// queryCves queries the vulnerabilities of sb, of cve only if not empty, from backend b
+func queryCves(ctx context.Context, sb *types.Sbom, cve string, b query.Backend) (*[]types.Cve, error) {
+	ctx, span := tracing.Start(ctx, "query cves", tracing.Digest(sb.Source.Image.Digest))
+	cves, err := b.QueryCves(ctx, sb, cve)
+	tracing.End(span, err)
+	if err == nil && cves != nil {
+		for _, c := range *cves {
+			metrics.ObserveCve(sbom.Severity(c))
+		}
+	}
+	return cves, err
+}
+
+func newSigner(key string, keyless bool, identityToken string) (attest.Signer, error) {
+	switch {
+	case key != "" && keyless:
+		return nil, errors.New("--key and --keyless can't be used together")
+	case key != "":
+		return attest.LoadKey(key, []byte(os.Getenv("COSIGN_PASSWORD")))
+	case keyless:
+		return attest.NewKeylessSigner(identityToken)
+	default:
+		return nil, errors.New("--attest requires --key or --keyless")
+	}
+}
+
+func newVerifier(key string, identity string, issuer string) (attest.Verifier, error) {
+	switch {
+	case key != "" && identity != "":
+		return nil, errors.New("--verify-key and --verify-identity can't be used together")
+	case key != "":
+		pub, err := attest.LoadPublicKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return attest.NewKeyVerifier(pub), nil
+	case identity != "" && issuer == "":
+		return nil, errors.New("--verify-identity requires --verify-issuer")
+	case identity != "":
+		return attest.NewKeylessVerifier(identity, issuer), nil
+	default:
+		return nil, errors.New("--reuse-attestation requires --verify-key or --verify-identity")
+	}
+}
+
+func attachAttestation(ctx context.Context, sb *types.Sbom, format string, signer attest.Signer) error {
+	predicateType, err := attest.PredicateType(format)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := sbom.WriteFormat(sb, format, &buf, writeSettings...); err != nil {
+		return err
+	}
+	statement, err := attest.NewStatement(sb.Source.Image.Name, sb.Source.Image.Digest, predicateType, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	envelope, err := attest.Sign(statement, signer)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]string{
+		"dev.cosignproject.cosign/signature": "",
+		"predicateType":                      predicateType,
+	}
+	if ks, ok := signer.(*attest.KeylessSigner); ok {
+		bundle, err := ks.Upload(raw)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(bundle)
+		if err != nil {
+			return err
+		}
+		annotations[attest.CertificateAnnotation] = string(ks.Certificate)
+		annotations[attest.ChainAnnotation] = string(ks.Chain)
+		annotations[attest.BundleAnnotation] = string(b)
+	}
+	return registry.AttachAttestation(ctx, sb.Source.Image.Name, sb.Source.Image.Digest, raw, annotations)
+}
+
+// logWatchResult logs the result of an image indexed by watch or sweep and writes its sbom
+// to dir if set, it returns false if indexing failed
+func logWatchResult(r watch.Result, format string, dir string) bool {
+	if r.Err != nil {
+		log.Warnf("Failed to index %s: %s", r.Image, r.Err)
+		return false
+	}
+	log.Infof("Indexed %s with %d packages and %d vulnerabilities", r.Image, len(r.Sbom.Artifacts), len(r.Sbom.Vulnerabilities))
+	if dir != "" {
+		if err := writeWatchResult(r, format, dir); err != nil {
+			log.Warnf("%s", err)
+		}
+	}
+	return true
+}
+
+// writeWatchResult writes the sbom of an image indexed by watch or sweep to
+// <repository>_<tag>-<digest>.<ext> in dir
+func writeWatchResult(r watch.Result, format string, dir string) error {
+	var buf bytes.Buffer
+	if err := sbom.WriteFormat(r.Sbom, format, &buf, writeSettings...); err != nil {
+		return err
+	}
+	i := strings.LastIndex(r.Image, ":")
+	repo := r.Image[:i]
+	if j := strings.Index(repo, "/"); j >= 0 {
+		repo = repo[j+1:]
+	}
+	tag := strings.ReplaceAll(repo, "/", "_") + "_" + r.Image[i+1:]
+	digest := strings.TrimPrefix(r.Digest, "sha256:")
+	if len(digest) > 12 {
+		digest = digest[:12]
+	}
+	ext := ".json"
+	if format == sbom.FormatSARIF {
+		ext = ".sarif"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create output directory %s", dir)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", tag, digest, ext))
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	log.Infof("SBOM written to %s", path)
+	return nil
+}
+
+// writeCacheEntries writes a table of the cached images followed by the total size
+func writeCacheEntries(entries []registry.CacheEntry, w io.Writer) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Digest", "Size", "Last used", "SBOM"})
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Name: "Size", Align: text.AlignRight, AlignFooter: text.AlignRight},
+	})
+	var size int64
+	for _, e := range entries {
+		indexed := ""
+		if e.Indexed {
+			indexed = "✓"
+		}
+		t.AppendRow(table.Row{e.Digest, units.HumanSize(float64(e.Size)), units.HumanDuration(time.Since(e.LastUsed)) + " ago", indexed})
+		size += e.Size
+	}
+	t.AppendFooter(table.Row{"Total", units.HumanSize(float64(size)), "", ""})
+	t.SetStyle(table.StyleLight)
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
+// writeClusterReport writes one row per image of every workload with its CVE counts
+func writeClusterReport(report *k8s.Report, w io.Writer) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Namespace", "Workload", "Image", "Critical", "High", "Medium", "Low"})
+	for _, ns := range report.Namespaces {
+		for _, wl := range ns.Workloads {
+			for _, image := range wl.Images {
+				if image.Error != "" {
+					t.AppendRow(table.Row{ns.Name, wl.Kind + "/" + wl.Name, image.Image, "failed", "", "", ""})
+					continue
+				}
+				v := image.Vulnerabilities
+				t.AppendRow(table.Row{ns.Name, wl.Kind + "/" + wl.Name, image.Image, v["CRITICAL"], v["HIGH"], v["MEDIUM"], v["LOW"]})
+			}
+		}
+	}
+	v := report.Vulnerabilities
+	t.AppendFooter(table.Row{"Total", "", "", v["CRITICAL"], v["HIGH"], v["MEDIUM"], v["LOW"]})
+	t.SetStyle(table.StyleLight)
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
+// previousScan returns the sbom to compare the vulnerabilities of sb with: the one read from path,
+// or the latest scan of its repository and platform in the history database at dsn
+func previousScan(ctx context.Context, sb *types.Sbom, path string, dsn string) (*types.Sbom, error) {
+	platform := sb.Source.Image.Platform.String()
+	if path != "" {
+		sboms, err := readSboms(path, platform)
+		if err != nil {
+			return nil, err
+		}
+		for _, previous := range sboms {
+			if previous.Source.Image.Platform.String() == platform {
+				return previous, nil
+			}
+		}
+		return sboms[0], nil
+	}
+	if dsn == "" {
+		return nil, nil
+	}
+	store, err := history.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return store.Previous(ctx, sb.Source.Image.Name, platform)
+}
+
+// recordScans records the scans of sboms in the history database at dsn
+func recordScans(ctx context.Context, dsn string, sboms []*types.Sbom) error {
+	store, err := history.Open(dsn)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	now := time.Now()
+	for _, sb := range sboms {
+		id, err := store.Record(ctx, sb, now)
+		if err != nil {
+			return err
+		}
+		log.Debugf("Scan of %s recorded as %d", sb.Source.Image.Name, id)
+	}
+	return nil
+}
+
+// writeHistory writes one row per scan with its CVE counts and the change of the total count
+// since the previous scan of the same platform
+func writeHistory(scans []history.Scan, w io.Writer) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Scanned", "Image", "Digest", "Platform", "Packages", "Critical", "High", "Medium", "Low", "Trend"})
+	for i, s := range scans {
+		v := s.Vulnerabilities
+		trend := ""
+		for _, previous := range scans[i+1:] {
+			if previous.Platform != s.Platform {
+				continue
+			}
+			switch delta := totalCves(v) - totalCves(previous.Vulnerabilities); {
+			case delta > 0:
+				trend = fmt.Sprintf("▲ %d", delta)
+			case delta < 0:
+				trend = fmt.Sprintf("▼ %d", -delta)
+			default:
+				trend = "="
+			}
+			break
+		}
+		digest := s.Digest
+		if len(digest) > 19 {
+			digest = digest[:19]
+		}
+		t.AppendRow(table.Row{s.Scanned.Local().Format(time.RFC3339), s.Image, digest, s.Platform, s.Packages, v["CRITICAL"], v["HIGH"], v["MEDIUM"], v["LOW"], trend})
+	}
+	t.SetStyle(table.StyleLight)
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
+func totalCves(v map[string]int) int {
+	total := 0
+	for _, count := range v {
+		total += count
+	}
+	return total
+}
+
+// writeComposeReport writes one row per service with the CVE counts of its image
+func writeComposeReport(report *compose.Report, w io.Writer) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Service", "Image", "Packages", "Critical", "High", "Medium", "Low"})
+	for _, s := range report.Services {
+		if s.Error != "" {
+			t.AppendRow(table.Row{s.Service, s.Image, "failed", "", "", "", ""})
+			continue
+		}
+		v := s.Vulnerabilities
+		t.AppendRow(table.Row{s.Service, s.Image, s.Packages, v["CRITICAL"], v["HIGH"], v["MEDIUM"], v["LOW"]})
+	}
+	v := report.Vulnerabilities
+	t.AppendFooter(table.Row{"Total", "", "", v["CRITICAL"], v["HIGH"], v["MEDIUM"], v["LOW"]})
+	t.SetStyle(table.StyleLight)
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
 func readWorkspace(args []string, cli command.Cli) (string, error) {
 	var workspace string
 	if len(args) == 1 {
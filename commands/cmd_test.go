@@ -0,0 +1,44 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"testing"
+)
+
+func TestCheckAllPlatforms(t *testing.T) {
+	tests := []struct {
+		opts imageOptions
+		err  string
+	}{
+		{opts: imageOptions{image: "alpine"}},
+		{opts: imageOptions{image: "alpine", remote: true}},
+		{opts: imageOptions{ociDir: "/tmp/image"}, err: "--all-platforms can't be used with --oci-dir"},
+		{opts: imageOptions{ociLayout: "/tmp/layout"}, err: "--all-platforms can't be used with --oci-layout"},
+		{opts: imageOptions{input: "image.tar"}, err: "--all-platforms can't be used with --input"},
+		{opts: imageOptions{image: "alpine", platform: "linux/arm64"}, err: "--all-platforms can't be used with --platform"},
+		{opts: imageOptions{image: "alpine", stream: true}, err: "--all-platforms can't be used with --stream"},
+	}
+	for _, test := range tests {
+		err := test.opts.checkAllPlatforms()
+		if test.err == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", test.opts, err)
+		} else if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%+v: expected error %q, got %v", test.opts, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	cliconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// flagDefaults are the flag defaults read from the config file, see applyDefaults
+type flagDefaults struct {
+	// Format is the output format of the commands writing sboms
+	Format            string   `yaml:"format,omitempty"`
+	Severity          []string `yaml:"severity,omitempty"`
+	FailOn            string   `yaml:"fail-on,omitempty"`
+	CacheDir          string   `yaml:"cache-dir,omitempty"`
+	Catalogers        []string `yaml:"catalogers,omitempty"`
+	ExcludeCatalogers []string `yaml:"exclude-catalogers,omitempty"`
+	Backend           string   `yaml:"backend,omitempty"`
+	Enrich            bool     `yaml:"enrich,omitempty"`
+	Registries        struct {
+		CA       string   `yaml:"ca,omitempty"`
+		Insecure []string `yaml:"insecure,omitempty"`
+	} `yaml:"registries,omitempty"`
+}
+
+// setting is a default of flag, overridden by the environment variable env
+type setting struct {
+	flag  string
+	env   string
+	value string
+}
+
+func (d flagDefaults) settings() []setting {
+	return []setting{
+		{flag: "format", env: "DOCKER_INDEX_FORMAT", value: d.Format},
+		{flag: "severity", env: "DOCKER_INDEX_SEVERITY", value: strings.Join(d.Severity, ",")},
+		{flag: "fail-on", env: "DOCKER_INDEX_FAIL_ON", value: d.FailOn},
+		{flag: "cache-dir", env: "DOCKER_INDEX_CACHE_DIR", value: d.CacheDir},
+		{flag: "catalogers", env: "DOCKER_INDEX_CATALOGERS", value: strings.Join(d.Catalogers, ",")},
+		{flag: "exclude-catalogers", env: "DOCKER_INDEX_EXCLUDE_CATALOGERS", value: strings.Join(d.ExcludeCatalogers, ",")},
+		{flag: "backend", env: "DOCKER_INDEX_BACKEND", value: d.Backend},
+		{flag: "enrich", env: "DOCKER_INDEX_ENRICH", value: enabled(d.Enrich)},
+		{flag: "registry-ca", env: "DOCKER_INDEX_REGISTRY_CA", value: d.Registries.CA},
+		{flag: "insecure-registry", env: "DOCKER_INDEX_INSECURE_REGISTRIES", value: strings.Join(d.Registries.Insecure, ",")},
+	}
+}
+
+// enabled returns the value of a bool setting, empty if it isn't set
+func enabled(b bool) string {
+	if b {
+		return "true"
+	}
+	return ""
+}
+
+// defaultsFile returns the path of the config file in the Docker config directory
+func defaultsFile() string {
+	return filepath.Join(cliconfig.Dir(), "index", "config.yaml")
+}
+
+// readDefaults reads the config file at path, or the one in the Docker config directory if
+// path is empty, which doesn't need to exist
+func readDefaults(path string) (flagDefaults, error) {
+	var defaults flagDefaults
+	file := path
+	if file == "" {
+		file = defaultsFile()
+	}
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) && path == "" {
+		return defaults, nil
+	} else if err != nil {
+		return defaults, errors.Wrapf(err, "failed to read config file %s", file)
+	}
+	if err := yaml.Unmarshal(b, &defaults); err != nil {
+		return defaults, errors.Wrapf(err, "failed to parse config file %s", file)
+	}
+	return defaults, nil
+}
+
+// applyDefaults sets the flags not passed on the command line to the value of their environment
+// variable or else the config file. Only commands writing sboms take the format, the reports
+// of e.g. k8s and history have formats of their own.
+func applyDefaults(flags *pflag.FlagSet, defaults flagDefaults) error {
+	for _, s := range defaults.settings() {
+		value := s.value
+		if env, ok := os.LookupEnv(s.env); ok {
+			value = env
+		}
+		f := flags.Lookup(s.flag)
+		if value == "" || f == nil || f.Changed {
+			continue
+		}
+		if s.flag == "format" && f.DefValue != sbom.FormatJSON {
+			continue
+		}
+		if err := flags.Set(s.flag, value); err != nil {
+			return errors.Wrapf(err, "invalid %s default %q", s.flag, value)
+		}
+	}
+	return nil
+}
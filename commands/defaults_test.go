@@ -0,0 +1,99 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `format: cyclonedx-json
+severity: [critical, high]
+fail-on: high
+catalogers: [syft, os]
+backend: osv
+registries:
+  ca: /etc/ssl/ca.pem
+  insecure: [registry.local:5000]
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defaults, err := readDefaults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_INDEX_BACKEND", "offline")
+
+	var format, failOn, backend, registryCA string
+	var severity, catalogers, insecure []string
+	flags := pflag.NewFlagSet("sbom", pflag.ContinueOnError)
+	flags.StringVar(&format, "format", "json", "")
+	flags.StringVar(&failOn, "fail-on", "", "")
+	flags.StringVar(&backend, "backend", "atomist", "")
+	flags.StringVar(&registryCA, "registry-ca", "", "")
+	flags.StringSliceVar(&severity, "severity", nil, "")
+	flags.StringSliceVar(&catalogers, "catalogers", nil, "")
+	flags.StringSliceVar(&insecure, "insecure-registry", nil, "")
+	if err := flags.Parse([]string{"--fail-on", "critical"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyDefaults(flags, defaults); err != nil {
+		t.Fatal(err)
+	}
+
+	if format != "cyclonedx-json" || registryCA != "/etc/ssl/ca.pem" {
+		t.Errorf("expected defaults of config file, got format %s and registry ca %s", format, registryCA)
+	}
+	if failOn != "critical" {
+		t.Errorf("expected flag to take precedence, got %s", failOn)
+	}
+	if backend != "offline" {
+		t.Errorf("expected environment variable to take precedence, got %s", backend)
+	}
+	if !reflect.DeepEqual(severity, []string{"critical", "high"}) || !reflect.DeepEqual(catalogers, []string{"syft", "os"}) || !reflect.DeepEqual(insecure, []string{"registry.local:5000"}) {
+		t.Errorf("unexpected list defaults %v, %v, %v", severity, catalogers, insecure)
+	}
+}
+
+func TestApplyDefaultsReportFormat(t *testing.T) {
+	var format string
+	flags := pflag.NewFlagSet("k8s", pflag.ContinueOnError)
+	flags.StringVar(&format, "format", "table", "")
+	if err := applyDefaults(flags, flagDefaults{Format: "spdx-json"}); err != nil {
+		t.Fatal(err)
+	}
+	if format != "table" {
+		t.Errorf("expected report format to be kept, got %s", format)
+	}
+}
+
+func TestReadDefaults(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	if _, err := readDefaults(""); err != nil {
+		t.Errorf("expected missing default config file to be ignored: %s", err)
+	}
+	if _, err := readDefaults(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected missing config file passed with --config to fail")
+	}
+}
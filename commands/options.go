@@ -0,0 +1,215 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	formatUsage     = "Output format (json, spdx-json, cyclonedx-json, syft-json, sarif, html, markdown, template, junit, csv, cves-csv, github-snapshot, gitlab)"
+	catalogersUsage = "Only run the given catalogers (syft, trivy, os, java, go, javascript, python, conda, ruby, php, dart, dotnet, nix, bitnami, binary)"
+)
+
+type imageOptions struct {
+	image, ociDir, ociLayout, input, platform string
+	remote, stream                            bool
+	requireAllCatalogers, includeRemoved      bool
+	files, packageFiles, waste, leftovers     bool
+	catalogers, excludeCatalogers             []string
+	annotations                               []string
+	// options are added to the indexer options of the flags, e.g. the secret scanner of the
+	// sbom command
+	options []sbom.Option
+}
+
+// addCatalogerFlags registers the flags selecting the catalogers to run
+func (o *imageOptions) addCatalogerFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&o.catalogers, "catalogers", nil, catalogersUsage)
+	flags.StringSliceVar(&o.excludeCatalogers, "exclude-catalogers", nil, "Skip the given catalogers, e.g. java to skip scanning Java archives")
+}
+
+// addBatchFlags registers the image flags of the commands indexing many images, daemon
+// offers --remote to commands that read images from the Docker daemon by default
+func (o *imageOptions) addBatchFlags(flags *pflag.FlagSet, daemon bool) {
+	if daemon {
+		flags.BoolVar(&o.remote, "remote", false, "Pull images from registry without using the Docker daemon")
+	}
+	flags.BoolVar(&o.stream, "stream", false, "Analyze layers while pulling them from the registry instead of caching images first")
+	o.addCatalogerFlags(flags)
+	flags.StringVar(&o.platform, "platform", "", "Platform of images to index (e.g. linux/arm64)")
+}
+
+func (o imageOptions) imageRef(args []string) string {
+	if o.image == "" && len(args) == 1 {
+		return args[0]
+	}
+	return o.image
+}
+
+// checkAllPlatforms returns an error for the options --all-platforms doesn't support as it
+// pulls every platform image from the registry
+func (o imageOptions) checkAllPlatforms() error {
+	flags := []struct {
+		name string
+		set  bool
+	}{
+		{"--oci-dir", o.ociDir != ""},
+		{"--oci-layout", o.ociLayout != ""},
+		{"--input", o.input != ""},
+		{"--platform", o.platform != ""},
+		{"--stream", o.stream},
+	}
+	for _, f := range flags {
+		if f.set {
+			return errors.Errorf("--all-platforms can't be used with %s", f.name)
+		}
+	}
+	return nil
+}
+
+func (o imageOptions) indexerOptions(cli command.Cli) ([]sbom.Option, error) {
+	if err := sbom.ValidateCatalogers(append(o.catalogers, o.excludeCatalogers...)); err != nil {
+		return nil, err
+	}
+	opts := []sbom.Option{sbom.WithPlatform(o.platform), sbom.WithClient(cli.Client())}
+	opts = append(opts, indexerSettings...)
+	if len(o.catalogers) > 0 {
+		opts = append(opts, sbom.WithCatalogers(o.catalogers...))
+	}
+	if len(o.excludeCatalogers) > 0 {
+		opts = append(opts, sbom.WithoutCatalogers(o.excludeCatalogers...))
+	}
+	if o.remote {
+		opts = append(opts, sbom.WithRemote())
+	}
+	if o.stream {
+		opts = append(opts, sbom.WithStreaming())
+	}
+	if o.requireAllCatalogers {
+		opts = append(opts, sbom.WithRequireAllCatalogers())
+	}
+	if o.includeRemoved {
+		opts = append(opts, sbom.WithRemovedPackages())
+	}
+	if o.files {
+		opts = append(opts, sbom.WithFiles())
+	}
+	if o.packageFiles {
+		opts = append(opts, sbom.WithPackageFiles())
+	}
+	if o.waste {
+		opts = append(opts, sbom.WithWaste())
+	}
+	if o.leftovers {
+		opts = append(opts, sbom.WithLeftovers())
+	}
+	if len(o.annotations) > 0 {
+		annotations, err := sbom.ParseAnnotations(o.annotations)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sbom.WithAnnotations(annotations))
+	}
+	return append(opts, o.options...), nil
+}
+
+// backendOptions select the vulnerability backend to match packages against
+type backendOptions struct {
+	name    string
+	offline bool
+}
+
+func (o *backendOptions) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.offline, "offline", false, "Match packages against the local vulnerability database")
+	flags.StringVar(&o.name, "backend", query.BackendAtomist, "Vulnerability backend (atomist, osv, offline)")
+}
+
+// newBackend returns the selected backend with the credentials of the Atomist workspace of
+// docker index login; --offline selects the local database. opts are added to the backend
+// options of the persistent flags.
+func (o backendOptions) newBackend(config *configfile.ConfigFile, opts ...query.Option) (query.Backend, error) {
+	name := o.name
+	if o.offline {
+		name = query.BackendOffline
+	}
+	workspace, _ := config.PluginConfig("index", "workspace")
+	apiKey, _ := config.PluginConfig("index", "api-key")
+	return query.NewBackend(name, workspace, apiKey, append(append([]query.Option{}, querySettings...), opts...)...)
+}
+
+// batchOptions limit the concurrent and per-image work of the commands indexing many images
+type batchOptions struct {
+	parallelism int
+	timeout     time.Duration
+}
+
+func (o *batchOptions) addFlags(flags *pflag.FlagSet) {
+	flags.IntVar(&o.parallelism, "parallelism", 0, "Number of images to index at the same time, defaults to the number of CPUs")
+	flags.DurationVar(&o.timeout, "timeout", 0, "Time limit to index a single image, no limit if 0")
+}
+
+// newIndexer returns the indexer of images pulled as given by imgOpts
+func (o batchOptions) newIndexer(imgOpts imageOptions, cli command.Cli) (*sbom.Indexer, error) {
+	indexerOpts, err := imgOpts.indexerOptions(cli)
+	if err != nil {
+		return nil, err
+	}
+	if o.parallelism > 0 {
+		indexerOpts = append(indexerOpts, sbom.WithParallelism(o.parallelism))
+	}
+	return sbom.NewIndexer(indexerOpts...), nil
+}
+
+// writeReport writes report as table with writeTable or as json to output, or stdout if
+// output is empty
+func writeReport(report interface{}, format string, output string, writeTable func(io.Writer) error) error {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "", "table":
+		err = writeTable(&buf)
+	case sbom.FormatJSON:
+		err = json.NewEncoder(&buf).Encode(report)
+	default:
+		err = errors.Errorf("unsupported output format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", output)
+		}
+		log.Infof("Report written to %s", output)
+	} else {
+		os.Stdout.Write(buf.Bytes())
+	}
+	return nil
+}
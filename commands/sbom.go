@@ -0,0 +1,606 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/go-units"
+	"github.com/docker/index-cli-plugin/attest"
+	"github.com/docker/index-cli-plugin/events"
+	"github.com/docker/index-cli-plugin/history"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/metrics"
+	"github.com/docker/index-cli-plugin/policy"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/sbom/secrets"
+	"github.com/docker/index-cli-plugin/storage"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// sbomOptions are the flags of the sbom command
+type sbomOptions struct {
+	image                      imageOptions
+	backend                    backendOptions
+	output, format, groupBy    string
+	storage                    storage.Options
+	sbomFile                   string
+	allPlatforms               bool
+	baseImages                 []string
+	baseImageLabel             bool
+	scanSecrets                bool
+	secretRules                string
+	includeCves                bool
+	previousSbom               string
+	onlyNew, onlyFixed         bool
+	severity                   []string
+	minEpss                    float64
+	onlyKev                    bool
+	sortBy                     string
+	vexFiles                   []string
+	ignoreFile                 string
+	licensePolicy              string
+	packagePolicy              string
+	policies                   []string
+	failOn                     string
+	failOnEol                  bool
+	failIfWasteAbove           string
+	push, githubSubmit         bool
+	uploadTargets              []string
+	dependencyTrack            sbom.DependencyTrack
+	slackWebhook, teamsWebhook string
+	notifyThresholds           []string
+	reportUrl                  string
+	eventsUrl                  string
+	eventFindings              bool
+	jira                       sbom.Jira
+	attest, keyless            bool
+	key, identityToken         string
+	reuseAttestation           bool
+	verifyKey, verifyIdentity  string
+	verifyIssuer               string
+	// historyDb is the persistent --history-db flag
+	historyDb string
+}
+
+// runSbom indexes the image, or reads the SBOM, matches and filters its CVEs, writes and
+// publishes the SBOM and exits with status code 1 if any of the checks failed
+func runSbom(cmd *cobra.Command, args []string, opts *sbomOptions, cli command.Cli) error {
+	ctx := cmd.Context()
+	notifiers, err := opts.notifiers()
+	if err != nil {
+		return err
+	}
+	wasteThreshold, err := opts.wasteThreshold()
+	if err != nil {
+		return err
+	}
+	if err := opts.configureIndexer(); err != nil {
+		return err
+	}
+	var rules *policy.Policy
+	if len(opts.policies) > 0 {
+		if rules, err = policy.Load(opts.policies...); err != nil {
+			return err
+		}
+	}
+
+	sboms, err := readOrIndexSboms(ctx, opts, args, cli)
+	if err != nil {
+		return err
+	}
+	for _, sb := range sboms {
+		annotateSbom(sb, opts)
+	}
+	vulnerable := 0
+	if opts.includeCves || len(opts.severity) > 0 || opts.failOn != "" || opts.onlyFixed || len(opts.vexFiles) > 0 || opts.minEpss > 0 || opts.onlyKev || len(opts.policies) > 0 || opts.previousSbom != "" || opts.onlyNew || len(notifiers) > 0 || opts.jira.Url != "" {
+		ignoreFileSet := cmd.Flags().Changed("ignore-file")
+		if vulnerable, err = matchCves(ctx, sboms, opts, ignoreFileSet, cli); err != nil {
+			return err
+		}
+	}
+	if opts.historyDb != "" && opts.sbomFile == "" {
+		if err := recordScans(ctx, opts.historyDb, sboms); err != nil {
+			return err
+		}
+	}
+	if opts.onlyNew {
+		for _, sb := range sboms {
+			sb.Vulnerabilities = sbom.FilterNewCves(sb)
+		}
+	}
+
+	if opts.groupBy != "" && opts.groupBy != "layer" {
+		return errors.Errorf("unsupported --group-by value: %s", opts.groupBy)
+	}
+	if opts.groupBy == "layer" {
+		for _, sb := range sboms {
+			if err := sbom.WriteLayerView(sb, os.Stdout); err != nil {
+				return err
+			}
+		}
+	}
+	// the layer view replaces the SBOM on stdout, it's still written to --output
+	if opts.groupBy != "layer" || opts.output != "" {
+		if err := writeSboms(ctx, sboms, opts.format, opts.output, opts.storage); err != nil {
+			return err
+		}
+	}
+	if err := publishSboms(ctx, sboms, opts, notifiers); err != nil {
+		return err
+	}
+
+	fail, err := checkSboms(ctx, sboms, opts, rules, wasteThreshold, vulnerable)
+	if err != nil {
+		return err
+	}
+	if fail {
+		exit(1)
+	}
+	return nil
+}
+
+// notifiers validates the flags and returns the configured Slack and Teams notifiers
+func (o *sbomOptions) notifiers() ([]sbom.Notifier, error) {
+	if o.failOn != "" {
+		if _, err := sbom.ParseSeverity(o.failOn); err != nil {
+			return nil, err
+		}
+	}
+	if o.slackWebhook == "" {
+		o.slackWebhook = os.Getenv("SLACK_WEBHOOK_URL")
+	}
+	if o.teamsWebhook == "" {
+		o.teamsWebhook = os.Getenv("TEAMS_WEBHOOK_URL")
+	}
+	if o.jira.Token == "" {
+		o.jira.Token = os.Getenv("JIRA_API_TOKEN")
+	}
+	if o.jira.Url != "" && o.historyDb == "" {
+		return nil, errors.New("--jira-url requires --history-db to record the opened issues")
+	}
+	if o.attest {
+		if _, err := attest.PredicateType(o.format); err != nil {
+			return nil, err
+		}
+	}
+	notifiers := make([]sbom.Notifier, 0)
+	if o.slackWebhook != "" || o.teamsWebhook != "" {
+		thresholds, err := sbom.ParseNotifyThresholds(o.notifyThresholds)
+		if err != nil {
+			return nil, err
+		}
+		if o.slackWebhook != "" {
+			notifiers = append(notifiers, sbom.Notifier{Kind: sbom.NotifySlack, Url: o.slackWebhook, ReportUrl: o.reportUrl, Thresholds: thresholds})
+		}
+		if o.teamsWebhook != "" {
+			notifiers = append(notifiers, sbom.Notifier{Kind: sbom.NotifyTeams, Url: o.teamsWebhook, ReportUrl: o.reportUrl, Thresholds: thresholds})
+		}
+	}
+	return notifiers, nil
+}
+
+// wasteThreshold returns the size of --fail-if-waste-above, which implies --waste
+func (o *sbomOptions) wasteThreshold() (int64, error) {
+	if o.failIfWasteAbove == "" {
+		return 0, nil
+	}
+	threshold, err := units.FromHumanSize(o.failIfWasteAbove)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid --fail-if-waste-above size %s", o.failIfWasteAbove)
+	}
+	o.image.waste = true
+	return threshold, nil
+}
+
+// configureIndexer adds the indexer options of attestation reuse and secret scanning
+func (o *sbomOptions) configureIndexer() error {
+	if o.reuseAttestation {
+		verifier, err := newVerifier(o.verifyKey, o.verifyIdentity, o.verifyIssuer)
+		if err != nil {
+			return err
+		}
+		o.image.options = append(o.image.options, sbom.WithAttestationReuse(verifier))
+	}
+	if !o.scanSecrets {
+		o.image.options = append(o.image.options, sbom.WithSecretScanner(nil))
+	} else if o.secretRules != "" {
+		config, err := secrets.ReadConfig(o.secretRules)
+		if err != nil {
+			return err
+		}
+		scanner, err := secrets.NewScanner(config)
+		if err != nil {
+			return err
+		}
+		o.image.options = append(o.image.options, sbom.WithSecretScanner(scanner))
+	}
+	return nil
+}
+
+// readOrIndexSboms reads the SBOMs of --sbom or indexes the image, every platform image
+// with --all-platforms
+func readOrIndexSboms(ctx context.Context, opts *sbomOptions, args []string, cli command.Cli) ([]*types.Sbom, error) {
+	if opts.allPlatforms {
+		if opts.sbomFile != "" {
+			return nil, errors.New("--all-platforms can't be used with --sbom")
+		}
+		if err := opts.image.checkAllPlatforms(); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case opts.sbomFile != "":
+		return readSboms(opts.sbomFile, opts.image.platform)
+	case opts.allPlatforms:
+		indexerOpts, err := opts.image.indexerOptions(cli)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		results, err := sbom.NewIndexer(indexerOpts...).IndexAllPlatforms(ctx, opts.image.imageRef(args))
+		metrics.ObserveScan(start, err)
+		if err != nil {
+			return nil, err
+		}
+		sboms := make([]*types.Sbom, 0)
+		for _, r := range results {
+			sboms = append(sboms, r.Sbom)
+		}
+		return sboms, nil
+	default:
+		sb, _, err := indexImage(ctx, opts.image, args, cli)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.Sbom{sb}, nil
+	}
+}
+
+// annotateSbom detects the base image and end of life of sb and logs the findings about
+// the image
+func annotateSbom(sb *types.Sbom, opts *sbomOptions) {
+	if failed := sb.FailedCatalogers(); len(failed) > 0 {
+		log.Warnf("SBOM is missing packages of failed catalogers %s", strings.Join(failed, ", "))
+	}
+	sbom.DetectBaseImage(sb, opts.baseImages, opts.baseImageLabel)
+	sbom.AnnotateEol(sb, time.Now())
+	warnEol(sb)
+	for _, f := range sb.ConfigFindings {
+		log.Warnf("Config %s (%s): %s", f.Check, strings.ToLower(f.Severity), f.Message)
+	}
+	for _, l := range sb.Leftovers {
+		log.Warnf("Leftover %s (%s): %s", l.Check, sbom.LeftoverSize(l), l.Message)
+	}
+}
+
+// matchCves queries the CVEs of sboms, applies the VEX statements and ignore file, filters
+// them by the flags and annotates the delta to the previous scan. It returns the number of
+// CVEs at or above --fail-on before they were filtered.
+func matchCves(ctx context.Context, sboms []*types.Sbom, opts *sbomOptions, ignoreFileSet bool, cli command.Cli) (int, error) {
+	vexStatements := make([]sbom.VexStatement, 0)
+	for _, f := range opts.vexFiles {
+		statements, err := sbom.ReadVex(f)
+		if err != nil {
+			return 0, err
+		}
+		vexStatements = append(vexStatements, statements...)
+	}
+	var ignore *sbom.IgnoreFile
+	if _, err := os.Stat(opts.ignoreFile); err == nil || ignoreFileSet {
+		ignore, err = sbom.ReadIgnoreFile(opts.ignoreFile)
+		if err != nil {
+			return 0, err
+		}
+	}
+	queryOpts := make([]query.Option, 0)
+	if opts.minEpss > 0 || opts.onlyKev || opts.sortBy == "epss" || opts.sortBy == "kev" {
+		queryOpts = append(queryOpts, query.WithEnrichment())
+	}
+	b, err := opts.backend.newBackend(cli.ConfigFile(), queryOpts...)
+	if err != nil {
+		return 0, err
+	}
+	vulnerable := 0
+	for _, sb := range sboms {
+		cves, err := queryCves(ctx, sb, "", b)
+		if err != nil {
+			return 0, err
+		}
+		if cves != nil {
+			sb.Vulnerabilities = sbom.ApplyVex(*cves, vexStatements, sb.Source.Image)
+			sb.Vulnerabilities = sbom.ApplyIgnoreFile(sb.Vulnerabilities, ignore, time.Now())
+			sbom.SortSbom(sb)
+		}
+		// --fail-on applies to all CVEs not excluded by VEX or the ignore file, not only
+		// to the ones selected for display
+		if opts.failOn != "" {
+			above, err := sbom.CvesAboveThreshold(sb.Vulnerabilities, opts.failOn)
+			if err != nil {
+				return 0, err
+			}
+			vulnerable += len(above)
+		}
+		if err := filterCves(sb, opts); err != nil {
+			return 0, err
+		}
+		if base := sb.Source.Image.BaseImage; base != nil {
+			inherited, introduced := sbom.SplitCves(sb)
+			log.Infof("%d vulnerabilities inherited from base image %s, %d introduced by build", len(inherited), base.Name, len(introduced))
+		}
+		previous, err := previousScan(ctx, sb, opts.previousSbom, opts.historyDb)
+		if err != nil {
+			return 0, err
+		}
+		if previous != nil {
+			sbom.AnnotateDelta(sb, previous)
+			log.Infof("%d new and %d resolved vulnerabilities since the scan of %s@%s", len(sb.Delta.New), len(sb.Delta.Resolved), previous.Source.Image.Name, previous.Source.Image.Digest)
+		}
+	}
+	return vulnerable, nil
+}
+
+// filterCves filters and sorts the CVEs of sb as selected by the flags
+func filterCves(sb *types.Sbom, opts *sbomOptions) error {
+	var err error
+	sb.Vulnerabilities, err = sbom.FilterCvesBySeverity(sb.Vulnerabilities, opts.severity)
+	if err != nil {
+		return err
+	}
+	if opts.onlyFixed {
+		sb.Vulnerabilities = sbom.FilterFixedCves(sb.Vulnerabilities)
+	}
+	if opts.minEpss > 0 {
+		sb.Vulnerabilities = sbom.FilterCvesByEpss(sb.Vulnerabilities, opts.minEpss)
+	}
+	if opts.onlyKev {
+		sb.Vulnerabilities = sbom.FilterKnownExploitedCves(sb.Vulnerabilities)
+	}
+	return sbom.SortCves(sb.Vulnerabilities, opts.sortBy)
+}
+
+// publishSboms pushes, attests and uploads sboms and sends the notifications and events
+func publishSboms(ctx context.Context, sboms []*types.Sbom, opts *sbomOptions, notifiers []sbom.Notifier) error {
+	if opts.push {
+		for _, sb := range sboms {
+			var buf bytes.Buffer
+			if err := sbom.WriteFormat(sb, opts.format, &buf, writeSettings...); err != nil {
+				return err
+			}
+			digest, err := registry.PushReferrer(ctx, sb.Source.Image.Name, sb.Source.Image.Digest, buf.Bytes(), sbom.MediaType(opts.format))
+			if err != nil {
+				return errors.Wrap(err, "failed to push SBOM")
+			}
+			log.Infof("SBOM pushed to %s@%s", sb.Source.Image.Name, digest)
+		}
+	}
+
+	if opts.attest {
+		signer, err := newSigner(opts.key, opts.keyless, opts.identityToken)
+		if err != nil {
+			return err
+		}
+		for _, sb := range sboms {
+			if err := attachAttestation(ctx, sb, opts.format, signer); err != nil {
+				return err
+			}
+			log.Infof("SBOM attestation attached to %s@%s", sb.Source.Image.Name, sb.Source.Image.Digest)
+		}
+	}
+
+	for _, target := range opts.uploadTargets {
+		switch target {
+		case sbom.UploadDependencyTrack:
+			if opts.dependencyTrack.ApiKey == "" {
+				opts.dependencyTrack.ApiKey = os.Getenv("DT_API_KEY")
+			}
+			for _, sb := range sboms {
+				token, err := opts.dependencyTrack.Upload(ctx, sb)
+				if err != nil {
+					return err
+				}
+				log.Infof("SBOM of %s uploaded to Dependency-Track (token %s)", sb.Source.Image.Name, token)
+			}
+		default:
+			return errors.Errorf("unsupported --upload target: %s", target)
+		}
+	}
+	if opts.githubSubmit {
+		repository := os.Getenv("GITHUB_REPOSITORY")
+		for _, sb := range sboms {
+			snapshot := sbom.ToGitHubSnapshot(sb, time.Now())
+			if err := sbom.SubmitGitHubSnapshot(ctx, snapshot, repository, os.Getenv("GITHUB_TOKEN")); err != nil {
+				return err
+			}
+			log.Infof("Dependency snapshot of %s submitted to %s", sb.Source.Image.Name, repository)
+		}
+	}
+
+	for _, n := range notifiers {
+		for _, sb := range sboms {
+			sent, err := n.Notify(ctx, sb)
+			if err != nil {
+				return err
+			}
+			if sent {
+				log.Infof("Summary of %s sent to %s", sb.Source.Image.Name, n.Kind)
+			}
+		}
+	}
+
+	if opts.eventsUrl != "" {
+		publisher, err := events.Open(opts.eventsUrl)
+		if err != nil {
+			return err
+		}
+		defer publisher.Close()
+		for _, sb := range sboms {
+			if err := publisher.Publish(ctx, sb.Source.Image.Digest, events.NewEvents(sb, time.Now(), opts.eventFindings)); err != nil {
+				return err
+			}
+			log.Infof("Scan events of %s sent to %s", sb.Source.Image.Name, opts.eventsUrl)
+		}
+	}
+	if opts.jira.Url != "" {
+		store, err := history.Open(opts.historyDb)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		for _, sb := range sboms {
+			issues, err := opts.jira.OpenIssues(ctx, sb, store)
+			for _, i := range issues {
+				log.Infof("Opened Jira issue %s for %s in %s", i.Key, i.Cve, sb.Source.Image.Name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkSboms evaluates the license, package and Rego policies, the end of life, wasted
+// space and severity checks and returns true if any of them failed; vulnerable is the
+// number of CVEs at or above --fail-on counted by matchCves
+func checkSboms(ctx context.Context, sboms []*types.Sbom, opts *sbomOptions, rules *policy.Policy, wasteThreshold int64, vulnerable int) (bool, error) {
+	licensesDenied, err := checkLicenses(sboms, opts.licensePolicy)
+	if err != nil {
+		return false, err
+	}
+	packagesDenied, err := checkPackages(sboms, opts.packagePolicy)
+	if err != nil {
+		return false, err
+	}
+	policiesFailed, err := evaluatePolicies(ctx, sboms, rules)
+	if err != nil {
+		return false, err
+	}
+	eol := opts.failOnEol && len(sbom.EndOfLife(sboms)) > 0
+	wasted := checkWaste(sboms, wasteThreshold)
+	return licensesDenied || packagesDenied || policiesFailed || eol || wasted || checkSeverity(vulnerable, opts.failOn), nil
+}
+
+func checkLicenses(sboms []*types.Sbom, path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	policy, err := sbom.ReadLicensePolicy(path)
+	if err != nil {
+		return false, err
+	}
+	denied := 0
+	for _, sb := range sboms {
+		for _, v := range sbom.CheckLicensePolicy(sb, policy) {
+			if v.Action == sbom.LicenseDeny {
+				log.Warnf("Denied license %s in %s", v.License, v.Purl)
+				denied++
+			} else {
+				log.Warnf("Flagged license %s in %s", v.License, v.Purl)
+			}
+		}
+	}
+	if denied > 0 {
+		log.Warnf("Detected %d packages with denied licenses", denied)
+	}
+	return denied > 0, nil
+}
+
+func checkPackages(sboms []*types.Sbom, path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	policy, err := sbom.ReadPackagePolicy(path)
+	if err != nil {
+		return false, err
+	}
+	denied := 0
+	for _, sb := range sboms {
+		for _, v := range sbom.CheckPackagePolicy(sb, policy) {
+			log.Warnf("Denied package %s by rule %s", v.Purl, v.Rule)
+			denied++
+		}
+	}
+	if denied > 0 {
+		log.Warnf("Detected %d denied packages", denied)
+	}
+	return denied > 0, nil
+}
+
+func evaluatePolicies(ctx context.Context, sboms []*types.Sbom, rules *policy.Policy) (bool, error) {
+	if rules == nil {
+		return false, nil
+	}
+	fail := false
+	for _, sb := range sboms {
+		results, err := rules.Evaluate(ctx, sb)
+		if err != nil {
+			return false, err
+		}
+		for _, r := range results {
+			for _, w := range r.Warnings {
+				log.Warnf("Policy %s: %s", r.Name(), w)
+			}
+			if r.Passed() {
+				log.Infof("Policy %s passed", r.Name())
+				continue
+			}
+			for _, v := range r.Violations {
+				log.Warnf("Policy %s failed: %s", r.Name(), v)
+			}
+		}
+		if failed := policy.Failed(results); len(failed) > 0 {
+			log.Warnf("%d of %d policies failed for %s", len(failed), len(results), sb.Source.Image.Name)
+			fail = true
+		}
+	}
+	return fail, nil
+}
+
+func checkWaste(sboms []*types.Sbom, threshold int64) bool {
+	if threshold <= 0 {
+		return false
+	}
+	fail := false
+	for _, sb := range sboms {
+		if sb.Waste == nil {
+			log.Warnf("No wasted space analysis in SBOM of %s", sb.Source.Image.Name)
+			continue
+		}
+		if sb.Waste.Total > threshold {
+			log.Warnf("Wasted space of %s exceeds %s", units.HumanSize(float64(sb.Waste.Total)), units.HumanSize(float64(threshold)))
+			fail = true
+		}
+	}
+	return fail
+}
+
+func checkSeverity(vulnerable int, failOn string) bool {
+	if vulnerable > 0 {
+		log.Warnf("Detected %d vulnerabilities with severity %s or higher", vulnerable, strings.ToLower(failOn))
+	}
+	return vulnerable > 0
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compose
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Service is a service of a compose project that runs an image
+type Service struct {
+	Name  string `json:"service"`
+	Image string `json:"image"`
+}
+
+type composeFile struct {
+	Services map[string]struct {
+		Image    string      `yaml:"image"`
+		Build    interface{} `yaml:"build"`
+		Profiles []string    `yaml:"profiles"`
+	} `yaml:"services"`
+}
+
+type service struct {
+	image    string
+	build    bool
+	profiles []string
+}
+
+// variable matches $$, $VAR, ${VAR}, ${VAR:-default}, ${VAR-default}, ${VAR:?error} and ${VAR?error}
+var variable = regexp.MustCompile(`\$(?:\$|([A-Za-z_][A-Za-z0-9_]*)|\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-?])([^}]*))?\})`)
+
+// DefaultFiles returns the compose file docker compose uses in dir with its override file if present
+func DefaultFiles(dir string) ([]string, error) {
+	for _, name := range []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		files := []string{path}
+		ext := filepath.Ext(name)
+		override := filepath.Join(dir, strings.TrimSuffix(name, ext)+".override"+ext)
+		if _, err := os.Stat(override); err == nil {
+			files = append(files, override)
+		}
+		return files, nil
+	}
+	return nil, errors.Errorf("no compose file found in %s", dir)
+}
+
+// LoadServices reads the compose files, later files overriding earlier ones, and returns the
+// services of the active profiles with their interpolated image references. Variables are
+// read from the environment and the .env file next to the first compose file. Services
+// that are only built without an image name are skipped.
+func LoadServices(files []string, profiles []string) ([]Service, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no compose file given")
+	}
+	env, err := readEnv(filepath.Join(filepath.Dir(files[0]), ".env"))
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		if p := env["COMPOSE_PROFILES"]; p != "" {
+			profiles = strings.Split(p, ",")
+		}
+	}
+
+	services := make(map[string]*service)
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read compose file %s", f)
+		}
+		var cf composeFile
+		if err := yaml.Unmarshal(b, &cf); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse compose file %s", f)
+		}
+		for name, s := range cf.Services {
+			svc, ok := services[name]
+			if !ok {
+				svc = &service{}
+				services[name] = svc
+			}
+			if s.Image != "" {
+				svc.image, err = interpolate(s.Image, env)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to interpolate image of service %s", name)
+				}
+			}
+			if s.Build != nil {
+				svc.build = true
+			}
+			if s.Profiles != nil {
+				svc.profiles = s.Profiles
+			}
+		}
+	}
+
+	result := make([]Service, 0)
+	for name, s := range services {
+		if !active(s.profiles, profiles) {
+			continue
+		}
+		if s.image == "" {
+			if s.build {
+				log.Infof("Skipping service %s that is built without an image name", name)
+			}
+			continue
+		}
+		result = append(result, Service{Name: name, Image: s.image})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// active reports if a service with the given profiles runs; services without profiles always run
+func active(service []string, enabled []string) bool {
+	if len(service) == 0 {
+		return true
+	}
+	for _, p := range service {
+		for _, e := range enabled {
+			if p == e || e == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// interpolate replaces the variables in value like docker compose does
+func interpolate(value string, env map[string]string) (string, error) {
+	var err error
+	result := variable.ReplaceAllStringFunc(value, func(m string) string {
+		if m == "$$" {
+			return "$"
+		}
+		groups := variable.FindStringSubmatch(m)
+		name := groups[1] + groups[2]
+		v, set := env[name]
+		switch groups[3] {
+		case ":-":
+			if v == "" {
+				return groups[4]
+			}
+		case "-":
+			if !set {
+				return groups[4]
+			}
+		case ":?":
+			if v == "" {
+				err = errors.Errorf("required variable %s is missing a value: %s", name, groups[4])
+			}
+		case "?":
+			if !set {
+				err = errors.Errorf("required variable %s is missing a value: %s", name, groups[4])
+			}
+		}
+		return v
+	})
+	return result, err
+}
+
+// readEnv returns the environment with the variables of the env file at path added; variables
+// of the environment take precedence
+func readEnv(path string) (map[string]string, error) {
+	env := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, _ := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+			value = strings.TrimSpace(value)
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+			env[strings.TrimSpace(key)] = value
+		}
+	}
+	for _, e := range os.Environ() {
+		key, value, _ := strings.Cut(e, "=")
+		env[key] = value
+	}
+	return env, nil
+}
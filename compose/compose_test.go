@@ -0,0 +1,74 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadServices(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, ".env"), []byte("TAG=1.2\nREGISTRY=registry.example.com\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(`
+services:
+  web:
+    image: ${REGISTRY}/web:${TAG}
+  db:
+    image: postgres:${PG_VERSION:-15}
+  debug:
+    image: busybox
+    profiles: [debug]
+  worker:
+    build: ./worker
+`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "docker-compose.override.yml"), []byte(`
+services:
+  db:
+    image: postgres:14
+`), 0644)
+	t.Setenv("TAG", "1.3")
+
+	files := []string{filepath.Join(dir, "docker-compose.yml"), filepath.Join(dir, "docker-compose.override.yml")}
+	services, err := LoadServices(files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Service{{Name: "db", Image: "postgres:14"}, {Name: "web", Image: "registry.example.com/web:1.3"}}
+	if len(services) != len(expected) || services[0] != expected[0] || services[1] != expected[1] {
+		t.Errorf("expected services %v, got %v", expected, services)
+	}
+
+	services, err = LoadServices(files[:1], []string{"debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 3 || services[0].Image != "postgres:15" || services[1].Name != "debug" {
+		t.Errorf("expected services of debug profile and default values, got %v", services)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{"EMPTY": ""}
+	if _, err := interpolate("app:${EMPTY:?tag required}", env); err == nil {
+		t.Error("expected missing required variable to fail")
+	}
+	if v, _ := interpolate("app:${EMPTY-1.0}$$", env); v != "app:$" {
+		t.Errorf("expected set variable and escaped dollar, got %s", v)
+	}
+}
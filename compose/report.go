@@ -0,0 +1,98 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compose
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/sbom"
+)
+
+// Report is the combined vulnerability report of the services of a compose project
+type Report struct {
+	// Vulnerabilities counts the CVEs of all distinct images by severity
+	Vulnerabilities map[string]int  `json:"vulnerabilities"`
+	Services        []ServiceReport `json:"services"`
+}
+
+type ServiceReport struct {
+	Service  string `json:"service"`
+	Image    string `json:"image"`
+	Packages int    `json:"packages"`
+	// Vulnerabilities counts the CVEs of the image by severity
+	Vulnerabilities map[string]int `json:"vulnerabilities"`
+	Cves            []string       `json:"cves"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// Scan indexes the images of the services concurrently, every image once, and queries their
+// CVEs from backend
+func Scan(ctx context.Context, services []Service, indexer *sbom.Indexer, backend query.Backend, opts sbom.BatchOptions) *Report {
+	images := make([]string, 0)
+	for _, s := range services {
+		if !internal.Contains(images, s.Image) {
+			images = append(images, s.Image)
+		}
+	}
+	log.Infof("Indexing %d images of %d services", len(images), len(services))
+	results := indexer.IndexImages(ctx, images, opts)
+
+	report := Report{Vulnerabilities: make(map[string]int), Services: make([]ServiceReport, 0)}
+	byImage := make(map[string]ServiceReport)
+	for i, image := range images {
+		sr := imageReport(ctx, image, results[i], backend)
+		for severity, count := range sr.Vulnerabilities {
+			report.Vulnerabilities[severity] += count
+		}
+		byImage[image] = sr
+	}
+	for _, s := range services {
+		sr := byImage[s.Image]
+		sr.Service = s.Name
+		report.Services = append(report.Services, sr)
+	}
+	return &report
+}
+
+func imageReport(ctx context.Context, image string, result sbom.ImageIndexResult, backend query.Backend) ServiceReport {
+	sr := ServiceReport{Image: image, Vulnerabilities: make(map[string]int), Cves: make([]string, 0)}
+	if result.IndexError != nil {
+		log.Warnf("Failed to index %s: %s", image, result.IndexError)
+		sr.Error = result.IndexError.Error()
+		return sr
+	}
+	sr.Packages = len(result.Sbom.Artifacts)
+	cves, err := backend.QueryCves(ctx, result.Sbom, "")
+	if err != nil {
+		log.Warnf("Failed to query CVEs of %s: %s", image, err)
+		sr.Error = err.Error()
+		return sr
+	}
+	if cves == nil {
+		return sr
+	}
+	for _, c := range *cves {
+		sr.Vulnerabilities[sbom.Severity(c)]++
+		sr.Cves = append(sr.Cves, c.SourceId)
+	}
+	sort.Strings(sr.Cves)
+	return sr
+}
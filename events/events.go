@@ -0,0 +1,160 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TypeScanCompleted is the type of the event sent once an image was scanned
+	TypeScanCompleted = "com.docker.index.scan.completed"
+	// TypeFinding is the type of the events sent for every vulnerability of a scanned image
+	TypeFinding = "com.docker.index.finding"
+)
+
+// Envelope wraps events in the CloudEvents 1.0 JSON format
+type Envelope struct {
+	SpecVersion     string      `json:"specversion"`
+	Id              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// ScanCompleted is the data of scan completed events
+type ScanCompleted struct {
+	Image    string `json:"image"`
+	Digest   string `json:"digest"`
+	Platform string `json:"platform"`
+	Packages int    `json:"packages"`
+	// Vulnerabilities counts the CVEs by severity, ignoring suppressed ones and those a VEX
+	// statement declared as not affected
+	Vulnerabilities map[string]int `json:"vulnerabilities"`
+	// New and Resolved count the CVEs changed since the previous scan, if compared with one
+	New      *int `json:"new,omitempty"`
+	Resolved *int `json:"resolved,omitempty"`
+}
+
+// Finding is the data of finding events
+type Finding struct {
+	Image          string   `json:"image"`
+	Digest         string   `json:"digest"`
+	Platform       string   `json:"platform"`
+	Cve            string   `json:"cve"`
+	Purl           string   `json:"purl"`
+	Severity       string   `json:"severity"`
+	FixedBy        string   `json:"fixed_by,omitempty"`
+	KnownExploited bool     `json:"known_exploited"`
+	Epss           *float64 `json:"epss,omitempty"`
+	New            bool     `json:"new"`
+}
+
+// Publisher sends events to a Kafka topic or NATS subject
+type Publisher interface {
+	// Publish sends the events keyed by key, the image digest, so events of an image stay in order
+	Publish(ctx context.Context, key string, events []Envelope) error
+	Close() error
+}
+
+// Open returns the publisher of location, kafka://broker[,broker]/topic or nats://server/subject
+func Open(location string) (Publisher, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid event destination %s", location)
+	}
+	switch u.Scheme {
+	case "kafka":
+		return newKafkaPublisher(u)
+	case "nats", "tls":
+		return newNatsPublisher(u)
+	}
+	return nil, errors.Errorf("unsupported event destination %s", location)
+}
+
+// NewEvents returns the scan completed event of sb, followed by a finding event per vulnerability
+// if findings is set
+func NewEvents(sb *types.Sbom, now time.Time, findings bool) []Envelope {
+	image := sb.Source.Image
+	subject := image.Name + "@" + image.Digest
+	envelope := func(eventType string, data interface{}) Envelope {
+		return Envelope{
+			SpecVersion:     "1.0",
+			Id:              uuid.NewString(),
+			Source:          "docker-index/" + internal.FromBuild().Version,
+			Type:            eventType,
+			Subject:         subject,
+			Time:            now.UTC(),
+			DataContentType: "application/json",
+			Data:            data,
+		}
+	}
+
+	completed := ScanCompleted{
+		Image:           image.Name,
+		Digest:          image.Digest,
+		Platform:        image.Platform.String(),
+		Packages:        len(sb.Artifacts),
+		Vulnerabilities: make(map[string]int),
+	}
+	if d := sb.Delta; d != nil {
+		n, r := len(d.New), len(d.Resolved)
+		completed.New, completed.Resolved = &n, &r
+	}
+	cves := make([]types.Cve, 0)
+	for _, c := range sb.Vulnerabilities {
+		if sbom.IsAffected(c) && c.Suppression == nil {
+			completed.Vulnerabilities[sbom.Severity(c)]++
+			cves = append(cves, c)
+		}
+	}
+	events := []Envelope{envelope(TypeScanCompleted, completed)}
+	if !findings {
+		return events
+	}
+	for _, c := range cves {
+		f := Finding{
+			Image:          image.Name,
+			Digest:         image.Digest,
+			Platform:       image.Platform.String(),
+			Cve:            c.SourceId,
+			Purl:           c.Purl,
+			Severity:       sbom.Severity(c),
+			KnownExploited: c.KnownExploited,
+			New:            sbom.IsNew(sb, c),
+		}
+		if sbom.IsFixed(c) {
+			f.FixedBy = c.FixedBy
+		}
+		if c.Epss != nil {
+			f.Epss = &c.Epss.Score
+		}
+		events = append(events, envelope(TypeFinding, f))
+	}
+	return events
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// newKafkaPublisher writes to the topic of u on the comma separated brokers of its host,
+// authenticating with SASL/PLAIN if u has credentials and using TLS with ?tls=true
+func newKafkaPublisher(u *url.URL) (Publisher, error) {
+	topic := strings.Trim(u.Path, "/")
+	if u.Host == "" || topic == "" {
+		return nil, errors.Errorf("invalid Kafka destination %s: expected kafka://broker[,broker]/topic", u.Redacted())
+	}
+	transport := &kafka.Transport{}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		transport.SASL = plain.Mechanism{Username: u.User.Username(), Password: password}
+	}
+	if u.Query().Get("tls") == "true" {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return &kafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(strings.Split(u.Host, ",")...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		Transport:    transport,
+	}}, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, key string, events []Envelope) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, e := range events {
+		js, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Key:     []byte(key),
+			Value:   js,
+			Headers: []kafka.Header{{Key: "ce_type", Value: []byte(e.Type)}, {Key: "content-type", Value: []byte("application/cloudevents+json")}},
+		})
+	}
+	return errors.Wrapf(p.writer.WriteMessages(ctx, messages...), "failed to write events to Kafka topic %s", p.writer.Topic)
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
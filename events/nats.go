@@ -0,0 +1,80 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+)
+
+// flushTimeout is how long to wait for the server to acknowledge published events
+const flushTimeout = 30 * time.Second
+
+type natsPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// newNatsPublisher publishes to the subject of u on its server, with the credentials of u or the
+// credentials file in NATS_CREDS
+func newNatsPublisher(u *url.URL) (Publisher, error) {
+	subject := strings.Trim(u.Path, "/")
+	if u.Host == "" || subject == "" {
+		return nil, errors.Errorf("invalid NATS destination %s: expected nats://server/subject", u.Redacted())
+	}
+	opts := []nats.Option{nats.Name("docker-index")}
+	if creds := os.Getenv("NATS_CREDS"); creds != "" {
+		opts = append(opts, nats.UserCredentials(creds))
+	}
+	server := *u
+	server.Path, server.RawQuery = "", ""
+	conn, err := nats.Connect(server.String(), opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to NATS server %s", u.Host)
+	}
+	return &natsPublisher{conn: conn, subject: subject}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, key string, events []Envelope) error {
+	for _, e := range events {
+		js, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := p.conn.Publish(p.subject, js); err != nil {
+			return errors.Wrapf(err, "failed to publish event to NATS subject %s", p.subject)
+		}
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flushTimeout)
+		defer cancel()
+	}
+	return errors.Wrapf(p.conn.FlushWithContext(ctx), "failed to publish events to NATS subject %s", p.subject)
+}
+
+func (p *natsPublisher) Close() error {
+	p.conn.Close()
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package explore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/pkg/errors"
+)
+
+const help = "tab switch pane  1-3 view  / filter  s sort  q quit"
+
+// Run shows the explorer for sb in the terminal until the user quits
+func Run(sb *types.Sbom) error {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return errors.Wrap(err, "failed to open terminal")
+	}
+	if err := screen.Init(); err != nil {
+		return errors.Wrap(err, "failed to initialize terminal")
+	}
+	defer screen.Fini()
+	run(New(sb), screen)
+	return nil
+}
+
+func run(m *Model, screen tcell.Screen) {
+	for {
+		m.Draw(screen)
+		screen.Show()
+		switch ev := screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			screen.Sync()
+		case *tcell.EventKey:
+			if !m.Handle(ev) {
+				return
+			}
+		case nil:
+			return
+		}
+	}
+}
+
+// Draw renders the layer list on the left, the current view on the right, and the details of
+// the selected row and the key help at the bottom
+func (m *Model) Draw(s tcell.Screen) {
+	s.Clear()
+	w, h := s.Size()
+	bold := tcell.StyleDefault.Bold(true)
+	img := m.sb.Source.Image
+	drawText(s, 0, 0, w, fmt.Sprintf("%s  %s  %d packages  %d vulnerabilities", img.Name, img.Digest, len(m.sb.Artifacts), len(m.sb.Vulnerabilities)), bold)
+
+	left := w * 2 / 5
+	if left > 60 {
+		left = 60
+	}
+	m.height = h - 5
+	if m.height < 1 {
+		m.height = 1
+	}
+
+	layers := make([]row, 0, len(m.layers)+1)
+	layers = append(layers, row{cells: []string{fmt.Sprintf("All layers (%d)", len(m.layers))}, style: tcell.StyleDefault})
+	for _, l := range m.layers {
+		style := tcell.StyleDefault
+		if l.base {
+			style = style.Foreground(tcell.ColorGray)
+		}
+		layers = append(layers, row{cells: []string{fmt.Sprintf("%2d %8s %4d %s", l.ordinal, units.HumanSize(float64(l.size)), l.packages, l.createdBy)}, style: style})
+	}
+	drawText(s, 0, 2, left-1, "Layers", m.titleStyle(0))
+	m.drawRows(s, 0, 0, 3, left-1, layers)
+
+	tabs := make([]string, len(viewNames))
+	for i, n := range viewNames {
+		if view(i) == m.view {
+			n = "[" + n + "]"
+		}
+		tabs[i] = fmt.Sprintf("%d %s", i+1, n)
+	}
+	drawText(s, left, 2, w-left, fmt.Sprintf("%s   sort: %s", strings.Join(tabs, "  "), sortKeys[m.view][m.sort[m.view]]), m.titleStyle(1))
+	cols := columns[m.view]
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.title
+	}
+	rows := m.Rows()
+	drawCells(s, left, 3, w-left, cols, header, bold.Underline(true))
+	m.drawTable(s, left, 4, w-left, cols, rows)
+
+	detail := ""
+	if c := m.cursor[m.view+1]; c < len(rows) {
+		detail = rows[c].detail
+	}
+	drawText(s, 0, h-2, w, detail, tcell.StyleDefault)
+	status := help
+	if m.typing || m.filter != "" {
+		status = "filter: " + m.filter
+		if m.typing {
+			status += "_"
+		}
+	}
+	drawText(s, 0, h-1, w, status, tcell.StyleDefault.Reverse(true))
+}
+
+func (m *Model) titleStyle(pane int) tcell.Style {
+	style := tcell.StyleDefault.Bold(true)
+	if m.focus == pane {
+		style = style.Foreground(tcell.ColorGreen)
+	}
+	return style
+}
+
+func (m *Model) drawRows(s tcell.Screen, pane int, index int, y int, width int, rows []row) {
+	for i, r := range m.visible(pane, index, rows) {
+		drawText(s, 0, y+i, width, fmt.Sprintf("%-*s", width, r.cells[0]), r.style)
+	}
+}
+
+func (m *Model) drawTable(s tcell.Screen, x int, y int, width int, cols []column, rows []row) {
+	for i, r := range m.visible(1, int(m.view)+1, rows) {
+		drawCells(s, x, y+i, width, cols, r.cells, r.style)
+	}
+}
+
+// visible scrolls the list so its cursor is shown and returns the rows that fit the height,
+// highlighting the row under the cursor
+func (m *Model) visible(pane int, index int, rows []row) []row {
+	height := m.height
+	if pane == 1 {
+		height--
+	}
+	if m.cursor[index] >= len(rows) {
+		m.cursor[index] = len(rows) - 1
+	}
+	if m.cursor[index] < 0 {
+		m.cursor[index] = 0
+	}
+	if m.cursor[index] < m.offset[index] {
+		m.offset[index] = m.cursor[index]
+	}
+	if m.cursor[index] >= m.offset[index]+height {
+		m.offset[index] = m.cursor[index] - height + 1
+	}
+	end := m.offset[index] + height
+	if end > len(rows) {
+		end = len(rows)
+	}
+	if m.offset[index] >= end {
+		return nil
+	}
+	visible := make([]row, 0, end-m.offset[index])
+	for i := m.offset[index]; i < end; i++ {
+		r := rows[i]
+		if i == m.cursor[index] {
+			if m.focus == pane {
+				r.style = r.style.Reverse(true)
+			} else {
+				r.style = r.style.Bold(true)
+			}
+		}
+		visible = append(visible, r)
+	}
+	return visible
+}
+
+// drawCells draws cells in the columns, giving the column of width 0 the space left by the others
+func drawCells(s tcell.Screen, x int, y int, width int, cols []column, cells []string, style tcell.Style) {
+	fixed := 0
+	for _, c := range cols {
+		fixed += c.width + 1
+	}
+	for i, c := range cols {
+		cw := c.width
+		if cw == 0 {
+			cw = width - fixed
+			if cw < 8 {
+				cw = 8
+			}
+		}
+		drawText(s, x, y, cw, fmt.Sprintf("%-*s", cw, cells[i]), style)
+		s.SetContent(x+cw, y, ' ', nil, style)
+		x += cw + 1
+	}
+}
+
+// drawText draws text from x to at most x+width, truncating it with an ellipsis
+func drawText(s tcell.Screen, x int, y int, width int, text string, style tcell.Style) {
+	r := []rune(text)
+	if len(r) > width && width > 0 {
+		r = append(r[:width-1], '…')
+	}
+	for i, c := range r {
+		if i >= width {
+			break
+		}
+		s.SetContent(x+i, y, c, nil, style)
+	}
+}
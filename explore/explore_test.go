@@ -0,0 +1,129 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package explore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/gdamore/tcell/v2"
+)
+
+func testSbom() *types.Sbom {
+	base := &types.Layer{Ordinal: 0, Digest: "sha256:base", CreatedBy: "ADD rootfs.tar /"}
+	app := &types.Layer{Ordinal: 1, Digest: "sha256:app", CreatedBy: "RUN npm install"}
+	return &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{Name: "example:latest", Digest: "sha256:image"}},
+		Artifacts: []types.Package{
+			{Type: "apk", Name: "musl", Version: "1.2.3", Purl: "pkg:apk/alpine/musl@1.2.3", Layer: base,
+				Locations: []types.Location{{Path: "/lib/apk/db/installed"}}},
+			{Type: "npm", Name: "lodash", Version: "4.17.20", Purl: "pkg:npm/lodash@4.17.20", Layer: app,
+				Locations: []types.Location{{Path: "/app/node_modules/lodash/package.json"}}},
+			{Type: "npm", Name: "express", Version: "4.18.0", Purl: "pkg:npm/express@4.18.0", Layer: app,
+				Locations: []types.Location{{Path: "/app/node_modules/express/package.json"}}},
+		},
+		Vulnerabilities: []types.Cve{
+			{Purl: "pkg:npm/lodash@4.17.20", SourceId: "CVE-2021-23337", FixedBy: "4.17.21", Advisory: &types.Advisory{}},
+			{Purl: "pkg:apk/alpine/musl@1.2.3", SourceId: "CVE-2020-28928"},
+		},
+	}
+}
+
+func key(m *Model, k tcell.Key, r rune) bool {
+	return m.Handle(tcell.NewEventKey(k, r, tcell.ModNone))
+}
+
+func names(rows []row) []string {
+	n := make([]string, len(rows))
+	for i, r := range rows {
+		n[i] = r.cells[0]
+	}
+	return n
+}
+
+func TestLayers(t *testing.T) {
+	m := New(testSbom())
+	if len(m.layers) != 2 || m.layers[1].packages != 2 || m.layers[1].createdBy != "RUN npm install" {
+		t.Fatalf("unexpected layers: %+v", m.layers)
+	}
+	if got := strings.Join(names(m.Rows()), ","); got != "express,lodash,musl" {
+		t.Errorf("expected all packages, got %s", got)
+	}
+	key(m, tcell.KeyDown, 0)
+	key(m, tcell.KeyDown, 0)
+	if got := strings.Join(names(m.Rows()), ","); got != "express,lodash" {
+		t.Errorf("expected packages of layer 1, got %s", got)
+	}
+	key(m, tcell.KeyRune, '2')
+	if got := strings.Join(names(m.Rows()), ","); got != "/app/node_modules/express/package.json,/app/node_modules/lodash/package.json" {
+		t.Errorf("expected files of layer 1, got %s", got)
+	}
+}
+
+func TestFilterAndSort(t *testing.T) {
+	m := New(testSbom())
+	key(m, tcell.KeyRune, 's')
+	key(m, tcell.KeyRune, 's')
+	if got := strings.Join(names(m.Rows()), ","); got != "lodash,musl,express" {
+		t.Errorf("expected packages sorted by cves, got %s", got)
+	}
+	key(m, tcell.KeyRune, '/')
+	for _, r := range "npm" {
+		key(m, tcell.KeyRune, r)
+	}
+	key(m, tcell.KeyEnter, 0)
+	if got := strings.Join(names(m.Rows()), ","); got != "lodash,express" {
+		t.Errorf("expected npm packages, got %s", got)
+	}
+	key(m, tcell.KeyRune, '3')
+	if rows := m.Rows(); len(rows) != 1 || rows[0].cells[1] != "CVE-2021-23337" || rows[0].cells[3] != "4.17.21" {
+		t.Errorf("expected filtered cve, got %+v", rows)
+	}
+	if key(m, tcell.KeyRune, 'q') {
+		t.Error("expected q to quit")
+	}
+}
+
+func TestRun(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(120, 20)
+	m := New(testSbom())
+
+	screen.InjectKey(tcell.KeyRune, '3', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	run(m, screen)
+
+	cells, w, _ := screen.GetContents()
+	var b strings.Builder
+	for i, c := range cells {
+		if i%w == 0 {
+			b.WriteRune('\n')
+		}
+		b.WriteString(string(c.Runes))
+	}
+	content := b.String()
+	for _, s := range []string{"example:latest", "All layers (2)", "RUN npm install", "[CVEs]", "CVE-2021-23337", "filter"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("expected %q on screen:\n%s", s, content)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package explore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/gdamore/tcell/v2"
+)
+
+type view int
+
+const (
+	packagesView view = iota
+	filesView
+	cvesView
+)
+
+var viewNames = []string{"Packages", "Files", "CVEs"}
+
+// sortKeys are the orders each view cycles through with the s key
+var sortKeys = [][]string{
+	{"name", "layer", "cves"},
+	{"path", "size"},
+	{"severity", "id", "package"},
+}
+
+// columns are the headers and widths of each view; the column of width 0 takes the remaining space
+var columns = [][]column{
+	{{"Package", 0}, {"Version", 24}, {"Type", 10}, {"Layer", 6}, {"CVEs", 5}},
+	{{"Path", 0}, {"Size", 10}, {"Package", 32}},
+	{{"Severity", 12}, {"CVE", 22}, {"Package", 0}, {"Fixed by", 20}},
+}
+
+type column struct {
+	title string
+	width int
+}
+
+type layer struct {
+	ordinal   int
+	digest    string
+	size      int64
+	createdBy string
+	base      bool
+	packages  int
+}
+
+type row struct {
+	cells  []string
+	detail string
+	style  tcell.Style
+}
+
+// Model is the state of the explorer, kept apart from the screen so key handling can be tested
+type Model struct {
+	sb     *types.Sbom
+	layers []layer
+	cves   map[string][]types.Cve
+	names  map[string]string
+
+	view  view
+	focus int
+	// layer is the selected entry of the layer list, 0 selects all layers
+	layer  int
+	sort   [3]int
+	filter string
+	typing bool
+
+	// cursors and offsets of the layer list and the three views
+	cursor [4]int
+	offset [4]int
+	height int
+	rows   []row
+}
+
+// New returns the explorer model of sb
+func New(sb *types.Sbom) *Model {
+	m := &Model{
+		sb:    sb,
+		cves:  make(map[string][]types.Cve),
+		names: make(map[string]string),
+	}
+	for _, c := range sb.Vulnerabilities {
+		m.cves[c.Purl] = append(m.cves[c.Purl], c)
+	}
+	for _, p := range sb.Artifacts {
+		m.names[p.Purl] = packageName(p)
+	}
+	m.layers = imageLayers(sb)
+	return m
+}
+
+// imageLayers lists the layers of the image from its manifest, adding the layers packages are
+// attributed to if the sbom has no manifest
+func imageLayers(sb *types.Sbom) []layer {
+	img := sb.Source.Image
+	createdBy := sbom.LayerCreatedBy(img.Config)
+	baseLayers := 0
+	if img.BaseImage != nil {
+		baseLayers = img.BaseImage.LayerCount
+	}
+	layers := make([]layer, 0)
+	byOrdinal := make(map[int]int)
+	if img.Manifest != nil {
+		for i, l := range img.Manifest.Layers {
+			byOrdinal[i] = len(layers)
+			layers = append(layers, layer{
+				ordinal:   i,
+				digest:    l.Digest.String(),
+				size:      l.Size,
+				createdBy: createdBy[i],
+				base:      i < baseLayers,
+			})
+		}
+	}
+	for _, p := range sb.Artifacts {
+		if p.Layer == nil {
+			continue
+		}
+		i, ok := byOrdinal[p.Layer.Ordinal]
+		if !ok {
+			i = len(layers)
+			byOrdinal[p.Layer.Ordinal] = i
+			layers = append(layers, layer{
+				ordinal:   p.Layer.Ordinal,
+				digest:    p.Layer.Digest,
+				createdBy: p.Layer.CreatedBy,
+				base:      p.Layer.BaseImage,
+			})
+		}
+		layers[i].packages++
+	}
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layers[i].ordinal < layers[j].ordinal
+	})
+	return layers
+}
+
+// Handle updates the model for a key press and returns false if the explorer should quit
+func (m *Model) Handle(ev *tcell.EventKey) bool {
+	if m.typing {
+		switch ev.Key() {
+		case tcell.KeyEnter:
+			m.typing = false
+		case tcell.KeyEscape:
+			m.typing = false
+			m.setFilter("")
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if r := []rune(m.filter); len(r) > 0 {
+				m.setFilter(string(r[:len(r)-1]))
+			}
+		case tcell.KeyRune:
+			m.setFilter(m.filter + string(ev.Rune()))
+		}
+		return true
+	}
+	page := m.height - 1
+	if page < 1 {
+		page = 1
+	}
+	switch ev.Key() {
+	case tcell.KeyCtrlC, tcell.KeyEscape:
+		return false
+	case tcell.KeyTab, tcell.KeyBacktab:
+		m.focus = 1 - m.focus
+	case tcell.KeyUp:
+		m.move(-1)
+	case tcell.KeyDown:
+		m.move(1)
+	case tcell.KeyPgUp:
+		m.move(-page)
+	case tcell.KeyPgDn:
+		m.move(page)
+	case tcell.KeyHome:
+		m.move(-m.count())
+	case tcell.KeyEnd:
+		m.move(m.count())
+	case tcell.KeyLeft:
+		m.focus = 0
+	case tcell.KeyRight:
+		m.focus = 1
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			return false
+		case 'k':
+			m.move(-1)
+		case 'j':
+			m.move(1)
+		case '1', '2', '3':
+			m.view = view(ev.Rune() - '1')
+			m.focus = 1
+			m.rows = nil
+		case 's':
+			m.sort[m.view] = (m.sort[m.view] + 1) % len(sortKeys[m.view])
+			m.rows = nil
+		case '/':
+			m.typing = true
+			m.focus = 1
+		}
+	}
+	return true
+}
+
+func (m *Model) setFilter(filter string) {
+	m.filter = filter
+	m.rows = nil
+	m.cursor[m.view+1] = 0
+}
+
+// index returns the cursor slot of the focused list
+func (m *Model) index() int {
+	if m.focus == 0 {
+		return 0
+	}
+	return int(m.view) + 1
+}
+
+func (m *Model) count() int {
+	if m.focus == 0 {
+		return len(m.layers) + 1
+	}
+	return len(m.Rows())
+}
+
+func (m *Model) move(delta int) {
+	i := m.index()
+	c := m.cursor[i] + delta
+	if c >= m.count() {
+		c = m.count() - 1
+	}
+	if c < 0 {
+		c = 0
+	}
+	m.cursor[i] = c
+	if i == 0 && m.layer != c {
+		m.layer = c
+		m.rows = nil
+		m.cursor[1], m.cursor[2], m.cursor[3] = 0, 0, 0
+	}
+}
+
+// inLayer returns true if l is the selected layer or all layers are selected
+func (m *Model) inLayer(l *types.Layer) bool {
+	if m.layer == 0 {
+		return true
+	}
+	return l != nil && l.Ordinal == m.layers[m.layer-1].ordinal
+}
+
+// Rows returns the rows of the current view for the selected layer, filtered and sorted
+func (m *Model) Rows() []row {
+	if m.rows != nil {
+		return m.rows
+	}
+	var rows []row
+	switch m.view {
+	case packagesView:
+		rows = m.packageRows()
+	case filesView:
+		rows = m.fileRows()
+	case cvesView:
+		rows = m.cveRows()
+	}
+	filtered := make([]row, 0, len(rows))
+	filter := strings.ToLower(m.filter)
+	for _, r := range rows {
+		if filter == "" || strings.Contains(strings.ToLower(strings.Join(r.cells, " ")+" "+r.detail), filter) {
+			filtered = append(filtered, r)
+		}
+	}
+	m.rows = filtered
+	return m.rows
+}
+
+func (m *Model) packageRows() []row {
+	packages := make([]types.Package, 0)
+	for _, p := range m.sb.Artifacts {
+		if m.inLayer(p.Layer) {
+			packages = append(packages, p)
+		}
+	}
+	ordinal := func(p types.Package) int {
+		if p.Layer == nil {
+			return -1
+		}
+		return p.Layer.Ordinal
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		a, b := packages[i], packages[j]
+		switch sortKeys[packagesView][m.sort[packagesView]] {
+		case "layer":
+			if ordinal(a) != ordinal(b) {
+				return ordinal(a) < ordinal(b)
+			}
+		case "cves":
+			if len(m.cves[a.Purl]) != len(m.cves[b.Purl]) {
+				return len(m.cves[a.Purl]) > len(m.cves[b.Purl])
+			}
+		}
+		return packageName(a) < packageName(b)
+	})
+	rows := make([]row, len(packages))
+	for i, p := range packages {
+		layer := "-"
+		if p.Layer != nil {
+			layer = fmt.Sprint(p.Layer.Ordinal)
+		}
+		paths := make([]string, 0)
+		for _, l := range p.Locations {
+			paths = append(paths, l.Path)
+		}
+		style := tcell.StyleDefault
+		if len(m.cves[p.Purl]) > 0 {
+			style = style.Foreground(tcell.ColorYellow)
+		}
+		rows[i] = row{
+			cells:  []string{packageName(p), p.Version, p.Type, layer, fmt.Sprint(len(m.cves[p.Purl]))},
+			detail: fmt.Sprintf("%s  %s  %s", p.Purl, strings.Join(p.Licenses, ", "), strings.Join(paths, ", ")),
+			style:  style,
+		}
+	}
+	return rows
+}
+
+// fileRows lists the file inventory, or the locations of the packages if the sbom has none
+func (m *Model) fileRows() []row {
+	files := make([]types.File, 0)
+	if len(m.sb.Files) > 0 {
+		for _, f := range m.sb.Files {
+			if m.inLayer(f.Layer) {
+				files = append(files, f)
+			}
+		}
+	} else {
+		for _, p := range m.sb.Artifacts {
+			if !m.inLayer(p.Layer) {
+				continue
+			}
+			for _, l := range p.Locations {
+				files = append(files, types.File{Path: l.Path, Size: -1, Package: p.Purl, Layer: p.Layer})
+			}
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if sortKeys[filesView][m.sort[filesView]] == "size" && files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+	rows := make([]row, len(files))
+	for i, f := range files {
+		size := "-"
+		if f.Size >= 0 {
+			size = units.HumanSize(float64(f.Size))
+		}
+		name := m.names[f.Package]
+		if name == "" {
+			name = f.Package
+		}
+		rows[i] = row{
+			cells:  []string{f.Path, size, name},
+			detail: strings.TrimSpace(fmt.Sprintf("%s  %s", f.Sha256, f.Package)),
+			style:  tcell.StyleDefault,
+		}
+	}
+	return rows
+}
+
+func (m *Model) cveRows() []row {
+	cves := make([]types.Cve, 0)
+	for _, p := range m.sb.Artifacts {
+		if m.inLayer(p.Layer) {
+			cves = append(cves, m.cves[p.Purl]...)
+		}
+	}
+	sort.SliceStable(cves, func(i, j int) bool {
+		if sortKeys[cvesView][m.sort[cvesView]] == "package" && cves[i].Purl != cves[j].Purl {
+			return m.names[cves[i].Purl] < m.names[cves[j].Purl]
+		}
+		return cves[i].SourceId < cves[j].SourceId
+	})
+	if sortKeys[cvesView][m.sort[cvesView]] == "severity" {
+		_ = sbom.SortCves(cves, "severity")
+	}
+	rows := make([]row, len(cves))
+	for i, c := range cves {
+		severity := sbom.Severity(c)
+		fixedBy := c.FixedBy
+		if fixedBy == "" {
+			fixedBy = "not fixed"
+		}
+		rows[i] = row{
+			cells:  []string{severity, c.SourceId, m.names[c.Purl], fixedBy},
+			detail: strings.TrimSpace(fmt.Sprintf("%s  %s  %s", c.Purl, c.VulnerableRange, c.AdvisoryUrl)),
+			style:  tcell.StyleDefault.Foreground(severityColor(severity)),
+		}
+	}
+	return rows
+}
+
+func severityColor(severity string) tcell.Color {
+	switch severity {
+	case "CRITICAL":
+		return tcell.ColorRed
+	case "HIGH":
+		return tcell.ColorOrange
+	case "MEDIUM":
+		return tcell.ColorYellow
+	case "LOW":
+		return tcell.ColorBlue
+	default:
+		return tcell.ColorGray
+	}
+}
+
+func packageName(p types.Package) string {
+	if p.Namespace != "" {
+		return p.Namespace + "/" + p.Name
+	}
+	return p.Name
+}
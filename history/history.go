@@ -0,0 +1,242 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+	_ "modernc.org/sqlite"
+)
+
+var schemas = map[string]string{
+	"sqlite": `CREATE TABLE IF NOT EXISTS scans (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	repository TEXT NOT NULL,
+	image TEXT NOT NULL,
+	digest TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	scanned_at TIMESTAMP NOT NULL,
+	packages INTEGER NOT NULL,
+	critical INTEGER NOT NULL,
+	high INTEGER NOT NULL,
+	medium INTEGER NOT NULL,
+	low INTEGER NOT NULL,
+	unspecified INTEGER NOT NULL,
+	sbom BLOB NOT NULL
+)`,
+	"postgres": `CREATE TABLE IF NOT EXISTS scans (
+	id BIGSERIAL PRIMARY KEY,
+	repository TEXT NOT NULL,
+	image TEXT NOT NULL,
+	digest TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	scanned_at TIMESTAMPTZ NOT NULL,
+	packages INTEGER NOT NULL,
+	critical INTEGER NOT NULL,
+	high INTEGER NOT NULL,
+	medium INTEGER NOT NULL,
+	low INTEGER NOT NULL,
+	unspecified INTEGER NOT NULL,
+	sbom BYTEA NOT NULL
+)`,
+}
+
+const index = "CREATE INDEX IF NOT EXISTS scans_repository ON scans (repository, scanned_at)"
+
+// issueSchemas map the CVEs of a repository to the issues opened for them
+var issueSchemas = map[string]string{
+	"sqlite": `CREATE TABLE IF NOT EXISTS issues (
+	repository TEXT NOT NULL,
+	cve TEXT NOT NULL,
+	issue_key TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (repository, cve)
+)`,
+	"postgres": `CREATE TABLE IF NOT EXISTS issues (
+	repository TEXT NOT NULL,
+	cve TEXT NOT NULL,
+	issue_key TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (repository, cve)
+)`,
+}
+
+// Scan is a recorded scan of an image
+type Scan struct {
+	Id         int64     `json:"id"`
+	Repository string    `json:"repository"`
+	Image      string    `json:"image"`
+	Digest     string    `json:"digest"`
+	Platform   string    `json:"platform"`
+	Scanned    time.Time `json:"scanned_at"`
+	Packages   int       `json:"packages"`
+	// Vulnerabilities counts the affected CVEs of the image by severity
+	Vulnerabilities map[string]int `json:"vulnerabilities"`
+}
+
+// Store records scans in a SQLite or Postgres database
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the store at dsn, a postgres:// url or the path of a SQLite database, and
+// creates its table on first use
+func Open(dsn string) (*Store, error) {
+	driver := "sqlite"
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		driver = "postgres"
+	} else {
+		dsn = strings.TrimPrefix(dsn, "sqlite://")
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open history database")
+	}
+	for _, stmt := range []string{schemas[driver], index, issueSchemas[driver]} {
+		if _, err := db.Exec(stmt); err != nil {
+			_ = db.Close()
+			return nil, errors.Wrap(err, "failed to create history table")
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record stores the scan of sb including the complete sbom and returns its id
+func (s *Store) Record(ctx context.Context, sb *types.Sbom, scanned time.Time) (int64, error) {
+	js, err := json.Marshal(sb)
+	if err != nil {
+		return 0, err
+	}
+	v := Summarize(sb)
+	var id int64
+	err = s.db.QueryRowContext(ctx, `INSERT INTO scans
+	(repository, image, digest, platform, scanned_at, packages, critical, high, medium, low, unspecified, sbom)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		Repository(sb.Source.Image.Name), sb.Source.Image.Name, sb.Source.Image.Digest, sb.Source.Image.Platform.String(),
+		scanned.UTC(), len(sb.Artifacts), v["CRITICAL"], v["HIGH"], v["MEDIUM"], v["LOW"], v["UNSPECIFIED"], js).Scan(&id)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to record scan of %s", sb.Source.Image.Name)
+	}
+	return id, nil
+}
+
+// List returns the latest scans of the repository of image, newest first
+func (s *Store) List(ctx context.Context, image string, limit int) ([]Scan, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, repository, image, digest, platform, scanned_at, packages,
+	critical, high, medium, low, unspecified FROM scans WHERE repository = $1 ORDER BY scanned_at DESC, id DESC LIMIT $2`,
+		Repository(image), limit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list scans of %s", image)
+	}
+	defer rows.Close()
+	scans := make([]Scan, 0)
+	for rows.Next() {
+		var scan Scan
+		var critical, high, medium, low, unspecified int
+		if err := rows.Scan(&scan.Id, &scan.Repository, &scan.Image, &scan.Digest, &scan.Platform, &scan.Scanned,
+			&scan.Packages, &critical, &high, &medium, &low, &unspecified); err != nil {
+			return nil, errors.Wrapf(err, "failed to read scans of %s", image)
+		}
+		scan.Vulnerabilities = map[string]int{"CRITICAL": critical, "HIGH": high, "MEDIUM": medium, "LOW": low, "UNSPECIFIED": unspecified}
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
+
+// Previous returns the sbom of the latest scan of the repository of image for platform, or nil
+// if it was not scanned before
+func (s *Store) Previous(ctx context.Context, image string, platform string) (*types.Sbom, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM scans WHERE repository = $1 AND platform = $2
+	ORDER BY scanned_at DESC, id DESC LIMIT 1`, Repository(image), platform).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find previous scan of %s", image)
+	}
+	return s.Sbom(ctx, id)
+}
+
+// Sbom returns the sbom recorded with the scan of id
+func (s *Store) Sbom(ctx context.Context, id int64) (*types.Sbom, error) {
+	var js []byte
+	if err := s.db.QueryRowContext(ctx, "SELECT sbom FROM scans WHERE id = $1", id).Scan(&js); err != nil {
+		return nil, errors.Wrapf(err, "failed to read sbom of scan %d", id)
+	}
+	var sb types.Sbom
+	if err := json.Unmarshal(js, &sb); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse sbom of scan %d", id)
+	}
+	return &sb, nil
+}
+
+// Issue returns the key of the issue opened for cve in the repository of image, or an empty
+// string if none was opened yet
+func (s *Store) Issue(ctx context.Context, image string, cve string) (string, error) {
+	var key string
+	err := s.db.QueryRowContext(ctx, "SELECT issue_key FROM issues WHERE repository = $1 AND cve = $2", Repository(image), cve).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find issue of %s in %s", cve, image)
+	}
+	return key, nil
+}
+
+// RecordIssue stores the key of the issue opened for cve in the repository of image
+func (s *Store) RecordIssue(ctx context.Context, image string, cve string, key string) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO issues (repository, cve, issue_key, created_at) VALUES ($1, $2, $3, $4)",
+		Repository(image), cve, key, time.Now().UTC())
+	return errors.Wrapf(err, "failed to record issue %s of %s in %s", key, cve, image)
+}
+
+// Summarize counts the CVEs of sb by severity, ignoring suppressed ones and those a VEX
+// statement declared as not affected
+func Summarize(sb *types.Sbom) map[string]int {
+	v := make(map[string]int)
+	for _, c := range sb.Vulnerabilities {
+		if sbom.IsAffected(c) && c.Suppression == nil {
+			v[sbom.Severity(c)]++
+		}
+	}
+	return v
+}
+
+// Repository returns the normalized repository of image, e.g. index.docker.io/library/alpine
+// for alpine:3.16, so scans of all tags and digests are listed together
+func Repository(image string) string {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return image
+	}
+	return ref.Context().Name()
+}
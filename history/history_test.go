@@ -0,0 +1,124 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestStore(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	older := &types.Sbom{
+		Source:    types.Source{Image: types.ImageSource{Name: "alpine:3.15", Digest: "sha256:1111"}},
+		Artifacts: []types.Package{{Purl: "pkg:apk/alpine/musl@1.2.2"}},
+		Vulnerabilities: []types.Cve{
+			{SourceId: "CVE-2022-0001", Advisory: &types.Advisory{References: []types.Reference{{Source: "atomist", Scores: []types.Score{{Type: "atm_severity", Value: "CRITICAL"}}}}}},
+			{SourceId: "CVE-2022-0002", Suppression: &types.Suppression{}},
+		},
+	}
+	newer := &types.Sbom{
+		Source:    types.Source{Image: types.ImageSource{Name: "docker.io/library/alpine:3.16", Digest: "sha256:2222"}},
+		Artifacts: []types.Package{{Purl: "pkg:apk/alpine/musl@1.2.3"}, {Purl: "pkg:apk/alpine/zlib@1.2.12"}},
+	}
+	other := &types.Sbom{Source: types.Source{Image: types.ImageSource{Name: "busybox", Digest: "sha256:3333"}}}
+	now := time.Now()
+	for i, sb := range []*types.Sbom{older, other, newer} {
+		if _, err := store.Record(ctx, sb, now.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scans, err := store.List(ctx, "alpine", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 2 || scans[0].Digest != "sha256:2222" || scans[1].Digest != "sha256:1111" {
+		t.Fatalf("unexpected scans %+v", scans)
+	}
+	if scans[0].Packages != 2 || scans[1].Vulnerabilities["CRITICAL"] != 1 || scans[1].Vulnerabilities["UNSPECIFIED"] != 0 {
+		t.Errorf("unexpected summaries %+v", scans)
+	}
+	if d := scans[0].Scanned.Sub(now.Add(2 * time.Hour)); d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("unexpected scan time %s", scans[0].Scanned)
+	}
+
+	previous, err := store.Previous(ctx, "alpine:latest", newer.Source.Image.Platform.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous == nil || previous.Source.Image.Digest != "sha256:2222" {
+		t.Errorf("unexpected previous scan %+v", previous)
+	}
+	if previous, err := store.Previous(ctx, "nginx", ""); err != nil || previous != nil {
+		t.Errorf("expected no previous scan of nginx, got %v: %v", previous, err)
+	}
+
+	sb, err := store.Sbom(ctx, scans[1].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.Source.Image.Name != "alpine:3.15" || len(sb.Vulnerabilities) != 2 {
+		t.Errorf("unexpected sbom %+v", sb.Source)
+	}
+}
+
+func TestIssues(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	if key, err := store.Issue(ctx, "alpine:3.16", "CVE-2022-0001"); err != nil || key != "" {
+		t.Errorf("expected no issue, got %s: %v", key, err)
+	}
+	if err := store.RecordIssue(ctx, "alpine:3.16", "CVE-2022-0001", "SEC-1"); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := store.Issue(ctx, "docker.io/library/alpine:3.17", "CVE-2022-0001"); err != nil || key != "SEC-1" {
+		t.Errorf("expected issue of repository, got %s: %v", key, err)
+	}
+	if key, _ := store.Issue(ctx, "busybox", "CVE-2022-0001"); key != "" {
+		t.Errorf("expected no issue of other repository, got %s", key)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	for image, expected := range map[string]string{
+		"alpine":                           "index.docker.io/library/alpine",
+		"alpine:3.16":                      "index.docker.io/library/alpine",
+		"ghcr.io/org/app@sha256:" + sha256: "ghcr.io/org/app",
+	} {
+		if repository := Repository(image); repository != expected {
+			t.Errorf("expected %s for %s, got %s", expected, image, repository)
+		}
+	}
+}
+
+const sha256 = "5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270"
@@ -0,0 +1,210 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package archive
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxSymlinks is how many symlinks are followed resolving a path before it's treated as a loop
+const maxSymlinks = 40
+
+// Extract writes the entries of the tar stream r below dir, which is treated like the root
+// directory of the archive:
+//   - entries with .. elements leaving dir fail the extraction, absolute paths are relative to dir
+//   - symlinks are rewritten to relative links that can't point outside dir, and entries are
+//     never written through a symlink to a location outside dir
+//   - hard links must point to a file extracted before
+//   - device nodes, fifos and other special files are skipped, setuid, setgid and sticky bits dropped
+func Extract(r io.Reader, dir string) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read archive")
+		}
+		if err = extractEntry(root, hdr, tr); err != nil {
+			return errors.Wrapf(err, "failed to extract %s", hdr.Name)
+		}
+	}
+}
+
+func extractEntry(root string, hdr *tar.Header, r io.Reader) error {
+	name, err := entryPath(hdr.Name)
+	if err != nil || name == "" {
+		return err
+	}
+	parent, err := resolve(root, path.Dir(name))
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(parent, path.Base(name))
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if fi, err := os.Lstat(target); err == nil && !fi.IsDir() {
+			if err = os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if err = os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		return os.Chmod(target, hdr.FileInfo().Mode().Perm()|0700)
+	case tar.TypeReg:
+		if err = replace(parent, target); err != nil {
+			return err
+		}
+		// O_EXCL fails instead of following a symlink at target
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, hdr.FileInfo().Mode().Perm()|0600)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, r)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	case tar.TypeSymlink:
+		if err = replace(parent, target); err != nil {
+			return err
+		}
+		return os.Symlink(confine(name, hdr.Linkname), target)
+	case tar.TypeLink:
+		linkName, err := entryPath(hdr.Linkname)
+		if err != nil {
+			return err
+		}
+		if linkName == "" {
+			return errors.Errorf("invalid hard link to %s", hdr.Linkname)
+		}
+		linkParent, err := resolve(root, path.Dir(linkName))
+		if err != nil {
+			return err
+		}
+		source := filepath.Join(linkParent, path.Base(linkName))
+		fi, err := os.Lstat(source)
+		if err != nil {
+			return errors.Wrapf(err, "hard link target %s not found", hdr.Linkname)
+		}
+		if !fi.Mode().IsRegular() {
+			return errors.Errorf("hard link target %s is not a regular file", hdr.Linkname)
+		}
+		if err = replace(parent, target); err != nil {
+			return err
+		}
+		return os.Link(source, target)
+	default:
+		return nil
+	}
+}
+
+// entryPath returns the cleaned slash separated path of an entry relative to the root, or
+// an error if it leaves the root
+func entryPath(name string) (string, error) {
+	p := path.Clean(strings.TrimLeft(name, "/"))
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", errors.Errorf("path traversal in archive entry %s", name)
+	}
+	if p == "." {
+		return "", nil
+	}
+	return p, nil
+}
+
+// resolve returns the location of the directory rel below root following symlinks like a
+// chroot into root would, so neither .. elements nor absolute symlink targets leave root
+func resolve(root string, rel string) (string, error) {
+	current := ""
+	pending := strings.Split(rel, "/")
+	links := 0
+	for len(pending) > 0 {
+		element := pending[0]
+		pending = pending[1:]
+		switch element {
+		case "", ".":
+			continue
+		case "..":
+			if current = path.Dir(current); current == "." {
+				current = ""
+			}
+			continue
+		}
+		next := path.Join(current, element)
+		fi, err := os.Lstat(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", errors.Errorf("too many levels of symbolic links resolving %s", rel)
+		}
+		link, err := os.Readlink(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil {
+			return "", err
+		}
+		link = filepath.ToSlash(link)
+		if path.IsAbs(link) {
+			current = ""
+		}
+		pending = append(strings.Split(link, "/"), pending...)
+	}
+	return filepath.Join(root, filepath.FromSlash(current)), nil
+}
+
+// confine returns the target of the symlink name relative to its directory, resolving
+// absolute targets and .. elements against the root of the archive
+func confine(name string, target string) string {
+	dir := path.Join("/", path.Dir(name))
+	target = filepath.ToSlash(target)
+	if !path.IsAbs(target) {
+		target = path.Join(dir, target)
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(path.Clean(target)))
+	if err != nil {
+		return "."
+	}
+	return rel
+}
+
+// replace creates the directory parent and removes what an earlier entry extracted at target
+func replace(parent string, target string) error {
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	return os.RemoveAll(target)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+func tarball(t *testing.T, entries ...entry) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0644, Size: int64(len(e.content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	err := Extract(tarball(t,
+		entry{name: "./etc/", typeflag: tar.TypeDir},
+		entry{name: "/etc/os-release", typeflag: tar.TypeReg, content: "ID=alpine"},
+		entry{name: "etc/absolute", typeflag: tar.TypeSymlink, linkname: "/etc/os-release"},
+		entry{name: "etc/escape", typeflag: tar.TypeSymlink, linkname: "../../../outside"},
+		entry{name: "etc/escape/file", typeflag: tar.TypeReg, content: "inside"},
+		entry{name: "etc/hardlink", typeflag: tar.TypeLink, linkname: "etc/os-release"},
+		entry{name: "dev/null", typeflag: tar.TypeChar},
+		entry{name: "fifo", typeflag: tar.TypeFifo},
+	), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		"etc/os-release": "ID=alpine",
+		"etc/absolute":   "ID=alpine",
+		"etc/hardlink":   "ID=alpine",
+		"outside/file":   "inside",
+	} {
+		b, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil || string(b) != expected {
+			t.Errorf("expected %s to contain %q, got %q: %v", name, expected, b, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Error("expected symlink not to escape the extraction directory")
+	}
+	for _, name := range []string{"dev/null", "fifo"} {
+		if _, err := os.Lstat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("expected special file %s to be skipped", name)
+		}
+	}
+}
+
+func TestExtractUnsafe(t *testing.T) {
+	for name, entries := range map[string][]entry{
+		"traversal": {{name: "a/../../evil", typeflag: tar.TypeReg, content: "x"}},
+		"hard link": {{name: "link", typeflag: tar.TypeLink, linkname: "../etc/passwd"}},
+		"loop": {
+			{name: "a", typeflag: tar.TypeSymlink, linkname: "b"},
+			{name: "b", typeflag: tar.TypeSymlink, linkname: "a"},
+			{name: "a/file", typeflag: tar.TypeReg, content: "x"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := Extract(tarball(t, entries...), filepath.Join(dir, "root"))
+			if err == nil {
+				t.Fatal("expected extraction to fail")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+				t.Error("expected no file outside the extraction directory")
+			}
+			if name == "loop" && !strings.Contains(err.Error(), "symbolic links") {
+				t.Errorf("expected symlink loop error, got %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	rootCAs            *x509.CertPool
+	insecureRegistries []string
+	transport          http.RoundTripper = http.DefaultTransport
+)
+
+// SetTLSConfig trusts the PEM certificates of caFile in addition to the system roots and skips
+// certificate verification for the hosts of insecure registries
+func SetTLSConfig(caFile string, insecure []string) error {
+	rootCAs = nil
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read registry CA %s", caFile)
+		}
+		rootCAs, err = x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no certificates found in registry CA %s", caFile)
+		}
+	}
+	insecureRegistries = insecure
+	transport = Transport(http.DefaultTransport.(*http.Transport))
+	return nil
+}
+
+// IsInsecureRegistry returns true if host, with or without port, was configured as insecure registry
+func IsInsecureRegistry(host string) bool {
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	}
+	return Contains(insecureRegistries, host) || Contains(insecureRegistries, hostname)
+}
+
+// Transport returns base with the configured TLS settings. Like base it uses the proxies
+// of HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+func Transport(base *http.Transport) http.RoundTripper {
+	if rootCAs == nil && len(insecureRegistries) == 0 {
+		return base
+	}
+	secure := base.Clone()
+	secure.Proxy = http.ProxyFromEnvironment
+	if secure.TLSClientConfig == nil {
+		secure.TLSClientConfig = &tls.Config{}
+	}
+	if rootCAs != nil {
+		secure.TLSClientConfig.RootCAs = rootCAs
+	}
+	if len(insecureRegistries) == 0 {
+		return secure
+	}
+	insecure := secure.Clone()
+	insecure.TLSClientConfig.InsecureSkipVerify = true
+	return insecureTransport{secure: secure, insecure: insecure}
+}
+
+// HttpClient returns a client for query traffic using the configured TLS settings
+func HttpClient() *http.Client {
+	return &http.Client{Transport: transport}
+}
+
+type insecureTransport struct {
+	secure   http.RoundTripper
+	insecure http.RoundTripper
+}
+
+func (t insecureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if IsInsecureRegistry(req.URL.Host) {
+		return t.insecure.RoundTrip(req)
+	}
+	return t.secure.RoundTrip(req)
+}
@@ -19,6 +19,8 @@ package internal
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 )
 
@@ -54,3 +56,27 @@ func Contains[K interface{}](slice []K, value K) bool {
 	}
 	return false
 }
+
+// cachePath overrides the cache directory if set
+var cachePath string
+
+// SetCachePath makes CachePath return path, e.g. to honour a command line flag
+func SetCachePath(path string) {
+	cachePath = path
+}
+
+// CachePath returns the directory images and indexing results are cached in: the path set
+// with SetCachePath, ATOMIST_CACHE_DIR, the user cache directory (XDG_CACHE_HOME on Linux)
+// or the temp directory if there is no user cache directory
+func CachePath() string {
+	if cachePath != "" {
+		return cachePath
+	}
+	if v, ok := os.LookupEnv("ATOMIST_CACHE_DIR"); ok {
+		return filepath.Join(v, "docker-index")
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "docker-index")
+	}
+	return filepath.Join(os.TempDir(), "docker-index")
+}
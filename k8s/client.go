@@ -0,0 +1,235 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Workload is the top-level owner of pods, e.g. a Deployment, or the pod itself if it has none
+type Workload struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+}
+
+// Image is a container image running in the cluster with the workloads using it
+type Image struct {
+	// Image is the reference to pull the running image, pinned to its digest if known
+	Image     string     `json:"image"`
+	Digest    string     `json:"digest,omitempty"`
+	Workloads []Workload `json:"workloads"`
+}
+
+type ownerReference struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Controller bool   `json:"controller"`
+}
+
+type objectMeta struct {
+	Name            string           `json:"name"`
+	Namespace       string           `json:"namespace"`
+	OwnerReferences []ownerReference `json:"ownerReferences"`
+}
+
+type podList struct {
+	Items []struct {
+		Metadata objectMeta `json:"metadata"`
+		Spec     struct {
+			InitContainers []container `json:"initContainers"`
+			Containers     []container `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			InitContainerStatuses []containerStatus `json:"initContainerStatuses"`
+			ContainerStatuses     []containerStatus `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type container struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type containerStatus struct {
+	Name    string `json:"name"`
+	ImageID string `json:"imageID"`
+}
+
+// ownerApis are the API paths of owners that are owned by other workloads themselves
+var ownerApis = map[string]string{
+	"ReplicaSet": "/apis/apps/v1/namespaces/%s/replicasets/%s",
+	"Job":        "/apis/batch/v1/namespaces/%s/jobs/%s",
+}
+
+// Client reads pods from the Kubernetes API
+type Client struct {
+	config *Config
+	http   *http.Client
+	owners map[string]Workload
+}
+
+// NewClient returns a Client for the cluster of config
+func NewClient(config *Config) *Client {
+	return &Client{
+		config: config,
+		http:   &http.Client{Transport: config.transport},
+		owners: make(map[string]Workload),
+	}
+}
+
+// ListImages returns the images of all containers of the running pods in namespace, or in
+// all namespaces if namespace is empty, de-duplicated by digest
+func (c *Client) ListImages(ctx context.Context, namespace string) ([]Image, error) {
+	path := "/api/v1/pods"
+	if namespace != "" {
+		path = fmt.Sprintf("/api/v1/namespaces/%s/pods", namespace)
+	}
+	var pods podList
+	if err := c.get(ctx, path, &pods); err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+
+	images := make(map[string]*Image)
+	for _, pod := range pods.Items {
+		workload, err := c.workload(ctx, pod.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		digests := make(map[string]string)
+		for _, s := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			digests[s.Name] = s.ImageID
+		}
+		for _, ct := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			ref, digest := pinnedImage(ct.Image, digests[ct.Name])
+			key := digest
+			if key == "" {
+				key = ref
+			}
+			image, ok := images[key]
+			if !ok {
+				image = &Image{Image: ref, Digest: digest}
+				images[key] = image
+			}
+			if !containsWorkload(image.Workloads, workload) {
+				image.Workloads = append(image.Workloads, workload)
+			}
+		}
+	}
+
+	result := make([]Image, 0)
+	for _, image := range images {
+		result = append(result, *image)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Image < result[j].Image
+	})
+	return result, nil
+}
+
+// workload follows the controller references of meta up to the top-level workload
+func (c *Client) workload(ctx context.Context, meta objectMeta) (Workload, error) {
+	owner := Workload{Namespace: meta.Namespace, Kind: "Pod", Name: meta.Name}
+	for _, ref := range meta.OwnerReferences {
+		if !ref.Controller {
+			continue
+		}
+		owner = Workload{Namespace: meta.Namespace, Kind: ref.Kind, Name: ref.Name}
+		api, ok := ownerApis[ref.Kind]
+		if !ok {
+			return owner, nil
+		}
+		key := ref.Kind + "/" + meta.Namespace + "/" + ref.Name
+		if w, ok := c.owners[key]; ok {
+			return w, nil
+		}
+		var object struct {
+			Metadata objectMeta `json:"metadata"`
+		}
+		if err := c.get(ctx, fmt.Sprintf(api, meta.Namespace, ref.Name), &object); err != nil {
+			// a deleted owner still identifies the workload
+			c.owners[key] = owner
+			return owner, nil
+		}
+		w, err := c.workload(ctx, object.Metadata)
+		if err != nil {
+			return owner, err
+		}
+		// an owner without controller reports itself as Pod
+		if w.Kind == "Pod" {
+			w = owner
+		}
+		c.owners[key] = w
+		return w, nil
+	}
+	return owner, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.config.Server, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to query %s", path)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to query %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// pinnedImage returns the image reference pinned to the digest of the imageID reported by
+// the container runtime, e.g. docker-pullable://nginx@sha256:...
+func pinnedImage(image string, imageID string) (string, string) {
+	i := strings.LastIndex(imageID, "@sha256:")
+	if i < 0 {
+		return image, ""
+	}
+	digest := imageID[i+1:]
+	repo := strings.TrimPrefix(imageID[:i], "docker-pullable://")
+	if strings.Contains(repo, "://") {
+		// other runtime prefixes don't name the repository the image was pulled from
+		repo = image
+	}
+	if j := strings.Index(repo, "@"); j >= 0 {
+		repo = repo[:j]
+	} else if j := strings.LastIndex(repo, ":"); j > strings.LastIndex(repo, "/") {
+		repo = repo[:j]
+	}
+	return repo + "@" + digest, digest
+}
+
+func containsWorkload(workloads []Workload, workload Workload) bool {
+	for _, w := range workloads {
+		if w == workload {
+			return true
+		}
+	}
+	return false
+}
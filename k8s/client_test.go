@@ -0,0 +1,97 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const pods = `{"items": [
+  {"metadata": {"name": "web-5d4f-a", "namespace": "shop", "ownerReferences": [{"kind": "ReplicaSet", "name": "web-5d4f", "controller": true}]},
+   "spec": {"containers": [{"name": "nginx", "image": "nginx:1.23"}]},
+   "status": {"containerStatuses": [{"name": "nginx", "imageID": "docker-pullable://nginx@sha256:aaaa"}]}},
+  {"metadata": {"name": "web-5d4f-b", "namespace": "shop", "ownerReferences": [{"kind": "ReplicaSet", "name": "web-5d4f", "controller": true}]},
+   "spec": {"containers": [{"name": "nginx", "image": "nginx:1.23"}]},
+   "status": {"containerStatuses": [{"name": "nginx", "imageID": "docker-pullable://nginx@sha256:aaaa"}]}},
+  {"metadata": {"name": "proxy", "namespace": "edge"},
+   "spec": {"containers": [{"name": "nginx", "image": "nginx:latest"}]},
+   "status": {"containerStatuses": [{"name": "nginx", "imageID": "docker.io/library/nginx@sha256:aaaa"}]}}
+]}`
+
+func TestListImages(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/pods":
+			_, _ = w.Write([]byte(pods))
+		case "/apis/apps/v1/namespaces/shop/replicasets/web-5d4f":
+			_, _ = w.Write([]byte(`{"metadata": {"name": "web-5d4f", "namespace": "shop", "ownerReferences": [{"kind": "Deployment", "name": "web", "controller": true}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// the kubeconfig is split across KUBECONFIG with credentials relative to their file
+	dir := t.TempDir()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	_ = os.WriteFile(filepath.Join(dir, "ca.crt"), ca, 0644)
+	_ = os.WriteFile(filepath.Join(dir, "token"), []byte("secret\n"), 0600)
+	_ = os.WriteFile(filepath.Join(dir, "cluster"), []byte(`
+current-context: test
+contexts:
+  - name: test
+    context: {cluster: test, user: test, namespace: shop}
+clusters:
+  - name: test
+    cluster: {server: `+server.URL+`, certificate-authority: ca.crt}
+`), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "user"), []byte(`
+users:
+  - name: test
+    user: {tokenFile: token}
+`), 0644)
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "cluster")+string(os.PathListSeparator)+filepath.Join(dir, "user"))
+
+	config, err := LoadConfig("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Namespace != "shop" {
+		t.Errorf("expected namespace shop, got %s", config.Namespace)
+	}
+	images, err := NewClient(config).ListImages(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].Image != "nginx@sha256:aaaa" {
+		t.Fatalf("expected images to be de-duplicated by digest, got %+v", images)
+	}
+	expected := []Workload{{Namespace: "shop", Kind: "Deployment", Name: "web"}, {Namespace: "edge", Kind: "Pod", Name: "proxy"}}
+	if len(images[0].Workloads) != 2 || images[0].Workloads[0] != expected[0] || images[0].Workloads[1] != expected[1] {
+		t.Errorf("expected workloads %v, got %v", expected, images[0].Workloads)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Config holds the address and credentials of the Kubernetes API
+type Config struct {
+	Server string
+	// Namespace is the default namespace of the kubeconfig context
+	Namespace string
+	transport http.RoundTripper
+}
+
+// LoadConfig reads the given context of the kubeconfig at path, the current context if
+// context is empty. Without a path it merges the files of KUBECONFIG or reads ~/.kube/config
+// and falls back to the service account of the pod when running inside a cluster.
+//
+// Credentials are resolved by client-go, so relative file paths, rotated token files and
+// expiring tokens of credential plugins, e.g. aws eks get-token, are handled as in kubectl.
+func LoadConfig(path string, context string) (*Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = path
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: context})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load kubeconfig")
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read namespace of kubeconfig context")
+	}
+	transport, err := rest.TransportFor(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Kubernetes API transport")
+	}
+	return &Config{
+		Server:    restConfig.Host,
+		Namespace: namespace,
+		transport: transport,
+	}, nil
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/sbom"
+)
+
+// Report is the cluster-wide vulnerability report grouped by namespace and workload
+type Report struct {
+	// Vulnerabilities counts the CVEs of all distinct images by severity
+	Vulnerabilities map[string]int    `json:"vulnerabilities"`
+	Namespaces      []NamespaceReport `json:"namespaces"`
+}
+
+type NamespaceReport struct {
+	Name      string           `json:"name"`
+	Workloads []WorkloadReport `json:"workloads"`
+}
+
+type WorkloadReport struct {
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	Images []ImageReport `json:"images"`
+}
+
+type ImageReport struct {
+	Image    string `json:"image"`
+	Digest   string `json:"digest,omitempty"`
+	Packages int    `json:"packages"`
+	// Vulnerabilities counts the CVEs of the image by severity
+	Vulnerabilities map[string]int `json:"vulnerabilities"`
+	Cves            []string       `json:"cves"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// Scan indexes every image once, queries its CVEs from backend and reports them for every
+// workload running the image
+func Scan(ctx context.Context, images []Image, indexer *sbom.Indexer, backend query.Backend, opts sbom.BatchOptions) *Report {
+	refs := make([]string, 0)
+	for _, image := range images {
+		refs = append(refs, image.Image)
+	}
+	log.Infof("Indexing %d distinct images", len(refs))
+	results := indexer.IndexImages(ctx, refs, opts)
+
+	report := Report{Vulnerabilities: make(map[string]int), Namespaces: make([]NamespaceReport, 0)}
+	workloads := make(map[Workload][]ImageReport)
+	for i, image := range images {
+		ir := imageReport(ctx, image, results[i], backend)
+		for severity, count := range ir.Vulnerabilities {
+			report.Vulnerabilities[severity] += count
+		}
+		for _, w := range image.Workloads {
+			workloads[w] = append(workloads[w], ir)
+		}
+	}
+
+	namespaces := make(map[string]*NamespaceReport)
+	for w, images := range workloads {
+		ns, ok := namespaces[w.Namespace]
+		if !ok {
+			ns = &NamespaceReport{Name: w.Namespace, Workloads: make([]WorkloadReport, 0)}
+			namespaces[w.Namespace] = ns
+		}
+		ns.Workloads = append(ns.Workloads, WorkloadReport{Kind: w.Kind, Name: w.Name, Images: images})
+	}
+	for _, ns := range namespaces {
+		sort.Slice(ns.Workloads, func(i, j int) bool {
+			if ns.Workloads[i].Kind != ns.Workloads[j].Kind {
+				return ns.Workloads[i].Kind < ns.Workloads[j].Kind
+			}
+			return ns.Workloads[i].Name < ns.Workloads[j].Name
+		})
+		report.Namespaces = append(report.Namespaces, *ns)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Name < report.Namespaces[j].Name
+	})
+	return &report
+}
+
+func imageReport(ctx context.Context, image Image, result sbom.ImageIndexResult, backend query.Backend) ImageReport {
+	ir := ImageReport{
+		Image:           image.Image,
+		Digest:          image.Digest,
+		Vulnerabilities: make(map[string]int),
+		Cves:            make([]string, 0),
+	}
+	if result.IndexError != nil {
+		log.Warnf("Failed to index %s: %s", image.Image, result.IndexError)
+		ir.Error = result.IndexError.Error()
+		return ir
+	}
+	ir.Packages = len(result.Sbom.Artifacts)
+	cves, err := backend.QueryCves(ctx, result.Sbom, "")
+	if err != nil {
+		log.Warnf("Failed to query CVEs of %s: %s", image.Image, err)
+		ir.Error = err.Error()
+		return ir
+	}
+	if cves == nil {
+		return ir
+	}
+	for _, c := range *cves {
+		ir.Vulnerabilities[sbom.Severity(c)]++
+		ir.Cves = append(ir.Cves, c.SourceId)
+	}
+	sort.Strings(ir.Cves)
+	return ir
+}
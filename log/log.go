@@ -0,0 +1,90 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package log routes the log messages of all index packages to a single Logger that
+// applications embedding them can replace
+package log
+
+import (
+	"github.com/atomist-skills/go-skill"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	FormatText = "text"
+	FormatJson = "json"
+)
+
+// Logger receives log messages; a *logrus.Logger satisfies it
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+var logger Logger = skill.Log
+
+// SetLogger sends all log messages to l instead of skill.Log; nil discards them.
+// It is meant to be called once before indexing starts.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = discard{}
+	}
+	logger = l
+}
+
+// Current returns the logger set by SetLogger
+func Current() Logger {
+	return logger
+}
+
+// SetFormat selects the output format of skill.Log, the default logger: FormatText or
+// FormatJson to write one JSON object per message for machine-readable progress logs
+func SetFormat(format string) error {
+	switch format {
+	case FormatText:
+	case FormatJson:
+		skill.Log.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return errors.Errorf("unsupported log format %s, expected text or json", format)
+	}
+	return nil
+}
+
+func Debugf(format string, args ...interface{}) {
+	logger.Debugf(format, args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	logger.Infof(format, args...)
+}
+
+func Warnf(format string, args ...interface{}) {
+	logger.Warnf(format, args...)
+}
+
+func Errorf(format string, args ...interface{}) {
+	logger.Errorf(format, args...)
+}
+
+type discard struct{}
+
+func (discard) Debugf(string, ...interface{}) {}
+func (discard) Infof(string, ...interface{})  {}
+func (discard) Warnf(string, ...interface{})  {}
+func (discard) Errorf(string, ...interface{}) {}
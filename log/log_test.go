@@ -0,0 +1,57 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log
+
+import (
+	"fmt"
+	"testing"
+)
+
+type recorder struct {
+	messages []string
+}
+
+func (r *recorder) Debugf(format string, args ...interface{}) { r.record(format, args...) }
+func (r *recorder) Infof(format string, args ...interface{})  { r.record(format, args...) }
+func (r *recorder) Warnf(format string, args ...interface{})  { r.record(format, args...) }
+func (r *recorder) Errorf(format string, args ...interface{}) { r.record(format, args...) }
+
+func (r *recorder) record(format string, args ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(format, args...))
+}
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(Current())
+	r := &recorder{}
+	SetLogger(r)
+	Infof("Indexed %d packages", 3)
+	if len(r.messages) != 1 || r.messages[0] != "Indexed 3 packages" {
+		t.Errorf("expected message to be sent to logger, got %v", r.messages)
+	}
+
+	SetLogger(nil)
+	Warnf("discarded")
+	if len(r.messages) != 1 {
+		t.Errorf("expected message to be discarded, got %v", r.messages)
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	if err := SetFormat("xml"); err == nil {
+		t.Error("expected unsupported format to fail")
+	}
+}
@@ -17,28 +17,46 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
-	"github.com/atomist-skills/go-skill"
 	"github.com/docker/cli/cli-plugins/manager"
 	"github.com/docker/cli/cli-plugins/plugin"
 	"github.com/docker/cli/cli/command"
 	cliflags "github.com/docker/cli/cli/flags"
 	"github.com/docker/index-cli-plugin/commands"
 	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func runStandalone(cmd *command.DockerCli) error {
-	if err := cmd.Initialize(cliflags.NewClientOptions()); err != nil {
-		return err
-	}
 	rootCmd := commands.NewRootCmd(os.Args[0], false, cmd)
-	return rootCmd.Execute()
+
+	// connect to the daemon like the docker CLI does, e.g. over ssh:// hosts or TLS
+	opts := cliflags.NewClientOptions()
+	flags := pflag.NewFlagSet("docker", pflag.ContinueOnError)
+	opts.Common.InstallFlags(flags)
+	for _, name := range []string{"host", "tls", "tlsverify", "tlscacert", "tlscert", "tlskey"} {
+		rootCmd.PersistentFlags().AddFlag(flags.Lookup(name))
+	}
+	preRun := rootCmd.PersistentPreRunE
+	rootCmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		opts.Common.SetDefaultOptions(flags)
+		if err := cmd.Initialize(opts); err != nil {
+			return err
+		}
+		return preRun(c, args)
+	}
+	return rootCmd.ExecuteContext(interruptContext())
 }
 
 func runPlugin(cmd *command.DockerCli) error {
 	rootCmd := commands.NewRootCmd("index", true, cmd)
+	setContext(rootCmd, interruptContext())
 	return plugin.RunPlugin(cmd, rootCmd, manager.Metadata{
 		SchemaVersion: "0.1.0",
 		Vendor:        "Docker Inc.",
@@ -46,6 +64,25 @@ func runPlugin(cmd *command.DockerCli) error {
 	})
 }
 
+// interruptContext is cancelled on the first interrupt so pulls and queries can clean up,
+// a second interrupt terminates immediately
+func interruptContext() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx
+}
+
+// setContext sets ctx on all commands as the plugin command wrapping them doesn't pass it down
+func setContext(cmd *cobra.Command, ctx context.Context) {
+	cmd.SetContext(ctx)
+	for _, c := range cmd.Commands() {
+		setContext(c, ctx)
+	}
+}
+
 func main() {
 	cmd, err := command.NewDockerCli()
 	if err != nil {
@@ -63,6 +100,6 @@ func main() {
 		return
 	}
 
-	skill.Log.Errorf("%s", err)
+	log.Errorf("%s", err)
 	os.Exit(1)
 }
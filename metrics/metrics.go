@@ -0,0 +1,136 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	CacheImage = "image"
+	CacheSbom  = "sbom"
+	CacheQuery = "query"
+)
+
+// Registry holds the metrics of scan operations, served by Handler and sent by Push
+var Registry = prometheus.NewRegistry()
+
+var (
+	scanDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "docker_index_scan_duration_seconds",
+		Help:    "Time to index an image",
+		Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1200},
+	}, []string{"status"})
+	packagesIndexed = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "docker_index_packages_indexed",
+		Help:    "Number of packages in the sbom of an indexed image",
+		Buckets: prometheus.ExponentialBuckets(10, 2, 10),
+	})
+	cves = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "docker_index_cves_total",
+		Help: "Number of CVEs detected in scanned images",
+	}, []string{"severity"})
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "docker_index_cache_requests_total",
+		Help: "Lookups of images, sboms and vulnerability query responses in the cache",
+	}, []string{"cache", "result"})
+	pullBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "docker_index_registry_pull_bytes_total",
+		Help: "Bytes received from registries while pulling images",
+	})
+)
+
+func init() {
+	Registry.MustRegister(scanDuration, packagesIndexed, cves, cacheRequests, pullBytes)
+}
+
+// ObserveScan records the duration and outcome of indexing an image
+func ObserveScan(start time.Time, err error) {
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+	scanDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+}
+
+// ObservePackages records the number of packages of an indexed image
+func ObservePackages(count int) {
+	packagesIndexed.Observe(float64(count))
+}
+
+// ObserveCve records a detected CVE of the given severity, e.g. CRITICAL
+func ObserveCve(severity string) {
+	cves.WithLabelValues(strings.ToLower(severity)).Inc()
+}
+
+// ObserveCache records a hit or miss of the given cache, e.g. CacheImage
+func ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Push sends the metrics to the Prometheus pushgateway at url, grouped by job
+func Push(url string, job string) error {
+	if err := push.New(url, job).Gatherer(Registry).Push(); err != nil {
+		return errors.Wrapf(err, "failed to push metrics to %s", url)
+	}
+	return nil
+}
+
+// Transport counts the bytes of responses received through rt as registry pull bytes
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return countingTransport{rt: rt}
+}
+
+type countingTransport struct {
+	rt http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = countingReader{ReadCloser: resp.Body}
+	return resp, nil
+}
+
+type countingReader struct {
+	io.ReadCloser
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	pullBytes.Add(float64(n))
+	return n, err
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("layer"))
+	}))
+	defer registry.Close()
+	client := http.Client{Transport: Transport(http.DefaultTransport)}
+	resp, err := client.Get(registry.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	ObserveScan(time.Now(), nil)
+	ObserveCve("CRITICAL")
+	ObserveCache(CacheImage, true)
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	for _, m := range []string{
+		`docker_index_scan_duration_seconds_count{status="success"} 1`,
+		`docker_index_cves_total{severity="critical"} 1`,
+		`docker_index_cache_requests_total{cache="image",result="hit"} 1`,
+		`docker_index_registry_pull_bytes_total 5`,
+	} {
+		if !strings.Contains(string(b), m) {
+			t.Errorf("expected metric %s, got %s", m, b)
+		}
+	}
+}
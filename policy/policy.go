@@ -0,0 +1,236 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/pkg/errors"
+)
+
+// Policy is a set of compiled Rego modules; every package defining deny or warn rules is a rule
+// of the policy
+type Policy struct {
+	compiler *ast.Compiler
+	rules    []rule
+}
+
+type rule struct {
+	path  ast.Ref
+	title string
+	deny  bool
+	warn  bool
+}
+
+// Result is the outcome of evaluating one policy package against an SBOM
+type Result struct {
+	Package    string   `json:"package"`
+	Title      string   `json:"title,omitempty"`
+	Violations []string `json:"violations,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// Passed returns true if no deny rule of the package matched
+func (r Result) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// Name returns the title of the package from its METADATA annotation, or the package path
+func (r Result) Name() string {
+	if r.Title != "" {
+		return r.Title
+	}
+	return r.Package
+}
+
+// Load reads and compiles the .rego files at the given paths, descending into directories
+func Load(paths ...string) (*Policy, error) {
+	modules := make(map[string]*ast.Module)
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (path != p && filepath.Ext(path) != ".rego") || strings.HasSuffix(path, "_test.rego") {
+				return nil
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			module, err := ast.ParseModuleWithOpts(path, string(b), ast.ParserOptions{ProcessAnnotation: true})
+			if err != nil {
+				return err
+			}
+			modules[path] = module
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read policy %s", p)
+		}
+	}
+	compiler := ast.NewCompiler()
+	if compiler.Compile(modules); compiler.Failed() {
+		return nil, errors.Wrap(compiler.Errors, "failed to compile policies")
+	}
+
+	rules := make(map[string]*rule)
+	for _, module := range modules {
+		path := module.Package.Path.String()
+		r, ok := rules[path]
+		if !ok {
+			r = &rule{path: module.Package.Path}
+			rules[path] = r
+		}
+		for _, a := range module.Annotations {
+			if a.Scope == "package" && a.Title != "" {
+				r.title = a.Title
+			}
+		}
+		for _, d := range module.Rules {
+			switch d.Head.Name.String() {
+			case "deny":
+				r.deny = true
+			case "warn":
+				r.warn = true
+			}
+		}
+	}
+	policy := &Policy{compiler: compiler}
+	for _, r := range rules {
+		if r.deny || r.warn {
+			policy.rules = append(policy.rules, *r)
+		}
+	}
+	if len(policy.rules) == 0 {
+		return nil, errors.Errorf("no deny or warn rules found in %s", strings.Join(paths, ", "))
+	}
+	sort.Slice(policy.rules, func(i, j int) bool {
+		return policy.rules[i].path.String() < policy.rules[j].path.String()
+	})
+	return policy, nil
+}
+
+// Evaluate runs the deny and warn rules of every policy package with the SBOM as input
+func (p *Policy) Evaluate(ctx context.Context, sb *types.Sbom) ([]Result, error) {
+	in, err := input(sb)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(p.rules))
+	for _, r := range p.rules {
+		result := Result{
+			Package: strings.TrimPrefix(r.path.String(), "data."),
+			Title:   r.title,
+		}
+		if r.deny {
+			if result.Violations, err = p.messages(ctx, r.path.String()+".deny", in); err != nil {
+				return nil, err
+			}
+		}
+		if r.warn {
+			if result.Warnings, err = p.messages(ctx, r.path.String()+".warn", in); err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Failed returns the results with violations
+func Failed(results []Result) []Result {
+	failed := make([]Result, 0)
+	for _, r := range results {
+		if !r.Passed() {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// messages evaluates the set or array rule at ref and returns its elements as sorted messages
+func (p *Policy) messages(ctx context.Context, ref string, in interface{}) ([]string, error) {
+	rs, err := rego.New(
+		rego.Query(fmt.Sprintf("[m | m := %s[_]]", ref)),
+		rego.Compiler(p.compiler),
+		rego.Input(in),
+	).Eval(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to evaluate %s", ref)
+	}
+	messages := make([]string, 0)
+	for _, r := range rs {
+		for _, e := range r.Expressions {
+			values, _ := e.Value.([]interface{})
+			for _, v := range values {
+				messages = append(messages, message(v))
+			}
+		}
+	}
+	sort.Strings(messages)
+	return messages, nil
+}
+
+// message returns string values as is and the msg field of objects, encoding anything else as JSON
+func message(v interface{}) string {
+	switch m := v.(type) {
+	case string:
+		return m
+	case map[string]interface{}:
+		if msg, ok := m["msg"].(string); ok {
+			return msg
+		}
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// input returns the SBOM as generic JSON value, adding the normalized severity and whether a fix
+// is available and the vulnerability applies to each vulnerability, so policies don't have to
+// pick them from the advisory references
+func input(sb *types.Sbom) (map[string]interface{}, error) {
+	b, err := json.Marshal(sb)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal policy input")
+	}
+	var in map[string]interface{}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal policy input")
+	}
+	if vulnerabilities, ok := in["vulnerabilities"].([]interface{}); ok {
+		for i, v := range vulnerabilities {
+			if c, ok := v.(map[string]interface{}); ok {
+				cve := sb.Vulnerabilities[i]
+				c["severity"] = sbom.Severity(cve)
+				c["fixed"] = sbom.IsFixed(cve)
+				c["affected"] = sbom.IsAffected(cve) && cve.Suppression == nil
+			}
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+const policies = `
+# METADATA
+# title: No critical CVEs with fix available
+package cves
+
+deny[msg] {
+	v := input.vulnerabilities[_]
+	v.affected
+	v.fixed
+	v.severity == "CRITICAL"
+	msg := sprintf("%s in %s is fixed in %s", [v.source_id, v.purl, v.fixed_by])
+}
+
+warn[{"msg": msg}] {
+	v := input.vulnerabilities[_]
+	v.severity == "HIGH"
+	msg := sprintf("%s in %s", [v.source_id, v.purl])
+}
+---
+package licenses
+
+deny[msg] {
+	p := input.artifacts[_]
+	p.licenses[_] == "GPL-3.0"
+	msg := sprintf("%s is licensed under GPL-3.0", [p.purl])
+}
+---
+package base
+
+approved := {"alpine", "debian"}
+
+deny[msg] {
+	base := input.source.image.base_image.name
+	not approved[base]
+	msg := sprintf("base image %s is not approved", [base])
+}
+`
+
+func cve(id string, severity string, fixedBy string) types.Cve {
+	return types.Cve{
+		SourceId: id,
+		Purl:     "pkg:deb/debian/openssl@1.1.1n",
+		FixedBy:  fixedBy,
+		Advisory: &types.Advisory{
+			References: []types.Reference{{
+				Source: "atomist",
+				Scores: []types.Score{{Type: "atm_severity", Value: severity}},
+			}},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	for i, p := range strings.Split(policies, "---") {
+		_ = os.WriteFile(filepath.Join(dir, fmt.Sprintf("policy%d.rego", i)), []byte(p), 0644)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a policy"), 0644)
+	policy, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{BaseImage: &types.BaseImage{Name: "alpine"}}},
+		Artifacts: []types.Package{
+			{Purl: "pkg:deb/debian/bash@5.1", Licenses: []string{"GPL-3.0"}},
+			{Purl: "pkg:deb/debian/openssl@1.1.1n", Licenses: []string{"OpenSSL"}},
+		},
+		Vulnerabilities: []types.Cve{
+			cve("CVE-2022-0001", "CRITICAL", "1.1.1o"),
+			cve("CVE-2022-0002", "CRITICAL", "not fixed"),
+			cve("CVE-2022-0003", "HIGH", ""),
+		},
+	}
+	results, err := policy.Evaluate(context.Background(), sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", results)
+	}
+	if r := results[0]; r.Package != "base" || !r.Passed() {
+		t.Errorf("expected base policy to pass, got %v", r)
+	}
+	if r := results[1]; r.Name() != "No critical CVEs with fix available" || len(r.Violations) != 1 ||
+		r.Violations[0] != "CVE-2022-0001 in pkg:deb/debian/openssl@1.1.1n is fixed in 1.1.1o" ||
+		len(r.Warnings) != 1 || r.Warnings[0] != "CVE-2022-0003 in pkg:deb/debian/openssl@1.1.1n" {
+		t.Errorf("unexpected cves result %v", r)
+	}
+	if r := results[2]; r.Passed() || r.Violations[0] != "pkg:deb/debian/bash@5.1 is licensed under GPL-3.0" {
+		t.Errorf("unexpected licenses result %v", r)
+	}
+	if failed := Failed(results); len(failed) != 2 {
+		t.Errorf("expected 2 failed results, got %v", failed)
+	}
+
+	sb.Source.Image.BaseImage.Name = "ubuntu"
+	sb.Vulnerabilities = nil
+	results, _ = policy.Evaluate(context.Background(), sb)
+	if r := results[0]; r.Passed() || r.Violations[0] != "base image ubuntu is not approved" {
+		t.Errorf("expected base policy to fail, got %v", r)
+	}
+	if r := results[1]; !r.Passed() || len(r.Warnings) != 0 {
+		t.Errorf("expected cves policy to pass, got %v", r)
+	}
+}
+
+func TestLoadWithoutRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "empty.rego")
+	_ = os.WriteFile(file, []byte("package empty\n\nallow = true\n"), 0644)
+	if _, err := Load(file); err == nil {
+		t.Error("expected error for policy without deny or warn rules")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/docker/index-cli-plugin/log"
+)
+
+const (
+	barWidth       = 30
+	renderInterval = 100 * time.Millisecond
+)
+
+// Bar is a Reporter rendering the progress as a single line redrawn in place, meant for
+// interactive terminals. Call Done to clear the line before writing other output.
+type Bar struct {
+	out        io.Writer
+	mu         sync.Mutex
+	line       string
+	rendered   time.Time
+	pulled     int64
+	total      int64
+	extracted  int
+	layers     int
+	catalogers []string
+}
+
+// NewBar returns a Bar writing to out, usually os.Stderr
+func NewBar(out io.Writer) *Bar {
+	return &Bar{out: out}
+}
+
+func (b *Bar) Pulled(n int64, total int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pulled += n
+	b.total = total
+	b.render(fmt.Sprintf("Pulling    %s %s / %s", bar(b.pulled, b.total), units.HumanSize(float64(b.pulled)), units.HumanSize(float64(b.total))), b.pulled >= b.total)
+}
+
+func (b *Bar) LayersExtracted(extracted int, total int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// layers are reported by every cataloger reading the image, only show the furthest one
+	if extracted < b.extracted && total == b.layers {
+		return
+	}
+	b.extracted = extracted
+	b.layers = total
+	b.render(fmt.Sprintf("Extracting %s %d/%d layers", bar(int64(extracted), int64(total)), extracted, total), extracted >= total)
+}
+
+func (b *Bar) CatalogerCompleted(cataloger string, packages int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.catalogers = append(b.catalogers, fmt.Sprintf("%s failed", cataloger))
+	} else {
+		b.catalogers = append(b.catalogers, fmt.Sprintf("%s %d", cataloger, packages))
+	}
+	b.render(fmt.Sprintf("Cataloging %s", strings.Join(b.catalogers, ", ")), true)
+}
+
+func (b *Bar) PackagesFound(count int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.render(fmt.Sprintf("Indexed    %d packages", count), true)
+}
+
+// Done clears the line of the bar
+func (b *Bar) Done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.line != "" {
+		_, _ = fmt.Fprint(b.out, "\r\033[K")
+		b.line = ""
+	}
+}
+
+// Logger returns l writing its messages above the bar instead of into its line
+func (b *Bar) Logger(l log.Logger) log.Logger {
+	return barLogger{bar: b, logger: l}
+}
+
+// write runs log with the line of the bar cleared and redraws it afterwards
+func (b *Bar) write(log func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.line == "" {
+		log()
+		return
+	}
+	_, _ = fmt.Fprint(b.out, "\r\033[K")
+	log()
+	_, _ = fmt.Fprint(b.out, b.line)
+}
+
+type barLogger struct {
+	bar    *Bar
+	logger log.Logger
+}
+
+func (l barLogger) Debugf(format string, args ...interface{}) {
+	l.bar.write(func() { l.logger.Debugf(format, args...) })
+}
+
+func (l barLogger) Infof(format string, args ...interface{}) {
+	l.bar.write(func() { l.logger.Infof(format, args...) })
+}
+
+func (l barLogger) Warnf(format string, args ...interface{}) {
+	l.bar.write(func() { l.logger.Warnf(format, args...) })
+}
+
+func (l barLogger) Errorf(format string, args ...interface{}) {
+	l.bar.write(func() { l.logger.Errorf(format, args...) })
+}
+
+// render redraws the bar with line, at most every renderInterval unless force is set or
+// line starts a new phase
+func (b *Bar) render(line string, force bool) {
+	if line == b.line {
+		return
+	}
+	if !force && phase(line) == phase(b.line) && time.Since(b.rendered) < renderInterval {
+		return
+	}
+	_, _ = fmt.Fprintf(b.out, "\r\033[K%s", line)
+	b.line = line
+	b.rendered = time.Now()
+}
+
+func phase(line string) string {
+	p, _, _ := strings.Cut(line, " ")
+	return p
+}
+
+func bar(current int64, total int64) string {
+	if total <= 0 {
+		return "[" + strings.Repeat(" ", barWidth) + "]"
+	}
+	if current > total {
+		current = total
+	}
+	done := int(current * barWidth / total)
+	return fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("=", done), strings.Repeat(" ", barWidth-done), current*100/total)
+}
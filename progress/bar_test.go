@@ -0,0 +1,93 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	out *bytes.Buffer
+}
+
+func (r recorder) Debugf(format string, args ...interface{}) {}
+func (r recorder) Infof(format string, args ...interface{}) {
+	fmt.Fprintf(r.out, format+"\n", args...)
+}
+func (r recorder) Warnf(format string, args ...interface{})  {}
+func (r recorder) Errorf(format string, args ...interface{}) {}
+
+// lastLine returns the line the bar currently shows in out
+func lastLine(out string) string {
+	lines := strings.Split(out, "\r\033[K")
+	return lines[len(lines)-1]
+}
+
+func TestBar(t *testing.T) {
+	var out bytes.Buffer
+	bar := NewBar(&out)
+
+	bar.Pulled(50, 200)
+	if line := lastLine(out.String()); !strings.HasPrefix(line, "Pulling") || !strings.Contains(line, " 25%") {
+		t.Errorf("unexpected pull progress %q", line)
+	}
+	bar.Pulled(150, 200)
+	if line := lastLine(out.String()); !strings.Contains(line, "100%") {
+		t.Errorf("expected completed pull to be rendered, got %q", line)
+	}
+
+	bar.LayersExtracted(3, 4)
+	bar.LayersExtracted(1, 4)
+	if line := lastLine(out.String()); !strings.Contains(line, "3/4 layers") {
+		t.Errorf("expected furthest layer to be shown, got %q", line)
+	}
+
+	bar.CatalogerCompleted("trivy", 12, nil)
+	bar.CatalogerCompleted("syft", 0, errors.New("failed"))
+	if line := lastLine(out.String()); line != "Cataloging trivy 12, syft failed" {
+		t.Errorf("unexpected cataloger progress %q", line)
+	}
+
+	bar.PackagesFound(12)
+	if line := lastLine(out.String()); line != "Indexed    12 packages" {
+		t.Errorf("unexpected packages %q", line)
+	}
+
+	bar.Done()
+	if !strings.HasSuffix(out.String(), "Indexed    12 packages\r\033[K") {
+		t.Errorf("expected bar to be cleared, got %q", out.String())
+	}
+}
+
+func TestBarLogger(t *testing.T) {
+	var out bytes.Buffer
+	bar := NewBar(&out)
+	logger := bar.Logger(recorder{out: &out})
+
+	logger.Infof("Pulling image %s", "alpine")
+	bar.CatalogerCompleted("syft", 3, nil)
+	logger.Infof("Indexed %d packages", 3)
+
+	expected := "Pulling image alpine\n\r\033[KCataloging syft 3\r\033[KIndexed 3 packages\nCataloging syft 3"
+	if out.String() != expected {
+		t.Errorf("expected message above the bar, got %q", out.String())
+	}
+}
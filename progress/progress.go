@@ -0,0 +1,45 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package progress reports how far indexing an image got, so that long pulls and scans of
+// large images can be shown to users instead of a single "Indexing" message
+package progress
+
+// Reporter receives the progress of indexing an image. Catalogers run concurrently, so
+// implementations must be safe for concurrent use.
+type Reporter interface {
+	// Pulled reports n more bytes of the image written to the cache, out of total bytes
+	Pulled(n int64, total int64)
+	// LayersExtracted reports the number of layers whose file trees were read, out of total
+	LayersExtracted(extracted int, total int)
+	// CatalogerCompleted reports that cataloger finished with the number of packages it found,
+	// or the error it failed with
+	CatalogerCompleted(cataloger string, packages int, err error)
+	// PackagesFound reports the number of packages of the sbom once indexing completed
+	PackagesFound(count int)
+}
+
+// Nop returns a Reporter that ignores all progress
+func Nop() Reporter {
+	return nop{}
+}
+
+type nop struct{}
+
+func (nop) Pulled(int64, int64)                   {}
+func (nop) LayersExtracted(int, int)              {}
+func (nop) CatalogerCompleted(string, int, error) {}
+func (nop) PackagesFound(int)                     {}
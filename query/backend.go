@@ -0,0 +1,148 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	BackendAtomist = "atomist"
+	BackendOsv     = "osv"
+	BackendOffline = "offline"
+)
+
+// Backend matches the packages of a sbom against a vulnerability source
+type Backend interface {
+	// QueryCves returns the vulnerabilities affecting the packages of the sbom, or only
+	// those for the given cve
+	QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error)
+}
+
+// Option configures the backends returned by NewBackend
+type Option func(*settings)
+
+type settings struct {
+	chunkSize int
+	// cacheTTL is how long query responses are cached, cacheUnversioned caches the responses
+	// of backends that can't tell the version of their advisory data as well
+	cacheTTL         time.Duration
+	cacheUnversioned bool
+	timeout          time.Duration
+	enrich           bool
+}
+
+func newSettings(opts []Option) settings {
+	s := settings{chunkSize: DefaultChunkSize, cacheTTL: DefaultCacheTTL}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// WithChunkSize sets the number of packages sent per vulnerability query of the Atomist backend;
+// the queries of an sbom with more packages run concurrently
+func WithChunkSize(size int) Option {
+	return func(s *settings) {
+		if size > 0 {
+			s.chunkSize = size
+		}
+	}
+}
+
+// WithCacheTTL sets how long vulnerability query responses are cached; 0 disables the cache.
+// By default only responses of backends with a data version are cached for DefaultCacheTTL,
+// setting a TTL caches the responses of the others, like the Atomist backend, as well.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(s *settings) {
+		s.cacheTTL = ttl
+		s.cacheUnversioned = true
+	}
+}
+
+// WithTimeout sets how long a vulnerability query of a sbom may take before it fails, 0 waits forever
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *settings) {
+		s.timeout = timeout
+	}
+}
+
+// WithEnrichment adds EPSS scores and the CISA known exploited flag to the vulnerabilities
+// found, see EnrichCves. The offline backend never does as it must not make network requests.
+func WithEnrichment() Option {
+	return func(s *settings) {
+		s.enrich = true
+	}
+}
+
+// NewBackend returns the vulnerability backend with the given name configured by opts
+func NewBackend(name string, workspace string, apiKey string, opts ...Option) (Backend, error) {
+	s := newSettings(opts)
+	b, err := newBackend(name, workspace, apiKey, s)
+	if err != nil {
+		return nil, err
+	}
+	if s.enrich && name != BackendOffline {
+		b = enrichedBackend{backend: b}
+	}
+	if s.timeout <= 0 {
+		return b, nil
+	}
+	return timeoutBackend{backend: b, timeout: s.timeout}, nil
+}
+
+func newBackend(name string, workspace string, apiKey string, s settings) (Backend, error) {
+	switch name {
+	case "", BackendAtomist:
+		return newAtomistBackend(workspace, apiKey, s), nil
+	case BackendOsv:
+		return newCachedBackend(OsvBackend{}, BackendOsv, s), nil
+	case BackendOffline:
+		return OfflineBackend{}, nil
+	default:
+		return nil, errors.Errorf("unsupported vulnerability backend: %s", name)
+	}
+}
+
+// newAtomistBackend returns the Atomist backend caching responses per workspace
+func newAtomistBackend(workspace string, apiKey string, s settings) Backend {
+	name := BackendAtomist
+	if workspace != "" && apiKey != "" {
+		name += "/" + workspace
+	}
+	return newCachedBackend(AtomistBackend{Workspace: workspace, ApiKey: apiKey, ChunkSize: s.chunkSize}, name, s)
+}
+
+// timeoutBackend fails queries of backend taking longer than timeout
+type timeoutBackend struct {
+	backend Backend
+	timeout time.Duration
+}
+
+func (b timeoutBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	cves, err := b.backend.QueryCves(queryCtx, sb, cve)
+	if err != nil && ctx.Err() == nil && queryCtx.Err() == context.DeadlineExceeded {
+		return nil, errors.Wrapf(err, "vulnerability query timed out after %s", b.timeout)
+	}
+	return cves, err
+}
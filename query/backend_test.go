@@ -0,0 +1,59 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// stuckBackend blocks until the query is cancelled
+type stuckBackend struct{}
+
+func (stuckBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestQueryTimeout(t *testing.T) {
+	b := timeoutBackend{backend: stuckBackend{}, timeout: 50 * time.Millisecond}
+	_, err := b.QueryCves(context.Background(), &types.Sbom{}, "")
+	if err == nil || !strings.Contains(err.Error(), "vulnerability query timed out after 50ms") {
+		t.Errorf("expected query to time out, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = b.QueryCves(ctx, &types.Sbom{}, "")
+	if err != context.Canceled {
+		t.Errorf("expected cancellation to be returned, got %v", err)
+	}
+}
+
+func TestNewBackendTimeout(t *testing.T) {
+	b, err := NewBackend(BackendOffline, "", "", WithTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(timeoutBackend); !ok {
+		t.Errorf("expected backend to be limited by timeout, got %T", b)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/metrics"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// DefaultCacheTTL is how long vulnerability query responses are cached by default
+const DefaultCacheTTL = time.Hour
+
+// versioned is implemented by backends that can tell the version of their advisory data.
+// Cached responses of an older version are not used.
+type versioned interface {
+	DataVersion(ctx context.Context, sb *types.Sbom) (string, error)
+}
+
+// cachedBackend caches the vulnerabilities returned by backend keyed by the set of purls
+// queried, so that repeated scans of the same packages don't query them again
+type cachedBackend struct {
+	backend Backend
+	// name identifies the backend and workspace the responses were cached for
+	name string
+	ttl  time.Duration
+	// unversioned caches the responses of a backend that can't tell the version of its
+	// advisory data, which then may be outdated until they expire
+	unversioned bool
+}
+
+func newCachedBackend(backend Backend, name string, s settings) cachedBackend {
+	return cachedBackend{backend: backend, name: name, ttl: s.cacheTTL, unversioned: s.cacheUnversioned}
+}
+
+type queryCacheEntry struct {
+	Created     time.Time   `json:"created"`
+	Version     string      `json:"version"`
+	DataVersion string      `json:"data_version,omitempty"`
+	Cves        []types.Cve `json:"cves"`
+}
+
+func (b cachedBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	if b.ttl <= 0 {
+		return b.backend.QueryCves(ctx, sb, cve)
+	}
+	var dataVersion string
+	if v, ok := b.backend.(versioned); ok {
+		var err error
+		if dataVersion, err = v.DataVersion(ctx, sb); err != nil {
+			log.Debugf("Skipping query cache as advisory data version is unknown: %s", err)
+			return b.backend.QueryCves(ctx, sb, cve)
+		}
+	} else if !b.unversioned {
+		return b.backend.QueryCves(ctx, sb, cve)
+	}
+
+	path := queryCachePath(b.name, sb, cve)
+	if cves, ok := readQueryCache(path, dataVersion, b.ttl); ok {
+		metrics.ObserveCache(metrics.CacheQuery, true)
+		log.Infof("Using %d cached vulnerabilities", len(cves))
+		return &cves, nil
+	}
+	metrics.ObserveCache(metrics.CacheQuery, false)
+	cves, err := b.backend.QueryCves(ctx, sb, cve)
+	if err == nil && cves != nil {
+		writeQueryCache(path, dataVersion, *cves)
+	}
+	return cves, err
+}
+
+// queryCachePath returns the cache file of the query for the purls of the sbom
+func queryCachePath(name string, sb *types.Sbom, cve string) string {
+	purls := make([]string, 0, len(sb.Artifacts))
+	for _, p := range sb.Artifacts {
+		purls = append(purls, p.Purl)
+	}
+	sort.Strings(purls)
+	key := internal.Hash(fmt.Sprintf("%s\n%s\n%s", name, cve, strings.Join(purls, "\n")))
+	return filepath.Join(internal.CachePath(), "queries", key+".json")
+}
+
+func readQueryCache(path string, dataVersion string, ttl time.Duration) ([]types.Cve, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry queryCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, false
+	}
+	if time.Since(entry.Created) > ttl || entry.Version != internal.FromBuild().Version || entry.DataVersion != dataVersion {
+		return nil, false
+	}
+	return entry.Cves, true
+}
+
+func writeQueryCache(path string, dataVersion string, cves []types.Cve) {
+	b, err := json.Marshal(queryCacheEntry{
+		Created:     time.Now().UTC(),
+		Version:     internal.FromBuild().Version,
+		DataVersion: dataVersion,
+		Cves:        cves,
+	})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, b, 0644)
+}
+
+// DataVersion returns the modification times of the OSV advisory exports of the ecosystems
+// of the sbom packages
+func (b OsvBackend) DataVersion(ctx context.Context, sb *types.Sbom) (string, error) {
+	ecosystems := make([]string, 0)
+	for _, p := range sb.Artifacts {
+		if purl, err := types.ToPackageUrl(p.Purl); err == nil {
+			if e, ok := purlEcosystems[purl.Type]; ok && !internal.Contains(ecosystems, e) {
+				ecosystems = append(ecosystems, e)
+			}
+		}
+	}
+	sort.Strings(ecosystems)
+
+	versions := make([]string, 0)
+	for _, e := range ecosystems {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, fmt.Sprintf(osvDumpUrl, e), nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := doWithRetry(internal.HttpClient(), req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", errors.Errorf("failed to read version of %s advisories: %s", e, resp.Status)
+		}
+		version := resp.Header.Get("ETag")
+		if version == "" {
+			version = resp.Header.Get("Last-Modified")
+		}
+		versions = append(versions, e+"="+version)
+	}
+	return strings.Join(versions, ","), nil
+}
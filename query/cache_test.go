@@ -0,0 +1,104 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+type countingBackend struct {
+	queries *int
+	version *string
+}
+
+func (b countingBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	*b.queries++
+	return &[]types.Cve{{Purl: sb.Artifacts[0].Purl, SourceId: "CVE-2022-0001"}}, nil
+}
+
+func (b countingBackend) DataVersion(ctx context.Context, sb *types.Sbom) (string, error) {
+	return *b.version, nil
+}
+
+func TestCachedBackend(t *testing.T) {
+	internal.SetCachePath(t.TempDir())
+	defer internal.SetCachePath("")
+
+	queries, version := 0, "1"
+	b := newCachedBackend(countingBackend{queries: &queries, version: &version}, "test", newSettings(nil))
+	sb := &types.Sbom{Artifacts: []types.Package{{Purl: "pkg:npm/lodash@4.17.20"}, {Purl: "pkg:npm/express@4.18.2"}}}
+	reordered := &types.Sbom{Artifacts: []types.Package{sb.Artifacts[1], sb.Artifacts[0]}}
+
+	for _, s := range []*types.Sbom{sb, reordered} {
+		cves, err := b.QueryCves(context.Background(), s, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*cves) != 1 || (*cves)[0].SourceId != "CVE-2022-0001" {
+			t.Errorf("unexpected vulnerabilities %v", *cves)
+		}
+	}
+	if queries != 1 {
+		t.Errorf("expected 1 query of the same packages, got %d", queries)
+	}
+
+	version = "2"
+	_, _ = b.QueryCves(context.Background(), sb, "")
+	if queries != 2 {
+		t.Errorf("expected query after data version changed, got %d queries", queries)
+	}
+
+	b = newCachedBackend(b.backend, "test", newSettings([]Option{WithCacheTTL(0)}))
+	_, _ = b.QueryCves(context.Background(), sb, "")
+	if queries != 3 {
+		t.Errorf("expected query with disabled cache, got %d queries", queries)
+	}
+}
+
+type unversionedBackend struct {
+	queries *int
+}
+
+func (b unversionedBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	*b.queries++
+	return &[]types.Cve{}, nil
+}
+
+func TestCachedBackendUnversioned(t *testing.T) {
+	internal.SetCachePath(t.TempDir())
+	defer internal.SetCachePath("")
+
+	queries := 0
+	b := newCachedBackend(unversionedBackend{queries: &queries}, "test", newSettings(nil))
+	sb := &types.Sbom{Artifacts: []types.Package{{Purl: "pkg:npm/lodash@4.17.20"}}}
+	_, _ = b.QueryCves(context.Background(), sb, "")
+	_, _ = b.QueryCves(context.Background(), sb, "")
+	if queries != 2 {
+		t.Errorf("expected backend without data version not to be cached by default, got %d queries", queries)
+	}
+
+	b = newCachedBackend(b.backend, "test", newSettings([]Option{WithCacheTTL(DefaultCacheTTL)}))
+	_, _ = b.QueryCves(context.Background(), sb, "")
+	_, _ = b.QueryCves(context.Background(), sb, "")
+	if queries != 3 {
+		t.Errorf("expected backend without data version to be cached with explicit TTL, got %d queries", queries)
+	}
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+var osvDumpUrl = "https://osv-vulnerabilities.storage.googleapis.com/%s/all.zip"
+
+// DefaultEcosystems are the OSV ecosystems downloaded into the local vulnerability database
+var DefaultEcosystems = []string{"npm", "PyPI", "Maven", "Go", "RubyGems", "crates.io", "NuGet", "Packagist", "Debian", "Alpine"}
+
+type DbMetadata struct {
+	Updated    time.Time `json:"updated"`
+	Ecosystems []string  `json:"ecosystems"`
+}
+
+// DbPath returns the directory of the local vulnerability database
+func DbPath() string {
+	return filepath.Join(internal.CachePath(), "vulnerability-db")
+}
+
+// ReadDbMetadata returns the metadata of the local vulnerability database
+func ReadDbMetadata() (*DbMetadata, error) {
+	b, err := os.ReadFile(filepath.Join(DbPath(), "metadata.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "no local vulnerability database found, run docker index db download")
+	}
+	var metadata DbMetadata
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, errors.Wrap(err, "failed to parse vulnerability database metadata")
+	}
+	return &metadata, nil
+}
+
+// DownloadDb downloads the OSV advisories of the given ecosystems into the local vulnerability database
+func DownloadDb(ecosystems []string) error {
+	path := DbPath()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create vulnerability database directory %s", path)
+	}
+	for _, ecosystem := range ecosystems {
+		log.Infof("Downloading %s advisories", ecosystem)
+		entries, err := downloadOsvDump(ecosystem)
+		if err != nil {
+			return err
+		}
+		if err := writeDbEntries(filepath.Join(path, dbFileName(ecosystem)), entries); err != nil {
+			return err
+		}
+		log.Infof("Stored %d %s advisories", len(entries), ecosystem)
+	}
+	metadata, err := json.MarshalIndent(DbMetadata{Updated: time.Now().UTC(), Ecosystems: ecosystems}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, "metadata.json"), metadata, 0644)
+}
+
+// UpdateDb downloads the ecosystems already present in the local vulnerability database again
+func UpdateDb() error {
+	metadata, err := ReadDbMetadata()
+	if err != nil {
+		return err
+	}
+	return DownloadDb(metadata.Ecosystems)
+}
+
+// OfflineBackend matches packages against the local vulnerability database
+type OfflineBackend struct{}
+
+func (b OfflineBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	metadata, err := ReadDbMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if age := time.Since(metadata.Updated); age > 7*24*time.Hour {
+		log.Warnf("Local vulnerability database is %d days old, run docker index db update", int(age.Hours()/24))
+	}
+
+	entries := make(map[string]map[string][]OsvEntry)
+	cves := make([]types.Cve, 0)
+	for _, p := range sb.Artifacts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		purl, err := types.ToPackageUrl(p.Purl)
+		if err != nil {
+			continue
+		}
+		ecosystem, ok := purlEcosystems[purl.Type]
+		if !ok {
+			continue
+		}
+		if _, ok := entries[ecosystem]; !ok {
+			entries[ecosystem], err = readDbEntries(filepath.Join(DbPath(), dbFileName(ecosystem)))
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, e := range entries[ecosystem][osvPackageName(purl.Type, purl.Namespace, purl.Name)] {
+			c, ok := matchOsvEntry(e, ecosystem, p)
+			if !ok || (cve != "" && c.SourceId != cve && !internal.Contains(e.Aliases, cve) && e.Id != cve) {
+				continue
+			}
+			cves = append(cves, c)
+		}
+	}
+	log.Infof("Detected %d vulnerabilities", len(cves))
+	return &cves, nil
+}
+
+func downloadOsvDump(ecosystem string) ([]OsvEntry, error) {
+	resp, err := internal.HttpClient().Get(fmt.Sprintf(osvDumpUrl, ecosystem))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download %s advisories", ecosystem)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to download %s advisories: %s", ecosystem, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp("", "osv-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download %s advisories", ecosystem)
+	}
+
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s advisories", ecosystem)
+	}
+	entries := make([]OsvEntry, 0)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		var entry OsvEntry
+		err = json.NewDecoder(r).Decode(&entry)
+		r.Close()
+		if err != nil {
+			log.Debugf("Skipping invalid advisory %s: %s", f.Name, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func writeDbEntries(path string, entries []OsvEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	if err := json.NewEncoder(gw).Encode(entries); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return gw.Close()
+}
+
+// readDbEntries reads the advisories of one ecosystem indexed by package name
+func readDbEntries(path string) (map[string][]OsvEntry, error) {
+	index := make(map[string][]OsvEntry)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	var entries []OsvEntry
+	if err := json.NewDecoder(gr).Decode(&entries); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	for _, e := range entries {
+		names := make([]string, 0)
+		for _, a := range e.Affected {
+			if !internal.Contains(names, a.Package.Name) {
+				names = append(names, a.Package.Name)
+			}
+		}
+		for _, n := range names {
+			index[n] = append(index[n], e)
+		}
+	}
+	return index, nil
+}
+
+func dbFileName(ecosystem string) string {
+	return strings.ToLower(ecosystem) + ".json.gz"
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	epssUrl = "https://api.first.org/data/v1/epss"
+	kevUrl  = "https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json"
+)
+
+// kevCacheTTL is how long the CISA known exploited vulnerabilities catalog is reused from the cache
+const kevCacheTTL = 24 * time.Hour
+
+// enrichedBackend enriches the vulnerabilities found by backend
+type enrichedBackend struct {
+	backend Backend
+}
+
+func (b enrichedBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	cves, err := b.backend.QueryCves(ctx, sb, cve)
+	if err == nil && cves != nil {
+		EnrichCvesContext(ctx, *cves)
+	}
+	return cves, err
+}
+
+type epssResponse struct {
+	Data []struct {
+		Cve        string `json:"cve"`
+		Epss       string `json:"epss"`
+		Percentile string `json:"percentile"`
+	} `json:"data"`
+}
+
+type kevCatalog struct {
+	Vulnerabilities []struct {
+		CveId string `json:"cveID"`
+	} `json:"vulnerabilities"`
+}
+
+// EnrichCves adds EPSS scores and the CISA known exploited flag to the vulnerabilities.
+// Failures to reach either feed are logged and leave the vulnerabilities untouched.
+func EnrichCves(cves []types.Cve) {
+	EnrichCvesContext(context.Background(), cves)
+}
+
+// EnrichCvesContext is EnrichCves with a context to cancel the feed requests
+func EnrichCvesContext(ctx context.Context, cves []types.Cve) {
+	ids := make([]string, 0)
+	for _, c := range cves {
+		if id := CveId(c); id != "" && !internal.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	scores, err := queryEpss(ctx, ids)
+	if err != nil {
+		log.Warnf("Failed to query EPSS scores: %s", err)
+	}
+	kev, err := queryKev(ctx)
+	if err != nil {
+		log.Warnf("Failed to query CISA known exploited vulnerabilities: %s", err)
+	}
+	for i, c := range cves {
+		id := CveId(c)
+		if epss, ok := scores[id]; ok {
+			cves[i].Epss = &epss
+		}
+		cves[i].KnownExploited = kev[id]
+	}
+}
+
+// CveId returns the CVE identifier of the vulnerability, which might be an alias of
+// a vendor advisory
+func CveId(c types.Cve) string {
+	if strings.HasPrefix(c.SourceId, "CVE-") {
+		return c.SourceId
+	}
+	if c.Cve != nil && strings.HasPrefix(c.Cve.SourceId, "CVE-") {
+		return c.Cve.SourceId
+	}
+	return ""
+}
+
+func queryEpss(ctx context.Context, ids []string) (map[string]types.Epss, error) {
+	scores := make(map[string]types.Epss)
+	for _, chunk := range internal.ChunkSlice(ids, 100) {
+		var result epssResponse
+		if err := getJson(ctx, fmt.Sprintf("%s?cve=%s", epssUrl, strings.Join(chunk, ",")), &result); err != nil {
+			return scores, err
+		}
+		for _, d := range result.Data {
+			score, _ := strconv.ParseFloat(d.Epss, 64)
+			percentile, _ := strconv.ParseFloat(d.Percentile, 64)
+			scores[d.Cve] = types.Epss{Score: score, Percentile: percentile}
+		}
+	}
+	return scores, nil
+}
+
+// queryKev returns the CVE ids of the CISA known exploited vulnerabilities catalog, which is
+// cached for kevCacheTTL. A stale cached catalog is used if the download fails.
+func queryKev(ctx context.Context) (map[string]bool, error) {
+	kev := make(map[string]bool)
+	var catalog kevCatalog
+	path := filepath.Join(internal.CachePath(), "kev", "known_exploited_vulnerabilities.json")
+	info, statErr := os.Stat(path)
+	if statErr != nil || time.Since(info.ModTime()) > kevCacheTTL {
+		if err := getJson(ctx, kevUrl, &catalog); err != nil {
+			if statErr != nil {
+				return kev, err
+			}
+			log.Debugf("Using stale CISA known exploited vulnerabilities: %s", err)
+		} else {
+			writeKevCache(path, catalog)
+		}
+	}
+	if len(catalog.Vulnerabilities) == 0 {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return kev, errors.Wrap(err, "failed to read cached CISA known exploited vulnerabilities")
+		}
+		if err := json.Unmarshal(b, &catalog); err != nil {
+			return kev, errors.Wrap(err, "failed to parse cached CISA known exploited vulnerabilities")
+		}
+	}
+	for _, v := range catalog.Vulnerabilities {
+		kev[v.CveId] = true
+	}
+	return kev, nil
+}
+
+func writeKevCache(path string, catalog kevCatalog) {
+	b, err := json.Marshal(catalog)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, b, 0644)
+}
+
+func getJson(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create http request")
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("index-cli-plugin/%s", internal.FromBuild().Version))
+	resp, err := doWithRetry(internal.HttpClient(), req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "failed to unmarshal response from %s", url)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestNewBackendEnrichment(t *testing.T) {
+	tests := []struct {
+		name     string
+		backend  string
+		enrich   bool
+		enriched bool
+	}{
+		{name: "disabled", backend: BackendOsv},
+		{name: "enabled", backend: BackendOsv, enrich: true, enriched: true},
+		{name: "offline", backend: BackendOffline, enrich: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := make([]Option, 0)
+			if test.enrich {
+				opts = append(opts, WithEnrichment())
+			}
+			b, err := NewBackend(test.backend, "", "", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := b.(enrichedBackend); ok != test.enriched {
+				t.Errorf("expected enriched %v, got %T", test.enriched, b)
+			}
+		})
+	}
+}
+
+func TestEnrichedBackend(t *testing.T) {
+	internal.SetCachePath(t.TempDir())
+	defer internal.SetCachePath("")
+
+	kevRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/epss":
+			_, _ = w.Write([]byte(`{"data": [{"cve": "CVE-2022-0001", "epss": "0.5", "percentile": "0.9"}]}`))
+		case "/kev":
+			kevRequests++
+			if kevRequests > 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"vulnerabilities": [{"cveID": "CVE-2022-0001"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer func(epss, kev string) { epssUrl, kevUrl = epss, kev }(epssUrl, kevUrl)
+	epssUrl, kevUrl = server.URL+"/epss", server.URL+"/kev"
+
+	queries, version := 0, "1"
+	b := enrichedBackend{backend: countingBackend{queries: &queries, version: &version}}
+	sb := &types.Sbom{Artifacts: []types.Package{{Purl: "pkg:npm/lodash@4.17.20"}}}
+	for i := 0; i < 2; i++ {
+		cves, err := b.QueryCves(context.Background(), sb, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := (*cves)[0]
+		if c.Epss == nil || c.Epss.Score != 0.5 || !c.KnownExploited {
+			t.Errorf("expected vulnerability to be enriched, got %+v", c)
+		}
+	}
+	if kevRequests != 1 {
+		t.Errorf("expected cached CISA catalog to be reused, got %d requests", kevRequests)
+	}
+}
@@ -0,0 +1,309 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+var osvApiUrl = "https://api.osv.dev/v1"
+
+// purlEcosystems maps the purl types of normalized packages onto OSV ecosystems, see
+// types.PackageTypeMapping
+var purlEcosystems = map[string]string{
+	"npm":      "npm",
+	"pypi":     "PyPI",
+	"maven":    "Maven",
+	"golang":   "Go",
+	"gem":      "RubyGems",
+	"cargo":    "crates.io",
+	"nuget":    "NuGet",
+	"composer": "Packagist",
+	"deb":      "Debian",
+	"alpine":   "Alpine",
+	// Alpine packages converted from SBOMs of other tools
+	"apk": "Alpine",
+}
+
+type OsvEntry struct {
+	Id               string         `json:"id"`
+	Aliases          []string       `json:"aliases,omitempty"`
+	Details          string         `json:"details,omitempty"`
+	Affected         []OsvAffected  `json:"affected"`
+	References       []OsvReference `json:"references,omitempty"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity,omitempty"`
+	} `json:"database_specific"`
+}
+
+type OsvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges   []OsvRange `json:"ranges,omitempty"`
+	Versions []string   `json:"versions,omitempty"`
+}
+
+type OsvRange struct {
+	Type   string              `json:"type"`
+	Events []map[string]string `json:"events"`
+}
+
+type OsvReference struct {
+	Type string `json:"type"`
+	Url  string `json:"url"`
+}
+
+type osvBatchQuery struct {
+	Queries []osvQuery `json:"queries"`
+}
+
+type osvQuery struct {
+	Package struct {
+		Purl string `json:"purl"`
+	} `json:"package"`
+}
+
+type osvBatchResponse struct {
+	Results []struct {
+		Vulns []struct {
+			Id string `json:"id"`
+		} `json:"vulns"`
+	} `json:"results"`
+}
+
+// OsvBackend queries vulnerabilities from the public OSV.dev API
+type OsvBackend struct{}
+
+func (b OsvBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	pkgs := make([]types.Package, 0)
+	for _, p := range sb.Artifacts {
+		if purl, err := types.ToPackageUrl(p.Purl); err == nil && purl.Version != "" {
+			if _, ok := purlEcosystems[purl.Type]; ok {
+				pkgs = append(pkgs, p)
+			}
+		}
+	}
+
+	entries := make(map[string]OsvEntry)
+	cves := make([]types.Cve, 0)
+	for _, chunk := range internal.ChunkSlice(pkgs, 1000) {
+		batch := osvBatchQuery{Queries: make([]osvQuery, len(chunk))}
+		for i, p := range chunk {
+			purl, _ := types.ToPackageUrl(p.Purl)
+			purl.Qualifiers = nil
+			purl.Subpath = ""
+			// OSV only knows the apk type of the purl spec for Alpine packages
+			if purl.Type == "alpine" {
+				purl.Type = "apk"
+				purl.Namespace = "alpine"
+			}
+			batch.Queries[i].Package.Purl = purl.ToString()
+		}
+		var result osvBatchResponse
+		if err := postJson(ctx, osvApiUrl+"/querybatch", batch, &result); err != nil {
+			return nil, errors.Wrap(err, "failed to query OSV")
+		}
+
+		for i, r := range result.Results {
+			if i >= len(chunk) {
+				break
+			}
+			p := chunk[i]
+			purl, _ := types.ToPackageUrl(p.Purl)
+			for _, v := range r.Vulns {
+				e, ok := entries[v.Id]
+				if !ok {
+					if err := getJson(ctx, fmt.Sprintf("%s/vulns/%s", osvApiUrl, v.Id), &e); err != nil {
+						return nil, errors.Wrapf(err, "failed to query OSV advisory %s", v.Id)
+					}
+					entries[v.Id] = e
+				}
+				// OSV already matched the version, the local match only adds range and fix details
+				c, ok := matchOsvEntry(e, purlEcosystems[purl.Type], p)
+				if !ok {
+					c = toOsvCve(e, p, "", "not fixed")
+				}
+				if cve != "" && c.SourceId != cve && e.Id != cve && !internal.Contains(e.Aliases, cve) {
+					continue
+				}
+				cves = append(cves, c)
+			}
+		}
+	}
+	log.Infof("Detected %d vulnerabilities", len(cves))
+	return &cves, nil
+}
+
+func osvPackageName(purlType, namespace, name string) string {
+	switch purlType {
+	case "maven":
+		return namespace + ":" + name
+	case "golang", "composer", "npm":
+		if namespace != "" {
+			return namespace + "/" + name
+		}
+	}
+	return name
+}
+
+// matchOsvEntry checks if the version of the package is affected by the advisory
+func matchOsvEntry(e OsvEntry, ecosystem string, p types.Package) (types.Cve, bool) {
+	for _, a := range e.Affected {
+		// distro ecosystems are suffixed with the release, e.g. Debian:11
+		if a.Package.Ecosystem != ecosystem && !strings.HasPrefix(a.Package.Ecosystem, ecosystem+":") {
+			continue
+		}
+		affected := internal.Contains(a.Versions, p.Version)
+		vulnerableRange, fixedBy := "", "not fixed"
+		for _, r := range a.Ranges {
+			if r.Type == "GIT" {
+				continue
+			}
+			ok, vr, fixed := inOsvRange(r, p.Version)
+			vulnerableRange = vr
+			if fixed != "" {
+				fixedBy = fixed
+			}
+			if ok {
+				affected = true
+				break
+			}
+		}
+		if !affected {
+			continue
+		}
+		return toOsvCve(e, p, vulnerableRange, fixedBy), true
+	}
+	return types.Cve{}, false
+}
+
+// inOsvRange evaluates the introduced/fixed/last_affected events of a range against version
+func inOsvRange(r OsvRange, version string) (bool, string, string) {
+	introduced, fixed, lastAffected := "", "", ""
+	for _, e := range r.Events {
+		if v, ok := e["introduced"]; ok {
+			introduced = v
+			fixed, lastAffected = "", ""
+		}
+		if v, ok := e["fixed"]; ok {
+			fixed = v
+		}
+		if v, ok := e["last_affected"]; ok {
+			lastAffected = v
+		}
+		if fixed == "" && lastAffected == "" {
+			continue
+		}
+		if (introduced == "0" || CompareVersions(version, introduced) >= 0) &&
+			((fixed != "" && CompareVersions(version, fixed) < 0) || (lastAffected != "" && CompareVersions(version, lastAffected) <= 0)) {
+			return true, toVulnerableRange(introduced, fixed, lastAffected), fixed
+		}
+	}
+	if fixed == "" && lastAffected == "" && introduced != "" &&
+		(introduced == "0" || CompareVersions(version, introduced) >= 0) {
+		return true, toVulnerableRange(introduced, "", ""), ""
+	}
+	return false, "", ""
+}
+
+func toVulnerableRange(introduced, fixed, lastAffected string) string {
+	parts := make([]string, 0)
+	if introduced != "" && introduced != "0" {
+		parts = append(parts, ">="+introduced)
+	}
+	if fixed != "" {
+		parts = append(parts, "<"+fixed)
+	}
+	if lastAffected != "" {
+		parts = append(parts, "<="+lastAffected)
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+func toOsvCve(e OsvEntry, p types.Package, vulnerableRange, fixedBy string) types.Cve {
+	sourceId := e.Id
+	for _, a := range e.Aliases {
+		if strings.HasPrefix(a, "CVE-") {
+			sourceId = a
+			break
+		}
+	}
+	severity := strings.ToUpper(e.DatabaseSpecific.Severity)
+	if severity == "MODERATE" {
+		severity = "MEDIUM"
+	}
+	advisory := &types.Advisory{
+		Source:      "osv",
+		SourceId:    e.Id,
+		Description: e.Details,
+		References:  []types.Reference{},
+	}
+	if severity != "" {
+		advisory.References = append(advisory.References, types.Reference{
+			Source: "atomist",
+			Scores: []types.Score{{Type: "atm_severity", Value: severity}},
+		})
+	}
+	for _, r := range e.References {
+		advisory.Urls = append(advisory.Urls, types.Url{Name: r.Type, Value: r.Url})
+	}
+	return types.Cve{
+		Purl:            p.Purl,
+		Source:          "osv",
+		SourceId:        sourceId,
+		VulnerableRange: vulnerableRange,
+		FixedBy:         fixedBy,
+		Advisory:        advisory,
+	}
+}
+
+func postJson(ctx context.Context, url string, body interface{}, v interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create http request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("index-cli-plugin/%s", internal.FromBuild().Version))
+	resp, err := doWithRetry(internal.HttpClient(), req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to post to %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to post to %s: %s", url, resp.Status)
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "failed to unmarshal response from %s", url)
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestOsvBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/querybatch":
+			_, _ = w.Write([]byte(`{"results": [{"vulns": [{"id": "GHSA-35jh-r3h4-6jhm"}]}, {}]}`))
+		case "/vulns/GHSA-35jh-r3h4-6jhm":
+			_, _ = w.Write([]byte(`{
+  "id": "GHSA-35jh-r3h4-6jhm",
+  "aliases": ["CVE-2021-23337"],
+  "affected": [{"package": {"ecosystem": "npm", "name": "lodash"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "4.17.21"}]}]}],
+  "database_specific": {"severity": "HIGH"}
+}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	osvApiUrl = server.URL
+
+	sb := &types.Sbom{Artifacts: []types.Package{
+		{Purl: "pkg:npm/lodash@4.17.20", Version: "4.17.20"},
+		{Purl: "pkg:npm/express@4.18.2", Version: "4.18.2"},
+	}}
+	cves, err := OsvBackend{}.QueryCves(context.Background(), sb, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*cves) != 1 {
+		t.Fatalf("expected 1 vulnerability, got %d", len(*cves))
+	}
+	c := (*cves)[0]
+	if c.SourceId != "CVE-2021-23337" || c.FixedBy != "4.17.21" || c.Purl != "pkg:npm/lodash@4.17.20" {
+		t.Errorf("unexpected vulnerability %+v", c)
+	}
+}
+
+func TestOsvBackendAlpine(t *testing.T) {
+	var queried string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/querybatch":
+			var batch osvBatchQuery
+			_ = json.NewDecoder(r.Body).Decode(&batch)
+			queried = batch.Queries[0].Package.Purl
+			_, _ = w.Write([]byte(`{"results": [{"vulns": [{"id": "ALPINE-CVE-2022-28391"}]}]}`))
+		case "/vulns/ALPINE-CVE-2022-28391":
+			_, _ = w.Write([]byte(`{
+  "id": "ALPINE-CVE-2022-28391",
+  "aliases": ["CVE-2022-28391"],
+  "affected": [{"package": {"ecosystem": "Alpine:v3.16", "name": "busybox"}, "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.35.0-r18"}]}]}]
+}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	osvApiUrl = server.URL
+
+	// the purl type of Alpine packages cataloged by syft
+	pkgs, err := types.NormalizePackages([]types.Package{{Purl: "pkg:alpine/busybox@1.35.0-r17?arch=x86_64&os_name=alpine&os_version=3.16"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cves, err := OsvBackend{}.QueryCves(context.Background(), &types.Sbom{Artifacts: pkgs}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queried != "pkg:apk/alpine/busybox@1.35.0-r17" {
+		t.Errorf("expected apk purl to be queried, got %s", queried)
+	}
+	if len(*cves) != 1 || (*cves)[0].FixedBy != "1.35.0-r18" {
+		t.Errorf("expected fixed busybox vulnerability, got %+v", *cves)
+	}
+}
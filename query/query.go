@@ -17,15 +17,18 @@
 package query
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
 
 	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
 	"github.com/docker/index-cli-plugin/types"
 
-	"github.com/atomist-skills/go-skill"
 	"github.com/pkg/errors"
 	"olympos.io/encoding/edn"
 )
@@ -50,19 +53,103 @@ var packageCvesQuery string
 var packageCveQuery string
 
 func CheckAuth(workspace string, apiKey string) (bool, error) {
-	resp, err := query(enabledSkillsQuery, "auth_check", workspace, apiKey)
+	resp, err := query(context.Background(), enabledSkillsQuery, "auth_check", workspace, apiKey)
+	if IsAuthError(err) {
+		return false, nil
+	}
 	if err != nil {
 		return false, errors.Wrap(err, "failed to check auth")
 	}
-	if resp.StatusCode != 200 || err != nil {
-		return false, nil
-	}
+	resp.Body.Close()
 	return true, nil
 }
 
+// DefaultChunkSize is the default number of packages sent per vulnerability query
+const DefaultChunkSize = 500
+
+var queryParallelism = 4
+
+// QueryCves returns the vulnerabilities affecting the packages of the sbom, or only those
+// for the given cve. Setting ATOMIST_OFFLINE uses the local vulnerability database.
 func QueryCves(sb *types.Sbom, cve string, workspace string, apiKey string) (*[]types.Cve, error) {
+	return QueryCvesContext(context.Background(), sb, cve, workspace, apiKey)
+}
+
+// QueryCvesContext is QueryCves with a context to cancel the query
+func QueryCvesContext(ctx context.Context, sb *types.Sbom, cve string, workspace string, apiKey string) (*[]types.Cve, error) {
+	if _, ok := os.LookupEnv("ATOMIST_OFFLINE"); ok {
+		return OfflineBackend{}.QueryCves(ctx, sb, cve)
+	}
+	return newAtomistBackend(workspace, apiKey, newSettings(nil)).QueryCves(ctx, sb, cve)
+}
+
+// AtomistBackend queries vulnerabilities from the Atomist datalog API
+type AtomistBackend struct {
+	Workspace string
+	ApiKey    string
+	// ChunkSize is the number of packages sent per query, DefaultChunkSize if 0
+	ChunkSize int
+}
+
+func (b AtomistBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunkSize := b.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	chunks := internal.ChunkSlice(sb.Artifacts, chunkSize)
+	results := make([]*[]types.Cve, len(chunks))
+	errs := make([]error, len(chunks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < queryParallelism && w < len(chunks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j], errs[j] = b.queryChunk(ctx, chunks[j], cve)
+				if errs[j] != nil {
+					// the other chunks are useless without this one
+					cancel()
+				}
+			}
+		}()
+	}
+	for j := range chunks {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cves := make([]types.Cve, 0)
+	for _, r := range results {
+		if r != nil {
+			cves = append(cves, *r...)
+		}
+	}
+	if len(cves) == 1 {
+		log.Infof("Detected %d vulnerability", len(cves))
+	} else {
+		log.Infof("Detected %d vulnerabilities", len(cves))
+	}
+	return &cves, nil
+}
+
+// queryChunk queries the vulnerabilities of one chunk of the packages
+func (b AtomistBackend) queryChunk(ctx context.Context, chunk []types.Package, cve string) (*[]types.Cve, error) {
 	pkgs := make([]string, 0)
-	for _, p := range sb.Artifacts {
+	for _, p := range chunk {
 		pkgs = append(pkgs, fmt.Sprintf(`["%s" "%s" "%s" "%s"]`, p.Purl, p.Type, p.Version, types.ToAdvisoryUrl(p)))
 	}
 
@@ -74,33 +161,34 @@ func QueryCves(sb *types.Sbom, cve string, workspace string, apiKey string) (*[]
 		q = fmt.Sprintf(packageCveQuery, cve, strings.Join(pkgs, " "))
 		name = "cve_query"
 	}
-	resp, err := query(q, name, workspace, apiKey)
+	resp, err := query(ctx, q, name, b.Workspace, b.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 	var result QueryResult
 	err = edn.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal response")
 	}
 	if len(result.Query.Data) > 0 {
-		if len(result.Query.Data) == 1 {
-			skill.Log.Infof("Detected %d vulnerability", len(result.Query.Data[0].Cves))
-		} else {
-			skill.Log.Infof("Detected %d vulnerabilities", len(result.Query.Data[0].Cves))
-		}
 		return &result.Query.Data[0].Cves, nil
-	} else {
-		return nil, nil
 	}
+	return nil, nil
 }
 
-func query(query string, name string, workspace string, apiKey string) (*http.Response, error) {
-	url := fmt.Sprintf("https://api.dso.docker.com/datalog/team/%s/queries", workspace)
-	if workspace == "" || apiKey == "" {
-		url = "https://api.dso.docker.com/datalog/shared-vulnerability/queries"
+var datalogUrl = "https://api.dso.docker.com/datalog"
 
+// query runs the datalog query, retrying transient failures. Rejected credentials are
+// returned as AuthError and other unsuccessful responses as error.
+func query(ctx context.Context, query string, name string, workspace string, apiKey string) (*http.Response, error) {
+	url := fmt.Sprintf("%s/team/%s/queries", datalogUrl, workspace)
+	if workspace == "" || apiKey == "" {
+		url = datalogUrl + "/shared-vulnerability/queries"
 	}
 	query = fmt.Sprintf(`{:queries [{:name "query" :query %s}]}`, query)
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(query))
+	client := internal.HttpClient()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(query))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create http request")
 	}
@@ -113,9 +201,17 @@ func query(query string, name string, workspace string, apiKey string) (*http.Re
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create http client")
 	}
-	resp, err := client.Do(req)
+	resp, err := doWithRetry(client, req)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to run query")
 	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, &AuthError{Workspace: workspace, StatusCode: resp.StatusCode}
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, errors.Errorf("failed to run query: %s", resp.Status)
+	}
 	return resp, nil
 }
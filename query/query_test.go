@@ -0,0 +1,62 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestAtomistBackendChunks(t *testing.T) {
+	var requests int32
+	purlPattern := regexp.MustCompile(`pkg:npm/[a-z]+@1`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		b, _ := io.ReadAll(r.Body)
+		cves := ""
+		for _, purl := range purlPattern.FindAllString(string(b), -1) {
+			cves += `{:purl "` + purl + `" :source "github" :source-id "GHSA-` + purl[8:len(purl)-2] + `"}`
+		}
+		_, _ = w.Write([]byte(`{:query {:data [{:cves [` + cves + `]}]}}`))
+	}))
+	defer server.Close()
+	datalogUrl = server.URL
+
+	sb := &types.Sbom{Artifacts: []types.Package{
+		{Purl: "pkg:npm/a@1"}, {Purl: "pkg:npm/b@1"}, {Purl: "pkg:npm/c@1"}, {Purl: "pkg:npm/d@1"}, {Purl: "pkg:npm/e@1"},
+	}}
+	cves, err := AtomistBackend{ChunkSize: 2}.QueryCves(context.Background(), sb, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 queries, got %d", requests)
+	}
+	if len(*cves) != 5 {
+		t.Fatalf("expected 5 vulnerabilities, got %d", len(*cves))
+	}
+	if (*cves)[4].SourceId != "GHSA-e" {
+		t.Errorf("unexpected order of vulnerabilities %v", *cves)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	maxAttempts   = 5
+	minBackoff    = 500 * time.Millisecond
+	maxBackoff    = 30 * time.Second
+	maxRetryAfter = 5 * time.Minute
+)
+
+// AuthError is returned when the query endpoint rejects the workspace credentials
+type AuthError struct {
+	Workspace  string
+	StatusCode int
+}
+
+func (e *AuthError) Error() string {
+	if e.Workspace == "" {
+		return fmt.Sprintf("authentication failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("authentication for workspace %s failed with status %d, run docker index login", e.Workspace, e.StatusCode)
+}
+
+// IsAuthError returns true if err is caused by rejected credentials
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
+
+// doWithRetry sends the request and retries connection errors, 5xx responses and 429
+// responses with exponential backoff and jitter. A Retry-After header on the response
+// takes precedence over the backoff.
+func doWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := client.Do(req)
+		if err == nil && !retryable(resp.StatusCode) {
+			return resp, nil
+		}
+		if ctx.Err() != nil || attempt == maxAttempts {
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+
+		wait := backoff(attempt)
+		if err == nil {
+			if d, ok := retryAfter(resp); ok {
+				wait = d
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			err = errors.New(resp.Status)
+		}
+		log.Debugf("Retrying request to %s in %s after attempt %d failed: %s", req.URL.Host, wait.Round(time.Millisecond), attempt, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// backoff returns the exponential backoff of attempt with equal jitter
+func backoff(attempt int) time.Duration {
+	d := minBackoff << (attempt - 1)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses the Retry-After header given in seconds or as HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if s, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(s) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d, true
+}
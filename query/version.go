@@ -0,0 +1,139 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CompareVersions compares two package versions segment by segment and returns -1, 0 or 1.
+// Numeric segments are compared numerically, a leading epoch like 1: is honored and a ~
+// sorts before everything else as in Debian versions. This is not exact for every
+// ecosystem but good enough to evaluate advisory ranges.
+func CompareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	ea, a := splitEpoch(a)
+	eb, b := splitEpoch(b)
+	if ea != eb {
+		return compareInts(ea, eb)
+	}
+
+	sa, sb := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		var x, y string
+		if i < len(sa) {
+			x = sa[i]
+		}
+		if i < len(sb) {
+			y = sb[i]
+		}
+		if c := compareSegments(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func splitEpoch(v string) (int, string) {
+	if i := strings.Index(v, ":"); i > 0 {
+		if epoch, err := strconv.Atoi(v[:i]); err == nil {
+			return epoch, v[i+1:]
+		}
+	}
+	return 0, v
+}
+
+// versionSegments splits a version into runs of digits, letters and ~ dropping other separators
+func versionSegments(v string) []string {
+	segments := make([]string, 0)
+	current := ""
+	kind := func(r rune) int {
+		switch {
+		case unicode.IsDigit(r):
+			return 1
+		case unicode.IsLetter(r):
+			return 2
+		case r == '~':
+			return 3
+		default:
+			return 0
+		}
+	}
+	last := -1
+	for _, r := range v {
+		k := kind(r)
+		if k != last || k == 3 {
+			if current != "" {
+				segments = append(segments, current)
+			}
+			current = ""
+		}
+		if k != 0 {
+			current += string(r)
+		}
+		last = k
+	}
+	if current != "" {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+func compareSegments(x, y string) int {
+	switch {
+	case x == y:
+		return 0
+	case x == "~":
+		return -1
+	case y == "~":
+		return 1
+	case x == "":
+		// 1.0 < 1.0.1 but 1.0-rc1 < 1.0
+		if unicode.IsLetter(rune(y[0])) {
+			return 1
+		}
+		return -1
+	case y == "":
+		if unicode.IsLetter(rune(x[0])) {
+			return -1
+		}
+		return 1
+	}
+	nx, errx := strconv.Atoi(x)
+	ny, erry := strconv.Atoi(y)
+	switch {
+	case errx == nil && erry == nil:
+		return compareInts(nx, ny)
+	case errx == nil:
+		return 1
+	case erry == nil:
+		return -1
+	}
+	return strings.Compare(x, y)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
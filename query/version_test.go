@@ -0,0 +1,52 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"v1.2.3", "1.2.2", 1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0", "1.0.1", -1},
+		{"1:1.0", "2.0", 1},
+		{"1.1.1n-0+deb11u3", "1.1.1n-0+deb11u4", -1},
+		{"2.0~beta1", "2.0", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestInOsvRange(t *testing.T) {
+	r := OsvRange{Type: "SEMVER", Events: []map[string]string{
+		{"introduced": "0"}, {"fixed": "4.17.21"},
+	}}
+	if ok, vr, fixed := inOsvRange(r, "4.17.20"); !ok || vr != "<4.17.21" || fixed != "4.17.21" {
+		t.Errorf("expected 4.17.20 to be affected, got %v %s %s", ok, vr, fixed)
+	}
+	if ok, _, _ := inOsvRange(r, "4.17.21"); ok {
+		t.Error("expected 4.17.21 not to be affected")
+	}
+}
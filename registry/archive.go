@@ -0,0 +1,157 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/index-cli-plugin/internal/archive"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+)
+
+const (
+	DockerArchive = "docker-archive"
+	OciArchive    = "oci-archive"
+)
+
+// DetectArchiveFormat inspects the tarball at path and returns whether it was
+// written as docker-archive (`docker save`, `podman save`) or as oci-archive
+func DetectArchiveFormat(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open archive %s", path)
+	}
+	defer f.Close()
+
+	var format string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read archive %s", path)
+		}
+		switch filepath.Clean(hdr.Name) {
+		case "manifest.json":
+			// docker save writes an OCI index next to the manifest.json since 25.0; prefer the docker format
+			return DockerArchive, nil
+		case "oci-layout":
+			format = OciArchive
+		}
+	}
+	if format == "" {
+		return "", errors.Errorf("unsupported archive format: %s", path)
+	}
+	return format, nil
+}
+
+// ReadArchive reads an image from a docker-archive or oci-archive tarball at path.
+// ref optionally selects the image by tag if the archive contains more than one. platform
+// selects the image of a multi-platform oci-archive and is checked against the config of
+// single platform images.
+// It returns the image, the image name recorded in the archive and a cleanup function.
+func ReadArchive(path string, ref string, platform string) (v1.Image, string, func(), error) {
+	format, err := DetectArchiveFormat(path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	log.Debugf("Detected %s format", format)
+
+	switch format {
+	case DockerArchive:
+		p, err := parsePlatform(platform)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		img, imageName, err := readDockerArchive(path, ref)
+		if err == nil && p != nil {
+			err = checkPlatform(img, *p)
+		}
+		return img, imageName, func() {}, err
+	default:
+		if info, err := os.Stat(path); err == nil {
+			if err := CheckDiskSpace(os.TempDir(), info.Size()); err != nil {
+				return nil, "", nil, err
+			}
+		}
+		dir, err := os.MkdirTemp("", "docker-index-oci-")
+		if err != nil {
+			return nil, "", nil, errors.Wrap(err, "failed to create temporary directory")
+		}
+		cleanup := func() {
+			_ = os.RemoveAll(dir)
+		}
+		if err = extractTar(path, dir); err != nil {
+			cleanup()
+			return nil, "", nil, errors.Wrapf(err, "failed to extract archive %s", path)
+		}
+		img, imageName, err := ReadOCILayout(dir, ref, platform)
+		if err != nil {
+			cleanup()
+			return nil, "", nil, err
+		}
+		return img, imageName, cleanup, nil
+	}
+}
+
+func readDockerArchive(path string, ref string) (v1.Image, string, error) {
+	manifest, err := tarball.LoadManifest(func() (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to read manifest.json from %s", path)
+	}
+
+	var tag *name.Tag
+	var imageName string
+	if ref != "" {
+		t, err := name.NewTag(ref)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to parse tag: %s", ref)
+		}
+		tag = &t
+		imageName = ref
+	} else if len(manifest) > 1 {
+		return nil, "", errors.Errorf("archive %s contains %d images; select one with --image", path, len(manifest))
+	} else if len(manifest) == 1 && len(manifest[0].RepoTags) > 0 {
+		imageName = manifest[0].RepoTags[0]
+	}
+
+	img, err := tarball.ImageFromPath(path, tag)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to read image from %s", path)
+	}
+	return img, imageName, nil
+}
+
+func extractTar(path string, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return archive.Extract(f, dir)
+}
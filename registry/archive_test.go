@@ -0,0 +1,85 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestReadDockerArchive(t *testing.T) {
+	img, _ := random.Image(1024, 2)
+	tag, _ := name.NewTag("example.com/app:1.2")
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(path, tag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	format, err := DetectArchiveFormat(path)
+	if err != nil || format != DockerArchive {
+		t.Errorf("expected %s format, got %s: %v", DockerArchive, format, err)
+	}
+
+	archiveImg, imageName, cleanup, err := ReadArchive(path, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if imageName != "example.com/app:1.2" {
+		t.Errorf("unexpected image name %s", imageName)
+	}
+	expected, _ := img.ConfigName()
+	actual, _ := archiveImg.ConfigName()
+	if expected != actual {
+		t.Errorf("expected config %s, got %s", expected, actual)
+	}
+}
+
+func TestReadArchivePlatform(t *testing.T) {
+	img := platformImage(t, v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+	tag, _ := name.NewTag("example.com/app:1.2")
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := tarball.WriteToFile(path, tag, img); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		platform string
+		err      string
+	}{
+		{platform: ""},
+		{platform: "linux/arm64"},
+		{platform: "linux/arm64/v8"},
+		{platform: "linux/amd64", err: "image has platform linux/arm64/v8, but linux/amd64 was requested"},
+	}
+	for _, test := range tests {
+		_, _, cleanup, err := ReadArchive(path, "", test.platform)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: %v", test.platform, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected error %q, got %v", test.platform, test.err, err)
+		}
+		if cleanup != nil {
+			cleanup()
+		}
+	}
+}
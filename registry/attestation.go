@@ -0,0 +1,117 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+)
+
+const DsseEnvelopeMediaType = "application/vnd.dsse.envelope.v1+json"
+
+// AttachAttestation adds the DSSE envelope to the attestations of the image digest in repository
+// using the sha256-<digest>.att tag as cosign does
+func AttachAttestation(ctx context.Context, repository string, digest string, envelope []byte, annotations map[string]string) error {
+	repo, err := newRepository(repository)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse repository %s", repository)
+	}
+	tag := attestationTag(repo, digest)
+
+	var base v1.Image
+	if desc, err := remote.Get(tag, withAuth(ctx), withTransport(), remote.WithContext(ctx)); err == nil {
+		if base, err = desc.Image(); err != nil {
+			return errors.Wrapf(err, "failed to read attestations %s", tag.String())
+		}
+	} else if terr, ok := err.(*transport.Error); ok && terr.StatusCode == http.StatusNotFound {
+		base = mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON)
+	} else {
+		return errors.Wrapf(err, "failed to get attestations %s", tag.String())
+	}
+
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer:       rawLayer{content: envelope, mediaType: DsseEnvelopeMediaType},
+		Annotations: annotations,
+		MediaType:   DsseEnvelopeMediaType,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add attestation")
+	}
+	return errors.Wrapf(remote.Write(tag, img, withAuth(ctx), withTransport(), remote.WithContext(ctx)), "failed to push attestations %s", tag.String())
+}
+
+// Attestation is a DSSE envelope attached to an image and the annotations of its layer, which
+// carry the signing certificate and transparency log bundle of keyless signatures
+type Attestation struct {
+	Envelope    []byte
+	Annotations map[string]string
+}
+
+// ReadAttestations returns the DSSE envelopes attached to the image digest in repository with cosign
+func ReadAttestations(ctx context.Context, repository string, digest string) ([]Attestation, error) {
+	repo, err := newRepository(repository)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse repository %s", repository)
+	}
+	tag := attestationTag(repo, digest)
+	img, err := remote.Image(tag, withAuth(ctx), withTransport(), remote.WithContext(ctx))
+	if terr, ok := err.(*transport.Error); ok && terr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get attestations %s", tag.String())
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read attestations %s", tag.String())
+	}
+	attestations := make([]Attestation, 0)
+	for _, l := range manifest.Layers {
+		if l.MediaType != DsseEnvelopeMediaType {
+			continue
+		}
+		layer, err := img.LayerByDigest(l.Digest)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read layer")
+		}
+		r, err := layer.Compressed()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read layer")
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read layer")
+		}
+		attestations = append(attestations, Attestation{Envelope: b, Annotations: l.Annotations})
+	}
+	return attestations, nil
+}
+
+func attestationTag(repo name.Repository, digest string) name.Tag {
+	return repo.Tag(strings.Replace(digest, ":", "-", 1) + ".att")
+}
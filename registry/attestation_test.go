@@ -0,0 +1,52 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestAttachAttestation(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	repository := strings.TrimPrefix(server.URL, "http://") + "/app"
+	digest := "sha256:4ff3ca91275773af45cb4b0834e12b7eb47d1c18f770a0b151381cd227f4c253"
+
+	for _, p := range []string{"https://spdx.dev/Document", "https://cyclonedx.org/bom"} {
+		err := AttachAttestation(context.Background(), repository, digest, []byte(`{"payloadType": "application/vnd.in-toto+json"}`), map[string]string{"predicateType": p})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tag, _ := name.NewTag(repository + ":sha256-4ff3ca91275773af45cb4b0834e12b7eb47d1c18f770a0b151381cd227f4c253.att")
+	img, err := remote.Image(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ := img.Manifest()
+	if len(manifest.Layers) != 2 || manifest.Layers[1].MediaType != DsseEnvelopeMediaType || manifest.Layers[1].Annotations["predicateType"] != "https://cyclonedx.org/bom" {
+		t.Errorf("unexpected attestation manifest %v", manifest)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CacheEntry is an image stored in the cache
+type CacheEntry struct {
+	Digest   string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+	// Indexed reports if the sbom of the image is cached as well
+	Indexed bool
+}
+
+// Entries returns the images in the cache, most recently used first
+func (c Cache) Entries() ([]CacheEntry, error) {
+	entries := make([]CacheEntry, 0)
+	algorithms, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read cache %s", c.Dir)
+	}
+	for _, a := range algorithms {
+		// images are stored as <algorithm>/<hex>, other directories like the vulnerability
+		// database are not managed here
+		if !a.IsDir() || a.Name() != "sha256" {
+			continue
+		}
+		dirs, err := os.ReadDir(filepath.Join(c.Dir, a.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read cache %s", c.Dir)
+		}
+		for _, d := range dirs {
+			if !d.IsDir() {
+				continue
+			}
+			info, err := d.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(c.Dir, a.Name(), d.Name())
+			_, err = os.Stat(filepath.Join(path, "sbom.json"))
+			entries = append(entries, CacheEntry{
+				Digest:   a.Name() + ":" + d.Name(),
+				Path:     path,
+				Size:     dirSize(path),
+				LastUsed: info.ModTime(),
+				Indexed:  err == nil,
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Prune removes images not used within maxAge and then the least recently used images
+// until the cache is no larger than maxSize. Zero values disable the respective limit.
+// It returns the removed entries.
+func (c Cache) Prune(maxAge time.Duration, maxSize int64) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+
+	removed := make([]CacheEntry, 0)
+	// entries are ordered most recently used first, so evict from the end
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		expired := maxAge > 0 && time.Since(e.LastUsed) > maxAge
+		oversized := maxSize > 0 && size > maxSize
+		if !expired && !oversized {
+			break
+		}
+		c.logger().Debugf("Removing cached image %s", e.Digest)
+		if err := os.RemoveAll(e.Path); err != nil {
+			return removed, errors.Wrapf(err, "failed to remove cached image %s", e.Digest)
+		}
+		size -= e.Size
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// cacheDirs are the directories the plugin creates below the cache directory: images and
+// their sboms, the trivy layer cache, vulnerability query responses, the vulnerability
+// database, the CISA known exploited vulnerabilities catalog and the state of watch
+var cacheDirs = []string{"sha256", "trivy", "queries", "vulnerability-db", "kev", "watch"}
+
+// Purge removes all images, sboms and the vulnerability database from the cache. Only the
+// directories the plugin manages are removed, so other files in a cache directory passed by
+// mistake are kept, and the cache directory itself only if nothing else is left in it.
+func (c Cache) Purge() error {
+	for _, d := range cacheDirs {
+		if err := os.RemoveAll(filepath.Join(c.Dir, d)); err != nil {
+			return errors.Wrapf(err, "failed to remove cache %s", c.Dir)
+		}
+	}
+	_ = os.Remove(c.Dir)
+	return nil
+}
+
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachePrune(t *testing.T) {
+	cache := Cache{Dir: t.TempDir()}
+	add := func(hex string, size int, age time.Duration) {
+		path := filepath.Join(cache.Dir, "sha256", hex)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "blob"), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		used := time.Now().Add(-age)
+		_ = os.Chtimes(path, used, used)
+	}
+	add("old", 10, 48*time.Hour)
+	add("recent", 20, time.Hour)
+	add("new", 30, 0)
+	_ = os.MkdirAll(filepath.Join(cache.Dir, "vulnerability-db"), 0755)
+
+	entries, err := cache.Entries()
+	if err != nil || len(entries) != 3 || entries[0].Digest != "sha256:new" || entries[0].Size != 30 {
+		t.Fatalf("expected 3 entries most recently used first, got %v %s", entries, err)
+	}
+
+	removed, err := cache.Prune(24*time.Hour, 0)
+	if err != nil || len(removed) != 1 || removed[0].Digest != "sha256:old" {
+		t.Errorf("expected expired image to be removed, got %v %s", removed, err)
+	}
+
+	removed, err = cache.Prune(0, 40)
+	if err != nil || len(removed) != 1 || removed[0].Digest != "sha256:recent" {
+		t.Errorf("expected least recently used image to be removed, got %v %s", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(cache.Dir, "vulnerability-db")); err != nil {
+		t.Error("expected vulnerability database to be kept")
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		kept  bool
+	}{
+		{name: "managed", files: []string{"sha256/1234/sbom.json", "vulnerability-db/metadata.json", "queries/1.json"}},
+		{name: "foreign", files: []string{"sha256/1234/sbom.json", "notes.txt", "projects/app/main.go"}, kept: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := Cache{Dir: filepath.Join(t.TempDir(), "cache")}
+			for _, f := range test.files {
+				path := filepath.Join(cache.Dir, f)
+				_ = os.MkdirAll(filepath.Dir(path), 0755)
+				if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := cache.Purge(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(cache.Dir, "sha256")); !os.IsNotExist(err) {
+				t.Error("expected cached images to be removed")
+			}
+			if _, err := os.Stat(cache.Dir); os.IsNotExist(err) == test.kept {
+				t.Errorf("expected cache directory to be kept %v", test.kept)
+			}
+			for _, f := range test.files[1:] {
+				if _, err := os.Stat(filepath.Join(cache.Dir, f)); test.kept && err != nil {
+					t.Errorf("expected %s to be kept", f)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+)
+
+// DefaultDaemonTimeout is how long an image export from the Docker daemon may stall by default
+const DefaultDaemonTimeout = 2 * time.Minute
+
+var daemonTimeout = DefaultDaemonTimeout
+
+// SetDaemonTimeout sets how long an image export from the Docker daemon may not send any data
+// before it is aborted, 0 waits forever
+func SetDaemonTimeout(timeout time.Duration) {
+	daemonTimeout = timeout
+}
+
+// timeoutClient aborts stalled image exports, e.g. of a remote daemon whose ssh or TLS
+// connection was lost without being closed
+type timeoutClient struct {
+	client.APIClient
+}
+
+func withDaemonTimeout(c client.APIClient) client.APIClient {
+	if daemonTimeout <= 0 {
+		return c
+	}
+	return timeoutClient{APIClient: c}
+}
+
+func (c timeoutClient) ImageSave(ctx context.Context, images []string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	rc, err := c.APIClient.ImageSave(ctx, images)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	r := &idleReader{rc: rc, cancel: cancel, timeout: daemonTimeout}
+	r.timer = time.AfterFunc(r.timeout, func() {
+		r.expired.Store(true)
+		cancel()
+	})
+	return r, nil
+}
+
+type idleReader struct {
+	rc      io.ReadCloser
+	cancel  context.CancelFunc
+	timeout time.Duration
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if r.expired.Load() {
+		return n, errors.Errorf("image export from Docker daemon stalled for more than %s", r.timeout)
+	}
+	r.timer.Reset(r.timeout)
+	return n, err
+}
+
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.rc.Close()
+}
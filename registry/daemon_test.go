@@ -0,0 +1,59 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+type stallingClient struct {
+	client.APIClient
+}
+
+func (stallingClient) ImageSave(ctx context.Context, _ []string) (io.ReadCloser, error) {
+	r, w := io.Pipe()
+	go func() {
+		_, _ = w.Write([]byte("data"))
+		<-ctx.Done()
+		_ = w.CloseWithError(ctx.Err())
+	}()
+	return r, nil
+}
+
+func TestDaemonTimeout(t *testing.T) {
+	defer SetDaemonTimeout(DefaultDaemonTimeout)
+	SetDaemonTimeout(50 * time.Millisecond)
+
+	rc, err := withDaemonTimeout(stallingClient{}).ImageSave(context.Background(), []string{"alpine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if string(b) != "data" {
+		t.Errorf("expected data before stall, got %q", b)
+	}
+	if err == nil || !strings.Contains(err.Error(), "stalled") {
+		t.Errorf("expected stalled export to be aborted, got %v", err)
+	}
+}
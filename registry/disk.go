@@ -0,0 +1,98 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/docker/go-units"
+	"github.com/docker/index-cli-plugin/log"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// layersRemovedFile marks cached images whose layers were removed by RemoveLayers
+const layersRemovedFile = "layers-removed"
+
+// CheckDiskSpace returns an error if the volume of dir has less than size bytes available.
+// If the free space can't be determined the check passes.
+func CheckDiskSpace(dir string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	// dir may not exist yet, so check the closest existing parent
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		log.Debugf("Failed to determine free disk space of %s: %s", dir, err)
+		return nil
+	}
+	if free < uint64(size) {
+		return errors.Errorf("not enough disk space in %s: the image needs %s but only %s are available",
+			dir, units.HumanSize(float64(size)), units.HumanSize(float64(free)))
+	}
+	return nil
+}
+
+// CheckSpace returns an error if the cache volume has less than size bytes available
+func (c Cache) CheckSpace(size int64) error {
+	return CheckDiskSpace(c.Dir, size)
+}
+
+// ImageSize returns the size of the compressed distributable layers and config of img. For images read
+// from docker archives this compresses every layer.
+func ImageSize(img v1.Image) (int64, error) {
+	m, err := img.Manifest()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read manifest")
+	}
+	size := m.Config.Size
+	for _, l := range m.Layers {
+		// non-distributable layers aren't stored, see WithoutForeignLayers
+		if l.MediaType.IsDistributable() {
+			size += l.Size
+		}
+	}
+	return size, nil
+}
+
+// RemoveLayers frees the disk space of the layers of the indexed image img stored at path.
+// Its manifest, config and sbom stay in the cache; the layers are pulled again the next time
+// the image is saved.
+func (c Cache) RemoveLayers(path string, img v1.Image) error {
+	m, err := img.Manifest()
+	if err != nil {
+		return errors.Wrap(err, "failed to read manifest")
+	}
+	if err := os.WriteFile(filepath.Join(path, layersRemovedFile), nil, 0644); err != nil {
+		return errors.Wrapf(err, "failed to mark layers of %s as removed", path)
+	}
+	for _, l := range m.Layers {
+		blob := filepath.Join(path, "blobs", l.Digest.Algorithm, l.Digest.Hex)
+		if err := os.Remove(blob); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove layer %s", l.Digest.String())
+		}
+	}
+	c.logger().Debugf("Removed layers of cached image %s", path)
+	return nil
+}
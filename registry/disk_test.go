@@ -0,0 +1,71 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "not", "created")
+	if err := CheckDiskSpace(dir, 1024); err != nil {
+		t.Errorf("expected small image to fit: %s", err)
+	}
+	if err := CheckDiskSpace(dir, math.MaxInt64); err == nil {
+		t.Error("expected huge image not to fit")
+	}
+}
+
+func TestRemoveLayers(t *testing.T) {
+	cache := Cache{Dir: t.TempDir()}
+	img, _ := random.Image(1024, 2)
+	d, _ := img.Digest()
+	path, err := cache.saveOci(d.String(), img, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, _ := img.Layers()
+	blob := func() string {
+		digest, _ := layers[0].Digest()
+		return filepath.Join(path, "blobs", digest.Algorithm, digest.Hex)
+	}
+
+	if err := cache.RemoveLayers(path, img); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(blob()); !os.IsNotExist(err) {
+		t.Error("expected layer to be removed")
+	}
+	if _, err := ReadImage(path); err != nil {
+		t.Errorf("expected manifest to be kept: %s", err)
+	}
+
+	if _, err := cache.saveOci(d.String(), img, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(blob()); err != nil {
+		t.Error("expected layer to be written again")
+	}
+	if _, err := os.Stat(filepath.Join(path, layersRemovedFile)); !os.IsNotExist(err) {
+		t.Error("expected marker to be removed")
+	}
+}
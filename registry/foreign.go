@@ -0,0 +1,127 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// emptyLayer is the gzipped empty tar stored instead of the content of non-distributable layers
+var emptyLayer = func() []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_ = tar.NewWriter(gz).Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}()
+
+// ForeignLayers returns the ordinals of the non-distributable layers of img, e.g. the Windows
+// base layers hosted by Microsoft or restricted layers of vendor images
+func ForeignLayers(img v1.Image) []int {
+	ordinals := make([]int, 0)
+	m, err := img.Manifest()
+	if err != nil {
+		return ordinals
+	}
+	for i, l := range m.Layers {
+		if !l.MediaType.IsDistributable() {
+			ordinals = append(ordinals, i)
+		}
+	}
+	return ordinals
+}
+
+// WithoutForeignLayers returns img with the content of its non-distributable layers replaced
+// by an empty tar, so they are neither downloaded nor analyzed. Manifest, digests and diff ids
+// are kept, which keeps the layers aligned with the config and history of the image.
+func WithoutForeignLayers(img v1.Image) v1.Image {
+	m, err := img.Manifest()
+	if err != nil {
+		return img
+	}
+	foreign := make(map[v1.Hash]bool)
+	for _, l := range m.Layers {
+		if !l.MediaType.IsDistributable() {
+			foreign[l.Digest] = true
+		}
+	}
+	if len(foreign) == 0 {
+		return img
+	}
+	return foreignLayersImage{Image: img, foreign: foreign}
+}
+
+type foreignLayersImage struct {
+	v1.Image
+	foreign map[v1.Hash]bool
+}
+
+func (i foreignLayersImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	for j, l := range layers {
+		layers[j] = i.withoutContent(l)
+	}
+	return layers, nil
+}
+
+func (i foreignLayersImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	l, err := i.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.withoutContent(l), nil
+}
+
+func (i foreignLayersImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	l, err := i.Image.LayerByDiffID(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.withoutContent(l), nil
+}
+
+func (i foreignLayersImage) withoutContent(l v1.Layer) v1.Layer {
+	if d, err := l.Digest(); err != nil || !i.foreign[d] {
+		return l
+	}
+	return foreignLayer{Layer: l}
+}
+
+// foreignLayer is a non-distributable layer whose content is replaced by an empty tar
+type foreignLayer struct {
+	v1.Layer
+}
+
+func (l foreignLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(emptyLayer)), nil
+}
+
+func (l foreignLayer) Uncompressed() (io.ReadCloser, error) {
+	return gzip.NewReader(bytes.NewReader(emptyLayer))
+}
+
+func (l foreignLayer) Size() (int64, error) {
+	return int64(len(emptyLayer)), nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+func TestForeignLayers(t *testing.T) {
+	base, _ := random.Image(1024, 1)
+	foreign, _ := random.Layer(1024, types.DockerLayer)
+	img, err := mutate.Append(base, mutate.Addendum{Layer: foreign, MediaType: types.DockerForeignLayer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ordinals := ForeignLayers(img); len(ordinals) != 1 || ordinals[0] != 1 {
+		t.Fatalf("expected second layer to be foreign, got %v", ordinals)
+	}
+
+	cache := Cache{Dir: t.TempDir()}
+	d, _ := img.Digest()
+	path, err := cache.saveOci(d.String(), img, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := ReadImage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storedDigest, _ := stored.Digest(); storedDigest != d {
+		t.Errorf("expected manifest to be kept, got %s", storedDigest)
+	}
+	digest, _ := foreign.Digest()
+	b, err := os.ReadFile(filepath.Join(path, "blobs", digest.Algorithm, digest.Hex))
+	if err != nil || !bytes.Equal(b, emptyLayer) {
+		t.Errorf("expected foreign layer to be stored empty: %v", err)
+	}
+	diffIds, _ := stored.ConfigFile()
+	if len(diffIds.RootFS.DiffIDs) != 2 {
+		t.Errorf("expected diff ids to stay aligned with layers, got %v", diffIds.RootFS.DiffIDs)
+	}
+}
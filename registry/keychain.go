@@ -0,0 +1,112 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/trivy/pkg/fanal/image/token"
+	ftypes "github.com/aquasecurity/trivy/pkg/fanal/types"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// keychain resolves credentials from the Docker config in DOCKER_CONFIG or ~/.docker, including
+// credential helpers and stores, and falls back to the credentials of the cloud provider for ECR,
+// GCR, Artifact Registry and ACR registries
+var keychain = authn.NewMultiKeychain(authn.DefaultKeychain, &cloudKeychain{tokens: make(map[string]cloudToken)})
+
+type credentialsKey struct{}
+
+// repositoryCredentials are the credentials of a single repository added to a context
+type repositoryCredentials struct {
+	repository string
+	auth       authn.Authenticator
+}
+
+func (c repositoryCredentials) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if target.String() == c.repository {
+		return c.auth, nil
+	}
+	return authn.Anonymous, nil
+}
+
+// WithCredentials returns a context in which images of repository, e.g.
+// registry.example.com/team/app, are pulled with auth instead of the credentials of the Docker
+// config, e.g. with the short-lived robot account Harbor hands to its scanner for one scan
+func WithCredentials(ctx context.Context, repository string, auth authn.Authenticator) (context.Context, error) {
+	repo, err := newRepository(repository)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, credentialsKey{}, repositoryCredentials{repository: repo.String(), auth: auth}), nil
+}
+
+// envKeychain resolves the credentials of the ATOMIST_REGISTRY_* environment variables for
+// every registry
+type envKeychain struct{}
+
+func (envKeychain) Resolve(authn.Resource) (authn.Authenticator, error) {
+	if auth, ok := envAuthenticator(); ok {
+		return auth, nil
+	}
+	return authn.Anonymous, nil
+}
+
+// contextKeychain returns the keychain resolving the credentials added to ctx with
+// WithCredentials, then those of the environment and then the default keychain
+func contextKeychain(ctx context.Context) authn.Keychain {
+	if c, ok := ctx.Value(credentialsKey{}).(repositoryCredentials); ok {
+		return authn.NewMultiKeychain(c, envKeychain{}, keychain)
+	}
+	return authn.NewMultiKeychain(envKeychain{}, keychain)
+}
+
+// cloudTokenTTL is how long cloud registry tokens are reused, well below the lifetime of
+// ECR (12h), ACR (3h) and Google (1h) tokens
+const cloudTokenTTL = 30 * time.Minute
+
+// cloudKeychain exchanges the ambient cloud credentials, e.g. of an instance role or the
+// gcloud and az CLIs, for registry tokens
+type cloudKeychain struct {
+	mu     sync.Mutex
+	tokens map[string]cloudToken
+}
+
+type cloudToken struct {
+	auth    authn.Basic
+	expires time.Time
+}
+
+func (k *cloudKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	domain := target.RegistryStr()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if t, ok := k.tokens[domain]; ok && time.Now().Before(t.expires) {
+		return &t.auth, nil
+	}
+
+	auth := token.GetToken(context.Background(), domain, ftypes.DockerOption{})
+	if auth.Username == "" && auth.Password == "" {
+		return authn.Anonymous, nil
+	}
+	log.Debugf("Using cloud provider credentials for registry %s", domain)
+	k.tokens[domain] = cloudToken{auth: auth, expires: time.Now().Add(cloudTokenTTL)}
+	return &auth, nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+func TestKeychainDockerConfig(t *testing.T) {
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "docker-credential-test")
+	if err := os.WriteFile(helper, []byte("#!/bin/sh\necho '{\"Username\":\"helper\",\"Secret\":\"s3cret\"}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNz"}}, "credHelpers": {"helper.example.com": "test"}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	for registry, expected := range map[string]authn.AuthConfig{
+		"registry.example.com": {Username: "user", Password: "pass"},
+		"helper.example.com":   {Username: "helper", Password: "s3cret"},
+		"other.example.com":    {},
+	} {
+		repo, _ := name.NewRepository(registry + "/app")
+		auth, err := keychain.Resolve(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, _ := auth.Authorization()
+		if cfg.Username != expected.Username || cfg.Password != expected.Password {
+			t.Errorf("unexpected credentials for %s: %+v", registry, cfg)
+		}
+	}
+}
+
+func TestWithCredentials(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	t.Setenv("ATOMIST_REGISTRY_USER", "env")
+	t.Setenv("ATOMIST_REGISTRY_PASSWORD", "secret")
+	ctx, err := WithCredentials(context.Background(), "harbor.example.com/library/alpine", &authn.Basic{Username: "robot", Password: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ctx        context.Context
+		repository string
+		username   string
+	}{
+		{ctx: ctx, repository: "harbor.example.com/library/alpine", username: "robot"},
+		{ctx: ctx, repository: "harbor.example.com/library/nginx", username: "env"},
+		{ctx: context.Background(), repository: "harbor.example.com/library/alpine", username: "env"},
+	}
+	for _, test := range tests {
+		repo, _ := name.NewRepository(test.repository)
+		auth, err := authenticator(test.ctx, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg, _ := auth.Authorization(); cfg.Username != test.username {
+			t.Errorf("expected credentials of %s for %s, got %+v", test.username, test.repository, cfg)
+		}
+	}
+}
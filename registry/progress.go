@@ -0,0 +1,89 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"io"
+
+	"github.com/docker/index-cli-plugin/progress"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// progressImage reports the bytes of the layers of an image read while it is written to the cache
+type progressImage struct {
+	v1.Image
+	progress progress.Reporter
+	total    int64
+}
+
+// withProgress wraps img to report the bytes read of its size total to p, unless p is nil.
+// A total of 0 is replaced by the size of the layers of img.
+func withProgress(img v1.Image, p progress.Reporter, total int64) v1.Image {
+	if p == nil {
+		return img
+	}
+	if total <= 0 {
+		total, _ = ImageSize(img)
+	}
+	return progressImage{Image: img, progress: p, total: total}
+}
+
+func (i progressImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	reporting := make([]v1.Layer, len(layers))
+	for j, l := range layers {
+		reporting[j] = progressLayer{Layer: l, image: i}
+	}
+	return reporting, nil
+}
+
+func (i progressImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	l, err := i.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return progressLayer{Layer: l, image: i}, nil
+}
+
+type progressLayer struct {
+	v1.Layer
+	image progressImage
+}
+
+func (l progressLayer) Compressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	return progressReader{ReadCloser: rc, image: l.image}, nil
+}
+
+type progressReader struct {
+	io.ReadCloser
+	image progressImage
+}
+
+func (r progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.image.progress.Pulled(int64(n), r.image.total)
+	}
+	return n, err
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+type pullRecorder struct {
+	mu     sync.Mutex
+	pulled int64
+	total  int64
+}
+
+func (r *pullRecorder) Pulled(n int64, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pulled += n
+	r.total = total
+}
+
+func (r *pullRecorder) LayersExtracted(int, int)              {}
+func (r *pullRecorder) CatalogerCompleted(string, int, error) {}
+func (r *pullRecorder) PackagesFound(int)                     {}
+
+func TestSaveProgress(t *testing.T) {
+	recorder := &pullRecorder{}
+	cache := Cache{Dir: t.TempDir(), Progress: recorder}
+	img, _ := random.Image(1024, 3)
+	d, _ := img.Digest()
+	if _, err := cache.saveOci(d.String(), img, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var layersSize int64
+	layers, _ := img.Layers()
+	for _, l := range layers {
+		size, _ := l.Size()
+		layersSize += size
+	}
+	if recorder.pulled != layersSize {
+		t.Errorf("expected %d bytes pulled, got %d", layersSize, recorder.pulled)
+	}
+	if size, _ := ImageSize(img); recorder.total != size {
+		t.Errorf("expected total of %d bytes, got %d", size, recorder.total)
+	}
+
+	// cached images aren't pulled again
+	recorder.pulled = 0
+	if _, err := cache.saveOci(d.String(), img, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.pulled != 0 {
+		t.Errorf("expected no bytes pulled for cached image, got %d", recorder.pulled)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/pkg/errors"
+)
+
+// rateLimitWait is how long pulls wait in total for a registry rate limit to reset
+var rateLimitWait time.Duration
+
+// SetRateLimitWait makes pulls rejected by a registry rate limit wait up to d in total and
+// retry instead of failing with RateLimitError right away
+func SetRateLimitWait(d time.Duration) {
+	rateLimitWait = d
+}
+
+// RateLimitError is returned when a registry rejects a request with 429 Too Many Requests,
+// e.g. once the Docker Hub pull limit of anonymous or free users is used up
+type RateLimitError struct {
+	Registry string
+	// Limit and Remaining are the number of pulls allowed and left in Window, if the
+	// registry reports them like Docker Hub
+	Limit, Remaining int
+	Window           time.Duration
+	// RetryAfter is the wait requested by the registry, 0 if unknown
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("rate limit of registry %s exceeded", e.Registry)
+	if e.Limit > 0 {
+		msg += fmt.Sprintf(" (%d pulls per %s)", e.Limit, e.Window)
+	}
+	if e.Registry == "index.docker.io" || e.Registry == "registry-1.docker.io" {
+		msg += ", run docker login to pull with the higher limit of your account"
+	}
+	return msg
+}
+
+// IsRateLimitError returns true if err is caused by a registry rate limit
+func IsRateLimitError(err error) bool {
+	var rateLimitErr *RateLimitError
+	return errors.As(err, &rateLimitErr)
+}
+
+// rateLimitTransport turns 429 responses into RateLimitError and retries them as long as
+// the total wait stays within rateLimitWait
+type rateLimitTransport struct {
+	rt http.RoundTripper
+}
+
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := t.rt.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		rateLimitErr := toRateLimitError(req, resp)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		// Docker Hub counts pulls over a sliding window, so without Retry-After some of
+		// them are available again after a while
+		wait := rateLimitErr.RetryAfter
+		if wait <= 0 {
+			wait = time.Minute << attempt
+		}
+		if waited+wait > rateLimitWait || (req.Body != nil && req.GetBody == nil) {
+			return nil, rateLimitErr
+		}
+		log.Warnf("Rate limit of registry %s exceeded, retrying in %s", rateLimitErr.Registry, wait)
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		waited += wait
+	}
+}
+
+// toRateLimitError reads the RateLimit-Limit, RateLimit-Remaining (e.g. 100;w=21600) and
+// Retry-After headers of the response
+func toRateLimitError(req *http.Request, resp *http.Response) *RateLimitError {
+	err := RateLimitError{Registry: req.URL.Host}
+	err.Limit, err.Window = parseRateLimit(resp.Header.Get("RateLimit-Limit"))
+	err.Remaining, _ = parseRateLimit(resp.Header.Get("RateLimit-Remaining"))
+	if s, e := strconv.Atoi(resp.Header.Get("Retry-After")); e == nil && s > 0 {
+		err.RetryAfter = time.Duration(s) * time.Second
+	} else if t, e := http.ParseTime(resp.Header.Get("Retry-After")); e == nil && time.Until(t) > 0 {
+		err.RetryAfter = time.Until(t)
+	}
+	return &err
+}
+
+func parseRateLimit(value string) (int, time.Duration) {
+	count, window, _ := strings.Cut(value, ";")
+	n, _ := strconv.Atoi(strings.TrimSpace(count))
+	var w int
+	if strings.HasPrefix(window, "w=") {
+		w, _ = strconv.Atoi(strings.TrimPrefix(window, "w="))
+	}
+	return n, time.Duration(w) * time.Second
+}
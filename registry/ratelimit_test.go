@@ -0,0 +1,73 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestRateLimit(t *testing.T) {
+	var limited int32
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") && atomic.AddInt32(&limited, -1) >= 0 {
+			w.Header().Set("RateLimit-Limit", "100;w=21600")
+			w.Header().Set("RateLimit-Remaining", "0;w=21600")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"TOOMANYREQUESTS","message":"You have reached your pull rate limit."}]}`))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1.0"
+	img, _ := random.Image(1024, 1)
+	tag, _ := name.NewTag(image)
+	if err := remote.Write(tag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&limited, 1)
+	_, err := ReadRemoteImageContext(context.Background(), image, "")
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if rateLimitErr.Limit != 100 || rateLimitErr.Window != 6*time.Hour || rateLimitErr.RetryAfter != time.Second {
+		t.Errorf("unexpected rate limit %+v", rateLimitErr)
+	}
+
+	SetRateLimitWait(5 * time.Second)
+	defer SetRateLimitWait(0)
+	atomic.StoreInt32(&limited, 1)
+	if _, err := ReadRemoteImageContext(context.Background(), image, ""); err != nil {
+		t.Errorf("expected pull to succeed after waiting, got %v", err)
+	}
+}
@@ -17,11 +17,19 @@
 package registry
 
 import (
+	"runtime"
+	"strings"
+
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/pkg/errors"
 )
 
+const (
+	refNameAnnotation        = "org.opencontainers.image.ref.name"
+	containerdNameAnnotation = "io.containerd.image.name"
+)
+
 func ReadImage(path string) (v1.Image, error) {
 	index, err := layout.ImageIndexFromPath(path)
 	if err != nil {
@@ -31,3 +39,122 @@ func ReadImage(path string) (v1.Image, error) {
 	hash := mani.Manifests[0].Digest
 	return index.Image(hash)
 }
+
+// ReadOCILayout reads an image from an OCI image layout directory as written by
+// `buildx --output type=oci,tar=false` or `skopeo copy`. If ref is not empty, the
+// manifest annotated with a matching ref name is selected; otherwise the first one.
+// Nested image indexes are resolved to the image matching platform (e.g. linux/arm64),
+// or the current platform if empty.
+// It returns the image together with the image name found in the annotations.
+func ReadOCILayout(path string, ref string, platform string) (v1.Image, string, error) {
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, "", err
+	}
+	index, err := layout.ImageIndexFromPath(path)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to read OCI layout at %s", path)
+	}
+	mani, err := index.IndexManifest()
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to read index.json at %s", path)
+	}
+
+	var desc *v1.Descriptor
+	for i, m := range mani.Manifests {
+		if ref == "" || matchesRef(m, ref) {
+			desc = &mani.Manifests[i]
+			break
+		}
+	}
+	if desc == nil {
+		return nil, "", errors.Errorf("no image matching %s found in OCI layout at %s", ref, path)
+	}
+
+	imageName := desc.Annotations[containerdNameAnnotation]
+	if imageName == "" && strings.Contains(desc.Annotations[refNameAnnotation], ":") {
+		imageName = desc.Annotations[refNameAnnotation]
+	}
+	if imageName == "" && ref != "" && strings.ContainsAny(ref, ":/") {
+		imageName = ref
+	}
+
+	img, err := resolveImage(index, *desc, p)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to read image from OCI layout at %s", path)
+	}
+	if p != nil && !desc.MediaType.IsIndex() {
+		if err := checkPlatform(img, *p); err != nil {
+			return nil, "", err
+		}
+	}
+	return img, imageName, nil
+}
+
+func matchesRef(desc v1.Descriptor, ref string) bool {
+	refName := desc.Annotations[refNameAnnotation]
+	imageName := desc.Annotations[containerdNameAnnotation]
+	if refName == ref || imageName == ref || desc.Digest.String() == ref {
+		return true
+	}
+	// ref names written by buildx are full references; allow matching by tag only
+	if i := strings.LastIndex(refName, ":"); i > 0 && refName[i+1:] == ref {
+		return true
+	}
+	return false
+}
+
+// resolveImage resolves desc to an image; image indexes are resolved to the image matching
+// platform, or the current platform falling back to the first image if platform is nil
+func resolveImage(index v1.ImageIndex, desc v1.Descriptor, platform *v1.Platform) (v1.Image, error) {
+	if !desc.MediaType.IsIndex() {
+		return index.Image(desc.Digest)
+	}
+	child, err := index.ImageIndex(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	mani, err := child.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	if len(mani.Manifests) == 0 {
+		return nil, errors.Errorf("empty image index %s", desc.Digest)
+	}
+	want := v1.Platform{OS: "linux", Architecture: runtime.GOARCH}
+	if platform != nil {
+		want = *platform
+	}
+	for _, m := range mani.Manifests {
+		if matchesPlatform(m.Platform, want) {
+			return resolveImage(child, m, platform)
+		}
+	}
+	if platform != nil {
+		return nil, errors.Errorf("no image with platform %s in image index %s", platform.String(), desc.Digest)
+	}
+	return resolveImage(child, mani.Manifests[0], platform)
+}
+
+// checkPlatform returns an error if the config of img doesn't match platform
+func checkPlatform(img v1.Image, platform v1.Platform) error {
+	config, err := img.ConfigFile()
+	if err != nil {
+		return errors.Wrap(err, "failed to read image config")
+	}
+	actual := v1.Platform{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant}
+	if !matchesPlatform(&actual, platform) {
+		return errors.Errorf("image has platform %s, but %s was requested", actual.String(), platform.String())
+	}
+	return nil
+}
+
+func matchesPlatform(p *v1.Platform, want v1.Platform) bool {
+	if p == nil {
+		return false
+	}
+	if p.OS != want.OS || p.Architecture != want.Architecture {
+		return false
+	}
+	return want.Variant == "" || p.Variant == want.Variant
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+func TestReadOCILayout(t *testing.T) {
+	amd64 := platformImage(t, v1.Platform{OS: "linux", Architecture: "amd64"})
+	arm64 := platformImage(t, v1.Platform{OS: "linux", Architecture: "arm64"})
+	multi := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}}})
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AppendIndex(multi, layout.WithAnnotations(map[string]string{refNameAnnotation: "example/app:multi"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AppendImage(arm64, layout.WithAnnotations(map[string]string{refNameAnnotation: "example/app:single"})); err != nil {
+		t.Fatal(err)
+	}
+	amd64Digest, _ := amd64.Digest()
+	arm64Digest, _ := arm64.Digest()
+	current := amd64Digest
+	if runtime.GOARCH == "arm64" {
+		current = arm64Digest
+	}
+
+	tests := []struct {
+		ref       string
+		platform  string
+		digest    v1.Hash
+		imageName string
+		err       string
+	}{
+		{ref: "multi", digest: current, imageName: "example/app:multi"},
+		{ref: "multi", platform: "linux/amd64", digest: amd64Digest, imageName: "example/app:multi"},
+		{ref: "multi", platform: "linux/arm64", digest: arm64Digest, imageName: "example/app:multi"},
+		{ref: "multi", platform: "linux/s390x", err: "no image with platform linux/s390x"},
+		{ref: "single", digest: arm64Digest, imageName: "example/app:single"},
+		{ref: "single", platform: "linux/arm64", digest: arm64Digest, imageName: "example/app:single"},
+		{ref: "single", platform: "linux/amd64", err: "image has platform linux/arm64, but linux/amd64 was requested"},
+		{ref: "missing", err: "no image matching missing"},
+	}
+	for _, test := range tests {
+		img, imageName, err := ReadOCILayout(dir, test.ref, test.platform)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s %s: expected error %q, got %v", test.ref, test.platform, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %v", test.ref, test.platform, err)
+			continue
+		}
+		if d, _ := img.Digest(); d != test.digest || imageName != test.imageName {
+			t.Errorf("%s %s: expected %s (%s), got %s (%s)", test.ref, test.platform, test.digest, test.imageName, d, imageName)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	ociEmptyMediaType    = "application/vnd.oci.empty.v1+json"
+)
+
+// ociDescriptor extends v1.Descriptor with the OCI 1.1 artifactType field
+type ociDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Subject       *ociDescriptor    `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// PushReferrer attaches content as OCI 1.1 artifact to the image digest in repository.
+// Registries without referrers API support get the artifact added to the sha256-<digest>
+// tag index instead. The digest of the pushed artifact manifest is returned.
+func PushReferrer(ctx context.Context, repository string, digest string, content []byte, artifactType string) (string, error) {
+	repo, err := newRepository(repository)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse repository %s", repository)
+	}
+	subjectRef := repo.Digest(digest)
+	subject, err := remote.Head(subjectRef, withAuth(ctx), withTransport(), remote.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find image %s in registry", subjectRef.String())
+	}
+
+	blob := rawLayer{content: content, mediaType: types.MediaType(artifactType)}
+	empty := rawLayer{content: []byte("{}"), mediaType: ociEmptyMediaType}
+	for _, l := range []rawLayer{empty, blob} {
+		if err := remote.WriteLayer(repo, l, withAuth(ctx), withTransport(), remote.WithContext(ctx)); err != nil {
+			return "", errors.Wrap(err, "failed to upload artifact blob")
+		}
+	}
+
+	blobDigest, _ := blob.Digest()
+	emptyDigest, _ := empty.Digest()
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  artifactType,
+		Config: ociDescriptor{
+			MediaType: ociEmptyMediaType,
+			Digest:    emptyDigest.String(),
+			Size:      int64(len(empty.content)),
+		},
+		Layers: []ociDescriptor{{
+			MediaType: artifactType,
+			Digest:    blobDigest.String(),
+			Size:      int64(len(content)),
+		}},
+		Subject: &ociDescriptor{
+			MediaType: string(subject.MediaType),
+			Digest:    subject.Digest.String(),
+			Size:      subject.Size,
+		},
+		Annotations: map[string]string{
+			"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	manifestDigest, size, err := v1.SHA256(bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	if err := remote.Put(repo.Digest(manifestDigest.String()), rawManifest{raw: raw, mediaType: ociManifestMediaType}, withAuth(ctx), withTransport(), remote.WithContext(ctx)); err != nil {
+		return "", errors.Wrap(err, "failed to push artifact manifest")
+	}
+
+	supported, err := supportsReferrers(ctx, repo, digest)
+	if err != nil {
+		return "", err
+	}
+	if !supported {
+		log.Debugf("Registry %s does not support the referrers API, falling back to tag schema", repo.RegistryStr())
+		err = addToReferrersTag(ctx, repo, digest, ociDescriptor{
+			MediaType:    ociManifestMediaType,
+			Digest:       manifestDigest.String(),
+			Size:         size,
+			ArtifactType: artifactType,
+			Annotations:  manifest.Annotations,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return manifestDigest.String(), nil
+}
+
+// supportsReferrers checks if the registry serves the OCI 1.1 referrers API
+func supportsReferrers(ctx context.Context, repo name.Repository, digest string) (bool, error) {
+	auth, err := authenticator(ctx, repo)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to resolve registry credentials")
+	}
+	tr, err := transport.NewWithContext(ctx, repo.Registry, auth, registryTransport(), []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create registry transport")
+	}
+	url := fmt.Sprintf("%s://%s/v2/%s/referrers/%s", repo.Registry.Scheme(), repo.RegistryStr(), repo.RepositoryStr(), digest)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to query referrers")
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK && strings.HasPrefix(resp.Header.Get("Content-Type"), ociIndexMediaType), nil
+}
+
+// addToReferrersTag adds the artifact to the index tagged with the digest of the subject
+// as described by the referrers tag schema of the OCI distribution spec
+func addToReferrersTag(ctx context.Context, repo name.Repository, digest string, artifact ociDescriptor) error {
+	tag := repo.Tag(strings.Replace(digest, ":", "-", 1))
+	index := ociIndex{
+		SchemaVersion: 2,
+		MediaType:     ociIndexMediaType,
+		Manifests:     make([]ociDescriptor, 0),
+	}
+	if desc, err := remote.Get(tag, withAuth(ctx), withTransport(), remote.WithContext(ctx)); err == nil {
+		if err := json.Unmarshal(desc.Manifest, &index); err != nil {
+			return errors.Wrapf(err, "failed to parse referrers index %s", tag.String())
+		}
+	} else if terr, ok := err.(*transport.Error); !ok || terr.StatusCode != http.StatusNotFound {
+		return errors.Wrapf(err, "failed to get referrers index %s", tag.String())
+	}
+	index.Manifests = append(index.Manifests, artifact)
+
+	raw, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(remote.Put(tag, rawManifest{raw: raw, mediaType: ociIndexMediaType}, withAuth(ctx), withTransport(), remote.WithContext(ctx)), "failed to push referrers index")
+}
+
+// rawManifest is a remote.Taggable of a serialized manifest
+type rawManifest struct {
+	raw       []byte
+	mediaType types.MediaType
+}
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m.raw, nil
+}
+
+func (m rawManifest) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
+}
+
+// rawLayer is a v1.Layer of uncompressed content to upload arbitrary blobs
+type rawLayer struct {
+	content   []byte
+	mediaType types.MediaType
+}
+
+func (l rawLayer) Digest() (v1.Hash, error) {
+	h, _, err := v1.SHA256(bytes.NewReader(l.content))
+	return h, err
+}
+
+func (l rawLayer) DiffID() (v1.Hash, error) {
+	return l.Digest()
+}
+
+func (l rawLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(l.content)), nil
+}
+
+func (l rawLayer) Uncompressed() (io.ReadCloser, error) {
+	return l.Compressed()
+}
+
+func (l rawLayer) Size() (int64, error) {
+	return int64(len(l.content)), nil
+}
+
+func (l rawLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestPushReferrerTagSchema(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	repository := strings.TrimPrefix(server.URL, "http://") + "/app"
+
+	img, _ := random.Image(1024, 1)
+	tag, _ := name.NewTag(repository + ":1.0")
+	if err := remote.Write(tag, img); err != nil {
+		t.Fatal(err)
+	}
+	digest, _ := img.Digest()
+
+	artifact, err := PushReferrer(context.Background(), repository, digest.String(), []byte(`{"spdxVersion": "SPDX-2.3"}`), "application/spdx+json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the in-memory registry has no referrers API so the artifact is added to the tag schema index
+	referrers, _ := name.NewTag(repository + ":" + strings.Replace(digest.String(), ":", "-", 1))
+	desc, err := remote.Get(referrers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index ociIndex
+	_ = json.Unmarshal(desc.Manifest, &index)
+	if len(index.Manifests) != 1 || index.Manifests[0].Digest != artifact || index.Manifests[0].ArtifactType != "application/spdx+json" {
+		t.Errorf("unexpected referrers index %s", string(desc.Manifest))
+	}
+}
@@ -18,12 +18,17 @@ package registry
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/atomist-skills/go-skill"
 	"github.com/docker/docker/client"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/metrics"
+	"github.com/docker/index-cli-plugin/progress"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -58,67 +63,363 @@ func (i ImageId) String() string {
 	return i.name
 }
 
+// Cache stores images in OCI image layouts below Dir, one directory per image digest
+type Cache struct {
+	Dir string
+	// Logger receives progress messages, the logger set with log.SetLogger if nil
+	Logger log.Logger
+	// Progress receives the bytes of images written to the cache if not nil
+	Progress progress.Reporter
+}
+
+func (c Cache) logger() log.Logger {
+	if c.Logger == nil {
+		return log.Current()
+	}
+	return c.Logger
+}
+
+// DefaultCache returns the cache in ATOMIST_CACHE_DIR or the temp directory
+func DefaultCache() Cache {
+	return Cache{Dir: internal.CachePath()}
+}
+
 // SaveImage stores the v1.Image at path returned in OCI format
 func SaveImage(image string, client client.APIClient) (v1.Image, string, error) {
-	ref, err := name.ParseReference(image)
+	return SavePlatformImage(image, "", client)
+}
+
+// SavePlatformImage stores the v1.Image for the given platform (e.g. linux/arm64) at
+// path returned in OCI format. An empty platform selects the default platform.
+func SavePlatformImage(image string, platform string, client client.APIClient) (v1.Image, string, error) {
+	return SavePlatformImageContext(context.Background(), image, platform, client)
+}
+
+// SavePlatformImageContext is SavePlatformImage with a context to cancel the pull
+func SavePlatformImageContext(ctx context.Context, image string, platform string, client client.APIClient) (v1.Image, string, error) {
+	return DefaultCache().SavePlatformImage(ctx, image, platform, client)
+}
+
+// SavePlatformImage stores the v1.Image for the given platform in the cache, see SavePlatformImage
+func (c Cache) SavePlatformImage(ctx context.Context, image string, platform string, client client.APIClient) (v1.Image, string, error) {
+	pullCtx, cancel := withPullTimeout(ctx)
+	defer cancel()
+	img, path, err := c.savePlatformImage(pullCtx, image, platform, client)
+	return img, path, pullTimeoutError(ctx, pullCtx, err)
+}
+
+func (c Cache) savePlatformImage(ctx context.Context, image string, platform string, client client.APIClient) (v1.Image, string, error) {
+	ref, err := parseReference(image)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to parse reference: %s", image)
+	}
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, "", err
+	}
+
+	img, path, remoteErr := c.saveRemoteImage(ctx, ref, p)
+	if remoteErr == nil {
+		return img, path, nil
+	}
+	if ctx.Err() != nil || client == nil {
+		return nil, "", errors.Wrapf(remoteErr, "failed to pull image: %s", image)
+	}
+
+	img, err = daemon.Image(ImageId{name: image}, daemon.WithClient(withDaemonTimeout(client)), daemon.WithContext(ctx))
+	if err != nil {
+		// the image isn't available locally either, so the rate limit is what to fix
+		if IsRateLimitError(remoteErr) {
+			return nil, "", errors.Wrapf(remoteErr, "failed to pull image: %s", image)
+		}
+		return nil, "", errors.Wrapf(err, "failed to pull image: %s", image)
+	}
+	im, _, err := client.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to get local image: %s", image)
+	}
+	if p != nil {
+		actual := v1.Platform{OS: im.Os, Architecture: im.Architecture, Variant: im.Variant}
+		if !matchesPlatform(&actual, *p) {
+			return nil, "", errors.Errorf("local image %s has platform %s, but %s was requested", image, actual.String(), p.String())
+		}
+	}
+	path, err = c.saveOci(im.ID, img, ref, im.Size)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to save image: %s", image)
+	}
+	return img, path, nil
+}
+
+// SaveRemoteImage stores the v1.Image for the given platform at path returned in OCI
+// format. Layers are streamed from the registry directly without requiring a Docker daemon.
+func SaveRemoteImage(image string, platform string) (v1.Image, string, error) {
+	return SaveRemoteImageContext(context.Background(), image, platform)
+}
+
+// SaveRemoteImageContext is SaveRemoteImage with a context to cancel the pull
+func SaveRemoteImageContext(ctx context.Context, image string, platform string) (v1.Image, string, error) {
+	return DefaultCache().SaveRemoteImage(ctx, image, platform)
+}
+
+// SaveRemoteImage stores the v1.Image for the given platform in the cache, see SaveRemoteImage
+func (c Cache) SaveRemoteImage(ctx context.Context, image string, platform string) (v1.Image, string, error) {
+	pullCtx, cancel := withPullTimeout(ctx)
+	defer cancel()
+	img, path, err := c.saveRemote(pullCtx, image, platform)
+	return img, path, pullTimeoutError(ctx, pullCtx, err)
+}
+
+func (c Cache) saveRemote(ctx context.Context, image string, platform string) (v1.Image, string, error) {
+	ref, err := parseReference(image)
 	if err != nil {
 		return nil, "", errors.Wrapf(err, "failed to parse reference: %s", image)
 	}
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, "", err
+	}
+	img, path, err := c.saveRemoteImage(ctx, ref, p)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to pull image: %s", image)
+	}
+	return img, path, nil
+}
 
-	var path string
-	if v, ok := os.LookupEnv("ATOMIST_CACHE_DIR"); ok {
-		path = filepath.Join(v, "docker-index")
+func (c Cache) saveRemoteImage(ctx context.Context, ref name.Reference, platform *v1.Platform) (v1.Image, string, error) {
+	options := []remote.Option{withAuth(ctx), withTransport(), remote.WithContext(ctx)}
+	if platform != nil {
+		options = append(options, remote.WithPlatform(*platform))
+	}
+	desc, err := remote.Get(ref, options...)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.saveDescriptor(ref, desc, platform)
+}
+
+// saveDescriptor saves the image desc refers to, resolving image indexes to platform
+func (c Cache) saveDescriptor(ref name.Reference, desc *remote.Descriptor, platform *v1.Platform) (v1.Image, string, error) {
+	img, err := desc.Image()
+	if err != nil {
+		return nil, "", err
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, "", err
+	}
+	if desc.MediaType.IsIndex() {
+		c.logger().Infof("Resolved image index to platform %s/%s", config.OS, config.Architecture)
+	} else if platform != nil {
+		actual := v1.Platform{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant}
+		if !matchesPlatform(&actual, *platform) {
+			return nil, "", errors.Errorf("image %s has platform %s, but %s was requested", ref.Name(), actual.String(), platform.String())
+		}
+	}
+	var digest string
+	identifier := ref.Identifier()
+	if strings.HasPrefix(identifier, "sha256:") && !desc.MediaType.IsIndex() {
+		digest = identifier
 	} else {
-		path = filepath.Join(os.TempDir(), "docker-index")
+		digestHash, err := img.Digest()
+		if err != nil {
+			return nil, "", err
+		}
+		digest = digestHash.String()
+	}
+	size, err := ImageSize(img)
+	if err != nil {
+		return nil, "", err
 	}
+	path, err := c.saveOci(digest, img, ref, size)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to save image: %s", ref.Name())
+	}
+	return img, path, nil
+}
+
+// ReadRemoteImage resolves image for the given platform from the registry without
+// downloading its layers
+func ReadRemoteImage(image string, platform string) (v1.Image, error) {
+	return ReadRemoteImageContext(context.Background(), image, platform)
+}
 
-	desc, err := remote.Get(ref, withAuth())
+// ReadRemoteImageContext is ReadRemoteImage with a context to cancel reading the image
+// and its layers
+func ReadRemoteImageContext(ctx context.Context, image string, platform string) (v1.Image, error) {
+	ref, err := parseReference(image)
 	if err != nil {
-		img, err := daemon.Image(ImageId{name: image}, daemon.WithClient(client))
+		return nil, errors.Wrapf(err, "failed to parse reference: %s", image)
+	}
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+	options := []remote.Option{withAuth(ctx), withTransport(), remote.WithContext(ctx)}
+	if p != nil {
+		options = append(options, remote.WithPlatform(*p))
+	}
+	img, err := remote.Image(ref, options...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to pull image: %s", image)
+	}
+	return img, nil
+}
+
+func parsePlatform(platform string) (*v1.Platform, error) {
+	if platform == "" {
+		return nil, nil
+	}
+	p, err := v1.ParsePlatform(platform)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse platform: %s", platform)
+	}
+	return p, nil
+}
+
+type PlatformImage struct {
+	Image    v1.Image
+	Path     string
+	Platform v1.Platform
+}
+
+// SaveRemoteImages stores every platform image of the image index image refers to
+// in OCI format. If image refers to a single platform image only that is stored.
+func SaveRemoteImages(image string) ([]PlatformImage, error) {
+	return SaveRemoteImagesContext(context.Background(), image)
+}
+
+// SaveRemoteImagesContext is SaveRemoteImages with a context to cancel the pull
+func SaveRemoteImagesContext(ctx context.Context, image string) ([]PlatformImage, error) {
+	return DefaultCache().SaveRemoteImages(ctx, image)
+}
+
+// SaveRemoteImages stores every platform image in the cache, see SaveRemoteImages
+func (c Cache) SaveRemoteImages(ctx context.Context, image string) ([]PlatformImage, error) {
+	pullCtx, cancel := withPullTimeout(ctx)
+	defer cancel()
+	images, err := c.saveRemoteImages(pullCtx, image)
+	return images, pullTimeoutError(ctx, pullCtx, err)
+}
+
+func (c Cache) saveRemoteImages(ctx context.Context, image string) ([]PlatformImage, error) {
+	ref, err := parseReference(image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse reference: %s", image)
+	}
+	desc, err := remote.Get(ref, withAuth(ctx), withTransport(), remote.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to pull image: %s", image)
+	}
+
+	if !desc.MediaType.IsIndex() {
+		img, path, err := c.saveDescriptor(ref, desc, nil)
 		if err != nil {
-			return nil, "", errors.Wrapf(err, "failed to pull image: %s", image)
-		} else {
-			im, _, err := client.ImageInspectWithRaw(context.Background(), image)
-			if err != nil {
-				return nil, "", errors.Wrapf(err, "failed to get local image: %s", image)
-			}
-			path, err = saveOci(im.ID, img, ref, path)
-			if err != nil {
-				return nil, "", errors.Wrapf(err, "failed to save image: %s", image)
-			}
+			return nil, errors.Wrapf(err, "failed to pull image: %s", image)
 		}
-		return img, path, nil
-	} else {
-		img, err := desc.Image()
+		config, err := img.ConfigFile()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read config: %s", image)
+		}
+		return []PlatformImage{{
+			Image:    img,
+			Path:     path,
+			Platform: v1.Platform{OS: config.OS, Architecture: config.Architecture, Variant: config.Variant},
+		}}, nil
+	}
+
+	index, err := desc.ImageIndex()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read image index: %s", image)
+	}
+	mani, err := index.IndexManifest()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read image index: %s", image)
+	}
+
+	images := make([]PlatformImage, 0)
+	for _, m := range mani.Manifests {
+		// skip attestation manifests and nested indexes
+		if m.Platform == nil || m.Platform.OS == "unknown" || !m.MediaType.IsImage() {
+			continue
+		}
+		img, err := index.Image(m.Digest)
 		if err != nil {
-			return nil, "", errors.Wrapf(err, "failed to pull image: %s", image)
+			return nil, errors.Wrapf(err, "failed to pull image %s for platform %s", image, m.Platform.String())
 		}
-		var digest string
-		identifier := ref.Identifier()
-		if strings.HasPrefix(identifier, "sha256:") {
-			digest = identifier
-		} else {
-			digestHash, _ := img.Digest()
-			digest = digestHash.String()
+		size, err := ImageSize(img)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to pull image %s for platform %s", image, m.Platform.String())
 		}
-		path, err = saveOci(digest, img, ref, path)
+		path, err := c.saveOci(m.Digest.String(), img, ref, size)
 		if err != nil {
-			return nil, "", errors.Wrapf(err, "failed to save image: %s", image)
+			return nil, errors.Wrapf(err, "failed to save image %s for platform %s", image, m.Platform.String())
 		}
-		return img, path, nil
+		images = append(images, PlatformImage{
+			Image:    img,
+			Path:     path,
+			Platform: *m.Platform,
+		})
 	}
+	if len(images) == 0 {
+		return nil, errors.Errorf("no platform images found in image index: %s", image)
+	}
+	return images, nil
+}
+
+// CopyImage stores the v1.Image in the local cache in OCI format and returns the path
+func CopyImage(img v1.Image) (string, error) {
+	return DefaultCache().CopyImage(img)
+}
+
+// CopyImage stores the v1.Image in the cache in OCI format and returns the path
+// without checking the free disk space first, see CheckSpace
+func (c Cache) CopyImage(img v1.Image) (string, error) {
+	digest, err := img.Digest()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to obtain image digest")
+	}
+	path, err := c.saveOci(digest.String(), img, nil, 0)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to save image: %s", digest.String())
+	}
+	return path, nil
 }
 
-// saveOci writes the v1.Image img as an OCI Image Layout at path. If a layout
-// already exists at that path, it will add the image to the index.
-func saveOci(digest string, img v1.Image, ref name.Reference, path string) (string, error) {
-	finalPath := strings.Replace(filepath.Join(path, digest), ":", string(os.PathSeparator), 1)
-	skill.Log.Debugf("Copying image to %s", finalPath)
+// saveOci writes the v1.Image img as an OCI Image Layout below the cache directory. If a
+// layout already exists at that path, it will add the image to the index. Partially written
+// layouts, e.g. of cancelled pulls, and layouts whose config or layers don't match the
+// manifest are removed again. size is checked against the free disk space before writing, 0 skips the check.
+func (c Cache) saveOci(digest string, img v1.Image, ref name.Reference, size int64) (string, error) {
+	finalPath := strings.Replace(filepath.Join(c.Dir, digest), ":", string(os.PathSeparator), 1)
+	c.logger().Debugf("Copying image to %s", finalPath)
 
-	if _, err := os.Stat(finalPath); !os.IsNotExist(err) {
+	_, err := os.Stat(finalPath)
+	_, removedErr := os.Stat(filepath.Join(finalPath, layersRemovedFile))
+	layersRemoved := removedErr == nil
+	metrics.ObserveCache(metrics.CacheImage, !os.IsNotExist(err) && !layersRemoved)
+	if !os.IsNotExist(err) && !layersRemoved {
+		// record the use so that Prune evicts least recently used images first
+		now := time.Now()
+		_ = os.Chtimes(finalPath, now, now)
 		return finalPath, nil
 	}
-	err := os.MkdirAll(finalPath, os.ModePerm)
+	if err := c.CheckSpace(size); err != nil {
+		return "", err
+	}
+	if layersRemoved {
+		// the manifest is still in the index, only the removed layers need to be written
+		p, err := layout.FromPath(finalPath)
+		if err != nil {
+			return "", err
+		}
+		if err = p.WriteImage(withProgress(verifiedImage{Image: WithoutForeignLayers(img)}, c.Progress, size)); err != nil {
+			_ = os.RemoveAll(finalPath)
+			return "", err
+		}
+		return finalPath, os.Remove(filepath.Join(finalPath, layersRemovedFile))
+	}
+	err = os.MkdirAll(finalPath, os.ModePerm)
 	if err != nil {
 		return "", err
 	}
@@ -129,24 +430,71 @@ func saveOci(digest string, img v1.Image, ref name.Reference, path string) (stri
 			return "", err
 		}
 	}
-	if err = p.AppendImage(img); err != nil {
+	if err = p.AppendImage(withProgress(verifiedImage{Image: WithoutForeignLayers(img)}, c.Progress, size)); err != nil {
+		_ = os.RemoveAll(finalPath)
 		return "", err
 	}
 	return finalPath, nil
 }
 
-func withAuth() remote.Option {
+// withTransport counts the pulled bytes in the registry metrics and handles rate limits
+func withTransport() remote.Option {
+	return remote.WithTransport(registryTransport())
+}
+
+// registryTransport trusts the configured registry certificates, waits for rate limits and
+// counts the pulled bytes
+func registryTransport() http.RoundTripper {
+	return rateLimitTransport{rt: metrics.Transport(internal.Transport(remote.DefaultTransport))}
+}
+
+func withAuth(ctx context.Context) remote.Option {
+	return remote.WithAuthFromKeychain(contextKeychain(ctx))
+}
+
+// authenticator resolves the credentials for repo like withAuth
+func authenticator(ctx context.Context, repo name.Repository) (authn.Authenticator, error) {
+	return contextKeychain(ctx).Resolve(repo)
+}
+
+func envAuthenticator() (authn.Authenticator, bool) {
 	// check registry token env var
 	if token, ok := os.LookupEnv("ATOMIST_REGISTRY_TOKEN"); ok {
-		return remote.WithAuth(&authn.Bearer{Token: token})
+		return &authn.Bearer{Token: token}, true
 		// check user
 	} else if user, ok := os.LookupEnv("ATOMIST_REGISTRY_USER"); ok {
 		if password, ok := os.LookupEnv("ATOMIST_REGISTRY_PASSWORD"); ok {
-			return remote.WithAuth(&authn.Basic{
+			return &authn.Basic{
 				Username: user,
 				Password: password,
-			})
+			}, true
 		}
 	}
-	return remote.WithAuthFromKeychain(authn.DefaultKeychain)
+	return nil, false
+}
+
+// parseReference parses image, allowing plain HTTP for insecure registries
+func parseReference(image string) (name.Reference, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil || !internal.IsInsecureRegistry(ref.Context().RegistryStr()) {
+		return ref, err
+	}
+	return name.ParseReference(image, name.Insecure)
+}
+
+// newRepository parses repository like parseReference
+func newRepository(repository string) (name.Repository, error) {
+	repo, err := name.NewRepository(repository)
+	if err != nil || !internal.IsInsecureRegistry(repo.RegistryStr()) {
+		return repo, err
+	}
+	return name.NewRepository(repository, name.Insecure)
+}
+
+// newRegistry parses host like parseReference
+func newRegistry(host string) (name.Registry, error) {
+	if internal.IsInsecureRegistry(host) {
+		return name.NewRegistry(host, name.Insecure)
+	}
+	return name.NewRegistry(host)
 }
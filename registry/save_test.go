@@ -0,0 +1,176 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func platformImage(t *testing.T, platform v1.Platform) v1.Image {
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _ := img.ConfigFile()
+	config = config.DeepCopy()
+	config.OS, config.Architecture, config.Variant = platform.OS, platform.Architecture, platform.Variant
+	img, err = mutate.ConfigFile(img, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestSaveRemoteImage(t *testing.T) {
+	server := httptest.NewServer(ggcr.New(ggcr.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	repo := strings.TrimPrefix(server.URL, "http://") + "/app"
+
+	amd64 := platformImage(t, v1.Platform{OS: "linux", Architecture: "amd64"})
+	arm64 := platformImage(t, v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"})
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}}})
+	ref, _ := name.ParseReference(repo + ":multi")
+	if err := remote.WriteIndex(ref, index); err != nil {
+		t.Fatal(err)
+	}
+	ref, _ = name.ParseReference(repo + ":single")
+	if err := remote.Write(ref, arm64); err != nil {
+		t.Fatal(err)
+	}
+	amd64Digest, _ := amd64.Digest()
+	arm64Digest, _ := arm64.Digest()
+
+	tests := []struct {
+		image    string
+		platform string
+		digest   v1.Hash
+		err      string
+	}{
+		{image: repo + ":multi", digest: amd64Digest},
+		{image: repo + ":multi", platform: "linux/arm64", digest: arm64Digest},
+		{image: repo + ":single", digest: arm64Digest},
+		{image: repo + ":single", platform: "linux/arm64/v8", digest: arm64Digest},
+		{image: repo + ":single", platform: "linux/amd64", err: "has platform linux/arm64/v8, but linux/amd64 was requested"},
+		{image: repo + ":missing", err: "failed to pull image"},
+		{image: repo + ":multi", platform: "linux/s390x", err: "no child with platform linux/s390x"},
+	}
+	cache := Cache{Dir: t.TempDir()}
+	for _, test := range tests {
+		img, path, err := cache.SaveRemoteImage(context.Background(), test.image, test.platform)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s %s: expected error %q, got %v", test.image, test.platform, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %v", test.image, test.platform, err)
+			continue
+		}
+		if d, _ := img.Digest(); d != test.digest {
+			t.Errorf("%s %s: expected image %s, got %s", test.image, test.platform, test.digest, d)
+		}
+		if _, err := os.Stat(path); err != nil || !strings.HasSuffix(path, test.digest.Hex) {
+			t.Errorf("%s %s: expected layout at %s, got %s", test.image, test.platform, test.digest.Hex, path)
+		}
+		if saved, err := ReadImage(path); err != nil {
+			t.Errorf("%s %s: failed to read saved image: %v", test.image, test.platform, err)
+		} else if d, _ := saved.Digest(); d != test.digest {
+			t.Errorf("%s %s: expected saved image %s, got %s", test.image, test.platform, test.digest, d)
+		}
+	}
+
+	// without a daemon to fall back to, the registry error is returned
+	if _, _, err := cache.SavePlatformImage(context.Background(), repo+":missing", "", nil); err == nil || !strings.Contains(err.Error(), "failed to pull image") {
+		t.Errorf("expected pull error without daemon, got %v", err)
+	}
+}
+
+func TestSaveRemoteImages(t *testing.T) {
+	var mu sync.Mutex
+	manifests := make(map[string]int)
+	handler := ggcr.New(ggcr.Logger(log.New(io.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") {
+			mu.Lock()
+			manifests[r.Method+" "+path.Base(r.URL.Path)]++
+			mu.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	repo := strings.TrimPrefix(server.URL, "http://") + "/app"
+
+	amd64 := platformImage(t, v1.Platform{OS: "linux", Architecture: "amd64"})
+	arm64 := platformImage(t, v1.Platform{OS: "linux", Architecture: "arm64"})
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: "arm64"}}})
+	ref, _ := name.ParseReference(repo + ":multi")
+	if err := remote.WriteIndex(ref, index); err != nil {
+		t.Fatal(err)
+	}
+	ref, _ = name.ParseReference(repo + ":single")
+	if err := remote.Write(ref, arm64); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tag       string
+		platforms []string
+	}{
+		{tag: "multi", platforms: []string{"linux/amd64", "linux/arm64"}},
+		{tag: "single", platforms: []string{"linux/arm64"}},
+	}
+	cache := Cache{Dir: t.TempDir()}
+	for _, test := range tests {
+		images, err := cache.SaveRemoteImages(context.Background(), repo+":"+test.tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		platforms := make([]string, 0)
+		for _, pi := range images {
+			platforms = append(platforms, pi.Platform.String())
+		}
+		if strings.Join(platforms, " ") != strings.Join(test.platforms, " ") {
+			t.Errorf("%s: expected platforms %v, got %v", test.tag, test.platforms, platforms)
+		}
+		// the descriptor of the first request is reused for the image
+		if n := manifests["GET "+test.tag]; n != 1 {
+			t.Errorf("%s: expected 1 manifest request, got %d", test.tag, n)
+		}
+	}
+}
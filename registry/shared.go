@@ -0,0 +1,251 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+var errSharingStopped = errors.New("layer download stopped")
+
+// SharedLayers returns img with layers that are downloaded only once, however many catalogers
+// read them. The compressed content is written to a temporary file that readers follow while
+// it is still being downloaded. The returned function stops the downloads and removes the files.
+func SharedLayers(img v1.Image) (v1.Image, func(), error) {
+	dir, err := os.MkdirTemp("", "docker-index-layers-")
+	if err != nil {
+		return nil, func() {}, errors.Wrap(err, "failed to create layer directory")
+	}
+	shared := &sharedLayersImage{Image: img, dir: dir, layers: make(map[v1.Hash]*sharedLayer)}
+	return shared, shared.stop, nil
+}
+
+type sharedLayersImage struct {
+	v1.Image
+	dir string
+
+	mu      sync.Mutex
+	layers  map[v1.Hash]*sharedLayer
+	stopped bool
+}
+
+func (i *sharedLayersImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	for j, l := range layers {
+		if layers[j], err = i.share(l); err != nil {
+			return nil, err
+		}
+	}
+	return layers, nil
+}
+
+func (i *sharedLayersImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	l, err := i.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.share(l)
+}
+
+func (i *sharedLayersImage) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	l, err := i.Image.LayerByDiffID(h)
+	if err != nil {
+		return nil, err
+	}
+	return i.share(l)
+}
+
+// share returns the shared layer of l, the same for every caller
+func (i *sharedLayersImage) share(l v1.Layer) (v1.Layer, error) {
+	d, err := l.Digest()
+	if err != nil {
+		return nil, err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if s, ok := i.layers[d]; ok {
+		return s, nil
+	}
+	s := &sharedLayer{Layer: l, path: filepath.Join(i.dir, d.Algorithm+"-"+d.Hex)}
+	s.cond = sync.NewCond(&s.mu)
+	if i.stopped {
+		s.done, s.err = true, errSharingStopped
+	}
+	i.layers[d] = s
+	return s, nil
+}
+
+func (i *sharedLayersImage) stop() {
+	i.mu.Lock()
+	i.stopped = true
+	for _, l := range i.layers {
+		l.finish(errSharingStopped)
+	}
+	i.mu.Unlock()
+	_ = os.RemoveAll(i.dir)
+}
+
+// sharedLayer is downloaded to path on the first read
+type sharedLayer struct {
+	v1.Layer
+	path string
+	once sync.Once
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	written int64
+	done    bool
+	err     error
+}
+
+func (l *sharedLayer) Compressed() (io.ReadCloser, error) {
+	l.once.Do(l.download)
+	f, err := os.Open(l.path)
+	if err != nil {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.err != nil {
+			return nil, l.err
+		}
+		return nil, errors.Wrap(err, "failed to read layer")
+	}
+	return &sharedReader{layer: l, file: f}, nil
+}
+
+func (l *sharedLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(rc)
+	// like remote layers, the compressed content might not be gzipped after all
+	if magic, _ := br.Peek(2); len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return readCloser{Reader: br, Closer: rc}, nil
+	}
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		rc.Close()
+		return nil, errors.Wrap(err, "failed to decompress layer")
+	}
+	return readCloser{Reader: gz, Closer: rc}, nil
+}
+
+// download copies the compressed content of the layer to path in the background
+func (l *sharedLayer) download() {
+	l.mu.Lock()
+	stopped := l.done
+	l.mu.Unlock()
+	if stopped {
+		return
+	}
+	f, err := os.Create(l.path)
+	if err != nil {
+		l.finish(errors.Wrap(err, "failed to store layer"))
+		return
+	}
+	go func() {
+		defer f.Close()
+		rc, err := l.Layer.Compressed()
+		if err != nil {
+			l.finish(err)
+			return
+		}
+		defer rc.Close()
+		_, err = io.Copy(layerWriter{layer: l, file: f}, rc)
+		l.finish(err)
+	}()
+}
+
+func (l *sharedLayer) finish(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return
+	}
+	l.done, l.err = true, err
+	l.cond.Broadcast()
+}
+
+// layerWriter writes the downloaded content and wakes up the readers waiting for it
+type layerWriter struct {
+	layer *sharedLayer
+	file  *os.File
+}
+
+func (w layerWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.layer.mu.Lock()
+	defer w.layer.mu.Unlock()
+	if w.layer.done {
+		return n, w.layer.err
+	}
+	w.layer.written += int64(n)
+	w.layer.cond.Broadcast()
+	return n, err
+}
+
+// sharedReader reads the downloaded part of a layer, waiting for more until the download is done
+type sharedReader struct {
+	layer  *sharedLayer
+	file   *os.File
+	offset int64
+}
+
+func (r *sharedReader) Read(p []byte) (int, error) {
+	l := r.layer
+	l.mu.Lock()
+	for r.offset >= l.written && !l.done {
+		l.cond.Wait()
+	}
+	available, err := l.written-r.offset, l.err
+	l.mu.Unlock()
+	if available <= 0 {
+		if err != nil {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > available {
+		p = p[:available]
+	}
+	n, err := r.file.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *sharedReader) Close() error {
+	return r.file.Close()
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
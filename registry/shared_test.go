@@ -0,0 +1,106 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestSharedLayers(t *testing.T) {
+	var mu sync.Mutex
+	blobs := 0
+	handler := ggcr.New(ggcr.Logger(log.New(io.Discard, "", 0)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			mu.Lock()
+			blobs++
+			mu.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	img, _ := random.Image(64*1024, 2)
+	ref, _ := name.ParseReference(strings.TrimPrefix(server.URL, "http://") + "/app:latest")
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	pulled, err := remote.Image(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	blobs = 0
+	mu.Unlock()
+
+	shared, stop, err := SharedLayers(pulled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	expected, _ := img.Layers()
+	var wg sync.WaitGroup
+	// three catalogers reading all layers at the same time
+	for c := 0; c < 3; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			layers, err := shared.Layers()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for j, l := range layers {
+				rc, err := l.Uncompressed()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				b, err := io.ReadAll(rc)
+				rc.Close()
+				erc, _ := expected[j].Uncompressed()
+				e, _ := io.ReadAll(erc)
+				if err != nil || !bytes.Equal(b, e) {
+					t.Errorf("unexpected content of layer %d: %v", j, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if blobs != 2 {
+		t.Errorf("expected every layer to be pulled once, got %d blob requests", blobs)
+	}
+
+	stop()
+	diffIds, _ := img.ConfigFile()
+	l, _ := shared.LayerByDiffID(diffIds.RootFS.DiffIDs[0])
+	if _, err := l.Compressed(); err == nil {
+		t.Error("expected read of stopped layers to fail")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/pkg/errors"
+)
+
+// ErrNoCatalog is returned by ListRepositories for registries without catalog API
+var ErrNoCatalog = errors.New("registry doesn't support listing repositories")
+
+// catalogless are registries known to not offer the catalog API to their users
+var catalogless = map[string]bool{
+	"index.docker.io": true,
+	"ghcr.io":         true,
+	"public.ecr.aws":  true,
+	"quay.io":         true,
+}
+
+// ListRepositories returns the repositories below namespace, e.g. registry.example.com/myorg, from
+// the catalog API of the registry. A namespace without path lists all repositories of the registry.
+// Registries without catalog API, like Docker Hub and ghcr.io, return ErrNoCatalog.
+func ListRepositories(ctx context.Context, namespace string) ([]string, error) {
+	host, prefix, _ := strings.Cut(strings.TrimSuffix(namespace, "/"), "/")
+	reg, err := newRegistry(host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse registry: %s", host)
+	}
+	if catalogless[reg.RegistryStr()] {
+		return nil, errors.Wrapf(ErrNoCatalog, "failed to list repositories of %s", host)
+	}
+	repos, err := remote.Catalog(ctx, reg, withAuth(ctx), withTransport())
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && (terr.StatusCode == http.StatusNotFound || terr.StatusCode == http.StatusMethodNotAllowed) {
+			err = ErrNoCatalog
+		}
+		return nil, errors.Wrapf(err, "failed to list repositories of %s", host)
+	}
+	result := make([]string, 0)
+	for _, r := range repos {
+		if prefix == "" || strings.HasPrefix(r, prefix+"/") {
+			result = append(result, host+"/"+r)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// ListTags returns the tags of the repository repo, e.g. docker.io/library/alpine
+func ListTags(ctx context.Context, repo string) ([]string, error) {
+	r, err := newRepository(repo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse repository: %s", repo)
+	}
+	tags, err := remote.List(r, withAuth(ctx), withTransport(), remote.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list tags of %s", repo)
+	}
+	return tags, nil
+}
+
+// ResolveDigest returns the digest image currently refers to without pulling it
+func ResolveDigest(ctx context.Context, image string) (string, error) {
+	ref, err := parseReference(image)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse reference: %s", image)
+	}
+	desc, err := remote.Head(ref, withAuth(ctx), withTransport(), remote.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve digest of %s", image)
+	}
+	return desc.Digest.String(), nil
+}
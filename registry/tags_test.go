@@ -0,0 +1,64 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/pkg/errors"
+)
+
+func TestListRepositories(t *testing.T) {
+	server := httptest.NewServer(ggcr.New(ggcr.Logger(log.New(io.Discard, "", 0))))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	for _, repo := range []string{"myorg/web", "myorg/api", "other/app"} {
+		img, _ := random.Image(1024, 1)
+		ref, _ := name.ParseReference(host + "/" + repo + ":latest")
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		namespace string
+		repos     []string
+		err       error
+	}{
+		{namespace: host + "/myorg", repos: []string{host + "/myorg/api", host + "/myorg/web"}},
+		{namespace: "ghcr.io/myorg", err: ErrNoCatalog},
+		{namespace: "docker.io/myorg", err: ErrNoCatalog},
+	}
+	for _, test := range tests {
+		repos, err := ListRepositories(context.Background(), test.namespace)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected error %v, got %v", test.namespace, test.err, err)
+		}
+		if strings.Join(repos, ",") != strings.Join(test.repos, ",") {
+			t.Errorf("%s: expected repositories %v, got %v", test.namespace, test.repos, repos)
+		}
+	}
+}
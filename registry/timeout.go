@@ -0,0 +1,49 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var pullTimeout time.Duration
+
+// SetPullTimeout sets how long pulling an image into the cache may take before it fails,
+// 0 waits forever. Streamed images are read while they are indexed and not limited.
+func SetPullTimeout(timeout time.Duration) {
+	pullTimeout = timeout
+}
+
+// withPullTimeout returns ctx cancelled once the pull timeout passed
+func withPullTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if pullTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, pullTimeout)
+}
+
+// pullTimeoutError reports err as a timeout if pullCtx ran into the pull timeout rather than
+// ctx being cancelled
+func pullTimeoutError(ctx context.Context, pullCtx context.Context, err error) error {
+	if err == nil || ctx.Err() != nil || pullCtx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return errors.Wrapf(err, "image pull timed out after %s", pullTimeout)
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPullTimeout(t *testing.T) {
+	// a registry accepting connections but never answering
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	SetPullTimeout(100 * time.Millisecond)
+	defer SetPullTimeout(0)
+
+	image := strings.TrimPrefix(server.URL, "http://") + "/library/alpine:3.16"
+	cache := Cache{Dir: t.TempDir()}
+	_, _, err := cache.SaveRemoteImage(context.Background(), image, "")
+	if err == nil || !strings.Contains(err.Error(), "image pull timed out after 100ms") {
+		t.Errorf("expected pull to time out, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = cache.SaveRemoteImage(ctx, image, "")
+	if err == nil || strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected cancelled pull not to be reported as timeout, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/internal"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(ggcr.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	defer internal.SetTLSConfig("", nil) //nolint:errcheck
+
+	if _, err := ListTags(context.Background(), host+"/app"); err == nil {
+		t.Fatal("expected certificate verification to fail")
+	}
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(ca, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := internal.SetTLSConfig(ca, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListTags(context.Background(), host+"/app"); err != nil && strings.Contains(err.Error(), "certificate") {
+		t.Errorf("expected registry CA to be trusted: %s", err)
+	}
+
+	if err := internal.SetTLSConfig("", []string{host}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListTags(context.Background(), host+"/app"); err != nil && strings.Contains(err.Error(), "certificate") {
+		t.Errorf("expected insecure registry to skip verification: %s", err)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// IntegrityError is returned when a stored layer or config doesn't match the digest or size
+// the image manifest records for it, e.g. after a truncated or tampered download
+type IntegrityError struct {
+	// Blob is layer or config
+	Blob     string
+	Digest   v1.Hash
+	Actual   v1.Hash
+	Size     int64
+	Received int64
+}
+
+func (e *IntegrityError) Error() string {
+	if e.Size != e.Received {
+		return fmt.Sprintf("integrity check of %s %s failed: expected %d bytes, got %d", e.Blob, e.Digest, e.Size, e.Received)
+	}
+	return fmt.Sprintf("integrity check of %s %s failed: content has digest %s", e.Blob, e.Digest, e.Actual)
+}
+
+// IsIntegrityError returns true if err is caused by content not matching the image manifest
+func IsIntegrityError(err error) bool {
+	var integrityErr *IntegrityError
+	return errors.As(err, &integrityErr)
+}
+
+// verifiedImage checks the config and layers of img against the digests and sizes of its
+// manifest while they are read
+type verifiedImage struct {
+	v1.Image
+}
+
+func (i verifiedImage) RawConfigFile() ([]byte, error) {
+	b, err := i.Image.RawConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	m, err := i.Image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	if m.Config.Digest.Algorithm != "sha256" {
+		return b, nil
+	}
+	actual, size, err := v1.SHA256(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if actual != m.Config.Digest || (m.Config.Size > 0 && size != m.Config.Size) {
+		return nil, &IntegrityError{Blob: "config", Digest: m.Config.Digest, Actual: actual, Size: m.Config.Size, Received: size}
+	}
+	return b, nil
+}
+
+func (i verifiedImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	verified := make([]v1.Layer, len(layers))
+	for j, l := range layers {
+		verified[j] = verify(l)
+	}
+	return verified, nil
+}
+
+func (i verifiedImage) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	l, err := i.Image.LayerByDigest(h)
+	if err != nil {
+		return nil, err
+	}
+	return verify(l), nil
+}
+
+// verify wraps l unless it is a non-distributable layer whose content was left out
+func verify(l v1.Layer) v1.Layer {
+	if _, ok := l.(foreignLayer); ok {
+		return l
+	}
+	return verifiedLayer{Layer: l}
+}
+
+type verifiedLayer struct {
+	v1.Layer
+}
+
+func (l verifiedLayer) Compressed() (io.ReadCloser, error) {
+	digest, err := l.Digest()
+	if err != nil {
+		return nil, err
+	}
+	size, err := l.Size()
+	if err != nil {
+		return nil, err
+	}
+	rc, err := l.Layer.Compressed()
+	if err != nil || digest.Algorithm != "sha256" {
+		return rc, err
+	}
+	return &verifyingReader{rc: rc, hash: sha256.New(), digest: digest, size: size}, nil
+}
+
+// verifyingReader returns IntegrityError instead of io.EOF if the content read doesn't match
+// digest and size
+type verifyingReader struct {
+	rc     io.ReadCloser
+	hash   hash.Hash
+	digest v1.Hash
+	size   int64
+	read   int64
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.hash.Write(p[:n])
+	r.read += int64(n)
+	if err == io.EOF {
+		actual := v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(r.hash.Sum(nil))}
+		if r.read != r.size || actual != r.digest {
+			return n, &IntegrityError{Blob: "layer", Digest: r.digest, Actual: actual, Size: r.size, Received: r.read}
+		}
+	}
+	return n, err
+}
+
+func (r *verifyingReader) Close() error {
+	return r.rc.Close()
+}
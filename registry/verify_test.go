@@ -0,0 +1,67 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestCopyImageIntegrity(t *testing.T) {
+	for name, tamper := range map[string]func(blob string) error{
+		"tampered": func(blob string) error {
+			b, _ := os.ReadFile(blob)
+			b[len(b)-1] ^= 0xff
+			return os.WriteFile(blob, b, 0644)
+		},
+		"truncated": func(blob string) error {
+			return os.Truncate(blob, 10)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := t.TempDir()
+			p, _ := layout.Write(src, empty.Index)
+			img, _ := random.Image(1024, 1)
+			if err := p.AppendImage(img); err != nil {
+				t.Fatal(err)
+			}
+			layers, _ := img.Layers()
+			digest, _ := layers[0].Digest()
+			if err := tamper(filepath.Join(src, "blobs", digest.Algorithm, digest.Hex)); err != nil {
+				t.Fatal(err)
+			}
+
+			stored, err := ReadImage(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cache := Cache{Dir: t.TempDir()}
+			_, err = cache.CopyImage(stored)
+			if !IsIntegrityError(err) {
+				t.Fatalf("expected integrity error, got %v", err)
+			}
+			if entries, _ := cache.Entries(); len(entries) != 0 {
+				t.Errorf("expected corrupt image to be removed from cache, got %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// ParseAnnotations parses annotations given as key=value, e.g. git.commit=4f1c2a9
+func ParseAnnotations(values []string) (map[string]string, error) {
+	annotations := make(map[string]string)
+	for _, v := range values {
+		k, value, ok := strings.Cut(v, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			return nil, errors.Errorf("invalid annotation %s, expected key=value", v)
+		}
+		annotations[k] = value
+	}
+	return annotations, nil
+}
+
+// Annotate adds annotations to the descriptor of sb, replacing the values of existing keys
+func Annotate(sb *types.Sbom, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	if sb.Descriptor.Annotations == nil {
+		sb.Descriptor.Annotations = make(map[string]string)
+	}
+	for k, v := range annotations {
+		sb.Descriptor.Annotations[k] = v
+	}
+}
+
+// annotationKeys returns the keys of the annotations of sb in order
+func annotationKeys(sb *types.Sbom) []string {
+	keys := make([]string, 0, len(sb.Descriptor.Annotations))
+	for k := range sb.Descriptor.Annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
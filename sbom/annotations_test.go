@@ -0,0 +1,61 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	annotations, err := ParseAnnotations([]string{"git.commit=4f1c2a9", "build.url=https://ci.local/job/1?a=b", "team="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"git.commit": "4f1c2a9", "build.url": "https://ci.local/job/1?a=b", "team": ""}
+	if !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("expected %v, got %v", expected, annotations)
+	}
+	for _, v := range []string{"team", "=platform"} {
+		if _, err := ParseAnnotations([]string{v}); err == nil {
+			t.Errorf("expected %s to be invalid", v)
+		}
+	}
+}
+
+func TestAnnotationsRoundTrip(t *testing.T) {
+	sb := &types.Sbom{Descriptor: types.Descriptor{Name: "docker index", Version: "1.0"}}
+	sb.Source.Image.Digest = "sha256:4ff3ca91275773af45cb4b0834e12b7eb47d1c18f770a0b151381cd227f4c253"
+	expected := map[string]string{"git.commit": "4f1c2a9", "owner": "team-platform"}
+	Annotate(sb, expected)
+
+	fromCdx, err := FromCycloneDX(ToCycloneDX(sb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSpdx, err := FromSPDX(ToSPDX(sb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for format, converted := range map[string]*types.Sbom{"cyclonedx": fromCdx, "spdx": fromSpdx} {
+		if !reflect.DeepEqual(converted.Descriptor.Annotations, expected) {
+			t.Errorf("expected %s annotations %v, got %v", format, expected, converted.Descriptor.Annotations)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/attest"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// attestedSbom returns the sbom of the first verified attestation for the image digest that was
+// created by the current SbomVersion, or nil if there is none
+func (i *Indexer) attestedSbom(ctx context.Context, imageName string, digest string) *types.Sbom {
+	if i.attestationVerifier == nil {
+		return nil
+	}
+	ref, err := name.ParseReference(imageName)
+	if err != nil {
+		return nil
+	}
+	attestations, err := registry.ReadAttestations(ctx, ref.Context().String(), digest)
+	if err != nil {
+		i.logger.Debugf("Failed to read attestations: %s", err)
+	}
+	for _, a := range attestations {
+		statement, err := i.attestationVerifier.Verify(a.Envelope, a.Annotations)
+		if err != nil {
+			i.logger.Debugf("Skipping attestation: %s", err)
+			continue
+		}
+		if sb := statementSbom(statement, digest); sb != nil {
+			return sb
+		}
+	}
+	return nil
+}
+
+func statementSbom(statement *attest.Statement, digest string) *types.Sbom {
+	if statement.PredicateType != attest.PredicateIndexSbom {
+		return nil
+	}
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	subject := false
+	for _, s := range statement.Subject {
+		if s.Digest[algorithm] == hex {
+			subject = true
+		}
+	}
+	if !subject {
+		return nil
+	}
+	var sb types.Sbom
+	if err := json.Unmarshal(statement.Predicate, &sb); err != nil {
+		return nil
+	}
+	if sb.Source.Image.Digest != digest || CheckDescriptor(sb.Descriptor) != nil {
+		return nil
+	}
+	return &sb
+}
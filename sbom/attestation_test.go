@@ -0,0 +1,79 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/attest"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/types"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+)
+
+func TestAttestedSbom(t *testing.T) {
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	repository := strings.TrimPrefix(server.URL, "http://") + "/app"
+	digest := "sha256:4ff3ca91275773af45cb4b0834e12b7eb47d1c18f770a0b151381cd227f4c253"
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	attach := func(sbomVersion string) {
+		sb := types.Sbom{
+			Artifacts:  []types.Package{{Purl: "pkg:alpine/musl@1.2.3"}},
+			Source:     types.Source{Image: types.ImageSource{Digest: digest}},
+			Descriptor: types.Descriptor{SbomVersion: sbomVersion},
+		}
+		js, _ := json.Marshal(sb)
+		statement, _ := attest.NewStatement(repository, digest, attest.PredicateIndexSbom, js)
+		envelope, _ := attest.Sign(statement, attest.NewSigner(key))
+		raw, _ := json.Marshal(envelope)
+		if err := registry.AttachAttestation(context.Background(), repository, digest, raw, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	attach("1")
+	indexer := NewIndexer(WithAttestationReuse(attest.NewKeyVerifier(&key.PublicKey)))
+	if sb := indexer.attestedSbom(context.Background(), repository+"@"+digest, digest); sb != nil {
+		t.Error("expected attestation of outdated sbom version to be skipped")
+	}
+
+	attach(internal.FromBuild().SbomVersion)
+	if sb := indexer.attestedSbom(context.Background(), repository+"@"+digest, digest); sb == nil || len(sb.Artifacts) != 1 {
+		t.Errorf("expected sbom to be loaded from attestation, got %v", sb)
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	indexer = NewIndexer(WithAttestationReuse(attest.NewKeyVerifier(&other.PublicKey)))
+	if sb := indexer.attestedSbom(context.Background(), repository+"@"+digest, digest); sb != nil {
+		t.Error("expected attestation signed by other key to be skipped")
+	}
+
+	indexer = NewIndexer(WithAttestationReuse(nil))
+	if sb := indexer.attestedSbom(context.Background(), repository+"@"+digest, digest); sb != nil {
+		t.Error("expected attestation without verifier to be skipped")
+	}
+}
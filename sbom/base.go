@@ -0,0 +1,143 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	baseNameLabel   = "org.opencontainers.image.base.name"
+	baseDigestLabel = "org.opencontainers.image.base.digest"
+)
+
+// readRemoteImage reads the manifest and config of base image candidates from their registry
+var readRemoteImage = registry.ReadRemoteImage
+
+// DetectBaseImage matches the leading layers of the indexed image against the
+// candidate base images and, if fromLabels is set, the base image recorded in the
+// OCI image labels. Only then the registries of the candidates are queried.
+// The candidate sharing the most layers is recorded as base image on the sbom
+// source and all packages introduced by its layers are flagged accordingly.
+func DetectBaseImage(sb *types.Sbom, candidates []string, fromLabels bool) *types.BaseImage {
+	config := sb.Source.Image.Config
+	if config == nil {
+		return nil
+	}
+	candidates = append([]string{}, candidates...)
+	if fromLabels {
+		candidates = append(candidates, baseImageFromLabels(config)...)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var base *types.BaseImage
+	var baseHistory int
+	for _, candidate := range candidates {
+		img, err := readRemoteImage(candidate, sb.Source.Image.Platform.String())
+		if err != nil {
+			log.Warnf("Failed to read base image candidate %s: %s", candidate, err)
+			continue
+		}
+		c, err := img.ConfigFile()
+		if err != nil {
+			log.Warnf("Failed to read config of base image candidate %s: %s", candidate, err)
+			continue
+		}
+		count := commonLayers(config.RootFS.DiffIDs, c.RootFS.DiffIDs)
+		if count == 0 || count != len(c.RootFS.DiffIDs) {
+			continue
+		}
+		if base == nil || count > base.LayerCount {
+			digest, _ := img.Digest()
+			base = &types.BaseImage{
+				Name:       candidate,
+				Digest:     digest.String(),
+				LayerCount: count,
+			}
+			baseHistory = len(c.History)
+		}
+	}
+
+	if base == nil {
+		return nil
+	}
+	log.Infof("Detected base image %s", base.Name)
+	sb.Source.Image.BaseImage = base
+	for i, p := range sb.Artifacts {
+		if p.Layer != nil {
+			sb.Artifacts[i].Layer.BaseImage = p.Layer.Ordinal < base.LayerCount
+		}
+	}
+	// the history of an image starts with the history of its base image
+	for i, h := range sb.Source.Image.History {
+		sb.Source.Image.History[i].BaseImage = i < baseHistory
+		if h.Layer != nil {
+			h.Layer.BaseImage = h.Layer.Ordinal < base.LayerCount
+		}
+	}
+	return base
+}
+
+// IsFromBaseImage returns true if the package with purl was introduced by a layer of the base image
+func IsFromBaseImage(sb *types.Sbom, purl string) bool {
+	if p, ok := findPackage(sb, purl); ok && p.Layer != nil {
+		return p.Layer.BaseImage
+	}
+	return false
+}
+
+// SplitCves partitions the vulnerabilities of the sbom into those inherited from the
+// base image and those introduced by the image build
+func SplitCves(sb *types.Sbom) ([]types.Cve, []types.Cve) {
+	inherited := make([]types.Cve, 0)
+	introduced := make([]types.Cve, 0)
+	for _, c := range sb.Vulnerabilities {
+		if IsFromBaseImage(sb, c.Purl) {
+			inherited = append(inherited, c)
+		} else {
+			introduced = append(introduced, c)
+		}
+	}
+	return inherited, introduced
+}
+
+func baseImageFromLabels(config *v1.ConfigFile) []string {
+	name := config.Config.Labels[baseNameLabel]
+	if name == "" {
+		return nil
+	}
+	if digest := config.Config.Labels[baseDigestLabel]; digest != "" {
+		return []string{name + "@" + digest}
+	}
+	return []string{name}
+}
+
+func commonLayers(image []v1.Hash, base []v1.Hash) int {
+	count := 0
+	for i := range base {
+		if i >= len(image) || image[i] != base[i] {
+			break
+		}
+		count++
+	}
+	return count
+}
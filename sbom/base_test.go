@@ -0,0 +1,144 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/pkg/errors"
+)
+
+func layersConfig(diffIds ...string) *v1.ConfigFile {
+	config := &v1.ConfigFile{}
+	for _, d := range diffIds {
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, v1.Hash{Algorithm: "sha256", Hex: d})
+		config.History = append(config.History, v1.History{CreatedBy: "ADD " + d})
+	}
+	return config
+}
+
+func TestDetectBaseImage(t *testing.T) {
+	images := make(map[string]v1.Image)
+	for ref, diffIds := range map[string][]string{
+		"alpine:3.18":        {"a"},
+		"alpine-curl:3.18":   {"a", "b"},
+		"debian:12":          {"d"},
+		"labelled@sha256:42": {"a", "b"},
+	} {
+		img, err := mutate.ConfigFile(empty.Image, layersConfig(diffIds...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		images[ref] = img
+	}
+	defer func(f func(string, string) (v1.Image, error)) { readRemoteImage = f }(readRemoteImage)
+	var read []string
+	readRemoteImage = func(image string, platform string) (v1.Image, error) {
+		read = append(read, image)
+		if img, ok := images[image]; ok {
+			return img, nil
+		}
+		return nil, errors.Errorf("%s not found", image)
+	}
+
+	tests := []struct {
+		candidates []string
+		fromLabels bool
+		base       string
+		layers     int
+		read       string
+	}{
+		{candidates: nil},
+		{candidates: nil, fromLabels: true, base: "labelled@sha256:42", layers: 2, read: "labelled@sha256:42"},
+		{candidates: []string{"alpine:3.18"}, base: "alpine:3.18", layers: 1, read: "alpine:3.18"},
+		{candidates: []string{"alpine:3.18"}, fromLabels: true, base: "labelled@sha256:42", layers: 2, read: "alpine:3.18 labelled@sha256:42"},
+		{candidates: []string{"alpine:3.18", "alpine-curl:3.18", "debian:12"}, base: "alpine-curl:3.18", layers: 2, read: "alpine:3.18 alpine-curl:3.18 debian:12"},
+		{candidates: []string{"debian:12", "missing:1"}, read: "debian:12 missing:1"},
+	}
+	for _, test := range tests {
+		read = nil
+		config := layersConfig("a", "b", "c")
+		config.Config.Labels = map[string]string{baseNameLabel: "labelled", baseDigestLabel: "sha256:42"}
+		sb := &types.Sbom{
+			Source: types.Source{Image: types.ImageSource{Config: config, History: imageHistory(config, nil)}},
+			Artifacts: []types.Package{
+				{Name: "musl", Layer: &types.Layer{Ordinal: 0}},
+				{Name: "curl", Layer: &types.Layer{Ordinal: 1}},
+				{Name: "app", Layer: &types.Layer{Ordinal: 2}},
+			},
+		}
+		// candidates with spare capacity must not be written to
+		candidates := make([]string, len(test.candidates), len(test.candidates)+1)
+		copy(candidates, test.candidates)
+
+		base := DetectBaseImage(sb, candidates, test.fromLabels)
+		if strings.Join(read, " ") != test.read {
+			t.Errorf("%v %v: expected %q to be read, got %v", test.candidates, test.fromLabels, test.read, read)
+		}
+		if spare := candidates[:cap(candidates)][len(candidates)]; spare != "" {
+			t.Errorf("%v %v: candidates were modified: %s", test.candidates, test.fromLabels, spare)
+		}
+		if test.base == "" {
+			if base != nil || sb.Source.Image.BaseImage != nil {
+				t.Errorf("%v %v: expected no base image, got %+v", test.candidates, test.fromLabels, base)
+			}
+			continue
+		}
+		if base == nil || base.Name != test.base || base.LayerCount != test.layers || sb.Source.Image.BaseImage != base {
+			t.Errorf("%v %v: expected base image %s with %d layers, got %+v", test.candidates, test.fromLabels, test.base, test.layers, base)
+			continue
+		}
+		for _, p := range sb.Artifacts {
+			if p.Layer.BaseImage != (p.Layer.Ordinal < test.layers) {
+				t.Errorf("%v %v: unexpected base image flag of %s", test.candidates, test.fromLabels, p.Name)
+			}
+		}
+		for i, h := range sb.Source.Image.History {
+			if h.BaseImage != (i < test.layers) {
+				t.Errorf("%v %v: unexpected base image flag of history entry %d", test.candidates, test.fromLabels, i)
+			}
+		}
+	}
+
+	if base := DetectBaseImage(&types.Sbom{}, []string{"alpine:3.18"}, true); base != nil {
+		t.Errorf("expected no base image without config, got %+v", base)
+	}
+}
+
+func TestSplitCves(t *testing.T) {
+	sb := &types.Sbom{
+		Artifacts: []types.Package{
+			{Purl: "pkg:apk/alpine/musl@1.2.4", Layer: &types.Layer{Ordinal: 0, BaseImage: true}},
+			{Purl: "pkg:npm/app@1.0.0", Layer: &types.Layer{Ordinal: 1}},
+			{Purl: "pkg:npm/unknown@1.0.0"},
+		},
+		Vulnerabilities: []types.Cve{
+			{SourceId: "CVE-1", Purl: "pkg:apk/alpine/musl@1.2.4"},
+			{SourceId: "CVE-2", Purl: "pkg:npm/app@1.0.0"},
+			{SourceId: "CVE-3", Purl: "pkg:npm/unknown@1.0.0"},
+		},
+	}
+	inherited, introduced := SplitCves(sb)
+	if len(inherited) != 1 || inherited[0].SourceId != "CVE-1" || len(introduced) != 2 {
+		t.Errorf("expected CVE-1 inherited and 2 introduced, got %v and %v", inherited, introduced)
+	}
+}
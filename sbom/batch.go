@@ -0,0 +1,144 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// BatchOptions configure IndexImages. Parallelism, Client, Remote and Platform are ignored
+// by (*Indexer).IndexImages which uses the options of the Indexer instead.
+type BatchOptions struct {
+	// Parallelism limits the number of images indexed at the same time, defaults to the number of CPUs
+	Parallelism int
+	// Timeout limits the time to index a single image, no limit if zero
+	Timeout time.Duration
+	// Client is used to copy images from the Docker daemon unless Remote is set
+	Client   client.APIClient
+	Remote   bool
+	Platform string
+	// IncludeCves queries the CVEs of every indexed image
+	IncludeCves       bool
+	Workspace, ApiKey string
+}
+
+// queryFunc queries the CVEs of an indexed image; replaced in tests
+var queryFunc = query.QueryCvesContext
+
+// indexFunc indexes a single image; replaced in tests
+var indexFunc = func(ctx context.Context, indexer *Indexer, image string) (*types.Sbom, *v1.Image, error) {
+	return indexer.Index(ctx, image)
+}
+
+// IndexImages indexes the images with a bounded pool of workers and returns one result per image
+// in the order of images. Failed, timed out or cancelled images are reported in IndexError, failed
+// CVE queries in QueryError of the result.
+func IndexImages(ctx context.Context, images []string, opts BatchOptions) []ImageIndexResult {
+	indexerOpts := []Option{WithPlatform(opts.Platform), WithClient(opts.Client)}
+	if opts.Parallelism > 0 {
+		indexerOpts = append(indexerOpts, WithParallelism(opts.Parallelism))
+	}
+	if opts.Remote {
+		indexerOpts = append(indexerOpts, WithRemote())
+	}
+	return NewIndexer(indexerOpts...).IndexImages(ctx, images, opts)
+}
+
+// IndexImages indexes the images with at most the configured parallelism, see IndexImages
+func (i *Indexer) IndexImages(ctx context.Context, images []string, opts BatchOptions) []ImageIndexResult {
+	parallelism := i.parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+
+	results := make([]ImageIndexResult, len(images))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism && w < len(images); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = i.indexWithTimeout(ctx, images[j], opts)
+			}
+		}()
+	}
+
+	for j, image := range images {
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+			results[j] = ImageIndexResult{Input: image, IndexError: ctx.Err()}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// indexWithTimeout indexes the image and reports an error once the timeout or context expire.
+// It only returns after indexing stopped so that cancelled images still count against the
+// parallelism of the pool until their pull and catalogers cleaned up.
+func (i *Indexer) indexWithTimeout(ctx context.Context, image string, opts BatchOptions) ImageIndexResult {
+	if err := ctx.Err(); err != nil {
+		return ImageIndexResult{Input: image, IndexError: err}
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan ImageIndexResult, 1)
+	go func() {
+		done <- i.indexBatchImage(ctx, image, opts)
+	}()
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		<-done
+		return ImageIndexResult{Input: image, IndexError: errors.Wrapf(ctx.Err(), "indexing %s did not complete", image)}
+	}
+}
+
+func (i *Indexer) indexBatchImage(ctx context.Context, image string, opts BatchOptions) ImageIndexResult {
+	sb, img, err := indexFunc(ctx, i, image)
+	if err != nil {
+		return ImageIndexResult{Input: image, IndexError: err}
+	}
+	if opts.IncludeCves {
+		cves, err := queryFunc(ctx, sb, "", opts.Workspace, opts.ApiKey)
+		if err != nil {
+			return ImageIndexResult{Input: image, Image: img, Sbom: sb, QueryError: err}
+		}
+		if cves != nil {
+			sb.Vulnerabilities = *cves
+			SortSbom(sb)
+		}
+	}
+	return ImageIndexResult{Input: image, Image: img, Sbom: sb}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestIndexImages(t *testing.T) {
+	var running, maxRunning int32
+	defer func(f func(context.Context, *Indexer, string) (*types.Sbom, *v1.Image, error)) { indexFunc = f }(indexFunc)
+	indexFunc = func(ctx context.Context, indexer *Indexer, image string) (*types.Sbom, *v1.Image, error) {
+		r := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+				break
+			}
+		}
+		if image == "slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		} else {
+			time.Sleep(10 * time.Millisecond)
+		}
+		return &types.Sbom{Source: types.Source{Image: types.ImageSource{Name: image}}}, nil, nil
+	}
+
+	images := []string{"slow"}
+	for i := 0; i < 10; i++ {
+		images = append(images, fmt.Sprintf("image-%d", i))
+	}
+	results := IndexImages(context.Background(), images, BatchOptions{Parallelism: 3, Timeout: 100 * time.Millisecond})
+
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 images to be indexed at once, got %d", maxRunning)
+	}
+	if !errors.Is(results[0].IndexError, context.DeadlineExceeded) {
+		t.Errorf("expected slow image to time out, got %v", results[0].IndexError)
+	}
+	for i, r := range results[1:] {
+		if r.Err() != nil || r.Input != images[i+1] || r.Sbom.Source.Image.Name != images[i+1] {
+			t.Errorf("unexpected result for %s: %v", images[i+1], r)
+		}
+	}
+}
+
+func TestIndexImagesQueryError(t *testing.T) {
+	defer func(f func(context.Context, *Indexer, string) (*types.Sbom, *v1.Image, error)) { indexFunc = f }(indexFunc)
+	defer func(f func(context.Context, *types.Sbom, string, string, string) (*[]types.Cve, error)) {
+		queryFunc = f
+	}(queryFunc)
+	indexFunc = func(ctx context.Context, indexer *Indexer, image string) (*types.Sbom, *v1.Image, error) {
+		if image == "broken" {
+			return nil, nil, errors.New("failed to pull")
+		}
+		return &types.Sbom{}, nil, nil
+	}
+	queryFunc = func(ctx context.Context, sb *types.Sbom, cve string, workspace string, apiKey string) (*[]types.Cve, error) {
+		return nil, errors.New("unauthorized")
+	}
+
+	results := IndexImages(context.Background(), []string{"alpine", "broken"}, BatchOptions{IncludeCves: true})
+	if !results[0].Partial() || results[0].Sbom == nil || results[0].Err() == nil {
+		t.Errorf("expected partial result with query error, got %v", results[0])
+	}
+	if results[1].Partial() || results[1].IndexError == nil || results[1].QueryError != nil {
+		t.Errorf("expected index error, got %v", results[1])
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+const bitnamiSpdx = `{
+  "spdxVersion": "SPDX-2.3",
+  "name": "postgresql",
+  "packages": [{
+    "SPDXID": "SPDXRef-postgresql",
+    "name": "postgresql",
+    "versionInfo": "15.2.0",
+    "licenseConcluded": "PostgreSQL",
+    "externalRefs": [{
+      "referenceCategory": "PACKAGE-MANAGER",
+      "referenceType": "purl",
+      "referenceLocator": "pkg:bitnami/postgresql@15.2.0-0?arch=amd64&distro=debian-11"
+    }]
+  }]
+}`
+
+func TestIndexBitnamiComponents(t *testing.T) {
+	content := tarArchive(map[string][]byte{
+		"opt/bitnami/postgresql/.spdx-postgresql.spdx": []byte(bitnamiSpdx),
+		"opt/bitnami/.bitnami_components.json":         []byte(`{"postgresql":{"arch":"amd64","distro":"debian-11","type":"NAMI","version":"15.2.0-0"},"gosu":{"arch":"amd64","distro":"debian-11","type":"NAMI","version":"1.16.0-0"}}`),
+	})
+	layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+	img, _ := mutate.AppendLayers(empty.Image, layer)
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/postgresql:15"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerBitnami)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"pkg:bitnami/postgresql@15.2.0-0": "/opt/bitnami/postgresql/.spdx-postgresql.spdx",
+		"pkg:bitnami/gosu@1.16.0-0":       "/opt/bitnami/.bitnami_components.json",
+	}
+	if len(sb.Artifacts) != len(expected) {
+		t.Errorf("expected %d packages, got %v", len(expected), sb.Artifacts)
+	}
+	for _, p := range sb.Artifacts {
+		path, ok := expected[p.Purl]
+		if !ok {
+			t.Errorf("unexpected package %s", p.Purl)
+			continue
+		}
+		if p.Locations[0].Path != path {
+			t.Errorf("expected %s at %s, got %v", p.Purl, path, p.Locations)
+		}
+		if p.Name == "postgresql" && (len(p.Licenses) != 1 || p.Licenses[0] != "PostgreSQL") {
+			t.Errorf("expected PostgreSQL license, got %v", p.Licenses)
+		}
+	}
+}
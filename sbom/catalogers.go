@@ -0,0 +1,266 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	stereoscopeimage "github.com/anchore/stereoscope/pkg/image"
+	"github.com/aquasecurity/trivy/pkg/fanal/analyzer"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/sbom/detect"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	CatalogerSyft  = "syft"
+	CatalogerTrivy = "trivy"
+
+	CatalogerOs         = "os"
+	CatalogerJava       = "java"
+	CatalogerGo         = "go"
+	CatalogerJavascript = "javascript"
+	CatalogerPython     = "python"
+	CatalogerRuby       = "ruby"
+	CatalogerPhp        = "php"
+	CatalogerDotnet     = "dotnet"
+	CatalogerDart       = "dart"
+	CatalogerConda      = "conda"
+	CatalogerNix        = "nix"
+	CatalogerBitnami    = "bitnami"
+	CatalogerBinary     = "binary"
+)
+
+// Cataloger detects packages of formats syft and trivy don't know, e.g. in-house package formats.
+// Add implementations to all indexers with RegisterCataloger or to one with WithCataloger.
+type Cataloger interface {
+	// Name identifies the cataloger in WithCatalogers, WithoutCatalogers and the sbom descriptor
+	Name() string
+	// Catalog returns the packages found in the layers of the image, lowest layer first. Locations
+	// of the packages should carry the diff id of the layer and its digest from lm.
+	Catalog(ctx context.Context, layers []LayerFS, lm types.LayerMapping) ([]types.Package, error)
+}
+
+// LayerFS is the filesystem of a single image layer
+type LayerFS interface {
+	// DiffId returns the diff id of the layer
+	DiffId() string
+	// Files returns the paths of all regular files added or changed by the layer
+	Files() []string
+	// Open returns the content of the file at path
+	Open(path string) (io.ReadCloser, error)
+}
+
+var (
+	registeredCatalogers []Cataloger
+	registeredMutex      sync.Mutex
+)
+
+// RegisterCataloger adds c to the catalogers of every Indexer created afterwards
+func RegisterCataloger(c Cataloger) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	registeredCatalogers = append(registeredCatalogers, c)
+}
+
+func registered() []Cataloger {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	return append([]Cataloger{}, registeredCatalogers...)
+}
+
+// stereoscopeLayer is the LayerFS of a layer read by syft
+type stereoscopeLayer struct {
+	layer *stereoscopeimage.Layer
+}
+
+func (l stereoscopeLayer) DiffId() string {
+	// the stereoscope layers use diff_ids internally as their digest
+	return l.layer.Metadata.Digest
+}
+
+func (l stereoscopeLayer) Files() []string {
+	files := make([]string, 0)
+	for _, ref := range l.layer.Tree.AllFiles(file.TypeReg) {
+		files = append(files, string(ref.RealPath))
+	}
+	return files
+}
+
+func (l stereoscopeLayer) Open(path string) (io.ReadCloser, error) {
+	return l.layer.FileContents(file.Path(path))
+}
+
+// customSbom runs the custom catalogers on the layers of the image and sends one result per cataloger
+func customSbom(ctx context.Context, input imageInput, lm types.LayerMapping, catalogers []Cataloger, resultChan chan<- []types.IndexResult) {
+	defer close(resultChan)
+
+	results := make([]types.IndexResult, 0)
+	src, cleanup, err := syftSource(ctx, input)
+	defer cleanup()
+	if err != nil {
+		for _, c := range catalogers {
+			result := types.IndexResult{
+				Name:   c.Name(),
+				Status: types.Failed,
+				Error:  errors.Wrap(err, "failed to create image source"),
+			}
+			input.progress.CatalogerCompleted(result.Name, 0, result.Error)
+			results = append(results, result)
+		}
+		resultChan <- results
+		return
+	}
+
+	layers := make([]LayerFS, 0)
+	for _, l := range src.Image.Layers {
+		layers = append(layers, stereoscopeLayer{layer: l})
+	}
+	for _, c := range catalogers {
+		result := types.IndexResult{Name: c.Name(), Status: types.Success}
+		result.Packages, err = c.Catalog(ctx, layers, lm)
+		if err != nil {
+			result.Status = types.Failed
+			result.Error = errors.Wrapf(err, "failed to catalog packages with %s", c.Name())
+		}
+		input.progress.CatalogerCompleted(result.Name, len(result.Packages), result.Error)
+		results = append(results, result)
+	}
+	resultChan <- results
+}
+
+// catalogerGroup lists the syft catalogers and trivy analyzers that detect one kind of packages
+type catalogerGroup struct {
+	syft  []string
+	trivy []analyzer.Type
+}
+
+// catalogerGroups maps the cataloger names accepted by WithCatalogers and WithoutCatalogers,
+// besides CatalogerSyft and CatalogerTrivy, to the syft catalogers and trivy analyzers they select
+var catalogerGroups = map[string]catalogerGroup{
+	CatalogerOs:         {syft: []string{"alpmdb-cataloger", "apkdb-cataloger", "dpkgdb-cataloger", "rpm-db-cataloger", "portage-cataloger"}},
+	CatalogerJava:       {syft: []string{"java-cataloger"}, trivy: []analyzer.Type{analyzer.TypeJar, analyzer.TypePom}},
+	CatalogerGo:         {syft: []string{"go-module-binary-cataloger"}, trivy: []analyzer.Type{analyzer.TypeGoBinary, analyzer.TypeGoMod}},
+	CatalogerJavascript: {syft: []string{detect.JavascriptCataloger}},
+	CatalogerPython:     {syft: []string{"python-package-cataloger"}},
+	CatalogerRuby:       {syft: []string{"ruby-gemspec-cataloger", "ruby-gemfile-cataloger"}},
+	CatalogerPhp:        {syft: []string{"php-composer-installed-cataloger", "php-composer-lock-cataloger"}},
+	CatalogerDotnet:     {syft: []string{"dotnet-deps-cataloger"}},
+	CatalogerDart:       {syft: []string{"dartlang-lock-cataloger"}},
+	CatalogerConda:      {syft: []string{detect.CondaCataloger}},
+	CatalogerNix:        {syft: []string{detect.NixCataloger}},
+	CatalogerBitnami:    {syft: []string{detect.BitnamiCataloger}},
+	CatalogerBinary:     {syft: []string{detect.BinaryCataloger}},
+}
+
+// CatalogerNames returns the names accepted by WithCatalogers and WithoutCatalogers, including
+// those of registered catalogers
+func CatalogerNames() []string {
+	names := []string{CatalogerSyft, CatalogerTrivy}
+	groups := make([]string, 0)
+	for n := range catalogerGroups {
+		groups = append(groups, n)
+	}
+	for _, c := range registered() {
+		groups = append(groups, c.Name())
+	}
+	sort.Strings(groups)
+	return append(names, groups...)
+}
+
+// ValidateCatalogers returns an error if names contains unknown catalogers
+func ValidateCatalogers(names []string) error {
+	known := CatalogerNames()
+	for _, n := range names {
+		if !internal.Contains(known, n) {
+			return errors.Errorf("unknown cataloger %s, expected one of %s", n, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// enabled reports if the packages of group are cataloged by tool
+func (i *Indexer) enabled(tool string, group string) bool {
+	if i.excluded[tool] || i.excluded[group] {
+		return false
+	}
+	return len(i.catalogers) == 0 || i.catalogers[tool] || i.catalogers[group]
+}
+
+// runs reports if tool catalogs any group of packages
+func (i *Indexer) runs(tool string) bool {
+	for g, c := range catalogerGroups {
+		if (tool == CatalogerSyft && len(c.syft) > 0 || tool == CatalogerTrivy && len(c.trivy) > 0) && i.enabled(tool, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// customCatalogers returns the custom catalogers that are enabled
+func (i *Indexer) customCatalogers() []Cataloger {
+	enabled := make([]Cataloger, 0)
+	for _, c := range i.custom {
+		if !i.excluded[c.Name()] && (len(i.catalogers) == 0 || i.catalogers[c.Name()]) {
+			enabled = append(enabled, c)
+		}
+	}
+	return enabled
+}
+
+// defaultCatalogers reports if exactly the built-in catalogers run
+func (i *Indexer) defaultCatalogers() bool {
+	return len(i.catalogers) == 0 && len(i.excluded) == 0 && len(i.custom) == 0
+}
+
+// defaultSbom reports if the sbom is created by the built-in catalogers with the default purl
+// qualifiers and CPEs, the default secret rules, an unredacted config and without file inventory,
+// os package files, waste analysis or leftovers; only those sboms are cached or loaded from
+// attestations
+func (i *Indexer) defaultSbom() bool {
+	return i.defaultCatalogers() && !i.customSecretScanner && len(i.purlQualifiers) == 0 && i.cpes && !i.redactEnv && !i.files && !i.packageFiles && !i.waste && !i.leftovers
+}
+
+// syftCataloger reports if the syft cataloger with the given name is enabled
+func (i *Indexer) syftCataloger(name string) bool {
+	for g, c := range catalogerGroups {
+		for _, n := range c.syft {
+			if n == name {
+				return i.enabled(CatalogerSyft, g)
+			}
+		}
+	}
+	// catalogers of other ecosystems run unless syft is restricted to some groups
+	return i.runs(CatalogerSyft) && (len(i.catalogers) == 0 || i.catalogers[CatalogerSyft])
+}
+
+// disabledAnalyzers returns the trivy analyzers of the groups that are not enabled
+func (i *Indexer) disabledAnalyzers() []analyzer.Type {
+	disabled := make([]analyzer.Type, 0)
+	for g, c := range catalogerGroups {
+		if !i.enabled(CatalogerTrivy, g) {
+			disabled = append(disabled, c.trivy...)
+		}
+	}
+	return disabled
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// Checks of the image configuration
+const (
+	CheckRootUser       = "root-user"
+	CheckLatestTag      = "latest-tag"
+	CheckNoHealthcheck  = "missing-healthcheck"
+	CheckSecretEnv      = "secret-env"
+	CheckPrivilegedPort = "privileged-port"
+	CheckLayerCount     = "layer-count"
+)
+
+// maxLayers is the number of layers above which images are reported as oversized; every layer
+// adds to pull time and the overlay filesystem lookups
+const maxLayers = 50
+
+// secretEnv matches names of environment variables that usually hold credentials, except for
+// those pointing to a file holding them like POSTGRES_PASSWORD_FILE
+var secretEnv = regexp.MustCompile(`(?i)(PASSWORD|PASSWD|SECRET|TOKEN|API_?KEY|PRIVATE_?KEY|ACCESS_?KEY|CREDENTIALS?)`)
+var secretEnvReference = regexp.MustCompile(`(?i)_(FILE|PATH|DIR)$`)
+
+// CheckConfig returns the findings of the built-in checks of the image configuration and tags
+// of sb. Findings of secrets in the environment name the variable but never its value.
+func CheckConfig(sb *types.Sbom) []types.ConfigFinding {
+	findings := make([]types.ConfigFinding, 0)
+	image := sb.Source.Image
+	if image.Config == nil {
+		return findings
+	}
+	config := image.Config.Config
+	if image.Config.OS != "windows" && runsAsRoot(config.User) {
+		findings = append(findings, types.ConfigFinding{
+			Check:    CheckRootUser,
+			Severity: "HIGH",
+			Message:  "image runs as root; add a USER instruction with an unprivileged user",
+		})
+	}
+	if image.Tags != nil {
+		for _, t := range *image.Tags {
+			if t == "latest" {
+				findings = append(findings, types.ConfigFinding{
+					Check:    CheckLatestTag,
+					Severity: "LOW",
+					Message:  "image is referenced by the latest tag; pin a version tag or digest",
+				})
+			}
+		}
+	}
+	if h := config.Healthcheck; h == nil || len(h.Test) == 0 || h.Test[0] == "NONE" {
+		findings = append(findings, types.ConfigFinding{
+			Check:    CheckNoHealthcheck,
+			Severity: "LOW",
+			Message:  "image has no HEALTHCHECK",
+		})
+	}
+	for _, e := range config.Env {
+		name, value, _ := strings.Cut(e, "=")
+		if value != "" && secretEnv.MatchString(name) && !secretEnvReference.MatchString(name) {
+			findings = append(findings, types.ConfigFinding{
+				Check:    CheckSecretEnv,
+				Severity: "HIGH",
+				Message:  fmt.Sprintf("environment variable %s looks like a secret; pass it at runtime or use a build secret", name),
+			})
+		}
+	}
+	for port := range config.ExposedPorts {
+		number, protocol, _ := strings.Cut(port, "/")
+		if n, err := strconv.Atoi(number); err == nil && n > 0 && n < 1024 {
+			findings = append(findings, types.ConfigFinding{
+				Check:    CheckPrivilegedPort,
+				Severity: "LOW",
+				Message:  fmt.Sprintf("image exposes privileged port %d/%s which requires root or CAP_NET_BIND_SERVICE to bind", n, protocol),
+			})
+		}
+	}
+	if layers := len(image.Config.RootFS.DiffIDs); layers > maxLayers {
+		findings = append(findings, types.ConfigFinding{
+			Check:    CheckLayerCount,
+			Severity: "LOW",
+			Message:  fmt.Sprintf("image has %d layers, more than %d; combine RUN instructions or use a multi-stage build", layers, maxLayers),
+		})
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.Message < b.Message
+	})
+	return findings
+}
+
+// runsAsRoot returns true if user, in the name[:group] or uid[:gid] form of the USER
+// instruction, is empty or selects root
+func runsAsRoot(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "" || name == "root" || name == "0"
+}
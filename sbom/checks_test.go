@@ -0,0 +1,74 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestCheckConfig(t *testing.T) {
+	config := &v1.ConfigFile{
+		OS: "linux",
+		Config: v1.Config{
+			Env: []string{
+				"PATH=/usr/local/bin:/usr/bin",
+				"DB_PASSWORD=hunter2",
+				"POSTGRES_PASSWORD_FILE=/run/secrets/db",
+				"API_TOKEN=",
+			},
+			ExposedPorts: map[string]struct{}{"80/tcp": {}, "8080/tcp": {}},
+		},
+	}
+	for i := 0; i < 51; i++ {
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, v1.Hash{})
+	}
+	tags := []string{"latest"}
+	sb := &types.Sbom{Source: types.Source{Image: types.ImageSource{Config: config, Tags: &tags}}}
+
+	findings := CheckConfig(sb)
+	checks := make([]string, 0)
+	for _, f := range findings {
+		checks = append(checks, f.Check)
+		if strings.Contains(f.Message, "hunter2") {
+			t.Errorf("finding %s leaks the secret value: %s", f.Check, f.Message)
+		}
+	}
+	expected := "latest-tag layer-count missing-healthcheck privileged-port root-user secret-env"
+	if strings.Join(checks, " ") != expected {
+		t.Errorf("expected checks %s, got %v", expected, checks)
+	}
+
+	config.Config.User = "app:app"
+	config.Config.Env = nil
+	config.Config.ExposedPorts = nil
+	config.Config.Healthcheck = &v1.HealthConfig{Test: []string{"CMD", "true"}}
+	config.RootFS.DiffIDs = config.RootFS.DiffIDs[:3]
+	sb.Source.Image.Tags = &[]string{"1.2"}
+	if findings := CheckConfig(sb); len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+
+	config.Config.User = "0:0"
+	config.Config.Healthcheck = &v1.HealthConfig{Test: []string{"NONE"}}
+	if findings := CheckConfig(sb); len(findings) != 2 {
+		t.Errorf("expected root and healthcheck findings, got %v", findings)
+	}
+}
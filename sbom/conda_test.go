@@ -0,0 +1,90 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func pythonMetadata(name, version string) []byte {
+	return []byte("Metadata-Version: 2.1\nName: " + name + "\nVersion: " + version + "\n")
+}
+
+func TestIndexPythonEnvironments(t *testing.T) {
+	content := tarArchive(map[string][]byte{
+		"opt/conda/conda-meta/openssl-3.0.7-h0b41bf4_0.json": []byte(`{"name":"openssl","version":"3.0.7","build":"h0b41bf4_0",
+			"channel":"https://conda.anaconda.org/conda-forge/linux-64","subdir":"linux-64","license":"Apache-2.0",
+			"files":["lib/libssl.so.3","lib/libcrypto.so.3"]}`),
+		"opt/conda/conda-meta/numpy-1.23.4-py310h53a5b5f_0.json": []byte(`{"name":"numpy","version":"1.23.4","build":"py310h53a5b5f_0",
+			"channel":"https://conda.anaconda.org/conda-forge/linux-64","subdir":"linux-64",
+			"files":["lib/python3.10/site-packages/numpy-1.23.4.dist-info/METADATA"]}`),
+		"opt/conda/lib/python3.10/site-packages/numpy-1.23.4.dist-info/METADATA":   pythonMetadata("numpy", "1.23.4"),
+		"opt/venv/lib/python3.11/site-packages/requests-2.28.1.dist-info/METADATA": pythonMetadata("requests", "2.28.1"),
+	})
+	layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+	img, _ := mutate.AppendLayers(empty.Image, layer)
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerPython, CatalogerConda)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"pkg:conda/openssl@3.0.7":  "/opt/conda/conda-meta/openssl-3.0.7-h0b41bf4_0.json",
+		"pkg:pypi/numpy@1.23.4":    "/opt/conda/lib/python3.10/site-packages/numpy-1.23.4.dist-info/METADATA",
+		"pkg:pypi/requests@2.28.1": "/opt/venv/lib/python3.11/site-packages/requests-2.28.1.dist-info/METADATA",
+	}
+	for _, p := range sb.Artifacts {
+		if p.Purl == "pkg:conda/numpy@1.23.4" {
+			t.Errorf("expected numpy to be reported once")
+		}
+		path, ok := expected[p.Purl]
+		if !ok {
+			continue
+		}
+		if len(p.Locations) == 0 || p.Locations[0].Path != path {
+			t.Errorf("expected %s at %s, got %v", p.Purl, path, p.Locations)
+		}
+		if p.Purl == "pkg:conda/openssl@3.0.7" && (len(p.Files) != 2 || p.Files[1].Path != "/opt/conda/lib/libssl.so.3" || len(p.Licenses) != 1) {
+			t.Errorf("unexpected conda package %+v", p)
+		}
+		delete(expected, p.Purl)
+	}
+	if len(expected) > 0 {
+		t.Errorf("expected packages %v, got %v", expected, sb.Artifacts)
+	}
+}
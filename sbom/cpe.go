@@ -0,0 +1,32 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import "github.com/docker/index-cli-plugin/types"
+
+// addCpes sets the CPE names of packages that don't have any yet, unless disabled with
+// WithCpes
+func (i *Indexer) addCpes(packages []types.Package) {
+	if !i.cpes {
+		return
+	}
+	for j, p := range packages {
+		if len(p.Cpes) == 0 {
+			packages[j].Cpes = types.ToCpes(p)
+		}
+	}
+}
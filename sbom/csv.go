@@ -0,0 +1,200 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+type packageColumn = func(sb *types.Sbom, p types.Package) string
+type cveColumn = func(sb *types.Sbom, c types.Cve) string
+
+var packageColumns = map[string]packageColumn{
+	"type":               func(_ *types.Sbom, p types.Package) string { return p.Type },
+	"namespace":          func(_ *types.Sbom, p types.Package) string { return p.Namespace },
+	"name":               func(_ *types.Sbom, p types.Package) string { return p.Name },
+	"version":            func(_ *types.Sbom, p types.Package) string { return p.Version },
+	"purl":               func(_ *types.Sbom, p types.Package) string { return p.Purl },
+	"licenses":           func(_ *types.Sbom, p types.Package) string { return strings.Join(p.Licenses, "; ") },
+	"license_expression": func(_ *types.Sbom, p types.Package) string { return p.LicenseExpression },
+	"author":             func(_ *types.Sbom, p types.Package) string { return p.Author },
+	"url":                func(_ *types.Sbom, p types.Package) string { return p.Url },
+	"cpes":               func(_ *types.Sbom, p types.Package) string { return strings.Join(p.Cpes, "; ") },
+	"locations": func(_ *types.Sbom, p types.Package) string {
+		paths := make([]string, 0, len(p.Locations))
+		for _, l := range p.Locations {
+			paths = append(paths, l.Path)
+		}
+		return strings.Join(paths, "; ")
+	},
+	"layer":      func(_ *types.Sbom, p types.Package) string { return csvLayer(p.Layer) },
+	"created_by": func(_ *types.Sbom, p types.Package) string { return csvCreatedBy(p.Layer) },
+	"base_image": func(_ *types.Sbom, p types.Package) string {
+		return strconv.FormatBool(p.Layer != nil && p.Layer.BaseImage)
+	},
+	"cves": func(sb *types.Sbom, p types.Package) string {
+		ids := make([]string, 0)
+		for _, c := range sb.Vulnerabilities {
+			if c.Purl == p.Purl && IsAffected(c) && c.Suppression == nil {
+				ids = append(ids, c.SourceId)
+			}
+		}
+		return strings.Join(ids, "; ")
+	},
+}
+
+var cveColumns = map[string]cveColumn{
+	"id":               func(_ *types.Sbom, c types.Cve) string { return c.SourceId },
+	"source":           func(_ *types.Sbom, c types.Cve) string { return c.Source },
+	"severity":         func(_ *types.Sbom, c types.Cve) string { return Severity(c) },
+	"purl":             func(_ *types.Sbom, c types.Cve) string { return c.Purl },
+	"vulnerable_range": func(_ *types.Sbom, c types.Cve) string { return c.VulnerableRange },
+	"fixed_by":         func(_ *types.Sbom, c types.Cve) string { return c.FixedBy },
+	"status":           func(_ *types.Sbom, c types.Cve) string { return c.Status },
+	"justification":    func(_ *types.Sbom, c types.Cve) string { return c.Justification },
+	"suppressed":       func(_ *types.Sbom, c types.Cve) string { return strconv.FormatBool(c.Suppression != nil) },
+	"epss": func(_ *types.Sbom, c types.Cve) string {
+		if c.Epss == nil {
+			return ""
+		}
+		return strconv.FormatFloat(c.Epss.Score, 'f', -1, 64)
+	},
+	"known_exploited": func(_ *types.Sbom, c types.Cve) string { return strconv.FormatBool(c.KnownExploited) },
+	"url": func(_ *types.Sbom, c types.Cve) string {
+		for _, adv := range []*types.Advisory{c.Cve, c.Advisory} {
+			if adv != nil && len(adv.Urls) > 0 {
+				return adv.Urls[0].Value
+			}
+		}
+		return ""
+	},
+	"description": func(_ *types.Sbom, c types.Cve) string {
+		for _, adv := range []*types.Advisory{c.Cve, c.Advisory} {
+			if adv != nil && adv.Description != "" {
+				return adv.Description
+			}
+		}
+		return ""
+	},
+	"layer": func(sb *types.Sbom, c types.Cve) string {
+		p, _ := findPackage(sb, c.Purl)
+		return csvLayer(p.Layer)
+	},
+	"created_by": func(sb *types.Sbom, c types.Cve) string {
+		p, _ := findPackage(sb, c.Purl)
+		return csvCreatedBy(p.Layer)
+	},
+}
+
+var (
+	defaultPackageColumns = []string{"type", "namespace", "name", "version", "purl", "licenses", "layer", "cves"}
+	defaultCveColumns     = []string{"id", "severity", "purl", "vulnerable_range", "fixed_by", "status", "epss", "known_exploited", "url"}
+)
+
+// ValidateCsvColumns returns an error for columns unknown to both csv formats, see
+// WithCsvColumns
+func ValidateCsvColumns(columns []string) error {
+	for _, c := range columns {
+		_, isPackage := packageColumns[c]
+		_, isCve := cveColumns[c]
+		if !isPackage && !isCve {
+			return errors.Errorf("unknown csv column: %s", c)
+		}
+	}
+	return nil
+}
+
+// WritePackagesCSV writes the package inventory of the sbom as CSV with the given columns to w;
+// columns of the cves-csv format are skipped and the default columns written if empty
+func WritePackagesCSV(sb *types.Sbom, columns []string, w io.Writer) error {
+	columns = selectedColumns(columns, func(c string) bool {
+		_, ok := packageColumns[c]
+		return ok
+	}, defaultPackageColumns)
+	rows := make([][]string, 0, len(sb.Artifacts))
+	for _, p := range sb.Artifacts {
+		row := make([]string, 0, len(columns))
+		for _, c := range columns {
+			row = append(row, packageColumns[c](sb, p))
+		}
+		rows = append(rows, row)
+	}
+	return writeCSV(w, columns, rows)
+}
+
+// WriteCvesCSV writes the vulnerabilities of the sbom as CSV with the given columns to w;
+// columns of the csv format are skipped and the default columns written if empty
+func WriteCvesCSV(sb *types.Sbom, columns []string, w io.Writer) error {
+	columns = selectedColumns(columns, func(c string) bool {
+		_, ok := cveColumns[c]
+		return ok
+	}, defaultCveColumns)
+	rows := make([][]string, 0, len(sb.Vulnerabilities))
+	for _, cve := range sb.Vulnerabilities {
+		row := make([]string, 0, len(columns))
+		for _, c := range columns {
+			row = append(row, cveColumns[c](sb, cve))
+		}
+		rows = append(rows, row)
+	}
+	return writeCSV(w, columns, rows)
+}
+
+// selectedColumns returns the columns of csvColumns that are known, or the defaults if empty
+func selectedColumns(csvColumns []string, known func(string) bool, defaults []string) []string {
+	if len(csvColumns) == 0 {
+		return defaults
+	}
+	columns := make([]string, 0, len(csvColumns))
+	for _, c := range csvColumns {
+		if known(c) {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func csvLayer(l *types.Layer) string {
+	if l == nil {
+		return ""
+	}
+	return strconv.Itoa(l.Ordinal)
+}
+
+func csvCreatedBy(l *types.Layer) string {
+	if l == nil {
+		return ""
+	}
+	return l.CreatedBy
+}
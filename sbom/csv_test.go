@@ -0,0 +1,87 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestWriteCSV(t *testing.T) {
+	cve := cveWithSeverity("CVE-2022-0001", "HIGH")
+	cve.Purl, cve.FixedBy = "pkg:deb/debian/openssl@1.1.1n", "1.1.1o"
+	cve.Epss = &types.Epss{Score: 0.25}
+	sb := &types.Sbom{
+		Artifacts: []types.Package{
+			{Type: "deb", Namespace: "debian", Name: "openssl", Version: "1.1.1n", Purl: "pkg:deb/debian/openssl@1.1.1n",
+				Licenses: []string{"Apache-2.0", "OpenSSL"}, Layer: &types.Layer{Ordinal: 2, CreatedBy: "RUN apt-get install -y \"openssl\""}},
+			{Type: "npm", Name: "lodash", Version: "4.17.21", Purl: "pkg:npm/lodash@4.17.21"},
+		},
+		Vulnerabilities: []types.Cve{cve},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFormat(sb, FormatCSV, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `type,namespace,name,version,purl,licenses,layer,cves
+deb,debian,openssl,1.1.1n,pkg:deb/debian/openssl@1.1.1n,Apache-2.0; OpenSSL,2,CVE-2022-0001
+npm,,lodash,4.17.21,pkg:npm/lodash@4.17.21,,,
+`
+	if buf.String() != expected {
+		t.Errorf("expected packages csv\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteFormat(sb, FormatCvesCSV, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected = `id,severity,purl,vulnerable_range,fixed_by,status,epss,known_exploited,url
+CVE-2022-0001,HIGH,pkg:deb/debian/openssl@1.1.1n,,1.1.1o,,0.25,false,
+`
+	if buf.String() != expected {
+		t.Errorf("expected cves csv\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	columns := WithCsvColumns("name", "id", "created_by")
+	buf.Reset()
+	_ = WriteFormat(sb, FormatCSV, &buf, columns)
+	expected = `name,created_by
+openssl,"RUN apt-get install -y ""openssl"""
+lodash,
+`
+	if buf.String() != expected {
+		t.Errorf("expected packages csv\n%s\ngot\n%s", expected, buf.String())
+	}
+	buf.Reset()
+	_ = WriteFormat(sb, FormatCvesCSV, &buf, columns)
+	expected = `id,created_by
+CVE-2022-0001,"RUN apt-get install -y ""openssl"""
+`
+	if buf.String() != expected {
+		t.Errorf("expected cves csv\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	if err := ValidateCsvColumns([]string{"name", "id"}); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateCsvColumns([]string{"unknown"}); err == nil {
+		t.Error("expected error for unknown column")
+	}
+}
@@ -0,0 +1,553 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/uuid"
+)
+
+type CdxDocument struct {
+	BomFormat       string             `json:"bomFormat"`
+	SpecVersion     string             `json:"specVersion"`
+	SerialNumber    string             `json:"serialNumber"`
+	Version         int                `json:"version"`
+	Metadata        CdxMetadata        `json:"metadata"`
+	Components      []CdxComponent     `json:"components"`
+	Dependencies    []CdxDependency    `json:"dependencies,omitempty"`
+	Vulnerabilities []CdxVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type CdxMetadata struct {
+	Timestamp  string        `json:"timestamp"`
+	Tools      CdxTools      `json:"tools"`
+	Component  CdxComponent  `json:"component"`
+	Properties []CdxProperty `json:"properties,omitempty"`
+}
+
+type CdxTools struct {
+	Components []CdxComponent `json:"components"`
+}
+
+type CdxComponent struct {
+	BomRef      string        `json:"bom-ref,omitempty"`
+	Type        string        `json:"type"`
+	Author      string        `json:"author,omitempty"`
+	Group       string        `json:"group,omitempty"`
+	Name        string        `json:"name"`
+	Version     string        `json:"version,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Licenses    []CdxLicense  `json:"licenses,omitempty"`
+	Purl        string        `json:"purl,omitempty"`
+	Cpe         string        `json:"cpe,omitempty"`
+	Hashes      []CdxHash     `json:"hashes,omitempty"`
+	Properties  []CdxProperty `json:"properties,omitempty"`
+}
+
+type CdxLicense struct {
+	License CdxLicenseChoice `json:"license"`
+}
+
+type CdxLicenseChoice struct {
+	Id   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type CdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type CdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type CdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+type CdxVulnerability struct {
+	BomRef         string        `json:"bom-ref"`
+	Id             string        `json:"id"`
+	Source         *CdxSource    `json:"source,omitempty"`
+	Ratings        []CdxRating   `json:"ratings,omitempty"`
+	Cwes           []int         `json:"cwes,omitempty"`
+	Description    string        `json:"description,omitempty"`
+	Recommendation string        `json:"recommendation,omitempty"`
+	Advisories     []CdxAdvisory `json:"advisories,omitempty"`
+	Analysis       *CdxAnalysis  `json:"analysis,omitempty"`
+	Affects        []CdxAffect   `json:"affects"`
+}
+
+type CdxSource struct {
+	Name string `json:"name"`
+	Url  string `json:"url,omitempty"`
+}
+
+type CdxRating struct {
+	Source   *CdxSource `json:"source,omitempty"`
+	Score    *float64   `json:"score,omitempty"`
+	Severity string     `json:"severity"`
+	Method   string     `json:"method,omitempty"`
+	Vector   string     `json:"vector,omitempty"`
+}
+
+type CdxAdvisory struct {
+	Title string `json:"title,omitempty"`
+	Url   string `json:"url"`
+}
+
+type CdxAnalysis struct {
+	State         string   `json:"state"`
+	Justification string   `json:"justification,omitempty"`
+	Response      []string `json:"response,omitempty"`
+	Detail        string   `json:"detail,omitempty"`
+}
+
+type CdxAffect struct {
+	Ref      string              `json:"ref"`
+	Versions []CdxAffectedStatus `json:"versions,omitempty"`
+}
+
+type CdxAffectedStatus struct {
+	Version string `json:"version,omitempty"`
+	Range   string `json:"range,omitempty"`
+	Status  string `json:"status"`
+}
+
+// WriteCycloneDX writes the sbom and its vulnerabilities as CycloneDX 1.5 JSON document to w
+func WriteCycloneDX(sb *types.Sbom, w io.Writer) error {
+	doc := ToCycloneDX(sb)
+	js, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(js, '\n'))
+	return err
+}
+
+// ToCycloneDX converts the sbom into a CycloneDX 1.5 document
+func ToCycloneDX(sb *types.Sbom) CdxDocument {
+	image := sb.Source.Image
+	imageRef := "image:" + image.Digest
+	name := image.Name
+	if name == "" {
+		name = image.Digest
+	}
+
+	doc := CdxDocument{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: CdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: CdxTools{
+				Components: []CdxComponent{{
+					Type:    "application",
+					Author:  "Docker, Inc.",
+					Name:    sb.Descriptor.Name,
+					Version: sb.Descriptor.Version,
+				}},
+			},
+			Component: CdxComponent{
+				BomRef:  imageRef,
+				Type:    "container",
+				Name:    name,
+				Version: image.Digest,
+				Properties: []CdxProperty{
+					{Name: "docker:image:platform", Value: parsePlatform(sb)},
+					{Name: "docker:image:distro", Value: strings.Trim(image.Distro.OsName+":"+image.Distro.OsVersion, ":")},
+				},
+			},
+		},
+		Components: make([]CdxComponent, 0),
+	}
+	if image.Tags != nil {
+		for _, t := range *image.Tags {
+			doc.Metadata.Component.Properties = append(doc.Metadata.Component.Properties, CdxProperty{Name: "docker:image:tag", Value: t})
+		}
+	}
+	for _, l := range image.SkippedLayers {
+		doc.Metadata.Component.Properties = append(doc.Metadata.Component.Properties, CdxProperty{Name: "docker:image:skipped_layer", Value: l.Digest})
+	}
+	for _, k := range annotationKeys(sb) {
+		doc.Metadata.Properties = append(doc.Metadata.Properties, CdxProperty{Name: "docker:annotation:" + k, Value: sb.Descriptor.Annotations[k]})
+	}
+
+	refs := make([]string, 0)
+	for _, p := range sb.Artifacts {
+		c := CdxComponent{
+			BomRef:      p.Purl,
+			Type:        "library",
+			Author:      p.Author,
+			Group:       p.Namespace,
+			Name:        p.Name,
+			Version:     p.Version,
+			Description: p.Description,
+			Licenses:    toCdxLicenses(p.Licenses),
+			Purl:        p.Purl,
+			Properties:  toCdxLocationProperties(p),
+		}
+		if len(p.Cpes) > 0 {
+			c.Cpe = p.Cpes[0]
+		}
+		if p.Parent != "" {
+			c.Properties = append(c.Properties, CdxProperty{Name: "docker:package:parent", Value: p.Parent})
+		}
+		if p.Vcs != nil {
+			c.Properties = append(c.Properties, CdxProperty{Name: "docker:package:vcs_revision", Value: p.Vcs.Type + ":" + p.Vcs.Revision})
+		}
+		if len(p.Groups) > 0 {
+			c.Properties = append(c.Properties, CdxProperty{Name: "docker:package:groups", Value: strings.Join(p.Groups, ",")})
+		}
+		if p.Confidence != 0 {
+			c.Properties = append(c.Properties, CdxProperty{Name: "docker:package:confidence", Value: strconv.FormatFloat(p.Confidence, 'f', -1, 64)})
+		}
+		doc.Components = append(doc.Components, c)
+		refs = append(refs, p.Purl)
+	}
+	doc.Dependencies = []CdxDependency{{
+		Ref:       imageRef,
+		DependsOn: refs,
+	}}
+
+	doc.Vulnerabilities = toCdxVulnerabilities(sb.Vulnerabilities)
+	return doc
+}
+
+func toCdxLicenses(licenses []string) []CdxLicense {
+	cl := make([]CdxLicense, 0)
+	for _, l := range licenses {
+		if l == "" {
+			continue
+		}
+		if id, ok := spdxLicenseIds[strings.ToLower(l)]; ok {
+			cl = append(cl, CdxLicense{License: CdxLicenseChoice{Id: id}})
+		} else {
+			cl = append(cl, CdxLicense{License: CdxLicenseChoice{Name: l}})
+		}
+	}
+	return cl
+}
+
+func toCdxLocationProperties(p types.Package) []CdxProperty {
+	props := make([]CdxProperty, 0)
+	for i, loc := range p.Locations {
+		props = append(props,
+			CdxProperty{Name: fmt.Sprintf("docker:location:%d:path", i), Value: loc.Path},
+			CdxProperty{Name: fmt.Sprintf("docker:location:%d:diff_id", i), Value: loc.DiffId},
+			CdxProperty{Name: fmt.Sprintf("docker:location:%d:digest", i), Value: loc.Digest})
+	}
+	return props
+}
+
+// toCdxVulnerabilities merges the cves by their id and analysis, a CVE whose VEX status
+// differs between the affected packages results in one vulnerability per analysis
+func toCdxVulnerabilities(cves []types.Cve) []CdxVulnerability {
+	vulns := make([]CdxVulnerability, 0)
+	index := make(map[string]int)
+	refs := make(map[string]int)
+	for _, c := range cves {
+		analysis := toCdxAnalysis(c)
+		key := fmt.Sprintf("%s %+v", c.SourceId, *analysis)
+		affect := CdxAffect{
+			Ref: c.Purl,
+			Versions: []CdxAffectedStatus{{
+				Range:  c.VulnerableRange,
+				Status: toCdxAffectedStatus(c),
+			}},
+		}
+		if i, ok := index[key]; ok {
+			if !internal.Contains(vulns[i].Affects, affect) {
+				vulns[i].Affects = append(vulns[i].Affects, affect)
+			}
+			continue
+		}
+
+		bomRef := c.SourceId
+		if n := refs[c.SourceId]; n > 0 {
+			bomRef = fmt.Sprintf("%s-%d", c.SourceId, n)
+		}
+		refs[c.SourceId]++
+		v := CdxVulnerability{
+			BomRef:   bomRef,
+			Id:       c.SourceId,
+			Source:   &CdxSource{Name: c.Source},
+			Ratings:  toCdxRatings(c),
+			Affects:  []CdxAffect{affect},
+			Analysis: analysis,
+		}
+		if IsFixed(c) {
+			v.Recommendation = fmt.Sprintf("Upgrade to %s", c.FixedBy)
+		}
+		for _, adv := range []*types.Advisory{c.Cve, c.Advisory} {
+			if adv == nil {
+				continue
+			}
+			if v.Description == "" {
+				v.Description = adv.Description
+			}
+			for _, cwe := range adv.Cwes {
+				if id, err := strconv.Atoi(strings.TrimPrefix(cwe.SourceId, "CWE-")); err == nil && !internal.Contains(v.Cwes, id) {
+					v.Cwes = append(v.Cwes, id)
+				}
+			}
+			for _, u := range adv.Urls {
+				v.Advisories = append(v.Advisories, CdxAdvisory{Title: u.Name, Url: u.Value})
+			}
+		}
+		index[key] = len(vulns)
+		vulns = append(vulns, v)
+	}
+	return vulns
+}
+
+func toCdxRatings(c types.Cve) []CdxRating {
+	severity := strings.ToLower(toSeverity(c))
+	switch severity {
+	case "critical", "high", "medium", "low":
+	default:
+		severity = "unknown"
+	}
+	return []CdxRating{{
+		Source:   &CdxSource{Name: c.Source},
+		Severity: severity,
+	}}
+}
+
+func toCdxAnalysis(c types.Cve) *CdxAnalysis {
+	analysis := CdxAnalysis{State: "in_triage"}
+	switch c.Status {
+	case VexNotAffected:
+		analysis.State = "not_affected"
+		if _, ok := cdxJustifications[c.Justification]; ok {
+			analysis.Justification = cdxJustifications[c.Justification]
+		} else {
+			analysis.Detail = c.Justification
+		}
+	case VexFixed:
+		analysis.State = "resolved"
+	case VexAffected:
+		analysis.State = "exploitable"
+	}
+	if IsFixed(c) && IsAffected(c) {
+		analysis.Response = []string{"update"}
+	}
+	return &analysis
+}
+
+func toCdxAffectedStatus(c types.Cve) string {
+	if IsAffected(c) {
+		return "affected"
+	}
+	return "unaffected"
+}
+
+// cdxJustifications maps VEX justifications onto CycloneDX analysis justifications
+var cdxJustifications = map[string]string{
+	"component_not_present":                             "code_not_present",
+	"vulnerable_code_not_present":                       "code_not_present",
+	"vulnerable_code_not_in_execute_path":               "code_not_reachable",
+	"vulnerable_code_cannot_be_controlled_by_adversary": "protected_by_mitigating_control",
+	"inline_mitigations_already_exist":                  "protected_by_mitigating_control",
+}
+
+// FromCycloneDX converts a CycloneDX document written by ToCycloneDX into a sbom including
+// the layer locations and vulnerabilities it carries.
+func FromCycloneDX(doc CdxDocument) (*types.Sbom, error) {
+	return NewIndexer().fromCycloneDX(doc)
+}
+
+func (i *Indexer) fromCycloneDX(doc CdxDocument) (*types.Sbom, error) {
+	component := doc.Metadata.Component
+	image := types.ImageSource{
+		Name:   component.Name,
+		Digest: component.Version,
+	}
+	if image.Name == image.Digest {
+		image.Name = ""
+	}
+	tags := make([]string, 0)
+	for _, p := range component.Properties {
+		switch p.Name {
+		case "docker:image:platform":
+			parts := strings.SplitN(p.Value, "/", 3)
+			image.Platform.Os = parts[0]
+			if len(parts) > 1 {
+				image.Platform.Architecture = parts[1]
+			}
+			if len(parts) > 2 {
+				image.Platform.Variant = parts[2]
+			}
+		case "docker:image:distro":
+			image.Distro.OsName, image.Distro.OsVersion, _ = strings.Cut(p.Value, ":")
+		case "docker:image:tag":
+			tags = append(tags, p.Value)
+		}
+	}
+	if len(tags) > 0 {
+		image.Tags = &tags
+	}
+
+	pkgs := make([]types.Package, 0)
+	for _, c := range doc.Components {
+		if c.Purl == "" {
+			continue
+		}
+		pkg := types.Package{
+			Purl:        c.Purl,
+			Author:      c.Author,
+			Description: c.Description,
+			Licenses:    make([]string, 0),
+			Locations:   fromCdxLocationProperties(c.Properties),
+		}
+		for _, l := range c.Licenses {
+			if l.License.Id != "" {
+				pkg.Licenses = append(pkg.Licenses, l.License.Id)
+			} else if l.License.Name != "" {
+				pkg.Licenses = append(pkg.Licenses, l.License.Name)
+			}
+		}
+		for _, p := range c.Properties {
+			if p.Name == "docker:package:parent" {
+				pkg.Parent = p.Value
+			}
+			if p.Name == "docker:package:vcs_revision" {
+				vcs, revision, _ := strings.Cut(p.Value, ":")
+				pkg.Vcs = &types.Vcs{Type: vcs, Revision: revision}
+			}
+			if p.Name == "docker:package:groups" {
+				pkg.Groups = strings.Split(p.Value, ",")
+			}
+			if p.Name == "docker:package:confidence" {
+				pkg.Confidence, _ = strconv.ParseFloat(p.Value, 64)
+			}
+		}
+		if c.Cpe != "" {
+			pkg.Cpes = []string{c.Cpe}
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	sb, err := i.convertedSbom(image, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	sb.Vulnerabilities = fromCdxVulnerabilities(doc.Vulnerabilities)
+	for _, p := range doc.Metadata.Properties {
+		if k := strings.TrimPrefix(p.Name, "docker:annotation:"); k != p.Name {
+			Annotate(sb, map[string]string{k: p.Value})
+		}
+	}
+	return sb, nil
+}
+
+func fromCdxLocationProperties(props []CdxProperty) []types.Location {
+	locations := make([]types.Location, 0)
+	for _, p := range props {
+		parts := strings.Split(p.Name, ":")
+		// syft writes locations as syft:location:<n>:path and syft:location:<n>:layerID
+		if len(parts) != 4 || (parts[0] != "docker" && parts[0] != "syft") || parts[1] != "location" {
+			continue
+		}
+		i, err := strconv.Atoi(parts[2])
+		if err != nil || i < 0 {
+			continue
+		}
+		for len(locations) <= i {
+			locations = append(locations, types.Location{})
+		}
+		switch parts[3] {
+		case "path":
+			locations[i].Path = p.Value
+		case "diff_id", "layerID":
+			locations[i].DiffId = p.Value
+		case "digest":
+			locations[i].Digest = p.Value
+		}
+	}
+	return locations
+}
+
+func fromCdxVulnerabilities(vulns []CdxVulnerability) []types.Cve {
+	cves := make([]types.Cve, 0)
+	for _, v := range vulns {
+		advisory := types.Advisory{
+			SourceId:    v.Id,
+			Description: v.Description,
+			References:  make([]types.Reference, 0),
+		}
+		if v.Source != nil {
+			advisory.Source = v.Source.Name
+		}
+		for _, r := range v.Ratings {
+			if r.Severity != "" && r.Severity != "unknown" {
+				advisory.References = append(advisory.References, types.Reference{
+					Source: "atomist",
+					Scores: []types.Score{{Type: "atm_severity", Value: strings.ToUpper(r.Severity)}},
+				})
+				break
+			}
+		}
+		for _, cwe := range v.Cwes {
+			advisory.Cwes = append(advisory.Cwes, types.Cwe{SourceId: fmt.Sprintf("CWE-%d", cwe)})
+		}
+		for _, a := range v.Advisories {
+			advisory.Urls = append(advisory.Urls, types.Url{Name: a.Title, Value: a.Url})
+		}
+
+		for _, a := range v.Affects {
+			c := types.Cve{
+				Purl:     a.Ref,
+				Source:   advisory.Source,
+				SourceId: v.Id,
+				FixedBy:  strings.TrimPrefix(v.Recommendation, "Upgrade to "),
+				Cve:      &advisory,
+			}
+			if len(a.Versions) > 0 {
+				c.VulnerableRange = a.Versions[0].Range
+			}
+			if v.Analysis != nil {
+				switch v.Analysis.State {
+				case "not_affected":
+					c.Status = VexNotAffected
+					c.Justification = v.Analysis.Detail
+					if c.Justification == "" {
+						c.Justification = v.Analysis.Justification
+					}
+				case "resolved":
+					c.Status = VexFixed
+				case "exploitable":
+					c.Status = VexAffected
+				}
+			}
+			cves = append(cves, c)
+		}
+	}
+	return cves
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestToCdxAnalysis(t *testing.T) {
+	tests := []struct {
+		cve           types.Cve
+		state         string
+		justification string
+		detail        string
+		response      bool
+		status        string
+	}{
+		{cve: types.Cve{}, state: "in_triage", status: "affected"},
+		{cve: types.Cve{FixedBy: "1.2.3"}, state: "in_triage", response: true, status: "affected"},
+		{cve: types.Cve{FixedBy: "not fixed"}, state: "in_triage", status: "affected"},
+		{cve: types.Cve{Status: VexAffected, FixedBy: "1.2.3"}, state: "exploitable", response: true, status: "affected"},
+		{cve: types.Cve{Status: VexFixed, FixedBy: "1.2.3"}, state: "resolved", status: "unaffected"},
+		{cve: types.Cve{Status: VexNotAffected, Justification: "vulnerable_code_not_in_execute_path"}, state: "not_affected", justification: "code_not_reachable", status: "unaffected"},
+		{cve: types.Cve{Status: VexNotAffected, Justification: "only used in tests"}, state: "not_affected", detail: "only used in tests", status: "unaffected"},
+	}
+	for _, test := range tests {
+		a := toCdxAnalysis(test.cve)
+		if a.State != test.state || a.Justification != test.justification || a.Detail != test.detail || (len(a.Response) > 0) != test.response {
+			t.Errorf("%+v: expected state %s, justification %q, detail %q and response %v, got %+v", test.cve, test.state, test.justification, test.detail, test.response, a)
+		}
+		if s := toCdxAffectedStatus(test.cve); s != test.status {
+			t.Errorf("%+v: expected affected status %s, got %s", test.cve, test.status, s)
+		}
+	}
+}
+
+func TestToCdxVulnerabilities(t *testing.T) {
+	advisory := &types.Advisory{
+		Description: "heap overflow",
+		Cwes:        []types.Cwe{{SourceId: "CWE-122"}, {SourceId: "CWE-122"}, {SourceId: "NVD-CWE-Other"}},
+		Urls:        []types.Url{{Name: "NVD", Value: "https://nvd.nist.gov/vuln/detail/CVE-2023-0001"}},
+	}
+	cves := []types.Cve{
+		{Purl: "pkg:deb/debian/openssl@1.1.1", Source: "nist", SourceId: "CVE-2023-0001", VulnerableRange: "< 1.1.2", FixedBy: "1.1.2", Cve: advisory},
+		{Purl: "pkg:deb/debian/libssl@1.1.1", Source: "nist", SourceId: "CVE-2023-0001", VulnerableRange: "< 1.1.2", FixedBy: "1.1.2", Cve: advisory},
+		{Purl: "pkg:deb/debian/libssl@1.1.1", Source: "nist", SourceId: "CVE-2023-0001", VulnerableRange: "< 1.1.2", FixedBy: "1.1.2", Cve: advisory},
+		{Purl: "pkg:deb/debian/zlib@1.2.11", Source: "debian", SourceId: "CVE-2022-0002", VulnerableRange: "< 1.2.12", FixedBy: "not fixed"},
+	}
+	vulns := toCdxVulnerabilities(cves)
+	if len(vulns) != 2 {
+		t.Fatalf("expected 2 vulnerabilities, got %d", len(vulns))
+	}
+	tests := []struct {
+		id             string
+		affects        int
+		cwes           []int
+		recommendation string
+		description    string
+		advisories     int
+	}{
+		{id: "CVE-2023-0001", affects: 2, cwes: []int{122}, recommendation: "Upgrade to 1.1.2", description: "heap overflow", advisories: 1},
+		{id: "CVE-2022-0002", affects: 1},
+	}
+	for i, test := range tests {
+		v := vulns[i]
+		if v.Id != test.id || v.BomRef != test.id {
+			t.Errorf("expected vulnerability %s at %d, got %s", test.id, i, v.Id)
+		}
+		if len(v.Affects) != test.affects {
+			t.Errorf("%s: expected %d affected packages, got %v", test.id, test.affects, v.Affects)
+		}
+		if len(v.Cwes) != len(test.cwes) || (len(v.Cwes) > 0 && v.Cwes[0] != test.cwes[0]) {
+			t.Errorf("%s: expected cwes %v, got %v", test.id, test.cwes, v.Cwes)
+		}
+		if v.Recommendation != test.recommendation || v.Description != test.description || len(v.Advisories) != test.advisories {
+			t.Errorf("%s: unexpected recommendation, description or advisories: %+v", test.id, v)
+		}
+		if len(v.Ratings) != 1 || v.Ratings[0].Severity != "unknown" {
+			t.Errorf("%s: expected unknown severity rating, got %+v", test.id, v.Ratings)
+		}
+	}
+}
+
+func TestCycloneDXRoundTrip(t *testing.T) {
+	tags := []string{"1.0"}
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{
+			Name:     "example/app",
+			Digest:   "sha256:abc",
+			Tags:     &tags,
+			Platform: types.Platform{Os: "linux", Architecture: "arm64", Variant: "v8"},
+			Distro:   types.Distro{OsName: "debian", OsVersion: "12"},
+		}},
+		Artifacts: []types.Package{{
+			Type:      "deb",
+			Namespace: "debian",
+			Name:      "openssl",
+			Version:   "3.0.11",
+			Purl:      "pkg:deb/debian/openssl@3.0.11",
+			Licenses:  []string{"Apache-2.0", "custom license"},
+			Locations: []types.Location{{Path: "/var/lib/dpkg/status", DiffId: "sha256:d", Digest: "sha256:l"}},
+		}},
+	}
+	doc := ToCycloneDX(sb)
+	if doc.BomFormat != "CycloneDX" || doc.SpecVersion != "1.5" || len(doc.Dependencies) != 1 || len(doc.Dependencies[0].DependsOn) != 1 {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+	if l := doc.Components[0].Licenses; len(l) != 2 || l[0].License.Id != "Apache-2.0" || l[1].License.Name != "custom license" {
+		t.Errorf("expected spdx id and license name, got %+v", l)
+	}
+
+	converted, err := FromCycloneDX(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := converted.Source.Image
+	if image.Name != "example/app" || image.Digest != "sha256:abc" || image.Tags == nil || (*image.Tags)[0] != "1.0" {
+		t.Errorf("expected image to survive conversion, got %+v", image)
+	}
+	if image.Platform != sb.Source.Image.Platform || image.Distro.OsName != "debian" || image.Distro.OsVersion != "12" {
+		t.Errorf("expected platform and distro to survive conversion, got %+v %+v", image.Platform, image.Distro)
+	}
+	if len(converted.Artifacts) != 1 || !strings.HasPrefix(converted.Artifacts[0].Purl, "pkg:deb/debian/openssl@3.0.11") || len(converted.Artifacts[0].Locations) != 1 || converted.Artifacts[0].Locations[0].DiffId != "sha256:d" {
+		t.Errorf("expected package with its location, got %+v", converted.Artifacts)
+	}
+}
+
+func TestToCdxVulnerabilitiesAnalysis(t *testing.T) {
+	cves := []types.Cve{
+		{Purl: "pkg:deb/debian/openssl@1.1.1", Source: "nist", SourceId: "CVE-2023-0001", Status: VexAffected},
+		{Purl: "pkg:deb/debian/libssl@1.1.1", Source: "nist", SourceId: "CVE-2023-0001", Status: VexNotAffected, Justification: "vulnerable_code_not_present"},
+		{Purl: "pkg:deb/debian/libcrypto@1.1.1", Source: "nist", SourceId: "CVE-2023-0001", Status: VexAffected},
+	}
+	vulns := toCdxVulnerabilities(cves)
+	if len(vulns) != 2 {
+		t.Fatalf("expected 2 vulnerabilities, got %d", len(vulns))
+	}
+	tests := []struct {
+		bomRef  string
+		state   string
+		affects []string
+	}{
+		{bomRef: "CVE-2023-0001", state: "exploitable", affects: []string{"pkg:deb/debian/openssl@1.1.1", "pkg:deb/debian/libcrypto@1.1.1"}},
+		{bomRef: "CVE-2023-0001-1", state: "not_affected", affects: []string{"pkg:deb/debian/libssl@1.1.1"}},
+	}
+	for i, test := range tests {
+		v := vulns[i]
+		if v.Id != "CVE-2023-0001" || v.BomRef != test.bomRef || v.Analysis.State != test.state {
+			t.Errorf("expected %s with state %s at %d, got %s with %+v", test.bomRef, test.state, i, v.BomRef, v.Analysis)
+		}
+		if len(v.Affects) != len(test.affects) {
+			t.Fatalf("%s: expected %d affected packages, got %v", test.bomRef, len(test.affects), v.Affects)
+		}
+		for j, ref := range test.affects {
+			if v.Affects[j].Ref != ref {
+				t.Errorf("%s: expected %s affected, got %s", test.bomRef, ref, v.Affects[j].Ref)
+			}
+		}
+	}
+
+	statuses := make(map[string]string)
+	for _, c := range fromCdxVulnerabilities(vulns) {
+		statuses[c.Purl] = c.Status
+	}
+	if statuses["pkg:deb/debian/libssl@1.1.1"] != VexNotAffected || statuses["pkg:deb/debian/openssl@1.1.1"] != VexAffected {
+		t.Errorf("expected statuses to round trip, got %v", statuses)
+	}
+}
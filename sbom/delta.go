@@ -0,0 +1,60 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// AnnotateDelta records in sb the vulnerabilities that are new and resolved since the scan of
+// previous, the sbom of an earlier scan of the same repository
+func AnnotateDelta(sb *types.Sbom, previous *types.Sbom) {
+	sb.Delta = &types.Delta{
+		PreviousImage:  previous.Source.Image.Name,
+		PreviousDigest: previous.Source.Image.Digest,
+		New:            cvesWithoutIds(sb.Vulnerabilities, previous.Vulnerabilities),
+		Resolved:       cvesWithoutIds(previous.Vulnerabilities, sb.Vulnerabilities),
+	}
+}
+
+// IsNew returns true if the vulnerability was not detected by the previous scan of sb
+func IsNew(sb *types.Sbom, cve types.Cve) bool {
+	if sb.Delta == nil {
+		return false
+	}
+	for _, c := range sb.Delta.New {
+		if c.SourceId == cve.SourceId {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterNewCves returns the vulnerabilities of sb that are new since its previous scan; all of
+// them if there was no previous scan
+func FilterNewCves(sb *types.Sbom) []types.Cve {
+	if sb.Delta == nil {
+		return sb.Vulnerabilities
+	}
+	filtered := make([]types.Cve, 0)
+	for _, c := range sb.Vulnerabilities {
+		if IsNew(sb, c) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
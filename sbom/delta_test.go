@@ -0,0 +1,60 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestAnnotateDelta(t *testing.T) {
+	previous := &types.Sbom{
+		Source:          types.Source{Image: types.ImageSource{Name: "alpine:3.16", Digest: "sha256:1111"}},
+		Vulnerabilities: []types.Cve{cveWithSeverity("CVE-2022-0001", "HIGH"), cveWithSeverity("CVE-2022-0002", "LOW")},
+	}
+	sb := &types.Sbom{
+		Source:          types.Source{Image: types.ImageSource{Name: "alpine:3.16", Digest: "sha256:2222"}},
+		Vulnerabilities: []types.Cve{cveWithSeverity("CVE-2022-0001", "HIGH"), cveWithSeverity("CVE-2022-0003", "CRITICAL")},
+	}
+	if cves := FilterNewCves(sb); len(cves) != 2 {
+		t.Errorf("expected all CVEs without previous scan, got %d", len(cves))
+	}
+
+	AnnotateDelta(sb, previous)
+	if sb.Delta.PreviousDigest != "sha256:1111" || len(sb.Delta.New) != 1 || sb.Delta.New[0].SourceId != "CVE-2022-0003" {
+		t.Errorf("unexpected new CVEs %+v", sb.Delta.New)
+	}
+	if len(sb.Delta.Resolved) != 1 || sb.Delta.Resolved[0].SourceId != "CVE-2022-0002" {
+		t.Errorf("unexpected resolved CVEs %+v", sb.Delta.Resolved)
+	}
+	if cves := FilterNewCves(sb); len(cves) != 1 || cves[0].SourceId != "CVE-2022-0003" {
+		t.Errorf("unexpected new CVEs %+v", cves)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(sb, &buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"1 new and 1 resolved vulnerabilities since `alpine:3.16@sha256:1111`", "| CVE-2022-0003 | critical |", "| CVE-2022-0002 | low |"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected markdown to contain %q:\n%s", expected, buf.String())
+		}
+	}
+}
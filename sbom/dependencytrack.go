@@ -0,0 +1,95 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// UploadDependencyTrack is the --upload target of Dependency-Track
+const UploadDependencyTrack = "dependency-track"
+
+// DependencyTrack is the server and project sboms are uploaded to
+type DependencyTrack struct {
+	Url    string
+	ApiKey string
+	// Project is the name of the project, the image name if empty
+	Project string
+}
+
+type dependencyTrackBom struct {
+	ProjectName    string `json:"projectName"`
+	ProjectVersion string `json:"projectVersion"`
+	AutoCreate     bool   `json:"autoCreate"`
+	Bom            string `json:"bom"`
+}
+
+// Upload converts the sbom to CycloneDX and uploads it to the project named after the image,
+// with the image digest as version. The project is created on the first upload and reused
+// afterwards. The returned token identifies the processing of the upload on the server.
+func (dt DependencyTrack) Upload(ctx context.Context, sb *types.Sbom) (string, error) {
+	if dt.Url == "" || dt.ApiKey == "" {
+		return "", errors.New("uploading to Dependency-Track requires --dt-url and --dt-api-key")
+	}
+	var cdx bytes.Buffer
+	if err := WriteCycloneDX(sb, &cdx); err != nil {
+		return "", err
+	}
+	project := dt.Project
+	if project == "" {
+		project = sb.Source.Image.Name
+	}
+	js, err := json.Marshal(dependencyTrackBom{
+		ProjectName:    project,
+		ProjectVersion: sb.Source.Image.Digest,
+		AutoCreate:     true,
+		Bom:            base64.StdEncoding.EncodeToString(cdx.Bytes()),
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(dt.Url, "/")+"/api/v1/bom", bytes.NewReader(js))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", dt.ApiKey)
+	resp, err := internal.HttpClient().Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to upload sbom to Dependency-Track")
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("failed to upload sbom to Dependency-Track: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var result struct {
+		Token string `json:"token"`
+	}
+	_ = json.Unmarshal(body, &result)
+	return result.Token, nil
+}
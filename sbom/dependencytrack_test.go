@@ -0,0 +1,65 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestUploadDependencyTrack(t *testing.T) {
+	var uploaded dependencyTrackBom
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/api/v1/bom" || r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&uploaded)
+		_, _ = w.Write([]byte(`{"token":"abc"}`))
+	}))
+	defer ts.Close()
+
+	sb := &types.Sbom{
+		Source:    types.Source{Image: types.ImageSource{Name: "registry.example.com/app", Digest: "sha256:image"}},
+		Artifacts: []types.Package{{Type: "npm", Name: "lodash", Version: "4.17.21", Purl: "pkg:npm/lodash@4.17.21"}},
+	}
+	token, err := DependencyTrack{Url: ts.URL + "/", ApiKey: "key"}.Upload(context.Background(), sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "abc" || uploaded.ProjectName != "registry.example.com/app" || uploaded.ProjectVersion != "sha256:image" || !uploaded.AutoCreate {
+		t.Errorf("unexpected upload %s %+v", token, uploaded)
+	}
+	bom, _ := base64.StdEncoding.DecodeString(uploaded.Bom)
+	var doc CdxDocument
+	if err := json.Unmarshal(bom, &doc); err != nil || doc.BomFormat != "CycloneDX" {
+		t.Errorf("expected CycloneDX bom, got %s", bom)
+	}
+
+	if _, err := (DependencyTrack{Url: ts.URL, ApiKey: "wrong", Project: "app"}).Upload(context.Background(), sb); err == nil {
+		t.Error("expected error for rejected api key")
+	}
+	if _, err := (DependencyTrack{Url: ts.URL}).Upload(context.Background(), sb); err == nil {
+		t.Error("expected error without api key")
+	}
+}
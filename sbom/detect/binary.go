@@ -0,0 +1,338 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package detect
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// BinaryCataloger is the name of the syft cataloger group entry that enables BinaryPackages
+const BinaryCataloger = "binary-fingerprint-cataloger"
+
+const (
+	// metadataConfidence is the confidence of packages read from metadata embedded in binaries
+	metadataConfidence = 1.0
+	// signatureConfidence is the confidence of packages matched by version strings
+	signatureConfidence = 0.7
+
+	maxBinarySize = 512 * 1024 * 1024
+)
+
+var executableMIMETypes = []string{
+	"application/x-executable",
+	"application/x-mach-binary",
+	"application/x-elf",
+	"application/x-sharedlib",
+	"application/vnd.microsoft.portable-executable",
+}
+
+// binarySignature matches the version string a statically linked library compiles into binaries
+type binarySignature struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var binarySignatures = []binarySignature{
+	{name: "openssl", pattern: regexp.MustCompile(`OpenSSL (\d+\.\d+\.\d+[a-z]?) +\d{1,2} [A-Z][a-z]{2} \d{4}`)},
+	{name: "zlib", pattern: regexp.MustCompile(`(?:de|in)flate (\d+\.\d+(?:\.\d+)*) Copyright 1995-`)},
+	{name: "curl", pattern: regexp.MustCompile(`libcurl/(\d+\.\d+\.\d+)`)},
+}
+
+// BinaryPackages fingerprints the executables of the image: Rust binaries built with
+// cargo-auditable, .NET single-file deployments and statically linked C libraries
+func BinaryPackages(packages []types.Package, image source.Source, lm types.LayerMapping) []types.Package {
+	res, err := image.FileResolver(source.SquashedScope)
+	if err != nil {
+		return []types.Package{}
+	}
+	locations, err := res.FilesByMIMEType(executableMIMETypes...)
+	if err != nil {
+		log.Debugf("Failed to find binaries: %s", err)
+		return []types.Package{}
+	}
+
+	// files of OS packages are covered by their package already
+	owned := make(map[string]bool)
+	for _, p := range packages {
+		for _, f := range p.Files {
+			owned[f.Path] = true
+		}
+	}
+
+	binaryPackages := make([]types.Package, 0)
+	for _, loc := range locations {
+		b, err := readBinary(res, loc)
+		if err != nil {
+			log.Debugf("Failed to read binary %s: %s", loc.RealPath, err)
+			continue
+		}
+		pkgs := append(rustPackages(b), dotnetPackages(b)...)
+		if !owned[loc.RealPath] {
+			pkgs = append(pkgs, signaturePackages(b)...)
+		}
+		for _, pkg := range pkgs {
+			pkg.Locations = []types.Location{{
+				Path:   loc.RealPath,
+				DiffId: loc.FileSystemID,
+				Digest: lm.ByDiffId[loc.FileSystemID],
+			}}
+			binaryPackages = append(binaryPackages, pkg)
+		}
+	}
+	return binaryPackages
+}
+
+func readBinary(res source.FileResolver, loc source.Location) ([]byte, error) {
+	r, err := res.FileContentsByLocation(loc)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close() //nolint:errcheck
+	b, err := io.ReadAll(io.LimitReader(r, maxBinarySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxBinarySize {
+		return nil, errors.New("binary too large")
+	}
+	return b, nil
+}
+
+func binaryPackage(purl packageurl.PackageURL, confidence float64) types.Package {
+	return types.Package{
+		Type:       purl.Type,
+		Name:       purl.Name,
+		Version:    purl.Version,
+		Purl:       purl.ToString(),
+		Confidence: confidence,
+	}
+}
+
+// auditableDependencies is the dependency tree cargo-auditable embeds into Rust binaries
+type auditableDependencies struct {
+	Packages []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Source  string `json:"source"`
+		Kind    string `json:"kind"`
+	} `json:"packages"`
+}
+
+// rustPackages returns the crates listed in the zlib compressed .dep-v0 section of the binary
+func rustPackages(b []byte) []types.Package {
+	section := auditableSection(b)
+	if section == nil {
+		return nil
+	}
+	r, err := zlib.NewReader(bytes.NewReader(section))
+	if err != nil {
+		log.Debugf("Failed to decompress cargo-auditable metadata: %s", err)
+		return nil
+	}
+	defer r.Close() //nolint:errcheck
+	var deps auditableDependencies
+	if err := json.NewDecoder(r).Decode(&deps); err != nil {
+		log.Debugf("Failed to parse cargo-auditable metadata: %s", err)
+		return nil
+	}
+	pkgs := make([]types.Package, 0)
+	for _, p := range deps.Packages {
+		// build dependencies don't end up in the binary
+		if p.Kind == "build" {
+			continue
+		}
+		purl := packageurl.NewPackageURL("cargo", "", p.Name, p.Version, nil, "")
+		pkgs = append(pkgs, binaryPackage(*purl, metadataConfidence))
+	}
+	return pkgs
+}
+
+func auditableSection(b []byte) []byte {
+	var data []byte
+	if f, err := elf.NewFile(bytes.NewReader(b)); err == nil {
+		if s := f.Section(".dep-v0"); s != nil {
+			data, _ = s.Data()
+		}
+	} else if f, err := pe.NewFile(bytes.NewReader(b)); err == nil {
+		if s := f.Section(".dep-v0"); s != nil {
+			data, _ = s.Data()
+		}
+	} else if f, err := macho.NewFile(bytes.NewReader(b)); err == nil {
+		if s := f.Section(".dep-v0"); s != nil {
+			data, _ = s.Data()
+		}
+	}
+	return data
+}
+
+// bundleSignature marks the bundle header offset in the apphost of .NET single-file deployments
+var bundleSignature = []byte{
+	0x8b, 0x12, 0x02, 0xb9, 0x6a, 0x61, 0x20, 0x38, 0x72, 0x7b, 0x93, 0x02, 0x14, 0xd7, 0xa0, 0x32,
+	0x13, 0xf5, 0xb9, 0xe6, 0xef, 0xae, 0x33, 0x18, 0x3d, 0x44, 0x14, 0x61, 0x57, 0x46, 0x1f, 0x2d,
+}
+
+const bundleDepsJson = 3
+
+// dotnetPackages returns the NuGet packages listed in the deps.json bundled into a .NET
+// single-file deployment
+func dotnetPackages(b []byte) []types.Package {
+	i := bytes.Index(b, bundleSignature)
+	if i < 8 {
+		return nil
+	}
+	deps, err := bundledDepsJson(b, int64(binary.LittleEndian.Uint64(b[i-8:i])))
+	if err != nil {
+		log.Debugf("Failed to read .NET bundle: %s", err)
+		return nil
+	}
+	if deps == nil {
+		return nil
+	}
+	var depsJson struct {
+		Libraries map[string]struct {
+			Type string `json:"type"`
+		} `json:"libraries"`
+	}
+	if err := json.Unmarshal(deps, &depsJson); err != nil {
+		log.Debugf("Failed to parse bundled deps.json: %s", err)
+		return nil
+	}
+	pkgs := make([]types.Package, 0)
+	for lib, l := range depsJson.Libraries {
+		// project libraries are the application itself
+		name, version, ok := strings.Cut(lib, "/")
+		if l.Type != "package" || !ok {
+			continue
+		}
+		purl := packageurl.NewPackageURL("nuget", "", name, version, nil, "")
+		pkgs = append(pkgs, binaryPackage(*purl, metadataConfidence))
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Purl < pkgs[j].Purl
+	})
+	return pkgs
+}
+
+// bundledDepsJson reads the manifest of the bundle at offset and returns its deps.json, or nil
+// if the apphost isn't bundled
+func bundledDepsJson(b []byte, offset int64) ([]byte, error) {
+	if offset <= 0 || offset >= int64(len(b)) {
+		return nil, nil
+	}
+	r := bytes.NewReader(b[offset:])
+	var header struct {
+		Major     uint32
+		Minor     uint32
+		FileCount int32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, errors.Wrap(err, "failed to read bundle header")
+	}
+	if _, err := readBundleString(r); err != nil {
+		return nil, err
+	}
+	if header.Major >= 2 {
+		// offsets and sizes of deps.json and runtimeconfig.json, and the bundle flags
+		if _, err := r.Seek(5*8, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	for n := int32(0); n < header.FileCount; n++ {
+		var entry struct {
+			Offset         int64
+			Size           int64
+			CompressedSize int64
+		}
+		if err := binary.Read(r, binary.LittleEndian, &entry.Offset); err != nil {
+			return nil, errors.Wrap(err, "failed to read bundle entry")
+		}
+		if err := binary.Read(r, binary.LittleEndian, &entry.Size); err != nil {
+			return nil, errors.Wrap(err, "failed to read bundle entry")
+		}
+		// bundles of .NET 6 and later may compress their files
+		if header.Major >= 6 {
+			if err := binary.Read(r, binary.LittleEndian, &entry.CompressedSize); err != nil {
+				return nil, errors.Wrap(err, "failed to read bundle entry")
+			}
+		}
+		fileType, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read bundle entry")
+		}
+		if _, err := readBundleString(r); err != nil {
+			return nil, err
+		}
+		if fileType != bundleDepsJson {
+			continue
+		}
+		size := entry.Size
+		if entry.CompressedSize > 0 {
+			size = entry.CompressedSize
+		}
+		if entry.Offset < 0 || size < 0 || entry.Offset+size > int64(len(b)) {
+			return nil, errors.New("bundle entry out of bounds")
+		}
+		data := b[entry.Offset : entry.Offset+size]
+		if entry.CompressedSize > 0 {
+			return io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), entry.Size))
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// readBundleString reads a string prefixed with its 7-bit encoded length
+func readBundleString(r *bytes.Reader) (string, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil || l > uint64(r.Len()) {
+		return "", errors.New("failed to read bundle string")
+	}
+	s := make([]byte, l)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", errors.Wrap(err, "failed to read bundle string")
+	}
+	return string(s), nil
+}
+
+// signaturePackages returns the libraries whose version strings are found in the binary
+func signaturePackages(b []byte) []types.Package {
+	pkgs := make([]types.Package, 0)
+	for _, s := range binarySignatures {
+		if m := s.pattern.FindSubmatch(b); m != nil {
+			purl := packageurl.NewPackageURL("generic", "", s.name, string(m[1]), nil, "")
+			pkgs = append(pkgs, binaryPackage(*purl, signatureConfidence))
+		}
+	}
+	return pkgs
+}
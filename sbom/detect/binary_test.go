@@ -0,0 +1,192 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package detect
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// elfWithSection returns a minimal ELF file with a single section of the given name and data
+func elfWithSection(t *testing.T, name string, data []byte) []byte {
+	names := append([]byte{0}, []byte(name+"\x00.shstrtab\x00")...)
+	dataOffset := uint64(64)
+	namesOffset := dataOffset + uint64(len(data))
+	sectionsOffset := namesOffset + uint64(len(names))
+
+	var ident [elf.EI_NIDENT]byte
+	copy(ident[:], elf.ELFMAG)
+	ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	header := elf.Header64{
+		Ident:     ident,
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Shoff:     sectionsOffset,
+		Ehsize:    64,
+		Shentsize: 64,
+		Shnum:     3,
+		Shstrndx:  2,
+	}
+	sections := []elf.Section64{
+		{},
+		{Name: 1, Type: uint32(elf.SHT_PROGBITS), Off: dataOffset, Size: uint64(len(data)), Addralign: 1},
+		{Name: uint32(len(name) + 2), Type: uint32(elf.SHT_STRTAB), Off: namesOffset, Size: uint64(len(names)), Addralign: 1},
+	}
+
+	var b bytes.Buffer
+	for _, v := range []interface{}{header, data, names, sections} {
+		if err := binary.Write(&b, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b.Bytes()
+}
+
+func purls(pkgs []types.Package) []string {
+	p := make([]string, 0)
+	for _, pkg := range pkgs {
+		p = append(p, pkg.Purl)
+	}
+	return p
+}
+
+func TestRustPackages(t *testing.T) {
+	var deps bytes.Buffer
+	w := zlib.NewWriter(&deps)
+	_, _ = w.Write([]byte(`{"packages":[
+		{"name":"app","version":"0.1.0","source":"local","dependencies":[1],"root":true},
+		{"name":"serde","version":"1.0.147","source":"crates.io"},
+		{"name":"cc","version":"1.0.73","source":"crates.io","kind":"build"}
+	]}`))
+	_ = w.Close()
+
+	pkgs := rustPackages(elfWithSection(t, ".dep-v0", deps.Bytes()))
+	expected := []string{"pkg:cargo/app@0.1.0", "pkg:cargo/serde@1.0.147"}
+	if p := purls(pkgs); len(p) != len(expected) || p[0] != expected[0] || p[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, p)
+	}
+	if pkgs[1].Confidence != metadataConfidence {
+		t.Errorf("unexpected confidence %f", pkgs[1].Confidence)
+	}
+
+	if pkgs := rustPackages(elfWithSection(t, ".text", []byte{0x90})); len(pkgs) != 0 {
+		t.Errorf("expected no packages without cargo-auditable metadata, got %v", purls(pkgs))
+	}
+}
+
+// dotnetBundle returns an apphost with a bundle of the given major version holding deps
+func dotnetBundle(t *testing.T, major uint32, deps []byte, compress bool) []byte {
+	var b bytes.Buffer
+	write := func(v interface{}) {
+		if err := binary.Write(&b, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeString := func(s string) {
+		b.WriteByte(byte(len(s)))
+		b.WriteString(s)
+	}
+
+	b.WriteString("\x7fELF apphost")
+	size := int64(len(deps))
+	if compress {
+		var c bytes.Buffer
+		w, _ := flate.NewWriter(&c, flate.BestCompression)
+		_, _ = w.Write(deps)
+		_ = w.Close()
+		deps = c.Bytes()
+	}
+	depsOffset := int64(b.Len())
+	b.Write(deps)
+
+	headerOffset := int64(b.Len())
+	write(major)
+	write(uint32(0))
+	write(int32(2))
+	writeString("bundle-id")
+	write([5]int64{depsOffset, size})
+
+	write([2]int64{0, 10})
+	if major >= 6 {
+		write(int64(0))
+	}
+	b.WriteByte(1)
+	writeString("app.dll")
+
+	write([2]int64{depsOffset, size})
+	if major >= 6 {
+		if compress {
+			write(int64(len(deps)))
+		} else {
+			write(int64(0))
+		}
+	}
+	b.WriteByte(bundleDepsJson)
+	writeString("app.deps.json")
+
+	write(headerOffset)
+	b.Write(bundleSignature)
+	return b.Bytes()
+}
+
+func TestDotnetPackages(t *testing.T) {
+	deps := []byte(`{"libraries":{
+		"app/1.0.0":{"type":"project"},
+		"Newtonsoft.Json/13.0.1":{"type":"package"},
+		"Serilog/2.12.0":{"type":"package"}
+	}}`)
+	expected := []string{"pkg:nuget/Newtonsoft.Json@13.0.1", "pkg:nuget/Serilog@2.12.0"}
+	for _, bundle := range [][]byte{dotnetBundle(t, 2, deps, false), dotnetBundle(t, 6, deps, false), dotnetBundle(t, 6, deps, true)} {
+		pkgs := dotnetPackages(bundle)
+		if p := purls(pkgs); len(p) != len(expected) || p[0] != expected[0] || p[1] != expected[1] {
+			t.Errorf("expected %v, got %v", expected, p)
+		}
+	}
+
+	// apphosts of framework-dependent deployments carry the signature without a bundle
+	var b bytes.Buffer
+	b.Write(make([]byte, 16))
+	b.Write(bundleSignature)
+	if pkgs := dotnetPackages(b.Bytes()); len(pkgs) != 0 {
+		t.Errorf("expected no packages without bundle, got %v", purls(pkgs))
+	}
+}
+
+func TestSignaturePackages(t *testing.T) {
+	b := []byte("\x00OpenSSL 1.1.1k  25 Mar 2021\x00 deflate 1.2.11 Copyright 1995-2017 Jean-loup Gailly and Mark Adler \x00libcurl/7.79.1\x00")
+	pkgs := signaturePackages(b)
+	expected := []string{"pkg:generic/openssl@1.1.1k", "pkg:generic/zlib@1.2.11", "pkg:generic/curl@7.79.1"}
+	if p := purls(pkgs); len(p) != len(expected) || p[0] != expected[0] || p[1] != expected[1] || p[2] != expected[2] {
+		t.Errorf("expected %v, got %v", expected, p)
+	}
+	if pkgs[0].Confidence != signatureConfidence {
+		t.Errorf("unexpected confidence %f", pkgs[0].Confidence)
+	}
+
+	if pkgs := signaturePackages([]byte("OpenSSL 3.0.2 15 Mar 2022")); len(pkgs) != 1 || pkgs[0].Version != "3.0.2" {
+		t.Errorf("expected openssl 3.0.2, got %v", purls(pkgs))
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package detect
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// BitnamiCataloger is the name of the syft cataloger group entry that enables BitnamiPackages
+const BitnamiCataloger = "bitnami-cataloger"
+
+// bitnamiComponents is /opt/bitnami/.bitnami_components.json of older Bitnami images
+type bitnamiComponents map[string]struct {
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+	Distro  string `json:"distro"`
+}
+
+// bitnamiSpdx is the SPDX document Bitnami ships with every component, e.g.
+// /opt/bitnami/postgresql/.spdx-postgresql.spdx
+type bitnamiSpdx struct {
+	Packages []struct {
+		Name             string `json:"name"`
+		VersionInfo      string `json:"versionInfo"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		Homepage         string `json:"homepage"`
+		ExternalRefs     []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+// BitnamiPackages returns the components Bitnami installs below /opt/bitnami, like the
+// PostgreSQL server of bitnami/postgresql, which aren't managed by the OS package manager
+func BitnamiPackages(_ []types.Package, image source.Source, lm types.LayerMapping) []types.Package {
+	res, err := image.FileResolver(source.SquashedScope)
+	if err != nil {
+		return []types.Package{}
+	}
+
+	packages := make(map[string]types.Package)
+	add := func(pkg types.Package, loc source.Location) {
+		if _, ok := packages[pkg.Purl]; !ok {
+			pkg.Locations = []types.Location{toLocation(loc.RealPath, loc, lm)}
+			packages[pkg.Purl] = pkg
+		}
+	}
+
+	spdxFiles, err := res.FilesByGlob("/opt/bitnami/**/.spdx-*.spdx")
+	if err != nil {
+		log.Debugf("Failed to find Bitnami SPDX files: %s", err)
+	}
+	for _, loc := range spdxFiles {
+		b, err := readFile(res, loc)
+		if err != nil {
+			log.Debugf("Failed to read %s: %s", loc.RealPath, err)
+			continue
+		}
+		for _, pkg := range bitnamiSpdxPackages(b) {
+			add(pkg, loc)
+		}
+	}
+
+	components, err := res.FilesByPath("/opt/bitnami/.bitnami_components.json")
+	if err != nil {
+		log.Debugf("Failed to find Bitnami components: %s", err)
+	}
+	for _, loc := range components {
+		b, err := readFile(res, loc)
+		if err != nil {
+			log.Debugf("Failed to read %s: %s", loc.RealPath, err)
+			continue
+		}
+		for _, pkg := range bitnamiComponentPackages(b) {
+			add(pkg, loc)
+		}
+	}
+
+	bitnamiPackages := make([]types.Package, 0)
+	for _, p := range packages {
+		bitnamiPackages = append(bitnamiPackages, p)
+	}
+	sort.Slice(bitnamiPackages, func(i, j int) bool {
+		return bitnamiPackages[i].Purl < bitnamiPackages[j].Purl
+	})
+	return bitnamiPackages
+}
+
+func bitnamiSpdxPackages(b []byte) []types.Package {
+	var doc bitnamiSpdx
+	if err := json.Unmarshal(b, &doc); err != nil {
+		log.Debugf("Failed to parse Bitnami SPDX file: %s", err)
+		return nil
+	}
+	pkgs := make([]types.Package, 0)
+	for _, p := range doc.Packages {
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType != "purl" {
+				continue
+			}
+			purl, err := packageurl.FromString(ref.ReferenceLocator)
+			if err != nil {
+				continue
+			}
+			// arch and distro are those of the image
+			purl.Qualifiers = nil
+			pkg := types.Package{
+				Type:    purl.Type,
+				Name:    purl.Name,
+				Version: purl.Version,
+				Purl:    purl.ToString(),
+				Url:     p.Homepage,
+			}
+			if l := p.LicenseConcluded; l != "" && l != "NOASSERTION" && l != "NONE" {
+				pkg.Licenses = []string{l}
+			}
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs
+}
+
+func bitnamiComponentPackages(b []byte) []types.Package {
+	var components bitnamiComponents
+	if err := json.Unmarshal(b, &components); err != nil {
+		log.Debugf("Failed to parse Bitnami components: %s", err)
+		return nil
+	}
+	pkgs := make([]types.Package, 0)
+	for name, c := range components {
+		if c.Version == "" {
+			continue
+		}
+		purl := packageurl.NewPackageURL("bitnami", "", name, c.Version, nil, "")
+		pkgs = append(pkgs, types.Package{
+			Type:    purl.Type,
+			Name:    purl.Name,
+			Version: purl.Version,
+			Purl:    purl.ToString(),
+		})
+	}
+	return pkgs
+}
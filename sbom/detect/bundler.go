@@ -0,0 +1,206 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package detect
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// BundlerCataloger is the syft cataloger of the ruby group that enables BundlerGroups
+const BundlerCataloger = "ruby-gemfile-cataloger"
+
+const defaultGroup = "default"
+
+var (
+	gemPattern        = regexp.MustCompile(`^gem\s*\(?\s*["']([^"']+)["']`)
+	groupBlockPattern = regexp.MustCompile(`^group\s*\(?\s*((?::\w+|["'][^"']+["'])(?:\s*,\s*(?::\w+|["'][^"']+["']))*)`)
+	gemGroupsPattern  = regexp.MustCompile(`(?:\bgroups?:|:groups?\s*=>)\s*(\[[^\]]*\]|:\w+|["'][^"']+["'])`)
+	groupNamePattern  = regexp.MustCompile(`:(\w+)|["']([^"']+)["']`)
+	blockPattern      = regexp.MustCompile(`\bdo\s*(?:\|[^|]*\|)?\s*$`)
+)
+
+// BundlerGroups sets the groups of the gems cataloged from a Gemfile.lock to the groups of
+// the Gemfile next to it. Gems required by other gems inherit the groups of those.
+func BundlerGroups(packages []types.Package, image source.Source) {
+	res, err := image.FileResolver(source.SquashedScope)
+	if err != nil {
+		return
+	}
+	groupsByLockfile := make(map[string]map[string][]string)
+	for i, p := range packages {
+		if !strings.HasPrefix(p.Purl, "pkg:gem/") {
+			continue
+		}
+		for _, loc := range p.Locations {
+			if path.Base(loc.Path) != "Gemfile.lock" {
+				continue
+			}
+			groups, ok := groupsByLockfile[loc.Path]
+			if !ok {
+				groups = bundlerGroups(res, loc.Path)
+				groupsByLockfile[loc.Path] = groups
+			}
+			for _, g := range groups[gemName(p.Purl)] {
+				if !contains(packages[i].Groups, g) {
+					packages[i].Groups = append(packages[i].Groups, g)
+				}
+			}
+		}
+		sort.Strings(packages[i].Groups)
+	}
+}
+
+func gemName(purl string) string {
+	name := strings.TrimPrefix(purl, "pkg:gem/")
+	if i := strings.IndexAny(name, "@?#"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func bundlerGroups(res source.FileResolver, lockfile string) map[string][]string {
+	read := func(p string) []byte {
+		locations, err := res.FilesByPath(p)
+		if err != nil || len(locations) == 0 {
+			return nil
+		}
+		b, err := readFile(res, locations[0])
+		if err != nil {
+			log.Debugf("Failed to read %s: %s", p, err)
+		}
+		return b
+	}
+	lock := read(lockfile)
+	gemfile := read(path.Join(path.Dir(lockfile), "Gemfile"))
+	if gemfile == nil {
+		gemfile = read(path.Join(path.Dir(lockfile), "gems.rb"))
+	}
+	if lock == nil || gemfile == nil {
+		return nil
+	}
+	return resolveGroups(parseGemfile(gemfile), parseGemfileLock(lock))
+}
+
+// parseGemfile returns the groups of the gems declared in a Gemfile
+func parseGemfile(b []byte) map[string][]string {
+	gems := make(map[string][]string)
+	// the groups of the enclosing blocks; blocks other than group blocks add none
+	stack := make([][]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case line == "end":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case gemPattern.MatchString(line):
+			name := gemPattern.FindStringSubmatch(line)[1]
+			groups := make([]string, 0)
+			for _, s := range stack {
+				groups = append(groups, s...)
+			}
+			if m := gemGroupsPattern.FindStringSubmatch(line); m != nil {
+				groups = append(groups, groupNames(m[1])...)
+			}
+			if len(groups) == 0 {
+				groups = append(groups, defaultGroup)
+			}
+			gems[name] = append(gems[name], groups...)
+			if blockPattern.MatchString(line) {
+				stack = append(stack, nil)
+			}
+		case groupBlockPattern.MatchString(line) && blockPattern.MatchString(line):
+			stack = append(stack, groupNames(groupBlockPattern.FindStringSubmatch(line)[1]))
+		case blockPattern.MatchString(line):
+			stack = append(stack, nil)
+		}
+	}
+	return gems
+}
+
+func groupNames(s string) []string {
+	names := make([]string, 0)
+	for _, m := range groupNamePattern.FindAllStringSubmatch(s, -1) {
+		names = append(names, m[1]+m[2])
+	}
+	return names
+}
+
+// parseGemfileLock returns the dependencies of every gem in a Gemfile.lock
+func parseGemfileLock(b []byte) map[string][]string {
+	deps := make(map[string][]string)
+	var section, gem string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" && line[0] != ' ' {
+			section = strings.TrimSpace(line)
+			continue
+		}
+		if section != "GEM" && section != "GIT" && section != "PATH" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch indent := len(line) - len(strings.TrimLeft(line, " ")); indent {
+		case 4:
+			gem = fields[0]
+			if _, ok := deps[gem]; !ok {
+				deps[gem] = make([]string, 0)
+			}
+		case 6:
+			deps[gem] = append(deps[gem], fields[0])
+		}
+	}
+	return deps
+}
+
+// resolveGroups passes the groups of the gems in the Gemfile on to their dependencies
+func resolveGroups(direct map[string][]string, deps map[string][]string) map[string][]string {
+	groups := make(map[string][]string)
+	var visit func(gem string, group string)
+	visit = func(gem string, group string) {
+		if contains(groups[gem], group) {
+			return
+		}
+		groups[gem] = append(groups[gem], group)
+		for _, d := range deps[gem] {
+			visit(d, group)
+		}
+	}
+	for gem, gs := range direct {
+		for _, g := range gs {
+			visit(gem, g)
+		}
+	}
+	return groups
+}
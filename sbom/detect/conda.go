@@ -0,0 +1,113 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package detect
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// CondaCataloger is the name of the syft cataloger group entry that enables CondaPackages
+const CondaCataloger = "conda-meta-cataloger"
+
+// condaMeta is the record conda keeps of an installed package in <env>/conda-meta/<name>-<version>-<build>.json
+type condaMeta struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	License string   `json:"license"`
+	Url     string   `json:"url"`
+	Size    int      `json:"size"`
+	Files   []string `json:"files"`
+}
+
+// CondaPackages returns the packages of conda environments. Python packages installed by conda
+// are cataloged from their dist-info or egg-info already and aren't reported twice.
+func CondaPackages(packages []types.Package, image source.Source, lm types.LayerMapping) []types.Package {
+	res, err := image.FileResolver(source.SquashedScope)
+	if err != nil {
+		return []types.Package{}
+	}
+	locations, err := res.FilesByGlob("**/conda-meta/*.json")
+	if err != nil {
+		log.Debugf("Failed to find conda environments: %s", err)
+		return []types.Package{}
+	}
+
+	cataloged := make(map[string]bool)
+	for _, p := range packages {
+		for _, loc := range p.Locations {
+			cataloged[loc.Path] = true
+		}
+	}
+
+	condaPackages := make([]types.Package, 0)
+	for _, loc := range locations {
+		b, err := readFile(res, loc)
+		if err != nil {
+			log.Debugf("Failed to read %s: %s", loc.RealPath, err)
+			continue
+		}
+		var meta condaMeta
+		if err := json.Unmarshal(b, &meta); err != nil || meta.Name == "" || meta.Version == "" {
+			continue
+		}
+		env := path.Dir(path.Dir(loc.RealPath))
+		if pythonMetadataCataloged(env, meta.Files, cataloged) {
+			continue
+		}
+		condaPackages = append(condaPackages, condaPackage(meta, env, loc, lm))
+	}
+	return condaPackages
+}
+
+func pythonMetadataCataloged(env string, files []string, cataloged map[string]bool) bool {
+	for _, f := range files {
+		if strings.HasSuffix(f, ".dist-info/METADATA") || strings.HasSuffix(f, ".egg-info/PKG-INFO") || strings.HasSuffix(f, ".egg-info") {
+			if cataloged[path.Join(env, f)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func condaPackage(meta condaMeta, env string, loc source.Location, lm types.LayerMapping) types.Package {
+	purl := packageurl.NewPackageURL("conda", "", meta.Name, meta.Version, nil, "")
+	pkg := types.Package{
+		Type:      purl.Type,
+		Name:      purl.Name,
+		Version:   purl.Version,
+		Purl:      purl.ToString(),
+		Url:       meta.Url,
+		Size:      meta.Size,
+		Locations: []types.Location{toLocation(loc.RealPath, loc, lm)},
+	}
+	if meta.License != "" {
+		pkg.Licenses = []string{meta.License}
+	}
+	for _, f := range meta.Files {
+		// the files are attributed to the layer of the conda-meta record
+		pkg.Files = append(pkg.Files, toLocation(path.Join(env, f), loc, lm))
+	}
+	return pkg
+}
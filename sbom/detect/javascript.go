@@ -0,0 +1,291 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package detect
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// JavascriptCataloger is the syft cataloger of the javascript group that enables JavascriptPackages
+const JavascriptCataloger = "javascript-package-cataloger"
+
+const (
+	// bundledConfidence is the confidence of bundled packages with the version in their path
+	bundledConfidence = 0.8
+	// installedConfidence is the confidence of bundled packages with the version of the only
+	// installed package of the same name
+	installedConfidence = 0.5
+)
+
+var (
+	// yarnCachePattern matches the archives of packages in yarn berry caches, e.g.
+	// /app/.yarn/cache/lodash-npm-4.17.21-6382451519-eb835a2e51.zip
+	yarnCachePattern = regexp.MustCompile(`/\.yarn/(?:berry/)?cache/[^/]+\.zip$`)
+	// packageJsonPattern matches the package.json of a package in node_modules
+	packageJsonPattern = regexp.MustCompile(`(?:^|/)node_modules/((?:@[^/]+/)?[^/@.][^/]*)/package\.json$`)
+	// bundledModulePattern matches the last node_modules directory in a source of a map
+	bundledModulePattern = regexp.MustCompile(`^(.*)node_modules/((?:@[^/]+/)?[^/@.][^/]*)/`)
+	// pnpmVersionPattern matches the version of a pnpm store directory, e.g. .pnpm/@babel+core@7.20.2_x@1/
+	pnpmVersionPattern = regexp.MustCompile(`\.pnpm/(?:@[^/+]+\+)?[^/@]+@([^/_]+)[^/]*/$`)
+	// yarnVersionPattern matches the version of a yarn berry cache archive
+	yarnVersionPattern = regexp.MustCompile(`-npm-(\d[^-/]*)-[^/]+\.zip/$`)
+)
+
+type packageJson struct {
+	Name        string      `json:"name"`
+	Version     string      `json:"version"`
+	Description string      `json:"description"`
+	Homepage    string      `json:"homepage"`
+	License     interface{} `json:"license"`
+}
+
+func (p packageJson) licenses() []string {
+	switch l := p.License.(type) {
+	case string:
+		return []string{l}
+	case map[string]interface{}:
+		if t, ok := l["type"].(string); ok {
+			return []string{t}
+		}
+	}
+	return nil
+}
+
+func (p packageJson) toPackage() (types.Package, bool) {
+	if p.Name == "" || p.Version == "" {
+		return types.Package{}, false
+	}
+	purl := npmPurl(p.Name, p.Version)
+	return types.Package{
+		Type:        purl.Type,
+		Namespace:   purl.Namespace,
+		Name:        purl.Name,
+		Version:     purl.Version,
+		Purl:        purl.ToString(),
+		Description: p.Description,
+		Url:         p.Homepage,
+		Licenses:    p.licenses(),
+	}, true
+}
+
+func npmPurl(name, version string) packageurl.PackageURL {
+	namespace := ""
+	if scope, n, ok := strings.Cut(name, "/"); ok {
+		namespace, name = scope, n
+	}
+	return *packageurl.NewPackageURL(packageurl.TypeNPM, namespace, name, version, nil, "")
+}
+
+// JavascriptPackages complements the package.json files syft finds in node_modules with the
+// packages of yarn berry caches, which stay zipped with Plug'n'Play, and the packages bundled
+// into JavaScript files by webpack and other bundlers that are listed in their source maps
+func JavascriptPackages(packages []types.Package, image source.Source, lm types.LayerMapping) []types.Package {
+	res, err := image.FileResolver(source.SquashedScope)
+	if err != nil {
+		return []types.Package{}
+	}
+
+	jsPackages := make([]types.Package, 0)
+	archives, err := res.FilesByGlob("**/.yarn/cache/*.zip", "**/.yarn/berry/cache/*.zip")
+	if err != nil {
+		log.Debugf("Failed to find yarn cache: %s", err)
+	}
+	for _, loc := range archives {
+		if !yarnCachePattern.MatchString(loc.RealPath) {
+			continue
+		}
+		b, err := readFile(res, loc)
+		if err != nil {
+			log.Debugf("Failed to read %s: %s", loc.RealPath, err)
+			continue
+		}
+		for _, pkg := range yarnCachePackages(b, loc.RealPath) {
+			pkg.Locations = []types.Location{toLocation(pkg.Locations[0].Path, loc, lm)}
+			jsPackages = append(jsPackages, pkg)
+		}
+	}
+
+	installed := make(map[string][]string)
+	for _, p := range packages {
+		if strings.HasPrefix(p.Purl, "pkg:npm/") {
+			purl, err := packageurl.FromString(p.Purl)
+			if err == nil && !contains(installed[npmName(purl)], purl.Version) {
+				installed[npmName(purl)] = append(installed[npmName(purl)], purl.Version)
+			}
+		}
+	}
+	maps, err := res.FilesByGlob("**/*.js.map", "**/*.mjs.map", "**/*.cjs.map")
+	if err != nil {
+		log.Debugf("Failed to find source maps: %s", err)
+	}
+	for _, loc := range maps {
+		if strings.Contains(loc.RealPath, "/node_modules/") {
+			// maps shipped by packages themselves list their own sources
+			continue
+		}
+		b, err := readFile(res, loc)
+		if err != nil {
+			log.Debugf("Failed to read %s: %s", loc.RealPath, err)
+			continue
+		}
+		for _, pkg := range sourceMapPackages(b, installed) {
+			pkg.Locations = []types.Location{toLocation(loc.RealPath, loc, lm)}
+			jsPackages = append(jsPackages, pkg)
+		}
+	}
+	return jsPackages
+}
+
+func readFile(res source.FileResolver, loc source.Location) ([]byte, error) {
+	r, err := res.FileContentsByLocation(loc)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close() //nolint:errcheck
+	return io.ReadAll(r)
+}
+
+func toLocation(path string, loc source.Location, lm types.LayerMapping) types.Location {
+	return types.Location{
+		Path:   path,
+		DiffId: loc.FileSystemID,
+		Digest: lm.ByDiffId[loc.FileSystemID],
+	}
+}
+
+func npmName(purl packageurl.PackageURL) string {
+	if purl.Namespace != "" {
+		return purl.Namespace + "/" + purl.Name
+	}
+	return purl.Name
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// yarnCachePackages returns the packages of a yarn berry cache archive at path. Their location
+// is the package.json inside the archive, e.g. /app/.yarn/cache/x.zip!/node_modules/x/package.json.
+func yarnCachePackages(b []byte, path string) []types.Package {
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		log.Debugf("Failed to open %s: %s", path, err)
+		return nil
+	}
+	pkgs := make([]types.Package, 0)
+	for _, f := range r.File {
+		// skip packages nested in the node_modules of the package
+		if m := packageJsonPattern.FindStringSubmatch(f.Name); m == nil || strings.Count(f.Name, "node_modules/") != 1 {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		var p packageJson
+		err = json.NewDecoder(rc).Decode(&p)
+		_ = rc.Close()
+		if err != nil {
+			log.Debugf("Failed to parse %s!/%s: %s", path, f.Name, err)
+			continue
+		}
+		if pkg, ok := p.toPackage(); ok {
+			pkg.Locations = []types.Location{{Path: path + "!/" + f.Name}}
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs
+}
+
+type sourceMap struct {
+	Sources        []string  `json:"sources"`
+	SourcesContent []*string `json:"sourcesContent"`
+}
+
+// sourceMapPackages returns the packages whose modules are listed in the sources of a source map.
+// Their version is taken from the path of pnpm and yarn berry layouts, a bundled package.json,
+// or, with a lower confidence, from the only version of the package installed in the image.
+// Packages without a version are skipped.
+func sourceMapPackages(b []byte, installed map[string][]string) []types.Package {
+	var sm sourceMap
+	if err := json.Unmarshal(b, &sm); err != nil {
+		return nil
+	}
+	versions := make(map[string]string)
+	confidence := make(map[string]float64)
+	for i, s := range sm.Sources {
+		m := bundledModulePattern.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		name := m[2]
+		if _, ok := versions[name]; !ok {
+			versions[name] = ""
+		}
+		if v := pnpmVersionPattern.FindStringSubmatch(m[1]); v != nil {
+			versions[name], confidence[name] = v[1], bundledConfidence
+		} else if v := yarnVersionPattern.FindStringSubmatch(m[1]); v != nil {
+			versions[name], confidence[name] = v[1], bundledConfidence
+		} else if path.Base(s) == "package.json" && i < len(sm.SourcesContent) && sm.SourcesContent[i] != nil {
+			var p packageJson
+			if json.Unmarshal([]byte(*sm.SourcesContent[i]), &p) == nil && p.Name == name && p.Version != "" {
+				versions[name], confidence[name] = p.Version, bundledConfidence
+			}
+		}
+	}
+
+	pkgs := make([]types.Package, 0)
+	for name, version := range versions {
+		c := confidence[name]
+		if version == "" {
+			if len(installed[name]) != 1 {
+				continue
+			}
+			version, c = installed[name][0], installedConfidence
+		}
+		purl := npmPurl(name, version)
+		pkgs = append(pkgs, types.Package{
+			Type:       purl.Type,
+			Namespace:  purl.Namespace,
+			Name:       purl.Name,
+			Version:    purl.Version,
+			Purl:       purl.ToString(),
+			Confidence: c,
+		})
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Purl < pkgs[j].Purl
+	})
+	return pkgs
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package detect
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// NixCataloger is the name of the syft cataloger group entry that enables NixPackages
+const NixCataloger = "nix-store-cataloger"
+
+var (
+	// storePathPattern matches the store path of a derivation output or the derivation itself,
+	// e.g. /nix/store/0a8pkhcxh3xqx7q8a3gfhkd5c6pl9zjv-openssl-3.0.7-bin
+	storePathPattern = regexp.MustCompile(`^/nix/store/([0-9a-df-np-sv-z]{32})-([^/]+?)(\.drv)?(?:/|$)`)
+	// storeVersionPattern splits a derivation name into name and version like builtins.parseDrvName
+	storeVersionPattern = regexp.MustCompile(`^(.+?)-([^a-zA-Z].*)$`)
+)
+
+// nixOutputs are the names of derivation outputs besides out that are appended to store paths
+var nixOutputs = []string{"bin", "dev", "lib", "man", "doc", "devdoc", "info", "debug", "static"}
+
+// NixPackages returns the derivations in the Nix store of the image by their store paths
+func NixPackages(_ []types.Package, image source.Source, lm types.LayerMapping) []types.Package {
+	res, err := image.FileResolver(source.SquashedScope)
+	if err != nil {
+		return []types.Package{}
+	}
+	locations, err := res.FilesByGlob("/nix/store/**")
+	if err != nil {
+		log.Debugf("Failed to find Nix store: %s", err)
+		return []types.Package{}
+	}
+
+	packages := make(map[string]types.Package)
+	for _, loc := range locations {
+		m := storePathPattern.FindStringSubmatch(loc.RealPath)
+		if m == nil {
+			continue
+		}
+		name, version, ok := parseDrvName(m[2])
+		if !ok {
+			continue
+		}
+		purl := packageurl.NewPackageURL("nix", "", name, version, nil, "").ToString()
+		storePath := "/nix/store/" + m[1] + "-" + m[2] + m[3]
+		// every output of a derivation, like openssl-3.0.7 and openssl-3.0.7-bin, is a location
+		if p, ok := packages[purl]; ok {
+			if !containsLocation(p.Locations, storePath) {
+				p.Locations = append(p.Locations, toLocation(storePath, loc, lm))
+				packages[purl] = p
+			}
+			continue
+		}
+		packages[purl] = types.Package{
+			Type:      "nix",
+			Name:      name,
+			Version:   version,
+			Purl:      purl,
+			Locations: []types.Location{toLocation(storePath, loc, lm)},
+		}
+	}
+
+	nixPackages := make([]types.Package, 0)
+	for _, p := range packages {
+		nixPackages = append(nixPackages, p)
+	}
+	sort.Slice(nixPackages, func(i, j int) bool {
+		return nixPackages[i].Purl < nixPackages[j].Purl
+	})
+	return nixPackages
+}
+
+// parseDrvName returns name and version of a store path name without its hash, dropping the
+// output name, e.g. openssl and 3.0.7 of openssl-3.0.7-bin
+func parseDrvName(s string) (string, string, bool) {
+	m := storeVersionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+	version := m[2]
+	for _, o := range nixOutputs {
+		version = strings.TrimSuffix(version, "-"+o)
+	}
+	return m[1], version, true
+}
+
+func containsLocation(locations []types.Location, path string) bool {
+	for _, l := range locations {
+		if l.Path == path {
+			return true
+		}
+	}
+	return false
+}
@@ -17,9 +17,13 @@
 package sbom
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
-	"sync"
 
 	"github.com/anchore/packageurl-go"
 	"github.com/docker/docker/client"
@@ -28,6 +32,7 @@ import (
 	"github.com/gookit/color"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/pkg/errors"
 )
 
 type colors struct {
@@ -58,28 +63,184 @@ func init() {
 	}
 }
 
+const (
+	DiffFormatTable    = "table"
+	DiffFormatJSON     = "json"
+	DiffFormatMarkdown = "markdown"
+)
+
+// SbomDiff lists the package and vulnerability changes between two images
+type SbomDiff struct {
+	Added          []types.Package  `json:"added"`
+	Removed        []types.Package  `json:"removed"`
+	Upgraded       []PackageUpgrade `json:"upgraded"`
+	IntroducedCves []types.Cve      `json:"introduced_cves"`
+	ResolvedCves   []types.Cve      `json:"resolved_cves"`
+}
+
+// PackageUpgrade is a package that changed version between the two images
+type PackageUpgrade struct {
+	Package string `json:"package"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Purl    string `json:"purl"`
+}
+
+// DiffImages indexes both images and writes a comparison of their packages and CVEs to stdout
 func DiffImages(image1 string, image2 string, client client.APIClient, workspace string, apikey string) error {
-	resultChan := make(chan ImageIndexResult, 2)
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go indexImageAsync(&wg, image1, client, resultChan)
-	go indexImageAsync(&wg, image2, client, resultChan)
-	wg.Wait()
-	close(resultChan)
-
-	var result1, result2 ImageIndexResult
-	for result := range resultChan {
-		switch result.Input {
-		case image1:
-			result1 = result
-		case image2:
-			result2 = result
+	results := IndexImages(context.Background(), []string{image1, image2}, BatchOptions{
+		Parallelism: 2,
+		Client:      client,
+		IncludeCves: true,
+		Workspace:   workspace,
+		ApiKey:      apikey,
+	})
+	for _, result := range results {
+		if err := result.Err(); err != nil {
+			return err
+		}
+	}
+	result1, result2 := results[0], results[1]
+	return WriteDiff(result1.Sbom, result2.Sbom, DiffFormatTable, os.Stdout)
+}
+
+// Diff compares the packages and vulnerabilities of sb2 against sb1. Packages with a single
+// version in both sboms that differ are reported as upgraded instead of removed and added.
+func Diff(sb1, sb2 *types.Sbom) SbomDiff {
+	diff := SbomDiff{
+		Added:          make([]types.Package, 0),
+		Removed:        make([]types.Package, 0),
+		Upgraded:       make([]PackageUpgrade, 0),
+		IntroducedCves: make([]types.Cve, 0),
+		ResolvedCves:   make([]types.Cve, 0),
+	}
+
+	packages := make(PackageMap)
+	for _, p := range sb1.Artifacts {
+		v := packages[toPackageKey(p)]
+		v.image1 = append(v.image1, p)
+		packages[toPackageKey(p)] = v
+	}
+	for _, p := range sb2.Artifacts {
+		v := packages[toPackageKey(p)]
+		v.image2 = append(v.image2, p)
+		packages[toPackageKey(p)] = v
+	}
+	for k, v := range packages {
+		removed := packagesWithoutVersions(v.image1, v.image2)
+		added := packagesWithoutVersions(v.image2, v.image1)
+		if len(removed) == 1 && len(added) == 1 {
+			diff.Upgraded = append(diff.Upgraded, PackageUpgrade{
+				Package: k,
+				From:    removed[0].Version,
+				To:      added[0].Version,
+				Purl:    added[0].Purl,
+			})
+			continue
 		}
+		diff.Removed = append(diff.Removed, removed...)
+		diff.Added = append(diff.Added, added...)
 	}
 
-	diffPackages(result1, result2)
-	diffCves(result1, result2)
-	return nil
+	diff.ResolvedCves = cvesWithoutIds(sb1.Vulnerabilities, sb2.Vulnerabilities)
+	diff.IntroducedCves = cvesWithoutIds(sb2.Vulnerabilities, sb1.Vulnerabilities)
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Purl < diff.Added[j].Purl })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Purl < diff.Removed[j].Purl })
+	sort.Slice(diff.Upgraded, func(i, j int) bool { return diff.Upgraded[i].Package < diff.Upgraded[j].Package })
+	return diff
+}
+
+// packagesWithoutVersions returns the packages whose version is not in others
+func packagesWithoutVersions(packages []types.Package, others []types.Package) []types.Package {
+	result := make([]types.Package, 0)
+	for _, p := range packages {
+		found := false
+		for _, o := range others {
+			if o.Version == p.Version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// cvesWithoutIds returns the affecting cves whose id is not detected in others
+func cvesWithoutIds(cves []types.Cve, others []types.Cve) []types.Cve {
+	ids := make(map[string]bool)
+	for _, c := range others {
+		if IsAffected(c) {
+			ids[c.SourceId] = true
+		}
+	}
+	result := make([]types.Cve, 0)
+	for _, c := range cves {
+		if IsAffected(c) && !ids[c.SourceId] {
+			result = append(result, c)
+		}
+	}
+	_ = SortCves(result, "severity")
+	return result
+}
+
+// WriteDiff writes the comparison of both sboms in the given format (table, json or markdown)
+func WriteDiff(sb1, sb2 *types.Sbom, format string, w io.Writer) error {
+	diff := Diff(sb1, sb2)
+	switch format {
+	case "", DiffFormatTable:
+		diffPackages(sb1, sb2, w)
+		diffCves(sb1, sb2, w)
+		fmt.Fprintln(w, diffSummary(diff))
+		return nil
+	case DiffFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	case DiffFormatMarkdown:
+		return writeMarkdownDiff(sb1, sb2, diff, w)
+	default:
+		return errors.Errorf("unsupported diff format: %s", format)
+	}
+}
+
+func diffSummary(diff SbomDiff) string {
+	return fmt.Sprintf("%d packages added, %d removed, %d upgraded; %d vulnerabilities introduced, %d resolved",
+		len(diff.Added), len(diff.Removed), len(diff.Upgraded), len(diff.IntroducedCves), len(diff.ResolvedCves))
+}
+
+// writeMarkdownDiff writes the comparison as markdown, e.g. to comment on pull requests
+func writeMarkdownDiff(sb1, sb2 *types.Sbom, diff SbomDiff, w io.Writer) error {
+	header1, header2 := toHeader(sb1, sb2)
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Image comparison `%s` → `%s`\n\n%s\n", header1, header2, diffSummary(diff))
+
+	if len(diff.IntroducedCves) > 0 || len(diff.ResolvedCves) > 0 {
+		b.WriteString("\n| Vulnerability | Severity | Package | Status |\n|---|---|---|---|\n")
+		for _, c := range diff.IntroducedCves {
+			fmt.Fprintf(&b, "| %s | %s | `%s` | introduced |\n", c.SourceId, Severity(c), c.Purl)
+		}
+		for _, c := range diff.ResolvedCves {
+			fmt.Fprintf(&b, "| %s | %s | `%s` | resolved |\n", c.SourceId, Severity(c), c.Purl)
+		}
+	}
+	if len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Upgraded) > 0 {
+		b.WriteString("\n| Package | " + header1 + " | " + header2 + " |\n|---|---|---|\n")
+		for _, u := range diff.Upgraded {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", u.Package, u.From, u.To)
+		}
+		for _, p := range diff.Removed {
+			fmt.Fprintf(&b, "| %s | %s | |\n", toPackageKey(p), p.Version)
+		}
+		for _, p := range diff.Added {
+			fmt.Fprintf(&b, "| %s | | %s |\n", toPackageKey(p), p.Version)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
 }
 
 func toPackageKey(pkg types.Package) string {
@@ -98,8 +259,8 @@ func toPackageName(pkg packageurl.PackageURL) string {
 	}
 }
 
-func toImageName(result ImageIndexResult) string {
-	imageName := result.Sbom.Source.Image.Name
+func toImageName(sb *types.Sbom) string {
+	imageName := sb.Source.Image.Name
 	if strings.HasPrefix(imageName, "index.docker.io/") {
 		imageName = imageName[len("index.docker.io/"):]
 	}
@@ -109,9 +270,9 @@ func toImageName(result ImageIndexResult) string {
 	return imageName
 }
 
-func toHeader(result1, result2 ImageIndexResult) (string, string) {
-	image1 := result1.Sbom.Source.Image
-	image2 := result2.Sbom.Source.Image
+func toHeader(sb1, sb2 *types.Sbom) (string, string) {
+	image1 := sb1.Source.Image
+	image2 := sb2.Source.Image
 	if image1.Name == image2.Name {
 		if image1.Tags != nil && image2.Tags != nil {
 			return (*image1.Tags)[0], (*image2.Tags)[0]
@@ -119,7 +280,7 @@ func toHeader(result1, result2 ImageIndexResult) (string, string) {
 			return image1.Digest[7:17], image2.Digest[7:17]
 		}
 	} else {
-		return toImageName(result1), toImageName(result2)
+		return toImageName(sb1), toImageName(sb2)
 	}
 }
 
@@ -130,10 +291,10 @@ type PackageEntry struct {
 
 type PackageMap map[string]PackageEntry
 
-func diffPackages(result1, result2 ImageIndexResult) {
+func diffPackages(sb1, sb2 *types.Sbom, w io.Writer) {
 	dc := 0
 	packages := make(PackageMap)
-	for _, p := range result1.Sbom.Artifacts {
+	for _, p := range sb1.Artifacts {
 		key := toPackageKey(p)
 		if v, ok := packages[key]; ok {
 			v.image1 = append(v.image1, p)
@@ -145,7 +306,7 @@ func diffPackages(result1, result2 ImageIndexResult) {
 			}
 		}
 	}
-	for _, p := range result2.Sbom.Artifacts {
+	for _, p := range sb2.Artifacts {
 		key := toPackageKey(p)
 		if v, ok := packages[key]; ok {
 			v.image2 = append(v.image2, p)
@@ -158,7 +319,7 @@ func diffPackages(result1, result2 ImageIndexResult) {
 		}
 	}
 
-	header1, header2 := toHeader(result1, result2)
+	header1, header2 := toHeader(sb1, sb2)
 
 	t := table.NewWriter()
 	t.AppendHeader(table.Row{"Package", "Version", header1, header2})
@@ -222,8 +383,8 @@ func diffPackages(result1, result2 ImageIndexResult) {
 	t.SetStyle(table.StyleLight)
 	t.Style().Options.SeparateRows = true
 	if dc > 0 {
-		fmt.Println("Package Comparison")
-		fmt.Println(t.Render())
+		fmt.Fprintln(w, "Package Comparison")
+		fmt.Fprintln(w, t.Render())
 	}
 }
 
@@ -295,10 +456,10 @@ func toSeverityInt(cve types.Cve) int {
 	}
 }
 
-func diffCves(result1, result2 ImageIndexResult) {
+func diffCves(sb1, sb2 *types.Sbom, w io.Writer) {
 	dc := 0
 	cves := make(CveMap)
-	for _, c := range result1.Sbom.Vulnerabilities {
+	for _, c := range sb1.Vulnerabilities {
 		key := c.SourceId
 		if v, ok := cves[key]; ok {
 			v.image1 = append(v.image1, c)
@@ -310,7 +471,7 @@ func diffCves(result1, result2 ImageIndexResult) {
 			}
 		}
 	}
-	for _, c := range result2.Sbom.Vulnerabilities {
+	for _, c := range sb2.Vulnerabilities {
 		key := c.SourceId
 		if v, ok := cves[key]; ok {
 			v.image2 = append(v.image2, c)
@@ -323,7 +484,7 @@ func diffCves(result1, result2 ImageIndexResult) {
 		}
 	}
 
-	header1, header2 := toHeader(result1, result2)
+	header1, header2 := toHeader(sb1, sb2)
 
 	t := table.NewWriter()
 	t.AppendHeader(table.Row{"Id", "Sev", "CVE", "Severity", header1, header2})
@@ -385,8 +546,7 @@ func diffCves(result1, result2 ImageIndexResult) {
 	t.SetStyle(table.StyleLight)
 	t.Style().Options.SeparateRows = true
 	if dc > 0 {
-		fmt.Println("Vulnerability Comparison")
-		fmt.Println(t.Render())
+		fmt.Fprintln(w, "Vulnerability Comparison")
+		fmt.Fprintln(w, t.Render())
 	}
-
 }
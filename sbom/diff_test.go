@@ -0,0 +1,57 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestDiff(t *testing.T) {
+	sb1 := &types.Sbom{
+		Artifacts: []types.Package{
+			{Type: "alpine", Name: "musl", Version: "1.2.2", Purl: "pkg:alpine/musl@1.2.2"},
+			{Type: "alpine", Name: "zlib", Version: "1.2.11", Purl: "pkg:alpine/zlib@1.2.11"},
+		},
+		Vulnerabilities: []types.Cve{cveWithSeverity("CVE-1", "HIGH"), cveWithSeverity("CVE-2", "LOW")},
+	}
+	sb2 := &types.Sbom{
+		Artifacts: []types.Package{
+			{Type: "alpine", Name: "musl", Version: "1.2.3", Purl: "pkg:alpine/musl@1.2.3"},
+			{Type: "npm", Name: "lodash", Version: "4.17.21", Purl: "pkg:npm/lodash@4.17.21"},
+		},
+		Vulnerabilities: []types.Cve{cveWithSeverity("CVE-2", "LOW"), cveWithSeverity("CVE-3", "CRITICAL")},
+	}
+
+	diff := Diff(sb1, sb2)
+	if len(diff.Upgraded) != 1 || diff.Upgraded[0].From != "1.2.2" || diff.Upgraded[0].To != "1.2.3" {
+		t.Errorf("expected musl upgrade, got %v", diff.Upgraded)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "zlib" {
+		t.Errorf("expected zlib to be removed, got %v", diff.Removed)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Name != "lodash" {
+		t.Errorf("expected lodash to be added, got %v", diff.Added)
+	}
+	if len(diff.IntroducedCves) != 1 || diff.IntroducedCves[0].SourceId != "CVE-3" {
+		t.Errorf("expected CVE-3 to be introduced, got %v", diff.IntroducedCves)
+	}
+	if len(diff.ResolvedCves) != 1 || diff.ResolvedCves[0].SourceId != "CVE-1" {
+		t.Errorf("expected CVE-1 to be resolved, got %v", diff.ResolvedCves)
+	}
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"strings"
+
+	"github.com/anchore/syft/syft/linux"
+	pkg2 "github.com/anchore/syft/syft/pkg"
+)
+
+// releaseFromPackages identifies the distro of images without os-release, e.g. distroless
+// images, by the packages that carry the release of their distro
+func releaseFromPackages(catalog *pkg2.Catalog) *linux.Release {
+	for _, p := range catalog.Sorted() {
+		switch {
+		case p.Type == pkg2.DebPkg && p.Name == "base-files" && !strings.Contains(p.Version, "ubuntu"):
+			// e.g. 11.1+deb11u5
+			version := strings.FieldsFunc(p.Version, func(r rune) bool { return r == '.' || r == '+' })
+			if len(version) > 0 {
+				return &linux.Release{ID: "debian", VersionID: version[0]}
+			}
+		case p.Type == pkg2.ApkPkg && p.Name == "alpine-release":
+			version, _, _ := strings.Cut(p.Version, "-")
+			return &linux.Release{ID: "alpine", VersionID: version}
+		case p.Type == pkg2.ApkPkg && p.Name == "wolfi-baselayout":
+			return &linux.Release{ID: "wolfi"}
+		}
+	}
+	return nil
+}
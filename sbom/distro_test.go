@@ -0,0 +1,105 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anchore/syft/syft/linux"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestOsQualifiersDistro(t *testing.T) {
+	tests := []struct {
+		release  linux.Release
+		expected types.Distro
+	}{
+		{release: linux.Release{ID: "wolfi", Name: "Wolfi", VersionID: "20230201"}, expected: types.Distro{OsName: "wolfi", OsVersion: "rolling"}},
+		{release: linux.Release{ID: "chainguard", VersionID: "20230214"}, expected: types.Distro{OsName: "wolfi", OsVersion: "rolling"}},
+		// distroless
+		{release: linux.Release{ID: "debian", Version: "Debian GNU/Linux 11 (bullseye)", VersionID: "11"}, expected: types.Distro{OsName: "debian", OsVersion: "11"}},
+		{release: linux.Release{ID: "ubuntu", Version: "22.04.1 LTS (Jammy Jellyfish)", VersionID: "22.04"}, expected: types.Distro{OsName: "ubuntu", OsVersion: "22.04"}},
+	}
+	for _, test := range tests {
+		release := test.release
+		if distro, _ := osQualifiers(&release); distro != test.expected {
+			t.Errorf("expected %+v of %s, got %+v", test.expected, release.ID, distro)
+		}
+	}
+}
+
+func TestIndexDistrolessAndNix(t *testing.T) {
+	content := tarArchive(map[string][]byte{
+		// distroless images have no os-release but a dpkg status file per package
+		"var/lib/dpkg/status.d/base-files":                                               []byte("Package: base-files\nVersion: 11.1+deb11u5\nArchitecture: amd64\nStatus: install ok installed\n"),
+		"nix/store/0a8pkhcxh3xqx7q8a3gfhkd5c6pl9zjv-openssl-3.0.7-bin/bin/openssl":       []byte("openssl"),
+		"nix/store/1b9qkhcxh3xqx7q8a3gfhkd5c6pl9zjv-openssl-3.0.7/lib/libssl.so.3":       []byte("libssl"),
+		"nix/store/2c0rkhcxh3xqx7q8a3gfhkd5c6pl9zjv-python3.10-requests-2.28.1/lib/x.py": []byte(""),
+		"nix/store/3d1skhcxh3xqx7q8a3gfhkd5c6pl9zjv-source/README":                       []byte(""),
+	})
+	layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+	img, _ := mutate.AppendLayers(empty.Image, layer)
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerOs, CatalogerNix)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := sb.Source.Image.Distro; d.OsName != "debian" || d.OsVersion != "11" {
+		t.Errorf("expected debian 11, got %+v", sb.Source.Image.Distro)
+	}
+	expected := map[string]string{
+		"pkg:deb/base-files@11.1+deb11u5?os_name=debian&os_version=11": "/var/lib/dpkg/status.d/base-files",
+		"pkg:nix/openssl@3.0.7":              "/nix/store/0a8pkhcxh3xqx7q8a3gfhkd5c6pl9zjv-openssl-3.0.7-bin",
+		"pkg:nix/python3.10-requests@2.28.1": "/nix/store/2c0rkhcxh3xqx7q8a3gfhkd5c6pl9zjv-python3.10-requests-2.28.1",
+	}
+	for _, p := range sb.Artifacts {
+		if strings.HasPrefix(p.Purl, "pkg:nix/source") {
+			t.Errorf("unexpected package %s", p.Purl)
+		}
+		path, ok := expected[p.Purl]
+		if !ok {
+			continue
+		}
+		if len(p.Locations) == 0 || p.Locations[0].Path != path {
+			t.Errorf("expected %s at %s, got %v", p.Purl, path, p.Locations)
+		}
+		delete(expected, p.Purl)
+	}
+	if len(expected) > 0 {
+		t.Errorf("expected packages %v, got %v", expected, sb.Artifacts)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// maxDockerfilePackages limits the packages listed per instruction of a reconstructed Dockerfile
+const maxDockerfilePackages = 10
+
+var (
+	// the classic builder records instructions other than RUN as no-op shell commands
+	nopPattern = regexp.MustCompile(`^/bin/sh -c #\(nop\)\s*`)
+	// ADD and COPY of the classic builder list the checksum of the source, e.g. ADD file:abc in /
+	copyPattern            = regexp.MustCompile(`^(ADD|COPY) (\S+) in (.*)$`)
+	dockerfileInstructions = map[string]bool{
+		"ADD": true, "ARG": true, "CMD": true, "COPY": true, "ENTRYPOINT": true, "ENV": true,
+		"EXPOSE": true, "HEALTHCHECK": true, "LABEL": true, "MAINTAINER": true, "ONBUILD": true,
+		"RUN": true, "SHELL": true, "STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true,
+	}
+)
+
+// WriteDockerfile writes an approximate Dockerfile reconstructed from the image history, listing
+// the packages each layer introduced above the instruction that created it
+func WriteDockerfile(sb *types.Sbom, w io.Writer) error {
+	img := sb.Source.Image
+	history := img.History
+	if len(history) == 0 {
+		// sboms written before the history was recorded still have the config
+		history = imageHistory(img.Config, img.Manifest)
+	}
+	packages := make(map[int][]types.Package)
+	for _, p := range sb.Artifacts {
+		if p.Layer != nil {
+			packages[p.Layer.Ordinal] = append(packages[p.Layer.Ordinal], p)
+		}
+	}
+
+	var b strings.Builder
+	ref := img.Digest
+	if img.Name != "" {
+		ref = img.Name + "@" + img.Digest
+	}
+	fmt.Fprintf(&b, "# Reconstructed from the history of %s\n", ref)
+	if len(history) == 0 {
+		b.WriteString("# The image has no history\n")
+	}
+	if base := img.BaseImage; base != nil {
+		count := 0
+		for o, p := range packages {
+			if o < base.LayerCount {
+				count += len(p)
+			}
+		}
+		fmt.Fprintf(&b, "# %d packages from %d layers of the base image\n", count, base.LayerCount)
+		fmt.Fprintf(&b, "FROM %s@%s\n", base.Name, base.Digest)
+	} else {
+		b.WriteString("FROM scratch\n")
+	}
+	for _, h := range history {
+		if h.BaseImage || img.BaseImage != nil && h.Layer != nil && h.Layer.Ordinal < img.BaseImage.LayerCount {
+			continue
+		}
+		instruction := toInstruction(h.CreatedBy)
+		if instruction == "" {
+			continue
+		}
+		b.WriteString("\n")
+		if h.Layer != nil {
+			writeLayerPackages(&b, h.Layer.Ordinal, packages[h.Layer.Ordinal])
+		}
+		b.WriteString(instruction + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeLayerPackages(b *strings.Builder, ordinal int, packages []types.Package) {
+	if len(packages) == 0 {
+		fmt.Fprintf(b, "# layer %d\n", ordinal)
+		return
+	}
+	fmt.Fprintf(b, "# layer %d adds %d packages:\n", ordinal, len(packages))
+	for i, p := range packages {
+		if i == maxDockerfilePackages {
+			fmt.Fprintf(b, "#   and %d more\n", len(packages)-i)
+			break
+		}
+		fmt.Fprintf(b, "#   %s %s (%s)\n", packageName(p), p.Version, p.Type)
+	}
+}
+
+func packageName(p types.Package) string {
+	if p.Namespace != "" {
+		return p.Namespace + "/" + p.Name
+	}
+	return p.Name
+}
+
+// toInstruction turns the history entry createdBy of the classic builder or buildkit into a
+// Dockerfile instruction, or a comment if it was not created by a Dockerfile
+func toInstruction(createdBy string) string {
+	s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(createdBy), "# buildkit"))
+	if s == "" {
+		return ""
+	}
+	if m := buildArgsPattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		if fields := strings.SplitN(m[3], " ", n+1); len(fields) == n+1 {
+			s = fields[n]
+		}
+	}
+	if loc := nopPattern.FindStringIndex(s); loc != nil {
+		s = strings.TrimSpace(s[loc[1]:])
+		if m := copyPattern.FindStringSubmatch(s); m != nil {
+			return fmt.Sprintf("%s %s %s", m[1], m[2], strings.TrimSpace(m[3]))
+		}
+		return s
+	}
+	for _, prefix := range []string{"RUN /bin/sh -c ", "/bin/sh -c "} {
+		if strings.HasPrefix(s, prefix) {
+			return "RUN " + strings.TrimPrefix(s, prefix)
+		}
+	}
+	if keyword, _, _ := strings.Cut(s, " "); dockerfileInstructions[keyword] {
+		return s
+	}
+	return "# " + strings.ReplaceAll(s, "\n", "\n# ")
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestToInstruction(t *testing.T) {
+	for createdBy, expected := range map[string]string{
+		`/bin/sh -c #(nop) ADD file:7c4c8e4a12f7c8 in / `:         "ADD file:7c4c8e4a12f7c8 /",
+		`/bin/sh -c #(nop)  CMD ["/bin/sh"]`:                      `CMD ["/bin/sh"]`,
+		`/bin/sh -c apk add --no-cache curl`:                      "RUN apk add --no-cache curl",
+		`|2 VERSION=1.0 TOKEN=abc /bin/sh -c make install`:        "RUN make install",
+		`RUN /bin/sh -c npm ci # buildkit`:                        "RUN npm ci",
+		`RUN |1 VERSION=1.0 /bin/sh -c go build ./... # buildkit`: "RUN go build ./...",
+		`COPY . /app # buildkit`:                                  "COPY . /app",
+		`WORKDIR /app`:                                            "WORKDIR /app",
+		`apko`:                                                    "# apko",
+		``:                                                        "",
+	} {
+		if i := toInstruction(createdBy); i != expected {
+			t.Errorf("expected %q for %q, got %q", expected, createdBy, i)
+		}
+	}
+}
+
+func TestWriteDockerfile(t *testing.T) {
+	config := &v1.ConfigFile{
+		History: []v1.History{
+			{CreatedBy: "/bin/sh -c #(nop) ADD file:7c4c8e4a12f7c8 in / "},
+			{CreatedBy: `/bin/sh -c #(nop)  CMD ["/bin/sh"]`, EmptyLayer: true},
+			{CreatedBy: "WORKDIR /app", EmptyLayer: true},
+			{CreatedBy: "RUN /bin/sh -c apk add --no-cache nodejs # buildkit"},
+			{CreatedBy: "COPY . /app # buildkit"},
+		},
+	}
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{Name: "example", Digest: "sha256:abc", Config: config}},
+		Artifacts: []types.Package{
+			{Type: "apk", Name: "musl", Version: "1.2.3", Layer: &types.Layer{Ordinal: 0}},
+			{Type: "apk", Name: "nodejs", Version: "18.12.1", Layer: &types.Layer{Ordinal: 1}},
+		},
+	}
+
+	var b bytes.Buffer
+	if err := WriteDockerfile(sb, &b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# Reconstructed from the history of example@sha256:abc
+FROM scratch
+
+# layer 0 adds 1 packages:
+#   musl 1.2.3 (apk)
+ADD file:7c4c8e4a12f7c8 /
+
+CMD ["/bin/sh"]
+
+WORKDIR /app
+
+# layer 1 adds 1 packages:
+#   nodejs 18.12.1 (apk)
+RUN apk add --no-cache nodejs
+
+# layer 2
+COPY . /app
+`
+	if b.String() != expected {
+		t.Errorf("unexpected Dockerfile:\n%s", b.String())
+	}
+
+	sb.Source.Image.History = imageHistory(config, nil)
+	for i := range sb.Source.Image.History[:2] {
+		sb.Source.Image.History[i].BaseImage = true
+	}
+	sb.Source.Image.BaseImage = &types.BaseImage{Name: "alpine:3.17", Digest: "sha256:base", LayerCount: 1}
+	b.Reset()
+	if err := WriteDockerfile(sb, &b); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); !strings.Contains(s, "# 1 packages from 1 layers of the base image\nFROM alpine:3.17@sha256:base\n\nWORKDIR /app\n") || strings.Contains(s, "CMD") {
+		t.Errorf("expected steps of the base image to be omitted:\n%s", s)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	_ "embed"
+	"encoding/json"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// eolDates maps distros and their releases to the date their security support ends, taken
+// from https://endoflife.date
+//
+//go:embed eol.json
+var eolDates []byte
+
+var eol map[string]map[string]string
+
+func init() {
+	if err := json.Unmarshal(eolDates, &eol); err != nil {
+		panic(err)
+	}
+}
+
+// AnnotateEol records the end of life date of the distro of sb and if it passed at now.
+// Distros past their end of life get no security advisories, so their packages show no CVEs.
+func AnnotateEol(sb *types.Sbom, now time.Time) {
+	distro := &sb.Source.Image.Distro
+	distro.Eol, distro.EndOfLife = "", false
+	date, ok := eol[distro.OsName][distro.OsVersion]
+	if !ok {
+		return
+	}
+	distro.Eol = date
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		distro.EndOfLife = !now.Before(t)
+	}
+}
+
+// EndOfLife returns the sboms whose distro reached its end of life
+func EndOfLife(sboms []*types.Sbom) []*types.Sbom {
+	eol := make([]*types.Sbom, 0)
+	for _, sb := range sboms {
+		if sb.Source.Image.Distro.EndOfLife {
+			eol = append(eol, sb)
+		}
+	}
+	return eol
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestAnnotateEol(t *testing.T) {
+	now := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		distro    types.Distro
+		eol       string
+		endOfLife bool
+	}{
+		{distro: types.Distro{OsName: "debian", OsVersion: "9"}, eol: "2022-06-30", endOfLife: true},
+		{distro: types.Distro{OsName: "centos", OsVersion: "7"}, eol: "2024-06-30", endOfLife: true},
+		{distro: types.Distro{OsName: "alpine", OsVersion: "3.12"}, eol: "2022-05-01", endOfLife: true},
+		{distro: types.Distro{OsName: "alpine", OsVersion: "3.22"}, eol: "2027-05-01", endOfLife: false},
+		{distro: types.Distro{OsName: "wolfi", OsVersion: "rolling"}},
+	}
+	for _, test := range tests {
+		sb := &types.Sbom{}
+		sb.Source.Image.Distro = test.distro
+		AnnotateEol(sb, now)
+		if d := sb.Source.Image.Distro; d.Eol != test.eol || d.EndOfLife != test.endOfLife {
+			t.Errorf("%s %s: expected eol %q (%v), got %q (%v)", test.distro.OsName, test.distro.OsVersion, test.eol, test.endOfLife, d.Eol, d.EndOfLife)
+		}
+	}
+
+	eol := EndOfLife([]*types.Sbom{{}, {Source: types.Source{Image: types.ImageSource{Distro: types.Distro{EndOfLife: true}}}}})
+	if len(eol) != 1 {
+		t.Errorf("expected 1 sbom past end of life, got %d", len(eol))
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// Explanation tells where a package of an image came from and why its vulnerabilities matched
+type Explanation struct {
+	Package types.Package `json:"package"`
+	// Removed is set if a later layer deleted or replaced the package
+	Removed bool        `json:"removed,omitempty"`
+	Cves    []types.Cve `json:"vulnerabilities,omitempty"`
+}
+
+// Explain finds the packages of sb matching query, a purl, name, name@version or vulnerability
+// id, with their vulnerabilities; for a vulnerability id only that vulnerability is included
+func Explain(sb *types.Sbom, query string) ([]Explanation, error) {
+	cves := make(map[string][]types.Cve)
+	vulnerable := make(map[string]bool)
+	for _, c := range sb.Vulnerabilities {
+		if strings.EqualFold(c.SourceId, query) {
+			vulnerable[c.Purl] = true
+			cves[c.Purl] = append(cves[c.Purl], c)
+		}
+	}
+	if len(vulnerable) == 0 {
+		for _, c := range sb.Vulnerabilities {
+			cves[c.Purl] = append(cves[c.Purl], c)
+		}
+	}
+
+	explanations := make([]Explanation, 0)
+	add := func(packages []types.Package, removed bool) {
+		for _, p := range packages {
+			if vulnerable[p.Purl] || len(vulnerable) == 0 && matchesPackage(p, query) {
+				explanations = append(explanations, Explanation{Package: p, Removed: removed, Cves: cves[p.Purl]})
+			}
+		}
+	}
+	add(sb.Artifacts, false)
+	if len(vulnerable) == 0 {
+		add(sb.Removed, true)
+	}
+	if len(explanations) == 0 {
+		return nil, errors.Errorf("no package or vulnerability %s found in %s", query, sb.Source.Image.Name)
+	}
+	return explanations, nil
+}
+
+func matchesPackage(p types.Package, query string) bool {
+	name := p.Name
+	if p.Namespace != "" && strings.Contains(query, "/") {
+		name = p.Namespace + "/" + p.Name
+	}
+	return query == p.Purl || query == name || query == name+"@"+p.Version
+}
+
+// WriteExplanations writes the origin of the explained packages, the layer and the instruction
+// that introduced them, their files and catalogers, and the matched vulnerabilities
+func WriteExplanations(w io.Writer, explanations []Explanation) error {
+	var b strings.Builder
+	field := func(indent string, name string, value string) {
+		if value == "" {
+			return
+		}
+		if name != "" {
+			name += ":"
+		}
+		fmt.Fprintf(&b, "%s%-13s %s\n", indent, name, value)
+	}
+	for i, e := range explanations {
+		p := e.Package
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s\n", p.Purl)
+		field("  ", "Type", p.Type)
+		field("  ", "Version", p.Version)
+		if l := p.Layer; l != nil {
+			layer := fmt.Sprintf("%d %s", l.Ordinal, l.Digest)
+			if l.BaseImage {
+				layer += " (base image)"
+			}
+			field("  ", "Layer", layer)
+			field("  ", "Instruction", l.CreatedBy)
+		}
+		if l := p.RemovedBy; e.Removed && l != nil {
+			field("  ", "Removed by", fmt.Sprintf("layer %d %s", l.Ordinal, l.CreatedBy))
+		}
+		for j, loc := range p.Locations {
+			name := ""
+			if j == 0 {
+				name = "Locations"
+			}
+			field("  ", name, loc.Path)
+		}
+		field("  ", "Found by", strings.Join(p.FoundBy, ", "))
+		field("  ", "Parent", p.Parent)
+		for _, c := range e.Cves {
+			fmt.Fprintf(&b, "  %s %s\n", c.SourceId, Severity(c))
+			field("    ", "Source", c.Source)
+			if c.Advisory != nil && c.Advisory.Source != c.Source {
+				field("    ", "Advisory", c.Advisory.Source)
+			}
+			field("    ", "Affected", c.VulnerableRange)
+			fixedBy := c.FixedBy
+			if fixedBy == "" {
+				fixedBy = "not fixed"
+			}
+			field("    ", "Fixed by", fixedBy)
+			field("    ", "Status", c.Status)
+			field("    ", "URL", c.AdvisoryUrl)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func explainSbom() *types.Sbom {
+	return &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{Name: "example:latest"}},
+		Artifacts: []types.Package{{
+			Type:      "npm",
+			Name:      "lodash",
+			Version:   "4.17.20",
+			Purl:      "pkg:npm/lodash@4.17.20",
+			Locations: []types.Location{{Path: "/app/node_modules/lodash/package.json"}},
+			Layer:     &types.Layer{Ordinal: 2, Digest: "sha256:app", CreatedBy: "RUN npm install"},
+			FoundBy:   []string{"syft/javascript-package-cataloger", "trivy"},
+		}, {
+			Type:    "apk",
+			Name:    "musl",
+			Version: "1.2.3",
+			Purl:    "pkg:apk/alpine/musl@1.2.3",
+			Layer:   &types.Layer{Ordinal: 0, Digest: "sha256:base", CreatedBy: "ADD rootfs.tar /", BaseImage: true},
+		}},
+		Removed: []types.Package{{
+			Type:      "npm",
+			Name:      "lodash",
+			Version:   "4.17.19",
+			Purl:      "pkg:npm/lodash@4.17.19",
+			RemovedBy: &types.Layer{Ordinal: 2, CreatedBy: "RUN npm install"},
+		}},
+		Vulnerabilities: []types.Cve{
+			{Purl: "pkg:npm/lodash@4.17.20", SourceId: "CVE-2021-23337", Source: "github", VulnerableRange: "<4.17.21", FixedBy: "4.17.21"},
+			{Purl: "pkg:npm/lodash@4.17.20", SourceId: "CVE-2020-28500", Source: "github", VulnerableRange: "<4.17.21"},
+		},
+	}
+}
+
+func TestExplainPackage(t *testing.T) {
+	explanations, err := Explain(explainSbom(), "lodash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(explanations) != 2 || explanations[1].Package.Version != "4.17.19" || !explanations[1].Removed {
+		t.Fatalf("expected installed and removed lodash, got %+v", explanations)
+	}
+	if len(explanations[0].Cves) != 2 {
+		t.Errorf("expected 2 cves, got %d", len(explanations[0].Cves))
+	}
+
+	var b bytes.Buffer
+	if err := WriteExplanations(&b, explanations[:1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"Layer:        2 sha256:app",
+		"Instruction:  RUN npm install",
+		"Locations:    /app/node_modules/lodash/package.json",
+		"Found by:     syft/javascript-package-cataloger, trivy",
+		"CVE-2021-23337",
+		"Affected:     <4.17.21",
+		"Fixed by:     not fixed",
+	} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %q in:\n%s", s, b.String())
+		}
+	}
+}
+
+func TestExplainCve(t *testing.T) {
+	explanations, err := Explain(explainSbom(), "cve-2021-23337")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(explanations) != 1 || len(explanations[0].Cves) != 1 || explanations[0].Cves[0].SourceId != "CVE-2021-23337" {
+		t.Errorf("expected lodash with CVE-2021-23337 only, got %+v", explanations)
+	}
+	if _, err := Explain(explainSbom(), "pkg:npm/express@4.18.0"); err == nil {
+		t.Error("expected error for missing package")
+	}
+}
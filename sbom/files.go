@@ -0,0 +1,119 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// fileInventory records the regular files of the squashed filesystem of src with their size,
+// sha256 digest and the layer that wrote them
+func fileInventory(ctx context.Context, src *source.Source, lm types.LayerMapping) ([]types.File, error) {
+	resolver, err := src.FileResolver(source.SquashedScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create file resolver")
+	}
+	files := make([]types.File, 0)
+	// the locations are read to the end so that the resolver goroutine finishes on cancellation
+	for loc := range resolver.AllLocations() {
+		if ctx.Err() != nil {
+			continue
+		}
+		md, err := resolver.FileMetadataByLocation(loc)
+		if err != nil || md.Type != source.RegularFile {
+			continue
+		}
+		file := types.File{
+			Path: loc.RealPath,
+			Size: md.Size,
+		}
+		if digest, err := fileDigest(resolver, loc); err == nil {
+			file.Sha256 = digest
+		}
+		if ordinal, ok := lm.OrdinalByDiffId[loc.FileSystemID]; ok {
+			file.Layer = &types.Layer{
+				Ordinal: ordinal,
+				DiffId:  loc.FileSystemID,
+				Digest:  lm.ByDiffId[loc.FileSystemID],
+			}
+		}
+		files = append(files, file)
+	}
+	return files, ctx.Err()
+}
+
+func fileDigest(resolver source.FileResolver, loc source.Location) (string, error) {
+	r, err := resolver.FileContentsByLocation(loc)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close() //nolint:errcheck
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// fileOwners sets the package of the files installed by exactly one package, falling back to
+// the package found at the location of the file, like a jar or a binary
+func fileOwners(files []types.File, packages []types.Package) {
+	installed := make(map[string][]string)
+	found := make(map[string][]string)
+	for _, p := range packages {
+		for _, f := range p.Files {
+			installed[f.Path] = appendOwner(installed[f.Path], p.Purl)
+		}
+		for _, l := range p.Locations {
+			found[l.Path] = appendOwner(found[l.Path], p.Purl)
+		}
+	}
+	for i, f := range files {
+		owners, ok := installed[f.Path]
+		if !ok {
+			owners = found[f.Path]
+		}
+		if len(owners) == 1 {
+			files[i].Package = owners[0]
+		}
+	}
+}
+
+func appendOwner(owners []string, purl string) []string {
+	for _, o := range owners {
+		if o == purl {
+			return owners
+		}
+	}
+	return append(owners, purl)
+}
+
+// dropOsPackageFiles removes the files owned by apk, dpkg and rpm packages
+func dropOsPackageFiles(packages []types.Package) {
+	for i, p := range packages {
+		if p.Type == "alpine" || p.Type == "deb" || p.Type == "rpm" {
+			packages[i].Files = nil
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// pushLayers pushes an image with a layer of the files of every map to a test registry
+func pushLayers(t *testing.T, layers []map[string][]byte) string {
+	img := empty.Image
+	for _, files := range layers {
+		content := tarArchive(files)
+		layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		})
+		img, _ = mutate.AppendLayers(img, layer)
+	}
+	server := httptest.NewServer(ggcr.New())
+	t.Cleanup(server.Close)
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+func TestIndexFiles(t *testing.T) {
+	bash := []byte("#!bash")
+	layers := []map[string][]byte{
+		{
+			"etc/os-release":                 []byte("ID=debian\nVERSION_ID=\"11\"\n"),
+			"var/lib/dpkg/status":            []byte("Package: bash\nVersion: 5.1-2\nArchitecture: amd64\nStatus: install ok installed\n"),
+			"var/lib/dpkg/info/bash.md5sums": []byte("d41d8cd98f00b204e9800998ecf8427e  bin/bash\n"),
+			"bin/bash":                       bash,
+			"tmp/installer.sh":               []byte("curl | sh"),
+		},
+		{
+			"etc/motd":             []byte("hello"),
+			"tmp/.wh.installer.sh": nil,
+		},
+	}
+	image := pushLayers(t, layers)
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerOs), WithFiles()).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]types.File)
+	for _, f := range sb.Files {
+		files[f.Path] = f
+	}
+	if _, ok := files["/tmp/installer.sh"]; ok {
+		t.Error("expected deleted file to be missing")
+	}
+	f, ok := files["/bin/bash"]
+	if !ok {
+		t.Fatalf("expected /bin/bash in %+v", sb.Files)
+	}
+	if f.Size != int64(len(bash)) || f.Sha256 != fmt.Sprintf("%x", sha256.Sum256(bash)) {
+		t.Errorf("unexpected size or digest of /bin/bash: %+v", f)
+	}
+	if !strings.HasPrefix(f.Package, "pkg:deb/debian/bash@5.1-2") {
+		t.Errorf("expected /bin/bash to be owned by bash, got %s", f.Package)
+	}
+	if f.Layer == nil || f.Layer.Ordinal != 0 {
+		t.Errorf("expected /bin/bash in layer 0, got %+v", f.Layer)
+	}
+	if f := files["/etc/motd"]; f.Layer == nil || f.Layer.Ordinal != 1 || f.Package != "" {
+		t.Errorf("expected unowned /etc/motd in layer 1, got %+v", f)
+	}
+}
+
+func TestIndexPackageFiles(t *testing.T) {
+	image := pushLayers(t, []map[string][]byte{{
+		"etc/os-release":                    []byte("ID=debian\nVERSION_ID=\"11\"\n"),
+		"var/lib/dpkg/status":               []byte("Package: openssl\nVersion: 1.1.1n-0+deb11u3\nArchitecture: amd64\nStatus: install ok installed\n"),
+		"var/lib/dpkg/info/openssl.md5sums": []byte("d41d8cd98f00b204e9800998ecf8427e  usr/lib/libssl.so.1.1\n"),
+		"usr/lib/libssl.so.1.1":             []byte("libssl"),
+	}})
+
+	for _, packageFiles := range []bool{false, true} {
+		opts := []Option{WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerOs)}
+		if packageFiles {
+			opts = append(opts, WithPackageFiles())
+		}
+		sb, _, err := NewIndexer(opts...).Index(context.Background(), image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sb.Artifacts) != 1 {
+			t.Fatalf("expected openssl, got %+v", sb.Artifacts)
+		}
+		files := sb.Artifacts[0].Files
+		if !packageFiles && len(files) > 0 {
+			t.Errorf("expected no package files by default, got %+v", files)
+		}
+		if packageFiles && (len(files) != 1 || files[0].Path != "/usr/lib/libssl.so.1.1") {
+			t.Errorf("expected /usr/lib/libssl.so.1.1 owned by openssl, got %+v", files)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+var severities = map[string]int{
+	"CRITICAL":    4,
+	"HIGH":        3,
+	"MEDIUM":      2,
+	"LOW":         1,
+	"UNSPECIFIED": 0,
+}
+
+// ParseSeverity validates and normalizes a severity name like critical or high
+func ParseSeverity(severity string) (string, error) {
+	s := strings.ToUpper(strings.TrimSpace(severity))
+	if _, ok := severities[s]; !ok {
+		return "", errors.Errorf("unknown severity: %s", severity)
+	}
+	return s, nil
+}
+
+// Severity returns the normalized severity of the vulnerability
+func Severity(cve types.Cve) string {
+	severity := toSeverity(cve)
+	if _, ok := severities[severity]; !ok {
+		return "UNSPECIFIED"
+	}
+	return severity
+}
+
+// FilterCvesBySeverity returns the vulnerabilities matching one of the given severities
+func FilterCvesBySeverity(cves []types.Cve, include []string) ([]types.Cve, error) {
+	if len(include) == 0 {
+		return cves, nil
+	}
+	allowed := make(map[string]bool)
+	for _, s := range include {
+		severity, err := ParseSeverity(s)
+		if err != nil {
+			return nil, err
+		}
+		allowed[severity] = true
+	}
+	filtered := make([]types.Cve, 0)
+	for _, c := range cves {
+		if allowed[Severity(c)] {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// CvesAboveThreshold returns the vulnerabilities with the given severity or higher,
+// ignoring suppressed ones and those a VEX statement declared as not affected or fixed
+func CvesAboveThreshold(cves []types.Cve, threshold string) ([]types.Cve, error) {
+	severity, err := ParseSeverity(threshold)
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]types.Cve, 0)
+	for _, c := range cves {
+		if IsAffected(c) && c.Suppression == nil && severities[Severity(c)] >= severities[severity] {
+			matched = append(matched, c)
+		}
+	}
+	return matched, nil
+}
+
+// IsFixed returns true if the query backend reported a version fixing the vulnerability
+func IsFixed(cve types.Cve) bool {
+	return cve.FixedBy != "" && cve.FixedBy != "not fixed"
+}
+
+// FilterFixedCves returns the vulnerabilities for which a fixed version is known
+func FilterFixedCves(cves []types.Cve) []types.Cve {
+	fixed := make([]types.Cve, 0)
+	for _, c := range cves {
+		if IsFixed(c) {
+			fixed = append(fixed, c)
+		}
+	}
+	return fixed
+}
+
+// FilterCvesByEpss returns the vulnerabilities with an EPSS score of at least min
+func FilterCvesByEpss(cves []types.Cve, min float64) []types.Cve {
+	filtered := make([]types.Cve, 0)
+	for _, c := range cves {
+		if c.Epss != nil && c.Epss.Score >= min {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// FilterKnownExploitedCves returns the vulnerabilities listed in the CISA KEV catalog
+func FilterKnownExploitedCves(cves []types.Cve) []types.Cve {
+	filtered := make([]types.Cve, 0)
+	for _, c := range cves {
+		if c.KnownExploited {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// SortCves orders the vulnerabilities by severity, epss or kev with the most pressing first
+func SortCves(cves []types.Cve, by string) error {
+	epss := func(c types.Cve) float64 {
+		if c.Epss == nil {
+			return -1
+		}
+		return c.Epss.Score
+	}
+	var less func(a, b types.Cve) bool
+	switch by {
+	case "", "severity":
+		less = func(a, b types.Cve) bool {
+			return severities[Severity(a)] > severities[Severity(b)]
+		}
+	case "epss":
+		less = func(a, b types.Cve) bool {
+			return epss(a) > epss(b)
+		}
+	case "kev":
+		less = func(a, b types.Cve) bool {
+			if a.KnownExploited != b.KnownExploited {
+				return a.KnownExploited
+			}
+			return epss(a) > epss(b)
+		}
+	default:
+		return errors.Errorf("unsupported sort order: %s", by)
+	}
+	sort.SliceStable(cves, func(i, j int) bool {
+		return less(cves[i], cves[j])
+	})
+	return nil
+}
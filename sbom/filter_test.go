@@ -0,0 +1,88 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func cveWithSeverity(id string, severity string) types.Cve {
+	return types.Cve{
+		SourceId: id,
+		Advisory: &types.Advisory{
+			References: []types.Reference{{
+				Source: "atomist",
+				Scores: []types.Score{{Type: "atm_severity", Value: severity}},
+			}},
+		},
+	}
+}
+
+func TestFilterCvesBySeverity(t *testing.T) {
+	cves := []types.Cve{
+		cveWithSeverity("CVE-1", "CRITICAL"),
+		cveWithSeverity("CVE-2", "HIGH"),
+		cveWithSeverity("CVE-3", "LOW"),
+		{SourceId: "CVE-4"},
+	}
+	filtered, err := FilterCvesBySeverity(cves, []string{"critical", "high"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 2 {
+		t.Errorf("expected 2 cves, got %d", len(filtered))
+	}
+	if _, err = FilterCvesBySeverity(cves, []string{"severe"}); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+
+	above, _ := CvesAboveThreshold(cves, "high")
+	if len(above) != 2 {
+		t.Errorf("expected 2 cves above threshold, got %d", len(above))
+	}
+	above, _ = CvesAboveThreshold(cves, "unspecified")
+	if len(above) != 4 {
+		t.Errorf("expected 4 cves above threshold, got %d", len(above))
+	}
+}
+
+func TestSortCves(t *testing.T) {
+	cves := []types.Cve{
+		{SourceId: "CVE-1", Epss: &types.Epss{Score: 0.1}},
+		{SourceId: "CVE-2"},
+		{SourceId: "CVE-3", Epss: &types.Epss{Score: 0.9}},
+		{SourceId: "CVE-4", Epss: &types.Epss{Score: 0.01}, KnownExploited: true},
+	}
+	if err := SortCves(cves, "epss"); err != nil {
+		t.Fatal(err)
+	}
+	if cves[0].SourceId != "CVE-3" || cves[3].SourceId != "CVE-2" {
+		t.Errorf("unexpected epss order: %s, %s", cves[0].SourceId, cves[3].SourceId)
+	}
+	_ = SortCves(cves, "kev")
+	if cves[0].SourceId != "CVE-4" || cves[1].SourceId != "CVE-3" {
+		t.Errorf("unexpected kev order: %s, %s", cves[0].SourceId, cves[1].SourceId)
+	}
+	if len(FilterCvesByEpss(cves, 0.1)) != 2 {
+		t.Error("expected 2 cves with epss of at least 0.1")
+	}
+	if err := SortCves(cves, "name"); err == nil {
+		t.Error("expected error for unsupported sort order")
+	}
+}
@@ -0,0 +1,295 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/formats/syftjson/model"
+	"github.com/anchore/syft/syft/linux"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatSPDXJSON = "spdx-json"
+	FormatCdxJSON  = "cyclonedx-json"
+	FormatSyftJSON = "syft-json"
+	FormatSARIF    = "sarif"
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatTemplate = "template"
+	FormatJUnit    = "junit"
+	FormatCSV      = "csv"
+	FormatCvesCSV  = "cves-csv"
+	FormatGitHub   = "github-snapshot"
+	FormatGitLab   = "gitlab"
+)
+
+type FormatWriter = func(sb *types.Sbom, w io.Writer) error
+
+var formatWriters = map[string]FormatWriter{
+	FormatJSON:     WriteJSON,
+	FormatSPDXJSON: WriteSPDX,
+	FormatCdxJSON:  WriteCycloneDX,
+	FormatSyftJSON: WriteSyftJSON,
+	FormatSARIF:    WriteSARIF,
+	FormatHTML:     WriteHTML,
+	FormatMarkdown: WriteMarkdown,
+	FormatJUnit:    WriteJUnit,
+	FormatGitHub:   WriteGitHubSnapshot,
+	FormatGitLab:   WriteGitLab,
+}
+
+// mediaTypes are the artifact media types of the output formats when pushed to a registry
+var mediaTypes = map[string]string{
+	FormatJSON:     "application/vnd.docker.index.sbom.v1+json",
+	FormatSPDXJSON: "application/spdx+json",
+	FormatCdxJSON:  "application/vnd.cyclonedx+json",
+	FormatSyftJSON: "application/vnd.syft+json",
+	FormatSARIF:    "application/sarif+json",
+	FormatHTML:     "text/html",
+	FormatMarkdown: "text/markdown",
+	FormatTemplate: "text/plain",
+	FormatJUnit:    "application/xml",
+	FormatCSV:      "text/csv",
+	FormatCvesCSV:  "text/csv",
+	FormatGitHub:   "application/json",
+	FormatGitLab:   "application/json",
+}
+
+// fileNames are the names of the output formats when stored in a bucket
+var fileNames = map[string]string{
+	FormatJSON:     "sbom.json",
+	FormatSPDXJSON: "sbom.spdx.json",
+	FormatCdxJSON:  "sbom.cdx.json",
+	FormatSyftJSON: "sbom.syft.json",
+	FormatSARIF:    "report.sarif",
+	FormatHTML:     "report.html",
+	FormatMarkdown: "report.md",
+	FormatTemplate: "report.txt",
+	FormatJUnit:    "junit.xml",
+	FormatCSV:      "packages.csv",
+	FormatCvesCSV:  "cves.csv",
+	FormatGitHub:   "github-snapshot.json",
+	FormatGitLab:   "gl-container-scanning-report.json",
+}
+
+// FileName returns the file name of the output format
+func FileName(format string) string {
+	if format == "" {
+		format = FormatJSON
+	}
+	return fileNames[format]
+}
+
+// MediaType returns the media type of the output format
+func MediaType(format string) string {
+	if format == "" {
+		format = FormatJSON
+	}
+	return mediaTypes[format]
+}
+
+// WriteOption configures the output formats of WriteFormat
+type WriteOption func(*writeSettings)
+
+// writeSettings are the settings of the template and csv formats
+type writeSettings struct {
+	template   *template.Template
+	csvColumns []string
+}
+
+// WithTemplate renders the template format with t, see ParseTemplate
+func WithTemplate(t *template.Template) WriteOption {
+	return func(s *writeSettings) {
+		s.template = t
+	}
+}
+
+// WithCsvColumns selects the columns written by the csv formats; columns only known to one of
+// them are skipped by the other, see ValidateCsvColumns
+func WithCsvColumns(columns ...string) WriteOption {
+	return func(s *writeSettings) {
+		s.csvColumns = columns
+	}
+}
+
+// WriteFormat writes the sbom in the requested output format to w
+func WriteFormat(sb *types.Sbom, format string, w io.Writer, opts ...WriteOption) error {
+	if format == "" {
+		format = FormatJSON
+	}
+	var s writeSettings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	switch format {
+	case FormatTemplate:
+		return WriteTemplate(sb, s.template, w)
+	case FormatCSV:
+		return WritePackagesCSV(sb, s.csvColumns, w)
+	case FormatCvesCSV:
+		return WriteCvesCSV(sb, s.csvColumns, w)
+	}
+	writer, ok := formatWriters[format]
+	if !ok {
+		return errors.Errorf("unsupported output format: %s", format)
+	}
+	return writer(sb, w)
+}
+
+// WriteJSON writes the sbom in its native JSON format to w
+func WriteJSON(sb *types.Sbom, w io.Writer) error {
+	js, err := json.MarshalIndent(sb, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(js, '\n'))
+	return err
+}
+
+// ReadSboms reads the sbom at path with the default settings, see Indexer.ReadSboms
+func ReadSboms(path string) ([]*types.Sbom, error) {
+	return NewIndexer().ReadSboms(path)
+}
+
+// ReadSboms reads the sbom at path. Besides the native JSON format, including the combined
+// document of a multi-platform image written with --all-platforms, SPDX, CycloneDX and syft
+// JSON documents are converted into sboms with the purl qualifiers and CPEs of the indexer.
+func (i *Indexer) ReadSboms(path string) ([]*types.Sbom, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read sbom %s", path)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse sbom %s", path)
+	}
+
+	var sb *types.Sbom
+	switch {
+	case fields["spdxVersion"] != nil:
+		var doc SpdxDocument
+		if err = json.Unmarshal(b, &doc); err == nil {
+			sb, err = i.fromSPDX(doc)
+		}
+	case fields["bomFormat"] != nil:
+		var doc CdxDocument
+		if err = json.Unmarshal(b, &doc); err == nil {
+			sb, err = i.fromCycloneDX(doc)
+		}
+	case fields["schema"] != nil && fields["artifactRelationships"] != nil:
+		var doc model.Document
+		if err = json.Unmarshal(b, &doc); err == nil {
+			sb, err = i.fromSyftJSON(doc)
+		}
+	case fields["source"] != nil:
+		sb = &types.Sbom{}
+		err = json.Unmarshal(b, sb)
+	default:
+		var platforms map[string]*types.Sbom
+		if err := json.Unmarshal(b, &platforms); err != nil || len(platforms) == 0 {
+			return nil, errors.Errorf("failed to parse sbom %s: unsupported format", path)
+		}
+		keys := make([]string, 0)
+		for k := range platforms {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sboms := make([]*types.Sbom, 0)
+		for _, k := range keys {
+			sboms = append(sboms, platforms[k])
+		}
+		return sboms, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse sbom %s", path)
+	}
+	return []*types.Sbom{sb}, nil
+}
+
+// convertedSbom creates the sbom of image from packages read from another sbom format
+func (i *Indexer) convertedSbom(image types.ImageSource, pkgs []types.Package) (*types.Sbom, error) {
+	withOsQualifiers(&image, pkgs)
+	pkgs, err := types.NormalizePackages(pkgs, i.purlQualifiers...)
+	if err != nil {
+		return nil, err
+	}
+	i.addCpes(pkgs)
+	return &types.Sbom{
+		Source: types.Source{
+			Type:  "image",
+			Image: image,
+		},
+		Artifacts: pkgs,
+		Descriptor: types.Descriptor{
+			Name:        "docker index",
+			Version:     internal.FromBuild().Version,
+			SbomVersion: internal.FromBuild().SbomVersion,
+		},
+	}, nil
+}
+
+// withOsQualifiers adds the os qualifiers of the image distro to the purls of operating system
+// packages. Without a known distro they are derived from the distro qualifier other tools write.
+func withOsQualifiers(image *types.ImageSource, pkgs []types.Package) {
+	for i, p := range pkgs {
+		purl, err := packageurl.FromString(p.Purl)
+		if err != nil {
+			continue
+		}
+		// newer syft versions write alpine packages with the apk type
+		if purl.Type == "apk" {
+			purl.Type = "alpine"
+		}
+		if purl.Type != "deb" && purl.Type != "rpm" && purl.Type != "alpine" {
+			continue
+		}
+		q := purl.Qualifiers.Map()
+		if q["os_name"] == "" {
+			release := &linux.Release{ID: image.Distro.OsName, VersionID: image.Distro.OsVersion, VersionCodename: image.Distro.OsDistro}
+			if release.ID == "" {
+				name, version, ok := strings.Cut(q["distro"], "-")
+				if !ok {
+					continue
+				}
+				release = &linux.Release{ID: name, VersionID: version}
+			}
+			distro, qualifiers := osQualifiers(release)
+			if image.Distro.OsName == "" {
+				image.Distro = distro
+			}
+			// merge into the qualifiers written by the other tool, e.g. arch, epoch and upstream
+			for k, v := range qualifiers {
+				if q[k] == "" {
+					q[k] = v
+				}
+			}
+			purl.Qualifiers = packageurl.QualifiersFromMap(q)
+		}
+		pkgs[i].Purl = purl.String()
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestReadSbomsConverted(t *testing.T) {
+	sb := &types.Sbom{
+		Source: types.Source{
+			Image: types.ImageSource{
+				Name:     "index.docker.io/library/alpine",
+				Digest:   "sha256:1234",
+				Platform: types.Platform{Os: "linux", Architecture: "arm64", Variant: "v8"},
+			},
+		},
+		Artifacts: []types.Package{{
+			Purl:      "pkg:alpine/busybox@1.35.0-r17?os_name=alpine&os_version=3.16",
+			Licenses:  []string{"GPL-2.0-only"},
+			Locations: []types.Location{{Path: "/lib/apk/db/installed", DiffId: "sha256:a", Digest: "sha256:b"}},
+			Parent:    "pkg:alpine/busybox-src@1.35.0-r17?os_name=alpine&os_version=3.16",
+		}, {
+			Purl:      "pkg:alpine/busybox-src@1.35.0-r17?os_name=alpine&os_version=3.16",
+			Locations: []types.Location{{Path: "/lib/apk/db/installed", DiffId: "sha256:a", Digest: "sha256:b"}},
+		}},
+	}
+
+	for _, format := range []string{FormatSPDXJSON, FormatCdxJSON, FormatSyftJSON} {
+		path := filepath.Join(t.TempDir(), "sbom.json")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFormat(sb, format, f); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		sboms, err := ReadSboms(path)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(sboms) != 1 {
+			t.Fatalf("%s: expected 1 sbom, got %d", format, len(sboms))
+		}
+		converted := sboms[0]
+		if converted.Source.Image.Name != "index.docker.io/library/alpine" || converted.Source.Image.Digest != "sha256:1234" {
+			t.Errorf("%s: unexpected image %s@%s", format, converted.Source.Image.Name, converted.Source.Image.Digest)
+		}
+		if len(converted.Artifacts) != 2 {
+			t.Fatalf("%s: expected 2 packages, got %d", format, len(converted.Artifacts))
+		}
+		p := converted.Artifacts[0]
+		if p.Name != "busybox" || p.Version != "1.35.0-r17" || p.Type != "alpine" {
+			t.Errorf("%s: unexpected package %s", format, p.Purl)
+		}
+		if p.Parent != sb.Artifacts[0].Parent {
+			t.Errorf("%s: unexpected parent %s", format, p.Parent)
+		}
+		if len(p.Locations) != 1 || p.Locations[0].Path != "/lib/apk/db/installed" || p.Locations[0].DiffId != "sha256:a" {
+			t.Errorf("%s: unexpected locations %v", format, p.Locations)
+		}
+	}
+}
+
+func TestWithOsQualifiers(t *testing.T) {
+	tests := []struct {
+		image    types.ImageSource
+		purl     string
+		expected string
+	}{
+		{
+			image:    types.ImageSource{Distro: types.Distro{OsName: "redhatlinux", OsVersion: "8"}},
+			purl:     "pkg:rpm/rhel/openssl@1.1.1k-7.el8?arch=x86_64&epoch=1&upstream=openssl-1.1.1k-7.el8.src.rpm",
+			expected: "pkg:rpm/rhel/openssl@1.1.1k-7.el8?arch=x86_64&epoch=1&os_name=redhatlinux&os_version=8&upstream=openssl-1.1.1k-7.el8.src.rpm",
+		},
+		{
+			purl:     "pkg:deb/debian/libc6@2.31-13?arch=amd64&distro=debian-11&upstream=glibc",
+			expected: "pkg:deb/debian/libc6@2.31-13?arch=amd64&distro=debian-11&os_name=debian&os_version=11&upstream=glibc",
+		},
+		{
+			image:    types.ImageSource{Distro: types.Distro{OsName: "debian", OsVersion: "11"}},
+			purl:     "pkg:deb/debian/libc6@2.31-13?arch=amd64&os_name=debian&os_version=10",
+			expected: "pkg:deb/debian/libc6@2.31-13?arch=amd64&os_name=debian&os_version=10",
+		},
+	}
+	for _, test := range tests {
+		pkgs := []types.Package{{Purl: test.purl}}
+		withOsQualifiers(&test.image, pkgs)
+		if pkgs[0].Purl != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, pkgs[0].Purl)
+		}
+	}
+}
+
+func TestFromSPDXExternal(t *testing.T) {
+	doc := SpdxDocument{
+		SpdxVersion: "SPDX-2.3",
+		SpdxId:      "SPDXRef-DOCUMENT",
+		Packages: []SpdxPackage{{
+			SpdxId:      "SPDXRef-DocumentRoot-Image-alpine",
+			Name:        "alpine:3.16",
+			VersionInfo: "sha256:1234",
+		}, {
+			SpdxId:          "SPDXRef-Package-apk-busybox",
+			Name:            "busybox",
+			VersionInfo:     "1.35.0-r17",
+			LicenseDeclared: "GPL-2.0-only",
+			ExternalRefs: []SpdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  "pkg:apk/alpine/busybox@1.35.0-r17?arch=x86_64&distro=alpine-3.16.2",
+			}},
+		}},
+		Relationships: []SpdxRelationship{{
+			SpdxElementId:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSpdxElement: "SPDXRef-DocumentRoot-Image-alpine",
+		}},
+	}
+	sb, err := FromSPDX(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.Source.Image.Name != "alpine:3.16" || sb.Source.Image.Digest != "sha256:1234" {
+		t.Errorf("unexpected image %s@%s", sb.Source.Image.Name, sb.Source.Image.Digest)
+	}
+	if len(sb.Artifacts) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(sb.Artifacts))
+	}
+	if purl := sb.Artifacts[0].Purl; purl != "pkg:alpine/alpine/busybox@1.35.0-r17?os_name=alpine&os_version=3.16" {
+		t.Errorf("unexpected purl %s", purl)
+	}
+	if sb.Source.Image.Distro.OsName != "alpine" || sb.Source.Image.Distro.OsVersion != "3.16" {
+		t.Errorf("unexpected distro %v", sb.Source.Image.Distro)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+const defaultGitHubApiUrl = "https://api.github.com"
+
+// GitHubSnapshot is a snapshot of the GitHub dependency submission API, see
+// https://docs.github.com/en/rest/dependency-graph/dependency-submission
+type GitHubSnapshot struct {
+	Version   int                       `json:"version"`
+	Sha       string                    `json:"sha"`
+	Ref       string                    `json:"ref"`
+	Job       GitHubJob                 `json:"job"`
+	Detector  GitHubDetector            `json:"detector"`
+	Scanned   string                    `json:"scanned"`
+	Manifests map[string]GitHubManifest `json:"manifests"`
+}
+
+type GitHubJob struct {
+	Correlator string `json:"correlator"`
+	Id         string `json:"id"`
+	HtmlUrl    string `json:"html_url,omitempty"`
+}
+
+type GitHubDetector struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Url     string `json:"url"`
+}
+
+type GitHubManifest struct {
+	Name     string                      `json:"name"`
+	Metadata map[string]string           `json:"metadata,omitempty"`
+	Resolved map[string]GitHubDependency `json:"resolved"`
+}
+
+type GitHubDependency struct {
+	PackageUrl   string `json:"package_url"`
+	Relationship string `json:"relationship"`
+	Scope        string `json:"scope"`
+}
+
+// WriteGitHubSnapshot writes the packages of the sbom as GitHub dependency snapshot to w
+func WriteGitHubSnapshot(sb *types.Sbom, w io.Writer) error {
+	js, err := json.MarshalIndent(ToGitHubSnapshot(sb, time.Now()), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(js, '\n'))
+	return err
+}
+
+// ToGitHubSnapshot converts the packages of the sbom into a GitHub dependency snapshot. The
+// commit, ref and job are taken from the GitHub Actions environment, falling back to the
+// org.opencontainers.image.revision label for the commit. The correlator includes the image and
+// platform so that snapshots of several images built by one job don't replace each other.
+func ToGitHubSnapshot(sb *types.Sbom, scanned time.Time) GitHubSnapshot {
+	image := toImageName(sb)
+	platform := sb.Source.Image.Platform.String()
+	sha := os.Getenv("GITHUB_SHA")
+	if c := sb.Source.Image.Config; sha == "" && c != nil {
+		sha = parseSha(c)
+	}
+	job := GitHubJob{
+		Correlator: strings.Join([]string{os.Getenv("GITHUB_WORKFLOW"), os.Getenv("GITHUB_JOB"), image, platform}, "_"),
+		Id:         os.Getenv("GITHUB_RUN_ID"),
+	}
+	if job.Id != "" && os.Getenv("GITHUB_REPOSITORY") != "" {
+		job.HtmlUrl = fmt.Sprintf("%s/%s/actions/runs/%s", githubServerUrl(), os.Getenv("GITHUB_REPOSITORY"), job.Id)
+	}
+
+	manifest := GitHubManifest{
+		Name:     image,
+		Metadata: map[string]string{"digest": sb.Source.Image.Digest, "platform": platform},
+		Resolved: make(map[string]GitHubDependency),
+	}
+	if base := sb.Source.Image.BaseImage; base != nil {
+		manifest.Metadata["base_image"] = base.Name
+	}
+	for _, p := range sb.Artifacts {
+		relationship := "direct"
+		if p.Parent != "" {
+			relationship = "indirect"
+		}
+		manifest.Resolved[p.Purl] = GitHubDependency{
+			PackageUrl:   p.Purl,
+			Relationship: relationship,
+			Scope:        "runtime",
+		}
+	}
+	return GitHubSnapshot{
+		Version: 0,
+		Sha:     sha,
+		Ref:     os.Getenv("GITHUB_REF"),
+		Job:     job,
+		Detector: GitHubDetector{
+			Name:    sb.Descriptor.Name,
+			Version: sb.Descriptor.Version,
+			Url:     "https://github.com/docker/index-cli-plugin",
+		},
+		Scanned:   scanned.UTC().Format(time.RFC3339),
+		Manifests: map[string]GitHubManifest{image: manifest},
+	}
+}
+
+// SubmitGitHubSnapshot submits the snapshot to the dependency graph of repository, given as
+// owner/name, so that its packages show up in the dependency graph and Dependabot alerts
+func SubmitGitHubSnapshot(ctx context.Context, snapshot GitHubSnapshot, repository string, token string) error {
+	if repository == "" || token == "" {
+		return errors.New("submitting a dependency snapshot requires GITHUB_REPOSITORY and GITHUB_TOKEN")
+	}
+	if snapshot.Sha == "" || snapshot.Ref == "" {
+		return errors.New("submitting a dependency snapshot requires GITHUB_SHA and GITHUB_REF")
+	}
+	js, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	apiUrl := os.Getenv("GITHUB_API_URL")
+	if apiUrl == "" {
+		apiUrl = defaultGitHubApiUrl
+	}
+	url := fmt.Sprintf("%s/repos/%s/dependency-graph/snapshots", strings.TrimSuffix(apiUrl, "/"), repository)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	resp, err := internal.HttpClient().Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to submit dependency snapshot")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("failed to submit dependency snapshot: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func githubServerUrl() string {
+	if url := os.Getenv("GITHUB_SERVER_URL"); url != "" {
+		return url
+	}
+	return "https://github.com"
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestGitHubSnapshot(t *testing.T) {
+	t.Setenv("GITHUB_SHA", "")
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	t.Setenv("GITHUB_WORKFLOW", "build")
+	t.Setenv("GITHUB_JOB", "image")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_REPOSITORY", "acme/app")
+	t.Setenv("GITHUB_SERVER_URL", "")
+
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{
+			Name:     "ghcr.io/acme/app",
+			Digest:   "sha256:image",
+			Platform: types.Platform{Os: "linux", Architecture: "amd64"},
+			Config: &v1.ConfigFile{Config: v1.Config{Labels: map[string]string{
+				"org.opencontainers.image.revision": "0123456789abcdef0123456789abcdef01234567",
+			}}},
+		}},
+		Descriptor: types.Descriptor{Name: "docker index", Version: "v1.0.0"},
+		Artifacts: []types.Package{
+			{Purl: "pkg:npm/express@4.18.2"},
+			{Purl: "pkg:npm/qs@6.11.0", Parent: "pkg:npm/express@4.18.2"},
+		},
+	}
+	snapshot := ToGitHubSnapshot(sb, time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+	if snapshot.Sha != "0123456789abcdef0123456789abcdef01234567" || snapshot.Ref != "refs/heads/main" || snapshot.Scanned != "2026-10-15T12:00:00Z" {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+	if snapshot.Job.Correlator != "build_image_ghcr.io/acme/app_linux/amd64" || snapshot.Job.HtmlUrl != "https://github.com/acme/app/actions/runs/42" {
+		t.Errorf("unexpected job %+v", snapshot.Job)
+	}
+	manifest := snapshot.Manifests["ghcr.io/acme/app"]
+	if manifest.Resolved["pkg:npm/express@4.18.2"].Relationship != "direct" || manifest.Resolved["pkg:npm/qs@6.11.0"].Relationship != "indirect" {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+
+	var submitted GitHubSnapshot
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/app/dependency-graph/snapshots" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&submitted)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+	t.Setenv("GITHUB_API_URL", ts.URL)
+	if err := SubmitGitHubSnapshot(context.Background(), snapshot, "acme/app", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if len(submitted.Manifests["ghcr.io/acme/app"].Resolved) != 2 {
+		t.Errorf("expected submitted snapshot with 2 packages, got %+v", submitted)
+	}
+	if err := SubmitGitHubSnapshot(context.Background(), snapshot, "acme/other", "token"); err == nil {
+		t.Error("expected error for failed submission")
+	}
+	if err := SubmitGitHubSnapshot(context.Background(), snapshot, "acme/app", ""); err == nil {
+		t.Error("expected error without token")
+	}
+}
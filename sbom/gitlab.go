@@ -0,0 +1,216 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/uuid"
+)
+
+const gitlabSchemaVersion = "15.0.6"
+
+// gitlabNamespace derives stable vulnerability ids, so GitLab tracks the same finding across scans
+var gitlabNamespace = uuid.MustParse("7f3b1c7e-1d5a-4c1e-9b0e-4a8e6f0b2d11")
+
+// GitLabReport is a GitLab container scanning security report, see
+// https://docs.gitlab.com/ee/development/integrations/secure.html#report
+type GitLabReport struct {
+	Version         string                `json:"version"`
+	Scan            GitLabScan            `json:"scan"`
+	Vulnerabilities []GitLabVulnerability `json:"vulnerabilities"`
+	Remediations    []interface{}         `json:"remediations"`
+}
+
+type GitLabScan struct {
+	Analyzer  GitLabTool `json:"analyzer"`
+	Scanner   GitLabTool `json:"scanner"`
+	Type      string     `json:"type"`
+	StartTime string     `json:"start_time"`
+	EndTime   string     `json:"end_time"`
+	Status    string     `json:"status"`
+}
+
+type GitLabTool struct {
+	Id      string       `json:"id"`
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Vendor  GitLabVendor `json:"vendor"`
+}
+
+type GitLabVendor struct {
+	Name string `json:"name"`
+}
+
+type GitLabVulnerability struct {
+	Id          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Severity    string             `json:"severity"`
+	Solution    string             `json:"solution,omitempty"`
+	Identifiers []GitLabIdentifier `json:"identifiers"`
+	Links       []GitLabLink       `json:"links,omitempty"`
+	Location    GitLabLocation     `json:"location"`
+}
+
+type GitLabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Url   string `json:"url,omitempty"`
+}
+
+type GitLabLink struct {
+	Url string `json:"url"`
+}
+
+type GitLabLocation struct {
+	Dependency      GitLabDependency `json:"dependency"`
+	OperatingSystem string           `json:"operating_system"`
+	Image           string           `json:"image"`
+}
+
+type GitLabDependency struct {
+	Package GitLabPackage `json:"package"`
+	Version string        `json:"version"`
+}
+
+type GitLabPackage struct {
+	Name string `json:"name"`
+}
+
+// WriteGitLab writes the vulnerabilities of the sbom as GitLab container scanning report to w
+func WriteGitLab(sb *types.Sbom, w io.Writer) error {
+	now := time.Now()
+	js, err := json.MarshalIndent(ToGitLab(sb, now, now), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(js, '\n'))
+	return err
+}
+
+// ToGitLab converts the vulnerabilities of the sbom into a GitLab container scanning report.
+// Suppressed vulnerabilities and those a VEX statement declared as not affected are left out.
+func ToGitLab(sb *types.Sbom, start, end time.Time) GitLabReport {
+	tool := GitLabTool{
+		Id:      "docker-index",
+		Name:    sb.Descriptor.Name,
+		Version: sb.Descriptor.Version,
+		Vendor:  GitLabVendor{Name: "Docker"},
+	}
+	report := GitLabReport{
+		Version: gitlabSchemaVersion,
+		Scan: GitLabScan{
+			Analyzer:  tool,
+			Scanner:   tool,
+			Type:      "container_scanning",
+			StartTime: start.UTC().Format("2006-01-02T15:04:05"),
+			EndTime:   end.UTC().Format("2006-01-02T15:04:05"),
+			Status:    "success",
+		},
+		Vulnerabilities: make([]GitLabVulnerability, 0),
+		Remediations:    make([]interface{}, 0),
+	}
+
+	image := sb.Source.Image.Name
+	if tags := sb.Source.Image.Tags; tags != nil && len(*tags) > 0 {
+		image += ":" + (*tags)[0]
+	} else if sb.Source.Image.Digest != "" {
+		image += "@" + sb.Source.Image.Digest
+	}
+	operatingSystem := "unknown"
+	if d := sb.Source.Image.Distro; d.OsName != "" {
+		operatingSystem = d.OsName + ":" + d.OsVersion
+	}
+	for _, c := range sb.Vulnerabilities {
+		if !IsAffected(c) || c.Suppression != nil {
+			continue
+		}
+		p, _ := findPackage(sb, c.Purl)
+		name, version := p.Name, p.Version
+		if name == "" {
+			if purl, err := types.ToPackageUrl(c.Purl); err == nil {
+				name, version = purl.Name, purl.Version
+			}
+		}
+		v := GitLabVulnerability{
+			Id:       uuid.NewSHA1(gitlabNamespace, []byte(image+"|"+c.SourceId+"|"+c.Purl)).String(),
+			Name:     fmt.Sprintf("%s in %s", c.SourceId, name),
+			Severity: toGitLabSeverity(Severity(c)),
+			Identifiers: []GitLabIdentifier{{
+				Type:  toGitLabIdentifierType(c.SourceId),
+				Name:  c.SourceId,
+				Value: c.SourceId,
+			}},
+			Location: GitLabLocation{
+				Dependency: GitLabDependency{
+					Package: GitLabPackage{Name: name},
+					Version: version,
+				},
+				OperatingSystem: operatingSystem,
+				Image:           image,
+			},
+		}
+		for _, adv := range []*types.Advisory{c.Cve, c.Advisory} {
+			if adv == nil {
+				continue
+			}
+			if v.Description == "" {
+				v.Description = adv.Description
+			}
+			for _, u := range adv.Urls {
+				if u.Value != "" {
+					v.Links = append(v.Links, GitLabLink{Url: u.Value})
+				}
+			}
+		}
+		if len(v.Links) > 0 {
+			v.Identifiers[0].Url = v.Links[0].Url
+		}
+		if IsFixed(c) {
+			v.Solution = fmt.Sprintf("Upgrade %s to %s", name, c.FixedBy)
+		}
+		report.Vulnerabilities = append(report.Vulnerabilities, v)
+	}
+	return report
+}
+
+func toGitLabSeverity(severity string) string {
+	switch severity {
+	case "CRITICAL", "HIGH", "MEDIUM", "LOW":
+		return strings.ToUpper(severity[:1]) + strings.ToLower(severity[1:])
+	default:
+		return "Unknown"
+	}
+}
+
+func toGitLabIdentifierType(id string) string {
+	switch {
+	case strings.HasPrefix(id, "CVE-"):
+		return "cve"
+	case strings.HasPrefix(id, "GHSA-"):
+		return "ghsa"
+	default:
+		return strings.ToLower(strings.SplitN(id, "-", 2)[0])
+	}
+}
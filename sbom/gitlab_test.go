@@ -0,0 +1,72 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestToGitLab(t *testing.T) {
+	cve := cveWithSeverity("CVE-2022-0001", "CRITICAL")
+	cve.Purl, cve.FixedBy = "pkg:deb/debian/openssl@1.1.1n?os_name=debian&os_version=11", "1.1.1o"
+	cve.Advisory.Description = "buffer overflow"
+	cve.Advisory.Urls = []types.Url{{Name: "nvd", Value: "https://nvd.nist.gov/vuln/detail/CVE-2022-0001"}}
+	ghsa := cveWithSeverity("GHSA-abcd-efgh-ijkl", "")
+	ghsa.Purl = "pkg:npm/lodash@4.17.20"
+	suppressed := cveWithSeverity("CVE-2022-0002", "HIGH")
+	suppressed.Purl = "pkg:npm/lodash@4.17.20"
+	suppressed.Suppression = &types.Suppression{}
+	tags := []string{"1.2"}
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{
+			Name:   "registry.gitlab.com/acme/app",
+			Tags:   &tags,
+			Distro: types.Distro{OsName: "debian", OsVersion: "11"},
+		}},
+		Descriptor: types.Descriptor{Name: "docker index", Version: "v1.0.0"},
+		Artifacts: []types.Package{
+			{Name: "openssl", Version: "1.1.1n", Purl: "pkg:deb/debian/openssl@1.1.1n?os_name=debian&os_version=11"},
+		},
+		Vulnerabilities: []types.Cve{cve, ghsa, suppressed},
+	}
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	report := ToGitLab(sb, now, now)
+	if report.Scan.Type != "container_scanning" || report.Scan.StartTime != "2026-10-15T12:00:00" {
+		t.Errorf("unexpected scan %+v", report.Scan)
+	}
+	if len(report.Vulnerabilities) != 2 {
+		t.Fatalf("expected 2 vulnerabilities, got %+v", report.Vulnerabilities)
+	}
+	v := report.Vulnerabilities[0]
+	if v.Name != "CVE-2022-0001 in openssl" || v.Severity != "Critical" || v.Solution != "Upgrade openssl to 1.1.1o" ||
+		v.Description != "buffer overflow" || v.Identifiers[0].Type != "cve" || v.Identifiers[0].Url != "https://nvd.nist.gov/vuln/detail/CVE-2022-0001" {
+		t.Errorf("unexpected vulnerability %+v", v)
+	}
+	if l := v.Location; l.Image != "registry.gitlab.com/acme/app:1.2" || l.OperatingSystem != "debian:11" || l.Dependency.Package.Name != "openssl" || l.Dependency.Version != "1.1.1n" {
+		t.Errorf("unexpected location %+v", l)
+	}
+	v = report.Vulnerabilities[1]
+	if v.Severity != "Unknown" || v.Identifiers[0].Type != "ghsa" || v.Location.Dependency.Package.Name != "lodash" || v.Location.Dependency.Version != "4.17.20" {
+		t.Errorf("unexpected vulnerability %+v", v)
+	}
+	if again := ToGitLab(sb, now, now); again.Vulnerabilities[0].Id != report.Vulnerabilities[0].Id {
+		t.Error("expected stable vulnerability ids")
+	}
+}
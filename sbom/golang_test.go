@@ -0,0 +1,93 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/anchore/syft/syft/artifact"
+	pkg2 "github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestGoBinaryVcs(t *testing.T) {
+	location := source.NewLocation("/usr/local/bin/app")
+	md := pkg2.GolangBinMetadata{
+		GoCompiledVersion: "go1.19.2",
+		Architecture:      "amd64",
+		MainModule:        "github.com/acme/app",
+		BuildSettings: map[string]string{
+			"vcs":          "git",
+			"vcs.revision": "41bc6bb410352845f22766e27dd48ba93aa825a4",
+			"vcs.time":     "2022-10-14T12:00:00Z",
+			"vcs.modified": "true",
+		},
+	}
+	main := pkg2.Package{
+		Name:         "github.com/acme/app",
+		Version:      "v0.0.0-20221014120000-41bc6bb41035",
+		PURL:         "pkg:golang/github.com/acme/app@v0.0.0-20221014120000-41bc6bb41035",
+		Locations:    source.NewLocationSet(location),
+		MetadataType: pkg2.GolangBinMetadataType,
+		Metadata:     md,
+	}
+	lm := types.LayerMapping{ByDiffId: map[string]string{}}
+
+	pkgs := toPackage(main, []artifact.Relationship{}, map[string]string{}, lm, packageMapping{})
+	if len(pkgs) != 2 {
+		t.Fatalf("expected main module and stdlib, got %d packages", len(pkgs))
+	}
+	pkg := pkgs[0]
+	if pkg.Purl != main.PURL {
+		t.Errorf("unexpected purl %s", pkg.Purl)
+	}
+	if pkg.Vcs == nil {
+		t.Fatal("expected vcs of main module")
+	}
+	if pkg.Vcs.Type != "git" || pkg.Vcs.Revision != "41bc6bb410352845f22766e27dd48ba93aa825a4" || pkg.Vcs.Time != "2022-10-14T12:00:00Z" || !pkg.Vcs.Modified {
+		t.Errorf("unexpected vcs %+v", pkg.Vcs)
+	}
+	if pkgs[1].Purl != "pkg:golang/stdlib@1.19.2" || pkgs[1].Vcs != nil {
+		t.Errorf("unexpected stdlib package %+v", pkgs[1])
+	}
+
+	// dependencies don't carry build settings
+	md.BuildSettings = nil
+	dep := pkg2.Package{
+		Name:         "github.com/pkg/errors",
+		Version:      "v0.9.1",
+		PURL:         "pkg:golang/github.com/pkg/errors@v0.9.1",
+		Locations:    source.NewLocationSet(location),
+		MetadataType: pkg2.GolangBinMetadataType,
+		Metadata:     md,
+	}
+	pkgs = toPackage(dep, []artifact.Relationship{}, map[string]string{}, lm, packageMapping{})
+	if pkgs[0].Vcs != nil {
+		t.Errorf("unexpected vcs %+v", pkgs[0].Vcs)
+	}
+
+	doc := ToCycloneDX(&types.Sbom{Artifacts: []types.Package{pkg}})
+	sb, err := FromCycloneDX(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := sb.Artifacts[0].Vcs; v == nil || v.Type != "git" || v.Revision != pkg.Vcs.Revision {
+		t.Errorf("expected vcs to survive cyclonedx, got %+v", v)
+	}
+}
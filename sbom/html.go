@@ -0,0 +1,182 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// htmlTemplate is a standalone report without external scripts or stylesheets, so it can be
+// shared as single file and opened offline
+//
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"lower": strings.ToLower,
+}).Parse(htmlTemplate))
+
+type htmlReport struct {
+	Sbom       *types.Sbom
+	Severities []htmlSeverity
+	Packages   []htmlPackage
+	Layers     []htmlLayer
+	Cves       []htmlCve
+}
+
+type htmlSeverity struct {
+	Name    string
+	Count   int
+	Percent int
+}
+
+type htmlPackage struct {
+	types.Package
+	Cves     int
+	Severity string
+}
+
+type htmlLayer struct {
+	Ordinal   int
+	Digest    string
+	CreatedBy string
+	BaseImage bool
+	Packages  int
+	Cves      int
+	Percent   int
+}
+
+type htmlCve struct {
+	types.Cve
+	Severity    string
+	Description string
+	Url         string
+	Layer       *types.Layer
+	New         bool
+}
+
+// WriteHTML writes a standalone HTML report of the sbom to w with a summary of the vulnerabilities
+// by severity, a searchable package table, the packages and vulnerabilities per layer and the
+// details of every vulnerability
+func WriteHTML(sb *types.Sbom, w io.Writer) error {
+	return htmlReportTemplate.Execute(w, toHTMLReport(sb))
+}
+
+func toHTMLReport(sb *types.Sbom) htmlReport {
+	report := htmlReport{Sbom: sb}
+	packages := make(map[string]*htmlPackage)
+	for _, p := range sb.Artifacts {
+		report.Packages = append(report.Packages, htmlPackage{Package: p})
+	}
+	for i := range report.Packages {
+		packages[report.Packages[i].Purl] = &report.Packages[i]
+	}
+
+	layers := make(map[int]*htmlLayer)
+	layer := func(ordinal int) *htmlLayer {
+		if l, ok := layers[ordinal]; ok {
+			return l
+		}
+		l := &htmlLayer{Ordinal: ordinal}
+		layers[ordinal] = l
+		return l
+	}
+	createdBy := LayerCreatedBy(sb.Source.Image.Config)
+	if m := sb.Source.Image.Manifest; m != nil {
+		for i, d := range m.Layers {
+			l := layer(i)
+			l.Digest = d.Digest.String()
+			l.CreatedBy = createdBy[i]
+			l.BaseImage = sb.Source.Image.BaseImage != nil && i < sb.Source.Image.BaseImage.LayerCount
+		}
+	}
+	for _, p := range sb.Artifacts {
+		if p.Layer != nil {
+			l := layer(p.Layer.Ordinal)
+			l.Packages++
+			l.BaseImage = l.BaseImage || p.Layer.BaseImage
+			if l.Digest == "" {
+				l.Digest, l.CreatedBy = p.Layer.Digest, p.Layer.CreatedBy
+			}
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, c := range sb.Vulnerabilities {
+		if !IsAffected(c) || c.Suppression != nil {
+			continue
+		}
+		cve := htmlCve{Cve: c, Severity: Severity(c), New: IsNew(sb, c)}
+		for _, adv := range []*types.Advisory{c.Cve, c.Advisory} {
+			if adv == nil {
+				continue
+			}
+			if cve.Description == "" {
+				cve.Description = adv.Description
+			}
+			if cve.Url == "" && len(adv.Urls) > 0 {
+				cve.Url = adv.Urls[0].Value
+			}
+		}
+		if p, ok := packages[c.Purl]; ok {
+			p.Cves++
+			if p.Severity == "" || severities[cve.Severity] > severities[p.Severity] {
+				p.Severity = cve.Severity
+			}
+			if p.Layer != nil {
+				cve.Layer = p.Layer
+				layer(p.Layer.Ordinal).Cves++
+			}
+		}
+		counts[cve.Severity]++
+		report.Cves = append(report.Cves, cve)
+	}
+	sort.SliceStable(report.Cves, func(i, j int) bool {
+		return severities[report.Cves[i].Severity] > severities[report.Cves[j].Severity]
+	})
+
+	for _, s := range []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNSPECIFIED"} {
+		severity := htmlSeverity{Name: s, Count: counts[s]}
+		if len(report.Cves) > 0 {
+			severity.Percent = 100 * counts[s] / len(report.Cves)
+		}
+		report.Severities = append(report.Severities, severity)
+	}
+
+	max := 0
+	for _, l := range layers {
+		report.Layers = append(report.Layers, *l)
+		if l.Packages > max {
+			max = l.Packages
+		}
+	}
+	sort.Slice(report.Layers, func(i, j int) bool {
+		return report.Layers[i].Ordinal < report.Layers[j].Ordinal
+	})
+	for i := range report.Layers {
+		if max > 0 {
+			report.Layers[i].Percent = 100 * report.Layers[i].Packages / max
+		}
+	}
+	return report
+}
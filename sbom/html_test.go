@@ -0,0 +1,83 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestWriteHTML(t *testing.T) {
+	base := &types.Layer{Ordinal: 0, Digest: "sha256:base", BaseImage: true, CreatedBy: "ADD rootfs.tar /"}
+	app := &types.Layer{Ordinal: 1, Digest: "sha256:app", CreatedBy: "RUN npm install <script>"}
+	critical := cveWithSeverity("CVE-2022-0001", "CRITICAL")
+	critical.Purl = "pkg:deb/debian/openssl@1.1.1n"
+	critical.FixedBy = "1.1.1o"
+	low := cveWithSeverity("CVE-2022-0002", "LOW")
+	low.Purl = "pkg:npm/lodash@4.17.20"
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{
+			Name:   "registry.example.com/app",
+			Digest: "sha256:image",
+			Manifest: &v1.Manifest{Layers: []v1.Descriptor{
+				{Digest: v1.Hash{Algorithm: "sha256", Hex: "base"}},
+				{Digest: v1.Hash{Algorithm: "sha256", Hex: "app"}},
+			}},
+			Config:   &v1.ConfigFile{History: []v1.History{{CreatedBy: "ADD rootfs.tar /"}, {CreatedBy: "RUN npm install <script>"}}},
+			Platform: types.Platform{Os: "linux", Architecture: "amd64"},
+		}},
+		Artifacts: []types.Package{
+			{Type: "deb", Namespace: "debian", Name: "openssl", Version: "1.1.1n", Purl: "pkg:deb/debian/openssl@1.1.1n", Layer: base},
+			{Type: "npm", Name: "lodash", Version: "4.17.20", Purl: "pkg:npm/lodash@4.17.20", Licenses: []string{"MIT"}, Layer: app},
+			{Type: "npm", Name: "express", Version: "4.18.2", Purl: "pkg:npm/express@4.18.2", Layer: app},
+		},
+		Vulnerabilities: []types.Cve{low, critical},
+	}
+
+	report := toHTMLReport(sb)
+	if len(report.Cves) != 2 || report.Cves[0].SourceId != "CVE-2022-0001" {
+		t.Errorf("expected critical vulnerability first, got %v", report.Cves)
+	}
+	if len(report.Layers) != 2 || report.Layers[0].Packages != 1 || report.Layers[0].Cves != 1 || !report.Layers[0].BaseImage ||
+		report.Layers[1].Packages != 2 || report.Layers[1].Percent != 100 || report.Layers[1].Cves != 1 {
+		t.Errorf("unexpected layers %v", report.Layers)
+	}
+	if p := report.Packages[0]; p.Cves != 1 || p.Severity != "CRITICAL" {
+		t.Errorf("expected openssl to have a critical vulnerability, got %v", p)
+	}
+	if s := report.Severities[0]; s.Name != "CRITICAL" || s.Count != 1 || s.Percent != 50 {
+		t.Errorf("unexpected critical summary %v", s)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFormat(sb, FormatHTML, &buf); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, s := range []string{"<!DOCTYPE html>", "registry.example.com/app", "linux/amd64", "CVE-2022-0001", "fixed in 1.1.1o", "lodash", "RUN npm install &lt;script&gt;"} {
+		if !strings.Contains(html, s) {
+			t.Errorf("expected report to contain %q", s)
+		}
+	}
+	if strings.Contains(html, "<script>\"") || strings.Contains(html, "install <script>") {
+		t.Error("expected created by to be escaped")
+	}
+}
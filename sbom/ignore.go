@@ -0,0 +1,100 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultIgnoreFile = ".docker-index-ignore.yaml"
+
+type IgnoreFile struct {
+	Ignore []IgnoreRule `yaml:"ignore"`
+}
+
+// IgnoreRule suppresses a vulnerability, optionally only for packages matching Purl,
+// until the end of the Expires date
+type IgnoreRule struct {
+	Id            string `yaml:"id"`
+	Purl          string `yaml:"purl,omitempty"`
+	Justification string `yaml:"justification"`
+	Expires       string `yaml:"expires"`
+}
+
+// ReadIgnoreFile reads and validates the suppression rules in the ignore file at path
+func ReadIgnoreFile(path string) (*IgnoreFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read ignore file %s", path)
+	}
+	var file IgnoreFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse ignore file %s", path)
+	}
+	for i, r := range file.Ignore {
+		if r.Id == "" {
+			return nil, errors.Errorf("ignore rule %d in %s is missing id", i, path)
+		}
+		if strings.TrimSpace(r.Justification) == "" {
+			return nil, errors.Errorf("ignore rule for %s in %s is missing justification", r.Id, path)
+		}
+		if _, err := time.Parse("2006-01-02", r.Expires); err != nil {
+			return nil, errors.Errorf("ignore rule for %s in %s requires expires date in YYYY-MM-DD format", r.Id, path)
+		}
+	}
+	return &file, nil
+}
+
+// Expired returns true if the rule is no longer valid at the given time
+func (r IgnoreRule) Expired(now time.Time) bool {
+	expires, err := time.Parse("2006-01-02", r.Expires)
+	if err != nil {
+		return true
+	}
+	return !now.UTC().Before(expires.AddDate(0, 0, 1))
+}
+
+// ApplyIgnoreFile marks vulnerabilities matched by a rule of the ignore file as suppressed.
+// Expired rules are reported and no longer suppress anything.
+func ApplyIgnoreFile(cves []types.Cve, file *IgnoreFile, now time.Time) []types.Cve {
+	if file == nil {
+		return cves
+	}
+	for _, r := range file.Ignore {
+		if r.Expired(now) {
+			log.Warnf("Ignore rule for %s expired on %s", r.Id, r.Expires)
+			continue
+		}
+		for i, c := range cves {
+			if !strings.EqualFold(r.Id, c.SourceId) || (r.Purl != "" && !matchesPurl(r.Purl, c.Purl)) {
+				continue
+			}
+			cves[i].Suppression = &types.Suppression{
+				Justification: r.Justification,
+				Expires:       r.Expires,
+			}
+		}
+	}
+	return cves
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestApplyIgnoreFile(t *testing.T) {
+	file := &IgnoreFile{Ignore: []IgnoreRule{
+		{Id: "CVE-2022-0001", Purl: "pkg:deb/debian/openssl", Justification: "not reachable", Expires: "2022-12-31"},
+		{Id: "CVE-2022-0002", Justification: "accepted risk", Expires: "2022-06-30"},
+	}}
+	now := time.Date(2022, 12, 31, 12, 0, 0, 0, time.UTC)
+	cves := ApplyIgnoreFile([]types.Cve{
+		{SourceId: "CVE-2022-0001", Purl: "pkg:deb/debian/openssl@1.1.1n"},
+		{SourceId: "CVE-2022-0001", Purl: "pkg:deb/debian/libssl@1.1.1n"},
+		{SourceId: "CVE-2022-0002", Purl: "pkg:npm/lodash@4.17.20"},
+	}, file, now)
+
+	if cves[0].Suppression == nil || cves[0].Suppression.Justification != "not reachable" {
+		t.Error("expected openssl CVE to be suppressed")
+	}
+	if cves[1].Suppression != nil {
+		t.Error("expected libssl CVE not to be suppressed")
+	}
+	if cves[2].Suppression != nil {
+		t.Error("expected expired rule not to suppress lodash CVE")
+	}
+}
@@ -17,113 +17,347 @@
 package sbom
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
+	"time"
 
-	"github.com/atomist-skills/go-skill"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-units"
 	"github.com/docker/index-cli-plugin/internal"
-	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/metrics"
+	"github.com/docker/index-cli-plugin/progress"
 	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom/secrets"
+	"github.com/docker/index-cli-plugin/tracing"
 	"github.com/docker/index-cli-plugin/types"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/pkg/errors"
 )
 
+// defaultSecretScanner scans the layers for secrets with the default rules in the same pass
+// packages are cataloged
+var defaultSecretScanner, _ = secrets.NewScanner(nil)
+
 type ImageIndexResult struct {
 	Input string
 	Image *v1.Image
 	Sbom  *types.Sbom
-	Error error
+	// IndexError is set if the image could not be indexed, Sbom is nil then
+	IndexError error
+	// QueryError is set if the CVEs of the indexed image could not be queried. Sbom is
+	// returned without vulnerabilities then and must not be mistaken for a clean image.
+	QueryError error
 }
 
-func indexImageAsync(wg *sync.WaitGroup, image string, client client.APIClient, resultChan chan<- ImageIndexResult) {
-	defer wg.Done()
-	sbom, img, err := IndexImage(image, client)
-	cves, err := query.QueryCves(sbom, "", "", "")
-	if err == nil {
-		sbom.Vulnerabilities = *cves
+// Err returns the index or query error of the result
+func (r ImageIndexResult) Err() error {
+	if r.IndexError != nil {
+		return errors.Wrapf(r.IndexError, "failed to index image %s", r.Input)
 	}
-	resultChan <- ImageIndexResult{
-		Input: image,
-		Image: img,
-		Sbom:  sbom,
-		Error: err,
+	if r.QueryError != nil {
+		return errors.Wrapf(r.QueryError, "failed to query CVEs of image %s", r.Input)
 	}
+	return nil
+}
+
+// Partial reports if the image was indexed but its CVEs could not be queried
+func (r ImageIndexResult) Partial() bool {
+	return r.IndexError == nil && r.QueryError != nil
 }
 
 func IndexPath(path string, name string) (*types.Sbom, *v1.Image, error) {
-	skill.Log.Infof("Loading image from %s", path)
-	img, err := registry.ReadImage(path)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to read image")
-	}
-	skill.Log.Infof("Loaded image")
-	return indexImage(img, name, path)
+	return IndexPathContext(context.Background(), path, name)
+}
+
+// IndexPathContext is IndexPath with a context to cancel indexing
+func IndexPathContext(ctx context.Context, path string, name string) (*types.Sbom, *v1.Image, error) {
+	return NewIndexer().IndexPath(ctx, path, name)
+}
+
+// IndexOCILayout indexes an image from an OCI image layout directory. ref selects
+// the image by its ref name annotation if the layout contains more than one image.
+func IndexOCILayout(path string, ref string) (*types.Sbom, *v1.Image, error) {
+	return IndexOCILayoutContext(context.Background(), path, ref)
+}
+
+// IndexOCILayoutContext is IndexOCILayout with a context to cancel indexing
+func IndexOCILayoutContext(ctx context.Context, path string, ref string) (*types.Sbom, *v1.Image, error) {
+	return NewIndexer().IndexOCILayout(ctx, path, ref)
+}
+
+// IndexArchive indexes an image from a tarball written by `docker save`, `podman save`
+// or in oci-archive format, without loading it into a Docker daemon.
+func IndexArchive(path string, ref string) (*types.Sbom, *v1.Image, error) {
+	return IndexArchiveContext(context.Background(), path, ref)
+}
+
+// IndexArchiveContext is IndexArchive with a context to cancel indexing
+func IndexArchiveContext(ctx context.Context, path string, ref string) (*types.Sbom, *v1.Image, error) {
+	return NewIndexer().IndexArchive(ctx, path, ref)
 }
 
 func IndexImage(image string, client client.APIClient) (*types.Sbom, *v1.Image, error) {
-	skill.Log.Infof("Copying image %s", image)
-	img, path, err := registry.SaveImage(image, client)
+	return IndexPlatformImage(image, "", client)
+}
+
+// IndexImageContext is IndexImage with a context to cancel pulling and indexing
+func IndexImageContext(ctx context.Context, image string, client client.APIClient) (*types.Sbom, *v1.Image, error) {
+	return IndexPlatformImageContext(ctx, image, "", client)
+}
+
+// IndexPlatformImage indexes the image for the given platform (e.g. linux/arm64)
+func IndexPlatformImage(image string, platform string, client client.APIClient) (*types.Sbom, *v1.Image, error) {
+	return IndexPlatformImageContext(context.Background(), image, platform, client)
+}
+
+// IndexPlatformImageContext is IndexPlatformImage with a context to cancel pulling and indexing
+func IndexPlatformImageContext(ctx context.Context, image string, platform string, client client.APIClient) (*types.Sbom, *v1.Image, error) {
+	return NewIndexer(WithPlatform(platform), WithClient(client)).Index(ctx, image)
+}
+
+func IndexRemoteImage(image string, platform string) (*types.Sbom, *v1.Image, error) {
+	return IndexRemoteImageContext(context.Background(), image, platform)
+}
+
+// IndexRemoteImageContext is IndexRemoteImage with a context to cancel pulling and indexing
+func IndexRemoteImageContext(ctx context.Context, image string, platform string) (*types.Sbom, *v1.Image, error) {
+	return NewIndexer(WithPlatform(platform), WithRemote()).Index(ctx, image)
+}
+
+// IndexAllPlatforms indexes every platform image of the image index image refers to
+// and returns one result per platform
+func IndexAllPlatforms(image string) ([]ImageIndexResult, error) {
+	return IndexAllPlatformsContext(context.Background(), image)
+}
+
+// IndexAllPlatformsContext is IndexAllPlatforms with a context to cancel pulling and indexing
+func IndexAllPlatformsContext(ctx context.Context, image string) ([]ImageIndexResult, error) {
+	return NewIndexer().IndexAllPlatforms(ctx, image)
+}
+
+// imageInput is the image handed to the catalogers: the OCI layout at path or, if path is
+// empty, the remote image whose layers are analyzed while they are downloaded, see
+// registry.SharedLayers
+type imageInput struct {
+	path     string
+	image    v1.Image
+	name     string
+	digest   string
+	progress progress.Reporter
+}
+
+// normalizeResults normalizes the packages of the cataloger results, recording the cataloger
+// that found them, and returns the normalized packages removed from the image
+func normalizeResults(ctx context.Context, digest string, syftResult, trivyResult *types.IndexResult, customResults []types.IndexResult, qualifiers []string) (_ []types.Package, err error) {
+	_, span := tracing.Start(ctx, "normalize", tracing.Digest(digest))
+	defer func() {
+		tracing.End(span, err)
+	}()
+	foundBy := func(r *types.IndexResult) {
+		for j, p := range r.Packages {
+			if len(p.FoundBy) == 0 {
+				r.Packages[j].FoundBy = []string{r.Name}
+			}
+		}
+	}
+	foundBy(syftResult)
+	foundBy(trivyResult)
+	for j := range customResults {
+		foundBy(&customResults[j])
+	}
+	if trivyResult.Packages, err = types.NormalizePackages(trivyResult.Packages, qualifiers...); err != nil {
+		return nil, err
+	}
+	if syftResult.Packages, err = types.NormalizePackages(syftResult.Packages, qualifiers...); err != nil {
+		return nil, err
+	}
+	removed, err := types.NormalizePackages(append(syftResult.Removed, trivyResult.Removed...), qualifiers...)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to download image")
+		return nil, err
+	}
+	for j, r := range customResults {
+		if customResults[j].Packages, err = types.NormalizePackages(r.Packages, qualifiers...); err != nil {
+			return nil, err
+		}
 	}
-	skill.Log.Infof("Copied image")
-	return indexImage(img, image, path)
+	return removed, nil
 }
 
-func indexImage(img v1.Image, imageName, path string) (*types.Sbom, *v1.Image, error) {
+// indexImage catalogs img stored at path, or streamed from the registry if path is empty.
+// Only sboms of images stored at path are cached.
+func (i *Indexer) indexImage(ctx context.Context, img v1.Image, imageName, path string) (_ *types.Sbom, _ *v1.Image, err error) {
+	digest, _ := img.Digest()
+	ctx, span := tracing.Start(ctx, "index", tracing.Image(imageName), tracing.Digest(digest.String()))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	// see if we can re-use an existing sbom
 	sbomPath := filepath.Join(path, "sbom.json")
-	if _, ok := os.LookupEnv("ATOMIST_NO_CACHE"); !ok {
-		if _, err := os.Stat(sbomPath); !os.IsNotExist(err) {
-			var sbom types.Sbom
-			b, err := os.ReadFile(sbomPath)
-			if err == nil {
-				err := json.Unmarshal(b, &sbom)
-				if err == nil {
-					if sbom.Descriptor.SbomVersion == internal.FromBuild().SbomVersion && sbom.Descriptor.Version == internal.FromBuild().Version {
-						skill.Log.Infof(`Indexed %d packages`, len(sbom.Artifacts))
-						return &sbom, &img, nil
-					}
-				}
+	if path != "" && !i.noCache && i.defaultSbom() {
+		if sbom, ok := readCachedSbom(sbomPath); ok {
+			metrics.ObserveCache(metrics.CacheSbom, true)
+			i.logger.Infof(`Indexed %d packages`, len(sbom.Artifacts))
+			return i.finish(sbom), &img, nil
+		}
+		metrics.ObserveCache(metrics.CacheSbom, false)
+	}
+
+	if i.reuseAttestations && i.defaultSbom() && imageName != "" {
+		if sbom := i.attestedSbom(ctx, imageName, digest.String()); sbom != nil {
+			i.logger.Infof(`Loaded %d packages from SBOM attestation`, len(sbom.Artifacts))
+			if js, err := json.MarshalIndent(sbom, "", "  "); err == nil && path != "" {
+				_ = os.WriteFile(sbomPath, js, 0644)
 			}
+			return i.finish(sbom), &img, nil
 		}
 	}
 
 	lm := createLayerMapping(img)
-	skill.Log.Debugf("Created layer mapping")
+	i.logger.Debugf("Created layer mapping")
 
-	skill.Log.Info("Indexing")
-	trivyResultChan := make(chan types.IndexResult)
-	syftResultChan := make(chan types.IndexResult)
-	go trivySbom(path, lm, trivyResultChan)
-	go syftSbom(path, lm, syftResultChan)
+	i.logger.Infof("Indexing")
+	input := imageInput{path: path, image: registry.WithoutForeignLayers(img), name: imageName, digest: digest.String(), progress: i.progress}
+	// buffered so the catalogers can finish and clean up after a cancelled index returned
+	trivyResultChan := make(chan types.IndexResult, 1)
+	syftResultChan := make(chan types.IndexResult, 1)
+	if i.runs(CatalogerTrivy) {
+		go trivySbom(ctx, input, lm, filepath.Join(i.cache.Dir, "trivy"), i.disabledAnalyzers(), trivyResultChan)
+	} else {
+		trivyResultChan <- types.IndexResult{Name: CatalogerTrivy, Status: types.Success}
+	}
+	if i.runs(CatalogerSyft) {
+		go syftSbom(ctx, input, lm, i.syftCataloger, i.secretScanner, i.files, i.leftovers, i.waste, syftResultChan)
+	} else {
+		syftResultChan <- types.IndexResult{Name: CatalogerSyft, Status: types.Success}
+	}
 
-	trivyResult := <-trivyResultChan
-	syftResult := <-syftResultChan
+	custom := i.customCatalogers()
+	customResultChan := make(chan []types.IndexResult, 1)
+	if len(custom) > 0 {
+		go customSbom(ctx, input, lm, custom, customResultChan)
+	} else {
+		customResultChan <- nil
+	}
 
-	var err error
-	trivyResult.Packages, err = types.NormalizePackages(trivyResult.Packages)
-	syftResult.Packages, err = types.NormalizePackages(syftResult.Packages)
+	trivyResult, err := awaitResult(ctx, trivyResultChan)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "indexing %s cancelled", imageName)
+	}
+	syftResult, err := awaitResult(ctx, syftResultChan)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "indexing %s cancelled", imageName)
+	}
+	var customResults []types.IndexResult
+	select {
+	case customResults = <-customResultChan:
+	case <-ctx.Done():
+	}
+	// catalogers report cancellation as failure, which must not be mistaken for a partial sbom
+	if err := ctx.Err(); err != nil {
+		return nil, nil, errors.Wrapf(err, "indexing %s cancelled", imageName)
+	}
+
+	catalogers := make([]types.CatalogerStatus, 0)
+	failed := make([]string, 0)
+	for _, r := range append([]types.IndexResult{syftResult, trivyResult}, customResults...) {
+		if (r.Name == CatalogerSyft || r.Name == CatalogerTrivy) && !i.runs(r.Name) {
+			continue
+		}
+		status := types.CatalogerStatus{Name: r.Name, Status: r.Status}
+		if r.Status != types.Success {
+			status.Error = fmt.Sprintf("%s", r.Error)
+			failed = append(failed, fmt.Sprintf("%s: %s", r.Name, r.Error))
+		}
+		catalogers = append(catalogers, status)
+	}
+	if len(failed) == len(catalogers) || (i.requireAllCatalogers && len(failed) > 0) {
+		return nil, nil, errors.Errorf("failed to index image %s with %s", imageName, strings.Join(failed, ", "))
+	}
+
+	removed, err := normalizeResults(ctx, digest.String(), &syftResult, &trivyResult, customResults, i.purlQualifiers)
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "failed to normalize packagess: %s", imageName)
 	}
 
-	packages := types.MergePackages(syftResult, trivyResult)
+	_, mergeSpan := tracing.Start(ctx, "merge", tracing.Digest(digest.String()))
+	packages := types.MergePackages(append([]types.IndexResult{syftResult, trivyResult}, customResults...)...)
+	tracing.End(mergeSpan, nil)
+	c, _ := img.ConfigFile()
+	distro := syftResult.Distro
+	if isWindows(c) {
+		packages = normalizeWindowsPackages(packages)
+		if p, ok := windowsPackage(c, lm); ok {
+			packages = append(packages, p)
+		}
+		distro = windowsDistro(c)
+	}
+	i.addCpes(packages)
 
-	skill.Log.Infof(`Indexed %d packages`, len(packages))
+	i.logger.Infof(`Indexed %d packages`, len(packages))
+	metrics.ObservePackages(len(packages))
+	i.progress.PackagesFound(len(packages))
 
 	manifest, _ := img.RawManifest()
 	config, _ := img.RawConfigFile()
-	c, _ := img.ConfigFile()
+	attributeLayers(packages, lm, c)
+	removed = removedFromImage(removed, packages)
+	attributeLayers(removed, lm, c)
+	createdBy := LayerCreatedBy(c)
+	for _, p := range removed {
+		p.RemovedBy.CreatedBy = createdBy[p.RemovedBy.Ordinal]
+	}
+	fileOwners(syftResult.Files, packages)
+	if !i.packageFiles {
+		dropOsPackageFiles(packages)
+	}
+	for _, f := range syftResult.Files {
+		if f.Layer != nil {
+			f.Layer.CreatedBy = createdBy[f.Layer.Ordinal]
+		}
+	}
+	for _, s := range syftResult.Secrets {
+		s.Layer.CreatedBy = createdBy[s.Layer.Ordinal]
+	}
+	for _, f := range syftResult.CacheFiles {
+		if f.Layer != nil {
+			f.Layer.CreatedBy = createdBy[f.Layer.Ordinal]
+		}
+	}
+	if w := syftResult.Waste; w != nil {
+		for _, f := range w.Files {
+			for _, l := range []*types.Layer{f.Layer, f.RemovedBy} {
+				if l != nil {
+					l.CreatedBy = createdBy[l.Ordinal]
+				}
+			}
+		}
+		if w.Total > 0 {
+			i.logger.Warnf("Detected %s of wasted space in %d files", units.HumanSize(float64(w.Total)), len(w.Files))
+		}
+	}
+	if len(syftResult.Secrets) > 0 {
+		i.logger.Warnf("Detected %d secrets", len(syftResult.Secrets))
+	}
+	skipped := make([]types.Layer, 0)
+	for _, o := range registry.ForeignLayers(img) {
+		skipped = append(skipped, types.Layer{
+			Ordinal:   o,
+			DiffId:    lm.DiffIdByOrdinal[o],
+			Digest:    lm.DigestByOrdinal[o],
+			CreatedBy: createdBy[o],
+		})
+	}
+	if len(skipped) > 0 {
+		i.logger.Warnf("Skipped %d non-distributable layers", len(skipped))
+	}
 	m, _ := img.Manifest()
-	d, _ := img.Digest()
 
 	var tag []string
 	if imageName != "" {
@@ -139,28 +373,35 @@ func indexImage(img v1.Image, imageName, path string) (*types.Sbom, *v1.Image, e
 
 	sbom := types.Sbom{
 		Artifacts: packages,
+		Secrets:   syftResult.Secrets,
+		Removed:   removed,
+		Files:     syftResult.Files,
+		Waste:     syftResult.Waste,
 		Source: types.Source{
 			Type: "image",
 			Image: types.ImageSource{
 				Name:        imageName,
-				Digest:      d.String(),
+				Digest:      digest.String(),
 				Manifest:    m,
 				Config:      c,
 				RawManifest: base64.StdEncoding.EncodeToString(manifest),
 				RawConfig:   base64.StdEncoding.EncodeToString(config),
-				Distro:      syftResult.Distro,
+				Distro:      distro,
 				Platform: types.Platform{
 					Os:           c.OS,
 					Architecture: c.Architecture,
 					Variant:      c.Variant,
 				},
-				Size: m.Config.Size,
+				Size:          m.Config.Size,
+				SkippedLayers: skipped,
+				History:       imageHistory(c, m),
 			},
 		},
 		Descriptor: types.Descriptor{
 			Name:        "docker index",
 			Version:     internal.FromBuild().Version,
 			SbomVersion: internal.FromBuild().SbomVersion,
+			Catalogers:  catalogers,
 		},
 	}
 
@@ -168,12 +409,69 @@ func indexImage(img v1.Image, imageName, path string) (*types.Sbom, *v1.Image, e
 		sbom.Source.Image.Tags = &tag
 	}
 
-	js, err := json.MarshalIndent(sbom, "", "  ")
-	if err == nil {
-		_ = os.WriteFile(sbomPath, js, 0644)
+	AnnotateEol(&sbom, time.Now())
+	// checked before the environment is redacted
+	sbom.ConfigFindings = CheckConfig(&sbom)
+	if len(sbom.ConfigFindings) > 0 {
+		i.logger.Warnf("Detected %d config findings", len(sbom.ConfigFindings))
+	}
+	if i.leftovers {
+		sbom.Leftovers = CheckLeftovers(&sbom, syftResult.CacheFiles)
+		if len(sbom.Leftovers) > 0 {
+			i.logger.Warnf("Detected %d build leftovers", len(sbom.Leftovers))
+		}
+	}
+	if i.redactEnv {
+		if err := RedactEnv(&sbom); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to redact config: %s", imageName)
+		}
+	}
+	SortSbom(&sbom)
+	// partial sboms are not cached so that the failed catalogers run again next time
+	if i.defaultSbom() && path != "" && len(failed) == 0 {
+		js, err := json.MarshalIndent(sbom, "", "  ")
+		if err == nil {
+			_ = os.WriteFile(sbomPath, js, 0644)
+		}
+	}
+
+	return i.finish(&sbom), &img, nil
+}
+
+// finish applies the options that don't change the cached sbom: removed packages are dropped
+// unless the indexer lists them and the annotations are added
+func (i *Indexer) finish(sb *types.Sbom) *types.Sbom {
+	if !i.removed {
+		sb.Removed = nil
+	}
+	Annotate(sb, i.annotations)
+	return sb
+}
+
+// readCachedSbom reads the sbom at path if it was written by the current version, as
+// catalogers may have changed even if the sbom format did not
+func readCachedSbom(path string) (*types.Sbom, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var sbom types.Sbom
+	if err := json.Unmarshal(b, &sbom); err != nil {
+		return nil, false
+	}
+	if CheckDescriptor(sbom.Descriptor) != nil || sbom.Descriptor.Version != internal.FromBuild().Version {
+		return nil, false
 	}
+	return &sbom, true
+}
 
-	return &sbom, &img, nil
+func awaitResult(ctx context.Context, c <-chan types.IndexResult) (types.IndexResult, error) {
+	select {
+	case r := <-c:
+		return r, nil
+	case <-ctx.Done():
+		return types.IndexResult{}, ctx.Err()
+	}
 }
 
 func createLayerMapping(img v1.Image) types.LayerMapping {
@@ -190,6 +488,10 @@ func createLayerMapping(img v1.Image) types.LayerMapping {
 	layers := manifest.Layers
 
 	for i := range layers {
+		// malformed images may list fewer diff ids than layers
+		if i >= len(diffIds) {
+			break
+		}
 		layer := layers[i]
 		diffId := diffIds[i]
 
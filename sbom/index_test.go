@@ -0,0 +1,138 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func pushRandomImage(t *testing.T) (string, v1.Image) {
+	server := httptest.NewServer(ggcr.New())
+	t.Cleanup(server.Close)
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	img, _ := random.Image(1024, 2)
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	return image, img
+}
+
+func TestIndexStreaming(t *testing.T) {
+	image, img := pushRandomImage(t)
+
+	dir := t.TempDir()
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(dir)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := img.Digest()
+	if sb.Source.Image.Digest != d.String() {
+		t.Errorf("expected sbom of %s, got %s", d, sb.Source.Image.Digest)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sha256")); !os.IsNotExist(err) {
+		t.Error("expected streamed image not to be stored in the cache")
+	}
+}
+
+func TestIndexPartial(t *testing.T) {
+	image, _ := pushRandomImage(t)
+	// trivy fails to create its cache below a file while syft succeeds
+	file := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(file, nil, 0644)
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(file)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := sb.FailedCatalogers(); len(failed) != 1 || failed[0] != CatalogerTrivy {
+		t.Errorf("expected trivy to be recorded as failed, got %v", sb.Descriptor.Catalogers)
+	}
+
+	_, _, err = NewIndexer(WithStreaming(), WithCacheDir(file), WithRequireAllCatalogers()).Index(context.Background(), image)
+	if err == nil {
+		t.Error("expected failed cataloger to fail indexing")
+	}
+}
+
+func TestIndexCustomSecretScannerNotCached(t *testing.T) {
+	image, _ := pushRandomImage(t)
+	cached := func(dir string) bool {
+		matches, _ := filepath.Glob(filepath.Join(dir, "sha256", "*", "sbom.json"))
+		return len(matches) > 0
+	}
+
+	dir := t.TempDir()
+	if _, _, err := NewIndexer(WithRemote(), WithCacheDir(dir), WithSecretScanner(nil)).Index(context.Background(), image); err != nil {
+		t.Fatal(err)
+	}
+	if cached(dir) {
+		t.Error("expected sbom without secret scanning not to be cached")
+	}
+
+	if _, _, err := NewIndexer(WithRemote(), WithCacheDir(dir)).Index(context.Background(), image); err != nil {
+		t.Fatal(err)
+	}
+	if !cached(dir) {
+		t.Error("expected default sbom to be cached")
+	}
+}
+
+type fakeCataloger struct{}
+
+func (c fakeCataloger) Name() string {
+	return "fake"
+}
+
+func (c fakeCataloger) Catalog(ctx context.Context, layers []LayerFS, lm types.LayerMapping) ([]types.Package, error) {
+	packages := make([]types.Package, 0)
+	for _, l := range layers {
+		packages = append(packages, types.Package{
+			Purl:      "pkg:generic/fake@" + strings.TrimPrefix(l.DiffId(), "sha256:")[:8],
+			Locations: []types.Location{{DiffId: l.DiffId(), Digest: lm.ByDiffId[l.DiffId()]}},
+		})
+	}
+	return packages, nil
+}
+
+func TestIndexCustomCataloger(t *testing.T) {
+	image, img := pushRandomImage(t)
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCataloger(fakeCataloger{}), WithCatalogers("fake")).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers, _ := img.Layers()
+	if len(sb.Artifacts) != len(layers) {
+		t.Errorf("expected one package per layer, got %v", sb.Artifacts)
+	}
+	if c := sb.Descriptor.Catalogers; len(c) != 1 || c[0].Name != "fake" || c[0].Status != types.Success {
+		t.Errorf("expected status of fake cataloger, got %v", c)
+	}
+}
@@ -0,0 +1,444 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"os"
+	"runtime"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/index-cli-plugin/attest"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/progress"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom/secrets"
+	"github.com/docker/index-cli-plugin/tracing"
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// Logger receives the progress messages of an Indexer
+type Logger = log.Logger
+
+// Indexer creates sboms of images. Use NewIndexer to create one; the zero value is not usable.
+type Indexer struct {
+	cache                registry.Cache
+	noCache              bool
+	catalogers           map[string]bool
+	excluded             map[string]bool
+	custom               []Cataloger
+	logger               Logger
+	parallelism          int
+	platform             string
+	client               client.APIClient
+	remote               bool
+	streaming            bool
+	requireAllCatalogers bool
+	secretScanner        *secrets.Scanner
+	customSecretScanner  bool
+	reuseAttestations    bool
+	attestationVerifier  attest.Verifier
+	keepImages           bool
+	removed              bool
+	files                bool
+	packageFiles         bool
+	waste                bool
+	leftovers            bool
+	purlQualifiers       []string
+	redactEnv            bool
+	cpes                 bool
+	annotations          map[string]string
+	progress             progress.Reporter
+}
+
+// Option configures an Indexer
+type Option func(*Indexer)
+
+// WithCacheDir stores pulled images and their sboms below dir instead of ATOMIST_CACHE_DIR
+func WithCacheDir(dir string) Option {
+	return func(i *Indexer) {
+		i.cache.Dir = dir
+	}
+}
+
+// WithKeepImages keeps the layers of indexed images in the cache instead of removing them
+// to free disk space. By default only their manifest, config and sbom are kept and the layers
+// are pulled again when needed.
+func WithKeepImages() Option {
+	return func(i *Indexer) {
+		i.keepImages = true
+	}
+}
+
+// WithoutCache always indexes images instead of reusing sboms of earlier runs
+func WithoutCache() Option {
+	return func(i *Indexer) {
+		i.noCache = true
+	}
+}
+
+// WithCatalogers only runs the given catalogers: CatalogerSyft or CatalogerTrivy select a tool,
+// the other names of CatalogerNames the packages of an ecosystem, e.g. CatalogerOs. All
+// catalogers run by default.
+func WithCatalogers(names ...string) Option {
+	return func(i *Indexer) {
+		i.catalogers = make(map[string]bool)
+		for _, n := range names {
+			i.catalogers[n] = true
+		}
+	}
+}
+
+// WithoutCatalogers skips the given catalogers, e.g. CatalogerJava to skip scanning Java archives
+func WithoutCatalogers(names ...string) Option {
+	return func(i *Indexer) {
+		i.excluded = make(map[string]bool)
+		for _, n := range names {
+			i.excluded[n] = true
+		}
+	}
+}
+
+// WithCataloger runs c in addition to the built-in and registered catalogers
+func WithCataloger(c Cataloger) Option {
+	return func(i *Indexer) {
+		i.custom = append(i.custom, c)
+	}
+}
+
+// WithLogger sends the progress messages of the Indexer and its image pulls to logger instead
+// of the one set with log.SetLogger
+func WithLogger(logger Logger) Option {
+	return func(i *Indexer) {
+		i.logger = logger
+	}
+}
+
+// WithParallelism limits the number of images IndexImages indexes at the same time
+func WithParallelism(parallelism int) Option {
+	return func(i *Indexer) {
+		i.parallelism = parallelism
+	}
+}
+
+// WithPlatform selects the platform image (e.g. linux/arm64) of multi-platform images
+func WithPlatform(platform string) Option {
+	return func(i *Indexer) {
+		i.platform = platform
+	}
+}
+
+// WithClient copies images from the Docker daemon if they can't be pulled from the registry
+func WithClient(client client.APIClient) Option {
+	return func(i *Indexer) {
+		i.client = client
+	}
+}
+
+// WithRemote pulls images from the registry only, without falling back to the Docker daemon
+func WithRemote() Option {
+	return func(i *Indexer) {
+		i.remote = true
+	}
+}
+
+// WithStreaming analyzes the layers of remote images while they are downloaded instead of
+// storing the image in the cache first, which reduces disk usage for large images. Implies
+// WithRemote; the sbom itself is not cached then.
+func WithStreaming() Option {
+	return func(i *Indexer) {
+		i.remote = true
+		i.streaming = true
+	}
+}
+
+// WithRequireAllCatalogers fails indexing if any cataloger fails instead of returning an sbom
+// without its packages that records the failed catalogers in its descriptor
+func WithRequireAllCatalogers() Option {
+	return func(i *Indexer) {
+		i.requireAllCatalogers = true
+	}
+}
+
+// WithRemovedPackages lists the packages installed by a layer and deleted or replaced by a later
+// layer in the removed section of the sbom
+func WithRemovedPackages() Option {
+	return func(i *Indexer) {
+		i.removed = true
+	}
+}
+
+// WithFiles records every regular file of the final filesystem with its size, sha256 digest,
+// owning package and introducing layer in the files section of the sbom
+func WithFiles() Option {
+	return func(i *Indexer) {
+		i.files = true
+	}
+}
+
+// WithWaste analyzes the space wasted by files that later layers overwrite or delete, duplicate
+// files and package manager caches
+func WithWaste() Option {
+	return func(i *Indexer) {
+		i.waste = true
+	}
+}
+
+// WithPurlQualifiers keeps the given arch, distro and epoch qualifiers in the purls of OS
+// packages, see types.ParsePurlQualifiers
+func WithPurlQualifiers(qualifiers ...string) Option {
+	return func(i *Indexer) {
+		i.purlQualifiers = qualifiers
+	}
+}
+
+// WithCpes enables or disables adding guessed CPE 2.3 names to packages for matchers based on
+// the NVD; they are added by default
+func WithCpes(enabled bool) Option {
+	return func(i *Indexer) {
+		i.cpes = enabled
+	}
+}
+
+// WithRedactEnv masks the values of environment variables and build args in the image config
+// embedded in sboms, as they often carry tokens, see RedactEnv
+func WithRedactEnv() Option {
+	return func(i *Indexer) {
+		i.redactEnv = true
+	}
+}
+
+// WithLeftovers reports the compilers, download tools, package manager caches and pip wheels
+// left in the final stage of the image, see CheckLeftovers
+func WithLeftovers() Option {
+	return func(i *Indexer) {
+		i.leftovers = true
+	}
+}
+
+// WithPackageFiles lists the files owned by os packages; they are left out by default as
+// distros install thousands of them
+func WithPackageFiles() Option {
+	return func(i *Indexer) {
+		i.packageFiles = true
+	}
+}
+
+// WithAnnotations adds key/value pairs like the git commit or build URL to the descriptor of
+// the sbom
+func WithAnnotations(annotations map[string]string) Option {
+	return func(i *Indexer) {
+		i.annotations = annotations
+	}
+}
+
+// WithProgress reports the bytes pulled, layers extracted, catalogers completed and packages
+// found while indexing to p
+func WithProgress(p progress.Reporter) Option {
+	return func(i *Indexer) {
+		i.progress = p
+	}
+}
+
+// WithSecretScanner scans layers with scanner instead of the one using the default rules; nil
+// disables secret scanning. Sboms of a custom scanner are not cached.
+func WithSecretScanner(scanner *secrets.Scanner) Option {
+	return func(i *Indexer) {
+		i.secretScanner = scanner
+		i.customSecretScanner = true
+	}
+}
+
+// WithAttestationReuse loads the sbom from existing cosign attestations of the image instead of
+// indexing it. Only attestations whose signature is accepted by verifier are used.
+func WithAttestationReuse(verifier attest.Verifier) Option {
+	return func(i *Indexer) {
+		i.reuseAttestations = true
+		i.attestationVerifier = verifier
+	}
+}
+
+// NewIndexer returns an Indexer configured by opts. Without options it behaves like the
+// package level functions: images are cached in ATOMIST_CACHE_DIR unless ATOMIST_NO_CACHE
+// is set and messages are logged to the logger set with log.SetLogger.
+func NewIndexer(opts ...Option) *Indexer {
+	_, noCache := os.LookupEnv("ATOMIST_NO_CACHE")
+	i := &Indexer{
+		cache:         registry.DefaultCache(),
+		noCache:       noCache,
+		custom:        registered(),
+		logger:        log.Current(),
+		parallelism:   runtime.NumCPU(),
+		secretScanner: defaultSecretScanner,
+		cpes:          true,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.cache.Logger = i.logger
+	i.cache.Progress = i.progress
+	if i.progress == nil {
+		i.progress = progress.Nop()
+	}
+	return i
+}
+
+// Index pulls the image for the configured platform and indexes it
+func (i *Indexer) Index(ctx context.Context, image string) (*types.Sbom, *v1.Image, error) {
+	var img v1.Image
+	var path string
+	var err error
+	if i.streaming {
+		i.logger.Infof("Streaming image %s", image)
+		img, err = registry.ReadRemoteImageContext(ctx, image, i.platform)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read image")
+		}
+		// syft, trivy and the custom catalogers read the layers of a single download
+		shared, stop, err := registry.SharedLayers(img)
+		defer stop()
+		if err != nil {
+			return nil, nil, err
+		}
+		sb, _, err := i.indexImage(ctx, shared, image, "")
+		return sb, &img, err
+	}
+	saveCtx, span := tracing.Start(ctx, "save image", tracing.Image(image))
+	if i.remote {
+		i.logger.Infof("Pulling image %s", image)
+		img, path, err = i.cache.SaveRemoteImage(saveCtx, image, i.platform)
+		if err != nil {
+			tracing.End(span, err)
+			return nil, nil, errors.Wrap(err, "failed to download image")
+		}
+		i.logger.Infof("Pulled image")
+	} else {
+		i.logger.Infof("Copying image %s", image)
+		img, path, err = i.cache.SavePlatformImage(saveCtx, image, i.platform, i.client)
+		if err != nil {
+			tracing.End(span, err)
+			return nil, nil, errors.Wrap(err, "failed to download image")
+		}
+		i.logger.Infof("Copied image")
+	}
+	if d, err := img.Digest(); err == nil {
+		span.SetAttributes(tracing.Digest(d.String()))
+	}
+	tracing.End(span, nil)
+	defer i.release(img, path)
+	return i.indexImage(ctx, img, image, path)
+}
+
+// IndexPath indexes the image in OCI format at path
+func (i *Indexer) IndexPath(ctx context.Context, path string, name string) (*types.Sbom, *v1.Image, error) {
+	i.logger.Infof("Loading image from %s", path)
+	img, err := registry.ReadImage(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read image")
+	}
+	i.logger.Infof("Loaded image")
+	return i.indexImage(ctx, img, name, path)
+}
+
+// IndexOCILayout indexes an image from an OCI image layout directory, see IndexOCILayout
+func (i *Indexer) IndexOCILayout(ctx context.Context, path string, ref string) (*types.Sbom, *v1.Image, error) {
+	i.logger.Infof("Loading image from OCI layout %s", path)
+	img, imageName, err := registry.ReadOCILayout(path, ref, i.platform)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read image")
+	}
+	size, err := registry.ImageSize(img)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read image")
+	}
+	if err := i.cache.CheckSpace(size); err != nil {
+		return nil, nil, err
+	}
+	cachePath, err := i.cache.CopyImage(img)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to copy image")
+	}
+	i.logger.Infof("Loaded image")
+	defer i.release(img, cachePath)
+	return i.indexImage(ctx, img, imageName, cachePath)
+}
+
+// IndexArchive indexes an image from a tarball, see IndexArchive
+func (i *Indexer) IndexArchive(ctx context.Context, path string, ref string) (*types.Sbom, *v1.Image, error) {
+	i.logger.Infof("Loading image from archive %s", path)
+	img, imageName, cleanup, err := registry.ReadArchive(path, ref, i.platform)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read image")
+	}
+	defer cleanup()
+	if info, err := os.Stat(path); err == nil {
+		if err := i.cache.CheckSpace(info.Size()); err != nil {
+			return nil, nil, err
+		}
+	}
+	cachePath, err := i.cache.CopyImage(img)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to copy image")
+	}
+	// re-read the image from the cache as the archive contents are removed on return
+	img, err = registry.ReadImage(cachePath)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read image")
+	}
+	i.logger.Infof("Loaded image")
+	defer i.release(img, cachePath)
+	return i.indexImage(ctx, img, imageName, cachePath)
+}
+
+// IndexAllPlatforms indexes every platform image of the image index image refers to
+// and returns one result per platform
+func (i *Indexer) IndexAllPlatforms(ctx context.Context, image string) ([]ImageIndexResult, error) {
+	i.logger.Infof("Pulling all platforms of image %s", image)
+	images, err := i.cache.SaveRemoteImages(ctx, image)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download image")
+	}
+	i.logger.Infof("Pulled %d platform images", len(images))
+
+	results := make([]ImageIndexResult, 0)
+	for _, pi := range images {
+		i.logger.Infof("Indexing platform %s", pi.Platform.String())
+		sb, img, err := i.indexImage(ctx, pi.Image, image, pi.Path)
+		i.release(pi.Image, pi.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to index platform %s", pi.Platform.String())
+		}
+		results = append(results, ImageIndexResult{
+			Input: pi.Platform.String(),
+			Image: img,
+			Sbom:  sb,
+		})
+	}
+	return results, nil
+}
+
+// release removes the layers of the indexed image img from the cache unless WithKeepImages is set
+func (i *Indexer) release(img v1.Image, path string) {
+	if i.keepImages || path == "" {
+		return
+	}
+	if err := i.cache.RemoveLayers(path, img); err != nil {
+		i.logger.Debugf("Failed to remove image layers: %s", err)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+)
+
+func TestNewIndexer(t *testing.T) {
+	t.Setenv("ATOMIST_NO_CACHE", "true")
+	i := NewIndexer()
+	if !i.noCache || !i.defaultCatalogers() || i.logger == nil {
+		t.Errorf("expected defaults from environment, got %+v", i)
+	}
+
+	i = NewIndexer(WithCacheDir("/tmp/index"), WithCatalogers(CatalogerSyft), WithParallelism(3))
+	if i.cache.Dir != "/tmp/index" || i.parallelism != 3 {
+		t.Errorf("expected options to be applied, got %+v", i)
+	}
+	if i.defaultCatalogers() || !i.catalogers[CatalogerSyft] {
+		t.Errorf("expected only syft cataloger, got %v", i.catalogers)
+	}
+
+	scanner, cpes := NewIndexer(WithSecretScanner(nil), WithCpes(false)), NewIndexer()
+	if scanner.secretScanner != nil || scanner.cpes || cpes.secretScanner == nil || !cpes.cpes {
+		t.Error("expected indexers not to share the secret scanner and CPE settings")
+	}
+	if scanner.defaultSbom() || NewIndexer(WithCpes(false)).defaultSbom() {
+		t.Error("expected sboms without default secret rules or CPEs not to be cached")
+	}
+
+	if !NewIndexer().defaultSbom() || NewIndexer(WithPurlQualifiers("arch")).defaultSbom() || NewIndexer(WithRedactEnv()).defaultSbom() || NewIndexer(WithLeftovers()).defaultSbom() {
+		t.Error("expected sboms with purl qualifiers, redacted config or leftovers not to be cached")
+	}
+}
+
+func TestCatalogerSelection(t *testing.T) {
+	i := NewIndexer(WithoutCatalogers(CatalogerJava))
+	if i.syftCataloger("java-cataloger") || !i.syftCataloger("javascript-package-cataloger") {
+		t.Error("expected only java cataloger of syft to be skipped")
+	}
+	if len(i.disabledAnalyzers()) != 2 {
+		t.Errorf("expected jar and pom analyzers to be disabled, got %v", i.disabledAnalyzers())
+	}
+
+	i = NewIndexer(WithCatalogers(CatalogerOs))
+	if !i.runs(CatalogerSyft) || i.runs(CatalogerTrivy) || i.syftCataloger("go-module-binary-cataloger") {
+		t.Error("expected only os package catalogers of syft to run")
+	}
+
+	if err := ValidateCatalogers([]string{"java", "cobol"}); err == nil {
+		t.Error("expected unknown cataloger to be rejected")
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func zipArchive(files map[string][]byte) []byte {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for n, c := range files {
+		f, _ := w.Create(n)
+		_, _ = f.Write(c)
+	}
+	_ = w.Close()
+	return b.Bytes()
+}
+
+func tarArchive(files map[string][]byte) []byte {
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	for n, c := range files {
+		_ = w.WriteHeader(&tar.Header{Name: n, Mode: 0644, Size: int64(len(c))})
+		_, _ = w.Write(c)
+	}
+	_ = w.Close()
+	return b.Bytes()
+}
+
+func mavenJar(group, artifact, version string, files map[string][]byte) []byte {
+	if files == nil {
+		files = make(map[string][]byte)
+	}
+	files["META-INF/MANIFEST.MF"] = []byte("Manifest-Version: 1.0\n")
+	files["META-INF/maven/"+group+"/"+artifact+"/pom.properties"] = []byte("groupId=" + group + "\nartifactId=" + artifact + "\nversion=" + version + "\n")
+	return zipArchive(files)
+}
+
+func TestIndexNestedJavaArchives(t *testing.T) {
+	log4j := mavenJar("org.apache.logging.log4j", "log4j-core", "2.14.1", nil)
+	// a spring boot fat jar with a nested jar and a shaded library
+	app := mavenJar("com.acme", "app", "1.0", map[string][]byte{
+		"BOOT-INF/lib/log4j-core-2.14.1.jar":                   log4j,
+		"META-INF/maven/com.google.guava/guava/pom.properties": []byte("groupId=com.google.guava\nartifactId=guava\nversion=30.0\n"),
+	})
+	war := zipArchive(map[string][]byte{"META-INF/MANIFEST.MF": []byte("Manifest-Version: 1.0\n"), "WEB-INF/lib/app.jar": app})
+	var dist bytes.Buffer
+	gz := gzip.NewWriter(&dist)
+	_, _ = gz.Write(tarArchive(map[string][]byte{"lib/commons-text.jar": mavenJar("org.apache.commons", "commons-text", "1.9", nil)}))
+	_ = gz.Close()
+	content := tarArchive(map[string][]byte{"app/app.war": war, "opt/dist.tar.gz": dist.Bytes()})
+
+	layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+	img, _ := mutate.AppendLayers(empty.Image, layer)
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	// trivy looks up jars by their checksum on Maven Central
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerJava), WithoutCatalogers(CatalogerTrivy)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1": "/app/app.war!/WEB-INF/lib/app.jar!/BOOT-INF/lib/log4j-core-2.14.1.jar",
+		"pkg:maven/com.google.guava/guava@30.0":                "/app/app.war!/WEB-INF/lib/app.jar!/META-INF/maven/com.google.guava/guava/pom.properties",
+		// syft only keeps the file name of archives in tar files
+		"pkg:maven/org.apache.commons/commons-text@1.9": "/opt/dist.tar.gz!/commons-text.jar",
+	}
+	for _, p := range sb.Artifacts {
+		if path, ok := expected[p.Purl]; ok {
+			if len(p.Locations) != 1 || p.Locations[0].Path != path {
+				t.Errorf("expected %s at %s, got %v", p.Purl, path, p.Locations)
+			}
+			delete(expected, p.Purl)
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("expected nested packages %v, got %v", expected, sb.Artifacts)
+	}
+}
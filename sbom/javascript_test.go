@@ -0,0 +1,106 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func packageJson(name, version string) []byte {
+	return []byte(`{"name":"` + name + `","version":"` + version + `","license":"MIT"}`)
+}
+
+func TestIndexJavascriptPackages(t *testing.T) {
+	sourceMap := []byte(`{"version":3,"sources":[
+		"webpack://app/./src/index.js",
+		"webpack://app/./node_modules/.pnpm/axios@1.1.3/node_modules/axios/lib/axios.js",
+		"webpack://app/./node_modules/express/index.js",
+		"webpack://app/./node_modules/left-pad/index.js"
+	]}`)
+	content := tarArchive(map[string][]byte{
+		"app/node_modules/express/package.json":                                  packageJson("express", "4.18.2"),
+		"app/node_modules/express/node_modules/debug/package.json":               packageJson("debug", "2.6.9"),
+		"app/node_modules/.pnpm/lodash@4.17.21/node_modules/lodash/package.json": packageJson("lodash", "4.17.21"),
+		"app/.yarn/cache/react-npm-18.2.0-2a4f9a3e8c-88e38092da.zip": zipArchive(map[string][]byte{
+			"node_modules/react/package.json": packageJson("react", "18.2.0"),
+		}),
+		"app/dist/main.js.map": sourceMap,
+	})
+	layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+	img, _ := mutate.AppendLayers(empty.Image, layer)
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerJavascript)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type expectation struct {
+		path       string
+		confidence float64
+	}
+	expected := map[string]expectation{
+		// bundled as well, the installed package outweighs the fingerprint
+		"pkg:npm/express@4.18.2": {path: "/app/node_modules/express/package.json"},
+		"pkg:npm/debug@2.6.9":    {path: "/app/node_modules/express/node_modules/debug/package.json"},
+		"pkg:npm/lodash@4.17.21": {path: "/app/node_modules/.pnpm/lodash@4.17.21/node_modules/lodash/package.json"},
+		"pkg:npm/react@18.2.0":   {path: "/app/.yarn/cache/react-npm-18.2.0-2a4f9a3e8c-88e38092da.zip!/node_modules/react/package.json"},
+		"pkg:npm/axios@1.1.3":    {path: "/app/dist/main.js.map", confidence: 0.8},
+	}
+	for _, p := range sb.Artifacts {
+		if p.Purl == "pkg:npm/left-pad@0.0.0" {
+			t.Errorf("expected bundled package without version to be skipped")
+		}
+		e, ok := expected[p.Purl]
+		if !ok {
+			continue
+		}
+		found := false
+		for _, loc := range p.Locations {
+			found = found || loc.Path == e.path && loc.DiffId != ""
+		}
+		if !found {
+			t.Errorf("expected %s at %s, got %v", p.Purl, e.path, p.Locations)
+		}
+		if p.Confidence != e.confidence {
+			t.Errorf("expected confidence %f of %s, got %f", e.confidence, p.Purl, p.Confidence)
+		}
+		delete(expected, p.Purl)
+	}
+	if len(expected) > 0 {
+		t.Errorf("expected packages %v, got %v", expected, sb.Artifacts)
+	}
+}
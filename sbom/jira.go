@@ -0,0 +1,176 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// Jira is the server and project issues for vulnerabilities are opened in
+type Jira struct {
+	Url string
+	// User is the account of the API token on Jira Cloud; without a user the token is sent as
+	// personal access token of Jira Server and Data Center
+	User      string
+	Token     string
+	Project   string
+	IssueType string
+}
+
+// JiraIssues maps the CVEs of an image repository to the keys of the issues opened for them
+type JiraIssues interface {
+	Issue(ctx context.Context, image string, cve string) (string, error)
+	RecordIssue(ctx context.Context, image string, cve string, key string) error
+}
+
+// JiraIssue is an issue opened for a vulnerability
+type JiraIssue struct {
+	Key string
+	Cve string
+}
+
+// JiraFindings returns the vulnerabilities of sb issues are opened for, the critical and known
+// exploited ones that are not suppressed, grouped by id
+func JiraFindings(sb *types.Sbom) map[string][]types.Cve {
+	findings := make(map[string][]types.Cve)
+	for _, c := range sb.Vulnerabilities {
+		if IsAffected(c) && c.Suppression == nil && (Severity(c) == "CRITICAL" || c.KnownExploited) {
+			findings[c.SourceId] = append(findings[c.SourceId], c)
+		}
+	}
+	return findings
+}
+
+// OpenIssues opens an issue for every finding of sb that has no issue in its repository yet and
+// records it in issues, so repeated scans don't open duplicates
+func (j Jira) OpenIssues(ctx context.Context, sb *types.Sbom, issues JiraIssues) ([]JiraIssue, error) {
+	if j.Url == "" || j.Token == "" || j.Project == "" {
+		return nil, errors.New("opening Jira issues requires --jira-url, --jira-token and --jira-project")
+	}
+	findings := JiraFindings(sb)
+	ids := make([]string, 0)
+	for id := range findings {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	opened := make([]JiraIssue, 0)
+	for _, id := range ids {
+		key, err := issues.Issue(ctx, sb.Source.Image.Name, id)
+		if err != nil {
+			return opened, err
+		}
+		if key != "" {
+			continue
+		}
+		if key, err = j.createIssue(ctx, sb, findings[id]); err != nil {
+			return opened, err
+		}
+		if err := issues.RecordIssue(ctx, sb.Source.Image.Name, id, key); err != nil {
+			return opened, err
+		}
+		opened = append(opened, JiraIssue{Key: key, Cve: id})
+	}
+	return opened, nil
+}
+
+type jiraFields struct {
+	Project     map[string]string `json:"project"`
+	IssueType   map[string]string `json:"issuetype"`
+	Summary     string            `json:"summary"`
+	Description string            `json:"description"`
+	Labels      []string          `json:"labels"`
+}
+
+// createIssue opens the issue for the packages affected by one vulnerability
+func (j Jira) createIssue(ctx context.Context, sb *types.Sbom, cves []types.Cve) (string, error) {
+	cve := cves[0]
+	image := toImageName(sb)
+	severity := strings.ToLower(Severity(cve))
+	issueType := j.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+
+	var description strings.Builder
+	fmt.Fprintf(&description, "%s (%s) was detected in image %s@%s by docker index.\n\n", cve.SourceId, severity, image, sb.Source.Image.Digest)
+	if cve.KnownExploited {
+		description.WriteString("The vulnerability is listed in the CISA known exploited vulnerabilities catalog.\n\n")
+	}
+	description.WriteString("Affected packages:\n")
+	for _, c := range cves {
+		fix := "no fix available"
+		if IsFixed(c) {
+			fix = "fixed in " + c.FixedBy
+		}
+		fmt.Fprintf(&description, "* {{%s}} (%s)\n", c.Purl, fix)
+	}
+	for _, adv := range []*types.Advisory{cve.Cve, cve.Advisory} {
+		if adv != nil && len(adv.Urls) > 0 {
+			fmt.Fprintf(&description, "\nAdvisory: %s\n", adv.Urls[0].Value)
+			break
+		}
+	}
+
+	js, err := json.Marshal(map[string]jiraFields{"fields": {
+		Project:     map[string]string{"key": j.Project},
+		IssueType:   map[string]string{"name": issueType},
+		Summary:     fmt.Sprintf("%s (%s) in %s", cve.SourceId, severity, image),
+		Description: description.String(),
+		Labels:      []string{"docker-index", "vulnerability"},
+	}})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(j.Url, "/")+"/rest/api/2/issue", bytes.NewReader(js))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if j.User != "" {
+		req.SetBasicAuth(j.User, j.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.Token)
+	}
+	resp, err := internal.HttpClient().Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open Jira issue for %s", cve.SourceId)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if resp.StatusCode != http.StatusCreated {
+		return "", errors.Errorf("failed to open Jira issue for %s: %s %s", cve.SourceId, resp.Status, strings.TrimSpace(string(body)))
+	}
+	var result struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Key == "" {
+		return "", errors.Errorf("failed to open Jira issue for %s: unexpected response %s", cve.SourceId, strings.TrimSpace(string(body)))
+	}
+	return result.Key, nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+type fakeIssues map[string]string
+
+func (f fakeIssues) Issue(ctx context.Context, image string, cve string) (string, error) {
+	return f[cve], nil
+}
+
+func (f fakeIssues) RecordIssue(ctx context.Context, image string, cve string, key string) error {
+	f[cve] = key
+	return nil
+}
+
+func TestJiraOpenIssues(t *testing.T) {
+	requests := make([]map[string]map[string]interface{}, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "me@example.com" || token != "token" || r.URL.Path != "/rest/api/2/issue" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"10001","key":"SEC-1"}`))
+	}))
+	defer server.Close()
+
+	critical := cveWithSeverity("CVE-2022-0001", "CRITICAL")
+	critical.Purl, critical.FixedBy = "pkg:deb/debian/openssl@1.1.1k", "1.1.1n"
+	exploited := cveWithSeverity("CVE-2022-0002", "MEDIUM")
+	exploited.KnownExploited = true
+	sb := &types.Sbom{
+		Source:          types.Source{Image: types.ImageSource{Name: "alpine", Digest: "sha256:1234"}},
+		Vulnerabilities: []types.Cve{critical, exploited, cveWithSeverity("CVE-2022-0003", "HIGH")},
+	}
+	if findings := JiraFindings(sb); len(findings) != 2 {
+		t.Errorf("expected critical and known exploited findings, got %v", findings)
+	}
+
+	jira := Jira{Url: server.URL, User: "me@example.com", Token: "token", Project: "SEC"}
+	issues := fakeIssues{"CVE-2022-0002": "SEC-0"}
+	opened, err := jira.OpenIssues(context.Background(), sb, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opened) != 1 || opened[0].Key != "SEC-1" || opened[0].Cve != "CVE-2022-0001" || issues["CVE-2022-0001"] != "SEC-1" {
+		t.Errorf("unexpected opened issues %+v", opened)
+	}
+	fields := requests[0]["fields"]
+	if fields["summary"] != "CVE-2022-0001 (critical) in alpine" || fields["project"].(map[string]interface{})["key"] != "SEC" {
+		t.Errorf("unexpected issue fields %v", fields)
+	}
+	if description := fields["description"].(string); !strings.Contains(description, "{{pkg:deb/debian/openssl@1.1.1k}} (fixed in 1.1.1n)") {
+		t.Errorf("unexpected description %s", description)
+	}
+
+	// a repeated scan doesn't open the issue again
+	if opened, err := jira.OpenIssues(context.Background(), sb, issues); err != nil || len(opened) != 0 || len(requests) != 1 {
+		t.Errorf("expected no duplicate issues, got %+v: %v", opened, err)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Skipped   *JUnitSkipped `xml:"skipped,omitempty"`
+}
+
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type JUnitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the vulnerabilities of the sbom as JUnit XML report to w
+func WriteJUnit(sb *types.Sbom, w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(ToJUnit(sb)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ToJUnit converts the sbom into a JUnit report with a test case per package, so CI systems list
+// vulnerable packages as failed tests. Packages with only suppressed vulnerabilities, or those a
+// VEX statement declared as not affected, are skipped.
+func ToJUnit(sb *types.Sbom) JUnitTestSuites {
+	suite := JUnitTestSuite{
+		Name:  toImageName(sb),
+		Cases: make([]JUnitTestCase, 0),
+	}
+	if suite.Name == "" {
+		suite.Name = sb.Source.Image.Digest
+	}
+	cves := make(map[string][]types.Cve)
+	for _, c := range sb.Vulnerabilities {
+		cves[c.Purl] = append(cves[c.Purl], c)
+	}
+	for _, p := range sb.Artifacts {
+		tc := JUnitTestCase{Name: p.Purl, ClassName: p.Type}
+		affected, suppressed := make([]types.Cve, 0), 0
+		for _, c := range cves[p.Purl] {
+			if IsAffected(c) && c.Suppression == nil {
+				affected = append(affected, c)
+			} else {
+				suppressed++
+			}
+		}
+		switch {
+		case len(affected) > 0:
+			var text strings.Builder
+			highest := "UNSPECIFIED"
+			for _, c := range affected {
+				severity := Severity(c)
+				if severities[severity] > severities[highest] {
+					highest = severity
+				}
+				fmt.Fprintf(&text, "%s %s", c.SourceId, strings.ToLower(severity))
+				if IsFixed(c) {
+					fmt.Fprintf(&text, ", fixed in %s", c.FixedBy)
+				}
+				text.WriteString("\n")
+			}
+			tc.Failure = &JUnitFailure{
+				Message: fmt.Sprintf("%d vulnerabilities detected in %s", len(affected), p.Purl),
+				Type:    strings.ToLower(highest),
+				Text:    text.String(),
+			}
+			suite.Failures++
+		case suppressed > 0:
+			tc.Skipped = &JUnitSkipped{Message: fmt.Sprintf("%d vulnerabilities suppressed or not affected", suppressed)}
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	return JUnitTestSuites{
+		Name:     sb.Descriptor.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []JUnitTestSuite{suite},
+	}
+}
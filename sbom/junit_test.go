@@ -0,0 +1,72 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	high := cveWithSeverity("CVE-2022-0001", "HIGH")
+	high.Purl, high.FixedBy = "pkg:deb/debian/openssl@1.1.1n", "1.1.1o"
+	critical := cveWithSeverity("CVE-2022-0002", "CRITICAL")
+	critical.Purl = "pkg:deb/debian/openssl@1.1.1n"
+	suppressed := cveWithSeverity("CVE-2022-0003", "LOW")
+	suppressed.Purl = "pkg:deb/debian/curl@7.74.0"
+	suppressed.Suppression = &types.Suppression{}
+	sb := &types.Sbom{
+		Source:     types.Source{Image: types.ImageSource{Name: "index.docker.io/library/app"}},
+		Descriptor: types.Descriptor{Name: "docker index"},
+		Artifacts: []types.Package{
+			{Type: "deb", Purl: "pkg:deb/debian/openssl@1.1.1n"},
+			{Type: "deb", Purl: "pkg:deb/debian/curl@7.74.0"},
+			{Type: "npm", Purl: "pkg:npm/lodash@4.17.21"},
+		},
+		Vulnerabilities: []types.Cve{high, critical, suppressed},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFormat(sb, FormatJUnit, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Error("expected xml header")
+	}
+	var report JUnitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Tests != 3 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	suite := report.Suites[0]
+	if suite.Name != "app" || suite.Skipped != 1 {
+		t.Errorf("unexpected suite %+v", suite)
+	}
+	failure := suite.Cases[0].Failure
+	if failure == nil || failure.Type != "critical" || failure.Text != "CVE-2022-0001 high, fixed in 1.1.1o\nCVE-2022-0002 critical\n" {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+	if suite.Cases[1].Skipped == nil || suite.Cases[2].Failure != nil || suite.Cases[2].Skipped != nil {
+		t.Errorf("unexpected test cases %+v", suite.Cases)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// attributeLayers sets the layer that introduced each package, which is the lowest
+// layer any of the package locations is found in
+func attributeLayers(packages []types.Package, lm types.LayerMapping, config *v1.ConfigFile) {
+	createdBy := LayerCreatedBy(config)
+	for i, p := range packages {
+		ordinal := -1
+		for _, loc := range p.Locations {
+			if o, ok := lm.OrdinalByDiffId[loc.DiffId]; ok && (ordinal == -1 || o < ordinal) {
+				ordinal = o
+			}
+		}
+		if ordinal == -1 {
+			continue
+		}
+		packages[i].Layer = &types.Layer{
+			Ordinal:   ordinal,
+			DiffId:    lm.DiffIdByOrdinal[ordinal],
+			Digest:    lm.DigestByOrdinal[ordinal],
+			CreatedBy: createdBy[ordinal],
+		}
+	}
+}
+
+// LayerCreatedBy maps layer ordinals to the history instruction that created them
+func LayerCreatedBy(config *v1.ConfigFile) map[int]string {
+	createdBy := make(map[int]string)
+	if config == nil {
+		return createdBy
+	}
+	lc := 0
+	for _, h := range config.History {
+		if h.EmptyLayer {
+			continue
+		}
+		createdBy[lc] = h.CreatedBy
+		lc++
+	}
+	return createdBy
+}
+
+// imageHistory lists the history entries of config with the layer each of them created
+func imageHistory(config *v1.ConfigFile, manifest *v1.Manifest) []types.HistoryEntry {
+	if config == nil {
+		return nil
+	}
+	history := make([]types.HistoryEntry, 0, len(config.History))
+	ordinal := 0
+	for _, h := range config.History {
+		entry := types.HistoryEntry{
+			CreatedBy:  h.CreatedBy,
+			Comment:    h.Comment,
+			EmptyLayer: h.EmptyLayer,
+		}
+		if !h.Created.IsZero() {
+			entry.Created = h.Created.UTC().Format(time.RFC3339)
+		}
+		if !h.EmptyLayer {
+			entry.Layer = &types.Layer{Ordinal: ordinal, CreatedBy: h.CreatedBy}
+			if ordinal < len(config.RootFS.DiffIDs) {
+				entry.Layer.DiffId = config.RootFS.DiffIDs[ordinal].String()
+			}
+			if manifest != nil && ordinal < len(manifest.Layers) {
+				entry.Layer.Digest = manifest.Layers[ordinal].Digest.String()
+			}
+			ordinal++
+		}
+		history = append(history, entry)
+	}
+	return history
+}
+
+// WriteLayerView writes a table of all packages grouped by the layer that introduced them, and of
+// the removed packages grouped by the layer that removed them
+func WriteLayerView(sb *types.Sbom, w io.Writer) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Ordinal", "Layer", "Package", "Version"})
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Name: "Ordinal", Hidden: true},
+		{Name: "Layer", AutoMerge: true, WidthMax: 60},
+	})
+
+	row := func(l *types.Layer) (int, string) {
+		if l == nil {
+			return -1, "unknown"
+		}
+		layer := fmt.Sprintf("%d: %s", l.Ordinal, l.Digest)
+		if createdBy := strings.TrimSpace(l.CreatedBy); createdBy != "" {
+			layer += "\n" + createdBy
+		}
+		return l.Ordinal, layer
+	}
+	for _, p := range sb.Artifacts {
+		ordinal, layer := row(p.Layer)
+		t.AppendRow(table.Row{ordinal, layer, toPackageKey(p), p.Version})
+	}
+	// removed packages show up in the layer that deleted them
+	for _, p := range sb.Removed {
+		ordinal, layer := row(p.RemovedBy)
+		t.AppendRow(table.Row{ordinal, layer, toPackageKey(p), p.Version + " (removed)"})
+	}
+
+	t.SortBy([]table.SortBy{
+		{Name: "Ordinal", Mode: table.AscNumeric},
+		{Name: "Package", Mode: table.Asc},
+	})
+	t.SetPageSize(-1)
+	t.SetStyle(table.StyleLight)
+	t.Style().Options.SeparateRows = true
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
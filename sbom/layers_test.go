@@ -0,0 +1,125 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestAttributeLayers(t *testing.T) {
+	lm := types.LayerMapping{
+		OrdinalByDiffId: map[string]int{"sha256:d0": 0, "sha256:d1": 1, "sha256:d2": 2},
+		DiffIdByOrdinal: map[int]string{0: "sha256:d0", 1: "sha256:d1", 2: "sha256:d2"},
+		DigestByOrdinal: map[int]string{0: "sha256:l0", 1: "sha256:l1", 2: "sha256:l2"},
+	}
+	config := &v1.ConfigFile{History: []v1.History{
+		{CreatedBy: "/bin/sh -c #(nop) ADD file:abc in / "},
+		{CreatedBy: "/bin/sh -c #(nop)  ENV LANG=C.UTF-8", EmptyLayer: true},
+		{CreatedBy: "RUN /bin/sh -c apt-get install -y curl # buildkit"},
+		{CreatedBy: "COPY app /app # buildkit"},
+	}}
+	tests := []struct {
+		name      string
+		locations []string
+		ordinal   int
+		createdBy string
+	}{
+		{name: "base", locations: []string{"sha256:d0"}, ordinal: 0, createdBy: "/bin/sh -c #(nop) ADD file:abc in / "},
+		{name: "curl", locations: []string{"sha256:d1"}, ordinal: 1, createdBy: "RUN /bin/sh -c apt-get install -y curl # buildkit"},
+		{name: "upgraded", locations: []string{"sha256:d2", "sha256:d1"}, ordinal: 1, createdBy: "RUN /bin/sh -c apt-get install -y curl # buildkit"},
+		{name: "app", locations: []string{"sha256:d2"}, ordinal: 2, createdBy: "COPY app /app # buildkit"},
+		{name: "unknown", locations: []string{"sha256:other"}, ordinal: -1},
+		{name: "none", ordinal: -1},
+	}
+	packages := make([]types.Package, 0)
+	for _, test := range tests {
+		p := types.Package{Name: test.name}
+		for _, l := range test.locations {
+			p.Locations = append(p.Locations, types.Location{DiffId: l})
+		}
+		packages = append(packages, p)
+	}
+	attributeLayers(packages, lm, config)
+	for i, test := range tests {
+		l := packages[i].Layer
+		if test.ordinal == -1 {
+			if l != nil {
+				t.Errorf("%s: expected no layer, got %+v", test.name, l)
+			}
+			continue
+		}
+		if l == nil || l.Ordinal != test.ordinal || l.CreatedBy != test.createdBy || l.DiffId != lm.DiffIdByOrdinal[test.ordinal] || l.Digest != lm.DigestByOrdinal[test.ordinal] {
+			t.Errorf("%s: expected layer %d created by %q, got %+v", test.name, test.ordinal, test.createdBy, l)
+		}
+	}
+
+	if createdBy := LayerCreatedBy(nil); len(createdBy) != 0 {
+		t.Errorf("expected no layers without config, got %v", createdBy)
+	}
+}
+
+func TestWriteLayerView(t *testing.T) {
+	sb := &types.Sbom{
+		Artifacts: []types.Package{
+			{Type: "npm", Name: "app", Version: "1.0.0", Layer: &types.Layer{Ordinal: 2, Digest: "sha256:l2", CreatedBy: "COPY app /app"}},
+			{Type: "deb", Name: "curl", Version: "7.88.1", Layer: &types.Layer{Ordinal: 1, Digest: "sha256:l1"}},
+			{Type: "deb", Name: "bash", Version: "5.2", Layer: &types.Layer{Ordinal: 0, Digest: "sha256:l0"}},
+			{Type: "generic", Name: "orphan", Version: "1"},
+		},
+		Removed: []types.Package{
+			{Type: "deb", Name: "wget", Version: "1.21", RemovedBy: &types.Layer{Ordinal: 2, Digest: "sha256:l2"}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteLayerView(sb, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	rows := []struct {
+		layer   string
+		pkg     string
+		version string
+	}{
+		{layer: "unknown", pkg: "generic/orphan", version: "1"},
+		{layer: "0: sha256:l0", pkg: "deb/bash", version: "5.2"},
+		{layer: "1: sha256:l1", pkg: "deb/curl", version: "7.88.1"},
+		{layer: "2: sha256:l2", pkg: "deb/wget", version: "1.21 (removed)"},
+		{layer: "2: sha256:l2", pkg: "npm/app", version: "1.0.0"},
+	}
+	last := -1
+	for _, row := range rows {
+		found := -1
+		for i, l := range lines {
+			if strings.Contains(l, row.layer) && strings.Contains(l, row.pkg) && strings.Contains(l, row.version) {
+				found = i
+				break
+			}
+		}
+		if found <= last {
+			t.Errorf("expected row %s %s %s after the previous rows in\n%s", row.layer, row.pkg, row.version, buf.String())
+		}
+		last = found
+	}
+	if !strings.Contains(buf.String(), "COPY app /app") {
+		t.Errorf("expected created by of layer 2 in\n%s", buf.String())
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/go-units"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// Checks of the build tooling left in the final stage of an image
+const (
+	LeftoverCompiler     = "compiler"
+	LeftoverDownloadTool = "download-tool"
+	LeftoverPackageCache = "package-cache"
+	LeftoverPipWheel     = "pip-wheel"
+)
+
+var (
+	// compilers and build toolchains of the apk, dpkg and rpm package names, including the
+	// versioned packages of debian like gcc-12
+	compilerPackages = regexp.MustCompile(`^((gcc|g\+\+|clang)(-[0-9.]+)?|gcc-c\+\+|build-base|build-essential|make|cmake|go|golang|golang-go|golang-[0-9.]+-go|rust|rustc|cargo)$`)
+	downloadPackages = regexp.MustCompile(`^(curl|wget)$`)
+)
+
+// leftoverFiles lists the files of package manager caches and the pip wheels of the squashed
+// filesystem of src with their size and the layer that wrote them
+func leftoverFiles(ctx context.Context, src *source.Source, lm types.LayerMapping) ([]types.File, error) {
+	resolver, err := src.FileResolver(source.SquashedScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create file resolver")
+	}
+	files := make([]types.File, 0)
+	// the locations are read to the end so that the resolver goroutine finishes on cancellation
+	for loc := range resolver.AllLocations() {
+		if ctx.Err() != nil {
+			continue
+		}
+		if c, _ := packageCache(loc.RealPath); c == "" && !isWheel(loc.RealPath) {
+			continue
+		}
+		md, err := resolver.FileMetadataByLocation(loc)
+		if err != nil || md.Type != source.RegularFile {
+			continue
+		}
+		file := types.File{
+			Path: loc.RealPath,
+			Size: md.Size,
+		}
+		if ordinal, ok := lm.OrdinalByDiffId[loc.FileSystemID]; ok {
+			file.Layer = &types.Layer{
+				Ordinal: ordinal,
+				DiffId:  loc.FileSystemID,
+				Digest:  lm.ByDiffId[loc.FileSystemID],
+			}
+		}
+		files = append(files, file)
+	}
+	return files, ctx.Err()
+}
+
+func isWheel(p string) bool {
+	return strings.HasSuffix(p, ".whl")
+}
+
+// CheckLeftovers returns the compilers and download tools installed as os packages of sb and
+// the package manager caches and pip wheels among files, with the space each of them takes
+func CheckLeftovers(sb *types.Sbom, files []types.File) []types.Leftover {
+	leftovers := make([]types.Leftover, 0)
+	fileSizes := make(map[string]int64)
+	for _, f := range sb.Files {
+		fileSizes[f.Path] = f.Size
+	}
+	for _, p := range sb.Artifacts {
+		if p.Type != "alpine" && p.Type != "deb" && p.Type != "rpm" {
+			continue
+		}
+		var leftover types.Leftover
+		switch {
+		case compilerPackages.MatchString(p.Name):
+			leftover = types.Leftover{
+				Check:   LeftoverCompiler,
+				Message: fmt.Sprintf("compiler %s %s is shipped in the final stage; build in an earlier stage of a multi-stage build and copy only the artifacts", p.Name, p.Version),
+			}
+		case downloadPackages.MatchString(p.Name):
+			leftover = types.Leftover{
+				Check:   LeftoverDownloadTool,
+				Message: fmt.Sprintf("download tool %s %s is shipped in the final stage; fetch files in an earlier stage or with ADD", p.Name, p.Version),
+			}
+		default:
+			continue
+		}
+		leftover.Size = installedSize(p, fileSizes)
+		leftover.Packages = []string{p.Purl}
+		leftover.Layer = copyLayer(p.Layer)
+		leftovers = append(leftovers, leftover)
+	}
+
+	caches := make(map[string][]types.File)
+	wheels := make(map[string][]types.File)
+	for _, f := range files {
+		// a cleaned apt cache keeps its empty lock files
+		if f.Size == 0 {
+			continue
+		}
+		if c, dir := packageCache(f.Path); c != "" {
+			caches[c+" "+dir] = append(caches[c+" "+dir], f)
+		} else if isWheel(f.Path) {
+			wheels[path.Dir(f.Path)] = append(wheels[path.Dir(f.Path)], f)
+		}
+	}
+	for key, cached := range caches {
+		c, dir, _ := strings.Cut(key, " ")
+		leftover := filesLeftover(cached)
+		leftover.Check = LeftoverPackageCache
+		leftover.Message = fmt.Sprintf("%s cache %s with %d files is shipped in the final stage; clean it in the same RUN instruction or use a cache mount", c, dir, len(cached))
+		leftover.Paths = []string{dir}
+		leftovers = append(leftovers, leftover)
+	}
+	for dir, whls := range wheels {
+		leftover := filesLeftover(whls)
+		leftover.Check = LeftoverPipWheel
+		leftover.Message = fmt.Sprintf("%d pip wheels in %s are shipped in the final stage; install them from an earlier stage or delete them after installing", len(whls), dir)
+		for _, f := range whls {
+			leftover.Paths = append(leftover.Paths, f.Path)
+		}
+		sort.Strings(leftover.Paths)
+		leftovers = append(leftovers, leftover)
+	}
+
+	sort.SliceStable(leftovers, func(i, j int) bool {
+		a, b := leftovers[i], leftovers[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.Message < b.Message
+	})
+	return leftovers
+}
+
+// filesLeftover sums the size of files and sets the layer that wrote the last of them
+func filesLeftover(files []types.File) types.Leftover {
+	leftover := types.Leftover{}
+	for _, f := range files {
+		leftover.Size += f.Size
+		if f.Layer != nil && (leftover.Layer == nil || f.Layer.Ordinal > leftover.Layer.Ordinal) {
+			leftover.Layer = copyLayer(f.Layer)
+		}
+	}
+	return leftover
+}
+
+// installedSize returns the installed size of the os package p in bytes from its metadata, or
+// the sum of the sizes of its files otherwise
+func installedSize(p types.Package, fileSizes map[string]int64) int64 {
+	switch {
+	case p.Type == "alpine" && p.InstalledSize > 0:
+		return int64(p.InstalledSize)
+	case p.Type == "deb" && p.InstalledSize > 0:
+		// dpkg records the installed size in KiB
+		return int64(p.InstalledSize) * 1024
+	case p.Type == "rpm" && p.Size > 0:
+		return int64(p.Size)
+	}
+	var size int64
+	for _, f := range p.Files {
+		size += fileSizes[f.Path]
+	}
+	return size
+}
+
+func copyLayer(l *types.Layer) *types.Layer {
+	if l == nil {
+		return nil
+	}
+	c := *l
+	return &c
+}
+
+// LeftoverSize formats the size of leftover for humans
+func LeftoverSize(leftover types.Leftover) string {
+	if leftover.Size == 0 {
+		return "unknown size"
+	}
+	return units.HumanSize(float64(leftover.Size))
+}
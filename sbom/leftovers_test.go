@@ -0,0 +1,87 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestCheckLeftovers(t *testing.T) {
+	layer := &types.Layer{Ordinal: 2, DiffId: "sha256:2"}
+	sb := &types.Sbom{
+		Artifacts: []types.Package{
+			{Type: "deb", Name: "gcc-12", Version: "12.2.0", Purl: "pkg:deb/debian/gcc-12@12.2.0", InstalledSize: 100, Layer: layer},
+			{Type: "alpine", Name: "curl", Version: "8.0.1", Purl: "pkg:alpine/curl@8.0.1", InstalledSize: 2048},
+			{Type: "rpm", Name: "make", Version: "4.3", Purl: "pkg:rpm/make@4.3", Files: []types.Location{{Path: "/usr/bin/make"}}},
+			{Type: "npm", Name: "make", Version: "1.0.0", Purl: "pkg:npm/make@1.0.0"},
+			{Type: "deb", Name: "libcurl4", Version: "7.88.1", Purl: "pkg:deb/debian/libcurl4@7.88.1"},
+		},
+		Files: []types.File{{Path: "/usr/bin/make", Size: 300}},
+	}
+	files := []types.File{
+		{Path: "/var/cache/apt/archives/lock", Size: 0},
+		{Path: "/var/lib/apt/lists/deb.debian.org_dists_bookworm_InRelease", Size: 40000, Layer: &types.Layer{Ordinal: 1}},
+		{Path: "/var/lib/apt/lists/deb.debian.org_dists_bookworm_main_binary-amd64_Packages.lz4", Size: 60000, Layer: layer},
+		{Path: "/root/.cache/pip/http/a/b/c", Size: 500},
+		{Path: "/tmp/wheels/requests-2.31.0-py3-none-any.whl", Size: 1000},
+		{Path: "/tmp/wheels/idna-3.4-py3-none-any.whl", Size: 800},
+	}
+
+	leftovers := CheckLeftovers(sb, files)
+	expected := []struct {
+		check string
+		size  int64
+	}{
+		{LeftoverCompiler, 102400},
+		{LeftoverPackageCache, 100000},
+		{LeftoverDownloadTool, 2048},
+		{LeftoverPipWheel, 1800},
+		{LeftoverPackageCache, 500},
+		{LeftoverCompiler, 300},
+	}
+	if len(leftovers) != len(expected) {
+		t.Fatalf("expected %d leftovers, got %v", len(expected), leftovers)
+	}
+	for i, e := range expected {
+		if leftovers[i].Check != e.check || leftovers[i].Size != e.size {
+			t.Errorf("expected %s of %d bytes at %d, got %s of %d bytes", e.check, e.size, i, leftovers[i].Check, leftovers[i].Size)
+		}
+	}
+
+	apt := leftovers[1]
+	if len(apt.Paths) != 1 || apt.Paths[0] != "/var/lib/apt/lists" {
+		t.Errorf("expected the apt lists directory, got %v", apt.Paths)
+	}
+	if apt.Layer == nil || apt.Layer.Ordinal != 2 {
+		t.Errorf("expected the cache to be written by layer 2, got %v", apt.Layer)
+	}
+	if gcc := leftovers[0]; len(gcc.Packages) != 1 || gcc.Packages[0] != "pkg:deb/debian/gcc-12@12.2.0" || gcc.Layer == layer {
+		t.Errorf("expected a copy of the layer of gcc-12, got %v", gcc)
+	}
+	if wheels := leftovers[3]; len(wheels.Paths) != 2 || wheels.Paths[0] != "/tmp/wheels/idna-3.4-py3-none-any.whl" {
+		t.Errorf("expected the sorted wheel paths, got %v", wheels.Paths)
+	}
+
+	if s := LeftoverSize(types.Leftover{}); s != "unknown size" {
+		t.Errorf("expected unknown size, got %s", s)
+	}
+	if leftovers := CheckLeftovers(&types.Sbom{}, nil); len(leftovers) != 0 {
+		t.Errorf("expected no leftovers, got %v", leftovers)
+	}
+}
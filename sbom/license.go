@@ -0,0 +1,101 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	LicenseDeny = "deny"
+	LicenseFlag = "flag"
+)
+
+// LicensePolicy lists SPDX license identifiers, or glob patterns like AGPL-*, that are
+// denied or flagged for review
+type LicensePolicy struct {
+	Deny []string `yaml:"deny"`
+	Flag []string `yaml:"flag"`
+	// Ignore skips packages matching the purl patterns, e.g. for exceptions granted by legal review
+	Ignore []string `yaml:"ignore"`
+}
+
+type LicenseViolation struct {
+	Purl    string
+	License string
+	Action  string
+}
+
+// ReadLicensePolicy reads a YAML license policy from file
+func ReadLicensePolicy(file string) (*LicensePolicy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read license policy %s", file)
+	}
+	var policy LicensePolicy
+	if err := yaml.Unmarshal(b, &policy); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse license policy %s", file)
+	}
+	for _, p := range append(policy.Deny, policy.Flag...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid license pattern %s in %s", p, file)
+		}
+	}
+	return &policy, nil
+}
+
+// CheckLicensePolicy returns all packages with a license denied or flagged by the policy
+func CheckLicensePolicy(sb *types.Sbom, policy *LicensePolicy) []LicenseViolation {
+	violations := make([]LicenseViolation, 0)
+	for _, p := range sb.Artifacts {
+		if ignoredByPolicy(p, policy.Ignore) {
+			continue
+		}
+		for _, l := range p.Licenses {
+			if matchesLicense(l, policy.Deny) {
+				violations = append(violations, LicenseViolation{Purl: p.Purl, License: l, Action: LicenseDeny})
+			} else if matchesLicense(l, policy.Flag) {
+				violations = append(violations, LicenseViolation{Purl: p.Purl, License: l, Action: LicenseFlag})
+			}
+		}
+	}
+	return violations
+}
+
+func matchesLicense(license string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(license)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func ignoredByPolicy(p types.Package, ignore []string) bool {
+	for _, i := range ignore {
+		if matchesPurl(i, p.Purl) {
+			return true
+		}
+	}
+	return false
+}
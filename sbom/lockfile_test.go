@@ -0,0 +1,140 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+const gemfile = `source "https://rubygems.org"
+
+gem "rails", "~> 7.0"
+gem "pg"
+
+group :development, :test do
+  gem "rspec-rails"
+end
+
+gem "rubocop", require: false, group: :development
+`
+
+const gemfileLock = `GEM
+  remote: https://rubygems.org/
+  specs:
+    activesupport (7.0.4)
+      i18n (>= 1.6, < 2)
+    i18n (1.12.0)
+    pg (1.4.4)
+    rails (7.0.4)
+      activesupport (= 7.0.4)
+    rspec-core (3.12.0)
+    rspec-rails (6.0.1)
+      activesupport (>= 6.1)
+      rspec-core (~> 3.12)
+    rubocop (1.38.0)
+
+PLATFORMS
+  x86_64-linux
+
+DEPENDENCIES
+  pg
+  rails (~> 7.0)
+  rspec-rails
+  rubocop
+
+BUNDLED WITH
+   2.3.24
+`
+
+const composerLock = `{
+  "packages": [{"name": "monolog/monolog", "version": "2.8.0", "type": "library"}],
+  "packages-dev": []
+}`
+
+const pubspecLock = `packages:
+  http:
+    dependency: "direct main"
+    description:
+      name: http
+      url: "https://pub.dartlang.org"
+    source: hosted
+    version: "0.13.5"
+sdks:
+  dart: ">=2.18.0 <3.0.0"
+`
+
+func TestIndexLockfiles(t *testing.T) {
+	content := tarArchive(map[string][]byte{
+		"app/Gemfile":          []byte(gemfile),
+		"app/Gemfile.lock":     []byte(gemfileLock),
+		"srv/composer.lock":    []byte(composerLock),
+		"flutter/pubspec.lock": []byte(pubspecLock),
+	})
+	layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+	img, _ := mutate.AppendLayers(empty.Image, layer)
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	sb, _, err := NewIndexer(WithStreaming(), WithCacheDir(t.TempDir()), WithCatalogers(CatalogerRuby, CatalogerPhp, CatalogerDart)).Index(context.Background(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"pkg:gem/rails@7.0.4":                {"default"},
+		"pkg:gem/activesupport@7.0.4":        {"default", "development", "test"},
+		"pkg:gem/i18n@1.12.0":                {"default", "development", "test"},
+		"pkg:gem/pg@1.4.4":                   {"default"},
+		"pkg:gem/rspec-rails@6.0.1":          {"development", "test"},
+		"pkg:gem/rspec-core@3.12.0":          {"development", "test"},
+		"pkg:gem/rubocop@1.38.0":             {"development"},
+		"pkg:composer/monolog/monolog@2.8.0": nil,
+		"pkg:pub/http@0.13.5":                nil,
+	}
+	for _, p := range sb.Artifacts {
+		groups, ok := expected[p.Purl]
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(p.Groups, groups) {
+			t.Errorf("expected groups %v of %s, got %v", groups, p.Purl, p.Groups)
+		}
+		delete(expected, p.Purl)
+	}
+	if len(expected) > 0 {
+		t.Errorf("expected packages %v, got %v", expected, sb.Artifacts)
+	}
+}
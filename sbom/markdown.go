@@ -0,0 +1,151 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// markdownTopCves is the number of fixable vulnerabilities listed in markdown summaries, keeping
+// them small enough for merge request comments
+const markdownTopCves = 10
+
+// WriteMarkdown writes a compact summary of the sbom as markdown to w, sized to be posted as
+// merge request comment: the vulnerabilities introduced by the build and inherited from the base
+// image by severity, the most severe fixable vulnerabilities and the packages the build added on
+// top of the base image
+func WriteMarkdown(sb *types.Sbom, w io.Writer) error {
+	var b strings.Builder
+	image := toImageName(sb)
+	if sb.Source.Image.Tags != nil && len(*sb.Source.Image.Tags) > 0 {
+		image += ":" + (*sb.Source.Image.Tags)[0]
+	}
+	fmt.Fprintf(&b, "### Scan of `%s` (%s)\n\n", image, sb.Source.Image.Platform.String())
+
+	base := sb.Source.Image.BaseImage
+	cves := make([]types.Cve, 0)
+	for _, c := range sb.Vulnerabilities {
+		if IsAffected(c) && c.Suppression == nil {
+			cves = append(cves, c)
+		}
+	}
+	introduced, inherited := make(map[string]int), make(map[string]int)
+	for _, c := range cves {
+		if IsFromBaseImage(sb, c.Purl) {
+			inherited[Severity(c)]++
+		} else {
+			introduced[Severity(c)]++
+		}
+	}
+	fromBase := 0
+	for _, p := range sb.Artifacts {
+		if p.Layer != nil && p.Layer.BaseImage {
+			fromBase++
+		}
+	}
+	fmt.Fprintf(&b, "%d packages, %d vulnerabilities", len(sb.Artifacts), len(cves))
+	if base != nil {
+		fmt.Fprintf(&b, "; %d packages from base image `%s`", fromBase, base.Name)
+	}
+	b.WriteString("\n")
+
+	if len(cves) > 0 {
+		if base != nil {
+			b.WriteString("\n| Severity | New | From base image |\n|---|---|---|\n")
+		} else {
+			b.WriteString("\n| Severity | Vulnerabilities |\n|---|---|\n")
+		}
+		for _, s := range []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNSPECIFIED"} {
+			if introduced[s] == 0 && inherited[s] == 0 {
+				continue
+			}
+			if base != nil {
+				fmt.Fprintf(&b, "| %s | %d | %d |\n", strings.ToLower(s), introduced[s], inherited[s])
+			} else {
+				fmt.Fprintf(&b, "| %s | %d |\n", strings.ToLower(s), introduced[s])
+			}
+		}
+	}
+
+	if d := sb.Delta; d != nil {
+		fmt.Fprintf(&b, "\n#### Changes since last scan\n\n%d new and %d resolved vulnerabilities since `%s@%s`\n", len(d.New), len(d.Resolved), d.PreviousImage, d.PreviousDigest)
+		if len(d.New)+len(d.Resolved) > 0 {
+			b.WriteString("\n| Vulnerability | Severity | Package | Change |\n|---|---|---|---|\n")
+		}
+		for _, change := range []struct {
+			name string
+			cves []types.Cve
+		}{{"new", d.New}, {"resolved", d.Resolved}} {
+			for i, c := range change.cves {
+				if i == markdownTopCves {
+					fmt.Fprintf(&b, "| | | %d more %s vulnerabilities not shown | |\n", len(change.cves)-markdownTopCves, change.name)
+					break
+				}
+				fmt.Fprintf(&b, "| %s | %s | `%s` | %s |\n", c.SourceId, strings.ToLower(Severity(c)), c.Purl, change.name)
+			}
+		}
+	}
+
+	fixable := make([]types.Cve, 0)
+	for _, c := range cves {
+		if IsFixed(c) {
+			fixable = append(fixable, c)
+		}
+	}
+	sort.SliceStable(fixable, func(i, j int) bool {
+		return severities[Severity(fixable[i])] > severities[Severity(fixable[j])]
+	})
+	if len(fixable) > 0 {
+		fmt.Fprintf(&b, "\n#### Top fixable vulnerabilities\n\n| Vulnerability | Severity | Package | Fixed in |\n|---|---|---|---|\n")
+		for i, c := range fixable {
+			if i == markdownTopCves {
+				fmt.Fprintf(&b, "\n%d more fixable vulnerabilities not shown\n", len(fixable)-markdownTopCves)
+				break
+			}
+			fmt.Fprintf(&b, "| %s | %s | `%s` | %s |\n", c.SourceId, strings.ToLower(Severity(c)), c.Purl, c.FixedBy)
+		}
+	}
+
+	if base != nil {
+		added, inBase := make(map[string]int), make(map[string]int)
+		for _, p := range sb.Artifacts {
+			if p.Layer != nil && p.Layer.BaseImage {
+				inBase[p.Type]++
+			} else {
+				added[p.Type]++
+			}
+		}
+		keys := make([]string, 0)
+		for t := range added {
+			keys = append(keys, t)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			b.WriteString("\n#### Packages added on top of the base image\n\n| Type | Added | Base image |\n|---|---|---|\n")
+			for _, t := range keys {
+				fmt.Fprintf(&b, "| %s | +%d | %d |\n", t, added[t], inBase[t])
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	base := &types.Layer{Ordinal: 0, BaseImage: true}
+	app := &types.Layer{Ordinal: 1}
+	tags := []string{"1.2"}
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{
+			Name:      "index.docker.io/library/app",
+			Tags:      &tags,
+			Platform:  types.Platform{Os: "linux", Architecture: "arm64"},
+			BaseImage: &types.BaseImage{Name: "debian:11", LayerCount: 1},
+		}},
+		Artifacts: []types.Package{
+			{Type: "deb", Purl: "pkg:deb/debian/openssl@1.1.1n", Layer: base},
+			{Type: "deb", Purl: "pkg:deb/debian/curl@7.74.0", Layer: app},
+			{Type: "npm", Purl: "pkg:npm/lodash@4.17.20", Layer: app},
+		},
+	}
+	for i := 0; i < 12; i++ {
+		c := cveWithSeverity(fmt.Sprintf("CVE-2022-%04d", i), "MEDIUM")
+		c.Purl, c.FixedBy = "pkg:npm/lodash@4.17.20", "4.17.21"
+		sb.Vulnerabilities = append(sb.Vulnerabilities, c)
+	}
+	critical := cveWithSeverity("CVE-2021-0001", "CRITICAL")
+	critical.Purl, critical.FixedBy = "pkg:deb/debian/openssl@1.1.1n", "1.1.1o"
+	unfixed := cveWithSeverity("CVE-2021-0002", "HIGH")
+	unfixed.Purl = "pkg:deb/debian/curl@7.74.0"
+	sb.Vulnerabilities = append(sb.Vulnerabilities, critical, unfixed)
+
+	var buf bytes.Buffer
+	if err := WriteFormat(sb, FormatMarkdown, &buf); err != nil {
+		t.Fatal(err)
+	}
+	md := buf.String()
+	for _, s := range []string{
+		"### Scan of `app:1.2` (linux/arm64)",
+		"3 packages, 14 vulnerabilities; 1 packages from base image `debian:11`",
+		"| critical | 0 | 1 |",
+		"| high | 1 | 0 |",
+		"| medium | 12 | 0 |",
+		"| CVE-2021-0001 | critical | `pkg:deb/debian/openssl@1.1.1n` | 1.1.1o |",
+		"3 more fixable vulnerabilities not shown",
+		"| deb | +1 | 1 |",
+		"| npm | +1 | 0 |",
+	} {
+		if !strings.Contains(md, s) {
+			t.Errorf("expected markdown to contain %q, got\n%s", s, md)
+		}
+	}
+	if strings.Contains(md, "CVE-2021-0002 |") {
+		t.Error("expected unfixed vulnerability not to be listed as fixable")
+	}
+}
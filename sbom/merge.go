@@ -0,0 +1,83 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// Merge combines sboms, e.g. of the platforms of an image or of an app and its sidecar, into
+// one. Packages with the same purl are merged with types.MergePackages keeping the locations
+// of every layer; vulnerabilities and secrets are de-duplicated. The source of the first sbom
+// becomes the source of the result, the others are recorded as merged sources.
+func Merge(sboms ...*types.Sbom) (*types.Sbom, error) {
+	if len(sboms) == 0 {
+		return nil, errors.New("no sboms to merge")
+	}
+	results := make([]types.IndexResult, 0)
+	merged := types.Sbom{
+		Source:          sboms[0].Source,
+		ConfigFindings:  sboms[0].ConfigFindings,
+		Leftovers:       sboms[0].Leftovers,
+		Vulnerabilities: make([]types.Cve, 0),
+		Secrets:         make([]types.Secret, 0),
+		Descriptor: types.Descriptor{
+			Name:        "docker index",
+			Version:     internal.FromBuild().Version,
+			SbomVersion: internal.FromBuild().SbomVersion,
+		},
+	}
+	cves := make(map[string]bool)
+	secrets := make(map[string]bool)
+	for i, sb := range sboms {
+		if i > 0 {
+			merged.MergedSources = append(merged.MergedSources, sb.Source)
+		}
+		merged.MergedSources = append(merged.MergedSources, sb.MergedSources...)
+		results = append(results, types.IndexResult{Name: sb.Source.Image.Name, Status: types.Success, Packages: sb.Artifacts})
+		for _, c := range sb.Vulnerabilities {
+			key := c.SourceId + "|" + c.Purl
+			if !cves[key] {
+				cves[key] = true
+				merged.Vulnerabilities = append(merged.Vulnerabilities, c)
+			}
+		}
+		for _, s := range sb.Secrets {
+			key := fmt.Sprintf("%s|%s|%d", s.RuleId, s.Path, s.Line)
+			if s.Layer != nil {
+				key += "|" + s.Layer.DiffId
+			}
+			if !secrets[key] {
+				secrets[key] = true
+				merged.Secrets = append(merged.Secrets, s)
+			}
+		}
+		// keep failed catalogers as the merged sbom misses their packages as well
+		for _, c := range sb.Descriptor.Catalogers {
+			if c.Status != types.Success {
+				merged.Descriptor.Catalogers = append(merged.Descriptor.Catalogers, c)
+			}
+		}
+	}
+	merged.Artifacts = types.MergePackages(results...)
+	SortSbom(&merged)
+	return &merged, nil
+}
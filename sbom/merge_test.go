@@ -0,0 +1,70 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestMerge(t *testing.T) {
+	amd64 := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{Name: "app", Platform: types.Platform{Architecture: "amd64"}}},
+		Artifacts: []types.Package{
+			{Purl: "pkg:deb/debian/openssl@3.0.5", Locations: []types.Location{{Path: "/var/lib/dpkg/status", DiffId: "sha256:a"}}},
+		},
+		Vulnerabilities: []types.Cve{{SourceId: "CVE-2022-3602", Purl: "pkg:deb/debian/openssl@3.0.5"}},
+	}
+	arm64 := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{Name: "app", Platform: types.Platform{Architecture: "arm64"}}},
+		Artifacts: []types.Package{
+			{Purl: "pkg:deb/debian/openssl@3.0.5", Locations: []types.Location{{Path: "/var/lib/dpkg/status", DiffId: "sha256:b"}}},
+			{Purl: "pkg:deb/debian/zlib@1.2.11", Locations: []types.Location{{Path: "/var/lib/dpkg/status", DiffId: "sha256:b"}}},
+		},
+		Vulnerabilities: []types.Cve{{SourceId: "CVE-2022-3602", Purl: "pkg:deb/debian/openssl@3.0.5"}},
+	}
+
+	// the combined document of --all-platforms
+	path := filepath.Join(t.TempDir(), "sbom.json")
+	js, _ := json.Marshal(map[string]*types.Sbom{"linux/arm64": arm64, "linux/amd64": amd64})
+	_ = os.WriteFile(path, js, 0644)
+	sboms, err := ReadSboms(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sboms) != 2 || sboms[0].Source.Image.Platform.Architecture != "amd64" {
+		t.Fatalf("expected sboms of both platforms, got %v", sboms)
+	}
+
+	merged, err := Merge(sboms...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Artifacts) != 2 || len(merged.Artifacts[0].Locations) != 2 {
+		t.Errorf("expected openssl with the locations of both images, got %v", merged.Artifacts)
+	}
+	if len(merged.Vulnerabilities) != 1 {
+		t.Errorf("expected vulnerabilities to be de-duplicated, got %v", merged.Vulnerabilities)
+	}
+	if len(merged.MergedSources) != 1 || merged.MergedSources[0].Image.Platform.Architecture != "arm64" {
+		t.Errorf("expected arm64 to be recorded as merged source, got %v", merged.MergedSources)
+	}
+}
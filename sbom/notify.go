@@ -0,0 +1,295 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	NotifySlack = "slack"
+	NotifyTeams = "teams"
+)
+
+// notifyTopCves is the number of critical vulnerabilities listed in notifications
+const notifyTopCves = 5
+
+// DefaultNotifyThresholds send notifications for any critical vulnerability
+var DefaultNotifyThresholds = map[string]int{"CRITICAL": 1}
+
+// Notifier posts a summary of sboms to a Slack or Microsoft Teams incoming webhook
+type Notifier struct {
+	Kind string
+	Url  string
+	// ReportUrl links the full report from the notification if set
+	ReportUrl string
+	// Thresholds are the number of vulnerabilities per severity needed to send a notification
+	Thresholds map[string]int
+}
+
+// ParseNotifyThresholds parses thresholds like critical=1,high=10
+func ParseNotifyThresholds(values []string) (map[string]int, error) {
+	if len(values) == 0 {
+		return DefaultNotifyThresholds, nil
+	}
+	thresholds := make(map[string]int)
+	for _, v := range values {
+		s, n, ok := strings.Cut(v, "=")
+		severity, err := ParseSeverity(s)
+		if err != nil {
+			return nil, err
+		}
+		count, err := strconv.Atoi(n)
+		if !ok || err != nil || count < 1 {
+			return nil, errors.Errorf("invalid notification threshold %s: expected <severity>=<count>", v)
+		}
+		thresholds[severity] = count
+	}
+	return thresholds, nil
+}
+
+// notification summarizes the vulnerabilities of an sbom; if the sbom was compared with a
+// previous scan, only the new vulnerabilities are counted
+type notification struct {
+	image    string
+	new      bool
+	counts   map[string]int
+	total    int
+	fixable  int
+	critical []types.Cve
+}
+
+func toNotification(sb *types.Sbom) notification {
+	n := notification{image: toImageName(sb), counts: make(map[string]int), critical: make([]types.Cve, 0)}
+	if tags := sb.Source.Image.Tags; tags != nil && len(*tags) > 0 {
+		n.image += ":" + (*tags)[0]
+	}
+	cves := sb.Vulnerabilities
+	if sb.Delta != nil {
+		n.new = true
+		cves = sb.Delta.New
+	}
+	for _, c := range cves {
+		if !IsAffected(c) || c.Suppression != nil {
+			continue
+		}
+		severity := Severity(c)
+		n.counts[severity]++
+		n.total++
+		if IsFixed(c) {
+			n.fixable++
+		}
+		if severity == "CRITICAL" {
+			n.critical = append(n.critical, c)
+		}
+	}
+	return n
+}
+
+func (n notification) exceeds(thresholds map[string]int) bool {
+	for severity, threshold := range thresholds {
+		if n.counts[severity] >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+func (n notification) title() string {
+	if n.new {
+		return fmt.Sprintf("%d new vulnerabilities in %s", n.total, n.image)
+	}
+	return fmt.Sprintf("%d vulnerabilities in %s", n.total, n.image)
+}
+
+func (n notification) severities() string {
+	parts := make([]string, 0)
+	for _, s := range []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNSPECIFIED"} {
+		if n.counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", strings.ToLower(s), n.counts[s]))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (n notification) fixes() string {
+	return fmt.Sprintf("%d of %d fixable", n.fixable, n.total)
+}
+
+// criticalLines lists the critical vulnerabilities with their fixed version
+func (n notification) criticalLines() []string {
+	lines := make([]string, 0)
+	for i, c := range n.critical {
+		if i == notifyTopCves {
+			lines = append(lines, fmt.Sprintf("%d more critical vulnerabilities", len(n.critical)-notifyTopCves))
+			break
+		}
+		fix := "no fix available"
+		if IsFixed(c) {
+			fix = "fixed in " + c.FixedBy
+		}
+		lines = append(lines, fmt.Sprintf("%s in %s (%s)", c.SourceId, c.Purl, fix))
+	}
+	return lines
+}
+
+// Notify posts the summary of sb if its vulnerabilities reach one of the thresholds and returns
+// whether a notification was sent
+func (nf Notifier) Notify(ctx context.Context, sb *types.Sbom) (bool, error) {
+	n := toNotification(sb)
+	thresholds := nf.Thresholds
+	if thresholds == nil {
+		thresholds = DefaultNotifyThresholds
+	}
+	if !n.exceeds(thresholds) {
+		return false, nil
+	}
+	var message interface{}
+	switch nf.Kind {
+	case NotifySlack:
+		message = toSlackMessage(n, nf.ReportUrl)
+	case NotifyTeams:
+		message = toTeamsMessage(n, nf.ReportUrl)
+	default:
+		return false, errors.Errorf("unsupported notification target: %s", nf.Kind)
+	}
+	js, err := json.Marshal(message)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nf.Url, bytes.NewReader(js))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := internal.HttpClient().Do(req)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to send %s notification", nf.Kind)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, errors.Errorf("failed to send %s notification: %s %s", nf.Kind, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return true, nil
+}
+
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+type SlackBlock struct {
+	Type   string      `json:"type"`
+	Text   *SlackText  `json:"text,omitempty"`
+	Fields []SlackText `json:"fields,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func toSlackMessage(n notification, reportUrl string) SlackMessage {
+	message := SlackMessage{
+		Text: n.title(),
+		Blocks: []SlackBlock{
+			{Type: "header", Text: &SlackText{Type: "plain_text", Text: n.title()}},
+			{Type: "section", Fields: []SlackText{
+				{Type: "mrkdwn", Text: "*Severities*\n" + n.severities()},
+				{Type: "mrkdwn", Text: "*Fixes*\n" + n.fixes()},
+			}},
+		},
+	}
+	if lines := n.criticalLines(); len(lines) > 0 {
+		message.Blocks = append(message.Blocks, SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: "*Critical*\n• " + strings.Join(lines, "\n• ")}})
+	}
+	if reportUrl != "" {
+		message.Blocks = append(message.Blocks, SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: "<" + reportUrl + "|Full report>"}})
+	}
+	return message
+}
+
+// TeamsMessage is a message card of a Microsoft Teams incoming webhook
+type TeamsMessage struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	Summary         string         `json:"summary"`
+	ThemeColor      string         `json:"themeColor"`
+	Title           string         `json:"title"`
+	Sections        []TeamsSection `json:"sections"`
+	PotentialAction []TeamsAction  `json:"potentialAction,omitempty"`
+}
+
+type TeamsSection struct {
+	Facts []TeamsFact `json:"facts,omitempty"`
+	Text  string      `json:"text,omitempty"`
+}
+
+type TeamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type TeamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []TeamsTarget `json:"targets"`
+}
+
+type TeamsTarget struct {
+	Os  string `json:"os"`
+	Uri string `json:"uri"`
+}
+
+func toTeamsMessage(n notification, reportUrl string) TeamsMessage {
+	color := "FBB552"
+	if n.counts["CRITICAL"] > 0 {
+		color = "D52536"
+	} else if n.counts["HIGH"] > 0 {
+		color = "DD7805"
+	}
+	message := TeamsMessage{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    n.title(),
+		ThemeColor: color,
+		Title:      n.title(),
+		Sections: []TeamsSection{{Facts: []TeamsFact{
+			{Name: "Severities", Value: n.severities()},
+			{Name: "Fixes", Value: n.fixes()},
+		}}},
+	}
+	if lines := n.criticalLines(); len(lines) > 0 {
+		message.Sections = append(message.Sections, TeamsSection{Text: "**Critical**\n\n- " + strings.Join(lines, "\n- ")})
+	}
+	if reportUrl != "" {
+		message.PotentialAction = []TeamsAction{{Type: "OpenUri", Name: "Full report", Targets: []TeamsTarget{{Os: "default", Uri: reportUrl}}}}
+	}
+	return message
+}
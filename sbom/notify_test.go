@@ -0,0 +1,88 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestParseNotifyThresholds(t *testing.T) {
+	thresholds, err := ParseNotifyThresholds([]string{"critical=1", "high=10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thresholds["CRITICAL"] != 1 || thresholds["HIGH"] != 10 || len(thresholds) != 2 {
+		t.Errorf("unexpected thresholds %v", thresholds)
+	}
+	for _, invalid := range []string{"critical", "critical=0", "severe=1"} {
+		if _, err := ParseNotifyThresholds([]string{invalid}); err == nil {
+			t.Errorf("expected %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var messages []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+
+	critical := cveWithSeverity("CVE-2022-0001", "CRITICAL")
+	critical.Purl, critical.FixedBy = "pkg:apk/alpine/openssl@1.1.1k", "1.1.1n"
+	sb := &types.Sbom{
+		Source:          types.Source{Image: types.ImageSource{Name: "alpine"}},
+		Vulnerabilities: []types.Cve{critical, cveWithSeverity("CVE-2022-0002", "HIGH")},
+	}
+
+	slack := Notifier{Kind: NotifySlack, Url: server.URL, ReportUrl: "https://reports.example.com/alpine.html"}
+	if sent, err := slack.Notify(context.Background(), sb); err != nil || !sent {
+		t.Fatalf("expected notification to be sent: %v", err)
+	}
+	var js strings.Builder
+	enc := json.NewEncoder(&js)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(messages[0])
+	for _, expected := range []string{"2 vulnerabilities in alpine", "critical 1 · high 1", "1 of 2 fixable", "CVE-2022-0001 in pkg:apk/alpine/openssl@1.1.1k (fixed in 1.1.1n)", "<https://reports.example.com/alpine.html|Full report>"} {
+		if !strings.Contains(js.String(), expected) {
+			t.Errorf("expected slack message to contain %q: %s", expected, js.String())
+		}
+	}
+
+	// only the new high vulnerability counts once compared with a previous scan
+	sb.Delta = &types.Delta{New: []types.Cve{cveWithSeverity("CVE-2022-0002", "HIGH")}}
+	teams := Notifier{Kind: NotifyTeams, Url: server.URL, Thresholds: map[string]int{"CRITICAL": 1}}
+	if sent, err := teams.Notify(context.Background(), sb); err != nil || sent {
+		t.Errorf("expected no notification below threshold: %v", err)
+	}
+	teams.Thresholds["HIGH"] = 1
+	if sent, err := teams.Notify(context.Background(), sb); err != nil || !sent {
+		t.Fatalf("expected notification to be sent: %v", err)
+	}
+	if messages[1]["@type"] != "MessageCard" || messages[1]["title"] != "1 new vulnerabilities in alpine" || messages[1]["themeColor"] != "DD7805" {
+		t.Errorf("unexpected teams message %v", messages[1])
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// PackagePolicy lists packages that are banned regardless of their vulnerabilities. Every rule
+// is a package name, glob pattern like netcat-* or purl without version, optionally followed by
+// comma separated version constraints, e.g. log4j-core < 2.17.1 or pkg:npm/event-stream = 3.3.6.
+type PackagePolicy struct {
+	Deny []string `yaml:"deny"`
+	// Allow exempts packages matching its rules from the deny rules, e.g. for patched builds
+	Allow []string `yaml:"allow"`
+
+	deny  []packageRule
+	allow []packageRule
+}
+
+type PackageViolation struct {
+	Purl string
+	Rule string
+}
+
+type packageRule struct {
+	rule        string
+	name        string
+	purl        *packageurl.PackageURL
+	constraints []versionConstraint
+}
+
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+var versionConstraintPattern = regexp.MustCompile(`^(<=|>=|!=|==|=|<|>)\s*(\S+)$`)
+
+// ReadPackagePolicy reads a YAML package policy from file
+func ReadPackagePolicy(file string) (*PackagePolicy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read package policy %s", file)
+	}
+	var policy PackagePolicy
+	if err := yaml.Unmarshal(b, &policy); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse package policy %s", file)
+	}
+	if policy.deny, err = parsePackageRules(policy.Deny); err != nil {
+		return nil, errors.Wrapf(err, "invalid deny rule in %s", file)
+	}
+	if policy.allow, err = parsePackageRules(policy.Allow); err != nil {
+		return nil, errors.Wrapf(err, "invalid allow rule in %s", file)
+	}
+	return &policy, nil
+}
+
+// CheckPackagePolicy returns all packages matching a deny rule and no allow rule of the policy
+func CheckPackagePolicy(sb *types.Sbom, policy *PackagePolicy) []PackageViolation {
+	violations := make([]PackageViolation, 0)
+	for _, p := range sb.Artifacts {
+		if _, ok := matchPackageRules(p, policy.allow); ok {
+			continue
+		}
+		if r, ok := matchPackageRules(p, policy.deny); ok {
+			violations = append(violations, PackageViolation{Purl: p.Purl, Rule: r.rule})
+		}
+	}
+	return violations
+}
+
+func parsePackageRules(rules []string) ([]packageRule, error) {
+	parsed := make([]packageRule, 0, len(rules))
+	for _, rule := range rules {
+		r, err := parsePackageRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+func parsePackageRule(rule string) (packageRule, error) {
+	name, constraints, _ := strings.Cut(strings.TrimSpace(rule), " ")
+	r := packageRule{rule: rule, name: strings.ToLower(name)}
+	if name == "" {
+		return r, errors.New("empty rule")
+	}
+	if strings.HasPrefix(name, "pkg:") {
+		purl, err := types.ToPackageUrl(name)
+		if err != nil {
+			return r, errors.Wrapf(err, "invalid purl in %s", rule)
+		}
+		if purl.Version != "" {
+			r.constraints = append(r.constraints, versionConstraint{op: "=", version: purl.Version})
+		}
+		r.purl = &purl
+	} else if _, err := path.Match(r.name, ""); err != nil {
+		return r, errors.Wrapf(err, "invalid package pattern in %s", rule)
+	}
+	if strings.TrimSpace(constraints) == "" {
+		return r, nil
+	}
+	for _, c := range strings.Split(constraints, ",") {
+		m := versionConstraintPattern.FindStringSubmatch(strings.TrimSpace(c))
+		if m == nil {
+			return r, errors.Errorf("invalid version constraint %q in %s", strings.TrimSpace(c), rule)
+		}
+		r.constraints = append(r.constraints, versionConstraint{op: m[1], version: m[2]})
+	}
+	return r, nil
+}
+
+func matchPackageRules(p types.Package, rules []packageRule) (packageRule, bool) {
+	for _, r := range rules {
+		if r.matches(p) {
+			return r, true
+		}
+	}
+	return packageRule{}, false
+}
+
+func (r packageRule) matches(p types.Package) bool {
+	if r.purl != nil {
+		purl, err := types.ToPackageUrl(p.Purl)
+		if err != nil || purl.Type != r.purl.Type || purl.Namespace != r.purl.Namespace || purl.Name != r.purl.Name {
+			return false
+		}
+	} else if ok, _ := path.Match(r.name, strings.ToLower(p.Name)); !ok {
+		return false
+	}
+	for _, c := range r.constraints {
+		if !c.matches(p.Version) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c versionConstraint) matches(version string) bool {
+	cmp := query.CompareVersions(version, c.version)
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		return cmp != 0
+	}
+	return cmp == 0
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestCheckPackagePolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "packages.yaml")
+	_ = os.WriteFile(file, []byte(`
+deny:
+  - log4j-core < 2.17.1
+  - netcat-*
+  - pkg:npm/event-stream@3.3.6
+  - openssl >= 1.1.0, < 1.1.1n
+allow:
+  - pkg:deb/debian/netcat-openbsd
+`), 0644)
+	policy, err := ReadPackagePolicy(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := &types.Sbom{Artifacts: []types.Package{
+		{Name: "log4j-core", Version: "2.14.1", Purl: "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"},
+		{Name: "log4j-core", Version: "2.17.1", Purl: "pkg:maven/org.apache.logging.log4j/log4j-core@2.17.1"},
+		{Name: "netcat-traditional", Version: "1.10", Purl: "pkg:deb/debian/netcat-traditional@1.10"},
+		{Name: "netcat-openbsd", Version: "1.219", Purl: "pkg:deb/debian/netcat-openbsd@1.219"},
+		{Name: "event-stream", Version: "3.3.6", Purl: "pkg:npm/event-stream@3.3.6"},
+		{Name: "event-stream", Version: "4.0.1", Purl: "pkg:npm/event-stream@4.0.1"},
+		{Name: "openssl", Version: "1.1.1k-r0", Purl: "pkg:apk/alpine/openssl@1.1.1k-r0"},
+		{Name: "openssl", Version: "3.0.7-r0", Purl: "pkg:apk/alpine/openssl@3.0.7-r0"},
+	}}
+	violations := CheckPackagePolicy(sb, policy)
+	expected := []PackageViolation{
+		{Purl: "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1", Rule: "log4j-core < 2.17.1"},
+		{Purl: "pkg:deb/debian/netcat-traditional@1.10", Rule: "netcat-*"},
+		{Purl: "pkg:npm/event-stream@3.3.6", Rule: "pkg:npm/event-stream@3.3.6"},
+		{Purl: "pkg:apk/alpine/openssl@1.1.1k-r0", Rule: "openssl >= 1.1.0, < 1.1.1n"},
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %v", len(expected), violations)
+	}
+	for i, v := range violations {
+		if v != expected[i] {
+			t.Errorf("expected violation %v, got %v", expected[i], v)
+		}
+	}
+
+	_ = os.WriteFile(file, []byte("deny:\n  - log4j-core ~ 2.17\n"), 0644)
+	if _, err := ReadPackagePolicy(file); err == nil {
+		t.Error("expected error for invalid version constraint")
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"sync"
+
+	"github.com/anchore/stereoscope"
+	"github.com/anchore/stereoscope/pkg/event"
+	stereoscopeimage "github.com/anchore/stereoscope/pkg/image"
+	"github.com/docker/index-cli-plugin/progress"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/wagoodman/go-partybus"
+)
+
+// stereoscope publishes the layers it reads on a single global bus, so the events are routed
+// to the images being indexed by the diff ids of their layers
+var (
+	layerBus      sync.Once
+	layerWatchers = make(map[*layerWatcher]bool)
+	watchersMutex sync.Mutex
+)
+
+type layerWatcher struct {
+	diffIds  map[string]bool
+	total    int
+	progress progress.Reporter
+}
+
+// watchLayers reports the layers of img extracted by stereoscope to p until the returned
+// function is called, which reports all layers as extracted if the image was read
+func watchLayers(img v1.Image, p progress.Reporter) func(read bool) {
+	config, err := img.ConfigFile()
+	if err != nil {
+		return func(bool) {}
+	}
+	w := &layerWatcher{diffIds: make(map[string]bool), total: len(config.RootFS.DiffIDs), progress: p}
+	for _, d := range config.RootFS.DiffIDs {
+		w.diffIds[d.String()] = true
+	}
+	layerBus.Do(func() {
+		bus := partybus.NewBus()
+		go dispatchLayers(bus.Subscribe(event.ReadLayer))
+		stereoscope.SetBus(bus)
+	})
+	watchersMutex.Lock()
+	layerWatchers[w] = true
+	watchersMutex.Unlock()
+	return func(read bool) {
+		watchersMutex.Lock()
+		defer watchersMutex.Unlock()
+		delete(layerWatchers, w)
+		if read {
+			w.progress.LayersExtracted(w.total, w.total)
+		}
+	}
+}
+
+// dispatchLayers reports the layers read before the one of each event to the watchers of its image
+func dispatchLayers(sub *partybus.Subscription) {
+	for e := range sub.Events() {
+		layer, ok := e.Source.(stereoscopeimage.LayerMetadata)
+		if !ok {
+			continue
+		}
+		watchersMutex.Lock()
+		for w := range layerWatchers {
+			if w.diffIds[layer.Digest] {
+				w.progress.LayersExtracted(int(layer.Index), w.total)
+			}
+		}
+		watchersMutex.Unlock()
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// Redacted replaces the values of environment variables and build args
+const Redacted = "[REDACTED]"
+
+var (
+	// build args are listed before the command of RUN instructions, e.g. |2 A=1 B=2 /bin/sh -c
+	buildArgsPattern = regexp.MustCompile(`^((?:RUN )?\|)(\d+) (.*)$`)
+	// ENV and ARG instructions of the classic builder and buildkit
+	assignmentPattern = regexp.MustCompile(`^((?:/bin/sh -c #\(nop\)\s+)?(?:ENV|ARG)\s+)(.*)$`)
+	variablePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+// RedactEnv masks the values of the environment variables and build args in the image config of
+// sb, in its raw config and in the history instructions of its layers
+func RedactEnv(sb *types.Sbom) error {
+	img := &sb.Source.Image
+	if img.Config != nil {
+		img.Config.Config.Env = redactVariables(img.Config.Config.Env)
+		for i, h := range img.Config.History {
+			img.Config.History[i].CreatedBy = RedactCreatedBy(h.CreatedBy)
+		}
+	}
+	if img.RawConfig != "" {
+		raw, err := redactRawConfig(img.RawConfig)
+		if err != nil {
+			return err
+		}
+		img.RawConfig = raw
+	}
+
+	redactLayer := func(l *types.Layer) {
+		if l != nil {
+			l.CreatedBy = RedactCreatedBy(l.CreatedBy)
+		}
+	}
+	for i := range sb.Artifacts {
+		redactLayer(sb.Artifacts[i].Layer)
+	}
+	for i := range sb.Removed {
+		redactLayer(sb.Removed[i].Layer)
+		redactLayer(sb.Removed[i].RemovedBy)
+	}
+	for i := range sb.Files {
+		redactLayer(sb.Files[i].Layer)
+	}
+	for i := range sb.Secrets {
+		redactLayer(sb.Secrets[i].Layer)
+	}
+	for i := range img.SkippedLayers {
+		redactLayer(&img.SkippedLayers[i])
+	}
+	for i := range sb.Leftovers {
+		redactLayer(sb.Leftovers[i].Layer)
+	}
+	for i := range img.History {
+		img.History[i].CreatedBy = RedactCreatedBy(img.History[i].CreatedBy)
+		redactLayer(img.History[i].Layer)
+	}
+	return nil
+}
+
+// redactRawConfig masks the variables of the base64 encoded config, keeping the other fields
+// even if the config types of go-containerregistry don't know about them
+func redactRawConfig(rawConfig string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(rawConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode raw config")
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return "", errors.Wrap(err, "failed to parse raw config")
+	}
+	for _, k := range []string{"config", "container_config"} {
+		if c, ok := config[k].(map[string]interface{}); ok {
+			if env, ok := c["Env"].([]interface{}); ok {
+				for i, v := range env {
+					if s, ok := v.(string); ok {
+						env[i] = redactVariable(s)
+					}
+				}
+			}
+		}
+	}
+	// the build info of buildkit lists the build args with their values too
+	delete(config, "moby.buildkit.buildinfo.v1")
+	if history, ok := config["history"].([]interface{}); ok {
+		for _, h := range history {
+			if h, ok := h.(map[string]interface{}); ok {
+				if createdBy, ok := h["created_by"].(string); ok {
+					h["created_by"] = RedactCreatedBy(createdBy)
+				}
+			}
+		}
+	}
+	b, err = json.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to write raw config")
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// RedactCreatedBy masks the build args of RUN instructions and the values of ENV and ARG
+// instructions in the history entry createdBy
+func RedactCreatedBy(createdBy string) string {
+	if m := buildArgsPattern.FindStringSubmatch(createdBy); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		fields := strings.SplitN(m[3], " ", n+1)
+		for i := 0; i < n && i < len(fields); i++ {
+			fields[i] = redactVariable(fields[i])
+		}
+		return m[1] + m[2] + " " + strings.Join(fields, " ")
+	}
+	if m := assignmentPattern.FindStringSubmatch(createdBy); m != nil {
+		return m[1] + redactAssignments(m[2])
+	}
+	return createdBy
+}
+
+// redactAssignments masks the values of ENV and ARG instructions, either in KEY=value or the
+// legacy KEY value form
+func redactAssignments(args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return args
+	}
+	if !strings.Contains(fields[0], "=") {
+		// ARG without a default value
+		if len(fields) == 1 {
+			return args
+		}
+		return fields[0] + " " + Redacted
+	}
+	redacted := make([]string, 0)
+	for _, f := range fields {
+		// parts of quoted values with spaces are dropped
+		if k, _, ok := strings.Cut(f, "="); ok && variablePattern.MatchString(k) {
+			redacted = append(redacted, k+"="+Redacted)
+		}
+	}
+	return strings.Join(redacted, " ")
+}
+
+func redactVariables(env []string) []string {
+	if env == nil {
+		return nil
+	}
+	redacted := make([]string, 0, len(env))
+	for _, v := range env {
+		redacted = append(redacted, redactVariable(v))
+	}
+	return redacted
+}
+
+func redactVariable(v string) string {
+	if k, _, ok := strings.Cut(v, "="); ok {
+		return k + "=" + Redacted
+	}
+	return v
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestRedactCreatedBy(t *testing.T) {
+	tests := map[string]string{
+		`RUN |2 NPM_TOKEN=abc VERSION=1.0 /bin/sh -c npm ci # buildkit`: `RUN |2 NPM_TOKEN=[REDACTED] VERSION=[REDACTED] /bin/sh -c npm ci # buildkit`,
+		`|1 TOKEN=abc /bin/sh -c make`:                                  `|1 TOKEN=[REDACTED] /bin/sh -c make`,
+		`/bin/sh -c #(nop)  ENV API_KEY=abc PATH=/usr/bin`:              `/bin/sh -c #(nop)  ENV API_KEY=[REDACTED] PATH=[REDACTED]`,
+		`/bin/sh -c #(nop)  ENV API_KEY abc def`:                        `/bin/sh -c #(nop)  ENV API_KEY [REDACTED]`,
+		`ENV GREETING="hello world"`:                                    `ENV GREETING=[REDACTED]`,
+		`ARG TOKEN=abc`:                                                 `ARG TOKEN=[REDACTED]`,
+		`ARG TOKEN`:                                                     `ARG TOKEN`,
+		`/bin/sh -c apt-get update`:                                     `/bin/sh -c apt-get update`,
+	}
+	for createdBy, expected := range tests {
+		if redacted := RedactCreatedBy(createdBy); redacted != expected {
+			t.Errorf("expected %s, got %s", expected, redacted)
+		}
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	raw := `{"architecture":"amd64","config":{"Env":["TOKEN=s3cr3t"]},"history":[{"created_by":"RUN |1 TOKEN=s3cr3t /bin/sh -c make"}],"moby.buildkit.buildinfo.v1":"e30=","os":"linux"}`
+	sb := &types.Sbom{
+		Artifacts: []types.Package{{Purl: "pkg:generic/app@1.0", Layer: &types.Layer{CreatedBy: "ENV TOKEN=s3cr3t"}}},
+	}
+	sb.Source.Image.Config = &v1.ConfigFile{
+		Config:  v1.Config{Env: []string{"TOKEN=s3cr3t", "PATH=/usr/bin"}},
+		History: []v1.History{{CreatedBy: "RUN |1 TOKEN=s3cr3t /bin/sh -c make"}},
+	}
+	sb.Source.Image.RawConfig = base64.StdEncoding.EncodeToString([]byte(raw))
+	sb.Source.Image.History = imageHistory(sb.Source.Image.Config, nil)
+
+	if err := RedactEnv(sb); err != nil {
+		t.Fatal(err)
+	}
+	if env := sb.Source.Image.Config.Config.Env; len(env) != 2 || env[0] != "TOKEN=[REDACTED]" {
+		t.Errorf("expected redacted env, got %v", env)
+	}
+	if h := sb.Source.Image.Config.History[0].CreatedBy; strings.Contains(h, "s3cr3t") {
+		t.Errorf("expected redacted history, got %s", h)
+	}
+	if h := sb.Source.Image.History[0]; strings.Contains(h.CreatedBy, "s3cr3t") || strings.Contains(h.Layer.CreatedBy, "s3cr3t") {
+		t.Errorf("expected redacted history entry, got %+v", h)
+	}
+	if l := sb.Artifacts[0].Layer.CreatedBy; l != "ENV TOKEN=[REDACTED]" {
+		t.Errorf("expected redacted layer instruction, got %s", l)
+	}
+	b, _ := base64.StdEncoding.DecodeString(sb.Source.Image.RawConfig)
+	if strings.Contains(string(b), "s3cr3t") || strings.Contains(string(b), "moby.buildkit.buildinfo.v1") || !strings.Contains(string(b), `"os":"linux"`) {
+		t.Errorf("expected redacted raw config without build info, got %s", b)
+	}
+}
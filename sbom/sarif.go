@@ -0,0 +1,262 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type SarifDocument struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool    SarifTool     `json:"tool"`
+	Results []SarifResult `json:"results"`
+}
+
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+type SarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationUri string      `json:"informationUri,omitempty"`
+	Rules          []SarifRule `json:"rules"`
+}
+
+type SarifRule struct {
+	Id               string                 `json:"id"`
+	Name             string                 `json:"name,omitempty"`
+	ShortDescription SarifMessage           `json:"shortDescription"`
+	FullDescription  *SarifMessage          `json:"fullDescription,omitempty"`
+	HelpUri          string                 `json:"helpUri,omitempty"`
+	Help             *SarifMessage          `json:"help,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+type SarifResult struct {
+	RuleId       string                 `json:"ruleId"`
+	Level        string                 `json:"level"`
+	Message      SarifMessage           `json:"message"`
+	Locations    []SarifLocation        `json:"locations"`
+	Suppressions []SarifSuppression     `json:"suppressions,omitempty"`
+	Properties   map[string]interface{} `json:"properties,omitempty"`
+}
+
+type SarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+}
+
+type SarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type SarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF writes the vulnerabilities of the sbom as SARIF 2.1 log to w
+func WriteSARIF(sb *types.Sbom, w io.Writer) error {
+	doc := ToSARIF(sb)
+	js, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(js, '\n'))
+	return err
+}
+
+// ToSARIF converts the vulnerabilities of the sbom into a SARIF 2.1 log
+func ToSARIF(sb *types.Sbom) SarifDocument {
+	run := SarifRun{
+		Tool: SarifTool{
+			Driver: SarifDriver{
+				Name:           sb.Descriptor.Name,
+				Version:        sb.Descriptor.Version,
+				InformationUri: "https://github.com/docker/index-cli-plugin",
+				Rules:          make([]SarifRule, 0),
+			},
+		},
+		Results: make([]SarifResult, 0),
+	}
+
+	rules := make(map[string]bool)
+	for _, c := range sb.Vulnerabilities {
+		if !IsAffected(c) {
+			continue
+		}
+		severity := toSeverity(c)
+		if _, ok := rules[c.SourceId]; !ok {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, toSarifRule(c, severity))
+			rules[c.SourceId] = true
+		}
+
+		pkg, ok := findPackage(sb, c.Purl)
+		msg := fmt.Sprintf("%s detected in %s", c.SourceId, c.Purl)
+		if IsFixed(c) {
+			msg += fmt.Sprintf(", fixed in %s", c.FixedBy)
+		}
+
+		result := SarifResult{
+			RuleId:  c.SourceId,
+			Level:   toSarifLevel(severity),
+			Message: SarifMessage{Text: msg},
+			Properties: map[string]interface{}{
+				"purl":             c.Purl,
+				"vulnerable_range": c.VulnerableRange,
+				"fixed_by":         c.FixedBy,
+			},
+		}
+		if c.Suppression != nil {
+			result.Suppressions = []SarifSuppression{{
+				Kind:          "external",
+				Justification: c.Suppression.Justification,
+			}}
+		}
+		if ok {
+			for _, loc := range pkg.Locations {
+				ordinal := layerOrdinal(sb, loc.DiffId)
+				result.Locations = append(result.Locations, SarifLocation{
+					PhysicalLocation: SarifPhysicalLocation{
+						ArtifactLocation: SarifArtifactLocation{Uri: strings.TrimPrefix(loc.Path, "/")},
+					},
+					LogicalLocations: []SarifLogicalLocation{{
+						Name:               fmt.Sprintf("layer %d", ordinal),
+						FullyQualifiedName: loc.Digest,
+						Kind:               "module",
+					}},
+				})
+			}
+		}
+		if len(result.Locations) == 0 {
+			result.Locations = []SarifLocation{{
+				PhysicalLocation: SarifPhysicalLocation{
+					ArtifactLocation: SarifArtifactLocation{Uri: sb.Source.Image.Name},
+				},
+			}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	return SarifDocument{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []SarifRun{run},
+	}
+}
+
+func toSarifRule(c types.Cve, severity string) SarifRule {
+	rule := SarifRule{
+		Id:               c.SourceId,
+		Name:             strings.ReplaceAll(c.SourceId, "-", ""),
+		ShortDescription: SarifMessage{Text: fmt.Sprintf("%s %s vulnerability", c.SourceId, strings.ToLower(severity))},
+		Properties: map[string]interface{}{
+			"security-severity": toSecuritySeverity(severity),
+			"tags":              []string{"security", "vulnerability", severity},
+		},
+	}
+	for _, adv := range []*types.Advisory{c.Cve, c.Advisory} {
+		if adv == nil {
+			continue
+		}
+		if rule.FullDescription == nil && adv.Description != "" {
+			rule.FullDescription = &SarifMessage{Text: adv.Description}
+		}
+		if rule.HelpUri == "" && len(adv.Urls) > 0 {
+			rule.HelpUri = adv.Urls[0].Value
+		}
+	}
+	if IsFixed(c) {
+		rule.Help = &SarifMessage{Text: fmt.Sprintf("Upgrade the affected package to %s", c.FixedBy)}
+	}
+	return rule
+}
+
+func toSarifLevel(severity string) string {
+	switch severity {
+	case "CRITICAL", "HIGH":
+		return "error"
+	case "MEDIUM":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// toSecuritySeverity maps severities onto the score ranges GitHub code scanning expects
+func toSecuritySeverity(severity string) string {
+	switch severity {
+	case "CRITICAL":
+		return "9.5"
+	case "HIGH":
+		return "8.0"
+	case "MEDIUM":
+		return "5.5"
+	case "LOW":
+		return "2.0"
+	default:
+		return "0.0"
+	}
+}
+
+func findPackage(sb *types.Sbom, purl string) (types.Package, bool) {
+	for _, p := range sb.Artifacts {
+		if p.Purl == purl {
+			return p, true
+		}
+	}
+	return types.Package{}, false
+}
+
+func layerOrdinal(sb *types.Sbom, diffId string) int {
+	if sb.Source.Image.Config == nil {
+		return -1
+	}
+	for i, l := range sb.Source.Image.Config.RootFS.DiffIDs {
+		if l.String() == diffId {
+			return i
+		}
+	}
+	return -1
+}
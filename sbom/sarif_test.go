@@ -0,0 +1,113 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func severityAdvisory(severity string) *types.Advisory {
+	return &types.Advisory{
+		Description: "use after free",
+		References:  []types.Reference{{Source: "atomist", Scores: []types.Score{{Type: "atm_severity", Value: severity}}}},
+		Urls:        []types.Url{{Name: "NVD", Value: "https://nvd.nist.gov/"}},
+	}
+}
+
+func TestToSarifLevel(t *testing.T) {
+	tests := []struct {
+		severity         string
+		level            string
+		securitySeverity string
+	}{
+		{severity: "CRITICAL", level: "error", securitySeverity: "9.5"},
+		{severity: "HIGH", level: "error", securitySeverity: "8.0"},
+		{severity: "MEDIUM", level: "warning", securitySeverity: "5.5"},
+		{severity: "LOW", level: "note", securitySeverity: "2.0"},
+		{severity: "", level: "note", securitySeverity: "0.0"},
+	}
+	for _, test := range tests {
+		if l := toSarifLevel(test.severity); l != test.level {
+			t.Errorf("%s: expected level %s, got %s", test.severity, test.level, l)
+		}
+		if s := toSecuritySeverity(test.severity); s != test.securitySeverity {
+			t.Errorf("%s: expected security severity %s, got %s", test.severity, test.securitySeverity, s)
+		}
+	}
+}
+
+func TestToSARIF(t *testing.T) {
+	diffId := v1.Hash{Algorithm: "sha256", Hex: "d1"}
+	sb := &types.Sbom{
+		Source: types.Source{Image: types.ImageSource{
+			Name:   "example/app",
+			Config: &v1.ConfigFile{RootFS: v1.RootFS{DiffIDs: []v1.Hash{{Algorithm: "sha256", Hex: "d0"}, diffId}}},
+		}},
+		Artifacts: []types.Package{{
+			Purl:      "pkg:deb/debian/openssl@1.1.1",
+			Locations: []types.Location{{Path: "/var/lib/dpkg/status", DiffId: diffId.String(), Digest: "sha256:l1"}},
+		}},
+		Vulnerabilities: []types.Cve{
+			{Purl: "pkg:deb/debian/openssl@1.1.1", SourceId: "CVE-2023-0001", FixedBy: "1.1.2", Advisory: severityAdvisory("HIGH")},
+			{Purl: "pkg:deb/debian/openssl@1.1.1", SourceId: "CVE-2023-0001", FixedBy: "1.1.2", Advisory: severityAdvisory("HIGH")},
+			{Purl: "pkg:npm/lodash@4.17.20", SourceId: "CVE-2023-0002", FixedBy: "not fixed", Suppression: &types.Suppression{Justification: "test only"}},
+			{Purl: "pkg:npm/lodash@4.17.20", SourceId: "CVE-2023-0003", Status: VexNotAffected},
+		},
+	}
+	doc := ToSARIF(sb)
+	run := doc.Runs[0]
+	if doc.Version != "2.1.0" || len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 3 {
+		t.Fatalf("expected 2 rules and 3 results of affected cves, got %+v", run)
+	}
+	tests := []struct {
+		ruleId     string
+		level      string
+		message    string
+		uri        string
+		layer      string
+		suppressed bool
+	}{
+		{ruleId: "CVE-2023-0001", level: "error", message: "CVE-2023-0001 detected in pkg:deb/debian/openssl@1.1.1, fixed in 1.1.2", uri: "var/lib/dpkg/status", layer: "layer 1"},
+		{ruleId: "CVE-2023-0001", level: "error", message: "CVE-2023-0001 detected in pkg:deb/debian/openssl@1.1.1, fixed in 1.1.2", uri: "var/lib/dpkg/status", layer: "layer 1"},
+		{ruleId: "CVE-2023-0002", level: "note", message: "CVE-2023-0002 detected in pkg:npm/lodash@4.17.20", uri: "example/app", suppressed: true},
+	}
+	for i, test := range tests {
+		r := run.Results[i]
+		if r.RuleId != test.ruleId || r.Level != test.level || r.Message.Text != test.message {
+			t.Errorf("expected result %s (%s): %s, got %+v", test.ruleId, test.level, test.message, r)
+		}
+		if len(r.Locations) != 1 || r.Locations[0].PhysicalLocation.ArtifactLocation.Uri != test.uri {
+			t.Errorf("%s: expected location %s, got %+v", test.ruleId, test.uri, r.Locations)
+		} else if test.layer != "" && (len(r.Locations[0].LogicalLocations) != 1 || r.Locations[0].LogicalLocations[0].Name != test.layer) {
+			t.Errorf("%s: expected logical location %s, got %+v", test.ruleId, test.layer, r.Locations[0].LogicalLocations)
+		}
+		if (len(r.Suppressions) == 1) != test.suppressed {
+			t.Errorf("%s: expected suppressed %v, got %+v", test.ruleId, test.suppressed, r.Suppressions)
+		}
+	}
+
+	rule := run.Tool.Driver.Rules[0]
+	if rule.Name != "CVE20230001" || rule.FullDescription == nil || rule.HelpUri != "https://nvd.nist.gov/" || rule.Help == nil || rule.Properties["security-severity"] != "8.0" {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+	if rule := run.Tool.Driver.Rules[1]; rule.Help != nil || rule.FullDescription != nil {
+		t.Errorf("expected rule without help and description, got %+v", rule)
+	}
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultMaxFileSize = 1024 * 1024
+
+// Rule detects a secret by a regular expression. If the expression has a capture group
+// and Entropy is set, the captured value must have at least that Shannon entropy.
+type Rule struct {
+	Id          string  `yaml:"id"`
+	Description string  `yaml:"description"`
+	Pattern     string  `yaml:"pattern"`
+	Entropy     float64 `yaml:"entropy,omitempty"`
+
+	regexp *regexp.Regexp
+}
+
+// Config holds the rules and limits of the secret scanner
+type Config struct {
+	Rules               []Rule   `yaml:"rules"`
+	DisableDefaultRules bool     `yaml:"disable-default-rules"`
+	AllowPaths          []string `yaml:"allow-paths"`
+	MaxFileSize         int64    `yaml:"max-file-size"`
+}
+
+var DefaultRules = []Rule{
+	{Id: "aws-access-key-id", Description: "AWS access key id", Pattern: `\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`},
+	{Id: "aws-secret-access-key", Description: "AWS secret access key", Pattern: `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`, Entropy: 3.5},
+	{Id: "private-key", Description: "Private key", Pattern: `-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----`},
+	{Id: "github-token", Description: "GitHub token", Pattern: `\b(gh[pousr]_[A-Za-z0-9]{36,255})\b`},
+	{Id: "gitlab-token", Description: "GitLab personal access token", Pattern: `\b(glpat-[A-Za-z0-9\-_]{20})\b`},
+	{Id: "slack-token", Description: "Slack token", Pattern: `\b(xox[baprs]-[A-Za-z0-9-]{10,48})\b`},
+	{Id: "stripe-secret-key", Description: "Stripe secret key", Pattern: `\b((?:sk|rk)_live_[0-9a-zA-Z]{24,99})\b`},
+	{Id: "google-api-key", Description: "Google API key", Pattern: `\b(AIza[0-9A-Za-z\-_]{35})\b`},
+	{Id: "npm-token", Description: "npm access token", Pattern: `\b(npm_[A-Za-z0-9]{36})\b`},
+	{Id: "generic-secret", Description: "Generic secret assignment", Pattern: `(?i)(?:secret|token|passw(?:or)?d|api_?key)["']?\s*[:=]\s*["']([A-Za-z0-9/+_\-.=]{16,})["']`, Entropy: 4.0},
+}
+
+// defaultAllowPaths skips documentation and test fixtures of installed packages
+var defaultAllowPaths = []string{
+	`^/usr/share/(?:doc|man|locale)/`,
+	`/(?:tests?|testdata|fixtures)/`,
+	`\.(?:md|txt|html)$`,
+}
+
+// Scanner finds secrets in the files of image layers
+type Scanner struct {
+	rules       []Rule
+	allowPaths  []*regexp.Regexp
+	maxFileSize int64
+}
+
+// ReadConfig reads a YAML secret scanner configuration from path
+func ReadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read secret rules %s", path)
+	}
+	var config Config
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse secret rules %s", path)
+	}
+	return &config, nil
+}
+
+// NewScanner compiles the rules of config; a nil config uses the default rules
+func NewScanner(config *Config) (*Scanner, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	rules := make([]Rule, 0)
+	if !config.DisableDefaultRules {
+		rules = append(rules, DefaultRules...)
+	}
+	rules = append(rules, config.Rules...)
+
+	s := Scanner{
+		rules:       make([]Rule, 0),
+		allowPaths:  make([]*regexp.Regexp, 0),
+		maxFileSize: config.MaxFileSize,
+	}
+	if s.maxFileSize <= 0 {
+		s.maxFileSize = defaultMaxFileSize
+	}
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compile secret rule %s", r.Id)
+		}
+		r.regexp = re
+		s.rules = append(s.rules, r)
+	}
+	for _, p := range append(defaultAllowPaths, config.AllowPaths...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compile allowed path %s", p)
+		}
+		s.allowPaths = append(s.allowPaths, re)
+	}
+	return &s, nil
+}
+
+// ScanLayer scans all regular files added or changed by the layer
+func (s *Scanner) ScanLayer(img *image.Image, layer *image.Layer, l *types.Layer) []types.Secret {
+	secrets := make([]types.Secret, 0)
+	for _, ref := range layer.Tree.AllFiles(file.TypeReg) {
+		path := string(ref.RealPath)
+		if s.allowed(path) {
+			continue
+		}
+		entry, err := img.FileCatalog.Get(ref)
+		if err != nil || entry.Metadata.Size > s.maxFileSize {
+			continue
+		}
+		r, err := layer.FileContents(ref.RealPath)
+		if err != nil {
+			log.Debugf("Failed to read %s for secret scanning: %s", path, err)
+			continue
+		}
+		secrets = append(secrets, s.Scan(path, r, l)...)
+		r.Close()
+	}
+	return secrets
+}
+
+// Scan reports all secrets found in the content of the file at path
+func (s *Scanner) Scan(path string, r io.Reader, l *types.Layer) []types.Secret {
+	content, err := io.ReadAll(io.LimitReader(r, s.maxFileSize))
+	if err != nil || isBinary(content) {
+		return nil
+	}
+	secrets := make([]types.Secret, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), int(s.maxFileSize))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		for _, rule := range s.rules {
+			for _, m := range rule.regexp.FindAllStringSubmatch(text, -1) {
+				value := m[0]
+				if len(m) > 1 {
+					value = m[1]
+				}
+				if rule.Entropy > 0 && entropy(value) < rule.Entropy {
+					continue
+				}
+				secrets = append(secrets, types.Secret{
+					RuleId:      rule.Id,
+					Description: rule.Description,
+					Path:        path,
+					Line:        line,
+					Match:       redact(value),
+					Layer:       l,
+				})
+			}
+		}
+	}
+	return secrets
+}
+
+func (s *Scanner) allowed(path string) bool {
+	for _, re := range s.allowPaths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBinary(content []byte) bool {
+	if len(content) > 512 {
+		content = content[:512]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// entropy returns the Shannon entropy of value in bits per character
+func entropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+	e := 0.0
+	length := float64(len([]rune(value)))
+	for _, c := range counts {
+		p := float64(c) / length
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// redact keeps only the first characters of a secret so reports don't leak it
+func redact(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return value[:4] + strings.Repeat("*", 8)
+}
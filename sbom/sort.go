@@ -0,0 +1,100 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"sort"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// SortSbom orders the packages, removed packages, locations, licenses, files, vulnerabilities
+// and secrets of sb in place, so that indexing the same image always produces byte-identical
+// JSON even though catalogers and vulnerability queries return them in varying order
+func SortSbom(sb *types.Sbom) {
+	sort.SliceStable(sb.Artifacts, func(i, j int) bool {
+		return sb.Artifacts[i].Purl < sb.Artifacts[j].Purl
+	})
+	for i := range sb.Artifacts {
+		p := &sb.Artifacts[i]
+		sortLocations(p.Locations)
+		sortLocations(p.Files)
+		sort.Strings(p.Licenses)
+	}
+	sort.SliceStable(sb.Removed, func(i, j int) bool {
+		a, b := sb.Removed[i], sb.Removed[j]
+		if a.Purl != b.Purl {
+			return a.Purl < b.Purl
+		}
+		return a.RemovedBy.Ordinal < b.RemovedBy.Ordinal
+	})
+	sort.SliceStable(sb.Files, func(i, j int) bool {
+		return sb.Files[i].Path < sb.Files[j].Path
+	})
+	sort.SliceStable(sb.Vulnerabilities, func(i, j int) bool {
+		a, b := sb.Vulnerabilities[i], sb.Vulnerabilities[j]
+		if a.Purl != b.Purl {
+			return a.Purl < b.Purl
+		}
+		if a.SourceId != b.SourceId {
+			return a.SourceId < b.SourceId
+		}
+		return a.Source < b.Source
+	})
+	for _, c := range sb.Vulnerabilities {
+		sortAdvisory(c.Advisory)
+		sortAdvisory(c.Cve)
+	}
+	sort.SliceStable(sb.Secrets, func(i, j int) bool {
+		a, b := sb.Secrets[i], sb.Secrets[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.RuleId < b.RuleId
+	})
+	if sb.Source.Image.Tags != nil {
+		sort.Strings(*sb.Source.Image.Tags)
+	}
+}
+
+func sortLocations(locations []types.Location) {
+	sort.SliceStable(locations, func(i, j int) bool {
+		a, b := locations[i], locations[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.DiffId < b.DiffId
+	})
+}
+
+func sortAdvisory(a *types.Advisory) {
+	if a == nil {
+		return
+	}
+	sort.SliceStable(a.References, func(i, j int) bool {
+		return a.References[i].Source < a.References[j].Source
+	})
+	sort.SliceStable(a.Cwes, func(i, j int) bool {
+		return a.Cwes[i].SourceId < a.Cwes[j].SourceId
+	})
+	sort.SliceStable(a.Urls, func(i, j int) bool {
+		return a.Urls[i].Value < a.Urls[j].Value
+	})
+}
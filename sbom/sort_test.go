@@ -0,0 +1,65 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestSortSbom(t *testing.T) {
+	sboms := make([]*types.Sbom, 0)
+	for _, reversed := range []bool{false, true} {
+		artifacts := []types.Package{
+			{Purl: "pkg:deb/debian/libc6@2.31", Licenses: []string{"GPL-2.0", "LGPL-2.1"}, Locations: []types.Location{{Path: "/var/lib/dpkg/status"}, {Path: "/usr/share/doc/libc6/copyright"}}},
+			{Purl: "pkg:npm/lodash@4.17.20", Locations: []types.Location{{Path: "/app/node_modules/lodash/package.json"}}},
+		}
+		cves := []types.Cve{
+			{Purl: "pkg:npm/lodash@4.17.20", SourceId: "CVE-2021-23337"},
+			{Purl: "pkg:npm/lodash@4.17.20", SourceId: "CVE-2020-28500"},
+			{Purl: "pkg:deb/debian/libc6@2.31", SourceId: "CVE-2021-33574"},
+		}
+		secrets := []types.Secret{{Path: "/app/.env", Line: 3}, {Path: "/app/.env", Line: 1}}
+		if reversed {
+			artifacts[0].Licenses = []string{"LGPL-2.1", "GPL-2.0"}
+			artifacts[0].Locations = []types.Location{artifacts[0].Locations[1], artifacts[0].Locations[0]}
+			artifacts = []types.Package{artifacts[1], artifacts[0]}
+			cves = []types.Cve{cves[2], cves[0], cves[1]}
+			secrets = []types.Secret{secrets[1], secrets[0]}
+		}
+		sboms = append(sboms, &types.Sbom{Artifacts: artifacts, Vulnerabilities: cves, Secrets: secrets})
+	}
+
+	var a, b bytes.Buffer
+	for _, sb := range sboms {
+		SortSbom(sb)
+	}
+	if err := WriteJSON(sboms[0], &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteJSON(sboms[1], &b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("expected identical output, got\n%s\n%s", a.String(), b.String())
+	}
+	if sboms[0].Vulnerabilities[0].SourceId != "CVE-2021-33574" || sboms[0].Artifacts[0].Locations[0].Path != "/usr/share/doc/libc6/copyright" {
+		t.Errorf("unexpected order %+v", sboms[0])
+	}
+}
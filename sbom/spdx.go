@@ -0,0 +1,427 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/uuid"
+	"golang.org/x/mod/module"
+)
+
+const (
+	spdxVersion     = "SPDX-2.3"
+	spdxNoAssertion = "NOASSERTION"
+	spdxDocumentId  = "SPDXRef-DOCUMENT"
+	spdxImageId     = "SPDXRef-Image"
+)
+
+type SpdxDocument struct {
+	SpdxVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SpdxId            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SpdxCreationInfo   `json:"creationInfo"`
+	Packages          []SpdxPackage      `json:"packages"`
+	Relationships     []SpdxRelationship `json:"relationships"`
+	Annotations       []SpdxAnnotation   `json:"annotations,omitempty"`
+}
+
+// SpdxAnnotation carries an annotation of the sbom descriptor as key=value comment
+type SpdxAnnotation struct {
+	Annotator      string `json:"annotator"`
+	AnnotationDate string `json:"annotationDate"`
+	AnnotationType string `json:"annotationType"`
+	Comment        string `json:"comment"`
+}
+
+type SpdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type SpdxPackage struct {
+	SpdxId                string            `json:"SPDXID"`
+	Name                  string            `json:"name"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	Homepage              string            `json:"homepage,omitempty"`
+	SourceInfo            string            `json:"sourceInfo,omitempty"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	Description           string            `json:"description,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []SpdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type SpdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type SpdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+var spdxInvalidChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// WriteSPDX writes the sbom as SPDX 2.3 JSON document to w
+func WriteSPDX(sb *types.Sbom, w io.Writer) error {
+	doc := ToSPDX(sb)
+	js, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(js, '\n'))
+	return err
+}
+
+// ToSPDX converts the sbom into a SPDX 2.3 document
+func ToSPDX(sb *types.Sbom) SpdxDocument {
+	image := sb.Source.Image
+	name := image.Name
+	if name == "" {
+		name = image.Digest
+	}
+
+	doc := SpdxDocument{
+		SpdxVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SpdxId:            spdxDocumentId,
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://dso.docker.com/spdx/%s-%s", spdxInvalidChars.ReplaceAllString(name, "-"), uuid.NewString()),
+		CreationInfo: SpdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{fmt.Sprintf("Tool: %s-%s", strings.ReplaceAll(sb.Descriptor.Name, " ", "-"), sb.Descriptor.Version)},
+		},
+		Packages: []SpdxPackage{{
+			SpdxId:                spdxImageId,
+			Name:                  name,
+			VersionInfo:           image.Digest,
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxNoAssertion,
+			CopyrightText:         spdxNoAssertion,
+			PrimaryPackagePurpose: "CONTAINER",
+			ExternalRefs:          imageExternalRefs(sb),
+		}},
+		Relationships: []SpdxRelationship{{
+			SpdxElementId:      spdxDocumentId,
+			RelationshipType:   "DESCRIBES",
+			RelatedSpdxElement: spdxImageId,
+		}},
+	}
+	for _, k := range annotationKeys(sb) {
+		doc.Annotations = append(doc.Annotations, SpdxAnnotation{
+			Annotator:      doc.CreationInfo.Creators[0],
+			AnnotationDate: doc.CreationInfo.Created,
+			AnnotationType: "OTHER",
+			Comment:        k + "=" + sb.Descriptor.Annotations[k],
+		})
+	}
+
+	ids := make(map[string]string)
+	for _, p := range sb.Artifacts {
+		ids[p.Purl] = toSpdxId(p)
+	}
+
+	for _, p := range sb.Artifacts {
+		id := ids[p.Purl]
+		pkg := SpdxPackage{
+			SpdxId:           id,
+			Name:             p.Name,
+			VersionInfo:      p.Version,
+			Supplier:         spdxNoAssertion,
+			DownloadLocation: toDownloadLocation(p),
+			Homepage:         p.Url,
+			SourceInfo:       toSourceInfo(p),
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  toSpdxLicenseExpression(p),
+			CopyrightText:    spdxNoAssertion,
+			Description:      p.Description,
+			ExternalRefs: []SpdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  p.Purl,
+			}},
+		}
+		for _, cpe := range p.Cpes {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, SpdxExternalRef{
+				ReferenceCategory: "SECURITY",
+				ReferenceType:     "cpe23Type",
+				ReferenceLocator:  cpe,
+			})
+		}
+		if p.Author != "" {
+			pkg.Supplier = "Organization: " + p.Author
+		}
+		doc.Packages = append(doc.Packages, pkg)
+
+		doc.Relationships = append(doc.Relationships, SpdxRelationship{
+			SpdxElementId:      spdxImageId,
+			RelationshipType:   "CONTAINS",
+			RelatedSpdxElement: id,
+		})
+		if parent, ok := ids[p.Parent]; ok && p.Parent != "" {
+			doc.Relationships = append(doc.Relationships, SpdxRelationship{
+				SpdxElementId:      id,
+				RelationshipType:   "GENERATED_FROM",
+				RelatedSpdxElement: parent,
+			})
+		}
+	}
+
+	return doc
+}
+
+func imageExternalRefs(sb *types.Sbom) []SpdxExternalRef {
+	image := sb.Source.Image
+	if image.Name == "" || image.Digest == "" {
+		return nil
+	}
+	parts := strings.Split(image.Name, "/")
+	purl := fmt.Sprintf("pkg:oci/%s@%s?repository_url=%s", parts[len(parts)-1], strings.ReplaceAll(image.Digest, ":", "%3A"), image.Name)
+	return []SpdxExternalRef{{
+		ReferenceCategory: "PACKAGE-MANAGER",
+		ReferenceType:     "purl",
+		ReferenceLocator:  purl,
+	}}
+}
+
+func toSpdxId(p types.Package) string {
+	return fmt.Sprintf("SPDXRef-Package-%s-%s", spdxInvalidChars.ReplaceAllString(p.Name, "-"), internal.Hash(p.Purl)[0:16])
+}
+
+// toSpdxLicenseExpression returns the normalized license expression of the package if all
+// its identifiers are valid and falls back to joining the individual licenses otherwise
+func toSpdxLicenseExpression(p types.Package) string {
+	if p.LicenseExpression != "" {
+		valid := true
+		for _, l := range p.Licenses {
+			if _, ok := toSpdxLicenseId(l); !ok {
+				valid = false
+			}
+		}
+		if valid {
+			return p.LicenseExpression
+		}
+	}
+	return toSpdxLicense(p.Licenses)
+}
+
+func toSpdxLicense(licenses []string) string {
+	if len(licenses) == 0 {
+		return spdxNoAssertion
+	}
+	ids := make([]string, 0)
+	for _, l := range licenses {
+		if l == "" {
+			continue
+		}
+		if id, ok := toSpdxLicenseId(l); ok {
+			l = id
+		} else {
+			l = "LicenseRef-" + strings.Trim(spdxInvalidChars.ReplaceAllString(l, "-"), "-")
+		}
+		if !internal.Contains(ids, l) {
+			ids = append(ids, l)
+		}
+	}
+	if len(ids) == 0 {
+		return spdxNoAssertion
+	}
+	return strings.Join(ids, " AND ")
+}
+
+// toSpdxLicenseId returns the SPDX license list identifier of l, keeping a trailing "+"
+// operator of licenses without an -or-later identifier. Identifiers not on the list need
+// to be declared as LicenseRef.
+func toSpdxLicenseId(l string) (string, bool) {
+	if strings.HasPrefix(l, "LicenseRef-") {
+		return l, true
+	}
+	if id, ok := spdxLicenseIds[strings.ToLower(l)]; ok {
+		return id, true
+	}
+	if id, ok := spdxLicenseIds[strings.ToLower(strings.TrimSuffix(l, "+"))]; ok && strings.HasSuffix(l, "+") {
+		return id + "+", true
+	}
+	return "", false
+}
+
+func toSourceInfo(p types.Package) string {
+	sources := make([]string, 0)
+	for _, loc := range p.Locations {
+		sources = append(sources, fmt.Sprintf("%s in layer %s", loc.Path, loc.DiffId))
+	}
+	if len(sources) == 0 {
+		return ""
+	}
+	return "acquired package info from " + strings.Join(sources, ", ")
+}
+
+func toDownloadLocation(p types.Package) string {
+	switch p.Type {
+	case "npm":
+		name := p.Name
+		if p.Namespace != "" {
+			name = p.Namespace + "/" + p.Name
+		}
+		return fmt.Sprintf("https://registry.npmjs.org/%s/-/%s-%s.tgz", name, p.Name, p.Version)
+	case "maven":
+		if p.Namespace != "" {
+			return fmt.Sprintf("https://repo1.maven.org/maven2/%s/%s/%s/%s-%s.jar", strings.ReplaceAll(p.Namespace, ".", "/"), p.Name, p.Version, p.Name, p.Version)
+		}
+	case "golang":
+		name := p.Name
+		if p.Namespace != "" {
+			name = p.Namespace + "/" + p.Name
+		}
+		// module paths and versions are case-encoded on the proxy, versions are normalized
+		// without their v prefix
+		path, err := module.EscapePath(name)
+		if err != nil {
+			return spdxNoAssertion
+		}
+		version, err := module.EscapeVersion("v" + strings.TrimPrefix(p.Version, "v"))
+		if err != nil {
+			return spdxNoAssertion
+		}
+		return fmt.Sprintf("https://proxy.golang.org/%s/@v/%s.zip", path, version)
+	case "gem":
+		return fmt.Sprintf("https://rubygems.org/downloads/%s-%s.gem", p.Name, p.Version)
+	case "pypi":
+		return fmt.Sprintf("https://pypi.org/project/%s/%s/", p.Name, p.Version)
+	case "cargo":
+		return fmt.Sprintf("https://crates.io/api/v1/crates/%s/%s/download", p.Name, p.Version)
+	}
+	return spdxNoAssertion
+}
+
+// FromSPDX converts a SPDX document written by ToSPDX into a sbom. Locations are restored
+// from the source info of the packages; layer digests are not part of the document.
+func FromSPDX(doc SpdxDocument) (*types.Sbom, error) {
+	return NewIndexer().fromSPDX(doc)
+}
+
+func (i *Indexer) fromSPDX(doc SpdxDocument) (*types.Sbom, error) {
+	// other tools describe the image by a package of their own
+	imageId := spdxImageId
+	for _, r := range doc.Relationships {
+		if r.SpdxElementId == spdxDocumentId && r.RelationshipType == "DESCRIBES" {
+			imageId = r.RelatedSpdxElement
+		}
+	}
+
+	var image types.ImageSource
+	purls := make(map[string]string)
+	for _, p := range doc.Packages {
+		if p.SpdxId == imageId {
+			if strings.HasPrefix(p.VersionInfo, "sha256:") {
+				image.Digest = p.VersionInfo
+			}
+			if p.Name != image.Digest {
+				image.Name = p.Name
+			}
+			continue
+		}
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				purls[p.SpdxId] = ref.ReferenceLocator
+			}
+		}
+	}
+	parents := make(map[string]string)
+	for _, r := range doc.Relationships {
+		if r.RelationshipType == "GENERATED_FROM" && purls[r.RelatedSpdxElement] != "" {
+			parents[r.SpdxElementId] = purls[r.RelatedSpdxElement]
+		}
+	}
+
+	pkgs := make([]types.Package, 0)
+	for _, p := range doc.Packages {
+		purl, ok := purls[p.SpdxId]
+		if !ok {
+			continue
+		}
+		pkg := types.Package{
+			Purl:        purl,
+			Description: p.Description,
+			Url:         p.Homepage,
+			Licenses:    fromSpdxLicense(p.LicenseDeclared),
+			Locations:   fromSourceInfo(p.SourceInfo),
+			Parent:      parents[p.SpdxId],
+		}
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "cpe23Type" {
+				pkg.Cpes = append(pkg.Cpes, ref.ReferenceLocator)
+			}
+		}
+		if strings.HasPrefix(p.Supplier, "Organization: ") {
+			pkg.Author = strings.TrimPrefix(p.Supplier, "Organization: ")
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	sb, err := i.convertedSbom(image, pkgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range doc.Annotations {
+		if k, v, ok := strings.Cut(a.Comment, "="); ok && a.AnnotationType == "OTHER" {
+			Annotate(sb, map[string]string{k: v})
+		}
+	}
+	return sb, nil
+}
+
+func fromSpdxLicense(expression string) []string {
+	licenses := make([]string, 0)
+	if expression == "" || expression == spdxNoAssertion {
+		return licenses
+	}
+	for _, l := range strings.Split(expression, " AND ") {
+		licenses = append(licenses, strings.Trim(l, "()"))
+	}
+	return licenses
+}
+
+func fromSourceInfo(sourceInfo string) []types.Location {
+	locations := make([]types.Location, 0)
+	if !strings.HasPrefix(sourceInfo, "acquired package info from ") {
+		return locations
+	}
+	for _, s := range strings.Split(strings.TrimPrefix(sourceInfo, "acquired package info from "), ", ") {
+		if path, diffId, ok := strings.Cut(s, " in layer "); ok {
+			locations = append(locations, types.Location{Path: path, DiffId: diffId})
+		}
+	}
+	return locations
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestToSPDX(t *testing.T) {
+	sb := &types.Sbom{
+		Source: types.Source{
+			Image: types.ImageSource{
+				Name:   "index.docker.io/library/alpine",
+				Digest: "sha256:1234",
+			},
+		},
+		Artifacts: []types.Package{{
+			Type:     "alpine",
+			Name:     "busybox",
+			Version:  "1.35.0-r17",
+			Purl:     "pkg:alpine/busybox@1.35.0-r17?os_name=alpine&os_version=3.16",
+			Licenses: []string{"GPL-2.0-only"},
+			Parent:   "pkg:alpine/busybox-src@1.35.0-r17?os_name=alpine&os_version=3.16",
+		}, {
+			Type:     "alpine",
+			Name:     "busybox-src",
+			Version:  "1.35.0-r17",
+			Purl:     "pkg:alpine/busybox-src@1.35.0-r17?os_name=alpine&os_version=3.16",
+			Licenses: []string{"GPL 2 or later"},
+		}},
+	}
+	doc := ToSPDX(sb)
+	if doc.SpdxVersion != "SPDX-2.3" {
+		t.Errorf("expected SPDX-2.3, got %s", doc.SpdxVersion)
+	}
+	if len(doc.Packages) != 3 {
+		t.Errorf("expected 3 packages, got %d", len(doc.Packages))
+	}
+	if len(doc.Relationships) != 4 {
+		t.Errorf("expected 4 relationships, got %d", len(doc.Relationships))
+	}
+	if l := doc.Packages[2].LicenseDeclared; l != "LicenseRef-GPL-2-or-later" {
+		t.Errorf("unexpected license %s", l)
+	}
+}
+
+func TestToSpdxLicense(t *testing.T) {
+	tests := []struct {
+		licenses []string
+		expected string
+	}{
+		{nil, "NOASSERTION"},
+		{[]string{"MIT"}, "MIT"},
+		{[]string{"apache-2.0"}, "Apache-2.0"},
+		{[]string{"GPL-2.0+"}, "GPL-2.0-or-later"},
+		{[]string{"Apache-2.0+"}, "Apache-2.0+"},
+		{[]string{"GPLv2"}, "LicenseRef-GPLv2"},
+		{[]string{"MIT", "BSD"}, "MIT AND LicenseRef-BSD"},
+	}
+	for _, test := range tests {
+		if l := toSpdxLicense(test.licenses); l != test.expected {
+			t.Errorf("expected %s for %v, got %s", test.expected, test.licenses, l)
+		}
+	}
+}
+
+func TestToDownloadLocation(t *testing.T) {
+	tests := []struct {
+		pkg      types.Package
+		expected string
+	}{
+		{types.Package{Type: "golang", Namespace: "github.com/BurntSushi", Name: "toml", Version: "1.2.0"}, "https://proxy.golang.org/github.com/!burnt!sushi/toml/@v/v1.2.0.zip"},
+		{types.Package{Type: "golang", Namespace: "golang.org/x", Name: "net", Version: "v0.0.0-20220909164309-bea034e7d591"}, "https://proxy.golang.org/golang.org/x/net/@v/v0.0.0-20220909164309-bea034e7d591.zip"},
+		{types.Package{Type: "golang", Name: "stdlib", Version: "(devel)"}, "NOASSERTION"},
+	}
+	for _, test := range tests {
+		if l := toDownloadLocation(test.pkg); l != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, l)
+		}
+	}
+}
@@ -17,28 +17,35 @@
 package sbom
 
 import (
+	"context"
+	"os"
 	"strings"
+	"unicode"
 
 	"github.com/anchore/packageurl-go"
 	stereoscopeimage "github.com/anchore/stereoscope/pkg/image"
-	"github.com/anchore/syft/syft"
 	"github.com/anchore/syft/syft/artifact"
 	"github.com/anchore/syft/syft/linux"
 	pkg2 "github.com/anchore/syft/syft/pkg"
 	"github.com/anchore/syft/syft/pkg/cataloger"
 	"github.com/anchore/syft/syft/pkg/cataloger/apkdb"
+	"github.com/anchore/syft/syft/pkg/cataloger/dart"
 	"github.com/anchore/syft/syft/pkg/cataloger/deb"
+	"github.com/anchore/syft/syft/pkg/cataloger/php"
 	"github.com/anchore/syft/syft/pkg/cataloger/rpm"
+	"github.com/anchore/syft/syft/pkg/cataloger/ruby"
 	"github.com/anchore/syft/syft/source"
 	"github.com/docker/index-cli-plugin/sbom/detect"
+	"github.com/docker/index-cli-plugin/sbom/secrets"
 	"github.com/docker/index-cli-plugin/sbom/util"
+	"github.com/docker/index-cli-plugin/tracing"
 	"github.com/docker/index-cli-plugin/types"
 	"github.com/pkg/errors"
 )
 
 type packageMapping map[string]*stereoscopeimage.Layer
 
-func syftSbom(ociPath string, lm types.LayerMapping, resultChan chan<- types.IndexResult) {
+func syftSbom(ctx context.Context, input imageInput, lm types.LayerMapping, enabled func(string) bool, scanner *secrets.Scanner, inventory bool, leftovers bool, waste bool, resultChan chan<- types.IndexResult) {
 	result := types.IndexResult{
 		Name:     "syft",
 		Status:   types.Success,
@@ -47,60 +54,90 @@ func syftSbom(ociPath string, lm types.LayerMapping, resultChan chan<- types.Ind
 
 	defer close(resultChan)
 
-	i := source.Input{
-		Scheme:      source.ImageScheme,
-		ImageSource: stereoscopeimage.OciDirectorySource,
-		Location:    ociPath,
-	}
-	src, cleanup, err := source.New(i, nil, nil)
+	ctx, span := tracing.Start(ctx, "syft", tracing.Digest(input.digest))
+	defer func() {
+		input.progress.CatalogerCompleted(result.Name, len(result.Packages), result.Error)
+		tracing.End(span, result.Error)
+	}()
+
+	src, cleanup, err := syftSource(ctx, input)
+	defer cleanup()
 	if err != nil {
 		result.Status = types.Failed
 		result.Error = errors.Wrap(err, "failed to create image source")
+		resultChan <- result
+		return
 	}
-	defer cleanup()
 
-	packageCatalog, packageRelationships, distro, err := syft.CatalogPackages(src, cataloger.DefaultConfig())
+	packageCatalog, packageRelationships, distro, err := catalogPackages(src, enabled)
 	if err != nil {
 		result.Status = types.Failed
 		result.Error = errors.Wrap(err, "failed to index image")
+		resultChan <- result
+		return
 	}
 
 	d, qualifiers := osQualifiers(distro)
 	result.Distro = d
 
 	pm := make(packageMapping, 0)
+	installed := make(packageDatabases)
+	removed := make(map[string]removedPackage)
+	// attribute packages to the layer that installed them last, after earlier layers removed them
+	apply := func(layer *stereoscopeimage.Layer, l *types.Layer, dbs map[string][]string, w whiteouts) {
+		added, deleted := installed.apply(dbs, w)
+		for _, k := range added {
+			pm[k] = layer
+			delete(removed, k)
+		}
+		for k, db := range deleted {
+			removed[k] = removedPackage{db: db, layer: pm[k], removedBy: l}
+		}
+	}
 	for _, layer := range src.Image.Layers {
+		if ctx.Err() != nil {
+			result.Status = types.Failed
+			result.Error = ctx.Err()
+			resultChan <- result
+			return
+		}
+		// the stereoscope layers use diff_ids internally as their digest
+		diffId := layer.Metadata.Digest
+		l := &types.Layer{
+			Ordinal: lm.OrdinalByDiffId[diffId],
+			DiffId:  diffId,
+			Digest:  lm.ByDiffId[diffId],
+		}
 		layerPkgs := make([]pkg2.Package, 0)
 		res := util.NewSingleLayerResolver(layer)
 		apkPkgs, _, err := apkdb.NewApkdbCataloger().Catalog(res)
 		if err != nil {
-			if err != nil {
-				result.Status = types.Failed
-				result.Error = errors.Wrap(err, "failed to catalog apk packages")
-			}
+			result.Status = types.Failed
+			result.Error = errors.Wrap(err, "failed to catalog apk packages")
 		}
 		layerPkgs = append(layerPkgs, apkPkgs...)
 		debPkgs, _, err := deb.NewDpkgdbCataloger().Catalog(res)
 		if err != nil {
-			if err != nil {
-				result.Status = types.Failed
-				result.Error = errors.Wrap(err, "failed to catalog dep packages")
-			}
+			result.Status = types.Failed
+			result.Error = errors.Wrap(err, "failed to catalog dep packages")
 		}
 		layerPkgs = append(layerPkgs, debPkgs...)
 		rpmPkgs, _, err := rpm.NewRpmdbCataloger().Catalog(res)
 		if err != nil {
-			if err != nil {
-				result.Status = types.Failed
-				result.Error = errors.Wrap(err, "failed to catalog rpm packages")
-			}
+			result.Status = types.Failed
+			result.Error = errors.Wrap(err, "failed to catalog rpm packages")
 		}
 		layerPkgs = append(layerPkgs, rpmPkgs...)
+		dbs := make(map[string][]string)
 		for _, p := range layerPkgs {
-			if _, ok := pm[toKey(p)]; !ok {
-				pm[toKey(p)] = layer
+			for _, loc := range p.Locations.ToSlice() {
+				dbs[loc.RealPath] = append(dbs[loc.RealPath], toKey(p))
 			}
 		}
+		apply(layer, l, dbs, layerWhiteouts(layer))
+		if scanner != nil {
+			result.Secrets = append(result.Secrets, scanner.ScanLayer(src.Image, layer, l)...)
+		}
 	}
 
 	result.Packages = make([]types.Package, 0)
@@ -109,11 +146,114 @@ func syftSbom(ociPath string, lm types.LayerMapping, resultChan chan<- types.Ind
 		pkg := toPackage(p, packageRelationships, qualifiers, lm, pm)
 		result.Packages = append(result.Packages, pkg...)
 	}
+	result.Removed = removedPackages(removed, d, qualifiers, lm)
 
 	result.Packages = append(result.Packages, detect.AdditionalPackages(result.Packages, *src, lm)...)
+	if enabled(detect.BundlerCataloger) {
+		detect.BundlerGroups(result.Packages, *src)
+	}
+	if enabled(detect.JavascriptCataloger) {
+		result.Packages = append(result.Packages, detect.JavascriptPackages(result.Packages, *src, lm)...)
+	}
+	if enabled(detect.CondaCataloger) {
+		result.Packages = append(result.Packages, detect.CondaPackages(result.Packages, *src, lm)...)
+	}
+	if enabled(detect.NixCataloger) {
+		result.Packages = append(result.Packages, detect.NixPackages(result.Packages, *src, lm)...)
+	}
+	if enabled(detect.BitnamiCataloger) {
+		result.Packages = append(result.Packages, detect.BitnamiPackages(result.Packages, *src, lm)...)
+	}
+	if enabled(detect.BinaryCataloger) {
+		result.Packages = append(result.Packages, detect.BinaryPackages(result.Packages, *src, lm)...)
+	}
+	if inventory {
+		if result.Files, err = fileInventory(ctx, src, lm); err != nil {
+			result.Status = types.Failed
+			result.Error = errors.Wrap(err, "failed to record files")
+		}
+	}
+	if leftovers {
+		if result.CacheFiles, err = leftoverFiles(ctx, src, lm); err != nil {
+			result.Status = types.Failed
+			result.Error = errors.Wrap(err, "failed to find package caches")
+		}
+	}
+	if waste {
+		if result.Waste, err = analyzeWaste(ctx, src.Image, lm); err != nil {
+			result.Status = types.Failed
+			result.Error = errors.Wrap(err, "failed to analyze wasted space")
+		}
+	}
 	resultChan <- result
 }
 
+// catalogPackages is syft.CatalogPackages with only the image catalogers that are enabled
+func catalogPackages(src *source.Source, enabled func(string) bool) (*pkg2.Catalog, []artifact.Relationship, *linux.Release, error) {
+	cfg := cataloger.DefaultConfig()
+	// look for Java archives in tar files as well, e.g. in application server distributions
+	cfg.Search.IncludeUnindexedArchives = true
+	resolver, err := src.FileResolver(cfg.Search.Scope)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to create file resolver")
+	}
+	release := linux.IdentifyRelease(resolver)
+	catalogers := make([]cataloger.Cataloger, 0)
+	// lock files of applications copied into images list their dependencies as well
+	lockCatalogers := []cataloger.Cataloger{
+		php.NewPHPComposerLockCataloger(),
+		ruby.NewGemFileLockCataloger(),
+		dart.NewPubspecLockCataloger(),
+	}
+	for _, c := range append(cataloger.ImageCatalogers(cfg), lockCatalogers...) {
+		if enabled(c.Name()) {
+			catalogers = append(catalogers, c)
+		}
+	}
+	catalog, relationships, err := cataloger.Catalog(resolver, release, catalogers...)
+	if release == nil && catalog != nil {
+		release = releaseFromPackages(catalog)
+	}
+	return catalog, relationships, release, err
+}
+
+// syftSource reads the image from its OCI layout or, when streaming, unpacks the layers of the
+// remote image into a temporary directory removed by the returned cleanup function
+func syftSource(ctx context.Context, input imageInput) (*source.Source, func(), error) {
+	if input.path != "" {
+		i := source.Input{
+			Scheme:      source.ImageScheme,
+			ImageSource: stereoscopeimage.OciDirectorySource,
+			Location:    input.path,
+		}
+		stop := watchLayers(input.image, input.progress)
+		src, cleanup, err := source.New(i, nil, nil)
+		stop(err == nil)
+		if cleanup == nil {
+			cleanup = func() {}
+		}
+		return src, cleanup, err
+	}
+	dir, err := os.MkdirTemp("", "docker-index-")
+	if err != nil {
+		return nil, func() {}, err
+	}
+	img := stereoscopeimage.NewImage(input.image, dir)
+	cleanup := func() {
+		_ = img.Cleanup()
+	}
+	_, span := tracing.Start(ctx, "extract layers", tracing.Digest(input.digest))
+	stop := watchLayers(input.image, input.progress)
+	err = img.Read()
+	stop(err == nil)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	src, err := source.NewFromImage(img, input.name)
+	return &src, cleanup, err
+}
+
 type sourcePackage struct {
 	name               string
 	overwriteNamespace bool
@@ -127,6 +267,9 @@ func toPackage(p pkg2.Package, rels []artifact.Relationship, qualifiers map[stri
 		Licenses:  p.Licenses,
 		Locations: make([]types.Location, 0),
 	}
+	if p.FoundBy != "" {
+		pkg.FoundBy = []string{"syft/" + p.FoundBy}
+	}
 
 	var sourceNameAndVersion sourcePackage
 	var virtualPath string
@@ -170,6 +313,15 @@ func toPackage(p pkg2.Package, rels []artifact.Relationship, qualifiers map[stri
 		}
 	case pkg2.GolangBinMetadataType:
 		md := p.Metadata.(pkg2.GolangBinMetadata)
+		// only the main module carries the build settings
+		if revision := md.BuildSettings["vcs.revision"]; revision != "" {
+			pkg.Vcs = &types.Vcs{
+				Type:     md.BuildSettings["vcs"],
+				Revision: revision,
+				Time:     md.BuildSettings["vcs.time"],
+				Modified: md.BuildSettings["vcs.modified"] == "true",
+			}
+		}
 		sourceNameAndVersion = sourcePackage{
 			name:               "stdlib",
 			overwriteNamespace: true,
@@ -203,7 +355,7 @@ func toPackage(p pkg2.Package, rels []artifact.Relationship, qualifiers map[stri
 		pkg.Size = md.InstalledSize
 	case pkg2.JavaMetadataType:
 		md := p.Metadata.(pkg2.JavaMetadata)
-		virtualPath = md.VirtualPath
+		virtualPath = javaPath(md)
 	case pkg2.ConanLockMetadataType:
 	case pkg2.CocoapodsMetadataType:
 	case pkg2.KbPackageMetadataType:
@@ -257,10 +409,21 @@ func toPackage(p pkg2.Package, rels []artifact.Relationship, qualifiers map[stri
 		}
 	}
 
-	// bring qualifiers into form we understand
+	// bring qualifiers into form we understand, keeping the arch, distro and epoch qualifiers
+	// of syft for types.NormalizePackages to select from
 	purl, _ := packageurl.FromString(pkg.Purl)
-	if purl.Type == "deb" || purl.Type == "rpm" || purl.Type == "alpine" {
-		purl.Qualifiers = packageurl.QualifiersFromMap(qualifiers)
+	osPackage := purl.Type == "deb" || purl.Type == "rpm" || purl.Type == "alpine"
+	if osPackage {
+		q := make(map[string]string)
+		for k, v := range qualifiers {
+			q[k] = v
+		}
+		for _, k := range []string{"arch", "distro", "epoch"} {
+			if v := purl.Qualifiers.Map()[k]; v != "" {
+				q[k] = v
+			}
+		}
+		purl.Qualifiers = packageurl.QualifiersFromMap(q)
 	}
 	purl.Version = p.Version
 	pkg.Purl = purl.String()
@@ -270,6 +433,13 @@ func toPackage(p pkg2.Package, rels []artifact.Relationship, qualifiers map[stri
 		if sourceNameAndVersion.overwriteNamespace {
 			purl.Namespace = ""
 		}
+		// arch and epoch of the binary package don't apply to its source package
+		if osPackage {
+			q := purl.Qualifiers.Map()
+			delete(q, "arch")
+			delete(q, "epoch")
+			purl.Qualifiers = packageurl.QualifiersFromMap(q)
+		}
 		purl.Name = sourceNameAndVersion.name
 		if sourceNameAndVersion.version != "" {
 			purl.Version = sourceNameAndVersion.version
@@ -284,6 +454,7 @@ func toPackage(p pkg2.Package, rels []artifact.Relationship, qualifiers map[stri
 			InstalledSize: pkg.InstalledSize,
 			Url:           pkg.Url,
 			Locations:     pkg.Locations,
+			FoundBy:       pkg.FoundBy,
 		}
 		if sourceNameAndVersion.relationship == "parent" {
 			pkg.Parent = url
@@ -294,6 +465,21 @@ func toPackage(p pkg2.Package, rels []artifact.Relationship, qualifiers map[stri
 	return []types.Package{pkg}
 }
 
+// javaPath returns the path chain of a Java package in nested archives, separated by !/ like in
+// jar URLs, e.g. /app/app.war!/WEB-INF/lib/app.jar!/META-INF/maven/org.apache.logging.log4j/log4j-core/pom.properties
+// for a library shaded into a jar of a war. syft separates the archives with colons and ends the
+// virtual path with the artifact id for packages read from pom.properties files.
+func javaPath(md pkg2.JavaMetadata) string {
+	if md.VirtualPath == "" {
+		return ""
+	}
+	parts := strings.Split(md.VirtualPath, ":")
+	if p := md.PomProperties; p != nil && p.Path != "" && len(parts) > 1 && parts[len(parts)-1] == p.ArtifactID {
+		parts[len(parts)-1] = strings.TrimPrefix(p.Path, "/")
+	}
+	return strings.Join(parts, "!/")
+}
+
 func osQualifiers(release *linux.Release) (types.Distro, map[string]string) {
 	qualifiers := make(map[string]string, 0)
 	distro := types.Distro{}
@@ -305,17 +491,23 @@ func osQualifiers(release *linux.Release) (types.Distro, map[string]string) {
 	} else if release.Name != "" {
 		distro.OsName = release.Name
 	}
-	if release.Version != "" {
+	// distroless images keep the os-release of Debian with a VERSION of Debian GNU/Linux 11 (bullseye)
+	if release.Version != "" && unicode.IsDigit(rune(release.Version[0])) {
 		distro.OsVersion = release.Version
 	} else if release.VersionID != "" {
 		distro.OsVersion = release.VersionID
+	} else {
+		distro.OsVersion = release.Version
 	}
 
 	if v, ok := types.NamespaceMapping[distro.OsName]; ok {
 		distro.OsName = v
 	}
 
-	if distro.OsVersion != "" {
+	if distro.OsName == "wolfi" {
+		// wolfi is a rolling distro with the build date as VERSION_ID
+		distro.OsVersion = "rolling"
+	} else if distro.OsVersion != "" {
 		// alpine: with comma
 		// amazonlinux: single digit
 		// debian: single digit
@@ -0,0 +1,248 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/anchore/syft/syft/formats/syftjson/model"
+	"github.com/anchore/syft/syft/linux"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// syftJSONSchemaVersion is the syft JSON schema version of the vendored syft release
+const syftJSONSchemaVersion = "4.1.0"
+
+// WriteSyftJSON writes the sbom as syft JSON document to w
+func WriteSyftJSON(sb *types.Sbom, w io.Writer) error {
+	doc := ToSyftJSON(sb)
+	js, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(js, '\n'))
+	return err
+}
+
+// ToSyftJSON converts the sbom into a syft JSON document. Locations refer to their layer by
+// diff id like syft does.
+func ToSyftJSON(sb *types.Sbom) model.Document {
+	image := sb.Source.Image
+	doc := model.Document{
+		Artifacts:             make([]model.Package, 0),
+		ArtifactRelationships: make([]model.Relationship, 0),
+		Source: model.Source{
+			ID:     image.Digest,
+			Type:   "image",
+			Target: toSyftImageMetadata(image),
+		},
+		Distro: model.LinuxRelease{
+			ID:              image.Distro.OsName,
+			VersionID:       image.Distro.OsVersion,
+			VersionCodename: image.Distro.OsDistro,
+		},
+		Descriptor: model.Descriptor{
+			Name:    sb.Descriptor.Name,
+			Version: sb.Descriptor.Version,
+		},
+		Schema: model.Schema{
+			Version: syftJSONSchemaVersion,
+			URL:     fmt.Sprintf("https://raw.githubusercontent.com/anchore/syft/main/schema/json/schema-%s.json", syftJSONSchemaVersion),
+		},
+	}
+
+	ids := make(map[string]string)
+	for _, p := range sb.Artifacts {
+		ids[p.Purl] = internal.Hash(p.Purl)[0:16]
+	}
+	for _, p := range sb.Artifacts {
+		locations := make([]source.Coordinates, 0)
+		for _, loc := range p.Locations {
+			locations = append(locations, source.Coordinates{RealPath: loc.Path, FileSystemID: loc.DiffId})
+		}
+		licenses := p.Licenses
+		if licenses == nil {
+			licenses = make([]string, 0)
+		}
+		cpes := p.Cpes
+		if cpes == nil {
+			cpes = make([]string, 0)
+		}
+		doc.Artifacts = append(doc.Artifacts, model.Package{
+			PackageBasicData: model.PackageBasicData{
+				ID:        ids[p.Purl],
+				Name:      p.Name,
+				Version:   p.Version,
+				Type:      pkg.TypeFromPURL(p.Purl),
+				FoundBy:   "docker-index",
+				Locations: locations,
+				Licenses:  licenses,
+				CPEs:      cpes,
+				PURL:      p.Purl,
+			},
+		})
+		if parent, ok := ids[p.Parent]; ok && p.Parent != "" {
+			doc.ArtifactRelationships = append(doc.ArtifactRelationships, model.Relationship{
+				Parent: parent,
+				Child:  ids[p.Purl],
+				Type:   "contains",
+			})
+		}
+	}
+	return doc
+}
+
+func toSyftImageMetadata(image types.ImageSource) source.ImageMetadata {
+	metadata := source.ImageMetadata{
+		UserInput:      image.Name,
+		ManifestDigest: image.Digest,
+		Tags:           make([]string, 0),
+		Size:           image.Size,
+		Layers:         make([]source.LayerMetadata, 0),
+		RawManifest:    []byte(image.RawManifest),
+		RawConfig:      []byte(image.RawConfig),
+		RepoDigests:    make([]string, 0),
+		Architecture:   image.Platform.Architecture,
+		Variant:        image.Platform.Variant,
+		OS:             image.Platform.Os,
+	}
+	if image.Tags != nil {
+		metadata.Tags = append(metadata.Tags, *image.Tags...)
+	}
+	if image.Name != "" && image.Digest != "" {
+		metadata.RepoDigests = append(metadata.RepoDigests, image.Name+"@"+image.Digest)
+	}
+	if image.Manifest != nil {
+		metadata.ID = image.Manifest.Config.Digest.String()
+		metadata.MediaType = string(image.Manifest.MediaType)
+	}
+	if image.Manifest != nil && image.Config != nil && len(image.Manifest.Layers) == len(image.Config.RootFS.DiffIDs) {
+		for i, l := range image.Manifest.Layers {
+			// stereoscope identifies layers by diff id
+			metadata.Layers = append(metadata.Layers, source.LayerMetadata{
+				MediaType: string(l.MediaType),
+				Digest:    image.Config.RootFS.DiffIDs[i].String(),
+				Size:      l.Size,
+			})
+		}
+	}
+	return metadata
+}
+
+// FromSyftJSON converts a syft JSON document of an image into a sbom. Layer digests of
+// locations are restored from the image manifest included in the document.
+func FromSyftJSON(doc model.Document) (*types.Sbom, error) {
+	return NewIndexer().fromSyftJSON(doc)
+}
+
+func (i *Indexer) fromSyftJSON(doc model.Document) (*types.Sbom, error) {
+	metadata, ok := doc.Source.Target.(source.ImageMetadata)
+	if !ok {
+		return nil, errors.Errorf("unsupported syft source type: %s", doc.Source.Type)
+	}
+
+	image := types.ImageSource{
+		Name:        metadata.UserInput,
+		Digest:      metadata.ManifestDigest,
+		RawManifest: string(metadata.RawManifest),
+		RawConfig:   string(metadata.RawConfig),
+		Size:        metadata.Size,
+		Platform: types.Platform{
+			Os:           metadata.OS,
+			Architecture: metadata.Architecture,
+			Variant:      metadata.Variant,
+		},
+	}
+	if len(metadata.Tags) > 0 {
+		tags := append([]string{}, metadata.Tags...)
+		image.Tags = &tags
+	}
+	if len(metadata.RawManifest) > 0 {
+		manifest, err := v1.ParseManifest(bytes.NewReader(metadata.RawManifest))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse image manifest")
+		}
+		image.Manifest = manifest
+	}
+	if len(metadata.RawConfig) > 0 {
+		config, err := v1.ParseConfigFile(bytes.NewReader(metadata.RawConfig))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse image config")
+		}
+		image.Config = config
+	}
+	if doc.Distro.ID != "" || doc.Distro.Name != "" {
+		image.Distro, _ = osQualifiers(&linux.Release{
+			ID:              doc.Distro.ID,
+			Name:            doc.Distro.Name,
+			Version:         doc.Distro.Version,
+			VersionID:       doc.Distro.VersionID,
+			VersionCodename: doc.Distro.VersionCodename,
+		})
+	}
+	digests := layerDigests(image)
+
+	purls := make(map[string]string)
+	for _, p := range doc.Artifacts {
+		purls[p.ID] = p.PURL
+	}
+	parents := make(map[string]string)
+	for _, r := range doc.ArtifactRelationships {
+		if r.Type == "contains" && purls[r.Parent] != "" {
+			parents[r.Child] = purls[r.Parent]
+		}
+	}
+
+	pkgs := make([]types.Package, 0)
+	for _, p := range doc.Artifacts {
+		if p.PURL == "" {
+			continue
+		}
+		locations := make([]types.Location, 0)
+		for _, loc := range p.Locations {
+			locations = append(locations, types.Location{Path: loc.RealPath, DiffId: loc.FileSystemID, Digest: digests[loc.FileSystemID]})
+		}
+		pkgs = append(pkgs, types.Package{
+			Purl:      p.PURL,
+			Cpes:      p.CPEs,
+			Licenses:  p.Licenses,
+			Locations: locations,
+			Parent:    parents[p.ID],
+		})
+	}
+	return i.convertedSbom(image, pkgs)
+}
+
+// layerDigests maps the diff ids of the image layers onto their digests
+func layerDigests(image types.ImageSource) map[string]string {
+	digests := make(map[string]string)
+	if image.Manifest == nil || image.Config == nil || len(image.Manifest.Layers) != len(image.Config.RootFS.DiffIDs) {
+		return digests
+	}
+	for i, l := range image.Manifest.Layers {
+		digests[image.Config.RootFS.DiffIDs[i].String()] = l.Digest.String()
+	}
+	return digests
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// templateFuncs are available in output templates in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	"severity": Severity,
+	"fixed":    IsFixed,
+	"affected": IsAffected,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"join":     strings.Join,
+	"json": func(v interface{}) (string, error) {
+		js, err := json.Marshal(v)
+		return string(js), err
+	},
+}
+
+// ParseTemplate parses the Go template used by the template format, see WithTemplate; text
+// starting with @ names a file to read the template from. It returns nil for empty text.
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	name := "template"
+	if strings.HasPrefix(text, "@") {
+		name = text[1:]
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read template %s", name)
+		}
+		text = string(b)
+	}
+	t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse template")
+	}
+	return t, nil
+}
+
+// WriteTemplate renders the sbom with the template t to w
+func WriteTemplate(sb *types.Sbom, t *template.Template, w io.Writer) error {
+	if t == nil {
+		return errors.New("template format requires a template, set it with --template")
+	}
+	if err := t.Execute(w, sb); err != nil {
+		return errors.Wrap(err, "failed to render template")
+	}
+	return nil
+}
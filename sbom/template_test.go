@@ -0,0 +1,70 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+func TestWriteTemplate(t *testing.T) {
+	critical := cveWithSeverity("CVE-2022-0001", "CRITICAL")
+	critical.Purl, critical.FixedBy = "pkg:deb/debian/openssl@1.1.1n", "1.1.1o"
+	sb := &types.Sbom{
+		Source:          types.Source{Image: types.ImageSource{Name: "app"}},
+		Artifacts:       []types.Package{{Name: "openssl", Licenses: []string{"Apache-2.0", "OpenSSL"}}},
+		Vulnerabilities: []types.Cve{critical},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFormat(sb, FormatTemplate, &buf); err == nil {
+		t.Error("expected error without template")
+	}
+
+	tmpl, err := ParseTemplate(`{{.Source.Image.Name}}{{range .Vulnerabilities}} {{.SourceId}} {{severity . | lower}} {{if fixed .}}{{.FixedBy}}{{end}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := WriteFormat(sb, FormatTemplate, &buf, WithTemplate(tmpl)); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != "app CVE-2022-0001 critical 1.1.1o" {
+		t.Errorf("unexpected template output %q", out)
+	}
+
+	file := filepath.Join(t.TempDir(), "licenses.tmpl")
+	_ = os.WriteFile(file, []byte(`{{range .Artifacts}}{{.Name}}: {{join .Licenses ", "}} {{json .Licenses}}{{end}}`), 0644)
+	if tmpl, err = ParseTemplate("@" + file); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := WriteFormat(sb, FormatTemplate, &buf, WithTemplate(tmpl)); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != `openssl: Apache-2.0, OpenSSL ["Apache-2.0","OpenSSL"]` {
+		t.Errorf("unexpected template output %q", out)
+	}
+
+	if _, err := ParseTemplate("{{.Missing"); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
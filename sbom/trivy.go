@@ -31,12 +31,16 @@ import (
 	aimage "github.com/aquasecurity/trivy/pkg/fanal/artifact/image"
 	"github.com/aquasecurity/trivy/pkg/fanal/cache"
 	"github.com/aquasecurity/trivy/pkg/fanal/image"
-	"github.com/aquasecurity/trivy/pkg/fanal/utils"
+	ftypes "github.com/aquasecurity/trivy/pkg/fanal/types"
+	"github.com/docker/index-cli-plugin/tracing"
 	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/pkg/errors"
 )
 
-func trivySbom(ociPath string, lm types.LayerMapping, resultChan chan<- types.IndexResult) {
+// trivySbom catalogs the image with trivy which caches the analysis of every layer by diff id
+// in cacheDir
+func trivySbom(ctx context.Context, input imageInput, lm types.LayerMapping, cacheDir string, disabled []analyzer.Type, resultChan chan<- types.IndexResult) {
 	result := types.IndexResult{
 		Name:     "trivy",
 		Status:   types.Success,
@@ -45,33 +49,70 @@ func trivySbom(ociPath string, lm types.LayerMapping, resultChan chan<- types.In
 
 	defer close(resultChan)
 
-	cacheClient, err := initializeCache()
+	ctx, span := tracing.Start(ctx, "trivy", tracing.Digest(input.digest))
+	defer func() {
+		input.progress.CatalogerCompleted(result.Name, len(result.Packages), result.Error)
+		tracing.End(span, result.Error)
+	}()
+
+	cacheClient, err := initializeCache(cacheDir)
 	if err != nil {
 		result.Status = types.Failed
 		result.Error = errors.Wrap(err, "failed to initialize cache")
+		resultChan <- result
+		return
 	}
 	defer cacheClient.Close()
 
-	img, err := image.NewArchiveImage(ociPath)
+	img, err := trivyImage(input)
 	if err != nil {
 		result.Status = types.Failed
 		result.Error = errors.Wrap(err, "failed to open archived image")
+		resultChan <- result
+		return
 	}
 
-	art, err := aimage.NewArtifact(img, cacheClient, artifact.Option{})
+	art, err := aimage.NewArtifact(img, cacheClient, artifact.Option{DisabledAnalyzers: disabled})
 	if err != nil {
 		result.Status = types.Failed
 		result.Error = errors.Wrap(err, "failed to create new artifact")
+		resultChan <- result
+		return
 	}
 
-	imageInfo, err := art.Inspect(context.Background())
+	imageInfo, err := art.Inspect(ctx)
 	if err != nil {
 		result.Status = types.Failed
 		result.Error = errors.Wrap(err, "failed to inspect image")
+		resultChan <- result
+		return
 	}
 
 	a := applier.NewApplier(cacheClient)
+	// the packages found in every file of the layers applied so far, so that packages in files a
+	// later layer deletes or replaces are not reported
+	files := make(map[string][]types.Package)
 	for v := range imageInfo.BlobIDs {
+		blob, err := cacheClient.GetBlob(imageInfo.BlobIDs[v])
+		if err != nil {
+			result.Status = types.Failed
+			result.Error = errors.Wrap(err, "failed to read layer")
+			continue
+		}
+		layer := &types.Layer{
+			Ordinal: lm.OrdinalByDiffId[blob.DiffID],
+			DiffId:  blob.DiffID,
+			Digest:  lm.ByDiffId[blob.DiffID],
+		}
+		w := newWhiteouts(blob.WhiteoutFiles, blob.OpaqueDirs)
+		for _, path := range sortedPaths(files) {
+			if w.deletes(path) {
+				result.Removed = append(result.Removed, replacedPackages(files[path], nil, layer)...)
+				delete(files, path)
+			}
+		}
+
+		layerFiles := make(map[string][]types.Package)
 		mergedLayer, err := a.ApplyLayers(imageInfo.ID, []string{imageInfo.BlobIDs[v]})
 		if err != nil {
 			switch err {
@@ -104,7 +145,7 @@ func trivySbom(ociPath string, lm types.LayerMapping, resultChan chan<- types.In
 							DiffId: lib.Layer.DiffID,
 						}},
 					}
-					result.Packages = append(result.Packages, pkg)
+					layerFiles["/"+app.FilePath] = append(layerFiles["/"+app.FilePath], pkg)
 				}
 			case "jar":
 				for _, lib := range app.Libraries {
@@ -130,18 +171,59 @@ func trivySbom(ociPath string, lm types.LayerMapping, resultChan chan<- types.In
 							DiffId: lib.Layer.DiffID,
 						}},
 					}
-					result.Packages = append(result.Packages, pkg)
+					layerFiles["/"+app.FilePath] = append(layerFiles["/"+app.FilePath], pkg)
 				}
 			default:
 			}
 		}
+		for _, path := range sortedPaths(layerFiles) {
+			result.Removed = append(result.Removed, replacedPackages(files[path], layerFiles[path], layer)...)
+			files[path] = layerFiles[path]
+		}
+	}
+	for _, path := range sortedPaths(files) {
+		result.Packages = append(result.Packages, files[path]...)
 	}
 	resultChan <- result
 }
 
-func initializeCache() (cache.Cache, error) {
+// trivyImage opens the image from its OCI layout or wraps the remote image whose layers
+// trivy then analyzes as they are downloaded
+func trivyImage(input imageInput) (ftypes.Image, error) {
+	if input.path != "" {
+		return image.NewArchiveImage(input.path)
+	}
+	return remoteImage{Image: input.image, name: input.name}, nil
+}
+
+type remoteImage struct {
+	v1.Image
+	name string
+}
+
+func (img remoteImage) Name() string {
+	return img.name
+}
+
+func (img remoteImage) ID() (string, error) {
+	return image.ID(img)
+}
+
+func (img remoteImage) LayerIDs() ([]string, error) {
+	return image.LayerIDs(img)
+}
+
+func (img remoteImage) RepoTags() []string {
+	return nil
+}
+
+func (img remoteImage) RepoDigests() []string {
+	return nil
+}
+
+func initializeCache(cacheDir string) (cache.Cache, error) {
 	var cacheClient cache.Cache
 	var err error
-	cacheClient, err = cache.NewFSCache(utils.CacheDir())
+	cacheClient, err = cache.NewFSCache(cacheDir)
 	return cacheClient, err
 }
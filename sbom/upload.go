@@ -23,6 +23,7 @@ import (
 
 	"github.com/atomist-skills/go-skill"
 	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
 	"github.com/docker/index-cli-plugin/types"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -211,7 +212,7 @@ func UploadSbom(sb *types.Sbom, img *v1.Image, workspace string, apikey string)
 	}
 	imageName += name
 
-	skill.Log.Infof("Inspect image at https://dso.docker.com/%s/overview/images/%s/digests/%s", workspace, imageName, image.Digest)
+	log.Infof("Inspect image at https://dso.docker.com/%s/overview/images/%s/digests/%s", workspace, imageName, image.Digest)
 
 	return nil
 }
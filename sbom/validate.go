@@ -0,0 +1,100 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// CheckDescriptor returns an error if the sbom described by d was not written in the current sbom_version
+func CheckDescriptor(d types.Descriptor) error {
+	if d.SbomVersion == "" {
+		return errors.New("descriptor has no sbom_version")
+	}
+	version, err := strconv.Atoi(d.SbomVersion)
+	if err != nil {
+		return errors.Errorf("invalid sbom_version %q", d.SbomVersion)
+	}
+	current, _ := strconv.Atoi(internal.FromBuild().SbomVersion)
+	switch {
+	case version > current:
+		return errors.Errorf("sbom_version %d was written by a newer version than %s, please upgrade", version, internal.FromBuild().Version)
+	case version < current:
+		return errors.Errorf("sbom_version %d is outdated, the current version is %d; index the image again", version, current)
+	}
+	return nil
+}
+
+// Validate checks the native JSON sbom, or the sboms of a multi-platform image keyed by platform,
+// against the sbom schema and the descriptor compatibility rules and returns the problems found
+func Validate(b []byte) ([]string, error) {
+	schema, err := types.Schema()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate sbom schema")
+	}
+	loader := gojsonschema.NewBytesLoader(schema)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to parse sbom")
+	}
+	documents := map[string]json.RawMessage{"": b}
+	switch {
+	case fields["spdxVersion"] != nil, fields["bomFormat"] != nil, fields["artifactRelationships"] != nil:
+		return nil, errors.New("only sboms in the native json format can be validated")
+	case fields["source"] == nil:
+		documents = fields
+	}
+
+	keys := make([]string, 0)
+	for k := range documents {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	problems := make([]string, 0)
+	for _, k := range keys {
+		prefix := ""
+		if k != "" {
+			prefix = k + ": "
+		}
+		result, err := gojsonschema.Validate(loader, gojsonschema.NewBytesLoader(documents[k]))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to validate sbom")
+		}
+		for _, e := range result.Errors() {
+			problems = append(problems, prefix+e.String())
+		}
+		var sb struct {
+			Descriptor types.Descriptor `json:"descriptor"`
+		}
+		if err := json.Unmarshal(documents[k], &sb); err != nil {
+			continue
+		}
+		if err := CheckDescriptor(sb.Descriptor); err != nil {
+			problems = append(problems, fmt.Sprintf("%sdescriptor: %s", prefix, err))
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func TestValidate(t *testing.T) {
+	img, _ := random.Image(64, 1)
+	manifest, _ := img.Manifest()
+	config, _ := img.ConfigFile()
+	sb := &types.Sbom{
+		Source: types.Source{Type: "image", Image: types.ImageSource{Name: "alpine", Manifest: manifest, Config: config}},
+		Artifacts: []types.Package{{
+			Type:      "apk",
+			Name:      "musl",
+			Version:   "1.2.3",
+			Purl:      "pkg:alpine/musl@1.2.3",
+			Locations: []types.Location{{Path: "/lib/apk/db/installed"}},
+			Layer:     &types.Layer{DiffId: "sha256:abc"},
+		}},
+		Vulnerabilities: []types.Cve{{Purl: "pkg:alpine/musl@1.2.3", SourceId: "CVE-2022-0001", Epss: &types.Epss{Score: 0.5}}},
+		Descriptor:      types.Descriptor{Name: "docker index", Version: internal.FromBuild().Version, SbomVersion: internal.FromBuild().SbomVersion},
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(sb, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := Validate(buf.Bytes()); err != nil || len(problems) > 0 {
+		t.Fatalf("expected sbom to be valid, got %v %v", problems, err)
+	}
+
+	var doc map[string]interface{}
+	_ = json.Unmarshal(buf.Bytes(), &doc)
+	doc["artifacts"] = "musl"
+	doc["descriptor"].(map[string]interface{})["sbom_version"] = "999"
+	invalid, _ := json.Marshal(map[string]interface{}{"linux/amd64": doc})
+	problems, err := Validate(invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 || !strings.HasPrefix(problems[0], "linux/amd64: artifacts") || !strings.Contains(problems[1], "newer version") {
+		t.Errorf("expected schema and descriptor problems, got %v", problems)
+	}
+
+	if _, err := Validate([]byte(`{"spdxVersion": "SPDX-2.3"}`)); err == nil {
+		t.Error("expected spdx document to be rejected")
+	}
+}
@@ -0,0 +1,358 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+)
+
+const (
+	VexAffected           = "affected"
+	VexNotAffected        = "not_affected"
+	VexFixed              = "fixed"
+	VexUnderInvestigation = "under_investigation"
+)
+
+// VexStatement is the normalized form of an OpenVEX or CSAF VEX statement. Image, if set,
+// is the purl of the image the products are subcomponents of.
+type VexStatement struct {
+	Vulnerability string
+	Image         string
+	Products      []string
+	Status        string
+	Justification string
+}
+
+type openVexDocument struct {
+	Context    string             `json:"@context"`
+	Statements []openVexStatement `json:"statements"`
+}
+
+type openVexStatement struct {
+	Vulnerability   json.RawMessage `json:"vulnerability"`
+	Products        json.RawMessage `json:"products"`
+	Status          string          `json:"status"`
+	Justification   string          `json:"justification"`
+	ImpactStatement string          `json:"impact_statement"`
+}
+
+type openVexProduct struct {
+	Id            string           `json:"@id"`
+	Subcomponents []openVexProduct `json:"subcomponents"`
+}
+
+type csafDocument struct {
+	Document struct {
+		Category string `json:"category"`
+	} `json:"document"`
+	ProductTree     csafProductTree     `json:"product_tree"`
+	Vulnerabilities []csafVulnerability `json:"vulnerabilities"`
+}
+
+type csafProductTree struct {
+	Branches         []csafBranch       `json:"branches"`
+	FullProductNames []csafProductName  `json:"full_product_names"`
+	Relationships    []csafRelationship `json:"relationships"`
+}
+
+type csafBranch struct {
+	Branches []csafBranch     `json:"branches"`
+	Product  *csafProductName `json:"product"`
+}
+
+type csafProductName struct {
+	ProductId string `json:"product_id"`
+	Helper    *struct {
+		Purl string `json:"purl"`
+	} `json:"product_identification_helper"`
+}
+
+type csafRelationship struct {
+	ProductReference string          `json:"product_reference"`
+	FullProductName  csafProductName `json:"full_product_name"`
+}
+
+type csafVulnerability struct {
+	Cve           string              `json:"cve"`
+	ProductStatus map[string][]string `json:"product_status"`
+	Flags         []struct {
+		Label      string   `json:"label"`
+		ProductIds []string `json:"product_ids"`
+	} `json:"flags"`
+}
+
+// csafStatus maps CSAF product status categories onto VEX statuses
+var csafStatus = map[string]string{
+	"known_affected":      VexAffected,
+	"known_not_affected":  VexNotAffected,
+	"fixed":               VexFixed,
+	"first_fixed":         VexFixed,
+	"under_investigation": VexUnderInvestigation,
+}
+
+// ReadVex reads the statements of an OpenVEX or CSAF VEX document
+func ReadVex(path string) ([]VexStatement, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read VEX document %s", path)
+	}
+	var csaf csafDocument
+	if err := json.Unmarshal(b, &csaf); err == nil && csaf.Document.Category != "" {
+		if csaf.Document.Category != "csaf_vex" {
+			return nil, errors.Errorf("unsupported CSAF document category %s in %s", csaf.Document.Category, path)
+		}
+		return csafStatements(csaf), nil
+	}
+	var openVex openVexDocument
+	if err := json.Unmarshal(b, &openVex); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse VEX document %s", path)
+	}
+	if !strings.Contains(openVex.Context, "openvex") {
+		return nil, errors.Errorf("unsupported VEX document %s", path)
+	}
+	return openVexStatements(openVex)
+}
+
+func openVexStatements(doc openVexDocument) ([]VexStatement, error) {
+	statements := make([]VexStatement, 0)
+	for _, s := range doc.Statements {
+		statement := VexStatement{
+			Status:        s.Status,
+			Justification: s.Justification,
+		}
+		if statement.Justification == "" {
+			statement.Justification = s.ImpactStatement
+		}
+
+		// vulnerability is a plain string up to OpenVEX v0.0.1 and an object afterwards
+		var vuln struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(s.Vulnerability, &statement.Vulnerability); err != nil {
+			if err := json.Unmarshal(s.Vulnerability, &vuln); err != nil {
+				return nil, errors.Wrap(err, "failed to parse VEX vulnerability")
+			}
+			statement.Vulnerability = vuln.Name
+		}
+
+		// same for products which used to be a list of purls
+		var products []openVexProduct
+		if err := json.Unmarshal(s.Products, &statement.Products); err == nil {
+			statements = append(statements, statement)
+			continue
+		}
+		if err := json.Unmarshal(s.Products, &products); err != nil {
+			return nil, errors.Wrap(err, "failed to parse VEX products")
+		}
+		for _, p := range products {
+			if len(p.Subcomponents) == 0 {
+				statement.Products = append(statement.Products, p.Id)
+				continue
+			}
+			// subcomponents of an image only apply to the packages of that image
+			sub := statement
+			if isImagePurl(p.Id) {
+				sub.Image = p.Id
+			}
+			sub.Products = nil
+			for _, sc := range p.Subcomponents {
+				sub.Products = append(sub.Products, sc.Id)
+			}
+			statements = append(statements, sub)
+		}
+		if len(statement.Products) > 0 {
+			statements = append(statements, statement)
+		}
+	}
+	return statements, nil
+}
+
+func csafStatements(doc csafDocument) []VexStatement {
+	purls := make(map[string]string)
+	var walk func(branches []csafBranch)
+	walk = func(branches []csafBranch) {
+		for _, b := range branches {
+			if b.Product != nil && b.Product.Helper != nil {
+				purls[b.Product.ProductId] = b.Product.Helper.Purl
+			}
+			walk(b.Branches)
+		}
+	}
+	walk(doc.ProductTree.Branches)
+	for _, p := range doc.ProductTree.FullProductNames {
+		if p.Helper != nil {
+			purls[p.ProductId] = p.Helper.Purl
+		}
+	}
+	for _, r := range doc.ProductTree.Relationships {
+		if purl, ok := purls[r.ProductReference]; ok {
+			purls[r.FullProductName.ProductId] = purl
+		}
+	}
+
+	statements := make([]VexStatement, 0)
+	for _, v := range doc.Vulnerabilities {
+		justifications := make(map[string]string)
+		for _, f := range v.Flags {
+			for _, id := range f.ProductIds {
+				justifications[id] = f.Label
+			}
+		}
+		// later statements take precedence, so the categories are applied in a stable order
+		categories := make([]string, 0, len(v.ProductStatus))
+		for category := range v.ProductStatus {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			status, ok := csafStatus[category]
+			if !ok {
+				continue
+			}
+			for _, id := range v.ProductStatus[category] {
+				purl, ok := purls[id]
+				if !ok {
+					continue
+				}
+				statements = append(statements, VexStatement{
+					Vulnerability: v.Cve,
+					Products:      []string{purl},
+					Status:        status,
+					Justification: justifications[id],
+				})
+			}
+		}
+	}
+	return statements
+}
+
+// ApplyVex sets the VEX status of all vulnerabilities of image matched by one of the statements.
+// Later statements take precedence over earlier ones. Image purls, as products or as the image
+// of subcomponents, only match if they identify image; products that are image purls apply to
+// all packages.
+func ApplyVex(cves []types.Cve, statements []VexStatement, image types.ImageSource) []types.Cve {
+	for i, c := range cves {
+		for _, s := range statements {
+			if !strings.EqualFold(s.Vulnerability, c.SourceId) || (s.Image != "" && !matchesVexProduct(s.Image, "", image)) {
+				continue
+			}
+			for _, p := range s.Products {
+				if matchesVexProduct(p, c.Purl, image) {
+					cves[i].Status = s.Status
+					cves[i].Justification = s.Justification
+					break
+				}
+			}
+		}
+	}
+	return cves
+}
+
+// IsAffected returns false if a VEX statement declared the vulnerability as not affecting
+// the package or as fixed
+func IsAffected(cve types.Cve) bool {
+	return cve.Status != VexNotAffected && cve.Status != VexFixed
+}
+
+func matchesVexProduct(product, purl string, image types.ImageSource) bool {
+	if isImagePurl(product) {
+		pp, _ := types.ToPackageUrl(product)
+		return matchesImage(pp, image)
+	}
+	return matchesPurl(product, purl)
+}
+
+func isImagePurl(purl string) bool {
+	p, err := types.ToPackageUrl(purl)
+	return err == nil && (p.Type == "oci" || p.Type == "docker")
+}
+
+// matchesImage compares an oci or docker purl with image: the name has to match the image
+// repository, and the version, if set, the image digest or one of its tags
+func matchesImage(product packageurl.PackageURL, image types.ImageSource) bool {
+	versionMatches := product.Version == "" || product.Version == image.Digest
+	if !versionMatches && image.Tags != nil {
+		versionMatches = internal.Contains(*image.Tags, product.Version)
+	}
+	if !versionMatches || image.Name == "" {
+		return false
+	}
+	repo, err := name.NewRepository(image.Name)
+	if err != nil {
+		return false
+	}
+	path := repo.RepositoryStr()
+	repositoryUrl := product.Qualifiers.Map()["repository_url"]
+	switch product.Type {
+	case "oci":
+		if product.Name != path[strings.LastIndex(path, "/")+1:] {
+			return false
+		}
+	default:
+		if strings.TrimPrefix(strings.Trim(product.Namespace+"/"+product.Name, "/"), "library/") != strings.TrimPrefix(path, "library/") {
+			return false
+		}
+		// docker purls only qualify the registry
+		if repositoryUrl != "" {
+			repositoryUrl += "/" + path
+		}
+	}
+	if repositoryUrl == "" {
+		return true
+	}
+	r, err := name.NewRepository(repositoryUrl)
+	return err == nil && r.Name() == repo.Name()
+}
+
+// matchesPurl compares a purl pattern like the product of a VEX statement with the purl
+// of a package. Version and qualifiers are only compared if the pattern specifies them;
+// image purls apply to all packages of the image.
+func matchesPurl(product, purl string) bool {
+	pp, err := types.ToPackageUrl(product)
+	if err != nil {
+		return false
+	}
+	if pp.Type == "oci" || pp.Type == "docker" {
+		return true
+	}
+	p, err := types.ToPackageUrl(purl)
+	if err != nil {
+		return false
+	}
+	if pp.Type != p.Type || pp.Namespace != p.Namespace || pp.Name != p.Name {
+		return false
+	}
+	if pp.Version != "" && pp.Version != p.Version {
+		return false
+	}
+	qualifiers := p.Qualifiers.Map()
+	for k, v := range pp.Qualifiers.Map() {
+		if qualifiers[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+)
+
+const openVexDoc = `{
+  "@context": "https://openvex.dev/ns/v0.2.0",
+  "statements": [
+    {
+      "vulnerability": {"name": "CVE-2022-0001"},
+      "products": [{"@id": "pkg:oci/app", "subcomponents": [{"@id": "pkg:deb/debian/openssl"}]}],
+      "status": "not_affected",
+      "justification": "vulnerable_code_not_in_execute_path"
+    }
+  ]
+}`
+
+const csafVexDoc = `{
+  "document": {"category": "csaf_vex"},
+  "product_tree": {
+    "branches": [{"branches": [{"product": {"product_id": "P1", "product_identification_helper": {"purl": "pkg:npm/lodash@4.17.20"}}}]}]
+  },
+  "vulnerabilities": [
+    {"cve": "CVE-2022-0002", "product_status": {"fixed": ["P1"]}}
+  ]
+}`
+
+func TestApplyVex(t *testing.T) {
+	dir := t.TempDir()
+	statements := make([]VexStatement, 0)
+	for _, doc := range []string{openVexDoc, csafVexDoc} {
+		path := filepath.Join(dir, "vex.json")
+		_ = os.WriteFile(path, []byte(doc), 0644)
+		s, err := ReadVex(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statements = append(statements, s...)
+	}
+	tags := []string{"1.0"}
+	image := types.ImageSource{Name: "index.docker.io/example/app", Digest: "sha256:abc", Tags: &tags}
+
+	cves := ApplyVex([]types.Cve{
+		{SourceId: "CVE-2022-0001", Purl: "pkg:deb/debian/openssl@1.1.1n-0+deb11u3?os_distro=bullseye"},
+		{SourceId: "CVE-2022-0002", Purl: "pkg:npm/lodash@4.17.20"},
+		{SourceId: "CVE-2022-0002", Purl: "pkg:npm/lodash@4.17.19"},
+	}, statements, image)
+
+	if cves[0].Status != VexNotAffected || cves[0].Justification != "vulnerable_code_not_in_execute_path" {
+		t.Errorf("expected openssl to be not affected, got %s", cves[0].Status)
+	}
+	if cves[1].Status != VexFixed {
+		t.Errorf("expected lodash 4.17.20 to be fixed, got %s", cves[1].Status)
+	}
+	if cves[2].Status != "" || !IsAffected(cves[2]) {
+		t.Errorf("expected lodash 4.17.19 to be unchanged, got %s", cves[2].Status)
+	}
+
+	// the subcomponents of app don't apply to other images
+	other := types.ImageSource{Name: "index.docker.io/example/other", Digest: "sha256:def"}
+	cves = ApplyVex([]types.Cve{{SourceId: "CVE-2022-0001", Purl: "pkg:deb/debian/openssl@1.1.1n"}}, statements, other)
+	if cves[0].Status != "" {
+		t.Errorf("expected openssl of another image to be unchanged, got %s", cves[0].Status)
+	}
+}
+
+func TestApplyVexImageProducts(t *testing.T) {
+	tags := []string{"1.0"}
+	image := types.ImageSource{Name: "index.docker.io/example/app", Digest: "sha256:abc", Tags: &tags}
+	tests := []struct {
+		product  string
+		image    types.ImageSource
+		expected bool
+	}{
+		{product: "pkg:oci/app", image: image, expected: true},
+		{product: "pkg:oci/app@sha256%3Aabc", image: image, expected: true},
+		{product: "pkg:oci/app@1.0", image: image, expected: true},
+		{product: "pkg:oci/app@sha256%3Adef", image: image, expected: false},
+		{product: "pkg:oci/app?repository_url=docker.io/example/app", image: image, expected: true},
+		{product: "pkg:oci/app?repository_url=ghcr.io/example/app", image: image, expected: false},
+		{product: "pkg:oci/other", image: image, expected: false},
+		{product: "pkg:docker/example/app@1.0", image: image, expected: true},
+		{product: "pkg:docker/example/app?repository_url=docker.io", image: image, expected: true},
+		{product: "pkg:docker/other/app", image: image, expected: false},
+		{product: "pkg:docker/alpine", image: types.ImageSource{Name: "index.docker.io/library/alpine"}, expected: true},
+		{product: "pkg:oci/app", image: types.ImageSource{Digest: "sha256:abc"}, expected: false},
+	}
+	for _, test := range tests {
+		statements := []VexStatement{{Vulnerability: "CVE-2022-0001", Products: []string{test.product}, Status: VexNotAffected}}
+		cves := ApplyVex([]types.Cve{
+			{SourceId: "CVE-2022-0001", Purl: "pkg:deb/debian/openssl@1.1.1n"},
+			{SourceId: "CVE-2022-0001", Purl: "pkg:npm/lodash@4.17.20"},
+		}, statements, test.image)
+		for _, c := range cves {
+			if (c.Status == VexNotAffected) != test.expected {
+				t.Errorf("%s %s: expected match %v for %s, got status %q", test.product, test.image.Name, test.expected, c.Purl, c.Status)
+			}
+		}
+	}
+}
+
+func TestCsafStatementsOrder(t *testing.T) {
+	doc := csafDocument{}
+	doc.ProductTree.FullProductNames = []csafProductName{{ProductId: "P1"}}
+	doc.ProductTree.FullProductNames[0].Helper = &struct {
+		Purl string `json:"purl"`
+	}{Purl: "pkg:npm/lodash@4.17.20"}
+	doc.Vulnerabilities = []csafVulnerability{{
+		Cve: "CVE-2022-0002",
+		ProductStatus: map[string][]string{
+			"under_investigation": {"P1"},
+			"known_affected":      {"P1"},
+			"fixed":               {"P1"},
+			"known_not_affected":  {"P1"},
+		},
+	}}
+	for i := 0; i < 20; i++ {
+		statements := csafStatements(doc)
+		statuses := make([]string, 0)
+		for _, s := range statements {
+			statuses = append(statuses, s.Status)
+		}
+		if strings.Join(statuses, " ") != "fixed affected not_affected under_investigation" {
+			t.Fatalf("expected statements in category order, got %v", statuses)
+		}
+	}
+}
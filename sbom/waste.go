@@ -0,0 +1,182 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	stereoscopeimage "github.com/anchore/stereoscope/pkg/image"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// cacheDirs are the directories package managers keep downloads and indexes in
+var cacheDirs = []struct {
+	dir   string
+	cache string
+}{
+	{"/var/cache/apt/", "apt"},
+	{"/var/lib/apt/lists/", "apt"},
+	{"/var/cache/apk/", "apk"},
+	{"/var/cache/yum/", "yum"},
+	{"/var/cache/dnf/", "dnf"},
+	{"/var/cache/zypp/", "zypper"},
+	{"/.cache/pip/", "pip"},
+	{"/.npm/_cacache/", "npm"},
+	{"/.cache/yarn/", "yarn"},
+	{"/.cache/go-build/", "go"},
+	{"/.composer/cache/", "composer"},
+	{"/.cache/composer/", "composer"},
+}
+
+// layerFile is a regular file written by a layer
+type layerFile struct {
+	path  string
+	size  int64
+	layer *types.Layer
+	src   *stereoscopeimage.Layer
+}
+
+// analyzeWaste reports the files of img that take space without being of use: files a later
+// layer overwrites or deletes, files with the same content written by more than one layer and
+// package manager caches left in the final filesystem
+func analyzeWaste(ctx context.Context, img *stereoscopeimage.Image, lm types.LayerMapping) (*types.Waste, error) {
+	waste := &types.Waste{Files: make([]types.WastedFile, 0)}
+	add := func(f layerFile, w types.WastedFile) {
+		w.Path = f.path
+		w.Size = f.size
+		w.Layer = f.layer
+		waste.Files = append(waste.Files, w)
+		waste.Total += f.size
+	}
+
+	final := make(map[string]layerFile)
+	for _, layer := range img.Layers {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		diffId := layer.Metadata.Digest
+		l := &types.Layer{
+			Ordinal: lm.OrdinalByDiffId[diffId],
+			DiffId:  diffId,
+			Digest:  lm.ByDiffId[diffId],
+		}
+		if w := layerWhiteouts(layer); len(w.Files) > 0 || len(w.Dirs) > 0 {
+			for p, f := range final {
+				if w.deletes(p) {
+					add(f, types.WastedFile{Reason: types.WasteDeleted, RemovedBy: l})
+					delete(final, p)
+				}
+			}
+		}
+		for _, ref := range layer.Tree.AllFiles(file.TypeReg) {
+			if ref.RealPath.IsWhiteout() {
+				continue
+			}
+			entry, err := img.FileCatalog.Get(ref)
+			if err != nil {
+				continue
+			}
+			f := layerFile{path: string(ref.RealPath), size: entry.Metadata.Size, layer: l, src: layer}
+			if prev, ok := final[f.path]; ok {
+				add(prev, types.WastedFile{Reason: types.WasteOverwritten, RemovedBy: l})
+			}
+			final[f.path] = f
+		}
+	}
+
+	bySize := make(map[int64][]layerFile)
+	for _, f := range final {
+		if f.size == 0 {
+			continue
+		}
+		if c, _ := packageCache(f.path); c != "" {
+			add(f, types.WastedFile{Reason: types.WasteCache, Cache: c})
+			continue
+		}
+		bySize[f.size] = append(bySize[f.size], f)
+	}
+	for _, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+		byDigest := make(map[string][]layerFile)
+		for _, f := range files {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if digest, err := layerFileDigest(f); err == nil {
+				byDigest[digest] = append(byDigest[digest], f)
+			}
+		}
+		for _, same := range byDigest {
+			sort.Slice(same, func(i, j int) bool {
+				if same[i].layer.Ordinal != same[j].layer.Ordinal {
+					return same[i].layer.Ordinal < same[j].layer.Ordinal
+				}
+				return same[i].path < same[j].path
+			})
+			if same[0].layer.Ordinal == same[len(same)-1].layer.Ordinal {
+				continue
+			}
+			for _, f := range same[1:] {
+				add(f, types.WastedFile{Reason: types.WasteDuplicate, DuplicateOf: same[0].path})
+			}
+		}
+	}
+
+	sort.Slice(waste.Files, func(i, j int) bool {
+		a, b := waste.Files[i], waste.Files[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Layer.Ordinal < b.Layer.Ordinal
+	})
+	return waste, nil
+}
+
+// packageCache returns the package manager whose cache directory contains path and the path of
+// that directory
+func packageCache(path string) (string, string) {
+	for _, c := range cacheDirs {
+		if i := strings.Index(path, c.dir); i != -1 {
+			return c.cache, path[:i+len(c.dir)-1]
+		}
+	}
+	return "", ""
+}
+
+func layerFileDigest(f layerFile) (string, error) {
+	r, err := f.src.FileContents(file.Path(f.path))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close() //nolint:errcheck
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	stereoscopeimage "github.com/anchore/stereoscope/pkg/image"
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func tarLayer(t *testing.T, files map[string]string) v1.Layer {
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	for name, content := range files {
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := tarball.LayerFromReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestAnalyzeWaste(t *testing.T) {
+	img, err := mutate.AppendLayers(empty.Image,
+		tarLayer(t, map[string]string{
+			"etc/app.conf":                  strings.Repeat("c", 100),
+			"big.bin":                       strings.Repeat("a", 1000),
+			"var/cache/apt/archives/x.deb":  strings.Repeat("d", 500),
+			"usr/share/doc/app/LICENSE":     strings.Repeat("l", 300),
+			"var/cache/apt/archives/.empty": "",
+		}),
+		tarLayer(t, map[string]string{
+			"big.bin":          strings.Repeat("b", 1000),
+			"etc/.wh.app.conf": "",
+			"opt/app/LICENSE":  strings.Repeat("l", 300),
+			"opt/app/NOTICE":   strings.Repeat("n", 300),
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simg := stereoscopeimage.NewImage(img, t.TempDir())
+	if err := simg.Read(); err != nil {
+		t.Fatal(err)
+	}
+	defer simg.Cleanup() //nolint:errcheck
+
+	waste, err := analyzeWaste(context.Background(), simg, createLayerMapping(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waste.Total != 1900 {
+		t.Errorf("expected 1900 bytes wasted, got %d", waste.Total)
+	}
+	expected := []struct {
+		path    string
+		reason  string
+		ordinal int
+	}{
+		{"/big.bin", types.WasteOverwritten, 0},
+		{"/var/cache/apt/archives/x.deb", types.WasteCache, 0},
+		{"/opt/app/LICENSE", types.WasteDuplicate, 1},
+		{"/etc/app.conf", types.WasteDeleted, 0},
+	}
+	if len(waste.Files) != len(expected) {
+		t.Fatalf("expected %d wasted files, got %+v", len(expected), waste.Files)
+	}
+	for i, e := range expected {
+		f := waste.Files[i]
+		if f.Path != e.path || f.Reason != e.reason || f.Layer.Ordinal != e.ordinal {
+			t.Errorf("expected %s %s in layer %d, got %+v", e.reason, e.path, e.ordinal, f)
+		}
+	}
+	if f := waste.Files[0]; f.RemovedBy == nil || f.RemovedBy.Ordinal != 1 {
+		t.Errorf("expected overwrite by layer 1, got %+v", f.RemovedBy)
+	}
+	if f := waste.Files[1]; f.Cache != "apt" {
+		t.Errorf("expected apt cache, got %s", f.Cache)
+	}
+	if f := waste.Files[2]; f.DuplicateOf != "/usr/share/doc/app/LICENSE" {
+		t.Errorf("expected duplicate of the license of layer 0, got %s", f.DuplicateOf)
+	}
+}
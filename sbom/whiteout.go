@@ -0,0 +1,212 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	stereoscopeimage "github.com/anchore/stereoscope/pkg/image"
+	"github.com/docker/index-cli-plugin/types"
+)
+
+// whiteouts are the paths a layer deletes from the layers below it, either with a whiteout
+// file like .wh.status or by marking a directory opaque with .wh..wh..opq
+type whiteouts struct {
+	Files []string `json:"files,omitempty"`
+	Dirs  []string `json:"dirs,omitempty"`
+}
+
+func newWhiteouts(files []string, dirs []string) whiteouts {
+	w := whiteouts{}
+	for _, f := range files {
+		w.Files = append(w.Files, path.Clean("/"+f))
+	}
+	for _, d := range dirs {
+		w.Dirs = append(w.Dirs, path.Clean("/"+d))
+	}
+	return w
+}
+
+// layerWhiteouts reads the whiteout entries of the diff tree of layer
+func layerWhiteouts(layer *stereoscopeimage.Layer) whiteouts {
+	files := make([]string, 0)
+	dirs := make([]string, 0)
+	for _, p := range layer.Tree.AllRealPaths() {
+		switch {
+		case p.IsDirWhiteout():
+			dirs = append(dirs, path.Dir(string(p)))
+		case p.IsWhiteout():
+			if f, err := p.UnWhiteoutPath(); err == nil {
+				files = append(files, string(f))
+			}
+		}
+	}
+	return newWhiteouts(files, dirs)
+}
+
+// deletes reports if the layer deletes the file at p written by a lower layer
+func (w whiteouts) deletes(p string) bool {
+	p = path.Clean("/" + p)
+	for _, f := range w.Files {
+		if p == f || strings.HasPrefix(p, f+"/") {
+			return true
+		}
+	}
+	for _, d := range w.Dirs {
+		if d == "/" || strings.HasPrefix(p, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// packageDatabases are the keys of the packages recorded in the package databases, like
+// /var/lib/dpkg/status, of the layers applied so far
+type packageDatabases map[string][]string
+
+// apply adds the databases a layer writes and drops the ones it deletes, returning the keys of
+// the packages the layer installs and the database paths of the ones it removes
+func (d packageDatabases) apply(dbs map[string][]string, w whiteouts) ([]string, map[string]string) {
+	before := d.installed()
+	for db := range d {
+		if w.deletes(db) {
+			delete(d, db)
+		}
+	}
+	for db, keys := range dbs {
+		d[db] = keys
+	}
+	after := d.installed()
+
+	added := make([]string, 0)
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	removed := make(map[string]string)
+	for k, db := range before {
+		if _, ok := after[k]; !ok {
+			removed[k] = db
+		}
+	}
+	return added, removed
+}
+
+// installed maps the keys of the packages to the database listing them
+func (d packageDatabases) installed() map[string]string {
+	keys := make(map[string]string)
+	for db, ks := range d {
+		for _, k := range ks {
+			keys[k] = db
+		}
+	}
+	return keys
+}
+
+// replacedPackages returns the packages found in a file that are no longer found after a layer
+// wrote the file again, flagged as removed by layer
+func replacedPackages(old []types.Package, new []types.Package, layer *types.Layer) []types.Package {
+	removed := make([]types.Package, 0)
+	for _, o := range old {
+		found := false
+		for _, n := range new {
+			if n.Purl == o.Purl {
+				found = true
+				break
+			}
+		}
+		if !found {
+			o.RemovedBy = layer
+			removed = append(removed, o)
+		}
+	}
+	return removed
+}
+
+// removedPackage is an os package a layer removed from the package database of a lower layer
+type removedPackage struct {
+	db        string
+	layer     *stereoscopeimage.Layer
+	removedBy *types.Layer
+}
+
+// removedPackages creates the os packages removed by a layer from their keys, located in the
+// package database of the layer that installed them
+func removedPackages(removed map[string]removedPackage, distro types.Distro, qualifiers map[string]string, lm types.LayerMapping) []types.Package {
+	keys := make([]string, 0, len(removed))
+	for k := range removed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	packages := make([]types.Package, 0)
+	for _, k := range keys {
+		r := removed[k]
+		purl, err := packageurl.FromString(k)
+		if err != nil {
+			continue
+		}
+		if t, ok := types.PackageTypeMapping[purl.Type]; ok {
+			purl.Type = t
+		}
+		purl.Namespace = distro.OsName
+		purl.Qualifiers = packageurl.QualifiersFromMap(qualifiers)
+		loc := types.Location{Path: r.db}
+		if r.layer != nil {
+			// the stereoscope layers use diff_ids internally as their digest
+			loc.DiffId = r.layer.Metadata.Digest
+			loc.Digest = lm.ByDiffId[loc.DiffId]
+		}
+		packages = append(packages, types.Package{
+			Purl:      purl.String(),
+			Locations: []types.Location{loc},
+			RemovedBy: r.removedBy,
+		})
+	}
+	return packages
+}
+
+// sortedPaths returns the paths of files in order
+func sortedPaths(files map[string][]types.Package) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// removedFromImage returns the removed packages that are not part of the final image at another
+// location
+func removedFromImage(removed []types.Package, packages []types.Package) []types.Package {
+	purls := make(map[string]bool)
+	for _, p := range packages {
+		purls[p.Purl] = true
+	}
+	filtered := make([]types.Package, 0)
+	for _, p := range removed {
+		if !purls[p.Purl] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
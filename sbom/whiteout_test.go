@@ -0,0 +1,123 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestWhiteoutsDeletes(t *testing.T) {
+	w := newWhiteouts([]string{"var/lib/dpkg/status", "/app/lib"}, []string{"opt/"})
+	tests := map[string]bool{
+		"/var/lib/dpkg/status":     true,
+		"/var/lib/dpkg/status-old": false,
+		"/app/lib/log4j.jar":       true,
+		"/app/library.jar":         false,
+		"/opt/app/app.jar":         true,
+		"/opt":                     false,
+	}
+	for path, expected := range tests {
+		if w.deletes(path) != expected {
+			t.Errorf("expected deletes(%s) to be %v", path, expected)
+		}
+	}
+}
+
+func TestIndexRemovedPackages(t *testing.T) {
+	status := func(packages ...string) []byte {
+		var b strings.Builder
+		for _, p := range packages {
+			n, v, _ := strings.Cut(p, "@")
+			b.WriteString("Package: " + n + "\nVersion: " + v + "\nArchitecture: amd64\nStatus: install ok installed\n\n")
+		}
+		return []byte(b.String())
+	}
+	layers := []map[string][]byte{
+		{
+			"etc/os-release":      []byte("ID=debian\nVERSION_ID=\"11\"\n"),
+			"var/lib/dpkg/status": status("bash@5.1-2", "curl@7.74.0-1.3"),
+		},
+		// apt-get remove curl
+		{"var/lib/dpkg/status": status("bash@5.1-2")},
+		// rm /var/lib/dpkg/status
+		{"var/lib/dpkg/.wh.status": nil},
+		// apt-get install bash
+		{"var/lib/dpkg/status": status("bash@5.1-2"), "bin/bash": []byte("bash")},
+	}
+	img := empty.Image
+	for _, files := range layers {
+		content := tarArchive(files)
+		layer, _ := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		})
+		img, _ = mutate.AppendLayers(img, layer)
+	}
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/app:1"
+	ref, _ := name.ParseReference(image)
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := t.TempDir()
+	index := func(opts ...Option) *types.Sbom {
+		opts = append(opts, WithStreaming(), WithCacheDir(cache), WithCatalogers(CatalogerOs))
+		sb, _, err := NewIndexer(opts...).Index(context.Background(), image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sb
+	}
+	sb := index()
+	if len(sb.Artifacts) != 1 || sb.Artifacts[0].Name != "bash" {
+		t.Fatalf("expected only bash, got %+v", sb.Artifacts)
+	}
+	if l := sb.Artifacts[0].Layer; l == nil || l.Ordinal != 3 {
+		t.Errorf("expected bash installed in layer 3, got %+v", l)
+	}
+	if len(sb.Removed) != 0 {
+		t.Errorf("expected no removed packages by default, got %+v", sb.Removed)
+	}
+
+	// the second run reads the layers from the cache
+	for _, sb := range []*types.Sbom{index(WithRemovedPackages(), WithoutCache()), index(WithRemovedPackages())} {
+		if len(sb.Removed) != 1 {
+			t.Fatalf("expected curl to be removed, got %+v", sb.Removed)
+		}
+		curl := sb.Removed[0]
+		if curl.Purl != "pkg:deb/debian/curl@7.74.0-1.3?os_name=debian&os_version=11" {
+			t.Errorf("unexpected purl %s", curl.Purl)
+		}
+		if curl.Layer == nil || curl.Layer.Ordinal != 0 || curl.RemovedBy == nil || curl.RemovedBy.Ordinal != 1 {
+			t.Errorf("expected curl installed in layer 0 and removed in layer 1, got %+v and %+v", curl.Layer, curl.RemovedBy)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// windowsReleases maps the build numbers of Windows base images to their release
+var windowsReleases = map[string]string{
+	"14393": "ltsc2016",
+	"17763": "ltsc2019",
+	"18362": "1903",
+	"18363": "1909",
+	"19041": "2004",
+	"19042": "20H2",
+	"20348": "ltsc2022",
+	"25398": "23H2",
+	"26100": "ltsc2025",
+}
+
+func isWindows(config *v1.ConfigFile) bool {
+	return config != nil && config.OS == "windows"
+}
+
+// windowsDistro returns the Windows release of the image from the os.version of its config,
+// e.g. 10.0.17763.4252 for ltsc2019, as Windows images have no os-release file
+func windowsDistro(config *v1.ConfigFile) types.Distro {
+	distro := types.Distro{OsName: "windows", OsVersion: config.OSVersion}
+	if parts := strings.Split(config.OSVersion, "."); len(parts) > 2 {
+		distro.OsDistro = windowsReleases[parts[2]]
+	}
+	return distro
+}
+
+// windowsPackage returns the Windows base OS of the image as package of its first layer
+func windowsPackage(config *v1.ConfigFile, lm types.LayerMapping) (types.Package, bool) {
+	if config.OSVersion == "" {
+		return types.Package{}, false
+	}
+	diffId := lm.DiffIdByOrdinal[0]
+	return types.Package{
+		Type:        "generic",
+		Namespace:   "microsoft",
+		Name:        "windows",
+		Version:     config.OSVersion,
+		Purl:        fmt.Sprintf("pkg:generic/microsoft/windows@%s?os_name=windows&os_version=%s", config.OSVersion, config.OSVersion),
+		Author:      "Microsoft Corporation",
+		Description: "Windows container base OS",
+		Url:         "https://learn.microsoft.com/virtualization/windowscontainers/",
+		Locations: []types.Location{{
+			Path:   "/",
+			DiffId: diffId,
+			Digest: lm.ByDiffId[diffId],
+		}},
+	}, true
+}
+
+// normalizeWindowsPackages maps the locations of packages in Windows layers, which store the
+// container file system below Files/, to paths in the container and drops the packages only
+// found in the utility VM used for Hyper-V isolation below UtilityVM/
+func normalizeWindowsPackages(packages []types.Package) []types.Package {
+	normalized := make([]types.Package, 0, len(packages))
+	for _, p := range packages {
+		locations := windowsLocations(p.Locations)
+		if len(p.Locations) > 0 && len(locations) == 0 {
+			continue
+		}
+		p.Locations = locations
+		if p.Files != nil {
+			p.Files = windowsLocations(p.Files)
+		}
+		normalized = append(normalized, p)
+	}
+	return normalized
+}
+
+func windowsLocations(locations []types.Location) []types.Location {
+	mapped := make([]types.Location, 0, len(locations))
+	for _, l := range locations {
+		path := "/" + strings.TrimPrefix(l.Path, "/")
+		if strings.HasPrefix(path, "/UtilityVM/") {
+			continue
+		}
+		if strings.HasPrefix(path, "/Files/") {
+			l.Path = strings.TrimPrefix(path, "/Files")
+		}
+		mapped = append(mapped, l)
+	}
+	return mapped
+}
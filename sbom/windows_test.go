@@ -0,0 +1,46 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestWindowsImage(t *testing.T) {
+	config := &v1.ConfigFile{OS: "windows", OSVersion: "10.0.17763.4252"}
+	distro := windowsDistro(config)
+	if distro.OsName != "windows" || distro.OsVersion != "10.0.17763.4252" || distro.OsDistro != "ltsc2019" {
+		t.Errorf("unexpected distro %+v", distro)
+	}
+
+	lm := types.LayerMapping{DiffIdByOrdinal: map[int]string{0: "sha256:base"}, ByDiffId: map[string]string{"sha256:base": "sha256:digest"}}
+	p, ok := windowsPackage(config, lm)
+	if !ok || p.Purl != "pkg:generic/microsoft/windows@10.0.17763.4252?os_name=windows&os_version=10.0.17763.4252" || p.Locations[0].DiffId != "sha256:base" {
+		t.Errorf("unexpected base OS package %+v", p)
+	}
+
+	packages := normalizeWindowsPackages([]types.Package{
+		{Purl: "pkg:nuget/app@1.0.0", Locations: []types.Location{{Path: "/Files/app/app.deps.json"}, {Path: "/UtilityVM/Files/app/app.deps.json"}}},
+		{Purl: "pkg:nuget/vm@1.0.0", Locations: []types.Location{{Path: "/UtilityVM/Files/Windows/vm.deps.json"}}},
+	})
+	if len(packages) != 1 || len(packages[0].Locations) != 1 || packages[0].Locations[0].Path != "/app/app.deps.json" {
+		t.Errorf("expected utility VM to be skipped and paths mapped, got %+v", packages)
+	}
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/pkg/errors"
+)
+
+// Media types of the Harbor pluggable scanner API, see
+// https://github.com/goharbor/pluggable-scanner-spec
+const (
+	harborMetadataMimeType     = "application/vnd.scanner.adapter.metadata+json; version=1.0"
+	harborScanRequestMimeType  = "application/vnd.scanner.adapter.scan.request+json; version=1.0"
+	harborScanResponseMimeType = "application/vnd.scanner.adapter.scan.response+json; version=1.0"
+	harborReportMimeType       = "application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0"
+	harborErrorMimeType        = "application/vnd.scanner.adapter.error+json; version=1.0"
+)
+
+// harborRefreshAfter is the number of seconds Harbor waits before polling unfinished reports again
+const harborRefreshAfter = "15"
+
+// HarborScanRequest is the body of POST /api/v1/scan
+type HarborScanRequest struct {
+	Registry HarborRegistry `json:"registry"`
+	Artifact HarborArtifact `json:"artifact"`
+}
+
+type HarborRegistry struct {
+	Url           string `json:"url"`
+	Authorization string `json:"authorization"`
+}
+
+type HarborArtifact struct {
+	Repository string `json:"repository"`
+	Digest     string `json:"digest"`
+	Tag        string `json:"tag,omitempty"`
+	MimeType   string `json:"mime_type,omitempty"`
+}
+
+type HarborScanner struct {
+	Name    string `json:"name"`
+	Vendor  string `json:"vendor"`
+	Version string `json:"version"`
+}
+
+type HarborMetadata struct {
+	Scanner      HarborScanner      `json:"scanner"`
+	Capabilities []HarborCapability `json:"capabilities"`
+	Properties   map[string]string  `json:"properties,omitempty"`
+}
+
+type HarborCapability struct {
+	ConsumesMimeTypes []string `json:"consumes_mime_types"`
+	ProducesMimeTypes []string `json:"produces_mime_types"`
+}
+
+// HarborReport is the vulnerability report returned by GET /api/v1/scan/{id}/report
+type HarborReport struct {
+	GeneratedAt     time.Time             `json:"generated_at"`
+	Artifact        HarborArtifact        `json:"artifact"`
+	Scanner         HarborScanner         `json:"scanner"`
+	Severity        string                `json:"severity"`
+	Vulnerabilities []HarborVulnerability `json:"vulnerabilities"`
+}
+
+type HarborVulnerability struct {
+	Id          string   `json:"id"`
+	Package     string   `json:"package"`
+	Version     string   `json:"version"`
+	FixVersion  string   `json:"fix_version,omitempty"`
+	Severity    string   `json:"severity"`
+	Description string   `json:"description,omitempty"`
+	Links       []string `json:"links,omitempty"`
+}
+
+func harborScanner() HarborScanner {
+	return HarborScanner{Name: "docker index", Vendor: "Docker", Version: internal.FromBuild().Version}
+}
+
+func (s *Server) handleHarborMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeHarborError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	w.Header().Set("Content-Type", harborMetadataMimeType)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(HarborMetadata{
+		Scanner: harborScanner(),
+		Capabilities: []HarborCapability{{
+			ConsumesMimeTypes: []string{
+				"application/vnd.oci.image.manifest.v1+json",
+				"application/vnd.docker.distribution.manifest.v2+json",
+			},
+			ProducesMimeTypes: []string{harborReportMimeType},
+		}},
+		Properties: map[string]string{
+			"harbor.scanner-adapter/scanner-type":                      "os-package-vulnerability",
+			"harbor.scanner-adapter/vulnerability-database-updated-at": time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+func (s *Server) handleHarborScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeHarborError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	var req HarborScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeHarborError(w, http.StatusBadRequest, "invalid request: %s", err)
+		return
+	}
+	if req.Registry.Url == "" || req.Artifact.Repository == "" || req.Artifact.Digest == "" {
+		writeHarborError(w, http.StatusUnprocessableEntity, "registry url, artifact repository and digest are required")
+		return
+	}
+	u, err := url.Parse(req.Registry.Url)
+	if err != nil || u.Host == "" {
+		writeHarborError(w, http.StatusUnprocessableEntity, "invalid registry url %s", req.Registry.Url)
+		return
+	}
+	repository := u.Host + "/" + req.Artifact.Repository
+	var auth authn.Authenticator
+	if req.Registry.Authorization != "" {
+		if auth, err = harborAuthenticator(req.Registry.Authorization); err == nil {
+			// the credentials are only used by this scan
+			_, err = registry.WithCredentials(r.Context(), repository, auth)
+		}
+		if err != nil {
+			writeHarborError(w, http.StatusUnprocessableEntity, "invalid registry authorization: %s", err)
+			return
+		}
+	}
+
+	scan := &Scan{
+		Id:         newId(),
+		Image:      repository + "@" + req.Artifact.Digest,
+		Status:     StatusPending,
+		Created:    time.Now().UTC(),
+		artifact:   &req.Artifact,
+		repository: repository,
+		auth:       auth,
+	}
+	if !s.enqueue(scan, true) {
+		writeHarborError(w, http.StatusTooManyRequests, "too many scans queued, retry later")
+		return
+	}
+	log.Infof("Harbor scan %s of %s requested", scan.Id, scan.Image)
+	w.Header().Set("Content-Type", harborScanResponseMimeType)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": scan.Id})
+}
+
+func (s *Server) handleHarborReport(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/scan/")
+	if !strings.HasSuffix(id, "/report") {
+		writeHarborError(w, http.StatusNotFound, "%s not found", r.URL.Path)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeHarborError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	id = strings.TrimSuffix(id, "/report")
+	s.mutex.Lock()
+	scan, ok := s.scans[id]
+	var response Scan
+	if ok {
+		response = *scan
+	}
+	s.mutex.Unlock()
+	switch {
+	case !ok || response.artifact == nil:
+		writeHarborError(w, http.StatusNotFound, "scan %s not found", id)
+	case response.Status == StatusFailed:
+		writeHarborError(w, http.StatusInternalServerError, "%s", response.Error)
+	case response.Status != StatusCompleted:
+		w.Header().Set("Location", r.URL.String())
+		w.Header().Set("Refresh-After", harborRefreshAfter)
+		w.WriteHeader(http.StatusFound)
+	default:
+		w.Header().Set("Content-Type", harborReportMimeType)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(toHarborReport(response.Sbom, *response.artifact, *response.Completed))
+	}
+}
+
+// toHarborReport converts the vulnerabilities of sb into a Harbor report; suppressed
+// vulnerabilities and those a VEX statement declared as not affected are left out
+func toHarborReport(sb *types.Sbom, artifact HarborArtifact, generated time.Time) HarborReport {
+	report := HarborReport{
+		GeneratedAt:     generated,
+		Artifact:        artifact,
+		Scanner:         harborScanner(),
+		Severity:        "None",
+		Vulnerabilities: make([]HarborVulnerability, 0),
+	}
+	packages := make(map[string]types.Package)
+	for _, p := range sb.Artifacts {
+		packages[p.Purl] = p
+	}
+	highest := -1
+	for _, c := range sb.Vulnerabilities {
+		if !sbom.IsAffected(c) || c.Suppression != nil {
+			continue
+		}
+		p, ok := packages[c.Purl]
+		if !ok {
+			if purl, err := types.ToPackageUrl(c.Purl); err == nil {
+				p = types.Package{Name: purl.Name, Version: purl.Version}
+			}
+		}
+		v := HarborVulnerability{
+			Id:       c.SourceId,
+			Package:  p.Name,
+			Version:  p.Version,
+			Severity: toHarborSeverity(sbom.Severity(c)),
+		}
+		if sbom.IsFixed(c) {
+			v.FixVersion = c.FixedBy
+		}
+		for _, adv := range []*types.Advisory{c.Cve, c.Advisory} {
+			if adv == nil {
+				continue
+			}
+			if v.Description == "" {
+				v.Description = adv.Description
+			}
+			for _, u := range adv.Urls {
+				if u.Value != "" {
+					v.Links = append(v.Links, u.Value)
+				}
+			}
+		}
+		if i := harborSeverityOrder(v.Severity); i > highest {
+			highest, report.Severity = i, v.Severity
+		}
+		report.Vulnerabilities = append(report.Vulnerabilities, v)
+	}
+	return report
+}
+
+var harborSeverities = []string{"Unknown", "Low", "Medium", "High", "Critical"}
+
+func toHarborSeverity(severity string) string {
+	switch severity {
+	case "CRITICAL", "HIGH", "MEDIUM", "LOW":
+		return strings.ToUpper(severity[:1]) + strings.ToLower(severity[1:])
+	default:
+		return "Unknown"
+	}
+}
+
+func harborSeverityOrder(severity string) int {
+	for i, s := range harborSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// harborAuthenticator parses the Basic or Bearer authorization Harbor passes for its registry
+func harborAuthenticator(authorization string) (authn.Authenticator, error) {
+	scheme, credentials, _ := strings.Cut(authorization, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		b, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid basic credentials")
+		}
+		user, password, _ := strings.Cut(string(b), ":")
+		return &authn.Basic{Username: user, Password: password}, nil
+	case "bearer":
+		return &authn.Bearer{Token: credentials}, nil
+	}
+	return nil, errors.Errorf("unsupported authorization scheme %s", scheme)
+}
+
+func writeHarborError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	w.Header().Set("Content-Type", harborErrorMimeType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": errors.Errorf(format, args...).Error()},
+	})
+}
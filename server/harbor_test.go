@@ -0,0 +1,164 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestHarborScan(t *testing.T) {
+	defer func(f func(context.Context, *sbom.Indexer, string) (*types.Sbom, *v1.Image, error)) { indexFunc = f }(indexFunc)
+	images := make(chan string, 1)
+	indexFunc = func(ctx context.Context, indexer *sbom.Indexer, image string) (*types.Sbom, *v1.Image, error) {
+		images <- image
+		return &types.Sbom{Source: types.Source{Image: types.ImageSource{Name: image}}}, nil, nil
+	}
+	s := New(context.Background(), sbom.NewIndexer(), fakeBackend{}, Options{Token: "secret"})
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+	client := &http.Client{
+		Transport:     bearerTransport{token: "secret"},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected request without token to be rejected, got %s", resp.Status)
+	}
+
+	resp, err = client.Get(server.URL + "/api/v1/metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metadata HarborMetadata
+	_ = json.NewDecoder(resp.Body).Decode(&metadata)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != harborMetadataMimeType || len(metadata.Capabilities) != 1 || metadata.Capabilities[0].ProducesMimeTypes[0] != harborReportMimeType {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("robot:secret"))
+	body := `{"registry":{"url":"https://harbor.example.com","authorization":"` + auth + `"},"artifact":{"repository":"library/alpine","digest":"sha256:1234"}}`
+	resp, err = client.Post(server.URL+"/api/v1/scan", harborScanRequestMimeType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created map[string]string
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || created["id"] == "" {
+		t.Fatalf("expected scan to be accepted, got %s", resp.Status)
+	}
+	if image := <-images; image != "harbor.example.com/library/alpine@sha256:1234" {
+		t.Errorf("unexpected image %s", image)
+	}
+
+	var report HarborReport
+	for i := 0; ; i++ {
+		resp, err := client.Get(server.URL + "/api/v1/scan/" + created["id"] + "/report")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			_ = json.NewDecoder(resp.Body).Decode(&report)
+			resp.Body.Close()
+			break
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound || resp.Header.Get("Refresh-After") != harborRefreshAfter || i == 100 {
+			t.Fatalf("unexpected report response %s", resp.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if report.Artifact.Digest != "sha256:1234" || len(report.Vulnerabilities) != 1 || report.Vulnerabilities[0].Id != "CVE-2022-0001" {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	resp, err = client.Get(server.URL + "/api/v1/scan/unknown/report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected unknown scan to be not found, got %s", resp.Status)
+	}
+}
+
+// bearerTransport authorizes requests with token like Harbor does with the authorization of
+// the registered scanner
+type bearerTransport struct {
+	token string
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestToHarborReport(t *testing.T) {
+	sb := &types.Sbom{
+		Artifacts: []types.Package{{Purl: "pkg:deb/debian/openssl@1.1.1k", Name: "openssl", Version: "1.1.1k"}},
+		Vulnerabilities: []types.Cve{
+			{SourceId: "CVE-2022-0001", Purl: "pkg:deb/debian/openssl@1.1.1k", FixedBy: "1.1.1n", Advisory: &types.Advisory{References: []types.Reference{{Source: "atomist", Scores: []types.Score{{Type: "atm_severity", Value: "HIGH"}}}}}},
+			{SourceId: "CVE-2022-0002", Purl: "pkg:deb/debian/openssl@1.1.1k", FixedBy: "not fixed", Advisory: &types.Advisory{References: []types.Reference{{Source: "atomist", Scores: []types.Score{{Type: "atm_severity", Value: "LOW"}}}}}},
+		},
+	}
+	report := toHarborReport(sb, HarborArtifact{Repository: "library/alpine"}, time.Now())
+	if report.Severity != "High" || len(report.Vulnerabilities) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if v := report.Vulnerabilities[0]; v.Package != "openssl" || v.Version != "1.1.1k" || v.FixVersion != "1.1.1n" || v.Severity != "High" {
+		t.Errorf("unexpected vulnerability %+v", v)
+	}
+	if v := report.Vulnerabilities[1]; v.FixVersion != "" || v.Severity != "Low" {
+		t.Errorf("unexpected vulnerability %+v", v)
+	}
+}
+
+func TestHarborAuthenticator(t *testing.T) {
+	auth, err := harborAuthenticator("Bearer token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, _ := auth.Authorization(); config.RegistryToken != "token" {
+		t.Errorf("unexpected bearer config %+v", config)
+	}
+	auth, _ = harborAuthenticator("Basic " + base64.StdEncoding.EncodeToString([]byte("robot:secret")))
+	if config, _ := auth.Authorization(); *config != (authn.AuthConfig{Username: "robot", Password: "secret"}) {
+		t.Errorf("unexpected basic config %+v", config)
+	}
+	if _, err := harborAuthenticator("Digest abc"); err == nil {
+		t.Error("expected unsupported scheme to fail")
+	}
+}
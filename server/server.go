@@ -0,0 +1,374 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/metrics"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// retention is the time finished scans can be fetched before they are removed
+const retention = time.Hour
+
+// indexFunc indexes a single image; replaced in tests
+var indexFunc = func(ctx context.Context, indexer *sbom.Indexer, image string) (*types.Sbom, *v1.Image, error) {
+	return indexer.Index(ctx, image)
+}
+
+// ScanRequest is the body of POST /scan
+type ScanRequest struct {
+	Image string `json:"image"`
+	// IncludeCves queries the CVEs of the image after indexing, defaults to true
+	IncludeCves *bool `json:"include_cves,omitempty"`
+}
+
+// Scan is the state of a scan returned by POST /scan and GET /scan/{id}
+type Scan struct {
+	Id        string      `json:"id"`
+	Image     string      `json:"image"`
+	Status    string      `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Created   time.Time   `json:"created"`
+	Completed *time.Time  `json:"completed,omitempty"`
+	Sbom      *types.Sbom `json:"sbom,omitempty"`
+
+	// artifact is the Harbor artifact of scans requested with the Harbor scanner API
+	artifact *HarborArtifact
+	// repository and auth are the registry credentials Harbor passed for the scan, see
+	// registry.WithCredentials
+	repository string
+	auth       authn.Authenticator
+}
+
+// DefaultQueueSize is the number of scans waiting for a free slot before new ones are rejected
+const DefaultQueueSize = 100
+
+// Options configure a Server
+type Options struct {
+	// Parallelism limits the number of images indexed at the same time, defaults to 1
+	Parallelism int
+	// QueueSize limits the number of scans waiting to be run, defaults to DefaultQueueSize.
+	// Requests beyond it are rejected with 429 Too Many Requests.
+	QueueSize int
+	// Timeout limits the time to index and query a single image, no limit if zero
+	Timeout time.Duration
+	// Token is the bearer token requests must be authorized with, no authorization if empty
+	Token string
+}
+
+// job is a scan waiting in the queue of the server
+type job struct {
+	scan        *Scan
+	includeCves bool
+}
+
+// Server runs scans requested over its REST API in the background
+type Server struct {
+	indexer *sbom.Indexer
+	backend query.Backend
+	opts    Options
+	ctx     context.Context
+	queue   chan job
+
+	mutex sync.Mutex
+	scans map[string]*Scan
+}
+
+// New returns a Server indexing images with indexer and querying their CVEs from backend.
+// Running scans are cancelled and queued ones failed once ctx is done.
+func New(ctx context.Context, indexer *sbom.Indexer, backend query.Backend, opts Options) *Server {
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	s := &Server{
+		indexer: indexer,
+		backend: backend,
+		opts:    opts,
+		ctx:     ctx,
+		queue:   make(chan job, opts.QueueSize),
+		scans:   make(map[string]*Scan),
+	}
+	for i := 0; i < opts.Parallelism; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// Handler returns the http.Handler serving the REST API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scan", s.authorize(s.handleCreate))
+	mux.HandleFunc("/scan/", s.authorize(s.handleGet))
+	mux.HandleFunc("/api/v1/metadata", s.authorize(s.handleHarborMetadata))
+	mux.HandleFunc("/api/v1/scan", s.authorize(s.handleHarborScan))
+	mux.HandleFunc("/api/v1/scan/", s.authorize(s.handleHarborReport))
+	mux.HandleFunc("/metrics", s.authorize(metrics.Handler().ServeHTTP))
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// ListenAndServe serves the REST API on addr until the context of the server is done.
+// Addresses other clients can reach are refused unless the server requires a token.
+func (s *Server) ListenAndServe(addr string) error {
+	if s.opts.Token == "" && !isLoopback(addr) {
+		return errors.Errorf("refusing to serve on %s without a token, set --token or DOCKER_INDEX_SERVE_TOKEN", addr)
+	}
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-s.ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	log.Infof("Listening on %s", addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return errors.Wrapf(err, "failed to serve on %s", addr)
+	}
+	return nil
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	var req ScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: %s", err)
+		return
+	}
+	if req.Image == "" {
+		writeError(w, http.StatusBadRequest, "image is required")
+		return
+	}
+	includeCves := req.IncludeCves == nil || *req.IncludeCves
+
+	scan := &Scan{
+		Id:      newId(),
+		Image:   req.Image,
+		Status:  StatusPending,
+		Created: time.Now().UTC(),
+	}
+	response := *scan
+	if !s.enqueue(scan, includeCves) {
+		writeError(w, http.StatusTooManyRequests, "too many scans queued, retry later")
+		return
+	}
+	log.Infof("Scan %s of %s requested", scan.Id, scan.Image)
+	writeJson(w, http.StatusAccepted, response)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/scan/")
+	s.mutex.Lock()
+	scan, ok := s.scans[id]
+	var response Scan
+	if ok {
+		response = *scan
+	}
+	s.mutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "scan %s not found", id)
+		return
+	}
+	writeJson(w, http.StatusOK, response)
+}
+
+// authorize wraps handler to reject requests without the bearer token of the server
+func (s *Server) authorize(handler http.HandlerFunc) http.HandlerFunc {
+	if s.opts.Token == "" {
+		return handler
+	}
+	expected := []byte("Bearer " + s.opts.Token)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// isLoopback returns true if addr only accepts connections from the local host
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// enqueue records the scan and queues it to be run, unless the queue is full
+func (s *Server) enqueue(scan *Scan, includeCves bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case s.queue <- job{scan: scan, includeCves: includeCves}:
+	default:
+		return false
+	}
+	s.removeExpired()
+	s.scans[scan.Id] = scan
+	return true
+}
+
+// work runs the queued scans one after the other until the context of the server is done,
+// then fails the scans still queued
+func (s *Server) work() {
+	for {
+		select {
+		case j := <-s.queue:
+			s.run(j.scan, j.includeCves)
+		case <-s.ctx.Done():
+			for {
+				select {
+				case j := <-s.queue:
+					s.finish(j.scan, nil, s.ctx.Err())
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run indexes the image and queries its CVEs
+func (s *Server) run(scan *Scan, includeCves bool) {
+	s.update(scan, StatusRunning)
+
+	ctx := s.ctx
+	if scan.auth != nil {
+		var err error
+		if ctx, err = registry.WithCredentials(ctx, scan.repository, scan.auth); err != nil {
+			s.finish(scan, nil, err)
+			return
+		}
+	}
+	if s.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	sb, _, err := indexFunc(ctx, s.indexer, scan.Image)
+	metrics.ObserveScan(start, err)
+	if err != nil {
+		s.finish(scan, nil, err)
+		return
+	}
+	if includeCves {
+		cves, err := s.backend.QueryCves(ctx, sb, "")
+		if err != nil {
+			s.finish(scan, nil, errors.Wrap(err, "failed to query CVEs"))
+			return
+		}
+		if cves != nil {
+			sb.Vulnerabilities = *cves
+			sbom.SortSbom(sb)
+			for _, c := range *cves {
+				metrics.ObserveCve(sbom.Severity(c))
+			}
+		}
+	}
+	s.finish(scan, sb, nil)
+}
+
+func (s *Server) update(scan *Scan, status string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	scan.Status = status
+}
+
+func (s *Server) finish(scan *Scan, sb *types.Sbom, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now().UTC()
+	scan.Completed = &now
+	if err != nil {
+		log.Warnf("Scan %s of %s failed: %s", scan.Id, scan.Image, err)
+		scan.Status = StatusFailed
+		scan.Error = err.Error()
+		return
+	}
+	log.Infof("Scan %s of %s completed", scan.Id, scan.Image)
+	scan.Status = StatusCompleted
+	scan.Sbom = sb
+}
+
+// removeExpired removes scans finished longer than the retention ago; callers hold the mutex
+func (s *Server) removeExpired() {
+	for id, scan := range s.scans {
+		if scan.Completed != nil && time.Since(*scan.Completed) > retention {
+			delete(s.scans, id)
+		}
+	}
+}
+
+func newId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJson(w, status, map[string]string{"error": errors.Errorf(format, args...).Error()})
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+type fakeBackend struct{}
+
+func (b fakeBackend) QueryCves(ctx context.Context, sb *types.Sbom, cve string) (*[]types.Cve, error) {
+	return &[]types.Cve{{SourceId: "CVE-2022-0001"}}, nil
+}
+
+func TestServer(t *testing.T) {
+	defer func(f func(context.Context, *sbom.Indexer, string) (*types.Sbom, *v1.Image, error)) { indexFunc = f }(indexFunc)
+	indexFunc = func(ctx context.Context, indexer *sbom.Indexer, image string) (*types.Sbom, *v1.Image, error) {
+		if image == "missing" {
+			return nil, nil, errors.New("image not found")
+		}
+		return &types.Sbom{Source: types.Source{Image: types.ImageSource{Name: image}}}, nil, nil
+	}
+	s := New(context.Background(), sbom.NewIndexer(), fakeBackend{}, Options{})
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	scan := func(image string) Scan {
+		resp, err := http.Post(server.URL+"/scan", "application/json", strings.NewReader(`{"image":"`+image+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatalf("expected scan to be accepted, got %s", resp.Status)
+		}
+		var created Scan
+		_ = json.NewDecoder(resp.Body).Decode(&created)
+		for i := 0; i < 100; i++ {
+			resp, err := http.Get(server.URL + "/scan/" + created.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var scan Scan
+			_ = json.NewDecoder(resp.Body).Decode(&scan)
+			resp.Body.Close()
+			if scan.Status == StatusCompleted || scan.Status == StatusFailed {
+				return scan
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("scan %s did not finish", created.Id)
+		return Scan{}
+	}
+
+	if s := scan("alpine"); s.Status != StatusCompleted || s.Sbom == nil || len(s.Sbom.Vulnerabilities) != 1 {
+		t.Errorf("expected completed scan with CVEs, got %+v", s)
+	}
+	if s := scan("missing"); s.Status != StatusFailed || s.Error != "image not found" {
+		t.Errorf("expected failed scan, got %+v", s)
+	}
+
+	resp, err := http.Get(server.URL + "/scan/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected unknown scan not to be found, got %s", resp.Status)
+	}
+}
+
+func TestServerAuthorization(t *testing.T) {
+	s := New(context.Background(), sbom.NewIndexer(), fakeBackend{}, Options{Token: "secret"})
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		path          string
+		authorization string
+		status        int
+	}{
+		{path: "/scan/unknown", status: http.StatusUnauthorized},
+		{path: "/scan/unknown", authorization: "Bearer other", status: http.StatusUnauthorized},
+		{path: "/scan/unknown", authorization: "Bearer secret", status: http.StatusNotFound},
+		{path: "/metrics", status: http.StatusUnauthorized},
+		{path: "/health", status: http.StatusOK},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+test.path, nil)
+		if test.authorization != "" {
+			req.Header.Set("Authorization", test.authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("expected %d for %s with %q, got %s", test.status, test.path, test.authorization, resp.Status)
+		}
+	}
+}
+
+func TestServerQueue(t *testing.T) {
+	defer func(f func(context.Context, *sbom.Indexer, string) (*types.Sbom, *v1.Image, error)) { indexFunc = f }(indexFunc)
+	started, release := make(chan string, 2), make(chan struct{})
+	indexFunc = func(ctx context.Context, indexer *sbom.Indexer, image string) (*types.Sbom, *v1.Image, error) {
+		started <- image
+		<-release
+		return &types.Sbom{}, nil, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(ctx, sbom.NewIndexer(), fakeBackend{}, Options{Parallelism: 1, QueueSize: 1})
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	post := func(image string) int {
+		resp, err := http.Post(server.URL+"/scan", "application/json", strings.NewReader(`{"image":"`+image+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := post("running"); status != http.StatusAccepted {
+		t.Fatalf("expected first scan to be accepted, got %d", status)
+	}
+	<-started
+	if status := post("queued"); status != http.StatusAccepted {
+		t.Errorf("expected second scan to be queued, got %d", status)
+	}
+	if status := post("rejected"); status != http.StatusTooManyRequests {
+		t.Errorf("expected third scan to be rejected, got %d", status)
+	}
+	close(release)
+	if image := <-started; image != "queued" {
+		t.Errorf("expected queued scan to run next, got %s", image)
+	}
+}
+
+func TestServerListenWithoutToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := New(ctx, sbom.NewIndexer(), fakeBackend{}, Options{})
+	if err := s.ListenAndServe(":0"); err == nil {
+		t.Error("expected serving on all interfaces without a token to fail")
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr     string
+		loopback bool
+	}{
+		{"127.0.0.1:8080", true},
+		{"localhost:8080", true},
+		{"[::1]:8080", true},
+		{":8080", false},
+		{"0.0.0.0:8080", false},
+		{"192.168.1.10:8080", false},
+		{"example.com:8080", false},
+	}
+	for _, test := range tests {
+		if l := isLoopback(test.addr); l != test.loopback {
+			t.Errorf("expected %v for %s, got %v", test.loopback, test.addr, l)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/pkg/errors"
+)
+
+// azureEndpoint is the blob service url of a storage account; replaced in tests
+var azureEndpoint = "https://%s.blob.core.windows.net"
+
+// azureVersion is the Blob service REST API version, supporting encryption scopes
+const azureVersion = "2021-08-06"
+
+type azureSink struct {
+	authorizer autorest.Authorizer
+	sas        string
+	account    string
+	container  string
+	prefix     string
+	opts       Options
+}
+
+// newAzureSink authorizes with the SAS token in AZURE_STORAGE_SAS_TOKEN, or else with Azure AD
+// credentials from the environment or the az CLI; the container is the first segment of prefix
+func newAzureSink(ctx context.Context, account, prefix string, opts Options) (Sink, error) {
+	if opts.Encryption != "" {
+		return nil, errors.Errorf("unsupported Azure encryption %s; set an encryption scope instead", opts.Encryption)
+	}
+	container, prefix, _ := strings.Cut(prefix, "/")
+	if container == "" {
+		return nil, errors.Errorf("missing container in az://%s/", account)
+	}
+	s := &azureSink{account: account, container: container, prefix: prefix, opts: opts}
+	if sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sas != "" {
+		s.sas = strings.TrimPrefix(sas, "?")
+		return s, nil
+	}
+	var err error
+	resource := "https://storage.azure.com/"
+	if os.Getenv("AZURE_CLIENT_ID") != "" {
+		s.authorizer, err = auth.NewAuthorizerFromEnvironmentWithResource(resource)
+	} else {
+		s.authorizer, err = auth.NewAuthorizerFromCLIWithResource(resource)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find Azure credentials")
+	}
+	return s, nil
+}
+
+func (s *azureSink) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	u := fmt.Sprintf(azureEndpoint, s.account) + "/" + s.container + "/" + s.prefix + key
+	if s.sas != "" {
+		u += "?" + s.sas
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-ms-version", azureVersion)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-blob-content-type", contentType)
+	if s.opts.KmsKey != "" {
+		req.Header.Set("x-ms-encryption-scope", s.opts.KmsKey)
+	}
+	if s.authorizer != nil {
+		if req, err = autorest.Prepare(req, s.authorizer.WithAuthorization()); err != nil {
+			return "", errors.Wrap(err, "failed to authorize Azure request")
+		}
+	}
+	location := "az://" + s.account + "/" + s.container + "/" + s.prefix + key
+	if err := do(req); err != nil {
+		return "", errors.Wrapf(err, "failed to upload %s", location)
+	}
+	return location, nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// gcsEndpoint is the base url of the GCS JSON API; replaced in tests
+var gcsEndpoint = "https://storage.googleapis.com"
+
+// gcsTokenSource returns the application default credentials; replaced in tests
+var gcsTokenSource = func(ctx context.Context) (oauth2.TokenSource, error) {
+	return google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
+}
+
+type gcsSink struct {
+	tokens oauth2.TokenSource
+	bucket string
+	prefix string
+	opts   Options
+}
+
+// newGcsSink uses the application default credentials, e.g. of gcloud or the metadata server
+func newGcsSink(ctx context.Context, bucket, prefix string, opts Options) (Sink, error) {
+	if opts.Encryption != "" {
+		return nil, errors.Errorf("unsupported GCS encryption %s; set a Cloud KMS key instead", opts.Encryption)
+	}
+	tokens, err := gcsTokenSource(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find Google credentials")
+	}
+	return &gcsSink{tokens: tokens, bucket: bucket, prefix: prefix, opts: opts}, nil
+}
+
+func (s *gcsSink) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	query := url.Values{"uploadType": {"media"}, "name": {s.prefix + key}}
+	if s.opts.KmsKey != "" {
+		query.Set("kmsKeyName", s.opts.KmsKey)
+	}
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", gcsEndpoint, url.PathEscape(s.bucket), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	token, err := s.tokens.Token()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get Google access token")
+	}
+	token.SetAuthHeader(req)
+	if err := do(req); err != nil {
+		return "", errors.Wrapf(err, "failed to upload gs://%s/%s%s", s.bucket, s.prefix, key)
+	}
+	return "gs://" + s.bucket + "/" + s.prefix + key, nil
+}
+
+// do sends req and turns unsuccessful responses into errors
+func do(req *http.Request) error {
+	resp, err := internal.HttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
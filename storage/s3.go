@@ -0,0 +1,84 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/pkg/errors"
+)
+
+// s3Endpoint overrides the S3 endpoint; replaced in tests
+var s3Endpoint = ""
+
+type s3Sink struct {
+	client *s3.S3
+	bucket string
+	prefix string
+	opts   Options
+}
+
+// newS3Sink uses the default AWS credential chain, i.e. the environment, shared config and
+// instance roles, in the region of the bucket
+func newS3Sink(ctx context.Context, bucket, prefix string, opts Options) (Sink, error) {
+	switch opts.Encryption {
+	case "", s3.ServerSideEncryptionAes256, s3.ServerSideEncryptionAwsKms:
+	default:
+		return nil, errors.Errorf("unsupported S3 encryption %s", opts.Encryption)
+	}
+	config := aws.NewConfig().WithHTTPClient(internal.HttpClient())
+	if s3Endpoint != "" {
+		config = config.WithEndpoint(s3Endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{Config: *config, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AWS session")
+	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		region, err := s3manager.GetBucketRegion(ctx, sess, bucket, "us-east-1")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get region of bucket %s", bucket)
+		}
+		sess.Config.Region = aws.String(region)
+	}
+	return &s3Sink{client: s3.New(sess), bucket: bucket, prefix: prefix, opts: opts}, nil
+}
+
+func (s *s3Sink) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.prefix + key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+	}
+	if s.opts.Encryption != "" {
+		input.ServerSideEncryption = aws.String(s.opts.Encryption)
+	}
+	if s.opts.KmsKey != "" {
+		input.SSEKMSKeyId = aws.String(s.opts.KmsKey)
+	}
+	if _, err := s.client.PutObjectWithContext(ctx, input); err != nil {
+		return "", errors.Wrapf(err, "failed to upload s3://%s/%s%s", s.bucket, s.prefix, key)
+	}
+	return "s3://" + s.bucket + "/" + s.prefix + key, nil
+}
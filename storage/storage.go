@@ -0,0 +1,82 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/pkg/errors"
+)
+
+// Options configure the server-side encryption of uploaded objects
+type Options struct {
+	// Encryption is the S3 server-side encryption, AES256 or aws:kms
+	Encryption string
+	// KmsKey is the KMS key id of aws:kms encryption, the Cloud KMS key name of GCS objects or
+	// the encryption scope of Azure blobs
+	KmsKey string
+}
+
+// Sink stores objects in a bucket of a cloud object storage
+type Sink interface {
+	// Put stores data at key below the prefix of the sink and returns the location of the object
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+}
+
+var schemes = map[string]func(ctx context.Context, bucket, prefix string, opts Options) (Sink, error){
+	"s3": newS3Sink,
+	"gs": newGcsSink,
+	"az": newAzureSink,
+}
+
+// IsLocation returns true if location is an s3://, gs:// or az:// url instead of a local path
+func IsLocation(location string) bool {
+	scheme, _, ok := strings.Cut(location, "://")
+	_, known := schemes[scheme]
+	return ok && known
+}
+
+// Open returns the sink of location, s3://bucket/prefix/, gs://bucket/prefix/ or
+// az://account/container/prefix/
+func Open(ctx context.Context, location string, opts Options) (Sink, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid storage location %s", location)
+	}
+	open, ok := schemes[u.Scheme]
+	if !ok || u.Host == "" {
+		return nil, errors.Errorf("unsupported storage location %s", location)
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return open(ctx, u.Host, prefix, opts)
+}
+
+// Key returns the key of the artifact name of sb below the digest of its image, like
+// sha256/<hex>/sbom.json, so results of the same image overwrite each other
+func Key(sb *types.Sbom, name string) (string, error) {
+	algorithm, hex, ok := strings.Cut(sb.Source.Image.Digest, ":")
+	if !ok || hex == "" {
+		return "", errors.Errorf("image %s has no digest to store results under", sb.Source.Image.Name)
+	}
+	return algorithm + "/" + hex + "/" + name, nil
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/types"
+	"golang.org/x/oauth2"
+)
+
+type request struct {
+	method, path, query string
+	header              http.Header
+	body                string
+}
+
+func recorder(t *testing.T) (*httptest.Server, *[]request) {
+	requests := make([]request, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.URL.RawQuery, r.Header, string(body)})
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestKey(t *testing.T) {
+	sb := &types.Sbom{Source: types.Source{Image: types.ImageSource{Name: "alpine", Digest: "sha256:1234"}}}
+	if key, err := Key(sb, "sbom.json"); err != nil || key != "sha256/1234/sbom.json" {
+		t.Errorf("unexpected key %s: %v", key, err)
+	}
+	sb.Source.Image.Digest = ""
+	if _, err := Key(sb, "sbom.json"); err == nil {
+		t.Error("expected image without digest to fail")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if !IsLocation("s3://bucket/prefix") || IsLocation("sbom.json") || IsLocation("ftp://host/sbom.json") {
+		t.Error("unexpected storage location detection")
+	}
+	if _, err := Open(context.Background(), "ftp://host/prefix/", Options{}); err == nil {
+		t.Error("expected unsupported scheme to fail")
+	}
+	if _, err := Open(context.Background(), "gs://bucket/", Options{Encryption: "AES256"}); err == nil {
+		t.Error("expected S3 encryption on GCS to fail")
+	}
+}
+
+func TestS3Sink(t *testing.T) {
+	server, requests := recorder(t)
+	defer func(endpoint string) { s3Endpoint = endpoint }(s3Endpoint)
+	s3Endpoint = server.URL
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	sink, err := Open(context.Background(), "s3://bucket/scans", Options{Encryption: "aws:kms", KmsKey: "alias/sbom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := sink.Put(context.Background(), "sha256/1234/sbom.json", []byte("{}"), "application/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "s3://bucket/scans/sha256/1234/sbom.json" {
+		t.Errorf("unexpected location %s", location)
+	}
+	r := (*requests)[0]
+	if r.method != http.MethodPut || r.path != "/bucket/scans/sha256/1234/sbom.json" || r.body != "{}" {
+		t.Errorf("unexpected request %+v", r)
+	}
+	if r.header.Get("Content-Type") != "application/json" || r.header.Get("X-Amz-Server-Side-Encryption") != "aws:kms" || r.header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "alias/sbom" {
+		t.Errorf("unexpected headers %v", r.header)
+	}
+}
+
+func TestGcsSink(t *testing.T) {
+	server, requests := recorder(t)
+	defer func(endpoint string) { gcsEndpoint = endpoint }(gcsEndpoint)
+	gcsEndpoint = server.URL
+	defer func(f func(context.Context) (oauth2.TokenSource, error)) { gcsTokenSource = f }(gcsTokenSource)
+	gcsTokenSource = func(ctx context.Context) (oauth2.TokenSource, error) {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), nil
+	}
+
+	sink, err := Open(context.Background(), "gs://bucket/scans/", Options{KmsKey: "projects/p/locations/l/keyRings/r/cryptoKeys/k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := sink.Put(context.Background(), "sha256/1234/report.html", []byte("<html>"), "text/html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "gs://bucket/scans/sha256/1234/report.html" {
+		t.Errorf("unexpected location %s", location)
+	}
+	r := (*requests)[0]
+	if r.method != http.MethodPost || r.path != "/upload/storage/v1/b/bucket/o" || r.body != "<html>" {
+		t.Errorf("unexpected request %+v", r)
+	}
+	if r.query != "kmsKeyName=projects%2Fp%2Flocations%2Fl%2FkeyRings%2Fr%2FcryptoKeys%2Fk&name=scans%2Fsha256%2F1234%2Freport.html&uploadType=media" {
+		t.Errorf("unexpected query %s", r.query)
+	}
+	if r.header.Get("Authorization") != "Bearer token" || r.header.Get("Content-Type") != "text/html" {
+		t.Errorf("unexpected headers %v", r.header)
+	}
+}
+
+func TestAzureSink(t *testing.T) {
+	server, requests := recorder(t)
+	defer func(endpoint string) { azureEndpoint = endpoint }(azureEndpoint)
+	azureEndpoint = server.URL + "/%s"
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2021&sig=abc")
+
+	if _, err := Open(context.Background(), "az://account", Options{}); err == nil {
+		t.Error("expected location without container to fail")
+	}
+	sink, err := Open(context.Background(), "az://account/container/scans", Options{KmsKey: "scope"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := sink.Put(context.Background(), "sha256/1234/sbom.json", []byte("{}"), "application/json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "az://account/container/scans/sha256/1234/sbom.json" {
+		t.Errorf("unexpected location %s", location)
+	}
+	r := (*requests)[0]
+	if r.method != http.MethodPut || r.path != "/account/container/scans/sha256/1234/sbom.json" || r.query != "sv=2021&sig=abc" {
+		t.Errorf("unexpected request %+v", r)
+	}
+	if r.header.Get("x-ms-blob-type") != "BlockBlob" || r.header.Get("x-ms-blob-content-type") != "application/json" || r.header.Get("x-ms-encryption-scope") != "scope" {
+		t.Errorf("unexpected headers %v", r.header)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	ctx, scan := Start(context.Background(), "scan", Image("alpine:3.16"))
+	_, syft := Start(ctx, "syft", Digest("sha256:1234"))
+	End(syft, errors.New("failed to index image"))
+	End(scan, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name() != "syft" || spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("expected syft span to be a child of scan")
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "failed to index image" {
+		t.Errorf("unexpected status %v", spans[0].Status())
+	}
+	if len(spans[0].Events()) != 1 {
+		t.Errorf("expected recorded error event")
+	}
+	if spans[1].Status().Code == codes.Error {
+		t.Errorf("expected scan span to succeed")
+	}
+	attrs := spans[0].Attributes()
+	if len(attrs) != 1 || string(attrs[0].Key) != "docker_index.image.digest" || attrs[0].Value.AsString() != "sha256:1234" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	shutdown, err := Setup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
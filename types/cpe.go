@@ -0,0 +1,177 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cpeDictionary maps packages to the vendor and product of their NVD CPEs. Keys are the purl
+// type, namespace and name, the purl type and name, or only the name of OS packages, whose
+// names are looked up for every distro.
+var cpeDictionary = map[string][]string{
+	// OS packages
+	"bash":           {"gnu:bash"},
+	"busybox":        {"busybox:busybox"},
+	"curl":           {"haxx:curl"},
+	"curl-minimal":   {"haxx:curl"},
+	"libcurl":        {"haxx:libcurl"},
+	"libcurl4":       {"haxx:libcurl"},
+	"e2fsprogs":      {"e2fsprogs_project:e2fsprogs"},
+	"expat":          {"libexpat_project:libexpat"},
+	"libexpat1":      {"libexpat_project:libexpat"},
+	"git":            {"git-scm:git"},
+	"glibc":          {"gnu:glibc"},
+	"libc6":          {"gnu:glibc"},
+	"libc-bin":       {"gnu:glibc"},
+	"gnutls":         {"gnu:gnutls"},
+	"libgnutls30":    {"gnu:gnutls"},
+	"gzip":           {"gnu:gzip"},
+	"krb5":           {"mit:kerberos_5"},
+	"krb5-libs":      {"mit:kerberos_5"},
+	"libkrb5-3":      {"mit:kerberos_5"},
+	"libgcrypt":      {"gnupg:libgcrypt"},
+	"libgcrypt20":    {"gnupg:libgcrypt"},
+	"libpng":         {"libpng:libpng"},
+	"libpng16-16":    {"libpng:libpng"},
+	"libxml2":        {"xmlsoft:libxml2"},
+	"musl":           {"musl-libc:musl"},
+	"ncurses":        {"gnu:ncurses"},
+	"libncurses6":    {"gnu:ncurses"},
+	"nginx":          {"f5:nginx"},
+	"openssh":        {"openbsd:openssh"},
+	"openssh-client": {"openbsd:openssh"},
+	"openssh-server": {"openbsd:openssh"},
+	"openssl":        {"openssl:openssl"},
+	"openssl-libs":   {"openssl:openssl"},
+	"libssl1.1":      {"openssl:openssl"},
+	"libssl3":        {"openssl:openssl"},
+	"libcrypto3":     {"openssl:openssl"},
+	"pcre2":          {"pcre:pcre2"},
+	"perl":           {"perl:perl"},
+	"perl-base":      {"perl:perl"},
+	"sqlite":         {"sqlite:sqlite"},
+	"sqlite-libs":    {"sqlite:sqlite"},
+	"libsqlite3-0":   {"sqlite:sqlite"},
+	"systemd":        {"systemd_project:systemd"},
+	"libsystemd0":    {"systemd_project:systemd"},
+	"tar":            {"gnu:tar"},
+	"util-linux":     {"kernel:util-linux"},
+	"zlib":           {"zlib:zlib"},
+	"zlib1g":         {"zlib:zlib"},
+
+	// language packages
+	"composer/laravel/framework":      {"laravel:framework"},
+	"gem/nokogiri":                    {"nokogiri:nokogiri"},
+	"gem/rails":                       {"rubyonrails:rails"},
+	"golang/github.com/gin-gonic/gin": {"gin-gonic:gin"},
+	"golang/stdlib":                   {"golang:go"},
+	"maven/com.fasterxml.jackson.core/jackson-databind": {"fasterxml:jackson-databind"},
+	"maven/org.apache.logging.log4j/log4j-core":         {"apache:log4j"},
+	"maven/org.apache.tomcat.embed/tomcat-embed-core":   {"apache:tomcat"},
+	"maven/org.springframework/spring-core":             {"vmware:spring_framework"},
+	"maven/org.springframework/spring-webmvc":           {"vmware:spring_framework"},
+	"maven/org.yaml/snakeyaml":                          {"snakeyaml_project:snakeyaml"},
+	"npm/axios":                                         {"axios:axios"},
+	"npm/jquery":                                        {"jquery:jquery"},
+	"npm/lodash":                                        {"lodash:lodash"},
+	"npm/minimist":                                      {"minimist_project:minimist"},
+	"nuget/newtonsoft.json":                             {"newtonsoft:json.net"},
+	"pypi/django":                                       {"djangoproject:django"},
+	"pypi/flask":                                        {"palletsprojects:flask"},
+	"pypi/pyyaml":                                       {"pyyaml:pyyaml"},
+	"pypi/requests":                                     {"python:requests"},
+	"pypi/urllib3":                                      {"python:urllib3"},
+}
+
+// ToCpes returns the CPE 2.3 names of pkg, using the curated dictionary for common products
+// and guessing vendor and product from the purl otherwise
+func ToCpes(pkg Package) []string {
+	purl, err := ToPackageUrl(pkg.Purl)
+	if err != nil || purl.Name == "" || purl.Version == "" {
+		return nil
+	}
+	osPackage := purl.Type == "deb" || purl.Type == "rpm" || purl.Type == "alpine"
+	version := purl.Version
+	if osPackage {
+		// NVD versions are upstream versions without epoch and distro revision
+		if _, v, ok := strings.Cut(version, ":"); ok {
+			version = v
+		}
+		if i := strings.LastIndex(version, "-"); i > 0 {
+			version = version[:i]
+		}
+	}
+
+	keys := []string{purl.Type + "/" + purl.Name}
+	if purl.Namespace != "" {
+		keys = append([]string{purl.Type + "/" + purl.Namespace + "/" + purl.Name}, keys...)
+	}
+	if osPackage {
+		keys = append(keys, purl.Name)
+	}
+	var products []string
+	for _, k := range keys {
+		if p, ok := cpeDictionary[strings.ToLower(k)]; ok {
+			products = p
+			break
+		}
+	}
+	if products == nil {
+		products = []string{guessCpeVendor(purl.Type, purl.Namespace, purl.Name) + ":" + purl.Name}
+	}
+
+	cpes := make([]string, 0, len(products))
+	for _, p := range products {
+		vendor, product, _ := strings.Cut(p, ":")
+		cpes = append(cpes, fmt.Sprintf("cpe:2.3:a:%s:%s:%s:*:*:*:*:*:*:*", escapeCpe(vendor), escapeCpe(product), escapeCpe(version)))
+	}
+	return cpes
+}
+
+// guessCpeVendor derives the vendor of a package missing in the dictionary from its namespace,
+// e.g. the organization of a Maven group or the owner of a Go module repository
+func guessCpeVendor(purlType, namespace, name string) string {
+	switch purlType {
+	case "maven":
+		if parts := strings.Split(namespace, "."); len(parts) > 1 {
+			return parts[1]
+		}
+	case "golang":
+		if parts := strings.Split(namespace, "/"); len(parts) > 1 {
+			return parts[1]
+		}
+	case "npm", "composer":
+		if namespace != "" {
+			return strings.TrimPrefix(namespace, "@")
+		}
+	}
+	return name
+}
+
+// escapeCpe escapes a value for the CPE 2.3 formatted string binding
+func escapeCpe(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.ReplaceAll(value, " ", "_")) {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
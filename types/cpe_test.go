@@ -0,0 +1,40 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToCpes(t *testing.T) {
+	tests := map[string][]string{
+		"pkg:deb/debian/libssl1.1@1:1.1.1n-0+deb11u3?os_name=debian&os_version=11": {"cpe:2.3:a:openssl:openssl:1.1.1n:*:*:*:*:*:*:*"},
+		"pkg:alpine/busybox@1.35.0-r17?os_name=alpine&os_version=3.16":             {"cpe:2.3:a:busybox:busybox:1.35.0:*:*:*:*:*:*:*"},
+		"pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1":                     {"cpe:2.3:a:apache:log4j:2.14.1:*:*:*:*:*:*:*"},
+		"pkg:maven/org.acme.billing/invoice-service@1.0.0":                         {"cpe:2.3:a:acme:invoice-service:1.0.0:*:*:*:*:*:*:*"},
+		"pkg:golang/github.com/sirupsen/logrus@1.9.0":                              {"cpe:2.3:a:sirupsen:logrus:1.9.0:*:*:*:*:*:*:*"},
+		"pkg:npm/%40babel/core@7.0.0%2Bbuild":                                      {"cpe:2.3:a:babel:core:7.0.0\\+build:*:*:*:*:*:*:*"},
+		"pkg:pypi/django@4.1":                                                      {"cpe:2.3:a:djangoproject:django:4.1:*:*:*:*:*:*:*"},
+		"pkg:npm/left-pad":                                                         nil,
+	}
+	for purl, expected := range tests {
+		if cpes := ToCpes(Package{Purl: purl}); !reflect.DeepEqual(cpes, expected) {
+			t.Errorf("expected %v for %s, got %v", expected, purl, cpes)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"regexp"
+	"strings"
+)
+
+// licenseAliases maps common non-SPDX license names as found in package metadata
+// (e.g. Debian copyright files, Python classifiers) onto SPDX license identifiers
+var licenseAliases = map[string]string{
+	"apache":                      "Apache-2.0",
+	"apache 2":                    "Apache-2.0",
+	"apache 2.0":                  "Apache-2.0",
+	"apache-2":                    "Apache-2.0",
+	"apache2":                     "Apache-2.0",
+	"apache license 2.0":          "Apache-2.0",
+	"apache license, version 2.0": "Apache-2.0",
+	"apache software license":     "Apache-2.0",
+	"the apache software license, version 2.0": "Apache-2.0",
+	"mit license":                "MIT",
+	"the mit license":            "MIT",
+	"expat":                      "MIT",
+	"bsd":                        "BSD-3-Clause",
+	"bsd license":                "BSD-3-Clause",
+	"bsd-2":                      "BSD-2-Clause",
+	"bsd-3":                      "BSD-3-Clause",
+	"new bsd license":            "BSD-3-Clause",
+	"simplified bsd license":     "BSD-2-Clause",
+	"isc license":                "ISC",
+	"mpl 2.0":                    "MPL-2.0",
+	"mpl-2":                      "MPL-2.0",
+	"mozilla public license 2.0": "MPL-2.0",
+	"gpl":                        "GPL-2.0-or-later",
+	"gpl-1":                      "GPL-1.0-only",
+	"gpl-1+":                     "GPL-1.0-or-later",
+	"gpl-2":                      "GPL-2.0-only",
+	"gpl-2+":                     "GPL-2.0-or-later",
+	"gpl-2.0":                    "GPL-2.0-only",
+	"gpl-2.0+":                   "GPL-2.0-or-later",
+	"gplv2":                      "GPL-2.0-only",
+	"gplv2+":                     "GPL-2.0-or-later",
+	"gpl-3":                      "GPL-3.0-only",
+	"gpl-3+":                     "GPL-3.0-or-later",
+	"gpl-3.0":                    "GPL-3.0-only",
+	"gpl-3.0+":                   "GPL-3.0-or-later",
+	"gplv3":                      "GPL-3.0-only",
+	"gplv3+":                     "GPL-3.0-or-later",
+	"lgpl":                       "LGPL-2.1-or-later",
+	"lgpl-2":                     "LGPL-2.0-only",
+	"lgpl-2+":                    "LGPL-2.0-or-later",
+	"lgpl-2.1":                   "LGPL-2.1-only",
+	"lgpl-2.1+":                  "LGPL-2.1-or-later",
+	"lgpl-3":                     "LGPL-3.0-only",
+	"lgpl-3+":                    "LGPL-3.0-or-later",
+	"lgpl-3.0":                   "LGPL-3.0-only",
+	"lgpl-3.0+":                  "LGPL-3.0-or-later",
+	"agpl-3":                     "AGPL-3.0-only",
+	"agpl-3+":                    "AGPL-3.0-or-later",
+	"agpl-3.0":                   "AGPL-3.0-only",
+	"agplv3":                     "AGPL-3.0-only",
+	"artistic":                   "Artistic-1.0-Perl",
+	"artistic-2":                 "Artistic-2.0",
+	"zlib":                       "Zlib",
+	"public-domain":              "LicenseRef-public-domain",
+	"public domain":              "LicenseRef-public-domain",
+}
+
+var licenseTokenPattern = regexp.MustCompile(`(?i)\s+(AND|OR|WITH)\s+|[()]`)
+
+// NormalizeLicense maps a single license name onto its SPDX license identifier if known
+func NormalizeLicense(license string) string {
+	license = strings.TrimSpace(license)
+	if id, ok := licenseAliases[strings.ToLower(license)]; ok {
+		return id
+	}
+	return license
+}
+
+// ToLicenseExpression combines the license declarations of a package into a single SPDX
+// expression, normalizing the license names and operators
+func ToLicenseExpression(licenses []string) string {
+	expressions := make([]string, 0)
+	for _, l := range licenses {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		e := normalizeExpression(l)
+		if strings.Contains(e, " ") && len(licenses) > 1 {
+			e = "(" + e + ")"
+		}
+		if !containsString(expressions, e) {
+			expressions = append(expressions, e)
+		}
+	}
+	return strings.Join(expressions, " AND ")
+}
+
+// normalizeExpression normalizes the license names of an expression keeping operators and parentheses
+func normalizeExpression(expression string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range licenseTokenPattern.FindAllStringSubmatchIndex(expression, -1) {
+		b.WriteString(NormalizeLicense(expression[last:m[0]]))
+		if m[2] >= 0 {
+			b.WriteString(" " + strings.ToUpper(expression[m[2]:m[3]]) + " ")
+		} else {
+			b.WriteString(expression[m[0]:m[1]])
+		}
+		last = m[1]
+	}
+	b.WriteString(NormalizeLicense(expression[last:]))
+	return strings.TrimSpace(b.String())
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
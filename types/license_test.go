@@ -0,0 +1,37 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import "testing"
+
+func TestToLicenseExpression(t *testing.T) {
+	tests := []struct {
+		licenses []string
+		want     string
+	}{
+		{[]string{"MIT"}, "MIT"},
+		{[]string{"Apache 2.0"}, "Apache-2.0"},
+		{[]string{"GPL-2+ or Artistic"}, "GPL-2.0-or-later OR Artistic-1.0-Perl"},
+		{[]string{"(MIT OR Apache-2.0)"}, "(MIT OR Apache-2.0)"},
+		{[]string{"GPL-2", "LGPL-2.1+ and BSD"}, "GPL-2.0-only AND (LGPL-2.1-or-later AND BSD-3-Clause)"},
+	}
+	for _, tt := range tests {
+		if got := ToLicenseExpression(tt.licenses); got != tt.want {
+			t.Errorf("ToLicenseExpression(%v) = %s, want %s", tt.licenses, got, tt.want)
+		}
+	}
+}
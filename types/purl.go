@@ -22,20 +22,43 @@ import (
 	"strings"
 
 	"github.com/anchore/packageurl-go"
-	"github.com/atomist-skills/go-skill"
+	"github.com/docker/index-cli-plugin/log"
 )
 
-func NormalizePackages(pkgs []Package) ([]Package, error) {
+// optionalQualifiers are the purl qualifiers of OS packages that can be kept in addition
+// to os_name, os_version and os_distro
+var optionalQualifiers = []string{"arch", "distro", "epoch"}
+
+// ParsePurlQualifiers validates the names of the arch, distro and epoch qualifiers to keep in
+// the purls of OS packages, as some vulnerability matchers require them
+func ParsePurlQualifiers(qualifiers []string) ([]string, error) {
+	selected := make([]string, 0)
+	for _, q := range qualifiers {
+		q = strings.ToLower(strings.TrimSpace(q))
+		if !containsString(optionalQualifiers, q) {
+			return nil, fmt.Errorf("unsupported purl qualifier %s, supported are %s", q, strings.Join(optionalQualifiers, ", "))
+		}
+		if !containsString(selected, q) {
+			selected = append(selected, q)
+		}
+	}
+	return selected, nil
+}
+
+// NormalizePackages normalizes the purls, versions, locations and licenses of pkgs. The purls
+// of OS packages keep os_name, os_version and os_distro plus the optional qualifiers given,
+// see ParsePurlQualifiers.
+func NormalizePackages(pkgs []Package, qualifiers ...string) ([]Package, error) {
 	nPks := make([]Package, 0)
 	for i := range pkgs {
 		pkg := pkgs[i]
 		purl, err := ToPackageUrl(pkg.Purl)
 		if err != nil {
-			skill.Log.Warnf("Failed to parse purl: %s", pkg.Purl)
+			log.Warnf("Failed to parse purl: %s", pkg.Purl)
 			continue
 		}
 		if purl.Type == "" || purl.Name == "" {
-			skill.Log.Warnf("Incomplete purl: %s", pkg.Purl)
+			log.Warnf("Incomplete purl: %s", pkg.Purl)
 			continue
 		}
 		purl.Namespace = toNamespace(purl)
@@ -49,20 +72,26 @@ func NormalizePackages(pkgs []Package) ([]Package, error) {
 		}
 
 		// select the qualifiers we support
+		epoch := purl.Qualifiers.Map()["epoch"]
 		if q := purl.Qualifiers.Map(); len(q) > 0 {
-			qualifiers := make(map[string]string, 0)
-			qualifiers["os_name"] = q["os_name"]
-			qualifiers["os_version"] = q["os_version"]
+			selected := make(map[string]string, 0)
+			selected["os_name"] = q["os_name"]
+			selected["os_version"] = q["os_version"]
 			if d := q["os_distro"]; d != "" {
-				qualifiers["os_distro"] = d
+				selected["os_distro"] = d
+			}
+			for _, k := range qualifiers {
+				if v := q[k]; v != "" && containsString(optionalQualifiers, k) {
+					selected[k] = v
+				}
 			}
-			purl.Qualifiers = packageurl.QualifiersFromMap(qualifiers)
+			purl.Qualifiers = packageurl.QualifiersFromMap(selected)
 		}
 
 		// filter out duplicate locations
 		locations := make([]Location, 0)
 		for _, loc := range pkg.Locations {
-			if !containsLocation(locations, loc.Path) {
+			if !containsLocation(locations, loc) {
 				locations = append(locations, loc)
 			}
 		}
@@ -71,20 +100,39 @@ func NormalizePackages(pkgs []Package) ([]Package, error) {
 		// filter out duplicate files
 		files := make([]Location, 0)
 		for _, f := range pkg.Files {
-			if !containsLocation(files, f.Path) {
+			if !containsLocation(files, f) {
 				files = append(files, f)
 			}
 		}
 		pkg.Files = files
 
-		// parse license expressions into list of strings
-		pkg.Licenses = parseLicenses(pkg.Licenses)
+		// parse license expressions into list of SPDX identifiers
+		pkg.LicenseExpression = ToLicenseExpression(pkg.Licenses)
+		licenses := make([]string, 0)
+		for _, l := range parseLicenses(pkg.Licenses) {
+			if l = NormalizeLicense(l); l != "" && !containsString(licenses, l) {
+				licenses = append(licenses, l)
+			}
+		}
+		pkg.Licenses = licenses
 
 		// fill in missing details
 		pkg.Type = purl.Type
 		pkg.Namespace = purl.Namespace
 		pkg.Name = purl.Name
 		pkg.Version = purl.Version
+		// the epoch qualifier replaces the epoch prefix of the version in the purl but stays
+		// part of the package version, also if the purl read from another SBOM only has the
+		// qualifier and it isn't selected
+		if epoch != "" {
+			version := strings.TrimPrefix(purl.Version, epoch+":")
+			pkg.Version = epoch + ":" + version
+			if purl.Qualifiers.Map()["epoch"] != "" {
+				purl.Version = version
+			} else {
+				purl.Version = pkg.Version
+			}
+		}
 		pkg.Purl = purl.String()
 
 		nPks = append(nPks, pkg)
@@ -170,18 +218,37 @@ func MergePackages(results ...IndexResult) []Package {
 	packages := make([]Package, 0)
 	for _, result := range results {
 		if result.Status != Success {
-			skill.Log.Warnf(`Failed to index image with %s: %s`, result.Name, result.Error)
+			log.Warnf(`Failed to index image with %s: %s`, result.Name, result.Error)
 			continue
 		}
 		for _, pkg := range result.Packages {
 			if p, ok := containsPackage(&packages, pkg); ok {
+				for _, f := range pkg.FoundBy {
+					if !containsString(packages[p].FoundBy, f) {
+						packages[p].FoundBy = append(packages[p].FoundBy, f)
+					}
+				}
+				if len(packages[p].Licenses) == 0 {
+					packages[p].Licenses = pkg.Licenses
+					packages[p].LicenseExpression = pkg.LicenseExpression
+				}
+				for _, g := range pkg.Groups {
+					if !containsString(packages[p].Groups, g) {
+						packages[p].Groups = append(packages[p].Groups, g)
+					}
+				}
+				sort.Strings(packages[p].Groups)
+				// a package cataloged from metadata outweighs fingerprints of the same package
+				if pkg.Confidence == 0 || packages[p].Confidence != 0 && pkg.Confidence > packages[p].Confidence {
+					packages[p].Confidence = pkg.Confidence
+				}
 				for _, loc := range pkg.Locations {
-					if !containsLocation(packages[p].Locations, loc.Path) {
+					if !containsLocation(packages[p].Locations, loc) {
 						packages[p].Locations = append(packages[p].Locations, loc)
 					}
 				}
 				for _, file := range pkg.Files {
-					if !containsLocation(packages[p].Files, file.Path) {
+					if !containsLocation(packages[p].Files, file) {
 						packages[p].Files = append(packages[p].Files, file)
 					}
 				}
@@ -205,9 +272,10 @@ func containsPackage(packages *[]Package, pkg Package) (int, bool) {
 	return -1, false
 }
 
-func containsLocation(locations []Location, path string) bool {
+// containsLocation reports if locations has the path of location in the same layer
+func containsLocation(locations []Location, location Location) bool {
 	for _, loc := range locations {
-		if loc.Path == path {
+		if loc.Path == location.Path && loc.DiffId == location.DiffId {
 			return true
 		}
 	}
@@ -17,12 +17,14 @@
 package types
 
 import (
+	"strings"
 	"testing"
 )
 
 func TestMergePackages(t *testing.T) {
 	pkga := Package{
-		Purl: "pkg:maven/foo@1.0.0",
+		Purl:    "pkg:maven/foo@1.0.0",
+		FoundBy: []string{"syft"},
 		Files: []Location{{
 			Path:   "/bar",
 			Digest: "sha256:1234",
@@ -30,7 +32,8 @@ func TestMergePackages(t *testing.T) {
 		}},
 	}
 	pkgb := Package{
-		Purl: "pkg:maven/foo@1.0.0",
+		Purl:    "pkg:maven/foo@1.0.0",
+		FoundBy: []string{"trivy"},
 		Files: []Location{{
 			Path:   "/bar",
 			Digest: "sha256:1234",
@@ -55,4 +58,45 @@ func TestMergePackages(t *testing.T) {
 	if len(fpkg.Files) != 2 {
 		t.Error("expected 2 files")
 	}
+	if f := strings.Join(fpkg.FoundBy, ","); f != "syft,trivy" {
+		t.Errorf("expected package found by syft and trivy, got %s", f)
+	}
+}
+
+func TestNormalizePackagesQualifiers(t *testing.T) {
+	pkgs := []Package{{Purl: "pkg:rpm/rhel/openssl@1:1.1.1k-7.el8?arch=x86_64&epoch=1&distro=rhel-8.6&upstream=openssl-1.1.1k-7.el8.src.rpm&os_name=redhatlinux&os_version=8"}}
+
+	normalized, _ := NormalizePackages(pkgs)
+	if p := normalized[0].Purl; p != "pkg:rpm/redhatlinux/openssl@1:1.1.1k-7.el8?os_name=redhatlinux&os_version=8" {
+		t.Errorf("unexpected default purl %s", p)
+	}
+
+	qualifiers, err := ParsePurlQualifiers([]string{"arch", "Epoch", "distro", "arch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, _ = NormalizePackages(pkgs, qualifiers...)
+	if p := normalized[0].Purl; p != "pkg:rpm/redhatlinux/openssl@1.1.1k-7.el8?arch=x86_64&distro=rhel-8.6&epoch=1&os_name=redhatlinux&os_version=8" {
+		t.Errorf("unexpected qualified purl %s", p)
+	}
+	if v := normalized[0].Version; v != "1:1.1.1k-7.el8" {
+		t.Errorf("expected epoch to stay part of the version, got %s", v)
+	}
+	if again, _ := NormalizePackages(normalized, qualifiers...); again[0].Purl != normalized[0].Purl || again[0].Version != normalized[0].Version {
+		t.Errorf("expected normalizing to be idempotent, got %+v", again[0])
+	}
+
+	// other tools write the epoch only as qualifier
+	converted, _ := NormalizePackages([]Package{{Purl: "pkg:rpm/rhel/openssl@1.1.1k-7.el8?arch=x86_64&epoch=1&os_name=redhatlinux&os_version=8"}}, qualifiers...)
+	if p, v := converted[0].Purl, converted[0].Version; p != "pkg:rpm/redhatlinux/openssl@1.1.1k-7.el8?arch=x86_64&epoch=1&os_name=redhatlinux&os_version=8" || v != "1:1.1.1k-7.el8" {
+		t.Errorf("expected epoch qualifier of converted purl to be kept, got %s %s", p, v)
+	}
+	converted, _ = NormalizePackages([]Package{{Purl: "pkg:rpm/rhel/openssl@1.1.1k-7.el8?arch=x86_64&epoch=1&os_name=redhatlinux&os_version=8"}})
+	if p, v := converted[0].Purl, converted[0].Version; p != "pkg:rpm/redhatlinux/openssl@1:1.1.1k-7.el8?os_name=redhatlinux&os_version=8" || v != "1:1.1.1k-7.el8" {
+		t.Errorf("expected epoch of converted purl to move into the version, got %s %s", p, v)
+	}
+
+	if _, err := ParsePurlQualifiers([]string{"upstream"}); err == nil {
+		t.Error("expected unsupported qualifier to be rejected")
+	}
 }
@@ -0,0 +1,129 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// Schema returns the JSON Schema of the native sbom format, generated from the Sbom type
+func Schema() ([]byte, error) {
+	g := schemaGenerator{definitions: make(map[string]interface{})}
+	schema := g.object(reflect.TypeOf(Sbom{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "docker index sbom"
+	schema["definitions"] = g.definitions
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type schemaGenerator struct {
+	definitions map[string]interface{}
+}
+
+// schema returns the schema of values of type t the way encoding/json marshals them
+func (g schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		return nullable(g.schema(t.Elem()))
+	}
+	if t.Implements(textMarshaler) {
+		return map[string]interface{}{"type": "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return nullable(map[string]interface{}{"type": "array", "items": g.schema(t.Elem())})
+	case reflect.Map:
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())})
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := t.Name()
+		if t.PkgPath() != reflect.TypeOf(Sbom{}).PkgPath() {
+			name = path.Base(t.PkgPath()) + "." + name
+		}
+		if _, ok := g.definitions[name]; !ok {
+			// register the name first so that recursive types terminate
+			g.definitions[name] = nil
+			g.definitions[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// object returns the schema of struct t, inlining the fields of embedded structs
+func (g schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				add(f.Type)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			properties[name] = g.schema(f.Type)
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	add(t)
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// nullable allows null in addition to the values of schema, as encoding/json writes nil pointers,
+// slices and maps as null
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if t, ok := schema["type"].(string); ok {
+		schema["type"] = []string{t, "null"}
+		return schema
+	}
+	return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+}
@@ -48,14 +48,29 @@ type Advisory struct {
 }
 
 type Cve struct {
-	Purl            string    `edn:"purl" json:"purl"`
-	Source          string    `edn:"source" json:"source"`
-	SourceId        string    `edn:"source-id" json:"source_id"`
-	VulnerableRange string    `edn:"vulnerable-range" json:"vulnerable_range"`
-	AdvisoryUrl     string    `edn:"url" json:"-"`
-	FixedBy         string    `edn:"fixed-by" json:"fixed_by,omitempty"`
-	Advisory        *Advisory `edn:"v" json:"vendor_advisory,omitempty"`
-	Cve             *Advisory `edn:"cve" json:"nist_cve,omitempty"`
+	Purl            string       `edn:"purl" json:"purl"`
+	Source          string       `edn:"source" json:"source"`
+	SourceId        string       `edn:"source-id" json:"source_id"`
+	VulnerableRange string       `edn:"vulnerable-range" json:"vulnerable_range"`
+	AdvisoryUrl     string       `edn:"url" json:"-"`
+	FixedBy         string       `edn:"fixed-by" json:"fixed_by,omitempty"`
+	Advisory        *Advisory    `edn:"v" json:"vendor_advisory,omitempty"`
+	Cve             *Advisory    `edn:"cve" json:"nist_cve,omitempty"`
+	Status          string       `edn:"-" json:"status,omitempty"`
+	Justification   string       `edn:"-" json:"justification,omitempty"`
+	Suppression     *Suppression `edn:"-" json:"suppression,omitempty"`
+	Epss            *Epss        `edn:"-" json:"epss,omitempty"`
+	KnownExploited  bool         `edn:"-" json:"known_exploited,omitempty"`
+}
+
+type Epss struct {
+	Score      float64 `json:"score"`
+	Percentile float64 `json:"percentile"`
+}
+
+type Suppression struct {
+	Justification string `json:"justification"`
+	Expires       string `json:"expires"`
 }
 
 type LayerMapping struct {
@@ -72,6 +87,15 @@ type IndexResult struct {
 	Status   string
 	Error    error
 	Distro   Distro
+	Secrets  []Secret
+	// Removed are the packages a layer installed and a later layer deleted or replaced
+	Removed []Package
+	// Files are the regular files of the final filesystem, only recorded in file inventory mode
+	Files []File
+	// Waste is the wasted space of the image, only analyzed on request
+	Waste *Waste
+	// CacheFiles are the files of package manager caches and the pip wheels of the final filesystem
+	CacheFiles []File
 }
 
 const (
@@ -83,6 +107,10 @@ type Distro struct {
 	OsName    string `json:"os_name,omitempty"`
 	OsVersion string `json:"os_version,omitempty"`
 	OsDistro  string `json:"os_distro,omitempty"`
+	// Eol is the date the security support of the distro release ends, e.g. 2022-06-30
+	Eol string `json:"eol,omitempty"`
+	// EndOfLife is set if the distro release was past its end of life when the sbom was created
+	EndOfLife bool `json:"end_of_life,omitempty"`
 }
 
 type Platform struct {
@@ -91,12 +119,34 @@ type Platform struct {
 	Variant      string `json:"variant,omitempty"`
 }
 
+func (p Platform) String() string {
+	platform := p.Os + "/" + p.Architecture
+	if p.Variant != "" {
+		platform += "/" + p.Variant
+	}
+	return platform
+}
+
 type Location struct {
 	Path   string `json:"path"`
 	Digest string `json:"digest"`
 	DiffId string `json:"diff_id"`
 }
 
+type Layer struct {
+	Ordinal   int    `json:"ordinal"`
+	DiffId    string `json:"diff_id"`
+	Digest    string `json:"digest"`
+	CreatedBy string `json:"created_by,omitempty"`
+	BaseImage bool   `json:"base_image,omitempty"`
+}
+
+type BaseImage struct {
+	Name       string `json:"name"`
+	Digest     string `json:"digest"`
+	LayerCount int    `json:"layer_count"`
+}
+
 type ImageSource struct {
 	Name        string         `json:"name"`
 	Digest      string         `json:"digest"`
@@ -108,12 +158,51 @@ type ImageSource struct {
 	Distro      Distro         `json:"distro"`
 	Platform    Platform       `json:"platform"`
 	Size        int64          `json:"size"`
+	BaseImage   *BaseImage     `json:"base_image,omitempty"`
+	// SkippedLayers are the non-distributable layers that were not analyzed
+	SkippedLayers []Layer `json:"skipped_layers,omitempty"`
+	// History are the build steps of the image recorded in its config, oldest first
+	History []HistoryEntry `json:"history,omitempty"`
+}
+
+// HistoryEntry is a build step of an image, e.g. a Dockerfile instruction
+type HistoryEntry struct {
+	CreatedBy  string `json:"created_by,omitempty"`
+	Created    string `json:"created,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	EmptyLayer bool   `json:"empty_layer,omitempty"`
+	// Layer is the layer the step created, unset for steps that only changed the config
+	Layer *Layer `json:"layer,omitempty"`
+	// BaseImage is set for the steps inherited from the detected base image
+	BaseImage bool `json:"base_image,omitempty"`
 }
 
 type Descriptor struct {
 	Name        string `json:"name"`
 	Version     string `json:"version"`
 	SbomVersion string `json:"sbom_version"`
+	// Catalogers records the outcome of every cataloger; packages of failed ones are missing
+	Catalogers []CatalogerStatus `json:"catalogers,omitempty"`
+	// Annotations are key/value pairs attached by the caller to trace the sbom to the build that
+	// produced it, e.g. the git commit or build URL
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type CatalogerStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// FailedCatalogers returns the names of the catalogers that failed to index the image
+func (s *Sbom) FailedCatalogers() []string {
+	failed := make([]string, 0)
+	for _, c := range s.Descriptor.Catalogers {
+		if c.Status == Failed {
+			failed = append(failed, c.Name)
+		}
+	}
+	return failed
 }
 
 type Source struct {
@@ -125,24 +214,138 @@ type Sbom struct {
 	Source          Source     `json:"source"`
 	Artifacts       []Package  `json:"artifacts"`
 	Vulnerabilities []Cve      `json:"vulnerabilities,omitempty"`
+	Secrets         []Secret   `json:"secrets,omitempty"`
 	Descriptor      Descriptor `json:"descriptor"`
+	// MergedSources are the sources of the other sboms merged into this one
+	MergedSources []Source `json:"merged_sources,omitempty"`
+	// Removed are the packages installed by a layer and deleted or replaced by a later one, so
+	// they are not part of the final image
+	Removed []Package `json:"removed,omitempty"`
+	// Files is the inventory of the regular files of the final filesystem of the image
+	Files []File `json:"files,omitempty"`
+	// ConfigFindings are the problems the built-in checks found in the image configuration
+	ConfigFindings []ConfigFinding `json:"config_findings,omitempty"`
+	// Delta are the vulnerability changes since the previous scan of the image repository
+	Delta *Delta `json:"delta,omitempty"`
+	// Waste is the space taken by files the final filesystem of the image doesn't need
+	Waste *Waste `json:"waste,omitempty"`
+	// Leftovers are the build tools and package manager caches shipped in the final stage
+	Leftovers []Leftover `json:"leftovers,omitempty"`
+}
+
+// Leftover is build tooling or a package manager cache shipped in the final stage of an image
+type Leftover struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+	// Size is the space the leftover takes in bytes, 0 if unknown
+	Size     int64    `json:"size"`
+	Packages []string `json:"packages,omitempty"`
+	Paths    []string `json:"paths,omitempty"`
+	// Layer is the layer that installed the package or last wrote the files
+	Layer *Layer `json:"layer,omitempty"`
+}
+
+// Waste lists the files that make an image larger without being of use in its final filesystem
+type Waste struct {
+	// Total is the sum of the sizes of the wasted files in bytes
+	Total int64        `json:"total"`
+	Files []WastedFile `json:"files,omitempty"`
+}
+
+const (
+	WasteOverwritten = "overwritten"
+	WasteDeleted     = "deleted"
+	WasteDuplicate   = "duplicate"
+	WasteCache       = "cache"
+)
+
+// WastedFile is a file written by a layer that is overwritten or deleted by a later layer, a
+// duplicate of another file, or a package manager cache
+type WastedFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+	Layer  *Layer `json:"layer,omitempty"`
+	// RemovedBy is the layer that overwrote or deleted the file
+	RemovedBy *Layer `json:"removed_by,omitempty"`
+	// DuplicateOf is the path of the first file with the same content
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// Cache is the package manager that left the cache behind, e.g. apt or pip
+	Cache string `json:"cache,omitempty"`
+}
+
+// Delta lists the vulnerabilities detected and no longer detected since a previous scan
+type Delta struct {
+	PreviousImage  string `json:"previous_image"`
+	PreviousDigest string `json:"previous_digest"`
+	New            []Cve  `json:"new"`
+	Resolved       []Cve  `json:"resolved"`
+}
+
+// ConfigFinding is a problem of the image configuration, like running as root
+type ConfigFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// File is a regular file of the final filesystem of the image
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256,omitempty"`
+	// Package is the purl of the package that installed the file, if known
+	Package string `json:"package,omitempty"`
+	Layer   *Layer `json:"layer,omitempty"`
+}
+
+type Secret struct {
+	RuleId      string `json:"rule_id"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
+	Line        int    `json:"line"`
+	Match       string `json:"match"`
+	Layer       *Layer `json:"layer,omitempty"`
 }
 
 type Package struct {
-	Type          string     `json:"type"`
-	Namespace     string     `json:"namespace,omitempty"`
-	Name          string     `json:"name"`
-	Version       string     `json:"version"`
-	Purl          string     `json:"purl"`
-	Author        string     `json:"author,omitempty"`
-	Description   string     `json:"description,omitempty"`
-	Licenses      []string   `json:"licenses,omitempty"`
-	Url           string     `json:"url,omitempty"`
-	Size          int        `json:"size,omitempty"`
-	InstalledSize int        `json:"installed_size,omitempty"`
-	Locations     []Location `json:"locations"`
-	Files         []Location `json:"files,omitempty"`
-	Parent        string     `json:"parent,omitempty"`
+	Type              string     `json:"type"`
+	Namespace         string     `json:"namespace,omitempty"`
+	Name              string     `json:"name"`
+	Version           string     `json:"version"`
+	Purl              string     `json:"purl"`
+	Cpes              []string   `json:"cpes,omitempty"`
+	Author            string     `json:"author,omitempty"`
+	Description       string     `json:"description,omitempty"`
+	Licenses          []string   `json:"licenses,omitempty"`
+	LicenseExpression string     `json:"license_expression,omitempty"`
+	Url               string     `json:"url,omitempty"`
+	Size              int        `json:"size,omitempty"`
+	InstalledSize     int        `json:"installed_size,omitempty"`
+	Locations         []Location `json:"locations"`
+	Files             []Location `json:"files,omitempty"`
+	Parent            string     `json:"parent,omitempty"`
+	Layer             *Layer     `json:"layer,omitempty"`
+	// Vcs is the revision a binary was built from, e.g. the main module of a Go binary
+	Vcs *Vcs `json:"vcs,omitempty"`
+	// Confidence is set for packages fingerprinted in binaries or bundles, from 0 to 1; packages
+	// without it are cataloged from package metadata
+	Confidence float64 `json:"confidence,omitempty"`
+	// Groups lists the dependency groups of the application declaring the package, e.g. the
+	// Bundler groups of a gem
+	Groups []string `json:"groups,omitempty"`
+	// RemovedBy is the layer that deleted or replaced the package, only set for packages in
+	// Sbom.Removed
+	RemovedBy *Layer `json:"removed_by,omitempty"`
+	// FoundBy are the catalogers that detected the package, e.g. syft/apkdb-cataloger or trivy
+	FoundBy []string `json:"found_by,omitempty"`
+}
+
+type Vcs struct {
+	Type     string `json:"type"`
+	Revision string `json:"revision"`
+	Time     string `json:"time,omitempty"`
+	Modified bool   `json:"modified,omitempty"`
 }
 
 var NamespaceMapping = map[string]string{
@@ -151,6 +354,8 @@ var NamespaceMapping = map[string]string{
 	"amazon": "amazonlinux",
 	"amzn":   "amazonlinux",
 	"rhel":   "redhatlinux",
+	// chainguard images are built from wolfi packages
+	"chainguard": "wolfi",
 }
 
 var PackageTypeMapping = map[string]string{
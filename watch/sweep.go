@@ -0,0 +1,133 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/pkg/errors"
+)
+
+// batchFunc indexes the images of a sweep; replaced in tests
+var batchFunc = func(ctx context.Context, indexer *sbom.Indexer, images []string, opts sbom.BatchOptions) []sbom.ImageIndexResult {
+	return indexer.IndexImages(ctx, images, opts)
+}
+
+// Sweep indexes the matching tags of all repositories below namespace, e.g. registry.example.com/myorg,
+// or of opts.Repositories with the parallelism of indexer and passes every result to emit. Tags
+// of the same digest are indexed once and digests indexed by an earlier sweep are skipped.
+func Sweep(ctx context.Context, namespace string, indexer *sbom.Indexer, opts Options, emit func(Result)) error {
+	if opts.StatePath == "" {
+		opts.StatePath = defaultStatePath(namespace)
+	}
+	indexed, err := readState(opts.StatePath)
+	if err != nil {
+		return err
+	}
+	repos, err := repositories(ctx, namespace, opts.Repositories)
+	if err != nil {
+		return err
+	}
+	log.Infof("Found %d repositories in %s", len(repos), namespace)
+
+	indexedDigests := make(map[string]bool)
+	for _, digest := range indexed {
+		indexedDigests[digest] = true
+	}
+	// images maps the digests to index to all their new or changed tags
+	images := make(map[string][]string)
+	for _, repo := range repos {
+		tags, err := registry.ListTags(ctx, repo)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warnf("%s", err)
+			continue
+		}
+		for _, tag := range tags {
+			if !matchesTag(opts.Tags, tag) {
+				continue
+			}
+			image := repo + ":" + tag
+			digest, err := registry.ResolveDigest(ctx, image)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Warnf("%s", err)
+				continue
+			}
+			if indexed[image] == digest {
+				continue
+			}
+			if indexedDigests[digest] {
+				log.Debugf("Skipping %s of already indexed digest %s", image, digest)
+				indexed[image] = digest
+				continue
+			}
+			images[digest] = append(images[digest], image)
+		}
+	}
+	// every digest is indexed once by its first tag
+	inputs := make([]string, 0)
+	digests := make(map[string]string)
+	for digest, tags := range images {
+		sort.Strings(tags)
+		inputs = append(inputs, tags[0])
+		digests[tags[0]] = digest
+	}
+	sort.Strings(inputs)
+	log.Infof("Indexing %d new or changed images", len(inputs))
+
+	results := batchFunc(ctx, indexer, inputs, sbom.BatchOptions{Timeout: opts.Timeout})
+	for _, r := range results {
+		result := queryResult(ctx, Result{Image: r.Input, Digest: digests[r.Input], Sbom: r.Sbom, Err: r.IndexError}, opts.Backend)
+		emit(result)
+		// failed images are retried on the next sweep
+		if result.Err == nil {
+			for _, image := range images[result.Digest] {
+				indexed[image] = result.Digest
+			}
+		}
+	}
+	if err := writeState(opts.StatePath, indexed); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// repositories returns the names below namespace or else lists the repositories of namespace
+func repositories(ctx context.Context, namespace string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		repos, err := registry.ListRepositories(ctx, namespace)
+		if errors.Is(err, registry.ErrNoCatalog) {
+			return nil, errors.Wrap(err, "select the repositories to index with --repository")
+		}
+		return repos, err
+	}
+	repos := make([]string, 0)
+	for _, n := range names {
+		repos = append(repos, strings.TrimSuffix(namespace, "/")+"/"+strings.Trim(n, "/"))
+	}
+	return repos, nil
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/index-cli-plugin/internal"
+	"github.com/docker/index-cli-plugin/log"
+	"github.com/docker/index-cli-plugin/query"
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/pkg/errors"
+)
+
+// indexFunc indexes a single image; replaced in tests
+var indexFunc = func(ctx context.Context, indexer *sbom.Indexer, image string) (*types.Sbom, *v1.Image, error) {
+	return indexer.Index(ctx, image)
+}
+
+// Options configure a Watcher
+type Options struct {
+	// Interval between two polls of the repository, defaults to five minutes
+	Interval time.Duration
+	// Tags are patterns like 1.* selecting the tags to index, all tags if empty
+	Tags []string
+	// StatePath stores the indexed digests so that restarts don't index them again,
+	// defaults to a file per repository in the cache directory
+	StatePath string
+	// Backend queries the CVEs of indexed images if set
+	Backend query.Backend
+	// Timeout limits the time to index a single image in Sweep, no limit if zero
+	Timeout time.Duration
+	// Repositories are the names of the repositories below the namespace of Sweep, e.g. web
+	// for ghcr.io/myorg/web, to index instead of listing them with the catalog API
+	Repositories []string
+}
+
+// Result is the outcome of indexing a new digest of a tag
+type Result struct {
+	Image  string
+	Digest string
+	Sbom   *types.Sbom
+	// Err is set if the image could not be indexed or its CVEs not queried
+	Err error
+}
+
+// Watcher polls a repository for new tags and digests and indexes them
+type Watcher struct {
+	repo    string
+	indexer *sbom.Indexer
+	opts    Options
+	// indexed maps tags to the digest they referred to when they were last indexed
+	indexed map[string]string
+}
+
+// New returns a Watcher for repo, e.g. registry.example.com/app, that indexes images with indexer
+func New(repo string, indexer *sbom.Indexer, opts Options) (*Watcher, error) {
+	for _, p := range opts.Tags {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid tag pattern %s", p)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Minute
+	}
+	if opts.StatePath == "" {
+		opts.StatePath = defaultStatePath(repo)
+	}
+	indexed, err := readState(opts.StatePath)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{repo: repo, indexer: indexer, opts: opts, indexed: indexed}, nil
+}
+
+// Run polls the repository until ctx is done and passes the result of every indexed image
+// to emit. Failed polls are logged and retried on the next interval.
+func (w *Watcher) Run(ctx context.Context, emit func(Result)) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Poll(ctx, emit); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Warnf("%s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll lists the tags of the repository once and indexes those with a new digest
+func (w *Watcher) Poll(ctx context.Context, emit func(Result)) error {
+	tags, err := registry.ListTags(ctx, w.repo)
+	if err != nil {
+		return err
+	}
+	sort.Strings(tags)
+	log.Debugf("Found %d tags in %s", len(tags), w.repo)
+	// tags of an already indexed digest, e.g. latest and 1.0, are recorded without indexing
+	// the image again
+	digests := make(map[string]bool)
+	for _, digest := range w.indexed {
+		digests[digest] = true
+	}
+	for _, tag := range tags {
+		if !matchesTag(w.opts.Tags, tag) {
+			continue
+		}
+		image := w.repo + ":" + tag
+		digest, err := registry.ResolveDigest(ctx, image)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warnf("%s", err)
+			continue
+		}
+		if w.indexed[tag] == digest {
+			continue
+		}
+		if digests[digest] {
+			log.Debugf("Skipping %s of already indexed digest %s", image, digest)
+			w.indexed[tag] = digest
+			if err := writeState(w.opts.StatePath, w.indexed); err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.Infof("Indexing new digest %s of %s", digest, image)
+		result := w.index(ctx, image, digest)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		emit(result)
+		// failed images are retried on the next poll
+		if result.Err == nil {
+			digests[digest] = true
+			w.indexed[tag] = digest
+			if err := writeState(w.opts.StatePath, w.indexed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) index(ctx context.Context, image string, digest string) Result {
+	sb, _, err := indexFunc(ctx, w.indexer, image)
+	return queryResult(ctx, Result{Image: image, Digest: digest, Sbom: sb, Err: err}, w.opts.Backend)
+}
+
+// queryResult queries the CVEs of the sbom of a successfully indexed image if backend is set
+func queryResult(ctx context.Context, result Result, backend query.Backend) Result {
+	if result.Err != nil {
+		return result
+	}
+	sb := result.Sbom
+	if backend != nil {
+		cves, err := backend.QueryCves(ctx, sb, "")
+		if err != nil {
+			result.Err = errors.Wrapf(err, "failed to query CVEs of %s", result.Image)
+			return result
+		}
+		if cves != nil {
+			sb.Vulnerabilities = *cves
+			sbom.SortSbom(sb)
+		}
+	}
+	return result
+}
+
+func matchesTag(patterns []string, tag string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultStatePath returns the state file of a repository or namespace in the cache directory
+func defaultStatePath(name string) string {
+	name = strings.NewReplacer("/", "_", ":", "_").Replace(name)
+	return filepath.Join(internal.CachePath(), "watch", name+".json")
+}
+
+// readState returns the digests indexed before, stored at path
+func readState(path string) (map[string]string, error) {
+	indexed := make(map[string]string)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return indexed, nil
+	}
+	if err := json.Unmarshal(b, &indexed); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse watch state %s", path)
+	}
+	return indexed, nil
+}
+
+func writeState(path string, indexed map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "failed to write watch state %s", path)
+	}
+	b, err := json.MarshalIndent(indexed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write watch state %s", path)
+	}
+	return nil
+}
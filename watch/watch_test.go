@@ -0,0 +1,187 @@
+/*
+ * Copyright © 2022 Docker, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/index-cli-plugin/registry"
+	"github.com/docker/index-cli-plugin/sbom"
+	"github.com/docker/index-cli-plugin/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcr "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestPoll(t *testing.T) {
+	defer func(f func(context.Context, *sbom.Indexer, string) (*types.Sbom, *v1.Image, error)) { indexFunc = f }(indexFunc)
+	indexFunc = func(ctx context.Context, indexer *sbom.Indexer, image string) (*types.Sbom, *v1.Image, error) {
+		return &types.Sbom{Source: types.Source{Image: types.ImageSource{Name: image}}}, nil, nil
+	}
+
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	repo := strings.TrimPrefix(server.URL, "http://") + "/app"
+	push := func(tag string) {
+		img, _ := random.Image(1024, 1)
+		ref, _ := name.ParseReference(repo + ":" + tag)
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push("1.0")
+	push("1.1")
+	push("dev")
+
+	state := filepath.Join(t.TempDir(), "state.json")
+	poll := func() []string {
+		w, err := New(repo, sbom.NewIndexer(), Options{Tags: []string{"1.*"}, StatePath: state})
+		if err != nil {
+			t.Fatal(err)
+		}
+		images := make([]string, 0)
+		err = w.Poll(context.Background(), func(r Result) {
+			if r.Err != nil {
+				t.Error(r.Err)
+			}
+			images = append(images, r.Image)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return images
+	}
+
+	if images := poll(); len(images) != 2 || images[0] != repo+":1.0" || images[1] != repo+":1.1" {
+		t.Errorf("expected matching tags to be indexed, got %v", images)
+	}
+	push("1.1")
+	if images := poll(); len(images) != 1 || images[0] != repo+":1.1" {
+		t.Errorf("expected only moved tag to be indexed again, got %v", images)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	defer func(f func(context.Context, *sbom.Indexer, []string, sbom.BatchOptions) []sbom.ImageIndexResult) {
+		batchFunc = f
+	}(batchFunc)
+	batchFunc = func(ctx context.Context, indexer *sbom.Indexer, images []string, opts sbom.BatchOptions) []sbom.ImageIndexResult {
+		results := make([]sbom.ImageIndexResult, 0)
+		for _, image := range images {
+			results = append(results, sbom.ImageIndexResult{Input: image, Sbom: &types.Sbom{}})
+		}
+		return results
+	}
+
+	server := httptest.NewServer(ggcr.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	for _, image := range []string{"myorg/web:latest", "myorg/web:1.0", "myorg/db:latest", "other/app:latest"} {
+		img, _ := random.Image(1024, 1)
+		ref, _ := name.ParseReference(host + "/" + image)
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := Options{Tags: []string{"latest"}, StatePath: filepath.Join(t.TempDir(), "state.json")}
+	sweep := func() []string {
+		images := make([]string, 0)
+		err := Sweep(context.Background(), host+"/myorg", sbom.NewIndexer(), opts, func(r Result) {
+			images = append(images, r.Image)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return images
+	}
+	if images := sweep(); len(images) != 2 || images[0] != host+"/myorg/db:latest" || images[1] != host+"/myorg/web:latest" {
+		t.Errorf("expected latest tags of namespace to be indexed, got %v", images)
+	}
+	if images := sweep(); len(images) != 0 {
+		t.Errorf("expected indexed digests to be skipped, got %v", images)
+	}
+}
+
+func TestSweepRepositories(t *testing.T) {
+	defer func(f func(context.Context, *sbom.Indexer, []string, sbom.BatchOptions) []sbom.ImageIndexResult) {
+		batchFunc = f
+	}(batchFunc)
+	batchFunc = func(ctx context.Context, indexer *sbom.Indexer, images []string, opts sbom.BatchOptions) []sbom.ImageIndexResult {
+		results := make([]sbom.ImageIndexResult, 0)
+		for _, image := range images {
+			results = append(results, sbom.ImageIndexResult{Input: image, Sbom: &types.Sbom{}})
+		}
+		return results
+	}
+
+	// a registry without catalog API
+	handler := ggcr.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/_catalog" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	push := func(img v1.Image, image string) {
+		ref, _ := name.ParseReference(host + "/" + image)
+		if err := remote.Write(ref, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	web, _ := random.Image(1024, 1)
+	api, _ := random.Image(1024, 1)
+	push(web, "myorg/web:latest")
+	push(web, "myorg/web:stable")
+	push(api, "myorg/api:latest")
+
+	opts := Options{StatePath: filepath.Join(t.TempDir(), "state.json")}
+	sweep := func() ([]string, error) {
+		images := make([]string, 0)
+		err := Sweep(context.Background(), host+"/myorg", sbom.NewIndexer(), opts, func(r Result) {
+			images = append(images, r.Image)
+		})
+		return images, err
+	}
+	if _, err := sweep(); !errors.Is(err, registry.ErrNoCatalog) {
+		t.Fatalf("expected missing catalog API to fail, got %v", err)
+	}
+
+	opts.Repositories = []string{"web", "api"}
+	images, err := sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || images[0] != host+"/myorg/api:latest" || images[1] != host+"/myorg/web:latest" {
+		t.Errorf("expected tags of the same digest to be indexed once, got %v", images)
+	}
+	push(web, "myorg/web:1.0")
+	if images, _ := sweep(); len(images) != 0 {
+		t.Errorf("expected new tag of indexed digest to be skipped, got %v", images)
+	}
+}